@@ -0,0 +1,264 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FunctionalAnnotation is a single annotation taken from the doc comment of a
+// controller or action, for example
+//   // @ViewArg(section, "Users")
+// Positional values are stored in Data using their index ("0", "1", ...),
+// named values (key=value) are stored using their name.
+type FunctionalAnnotation struct {
+	Name string
+	Data map[string]string
+}
+
+// FunctionalAnnotations is the list of annotations attached to a controller or method
+type FunctionalAnnotations []*FunctionalAnnotation
+
+// AnnotationProcessor is called once for every annotation of the registered name
+// when a controller is added. The methodType is nil when the annotation was
+// placed on the controller itself.
+type AnnotationProcessor func(controllerType *ControllerType, methodType *MethodType, annotation *FunctionalAnnotation) error
+
+var annotationProcessors = map[string][]AnnotationProcessor{}
+
+// The arguments of the known annotations, by lower case name
+var annotationSchemas = map[string][]string{}
+
+// AnnotationError is an annotation which is unknown or could not be processed
+type AnnotationError struct {
+	Controller string // The controller name with the namespace
+	Action     string // The method name, empty for an annotation on the controller
+	Annotation string
+	Err        error
+}
+
+func (e *AnnotationError) Error() string {
+	location := e.Controller
+	if e.Action != "" {
+		location += "." + e.Action
+	}
+	return fmt.Sprintf("%s: @%s %s", location, e.Annotation, e.Err)
+}
+
+// The errors found while processing the annotations of the registered controllers
+var annotationErrors []*AnnotationError
+
+// With annotations.strict=true the application refuses to start if an annotation is
+// unknown, has arguments its schema does not list or fails to be processed
+func init() {
+	OnAppStart(func() {
+		if len(annotationErrors) == 0 || !Config.BoolDefault("annotations.strict", false) {
+			return
+		}
+		for _, err := range annotationErrors {
+			controllerLog.Error("Invalid annotation", "controller", err.Controller, "action", err.Action, "annotation", err.Annotation, "error", err.Err)
+		}
+		controllerLog.Fatal("Invalid annotations found with annotations.strict enabled, see the errors above")
+	}, 5)
+}
+
+// RegisterAnnotationProcessor adds a processor for the named annotation, names are case insensitive.
+func RegisterAnnotationProcessor(name string, processor AnnotationProcessor) {
+	name = strings.ToLower(strings.TrimPrefix(name, "@"))
+	annotationProcessors[name] = append(annotationProcessors[name], processor)
+}
+
+// RegisterAnnotationSchema lists the arguments of the named annotation in their position
+// order, they may be passed by position or by name. An argument ending in ... takes all
+// the remaining positional values
+//   revel.RegisterAnnotationSchema("Cache", "ttl", "key", "vary")
+//   revel.RegisterAnnotationSchema("Produces", "types...")
+// An annotation without a schema or processor is unknown and fails the annotations.strict
+// check, an annotation with only a processor accepts any arguments.
+func RegisterAnnotationSchema(name string, args ...string) {
+	annotationSchemas[strings.ToLower(strings.TrimPrefix(name, "@"))] = args
+}
+
+// AnnotationErrors returns the errors found in the annotations of the registered controllers
+func AnnotationErrors() []*AnnotationError {
+	return annotationErrors
+}
+
+// Called by AddControllerType, controller annotations are processed first so
+// that method annotations can override them
+func processAnnotations(ct *ControllerType) {
+	// Registering the controller again replaces its errors
+	errors := annotationErrors[:0]
+	for _, err := range annotationErrors {
+		if err.Controller != ct.Name() {
+			errors = append(errors, err)
+		}
+	}
+	annotationErrors = errors
+
+	process := func(mt *MethodType, annotations FunctionalAnnotations) {
+		action := ""
+		if mt != nil {
+			action = mt.Name
+		}
+		for _, annotation := range annotations {
+			name := strings.ToLower(annotation.Name)
+			if err := validateAnnotation(annotation); err != nil {
+				annotationErrors = append(annotationErrors, &AnnotationError{ct.Name(), action, annotation.Name, err})
+				controllerLog.Debug("Invalid annotation", "controller", ct.Name(), "action", action, "annotation", annotation.Name, "error", err)
+			}
+			for _, processor := range annotationProcessors[name] {
+				if err := processor(ct, mt, annotation); err != nil {
+					annotationErrors = append(annotationErrors, &AnnotationError{ct.Name(), action, annotation.Name, err})
+					controllerLog.Error("Failed to process annotation", "controller", ct.Name(), "annotation", annotation.Name, "error", err)
+				}
+			}
+		}
+	}
+	process(nil, ct.Annotations)
+	for _, mt := range ct.Methods {
+		process(mt, mt.Annotations)
+	}
+}
+
+// Checks the annotation is known and its arguments match the schema
+func validateAnnotation(annotation *FunctionalAnnotation) error {
+	name := strings.ToLower(annotation.Name)
+	args, found := annotationSchemas[name]
+	if !found {
+		if len(annotationProcessors[name]) == 0 {
+			return fmt.Errorf("is not a known annotation")
+		}
+		return nil
+	}
+	variadic := len(args) > 0 && strings.HasSuffix(args[len(args)-1], "...")
+	for key := range annotation.Data {
+		if position, err := strconv.Atoi(key); err == nil {
+			if len(args) == 0 {
+				return fmt.Errorf("takes no arguments")
+			} else if position >= len(args) && !variadic {
+				return fmt.Errorf("takes at most %d arguments", len(args))
+			}
+			continue
+		}
+		known := false
+		for _, arg := range args {
+			if arg == key {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("has an unknown argument %s, expected one of %s", key, strings.Join(args, ", "))
+		}
+	}
+	return nil
+}
+
+// Value returns the data stored under the key, or the positional value if the key is not found
+func (a *FunctionalAnnotation) Value(key string, position int) (value string, found bool) {
+	if value, found = a.Data[key]; !found {
+		value, found = a.Data[strconv.Itoa(position)]
+	}
+	return
+}
+
+// ParseAnnotations returns all the annotations found in a comment block, each
+// annotation must start on its own line
+func ParseAnnotations(comment string) (annotations FunctionalAnnotations, err error) {
+	for _, line := range strings.Split(comment, "\n") {
+		line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "/*"))
+		if !strings.HasPrefix(line, "@") {
+			continue
+		}
+		annotation, err := ParseAnnotation(line)
+		if err != nil {
+			return nil, err
+		}
+		annotations = append(annotations, annotation)
+	}
+	return
+}
+
+// ParseAnnotation parses a single annotation in the form of
+//   @Name
+//   @Name(value1, "value 2", key=value)
+func ParseAnnotation(text string) (*FunctionalAnnotation, error) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "@") {
+		return nil, fmt.Errorf("annotation %q must start with @", text)
+	}
+	text = text[1:]
+	annotation := &FunctionalAnnotation{Data: map[string]string{}}
+	open := strings.Index(text, "(")
+	if open < 0 {
+		annotation.Name = strings.TrimSpace(text)
+		if annotation.Name == "" || strings.ContainsAny(annotation.Name, " \t)") {
+			return nil, fmt.Errorf("invalid annotation @%s", text)
+		}
+		return annotation, nil
+	}
+	if !strings.HasSuffix(text, ")") {
+		return nil, fmt.Errorf("annotation @%s is missing a closing bracket", text)
+	}
+	annotation.Name = strings.TrimSpace(text[:open])
+	if annotation.Name == "" {
+		return nil, fmt.Errorf("invalid annotation @%s", text)
+	}
+	args, err := splitAnnotationArgs(text[open+1 : len(text)-1])
+	if err != nil {
+		return nil, fmt.Errorf("annotation @%s: %s", annotation.Name, err)
+	}
+	position := 0
+	for _, arg := range args {
+		key := strconv.Itoa(position)
+		if eq := strings.Index(arg, "="); eq > 0 && !isQuoted(arg) {
+			key, arg = strings.TrimSpace(arg[:eq]), strings.TrimSpace(arg[eq+1:])
+		} else {
+			position++
+		}
+		if isQuoted(arg) {
+			if arg[0] == '\'' {
+				arg = arg[1 : len(arg)-1]
+			} else if arg, err = strconv.Unquote(arg); err != nil {
+				return nil, fmt.Errorf("annotation @%s: invalid value %s", annotation.Name, arg)
+			}
+		}
+		annotation.Data[key] = arg
+	}
+	return annotation, nil
+}
+
+// Split the arguments on commas which are not inside quotes
+func splitAnnotationArgs(text string) (args []string, err error) {
+	var quote rune
+	start := 0
+	for i, r := range text {
+		switch {
+		case quote != 0:
+			if r == quote && text[i-1] != '\\' {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == ',':
+			args = append(args, strings.TrimSpace(text[start:i]))
+			start = i + 1
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if last := strings.TrimSpace(text[start:]); last != "" || len(args) > 0 {
+		args = append(args, last)
+	}
+	return
+}
+
+func isQuoted(value string) bool {
+	return len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0]
+}
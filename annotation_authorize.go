@@ -0,0 +1,87 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+// The @Authorize annotation restricts an action to the authenticated users, optionally
+// with one of the roles
+//   // @Authorize(roles="admin,editor")
+// When placed on the controller it applies to every action, an annotation on the
+// action overrides it. The AuthorizeFilter enforces it using the AppAuthorizer, the
+// application fails to start when the filter does not run for an annotated action.
+func init() {
+	RegisterAnnotationProcessor("Authorize", authorizeAnnotationProcessor)
+	RegisterAnnotationSchema("Authorize", "roles")
+	// After the application has set its Filters
+	OnAppStart(func() {
+		if actions := actionsMissingFilter(AuthorizeFilter, func(mt *MethodType) bool { return mt.authorize != nil }); len(actions) > 0 {
+			controllerLog.Fatal("The AuthorizeFilter does not run for the actions annotated with @Authorize", "actions", actions)
+		}
+	}, 10)
+}
+
+// Authorizer is implemented by the application to check the user of the request
+type Authorizer interface {
+	// Authenticated returns true if the request has a logged in user
+	Authenticated(c *Controller) bool
+	// HasRole returns true if the logged in user has the role
+	HasRole(c *Controller, role string) bool
+}
+
+// AppAuthorizer is used by the AuthorizeFilter, it must be set when @Authorize is used.
+// Without an authorizer the annotated actions are forbidden.
+var AppAuthorizer Authorizer
+
+// The @Authorize settings of an action
+type authorizeSettings struct {
+	roles []string
+}
+
+func authorizeAnnotationProcessor(ct *ControllerType, mt *MethodType, annotation *FunctionalAnnotation) error {
+	settings := &authorizeSettings{roles: annotation.GetStrings("roles", 0)}
+
+	methods := ct.Methods
+	if mt != nil {
+		methods = []*MethodType{mt}
+	}
+	for _, method := range methods {
+		method.authorize = settings
+	}
+	return nil
+}
+
+// AuthorizeFilter enforces the @Authorize annotation of the action, a request without an
+// authenticated user receives a 401 and a user without any of the roles a 403
+func AuthorizeFilter(c *Controller, fc []Filter) {
+	if c.MethodType == nil || c.MethodType.authorize == nil {
+		fc[0](c, fc[1:])
+		return
+	}
+
+	if AppAuthorizer == nil {
+		controllerLog.Error("AuthorizeFilter: No AppAuthorizer set for @Authorize", "action", c.Action)
+		c.Result = c.Forbidden("Not authorized")
+		return
+	}
+	if !AppAuthorizer.Authenticated(c) {
+		c.Result = c.Unauthorized("Authentication required")
+		return
+	}
+	if roles := c.MethodType.authorize.roles; len(roles) > 0 {
+		allowed := false
+		for _, role := range roles {
+			if AppAuthorizer.HasRole(c, role) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			controllerLog.Warn("AuthorizeFilter: User does not have the role for the action", "action", c.Action, "roles", roles)
+			c.Result = c.Forbidden("Not authorized")
+			return
+		}
+	}
+
+	fc[0](c, fc[1:])
+}
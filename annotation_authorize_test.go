@@ -0,0 +1,96 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// Authorizes the roles listed in the X-Roles header
+type headerAuthorizer struct{}
+
+func (headerAuthorizer) Authenticated(c *Controller) bool {
+	return c.Request.GetHttpHeader("X-Roles") != ""
+}
+
+func (headerAuthorizer) HasRole(c *Controller, role string) bool {
+	for _, r := range strings.Split(c.Request.GetHttpHeader("X-Roles"), ",") {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+type AuthorizedController struct {
+	*Controller
+}
+
+func (c AuthorizedController) Index() Result {
+	return nil
+}
+
+func (c AuthorizedController) Edit() Result {
+	return nil
+}
+
+func TestAuthorizeFilter(t *testing.T) {
+	AppAuthorizer = headerAuthorizer{}
+	defer func() { AppAuthorizer = nil }()
+
+	loggedIn, _ := ParseAnnotation("@Authorize")
+	editor, _ := ParseAnnotation(`@Authorize(roles="admin,editor")`)
+	RegisterController((*AuthorizedController)(nil),
+		[]*MethodType{{Name: "Index"}, {Name: "Edit", Annotations: FunctionalAnnotations{editor}}},
+		loggedIn)
+
+	tests := []struct {
+		action, roles string
+		status        int
+	}{
+		{"Index", "", http.StatusUnauthorized},
+		{"Index", "user", 0},
+		{"Edit", "", http.StatusUnauthorized},
+		{"Edit", "user", http.StatusForbidden},
+		{"Edit", "user,editor", 0},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header.Set("X-Roles", test.roles)
+		c := NewTestController(nil, req)
+		if err := c.SetAction("AuthorizedController", test.action); err != nil {
+			t.Fatal(err)
+		}
+		called := false
+		AuthorizeFilter(c, []Filter{func(c *Controller, fc []Filter) { called = true }})
+		if c.Response.Status != test.status || called != (test.status == 0) {
+			t.Errorf("%s with roles %q: expected status %d got %d", test.action, test.roles, test.status, c.Response.Status)
+		}
+	}
+}
+
+func TestActionsMissingFilter(t *testing.T) {
+	loggedIn, _ := ParseAnnotation("@Authorize")
+	RegisterController((*AuthorizedController)(nil), []*MethodType{{Name: "Index"}, {Name: "Edit"}}, loggedIn)
+	authorized := func(mt *MethodType) bool { return mt.authorize != nil }
+	defer func(filters []Filter) {
+		Filters = filters
+		delete(filterOverrides, "AuthorizedController.Edit")
+	}(Filters)
+
+	if actions := actionsMissingFilter(AuthorizeFilter, authorized); len(actions) != 0 {
+		t.Errorf("Expected the default filters to authorize the actions, got %v", actions)
+	}
+	Filters = []Filter{RouterFilter, FilterConfiguringFilter, ParamsFilter, ActionInvoker}
+	if actions := actionsMissingFilter(AuthorizeFilter, authorized); strings.Join(actions, ",") != "AuthorizedController.Edit,AuthorizedController.Index" {
+		t.Errorf("Expected the actions without the filter, got %v", actions)
+	}
+	FilterAction(AuthorizedController.Edit).Insert(AuthorizeFilter, BEFORE, ParamsFilter)
+	if actions := actionsMissingFilter(AuthorizeFilter, authorized); strings.Join(actions, ",") != "AuthorizedController.Index" {
+		t.Errorf("Expected the filter configured for the action, got %v", actions)
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// The @BindFrom(id=route, token="header:X-Auth-Token") annotation restricts the source of
+// the arguments of an action, a query value cannot replace a route parameter
+//   // @BindFrom(id=route, page=query, token="header:X-Auth-Token", hint="cookie:session_hint")
+//   func (c Hotels) Show(id int, page int, token string, hint string) revel.Result
+// The sources are query, form, route, fixed, header and cookie. A header or cookie source
+// names the header or the cookie, the one of the name of the argument is used by default.
+// An argument restricted to a source is not bound from the request body. The header and
+// cookie arguments are documented as such by the OpenAPI document.
+// On a controller the annotation restricts the arguments of the name of all its actions.
+func init() {
+	RegisterAnnotationProcessor("BindFrom", bindFromAnnotationProcessor)
+}
+
+func bindFromAnnotationProcessor(ct *ControllerType, mt *MethodType, annotation *FunctionalAnnotation) error {
+	methods := ct.Methods
+	if mt != nil {
+		methods = []*MethodType{mt}
+	}
+	names := make([]string, 0, len(annotation.Data))
+	for name := range annotation.Data {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		source := annotation.Data[name]
+		if _, err := strconv.Atoi(name); err == nil {
+			return fmt.Errorf("@BindFrom names the arguments, as in @BindFrom(id=route), got %q", source)
+		}
+		if _, known := (&Params{}).sourced(source, name); !known {
+			return fmt.Errorf("@BindFrom %s must be query, form, route, fixed, header or cookie, got %q", name, source)
+		}
+		found := false
+		for _, method := range methods {
+			for _, arg := range method.Args {
+				if arg.Name == name {
+					arg.source, found = source, true
+				}
+			}
+		}
+		if !found && mt != nil {
+			return fmt.Errorf("@BindFrom %s is not an argument of the action", name)
+		}
+	}
+	return nil
+}
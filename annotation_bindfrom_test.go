@@ -0,0 +1,59 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+type requestMeta struct {
+	Trace string `header:"X-Request-Id"`
+	Theme string `cookie:"theme"`
+}
+
+func TestBindFromAnnotation(t *testing.T) {
+	annotation, _ := ParseAnnotation(`@BindFrom(id=route, token="header:X-Auth-Token", user=header, hint="cookie:session_hint")`)
+	mt := &MethodType{Args: []*MethodArg{
+		{Name: "id", Type: reflect.TypeOf(0)},
+		{Name: "token", Type: reflect.TypeOf("")},
+		{Name: "user", Type: reflect.TypeOf("")},
+		{Name: "page", Type: reflect.TypeOf(0)},
+		{Name: "hint", Type: reflect.TypeOf("")},
+		{Name: "meta", Type: reflect.TypeOf(requestMeta{})},
+	}}
+	if err := bindFromAnnotationProcessor(&ControllerType{}, mt, annotation); err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest("GET", "/hotels/12?id=99&token=query&page=2", nil)
+	req.Header.Set("X-Auth-Token", "secret")
+	req.Header.Set("User", "jane")
+	req.Header.Set("X-Request-Id", "r-42")
+	req.AddCookie(&http.Cookie{Name: "session_hint", Value: "mobile"})
+	req.AddCookie(&http.Cookie{Name: "theme", Value: "dark"})
+	c := NewTestController(nil, req)
+	c.Params.Route = url.Values{"id": {"12"}}
+	ParseParams(c.Params, c.Request)
+	// The query overrides the route parameter in the Values
+	c.Params.Values.Set("id", "99")
+
+	bound := []interface{}{}
+	for _, arg := range mt.Args {
+		bound = append(bound, arg.bind(c.Params).Interface())
+	}
+	if !reflect.DeepEqual(bound, []interface{}{12, "secret", "jane", 2, "mobile", requestMeta{Trace: "r-42", Theme: "dark"}}) {
+		t.Errorf("Expected the arguments bound from their sources, got %v", bound)
+	}
+
+	for _, invalid := range []string{`@BindFrom(id=session)`, `@BindFrom(route)`, `@BindFrom(missing=query)`} {
+		annotation, _ := ParseAnnotation(invalid)
+		if err := bindFromAnnotationProcessor(&ControllerType{}, mt, annotation); err == nil {
+			t.Errorf("Expected an error for %s", invalid)
+		}
+	}
+}
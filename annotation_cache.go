@@ -0,0 +1,270 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The @Cache(ttl=60s, key="user-:id", vary="Accept") annotation caches the rendered
+// result of a GET action in the ActionCache. The key is expanded using the parameters
+// (:id is replaced by the id parameter), without a key all the parameters are used.
+// The vary headers are used to store a variant of the result for each of their values.
+// Only the status, content type and body of a 200 response are cached, a response which
+// sets a cookie (a new or changed session, a flash message) is not cached.
+// The results are shared by all the users, the session option stores a variant for
+// each session so the pages which show the session (the user name, the cart) are not
+// served to the other users
+//   // @Cache(ttl=5m, session=true)
+// A session which has values but no ID yet (it was set by a login) receives an ID, its
+// response is not cached or served from the cache until the ID is in the session cookie.
+// The tags (expanded like the key) invalidate the results of several actions together
+//   // @Cache(ttl=10m, key="hotel-:id", tags="hotel-:id,hotels")
+//   revel.InvalidateActionCacheTags("hotel-" + id)
+// The tags need a cache which supports them (memory or redis).
+func init() {
+	RegisterAnnotationProcessor("Cache", cacheAnnotationProcessor)
+	RegisterAnnotationSchema("Cache", "ttl", "key", "vary", "tags", "session")
+}
+
+// ActionCacheStore is the store for the results cached by the @Cache annotation,
+// the cache module sets itself as the ActionCache when it is imported
+type ActionCacheStore interface {
+	Get(key string, ptrValue interface{}) error
+	Set(key string, value interface{}, expires time.Duration) error
+	Delete(key string) error
+}
+
+// TaggedActionCacheStore is an ActionCacheStore which removes its results by tag
+type TaggedActionCacheStore interface {
+	ActionCacheStore
+	SetWithTags(key string, value interface{}, expires time.Duration, tags ...string) error
+	InvalidateTags(tags ...string) error
+}
+
+// ActionCache stores the results of the actions annotated with @Cache, when nil the
+// results are not cached
+var ActionCache ActionCacheStore
+
+// The @Cache settings of an action
+type actionCacheSettings struct {
+	ttl     time.Duration
+	key     string
+	vary    []string
+	tags    []string
+	session bool // A variant is stored for each session
+}
+
+// A cached action result
+type actionCacheResponse struct {
+	ContentType string
+	Body        []byte
+}
+
+var actionCacheKeyParam = regexp.MustCompile(`:(\w+)`)
+
+func cacheAnnotationProcessor(ct *ControllerType, mt *MethodType, annotation *FunctionalAnnotation) (err error) {
+	settings := &actionCacheSettings{}
+	if settings.ttl, err = annotation.GetDuration("ttl", 0, 0); err != nil {
+		return err
+	}
+	settings.key, _ = annotation.Value("key", 1)
+	settings.vary = annotation.GetStrings("vary", 2)
+	settings.tags = annotation.GetStrings("tags", 3)
+	if settings.session, err = annotation.GetBool("session", 4, false); err != nil {
+		return err
+	}
+
+	methods := ct.Methods
+	if mt != nil {
+		methods = []*MethodType{mt}
+	}
+	for _, method := range methods {
+		method.cache = settings
+	}
+	return nil
+}
+
+// InvalidateActionCache removes the cached results of the action (in the form of Controller.Method)
+// for the parameters, all the variants of the result are removed
+func InvalidateActionCache(action string, params map[string]string) error {
+	if ActionCache == nil {
+		return nil
+	}
+	ct, mt, err := actionCacheMethod(action)
+	if err != nil {
+		return err
+	}
+	values := url.Values{}
+	for key, value := range params {
+		values.Set(key, value)
+	}
+	return ActionCache.Delete(actionCacheKey(ct, mt, values))
+}
+
+// InvalidateActionCacheTags removes the cached results of the actions which have any of the
+// tags (of their @Cache annotation)
+func InvalidateActionCacheTags(tags ...string) error {
+	if ActionCache == nil {
+		return nil
+	}
+	store, ok := ActionCache.(TaggedActionCacheStore)
+	if !ok {
+		return fmt.Errorf("The action cache has no tags")
+	}
+	return store.InvalidateTags(tags...)
+}
+
+// Returns the controller and method for the action, the method must be annotated with @Cache
+func actionCacheMethod(action string) (ct *ControllerType, mt *MethodType, err error) {
+	parts := strings.Split(action, ".")
+	if len(parts) != 2 {
+		return nil, nil, fmt.Errorf("Invalid action %s, expected Controller.Method", action)
+	}
+	if ct = ControllerTypeByName(parts[0], anyModule); ct == nil {
+		return nil, nil, fmt.Errorf("Controller not found for %s", action)
+	}
+	if mt = ct.Method(parts[1]); mt == nil || mt.cache == nil {
+		return nil, nil, fmt.Errorf("Action %s is not cached", action)
+	}
+	return
+}
+
+// Returns the key the results of the action are stored under, the controller name includes
+// the namespace so controllers with the same name in different modules do not share results
+func actionCacheKey(ct *ControllerType, mt *MethodType, params url.Values) string {
+	key := params.Encode()
+	if mt.cache.key != "" {
+		key = actionCacheKeyParam.ReplaceAllStringFunc(mt.cache.key, func(name string) string {
+			return params.Get(name[1:])
+		})
+	}
+	return "revel:action:" + ct.Name() + "." + mt.lowerName + ":" + key
+}
+
+// Returns the tags of the results of the action, expanded with the parameters
+func actionCacheTags(mt *MethodType, params url.Values) []string {
+	tags := make([]string, len(mt.cache.tags))
+	for i, tag := range mt.cache.tags {
+		tags[i] = actionCacheKeyParam.ReplaceAllStringFunc(tag, func(name string) string {
+			return params.Get(name[1:])
+		})
+	}
+	return tags
+}
+
+// Each variant of a result is stored under its own key, made of the key of the result, the
+// generation stored under the key of the result and the hash of the variant. Removing the
+// key of the result removes all its variants, the variants of a removed generation are no
+// longer read and expire.
+
+// Returns the cache key of the variant of the generation
+func actionCacheVariantKey(key, generation, variant string) string {
+	sum := sha256.Sum256([]byte(variant))
+	return key + ":" + generation + ":" + hex.EncodeToString(sum[:])
+}
+
+// Returns the response cached for the variant of the key, and the generation of the key
+// which is empty when no variant of the key is cached
+func getActionCacheVariant(key, variant string) (response actionCacheResponse, generation string, found bool) {
+	if err := ActionCache.Get(key, &generation); err != nil {
+		return response, "", false
+	}
+	found = ActionCache.Get(actionCacheVariantKey(key, generation, variant), &response) == nil
+	return
+}
+
+// Stores the response as the variant of the key, a new generation is stored for the key
+// when it has none. The variant is tagged with the tags when the ActionCache supports them.
+func setActionCacheVariant(key, generation, variant string, response actionCacheResponse, ttl time.Duration, tags []string) error {
+	if generation == "" {
+		generation = strconv.FormatInt(time.Now().UnixNano(), 36)
+		if err := ActionCache.Set(key, generation, ttl); err != nil {
+			return err
+		}
+	}
+	variantKey := actionCacheVariantKey(key, generation, variant)
+	if store, ok := ActionCache.(TaggedActionCacheStore); ok && len(tags) > 0 {
+		return store.SetWithTags(variantKey, response, ttl, tags...)
+	}
+	return ActionCache.Set(variantKey, response, ttl)
+}
+
+// Returns the result cached for the request, or nil and a result which stores the
+// action result when it is applied
+func (settings *actionCacheSettings) lookup(c *Controller) (cached Result, store func(Result) Result) {
+	if ActionCache == nil || c.Request.Method != "GET" {
+		return nil, nil
+	}
+	key := actionCacheKey(c.Type, c.MethodType, c.Params.Values)
+	variant := make([]string, len(settings.vary))
+	for i, header := range settings.vary {
+		variant[i] = c.Request.GetHttpHeader(header)
+	}
+	if settings.session {
+		id := c.Session[SessionIDKey]
+		if id == "" && len(c.Session) > 0 {
+			// The session would share the variant of the requests without a session, the
+			// ID is created for the next requests (the response sets the session cookie)
+			if !sessionReadOnly(c) {
+				c.Session.ID()
+			}
+			return nil, nil
+		}
+		variant = append(variant, id)
+	}
+	variantKey := strings.Join(variant, "\n")
+
+	response, generation, found := getActionCacheVariant(key, variantKey)
+	if found {
+		return &actionCacheResult{response: response}, nil
+	}
+	return nil, func(result Result) Result {
+		return &actionCacheResult{result: result, store: func(response actionCacheResponse) {
+			if err := setActionCacheVariant(key, generation, variantKey, response, settings.ttl, actionCacheTags(c.MethodType, c.Params.Values)); err != nil {
+				resultsLog.Warn("Failed to cache the action result", "action", c.Action, "key", key, "error", err)
+			}
+		}}
+	}
+}
+
+// actionCacheResult writes the cached response, or applies the action result
+// and stores the response when it is successful and does not set a cookie
+type actionCacheResult struct {
+	result   Result
+	response actionCacheResponse
+	store    func(actionCacheResponse)
+}
+
+func (r *actionCacheResult) Apply(req *Request, resp *Response) {
+	if r.result == nil {
+		resp.WriteHeader(http.StatusOK, r.response.ContentType)
+		if _, err := resp.GetWriter().Write(r.response.Body); err != nil {
+			resultsLog.Error("Apply: Response write failed", "error", err)
+		}
+		return
+	}
+
+	writer := resp.GetWriter()
+	body := &bytes.Buffer{}
+	resp.SetWriter(io.MultiWriter(writer, body))
+	r.result.Apply(req, resp)
+	resp.SetWriter(writer)
+	// The cookies are not cached, a cached response would not set them and may show
+	// the session they belong to
+	if resp.Status == http.StatusOK && len(resp.Out.Header().GetAll("Set-Cookie")) == 0 {
+		r.store(actionCacheResponse{ContentType: resp.ContentType, Body: body.Bytes()})
+	}
+}
@@ -0,0 +1,214 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// A map backed ActionCacheStore
+type testActionCache map[string]interface{}
+
+func (m testActionCache) Get(key string, ptrValue interface{}) error {
+	value, found := m[key]
+	if !found {
+		return fmt.Errorf("cache miss")
+	}
+	reflect.ValueOf(ptrValue).Elem().Set(reflect.ValueOf(value))
+	return nil
+}
+
+func (m testActionCache) Set(key string, value interface{}, expires time.Duration) error {
+	m[key] = value
+	return nil
+}
+
+func (m testActionCache) Delete(key string) error {
+	delete(m, key)
+	return nil
+}
+
+// A map backed TaggedActionCacheStore
+type testTaggedActionCache struct {
+	testActionCache
+	tags map[string][]string
+}
+
+func (m testTaggedActionCache) SetWithTags(key string, value interface{}, expires time.Duration, tags ...string) error {
+	for _, tag := range tags {
+		m.tags[tag] = append(m.tags[tag], key)
+	}
+	return m.Set(key, value, expires)
+}
+
+func (m testTaggedActionCache) InvalidateTags(tags ...string) error {
+	for _, tag := range tags {
+		for _, key := range m.tags[tag] {
+			_ = m.Delete(key)
+		}
+		delete(m.tags, tag)
+	}
+	return nil
+}
+
+type CachedController struct {
+	*Controller
+}
+
+var cachedControllerCalls int
+
+func (c CachedController) Show(id int) Result {
+	cachedControllerCalls++
+	return c.RenderText("hotel %d %s call %d", id, c.Request.GetHttpHeader("Accept"), cachedControllerCalls)
+}
+
+func invokeCachedAction(t *testing.T, accept string) string {
+	req, _ := http.NewRequest("GET", "/hotels/3", nil)
+	req.Header.Set("Accept", accept)
+	resp := httptest.NewRecorder()
+	c := NewTestController(resp, req)
+	if err := c.SetAction("CachedController", "Show"); err != nil {
+		t.Fatal(err)
+	}
+	c.Params = &Params{Values: url.Values{"id": {"3"}}}
+	ActionInvoker(c, nil)
+	c.Result.Apply(c.Request, c.Response)
+	return resp.Body.String()
+}
+
+func TestCacheAnnotation(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOption("results.chunked", "false")
+	store := testActionCache{}
+	ActionCache = store
+	defer func() { ActionCache = nil }()
+
+	annotation, _ := ParseAnnotation(`@Cache(ttl=60s, key="hotel-:id", vary="Accept")`)
+	RegisterController((*CachedController)(nil), []*MethodType{{
+		Name:        "Show",
+		Args:        []*MethodArg{{Name: "id", Type: reflect.TypeOf((*int)(nil))}},
+		Annotations: FunctionalAnnotations{annotation},
+	}})
+	if settings := ControllerTypeByName("CachedController", anyModule).Method("Show").cache; settings == nil || settings.ttl != time.Minute {
+		t.Fatalf("Expected the cache settings to be set, got %#v", settings)
+	}
+
+	first := invokeCachedAction(t, "text/html")
+	if first != "hotel 3 text/html call 1" || invokeCachedAction(t, "text/html") != first {
+		t.Errorf("Expected the cached result to be served, got %s", first)
+	}
+	if json := invokeCachedAction(t, "application/json"); json != "hotel 3 application/json call 2" {
+		t.Errorf("Expected a variant for each Accept header, got %s", json)
+	}
+	// The generation of the key and a key for each variant
+	if len(store) != 3 {
+		t.Errorf("Expected the variants to be stored under their own keys, got %v", store)
+	}
+
+	if err := InvalidateActionCache("CachedController.Show", map[string]string{"id": "3"}); err != nil {
+		t.Fatal(err)
+	}
+	if result := invokeCachedAction(t, "text/html"); result != "hotel 3 text/html call 3" {
+		t.Errorf("Expected the action to be called after the invalidation, got %s", result)
+	}
+}
+
+func TestCacheAnnotationTags(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOption("results.chunked", "false")
+	ActionCache = testTaggedActionCache{testActionCache{}, map[string][]string{}}
+	defer func() { ActionCache = nil }()
+
+	annotation, _ := ParseAnnotation(`@Cache(ttl=60s, tags="hotel-:id, hotels")`)
+	RegisterController((*CachedController)(nil), []*MethodType{{
+		Name:        "Show",
+		Args:        []*MethodArg{{Name: "id", Type: reflect.TypeOf((*int)(nil))}},
+		Annotations: FunctionalAnnotations{annotation},
+	}})
+	if tags := ControllerTypeByName("CachedController", anyModule).Method("Show").cache.tags; !reflect.DeepEqual(tags, []string{"hotel-:id", "hotels"}) {
+		t.Fatalf("Expected the tags to be set, got %#v", tags)
+	}
+
+	first := invokeCachedAction(t, "text/html")
+	if invokeCachedAction(t, "text/html") != first {
+		t.Errorf("Expected the cached result to be served, got %s", first)
+	}
+	if err := InvalidateActionCacheTags("hotel-4"); err != nil {
+		t.Fatal(err)
+	}
+	if result := invokeCachedAction(t, "text/html"); result != first {
+		t.Errorf("Expected the result of another tag to be kept, got %s", result)
+	}
+	if err := InvalidateActionCacheTags("hotel-3"); err != nil {
+		t.Fatal(err)
+	}
+	if result := invokeCachedAction(t, "text/html"); result == first {
+		t.Errorf("Expected the action to be called after the invalidation of its tag, got %s", result)
+	}
+}
+
+func (c CachedController) Profile() Result {
+	cachedControllerCalls++
+	if c.Params.Get("remember") != "" {
+		c.SetCookie(&http.Cookie{Name: "remember", Value: c.Session["user"]})
+	}
+	return c.RenderText("user %s call %d", c.Session["user"], cachedControllerCalls)
+}
+
+func TestCacheAnnotationSession(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOption("results.chunked", "false")
+	ActionCache = testActionCache{}
+	defer func() { ActionCache = nil }()
+
+	annotation, _ := ParseAnnotation(`@Cache(ttl=60s, session=true)`)
+	RegisterController((*CachedController)(nil), []*MethodType{{
+		Name:        "Profile",
+		Annotations: FunctionalAnnotations{annotation},
+	}})
+	cachedControllerCalls = 0
+	var session Session
+	invoke := func(id, user, remember string) string {
+		req, _ := http.NewRequest("GET", "/profile", nil)
+		resp := httptest.NewRecorder()
+		c := NewTestController(resp, req)
+		if err := c.SetAction("CachedController", "Profile"); err != nil {
+			t.Fatal(err)
+		}
+		c.Session = Session{"user": user}
+		if id != "" {
+			c.Session[SessionIDKey] = id
+		}
+		c.Params = &Params{Values: url.Values{"remember": {remember}}}
+		ActionInvoker(c, nil)
+		c.Result.Apply(c.Request, c.Response)
+		session = c.Session
+		return resp.Body.String()
+	}
+
+	if first := invoke("1", "alice", ""); first != "user alice call 1" || invoke("1", "alice", "") != first {
+		t.Errorf("Expected the result to be cached for the session, got %s", first)
+	}
+	if other := invoke("2", "bob", ""); other != "user bob call 2" {
+		t.Errorf("Expected a variant for each session, got %s", other)
+	}
+	if first, second := invoke("3", "carol", "true"), invoke("3", "carol", "true"); first == second {
+		t.Errorf("Expected a response which sets a cookie not to be cached, got %s", second)
+	}
+
+	// The cookie sessions set by a login have no ID, they do not share a variant
+	if dave, erin := invoke("", "dave", ""), invoke("", "erin", ""); dave == erin || erin != fmt.Sprintf("user erin call %d", cachedControllerCalls) {
+		t.Errorf("Expected the sessions without an ID not to share a variant, got %s and %s", dave, erin)
+	}
+	if session[SessionIDKey] == "" {
+		t.Error("Expected an ID to be created for the session without an ID")
+	}
+}
@@ -0,0 +1,44 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+// The @CSRFExempt and @CSRFRequired annotations decide if the SPACSRFFilter checks
+// the token for the state changing requests of an action, overriding `spa.csrf.default`
+// (required or exempt). A webhook receiver is exempted with
+//   // @CSRFExempt
+// When placed on the controller they apply to every action, an annotation on the
+// action overrides it.
+func init() {
+	RegisterAnnotationProcessor("CSRFExempt", csrfAnnotationProcessor(false))
+	RegisterAnnotationProcessor("CSRFRequired", csrfAnnotationProcessor(true))
+	RegisterAnnotationSchema("CSRFExempt")
+	RegisterAnnotationSchema("CSRFRequired")
+}
+
+func csrfAnnotationProcessor(required bool) AnnotationProcessor {
+	return func(ct *ControllerType, mt *MethodType, annotation *FunctionalAnnotation) error {
+		methods := ct.Methods
+		if mt != nil {
+			methods = []*MethodType{mt}
+		}
+		for _, method := range methods {
+			method.csrfRequired = &required
+		}
+		return nil
+	}
+}
+
+// Returns true if the token is required for the action the request is routed to, the
+// route is kept for the RouterFilter
+func spaCSRFRequired(c *Controller) bool {
+	if MainRouter != nil {
+		if route := routeOf(c.Request); route != nil && route.TypeOfController != nil {
+			if mt := route.TypeOfController.Method(route.MethodName); mt != nil && mt.csrfRequired != nil {
+				return *mt.csrfRequired
+			}
+		}
+	}
+	return !spaConfig.exempt
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestCSRFAnnotations(t *testing.T) {
+	startFakeBookingApp()
+	spaConfig.enabled = true
+	router := MainRouter
+	MainRouter = NewRouter("")
+	MainRouter.Routes, _ = parseRoutes(appModule, "", "", `
+POST /hotels/book Hotels.Book
+POST /hotels/show Hotels.Show
+`, false)
+	if err := MainRouter.updateTree(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		MainRouter = router
+		spaConfig.enabled, spaConfig.exempt = false, false
+	}()
+
+	hotels := ControllerTypeByName("Hotels", anyModule)
+	book := hotels.Method("Book")
+	defer func() { book.csrfRequired = nil }()
+
+	exempt, _ := ParseAnnotation("@CSRFExempt")
+	csrfAnnotationProcessor(false)(hotels, book, exempt)
+	if c, _, called := spaRequest("POST", "/hotels/book", "", "", nil); !called {
+		t.Errorf("Expected the @CSRFExempt action to be called without a token")
+	} else if !c.Request.routed || c.Request.route == nil || c.Request.route.MethodName != "Book" {
+		t.Errorf("Expected the route to be kept for the RouterFilter, got %#v", c.Request.route)
+	}
+	if c, _, called := spaRequest("POST", "/hotels/show", "", "", nil); called || c.Response.Status != http.StatusForbidden {
+		t.Errorf("Expected the action without an annotation to require the token")
+	}
+
+	spaConfig.exempt = true
+	if _, _, called := spaRequest("POST", "/hotels/show", "", "", nil); !called {
+		t.Errorf("Expected the action without an annotation to be exempt by default")
+	}
+	required, _ := ParseAnnotation("@CSRFRequired")
+	csrfAnnotationProcessor(true)(hotels, book, required)
+	if c, _, called := spaRequest("POST", "/hotels/book", "", "", nil); called || c.Response.Status != http.StatusForbidden {
+		t.Errorf("Expected the @CSRFRequired action to require the token")
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"sort"
+	"sync/atomic"
+)
+
+// The @Deprecated annotation marks an action which clients should stop calling
+//   // @Deprecated(since=1.2, use=Users.ShowV2)
+// Every call to the action is logged as a warning and counted, the counts are returned
+// by DeprecatedActions. The Deprecation header is added to the responses with
+//   deprecation.headers = true
+// On startup the deprecated actions which are still routed are reported in the log.
+func init() {
+	RegisterAnnotationProcessor("Deprecated", deprecatedAnnotationProcessor)
+	RegisterAnnotationSchema("Deprecated", "since", "use")
+	OnAppStart(func() {
+		deprecationHeaders = Config.BoolDefault("deprecation.headers", false)
+	})
+	OnAppStart(reportDeprecatedActions, 10)
+}
+
+// Set when the Deprecation header is added, loaded when the application starts
+var deprecationHeaders bool
+
+// DeprecatedAction describes an action annotated with @Deprecated
+type DeprecatedAction struct {
+	Action string   // The controller (with the namespace of a module) and method
+	Since  string   // The version the action was deprecated in
+	Use    string   // The action which replaces it
+	Routes []string // The routes to the action, filled by DeprecatedActions
+	calls  int64
+}
+
+// Calls returns the number of times the action was called since the application started
+func (d *DeprecatedAction) Calls() int64 {
+	return atomic.LoadInt64(&d.calls)
+}
+
+func deprecatedAnnotationProcessor(ct *ControllerType, mt *MethodType, annotation *FunctionalAnnotation) error {
+	since, _ := annotation.Value("since", 0)
+	use, _ := annotation.Value("use", 1)
+	controllerName := ct.Type.Name()
+	if ct.ModuleSource != appModule {
+		controllerName = ct.Namespace + controllerName
+	}
+	methods := ct.Methods
+	if mt != nil {
+		methods = []*MethodType{mt}
+	}
+	for _, method := range methods {
+		method.deprecated = &DeprecatedAction{
+			Action: controllerName + "." + method.Name,
+			Since:  since,
+			Use:    use,
+		}
+	}
+	return nil
+}
+
+// DeprecationFilter logs and counts the calls to the actions annotated with @Deprecated
+func DeprecationFilter(c *Controller, fc []Filter) {
+	if c.MethodType != nil && c.MethodType.deprecated != nil {
+		deprecated := c.MethodType.deprecated
+		atomic.AddInt64(&deprecated.calls, 1)
+		controllerLog.Warn("Deprecated action called", "action", deprecated.Action, "since", deprecated.Since, "use", deprecated.Use, "ip", c.ClientIP)
+		if deprecationHeaders {
+			c.Response.Out.Header().Set("Deprecation", "true")
+		}
+	}
+	fc[0](c, fc[1:])
+}
+
+// DeprecatedActions returns the actions annotated with @Deprecated sorted by name,
+// with the routes of the MainRouter which lead to them
+func DeprecatedActions() (actions []*DeprecatedAction) {
+	checked := map[*ControllerType]bool{}
+	for _, ct := range controllers {
+		if checked[ct] {
+			continue
+		}
+		checked[ct] = true
+		for _, mt := range ct.Methods {
+			if mt.deprecated != nil {
+				mt.deprecated.Routes = deprecatedActionRoutes(ct, mt)
+				actions = append(actions, mt.deprecated)
+			}
+		}
+	}
+	sort.Sort(deprecatedActionList(actions))
+	return
+}
+
+type deprecatedActionList []*DeprecatedAction
+
+func (l deprecatedActionList) Len() int           { return len(l) }
+func (l deprecatedActionList) Less(i, j int) bool { return l[i].Action < l[j].Action }
+func (l deprecatedActionList) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+
+// Returns the routes which lead to the action
+func deprecatedActionRoutes(ct *ControllerType, mt *MethodType) (routes []string) {
+	for _, route := range actionRoutes(ct, mt) {
+		routes = append(routes, route.Method+" "+route.Path)
+	}
+	return
+}
+
+// Logs the deprecated actions which can still be called
+func reportDeprecatedActions() {
+	for _, action := range DeprecatedActions() {
+		if len(action.Routes) > 0 {
+			controllerLog.Warn("Deprecated action is still routed", "action", action.Action, "since", action.Since, "use", action.Use, "routes", action.Routes)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type DeprecatedController struct {
+	*Controller
+}
+
+func (c DeprecatedController) Show() Result {
+	return nil
+}
+
+func (c DeprecatedController) ShowV2() Result {
+	return nil
+}
+
+func TestDeprecationFilter(t *testing.T) {
+	startFakeBookingApp()
+	annotation, _ := ParseAnnotation(`@Deprecated(since=1.2, use=DeprecatedController.ShowV2)`)
+	RegisterController((*DeprecatedController)(nil), []*MethodType{
+		{Name: "Show", Annotations: FunctionalAnnotations{annotation}},
+		{Name: "ShowV2"},
+	})
+	router := MainRouter
+	MainRouter = NewRouter("")
+	MainRouter.Routes, _ = parseRoutes(appModule, "", "", `
+GET /v1/show DeprecatedController.Show
+GET /v2/show DeprecatedController.ShowV2
+`, false)
+	if err := MainRouter.updateTree(); err != nil {
+		t.Fatal(err)
+	}
+	deprecationHeaders = true
+	defer func() {
+		MainRouter = router
+		deprecationHeaders = false
+	}()
+
+	for _, method := range []string{"Show", "ShowV2"} {
+		req, _ := http.NewRequest("GET", "/", nil)
+		resp := httptest.NewRecorder()
+		c := NewTestController(resp, req)
+		if err := c.SetAction("DeprecatedController", method); err != nil {
+			t.Fatal(err)
+		}
+		DeprecationFilter(c, NilChain)
+		if deprecated := resp.Header().Get("Deprecation") == "true"; deprecated != (method == "Show") {
+			t.Errorf("Unexpected Deprecation header for %s: %v", method, resp.Header())
+		}
+	}
+
+	var found *DeprecatedAction
+	for _, action := range DeprecatedActions() {
+		if strings.HasSuffix(action.Action, "DeprecatedController.Show") {
+			found = action
+		}
+	}
+	if found == nil {
+		t.Fatal("Expected DeprecatedController.Show to be reported")
+	}
+	if found.Since != "1.2" || found.Use != "DeprecatedController.ShowV2" || found.Calls() != 1 {
+		t.Errorf("Unexpected deprecated action %#v", found)
+	}
+	if !reflect.DeepEqual(found.Routes, []string{"GET /v1/show"}) {
+		t.Errorf("Expected the route to the action, got %v", found.Routes)
+	}
+}
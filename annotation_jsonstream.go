@@ -0,0 +1,22 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+// The @JSONStream annotation stops the ParamsFilter from reading the JSON request
+// body, the action decodes the elements using Params.BindJSONStream instead.
+// Since the body is not read the action arguments are not bound from the JSON.
+func init() {
+	RegisterAnnotationProcessor("JSONStream", func(ct *ControllerType, mt *MethodType, annotation *FunctionalAnnotation) error {
+		methods := ct.Methods
+		if mt != nil {
+			methods = []*MethodType{mt}
+		}
+		for _, method := range methods {
+			method.jsonStream = true
+		}
+		return nil
+	})
+	RegisterAnnotationSchema("JSONStream")
+}
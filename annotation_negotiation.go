@@ -0,0 +1,205 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// The @Produces and @Consumes annotations declare the content types of an action
+//   // @Produces("application/json")
+//   // @Consumes("application/json", "application/xml")
+// The NegotiationFilter returns a 415 when the Content-Type of a request is not one of
+// the consumed types (a POST, PUT or PATCH without a Content-Type is rejected as well),
+// and a 406 when the Accept header does not allow any of the produced types. Otherwise
+// the Request.Format is set to the produced type the client prefers, which is the
+// format RenderAuto renders.
+func init() {
+	RegisterAnnotationProcessor("Produces", negotiationAnnotationProcessor(true))
+	RegisterAnnotationProcessor("Consumes", negotiationAnnotationProcessor(false))
+	RegisterAnnotationSchema("Produces", "types...")
+	RegisterAnnotationSchema("Consumes", "types...")
+}
+
+// The formats of the Request.Format and their content type
+var formatContentTypes = map[string]string{
+	"html":    "text/html",
+	"json":    "application/json",
+	"xml":     "application/xml",
+	"txt":     "text/plain",
+	"proto":   ProtoContentType,
+	"msgpack": MsgPackContentType,
+}
+
+// The content types which map to a format besides the ones in formatContentTypes
+var contentTypeFormats = map[string]string{
+	"application/xhtml+xml":  "html",
+	"text/javascript":        "json",
+	"application/javascript": "json",
+	"text/xml":               "xml",
+	"application/x-msgpack":  "msgpack",
+}
+
+// The methods which send a body
+var negotiationBodyMethods = map[string]bool{"POST": true, "PUT": true, "PATCH": true}
+
+// An entry of the Accept header
+type acceptRange struct {
+	mediaType string
+	quality   float64
+}
+
+func negotiationAnnotationProcessor(produces bool) AnnotationProcessor {
+	return func(ct *ControllerType, mt *MethodType, annotation *FunctionalAnnotation) error {
+		var contentTypes []string
+		for position := 0; ; position++ {
+			value, found := annotation.Data[strconv.Itoa(position)]
+			if !found {
+				break
+			}
+			for _, contentType := range strings.Split(value, ",") {
+				if contentType = strings.ToLower(strings.TrimSpace(contentType)); contentType != "" {
+					contentTypes = append(contentTypes, contentType)
+				}
+			}
+		}
+		if len(contentTypes) == 0 {
+			return fmt.Errorf("@%s requires at least one content type", annotation.Name)
+		}
+
+		methods := ct.Methods
+		if mt != nil {
+			methods = []*MethodType{mt}
+		}
+		for _, method := range methods {
+			if produces {
+				method.produces = contentTypes
+			} else {
+				method.consumes = contentTypes
+			}
+		}
+		return nil
+	}
+}
+
+// NegotiationFilter enforces the @Consumes and @Produces annotations of the action
+func NegotiationFilter(c *Controller, fc []Filter) {
+	if c.MethodType == nil {
+		fc[0](c, fc[1:])
+		return
+	}
+
+	// A request without a Content-Type is checked if its method normally has a body
+	if consumes := c.MethodType.consumes; len(consumes) > 0 &&
+		(c.Request.GetHttpHeader("Content-Type") != "" || negotiationBodyMethods[c.Request.Method]) {
+		if !consumesContentType(consumes, c.Request.GetHttpHeader("Content-Type")) {
+			c.Response.Status = http.StatusUnsupportedMediaType
+			c.Result = c.RenderError(&Error{
+				Title:       "Unsupported Media Type",
+				Description: "The request body must be one of " + strings.Join(consumes, ", "),
+			})
+			return
+		}
+	}
+
+	if produces := c.MethodType.produces; len(produces) > 0 {
+		contentType := negotiateContentType(c.Request.GetHttpHeader("Accept"), produces)
+		if contentType == "" {
+			c.Response.Status = http.StatusNotAcceptable
+			c.Result = c.RenderError(&Error{
+				Title:       "Not Acceptable",
+				Description: "The response can only be one of " + strings.Join(produces, ", "),
+			})
+			return
+		}
+		if format := contentTypeFormat(contentType); format != "" {
+			c.Request.Format = format
+		}
+	}
+
+	fc[0](c, fc[1:])
+}
+
+// Returns the produced content type the Accept header prefers, an empty Accept header
+// accepts the first one. Returns an empty string if none is acceptable.
+func negotiateContentType(accept string, produces []string) string {
+	if strings.TrimSpace(accept) == "" {
+		return produces[0]
+	}
+	ranges := parseAccept(accept)
+	best, bestQuality := "", 0.0
+	for _, contentType := range produces {
+		quality, specificity := 0.0, -1
+		for _, r := range ranges {
+			// The most specific range which matches sets the quality
+			if s := acceptRangeMatch(r.mediaType, contentType); s > specificity {
+				quality, specificity = r.quality, s
+			}
+		}
+		if quality > bestQuality {
+			best, bestQuality = contentType, quality
+		}
+	}
+	return best
+}
+
+// Returns how specific the media range is if it matches the content type (2 for an
+// exact match, 1 for type/*, 0 for */*) or -1 if it does not match
+func acceptRangeMatch(mediaRange, contentType string) int {
+	switch {
+	case mediaRange == contentType:
+		return 2
+	case mediaRange == "*/*":
+		return 0
+	case strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(contentType, mediaRange[:len(mediaRange)-1]):
+		return 1
+	}
+	return -1
+}
+
+// Parses the Accept header into its media ranges with their quality
+func parseAccept(accept string) (ranges []acceptRange) {
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		r := acceptRange{mediaType: strings.ToLower(strings.TrimSpace(params[0])), quality: 1}
+		if r.mediaType == "" {
+			continue
+		}
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if quality, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					r.quality = quality
+				}
+			}
+		}
+		ranges = append(ranges, r)
+	}
+	return
+}
+
+// Returns the Request.Format of the content type, or an empty string if it has none
+func contentTypeFormat(contentType string) string {
+	for format, formatContentType := range formatContentTypes {
+		if formatContentType == contentType {
+			return format
+		}
+	}
+	return contentTypeFormats[contentType]
+}
+
+// Returns true if the content type (with its parameters) is one of the consumed types
+func consumesContentType(consumes []string, contentType string) bool {
+	contentType = strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	for _, consumed := range consumes {
+		if consumed == contentType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiateContentType(t *testing.T) {
+	produces := []string{"application/json", "application/xml"}
+	tests := map[string]string{
+		"":                                      "application/json",
+		"*/*":                                   "application/json",
+		"application/xml":                       "application/xml",
+		"application/*;q=0.5, application/xml":  "application/xml",
+		"application/json;q=0.2, */*;q=0.5":     "application/xml",
+		"text/html, application/xml;q=0.9":      "application/xml",
+		"text/html":                             "",
+		"application/json;q=0, application/xml": "application/xml",
+	}
+	for accept, expected := range tests {
+		if contentType := negotiateContentType(accept, produces); contentType != expected {
+			t.Errorf("Accept %q expected %q got %q", accept, expected, contentType)
+		}
+	}
+}
+
+type NegotiatedController struct {
+	*Controller
+}
+
+func (c NegotiatedController) Show() Result {
+	return nil
+}
+
+func (c NegotiatedController) Create() Result {
+	return nil
+}
+
+func TestNegotiationFilter(t *testing.T) {
+	startFakeBookingApp()
+	produces, _ := ParseAnnotation(`@Produces("application/json", "application/xml")`)
+	consumes, _ := ParseAnnotation(`@Consumes("application/json")`)
+	RegisterController((*NegotiatedController)(nil), []*MethodType{
+		{Name: "Show", Annotations: FunctionalAnnotations{produces}},
+		{Name: "Create", Annotations: FunctionalAnnotations{consumes}},
+	})
+
+	request := func(method, action string, header map[string]string) (*Controller, bool) {
+		req, _ := http.NewRequest(method, "/", nil)
+		for key, value := range header {
+			req.Header.Set(key, value)
+		}
+		c := NewTestController(httptest.NewRecorder(), req)
+		if err := c.SetAction("NegotiatedController", action); err != nil {
+			t.Fatal(err)
+		}
+		called := false
+		NegotiationFilter(c, []Filter{func(c *Controller, fc []Filter) { called = true }})
+		return c, called
+	}
+
+	c, called := request("GET", "Show", map[string]string{"Accept": "text/html, application/xml;q=0.9"})
+	if !called || c.Request.Format != "xml" {
+		t.Errorf("Expected the xml format to be selected, got %s", c.Request.Format)
+	}
+	if _, ok := c.RenderAuto(map[string]string{"id": "1"}).(RenderXMLResult); !ok {
+		t.Errorf("Expected RenderAuto to render XML")
+	}
+	if c, called = request("GET", "Show", map[string]string{"Accept": "text/html"}); called || c.Response.Status != http.StatusNotAcceptable {
+		t.Errorf("Expected a 406 for an unacceptable Accept header, got %d", c.Response.Status)
+	}
+
+	if _, called = request("POST", "Create", map[string]string{"Content-Type": "application/json; charset=utf-8"}); !called {
+		t.Errorf("Expected the JSON body to be accepted")
+	}
+	if c, called = request("POST", "Create", map[string]string{"Content-Type": "application/xml"}); called || c.Response.Status != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected a 415 for an XML body, got %d", c.Response.Status)
+	}
+	if c, called = request("POST", "Create", nil); called || c.Response.Status != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected a 415 for a body without a content type, got %d", c.Response.Status)
+	}
+	if _, called = request("GET", "Create", nil); !called {
+		t.Errorf("Expected a GET without a body to be allowed")
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"sort"
+	"strings"
+)
+
+// The annotations of the registered actions can be queried at runtime, for tooling or
+// for checks run on startup
+//   for _, action := range revel.ActionsWithoutAnnotation("Authorize") {
+//   	if len(action.Routes) > 0 {
+//   		revel.AppLog.Warn("Public action", "action", action.Name())
+//   	}
+//   }
+// The actions of the application which are routed and miss a required annotation stop
+// the application from starting with
+//   annotations.require = Authorize, RateLimit
+// An annotation on the controller counts for all its actions.
+func init() {
+	OnAppStart(checkRequiredAnnotations, 5)
+}
+
+// ActionRef is an action of a registered controller
+type ActionRef struct {
+	ControllerType *ControllerType
+	MethodType     *MethodType
+	Routes         []*Route // The routes of the MainRouter which lead to the action
+}
+
+// Name returns the controller (with the namespace of a module) and method of the action
+func (a *ActionRef) Name() string {
+	controllerName := a.ControllerType.Type.Name()
+	if a.ControllerType.ModuleSource != appModule {
+		controllerName = a.ControllerType.Namespace + controllerName
+	}
+	return controllerName + "." + a.MethodType.Name
+}
+
+// Annotation returns the named annotation (case insensitive) of the action, or of its
+// controller when the action does not have it, or nil
+func (a *ActionRef) Annotation(name string) *FunctionalAnnotation {
+	if annotation := a.MethodType.Annotations.Find(name); annotation != nil {
+		return annotation
+	}
+	return a.ControllerType.Annotations.Find(name)
+}
+
+type actionRefList []*ActionRef
+
+func (l actionRefList) Len() int           { return len(l) }
+func (l actionRefList) Less(i, j int) bool { return l[i].Name() < l[j].Name() }
+func (l actionRefList) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+
+// Actions returns the actions of the registered controllers sorted by name
+func Actions() []*ActionRef {
+	return filterActions(func(*ActionRef) bool { return true })
+}
+
+// ActionsWithAnnotation returns the actions which have the named annotation, or whose
+// controller has it, sorted by name
+func ActionsWithAnnotation(name string) []*ActionRef {
+	return filterActions(func(action *ActionRef) bool { return action.Annotation(name) != nil })
+}
+
+// ActionsWithoutAnnotation returns the actions which do not have the named annotation,
+// nor their controller, sorted by name
+func ActionsWithoutAnnotation(name string) []*ActionRef {
+	return filterActions(func(action *ActionRef) bool { return action.Annotation(name) == nil })
+}
+
+func filterActions(include func(*ActionRef) bool) (actions []*ActionRef) {
+	checked := map[*ControllerType]bool{}
+	for _, ct := range controllers {
+		if checked[ct] {
+			continue
+		}
+		checked[ct] = true
+		for _, mt := range ct.Methods {
+			action := &ActionRef{ControllerType: ct, MethodType: mt}
+			if include(action) {
+				action.Routes = actionRoutes(ct, mt)
+				actions = append(actions, action)
+			}
+		}
+	}
+	sort.Sort(actionRefList(actions))
+	return
+}
+
+// Returns the routes which lead to the action, a wildcard controller route leads to
+// the actions of all the controllers in its module
+func actionRoutes(ct *ControllerType, mt *MethodType) (routes []*Route) {
+	if MainRouter == nil {
+		return
+	}
+	for _, route := range MainRouter.Routes {
+		if route.ControllerName == "" || route.MethodName == "" {
+			continue
+		}
+		if route.ControllerName[0] == ':' {
+			if route.ModuleSource != ct.ModuleSource {
+				continue
+			}
+		} else if route.TypeOfController != ct {
+			continue
+		}
+		if route.MethodName[0] == ':' || ct.Method(route.MethodName) == mt {
+			routes = append(routes, route)
+		}
+	}
+	return
+}
+
+// Returns the routed actions of the application missing one of the annotations listed
+// in annotations.require
+func missingRequiredAnnotations() (missing map[string][]string) {
+	missing = map[string][]string{}
+	for _, name := range strings.Split(Config.StringDefault("annotations.require", ""), ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		for _, action := range ActionsWithoutAnnotation(name) {
+			if action.ControllerType.ModuleSource == appModule && len(action.Routes) > 0 {
+				missing[action.Name()] = append(missing[action.Name()], name)
+			}
+		}
+	}
+	return
+}
+
+func checkRequiredAnnotations() {
+	missing := missingRequiredAnnotations()
+	if len(missing) == 0 {
+		return
+	}
+	for action, names := range missing {
+		controllerLog.Error("Routed action is missing required annotations", "action", action, "annotations", names)
+	}
+	controllerLog.Fatal("Actions are missing the annotations listed in annotations.require, see the errors above")
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type QueriedController struct {
+	*Controller
+}
+
+func (c QueriedController) Index() Result {
+	return nil
+}
+
+func (c QueriedController) Delete() Result {
+	return nil
+}
+
+func (c QueriedController) Unrouted() Result {
+	return nil
+}
+
+func TestActionsWithAnnotation(t *testing.T) {
+	startFakeBookingApp()
+	authorize, _ := ParseAnnotation(`@Authorize(roles=admin)`)
+	deprecated, _ := ParseAnnotation(`@Deprecated`)
+	RegisterController((*QueriedController)(nil), []*MethodType{
+		{Name: "Index"},
+		{Name: "Delete", Annotations: FunctionalAnnotations{authorize}},
+		{Name: "Unrouted"},
+	}, deprecated)
+
+	router := MainRouter
+	MainRouter = NewRouter("")
+	MainRouter.Routes, _ = parseRoutes(appModule, "", "", `
+GET     /queried         QueriedController.Index
+DELETE  /queried         QueriedController.Delete
+`, false)
+	if err := MainRouter.updateTree(); err != nil {
+		t.Fatal(err)
+	}
+	Config.SetOption("annotations.require", "Authorize")
+	ct := ControllerTypeByName("QueriedController", anyModule)
+	module := ct.ModuleSource
+	ct.ModuleSource = appModule
+	defer func() {
+		MainRouter = router
+		Config.SetOption("annotations.require", "")
+		ct.ModuleSource = module
+	}()
+
+	names := func(actions []*ActionRef) (names []string) {
+		for _, action := range actions {
+			if strings.HasPrefix(action.Name(), "QueriedController.") {
+				names = append(names, action.MethodType.Name)
+			}
+		}
+		return
+	}
+	if found := names(ActionsWithAnnotation("authorize")); !reflect.DeepEqual(found, []string{"Delete"}) {
+		t.Errorf("Expected the @Authorize action, got %v", found)
+	}
+	if found := names(ActionsWithAnnotation("Deprecated")); len(found) != 3 {
+		t.Errorf("Expected the controller annotation to count for all the actions, got %v", found)
+	}
+	without := ActionsWithoutAnnotation("Authorize")
+	if found := names(without); !reflect.DeepEqual(found, []string{"Index", "Unrouted"}) {
+		t.Errorf("Expected the actions without @Authorize, got %v", found)
+	}
+	for _, action := range without {
+		if action.Name() == "QueriedController.Index" && (len(action.Routes) != 1 || action.Routes[0].Path != "/queried") {
+			t.Errorf("Expected the route of the action, got %v", action.Routes)
+		}
+	}
+
+	if missing := missingRequiredAnnotations(); !reflect.DeepEqual(missing, map[string][]string{"QueriedController.Index": {"Authorize"}}) {
+		t.Errorf("Expected only the routed action without @Authorize to be reported, got %v", missing)
+	}
+}
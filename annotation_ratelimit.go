@@ -0,0 +1,228 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The @RateLimit annotation limits the number of requests to an action
+//   // @RateLimit(rate="100/m", key="ip|user")
+// The rate is the number of requests per second (s), minute (m), hour (h) or day (d),
+// or per duration (10/30s). The key lists the values the requests are counted by,
+// ip is the client ip and user the RateLimitUser of the request. The limit is enforced
+// by the RateLimitFilter using a token bucket from the AppRateLimiter, the responses
+// have the RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers and a
+// limited request receives a 429 with Retry-After. The application fails to start when
+// the filter does not run for an annotated action.
+func init() {
+	RegisterAnnotationProcessor("RateLimit", rateLimitAnnotationProcessor)
+	RegisterAnnotationSchema("RateLimit", "rate", "key")
+	// After the application has set its Filters
+	OnAppStart(func() {
+		if actions := actionsMissingFilter(RateLimitFilter, func(mt *MethodType) bool { return mt.rateLimit != nil }); len(actions) > 0 {
+			controllerLog.Fatal("The RateLimitFilter does not run for the actions annotated with @RateLimit", "actions", actions)
+		}
+	}, 10)
+}
+
+// RateLimiter takes a token from the bucket of the key, the bucket holds limit tokens
+// and is refilled at limit tokens per period
+type RateLimiter interface {
+	Take(key string, limit int, period time.Duration) (allowed bool, remaining int, reset time.Duration)
+}
+
+// AppRateLimiter holds the buckets for the RateLimitFilter, the buckets are kept in
+// memory unless the cache module is imported, which stores them in the configured cache
+var AppRateLimiter RateLimiter = NewMemoryRateLimiter()
+
+// RateLimitUser returns the user the requests are counted by for key=user,
+// by default this is the session id. The requests without a user (a client which
+// has no session yet) are counted by the client ip.
+var RateLimitUser = func(c *Controller) string {
+	if c.Session == nil {
+		return ""
+	}
+	return c.Session[SessionIDKey]
+}
+
+// RateLimitBucket is the state of a token bucket, exported so it can be stored in a cache
+type RateLimitBucket struct {
+	Tokens  float64
+	Updated int64         // Unix nanoseconds
+	Period  time.Duration // The period of the limit, the bucket is full once it elapsed
+}
+
+// Take refills the bucket for the time elapsed since it was last updated and takes a token
+func (b *RateLimitBucket) Take(limit int, period time.Duration, now time.Time) (allowed bool, remaining int, reset time.Duration) {
+	rate := float64(limit) / period.Seconds() // tokens per second
+	if b.Updated == 0 {
+		b.Tokens = float64(limit)
+	} else if elapsed := now.Sub(time.Unix(0, b.Updated)).Seconds(); elapsed > 0 {
+		b.Tokens = math.Min(float64(limit), b.Tokens+elapsed*rate)
+	}
+	b.Updated, b.Period = now.UnixNano(), period
+	if b.Tokens >= 1 {
+		b.Tokens--
+		allowed = true
+		reset = time.Duration((float64(limit) - b.Tokens) / rate * float64(time.Second))
+	} else {
+		// The time until the next token
+		reset = time.Duration((1 - b.Tokens) / rate * float64(time.Second))
+	}
+	return allowed, int(b.Tokens), reset
+}
+
+// MemoryRateLimiter keeps the buckets in memory, the full buckets are removed once per
+// memoryRateLimiterSweep
+type MemoryRateLimiter struct {
+	mutex   sync.Mutex
+	buckets map[string]*RateLimitBucket
+	swept   time.Time
+}
+
+// The interval between the removals of the full buckets, the buckets are not swept on
+// every new key since the clients would choose the cost of their requests
+const memoryRateLimiterSweep = time.Minute
+
+// NewMemoryRateLimiter returns a rate limiter which keeps the buckets in memory
+func NewMemoryRateLimiter() *MemoryRateLimiter {
+	return &MemoryRateLimiter{buckets: map[string]*RateLimitBucket{}}
+}
+
+func (m *MemoryRateLimiter) Take(key string, limit int, period time.Duration) (allowed bool, remaining int, reset time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	now := time.Now()
+	bucket, found := m.buckets[key]
+	if !found {
+		// Remove the full buckets, so the map does not grow with every client, each
+		// bucket is refilled over the period of its own limit
+		if now.Sub(m.swept) > memoryRateLimiterSweep {
+			for k, b := range m.buckets {
+				if now.Sub(time.Unix(0, b.Updated)) > b.Period {
+					delete(m.buckets, k)
+				}
+			}
+			m.swept = now
+		}
+		bucket = &RateLimitBucket{}
+		m.buckets[key] = bucket
+	}
+	return bucket.Take(limit, period, now)
+}
+
+// The @RateLimit settings of an action
+type rateLimitSettings struct {
+	limit  int
+	period time.Duration
+	keys   []string
+}
+
+func rateLimitAnnotationProcessor(ct *ControllerType, mt *MethodType, annotation *FunctionalAnnotation) (err error) {
+	settings := &rateLimitSettings{}
+	rate, found := annotation.Value("rate", 0)
+	if !found {
+		return fmt.Errorf("@RateLimit requires a rate")
+	}
+	if settings.limit, settings.period, err = parseRateLimit(rate); err != nil {
+		return err
+	}
+	key, found := annotation.Value("key", 1)
+	if !found {
+		key = "ip"
+	}
+	for _, k := range strings.Split(key, "|") {
+		switch k = strings.TrimSpace(k); k {
+		case "ip", "user":
+			settings.keys = append(settings.keys, k)
+		default:
+			return fmt.Errorf("@RateLimit unknown key %s, expected ip or user", k)
+		}
+	}
+
+	methods := ct.Methods
+	if mt != nil {
+		methods = []*MethodType{mt}
+	}
+	for _, method := range methods {
+		method.rateLimit = settings
+	}
+	return nil
+}
+
+// Parses a rate in the form of 100/m or 10/30s
+func parseRateLimit(rate string) (limit int, period time.Duration, err error) {
+	parts := strings.Split(rate, "/")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("@RateLimit invalid rate %s, expected requests/period", rate)
+	}
+	if limit, err = strconv.Atoi(strings.TrimSpace(parts[0])); err != nil || limit <= 0 {
+		return 0, 0, fmt.Errorf("@RateLimit invalid number of requests in %s", rate)
+	}
+	switch unit := strings.TrimSpace(parts[1]); unit {
+	case "s", "sec", "second":
+		period = time.Second
+	case "m", "min", "minute":
+		period = time.Minute
+	case "h", "hour":
+		period = time.Hour
+	case "d", "day":
+		period = 24 * time.Hour
+	default:
+		if period, err = ParseDuration(unit, time.Second); err != nil || period <= 0 {
+			return 0, 0, fmt.Errorf("@RateLimit invalid period in %s", rate)
+		}
+	}
+	return
+}
+
+// RateLimitFilter enforces the @RateLimit annotation of the action
+func RateLimitFilter(c *Controller, fc []Filter) {
+	if c.MethodType == nil || c.MethodType.rateLimit == nil || AppRateLimiter == nil {
+		fc[0](c, fc[1:])
+		return
+	}
+
+	settings := c.MethodType.rateLimit
+	key := "revel:ratelimit:" + c.Type.Name() + "." + c.MethodType.lowerName
+	for _, k := range settings.keys {
+		switch k {
+		case "ip":
+			key += ":" + c.ClientIP
+		case "user":
+			if user := RateLimitUser(c); user != "" {
+				key += ":" + user
+			} else {
+				key += ":" + c.ClientIP
+			}
+		}
+	}
+
+	allowed, remaining, reset := AppRateLimiter.Take(key, settings.limit, settings.period)
+	seconds := strconv.Itoa(int(math.Ceil(reset.Seconds())))
+	header := c.Response.Out.Header()
+	header.Set("RateLimit-Limit", strconv.Itoa(settings.limit))
+	header.Set("RateLimit-Remaining", strconv.Itoa(remaining))
+	header.Set("RateLimit-Reset", seconds)
+	if !allowed {
+		controllerLog.Warn("RateLimitFilter: Rate limit exceeded", "action", c.Action, "key", key)
+		header.Set("Retry-After", seconds)
+		c.Response.Status = http.StatusTooManyRequests
+		c.Result = c.RenderError(&Error{
+			Title:       "Too Many Requests",
+			Description: "Rate limit exceeded, retry after " + seconds + " seconds",
+		})
+		return
+	}
+
+	fc[0](c, fc[1:])
+}
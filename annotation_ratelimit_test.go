@@ -0,0 +1,132 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	tests := map[string]struct {
+		limit  int
+		period time.Duration
+	}{
+		"100/m":    {100, time.Minute},
+		"10/s":     {10, time.Second},
+		"1000/day": {1000, 24 * time.Hour},
+		"5/30s":    {5, 30 * time.Second},
+	}
+	for rate, expected := range tests {
+		limit, period, err := parseRateLimit(rate)
+		if err != nil || limit != expected.limit || period != expected.period {
+			t.Errorf("Parsing %s expected %d/%s got %d/%s (%v)", rate, expected.limit, expected.period, limit, period, err)
+		}
+	}
+	for _, rate := range []string{"100", "0/m", "x/m", "10/fortnight"} {
+		if _, _, err := parseRateLimit(rate); err == nil {
+			t.Errorf("Expected an error parsing %s", rate)
+		}
+	}
+}
+
+func TestRateLimitBucket(t *testing.T) {
+	bucket := RateLimitBucket{}
+	now := time.Now()
+	for i := 0; i < 2; i++ {
+		if allowed, _, _ := bucket.Take(2, time.Minute, now); !allowed {
+			t.Fatalf("Expected request %d to be allowed", i)
+		}
+	}
+	if allowed, _, reset := bucket.Take(2, time.Minute, now); allowed || reset != 30*time.Second {
+		t.Errorf("Expected the empty bucket to deny the request for 30s, got %v %s", allowed, reset)
+	}
+	if allowed, _, _ := bucket.Take(2, time.Minute, now.Add(30*time.Second)); !allowed {
+		t.Errorf("Expected the bucket to be refilled")
+	}
+}
+
+func TestMemoryRateLimiterCleanup(t *testing.T) {
+	limiter := NewMemoryRateLimiter()
+	updated := time.Now().Add(-2 * time.Second).UnixNano()
+	limiter.buckets["daily"] = &RateLimitBucket{Updated: updated, Period: 24 * time.Hour}
+	limiter.buckets["second"] = &RateLimitBucket{Updated: updated, Period: time.Second}
+	limiter.Take("new", 10, time.Second)
+	if _, found := limiter.buckets["daily"]; !found {
+		t.Errorf("Expected the bucket of a daily limit to be kept until its day elapsed")
+	}
+	if _, found := limiter.buckets["second"]; found {
+		t.Errorf("Expected the full bucket to be removed")
+	}
+
+	// The buckets are swept once per interval, not on every new key
+	limiter.buckets["second"] = &RateLimitBucket{Updated: updated, Period: time.Second}
+	limiter.Take("other", 10, time.Second)
+	if _, found := limiter.buckets["second"]; !found {
+		t.Errorf("Expected the buckets not to be swept again before the interval")
+	}
+	limiter.swept = limiter.swept.Add(-memoryRateLimiterSweep)
+	limiter.Take("another", 10, time.Second)
+	if _, found := limiter.buckets["second"]; found {
+		t.Errorf("Expected the buckets to be swept after the interval")
+	}
+}
+
+type RateLimitedController struct {
+	*Controller
+}
+
+func (c RateLimitedController) Index() Result {
+	return nil
+}
+
+func TestRateLimitFilter(t *testing.T) {
+	startFakeBookingApp()
+	AppRateLimiter = NewMemoryRateLimiter()
+	annotation, _ := ParseAnnotation(`@RateLimit(rate="2/m", key="ip")`)
+	RegisterController((*RateLimitedController)(nil), []*MethodType{{Name: "Index", Annotations: FunctionalAnnotations{annotation}}})
+
+	for i, status := range []int{0, 0, http.StatusTooManyRequests} {
+		req, _ := http.NewRequest("GET", "/", nil)
+		resp := httptest.NewRecorder()
+		c := NewTestController(resp, req)
+		if err := c.SetAction("RateLimitedController", "Index"); err != nil {
+			t.Fatal(err)
+		}
+		RateLimitFilter(c, NilChain)
+		if c.Response.Status != status || resp.Header().Get("RateLimit-Limit") != "2" {
+			t.Errorf("Request %d expected status %d got %d %v", i, status, c.Response.Status, resp.Header())
+		}
+		if status != 0 && resp.Header().Get("Retry-After") != "30" {
+			t.Errorf("Expected Retry-After 30, got %s", resp.Header().Get("Retry-After"))
+		}
+	}
+}
+
+func TestRateLimitFilterAnonymousUser(t *testing.T) {
+	startFakeBookingApp()
+	AppRateLimiter = NewMemoryRateLimiter()
+	annotation, _ := ParseAnnotation(`@RateLimit(rate="1/m", key="user")`)
+	RegisterController((*RateLimitedController)(nil), []*MethodType{{Name: "Index", Annotations: FunctionalAnnotations{annotation}}})
+
+	// The clients without a session are counted by their ip, not in a shared bucket
+	for i, request := range []struct {
+		ip     string
+		status int
+	}{{"10.0.0.1", 0}, {"10.0.0.1", http.StatusTooManyRequests}, {"10.0.0.2", 0}} {
+		req, _ := http.NewRequest("GET", "/", nil)
+		c := NewTestController(httptest.NewRecorder(), req)
+		if err := c.SetAction("RateLimitedController", "Index"); err != nil {
+			t.Fatal(err)
+		}
+		c.ClientIP, c.Session = request.ip, Session{}
+		RateLimitFilter(c, NilChain)
+		if c.Response.Status != request.status {
+			t.Errorf("Request %d from %s expected status %d got %d", i, request.ip, request.status, c.Response.Status)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/klauspost/compress/gzip"
+)
+
+// The response annotations shape the result of an action, the ActionInvoker wraps the
+// result to set the headers before it is applied
+//   // @Gzip                               Compress the response when the client accepts gzip
+//   // @NoStore                            Cache-Control: no-store
+//   // @CacheControl("public, max-age=300")
+//   // @Header("X-Frame-Options", "DENY")
+// On a controller they apply to all its actions, an action annotation replaces the
+// header set by the controller.
+func init() {
+	RegisterAnnotationProcessor("Gzip", func(ct *ControllerType, mt *MethodType, annotation *FunctionalAnnotation) error {
+		for _, settings := range responseSettingsOf(ct, mt) {
+			settings.gzip = true
+		}
+		return nil
+	})
+	RegisterAnnotationProcessor("NoStore", func(ct *ControllerType, mt *MethodType, annotation *FunctionalAnnotation) error {
+		for _, settings := range responseSettingsOf(ct, mt) {
+			settings.headers["Cache-Control"] = "no-store"
+		}
+		return nil
+	})
+	RegisterAnnotationProcessor("CacheControl", func(ct *ControllerType, mt *MethodType, annotation *FunctionalAnnotation) error {
+		value, _ := annotation.Value("value", 0)
+		if value == "" {
+			return fmt.Errorf("@CacheControl requires a value")
+		}
+		for _, settings := range responseSettingsOf(ct, mt) {
+			settings.headers["Cache-Control"] = value
+		}
+		return nil
+	})
+	RegisterAnnotationProcessor("Header", func(ct *ControllerType, mt *MethodType, annotation *FunctionalAnnotation) error {
+		name, _ := annotation.Value("name", 0)
+		value, _ := annotation.Value("value", 1)
+		if name == "" {
+			return fmt.Errorf("@Header requires a name")
+		}
+		for _, settings := range responseSettingsOf(ct, mt) {
+			settings.headers[http.CanonicalHeaderKey(name)] = value
+		}
+		return nil
+	})
+	RegisterAnnotationSchema("Gzip")
+	RegisterAnnotationSchema("NoStore")
+	RegisterAnnotationSchema("CacheControl", "value")
+	RegisterAnnotationSchema("Header", "name", "value")
+}
+
+// The response settings of an action
+type responseSettings struct {
+	gzip    bool
+	headers map[string]string
+}
+
+// Returns the response settings of the method, or of all the methods of the controller
+// when the method is nil
+func responseSettingsOf(ct *ControllerType, mt *MethodType) (settings []*responseSettings) {
+	methods := ct.Methods
+	if mt != nil {
+		methods = []*MethodType{mt}
+	}
+	for _, method := range methods {
+		if method.response == nil {
+			method.response = &responseSettings{headers: map[string]string{}}
+		}
+		settings = append(settings, method.response)
+	}
+	return
+}
+
+// Wraps the result so the settings are applied with it
+func (settings *responseSettings) wrap(result Result) Result {
+	if result == nil {
+		return nil
+	}
+	return &responseSettingsResult{result, settings}
+}
+
+// responseSettingsResult sets the headers of the settings and compresses the response
+// of the result
+type responseSettingsResult struct {
+	result   Result
+	settings *responseSettings
+}
+
+func (r *responseSettingsResult) Apply(req *Request, resp *Response) {
+	for name, value := range r.settings.headers {
+		resp.Out.internalHeader.Set(name, value)
+	}
+	if !r.settings.gzip || resp.Out.internalHeader.Server == nil || !acceptsGzip(req) {
+		r.result.Apply(req, resp)
+		return
+	}
+	// The CompressFilter may already compress the response
+	writer := resp.GetWriter()
+	if _, compressed := writer.(*CompressResponseWriter); compressed {
+		r.result.Apply(req, resp)
+		return
+	}
+
+	header := resp.Out.internalHeader.Server
+	addVary(resp.Out.internalHeader, "Accept-Encoding")
+	compressWriter := newCompressResponseWriter(resp, "gzip", gzip.NewWriter(writer))
+	resp.SetWriter(compressWriter)
+	r.result.Apply(req, resp)
+	if err := compressWriter.Close(); err != nil {
+		resultsLog.Error("Apply: Failed to close the gzip writer", "error", err)
+	}
+	resp.Out.internalHeader.Server = header
+	resp.SetWriter(writer)
+}
+
+// Returns true if the Accept-Encoding header of the request allows gzip
+func acceptsGzip(req *Request) bool {
+	accepted := false
+	for _, encoding := range parseAccept(req.GetHttpHeader("Accept-Encoding")) {
+		switch encoding.mediaType {
+		case "gzip":
+			return encoding.quality > 0
+		case "*":
+			accepted = encoding.quality > 0
+		}
+	}
+	return accepted
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/gzip"
+)
+
+type ShapedController struct {
+	*Controller
+}
+
+func (c ShapedController) Show() Result {
+	return c.RenderText("%s", strings.Repeat("hotel ", 100))
+}
+
+func (c ShapedController) Prices() Result {
+	return c.RenderText("prices")
+}
+
+func TestResponseAnnotations(t *testing.T) {
+	startFakeBookingApp()
+	noStore, _ := ParseAnnotation(`@NoStore`)
+	header, _ := ParseAnnotation(`@Header("x-frame-options", "DENY")`)
+	gzipped, _ := ParseAnnotation(`@Gzip`)
+	cacheControl, _ := ParseAnnotation(`@CacheControl("public, max-age=300")`)
+	RegisterController((*ShapedController)(nil), []*MethodType{
+		{Name: "Show", Annotations: FunctionalAnnotations{gzipped}},
+		{Name: "Prices", Annotations: FunctionalAnnotations{cacheControl}},
+	}, noStore, header)
+
+	invoke := func(action, acceptEncoding string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+		resp := httptest.NewRecorder()
+		c := NewTestController(resp, req)
+		if err := c.SetAction("ShapedController", action); err != nil {
+			t.Fatal(err)
+		}
+		ActionInvoker(c, nil)
+		c.Result.Apply(c.Request, c.Response)
+		return resp
+	}
+
+	resp := invoke("Show", "gzip, deflate")
+	if resp.Header().Get("Cache-Control") != "no-store" || resp.Header().Get("X-Frame-Options") != "DENY" ||
+		resp.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Unexpected headers %v", resp.Header())
+	}
+	reader, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := ioutil.ReadAll(reader); !strings.HasPrefix(string(body), "hotel hotel") {
+		t.Errorf("Unexpected body %q", body)
+	}
+
+	if resp = invoke("Show", "gzip;q=0, *"); resp.Header().Get("Content-Encoding") != "" || !strings.HasPrefix(resp.Body.String(), "hotel") {
+		t.Errorf("Expected an uncompressed response, got %v", resp.Header())
+	}
+	if resp = invoke("Prices", "gzip"); resp.Header().Get("Cache-Control") != "public, max-age=300" ||
+		resp.Header().Get("Content-Encoding") != "" || resp.Body.String() != "prices" {
+		t.Errorf("Expected the action to replace the Cache-Control header, got %v %q", resp.Header(), resp.Body.String())
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// The @Scenario annotation selects the scenarios of the validate tags checked for the
+// arguments of an action (see Validation.Scenario)
+//   // @Scenario(update)
+//   func (c Users) Update(id int, user models.User) revel.Result
+// On a controller the annotation selects the scenarios of all its actions.
+func init() {
+	RegisterAnnotationProcessor("Scenario", scenarioAnnotationProcessor)
+	RegisterAnnotationSchema("Scenario", "scenarios...")
+}
+
+func scenarioAnnotationProcessor(ct *ControllerType, mt *MethodType, annotation *FunctionalAnnotation) error {
+	var scenarios []string
+	for position := 0; ; position++ {
+		if _, found := annotation.Data[strconv.Itoa(position)]; !found {
+			break
+		}
+		scenarios = append(scenarios, annotation.GetStrings("", position)...)
+	}
+	if len(scenarios) == 0 {
+		return fmt.Errorf("@Scenario requires at least one scenario")
+	}
+	methods := ct.Methods
+	if mt != nil {
+		methods = []*MethodType{mt}
+	}
+	for _, method := range methods {
+		method.scenarios = scenarios
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+// The @SessionReadOnly annotation marks an action which never writes the session, it does
+// not wait for the lock of the session (session.concurrency = lock) and does not store the
+// session, the changes of the session are discarded.
+//   // @SessionReadOnly
+//   func (c Hotels) Search(query string) revel.Result
+// On a controller the annotation marks all its actions.
+func init() {
+	RegisterAnnotationProcessor("SessionReadOnly", func(ct *ControllerType, mt *MethodType, annotation *FunctionalAnnotation) error {
+		methods := ct.Methods
+		if mt != nil {
+			methods = []*MethodType{mt}
+		}
+		for _, method := range methods {
+			method.sessionReadOnly = true
+		}
+		return nil
+	})
+	RegisterAnnotationSchema("SessionReadOnly")
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseAnnotation(t *testing.T) {
+	tests := []struct {
+		text string
+		name string
+		data map[string]string
+	}{
+		{"@Deprecated", "Deprecated", map[string]string{}},
+		{"@ViewArg(section, Users)", "ViewArg", map[string]string{"0": "section", "1": "Users"}},
+		{`@ViewArg(banner, "Sale, today only")`, "ViewArg", map[string]string{"0": "banner", "1": "Sale, today only"}},
+		{`@Cache(ttl=60s, key='user=:id')`, "Cache", map[string]string{"ttl": "60s", "key": "user=:id"}},
+	}
+	for _, test := range tests {
+		annotation, err := ParseAnnotation(test.text)
+		if err != nil {
+			t.Errorf("Unexpected error parsing %s: %s", test.text, err)
+			continue
+		}
+		if annotation.Name != test.name || !reflect.DeepEqual(annotation.Data, test.data) {
+			t.Errorf("Parsing %s, expected %s %v got %s %v", test.text, test.name, test.data, annotation.Name, annotation.Data)
+		}
+	}
+
+	for _, text := range []string{"ViewArg(a,b)", "@ViewArg(a,b", `@ViewArg(a,"b)`, "@(a)"} {
+		if _, err := ParseAnnotation(text); err == nil {
+			t.Errorf("Expected error parsing %s", text)
+		}
+	}
+}
+
+func TestParseAnnotations(t *testing.T) {
+	annotations, err := ParseAnnotations(`// Show displays the hotel
+	// @ViewArg(section, hotels)
+	// @Deprecated`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(annotations) != 2 || annotations[0].Name != "ViewArg" || annotations[1].Name != "Deprecated" {
+		t.Errorf("Unexpected annotations %v", annotations)
+	}
+}
+
+type ViewArgController struct {
+	*Controller
+}
+
+func (c ViewArgController) Index() Result {
+	return nil
+}
+
+func (c ViewArgController) Show() Result {
+	return nil
+}
+
+func TestViewArgAnnotation(t *testing.T) {
+	section, _ := ParseAnnotation("@ViewArg(section, hotels)")
+	layout, _ := ParseAnnotation("@ViewArg(layout, wide)")
+	override, _ := ParseAnnotation("@ViewArg(section, hotel)")
+	RegisterController((*ViewArgController)(nil),
+		[]*MethodType{
+			{Name: "Index"},
+			{Name: "Show", Annotations: FunctionalAnnotations{override}},
+		},
+		section, layout)
+
+	c := NewTestController(nil, showRequest)
+	if err := c.SetAction("ViewArgController", "Index"); err != nil {
+		t.Fatal(err)
+	}
+	if c.ViewArgs["section"] != "hotels" || c.ViewArgs["layout"] != "wide" {
+		t.Errorf("Expected controller view args, got %v", c.ViewArgs)
+	}
+
+	c = NewTestController(nil, showRequest)
+	if err := c.SetAction("ViewArgController", "Show"); err != nil {
+		t.Fatal(err)
+	}
+	if c.ViewArgs["section"] != "hotel" || c.ViewArgs["layout"] != "wide" {
+		t.Errorf("Expected action view arg to override controller, got %v", c.ViewArgs)
+	}
+}
+
+type StrictAnnotationController struct {
+	*Controller
+}
+
+func (c StrictAnnotationController) Index() Result {
+	return nil
+}
+
+func (c StrictAnnotationController) Show() Result {
+	return nil
+}
+
+func TestAnnotationErrors(t *testing.T) {
+	valid, _ := ParseAnnotation(`@Cache(60s, key="hotel-:id")`)
+	unknown, _ := ParseAnnotation("@Cahce(60s)")
+	extra, _ := ParseAnnotation("@ViewArg(section, hotels, extra)")
+	misnamed, _ := ParseAnnotation("@RateLimit(rate=10/m, keys=ip)")
+	malformed, _ := ParseAnnotation("@RateLimit(rate=10/fortnight)")
+	RegisterController((*StrictAnnotationController)(nil),
+		[]*MethodType{
+			{Name: "Index", Annotations: FunctionalAnnotations{valid, unknown, extra}},
+			{Name: "Show", Annotations: FunctionalAnnotations{misnamed, malformed}},
+		})
+
+	found := map[string]bool{}
+	for _, err := range AnnotationErrors() {
+		if strings.HasSuffix(err.Controller, "strictannotationcontroller") {
+			found[err.Action+" @"+err.Annotation] = true
+		}
+	}
+	expected := map[string]bool{
+		"Index @Cahce":    true,
+		"Index @ViewArg":  true,
+		"Show @RateLimit": true,
+	}
+	if !reflect.DeepEqual(found, expected) {
+		t.Errorf("Expected annotation errors %v, got %v", expected, found)
+	}
+	if !strings.Contains(AnnotationErrors()[0].Error(), "@") {
+		t.Errorf("Expected the error to name the annotation, got %s", AnnotationErrors()[0])
+	}
+
+	// Registering the controller again replaces its errors
+	RegisterController((*StrictAnnotationController)(nil), []*MethodType{{Name: "Index", Annotations: FunctionalAnnotations{valid}}, {Name: "Show"}})
+	for _, err := range AnnotationErrors() {
+		if strings.HasSuffix(err.Controller, "strictannotationcontroller") {
+			t.Errorf("Expected the errors to be replaced, got %s", err)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The typed accessors return the value stored under the key or at the position (like
+// Value), the default when it is not found, and an error naming the annotation when the
+// value cannot be converted.
+
+// GetInt returns the value as an int
+func (a *FunctionalAnnotation) GetInt(key string, position int, defaultValue int) (int, error) {
+	value, found := a.Value(key, position)
+	if !found {
+		return defaultValue, nil
+	}
+	i, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return defaultValue, fmt.Errorf("@%s %s must be a number, got %q", a.Name, key, value)
+	}
+	return i, nil
+}
+
+// GetBool returns the value as a bool, a key without a value (@Name(key=)) is true
+func (a *FunctionalAnnotation) GetBool(key string, position int, defaultValue bool) (bool, error) {
+	value, found := a.Value(key, position)
+	if !found {
+		return defaultValue, nil
+	}
+	if value = strings.TrimSpace(value); value == "" {
+		return true, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return defaultValue, fmt.Errorf("@%s %s must be true or false, got %q", a.Name, key, value)
+	}
+	return b, nil
+}
+
+// GetDuration returns the value as a duration (see ParseDuration), a number is in seconds
+func (a *FunctionalAnnotation) GetDuration(key string, position int, defaultValue time.Duration) (time.Duration, error) {
+	value, found := a.Value(key, position)
+	if !found {
+		return defaultValue, nil
+	}
+	duration, err := ParseDuration(value, time.Second)
+	if err != nil {
+		err.(*LiteralError).Key = "@" + a.Name + " " + key
+		return defaultValue, err
+	}
+	return duration, nil
+}
+
+// GetSize returns the value as a size in bytes (see ParseSize), a number is in bytes
+func (a *FunctionalAnnotation) GetSize(key string, position int, defaultValue int64) (int64, error) {
+	value, found := a.Value(key, position)
+	if !found {
+		return defaultValue, nil
+	}
+	size, err := ParseSize(value, 1)
+	if err != nil {
+		err.(*LiteralError).Key = "@" + a.Name + " " + key
+		return defaultValue, err
+	}
+	return size, nil
+}
+
+// GetStrings returns the comma separated values, trimmed and without the empty ones
+func (a *FunctionalAnnotation) GetStrings(key string, position int) (values []string) {
+	value, _ := a.Value(key, position)
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return
+}
+
+// Unmarshal stores the values of the annotation in the fields of the struct pointed to.
+// A field is filled from the key of its lower case name, or of its annotation tag,
+// which may give the position as well
+//   type cacheOptions struct {
+//   	TTL  time.Duration `annotation:"ttl,0"`
+//   	Key  string        `annotation:"key,1"`
+//   	Vary []string      `annotation:"vary,2"`
+//   }
+// The fields may be strings, bools, numbers, durations or string slices (comma separated).
+func (a *FunctionalAnnotation) Unmarshal(into interface{}) error {
+	target := reflect.ValueOf(into)
+	if target.Kind() != reflect.Ptr || target.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("@%s can only be unmarshalled into a struct pointer, got %T", a.Name, into)
+	}
+	target = target.Elem()
+	for i := 0; i < target.NumField(); i++ {
+		field := target.Type().Field(i)
+		if field.PkgPath != "" || field.Tag.Get("annotation") == "-" {
+			continue
+		}
+		key, position := strings.ToLower(field.Name), -1
+		if tag := field.Tag.Get("annotation"); tag != "" {
+			parts := strings.SplitN(tag, ",", 2)
+			key = parts[0]
+			if len(parts) == 2 {
+				var err error
+				if position, err = strconv.Atoi(parts[1]); err != nil {
+					return fmt.Errorf("Invalid annotation tag %q on field %s", tag, field.Name)
+				}
+			}
+		}
+		if _, found := a.Value(key, position); !found {
+			continue
+		}
+		if err := a.unmarshalField(target.Field(i), key, position); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *FunctionalAnnotation) unmarshalField(field reflect.Value, key string, position int) error {
+	value, _ := a.Value(key, position)
+	if field.Type() == reflect.TypeOf(time.Duration(0)) {
+		duration, err := a.GetDuration(key, position, 0)
+		field.SetInt(int64(duration))
+		return err
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := a.GetBool(key, position, false)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(strings.TrimSpace(value), 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("@%s %s must be a number, got %q", a.Name, key, value)
+		}
+		field.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(strings.TrimSpace(value), 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("@%s %s must be a positive number, got %q", a.Name, key, value)
+		}
+		field.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("@%s %s must be a number, got %q", a.Name, key, value)
+		}
+		field.SetFloat(f)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("@%s %s cannot be stored in a %s", a.Name, key, field.Type())
+		}
+		field.Set(reflect.ValueOf(a.GetStrings(key, position)).Convert(field.Type()))
+	default:
+		return fmt.Errorf("@%s %s cannot be stored in a %s", a.Name, key, field.Type())
+	}
+	return nil
+}
+
+// Find returns the first annotation with the name (case insensitive), or nil
+func (annotations FunctionalAnnotations) Find(name string) *FunctionalAnnotation {
+	name = strings.TrimPrefix(name, "@")
+	for _, annotation := range annotations {
+		if strings.EqualFold(annotation.Name, name) {
+			return annotation
+		}
+	}
+	return nil
+}
+
+// Has returns true if an annotation with the name (case insensitive) is in the list
+func (annotations FunctionalAnnotations) Has(name string) bool {
+	return annotations.Find(name) != nil
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAnnotationAccessors(t *testing.T) {
+	annotation, _ := ParseAnnotation(`@Sample(5, ttl=1m30s, enabled=true, tags="a, b,,c", bad=x)`)
+
+	if i, err := annotation.GetInt("count", 0, 1); i != 5 || err != nil {
+		t.Errorf("GetInt expected 5 got %d %v", i, err)
+	}
+	if i, err := annotation.GetInt("missing", 9, 1); i != 1 || err != nil {
+		t.Errorf("GetInt expected the default got %d %v", i, err)
+	}
+	if _, err := annotation.GetInt("bad", 9, 1); err == nil {
+		t.Errorf("GetInt expected an error for an invalid number")
+	}
+	if d, err := annotation.GetDuration("ttl", 9, 0); d != 90*time.Second || err != nil {
+		t.Errorf("GetDuration expected 1m30s got %s %v", d, err)
+	}
+	if b, err := annotation.GetBool("enabled", 9, false); !b || err != nil {
+		t.Errorf("GetBool expected true got %v %v", b, err)
+	}
+	if _, err := annotation.GetBool("bad", 9, false); err == nil {
+		t.Errorf("GetBool expected an error for an invalid bool")
+	}
+	if tags := annotation.GetStrings("tags", 9); !reflect.DeepEqual(tags, []string{"a", "b", "c"}) {
+		t.Errorf("GetStrings expected [a b c] got %v", tags)
+	}
+}
+
+func TestAnnotationUnmarshal(t *testing.T) {
+	var options struct {
+		Count   int           `annotation:"count,0"`
+		TTL     time.Duration `annotation:"ttl"`
+		Enabled bool
+		Tags    []string
+		Ratio   float64
+		Name    string
+		ignored string
+	}
+	annotation, _ := ParseAnnotation(`@Sample(5, ttl=60, enabled=true, tags="a,b", ratio=0.5)`)
+	if err := annotation.Unmarshal(&options); err != nil {
+		t.Fatal(err)
+	}
+	if options.Count != 5 || options.TTL != time.Minute || !options.Enabled || options.Ratio != 0.5 ||
+		!reflect.DeepEqual(options.Tags, []string{"a", "b"}) || options.Name != "" {
+		t.Errorf("Unexpected options %+v", options)
+	}
+
+	annotation, _ = ParseAnnotation(`@Sample(ratio=half)`)
+	if err := annotation.Unmarshal(&options); err == nil {
+		t.Errorf("Expected an error for an invalid number")
+	}
+	if err := annotation.Unmarshal(options); err == nil {
+		t.Errorf("Expected an error for a struct which is not a pointer")
+	}
+}
+
+func TestAnnotationsFind(t *testing.T) {
+	annotations, _ := ParseAnnotations("// @Cache(60)\n// @authorize(roles=admin)")
+	if found := annotations.Find("@Authorize"); found == nil || found.Data["roles"] != "admin" {
+		t.Errorf("Expected to find @Authorize, got %v", found)
+	}
+	if !annotations.Has("cache") || annotations.Has("RateLimit") {
+		t.Errorf("Unexpected Has results")
+	}
+}
@@ -0,0 +1,40 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"fmt"
+)
+
+// The @ViewArg(key, value) annotation adds a static value to the ViewArgs
+// before the action is called. When placed on the controller the value is
+// added for every action, an annotation on the action overrides it.
+func init() {
+	RegisterAnnotationProcessor("ViewArg", viewArgAnnotationProcessor)
+	RegisterAnnotationSchema("ViewArg", "key", "value")
+}
+
+func viewArgAnnotationProcessor(ct *ControllerType, mt *MethodType, annotation *FunctionalAnnotation) error {
+	key, found := annotation.Value("key", 0)
+	if !found || key == "" {
+		return fmt.Errorf("@ViewArg requires a key")
+	}
+	value, found := annotation.Value("value", 1)
+	if !found {
+		return fmt.Errorf("@ViewArg(%s) requires a value", key)
+	}
+
+	methods := ct.Methods
+	if mt != nil {
+		methods = []*MethodType{mt}
+	}
+	for _, method := range methods {
+		if method.viewArgs == nil {
+			method.viewArgs = map[string]interface{}{}
+		}
+		method.viewArgs[key] = value
+	}
+	return nil
+}
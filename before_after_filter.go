@@ -2,6 +2,7 @@ package revel
 
 import (
 	"reflect"
+	"runtime/debug"
 )
 
 // Autocalls any defined before and after methods on the target controller
@@ -14,7 +15,8 @@ func BeforeAfterFilter(c *Controller, fc []Filter) {
 	}()
 	defer func() {
 		if err := recover(); err != nil {
-			if resultValue := beforeAfterFilterInvoke(PANIC, c); resultValue != nil && !resultValue.IsNil() {
+			// Panic methods may accept the recovered value and the stack
+			if resultValue := beforeAfterFilterInvoke(PANIC, c, reflect.ValueOf(&err).Elem(), reflect.ValueOf(debug.Stack())); resultValue != nil && !resultValue.IsNil() {
 				c.Result = resultValue.Interface().(Result)
 			}
 			panic(err)
@@ -24,12 +26,14 @@ func BeforeAfterFilter(c *Controller, fc []Filter) {
 		c.Result = resultValue.Interface().(Result)
 	}
 	fc[0](c, fc[1:])
-	if resultValue := beforeAfterFilterInvoke(AFTER, c); resultValue != nil && !resultValue.IsNil() {
+	// After methods may accept the result of the action
+	if resultValue := beforeAfterFilterInvoke(AFTER, c, reflect.ValueOf(&c.Result).Elem()); resultValue != nil && !resultValue.IsNil() {
 		c.Result = resultValue.Interface().(Result)
 	}
 }
 
-func beforeAfterFilterInvoke(method When, c *Controller) (r *reflect.Value) {
+// The args are passed to the event methods which accept them
+func beforeAfterFilterInvoke(method When, c *Controller, args ...reflect.Value) (r *reflect.Value) {
 
 	if c.Type == nil {
 		return
@@ -50,7 +54,11 @@ func beforeAfterFilterInvoke(method When, c *Controller) (r *reflect.Value) {
 		return
 	}
 	for _, function := range index {
-		result := function.Invoke(reflect.ValueOf(c.AppController), nil)[0]
+		var input []reflect.Value
+		if function.ArgCount > 0 {
+			input = args[:function.ArgCount]
+		}
+		result := function.Invoke(reflect.ValueOf(c.AppController), input)[0]
 		if !result.IsNil() {
 			return &result
 		}
@@ -0,0 +1,69 @@
+package revel
+
+import (
+	"testing"
+)
+
+type EventController struct {
+	*Controller
+	afterResult Result
+	recovered   interface{}
+	stack       []byte
+}
+
+func (c *EventController) After(result Result) (Result, *EventController) {
+	c.afterResult = result
+	return c.RenderText("after"), c
+}
+
+func (c *EventController) Panic(recovered interface{}, stack []byte) (Result, *EventController) {
+	c.recovered, c.stack = recovered, stack
+	return nil, c
+}
+
+// Invalid signature, it is never called
+func (c *EventController) Before(name string) (Result, *EventController) {
+	panic("Before should not be called")
+}
+
+func (c EventController) Index() Result {
+	return c.RenderText("index")
+}
+
+func (c EventController) Fail() Result {
+	panic("failed")
+}
+
+func TestBeforeAfterFilterEventArgs(t *testing.T) {
+	RegisterController((*EventController)(nil), []*MethodType{{Name: "Index"}, {Name: "Fail"}})
+
+	c := NewTestController(nil, showRequest)
+	if err := c.SetAction("EventController", "Index"); err != nil {
+		t.Fatal(err)
+	}
+	BeforeAfterFilter(c, []Filter{ActionInvoker})
+	controller := c.AppController.(*EventController)
+	if text, ok := controller.afterResult.(*RenderTextResult); !ok || text.text != "index" {
+		t.Errorf("Expected After to receive the action result, got %#v", controller.afterResult)
+	}
+	if text, ok := c.Result.(*RenderTextResult); !ok || text.text != "after" {
+		t.Errorf("Expected After to replace the result, got %#v", c.Result)
+	}
+
+	c = NewTestController(nil, showRequest)
+	if err := c.SetAction("EventController", "Fail"); err != nil {
+		t.Fatal(err)
+	}
+	func() {
+		defer func() {
+			if err := recover(); err != "failed" {
+				t.Errorf("Expected the panic to continue, got %v", err)
+			}
+		}()
+		BeforeAfterFilter(c, []Filter{ActionInvoker})
+	}()
+	controller = c.AppController.(*EventController)
+	if controller.recovered != "failed" || len(controller.stack) == 0 {
+		t.Errorf("Expected Panic to receive the recovered value and stack, got %v", controller.recovered)
+	}
+}
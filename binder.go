@@ -5,13 +5,14 @@
 package revel
 
 import (
-	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"mime/multipart"
+	"net/url"
 	"os"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -77,19 +78,30 @@ var (
 	DateFormat     string
 	DateTimeFormat string
 
+	// The format of slice parameters in reverse routes, set by `params.slice.format`
+	//   indexed  - ids[0]=1&ids[1]=2 (default)
+	//   brackets - ids[]=1&ids[]=2
+	//   repeat   - ids=1&ids=2, repeated values are only bound to a slice in this format
+	SliceParamFormat = "indexed"
+
+	// The elements missing between the indexes of a bound slice, set by `params.slice.gaps`
+	//   zero    - items[0]=a&items[2]=c binds {a, "", c} (default)
+	//   compact - items[0]=a&items[2]=c binds {a, c}, the elements keep the order of their indexes
+	SliceGaps = "zero"
+
+	// The largest index of a bound slice element, set by `params.slice.maxindex`. The
+	// elements of larger indexes are bind errors, a request cannot allocate a huge slice.
+	SliceMaxIndex = 10000
+
 	IntBinder = Binder{
-		Bind: ValueBinder(func(val string, typ reflect.Type) reflect.Value {
-			if len(val) == 0 {
-				return reflect.Zero(typ)
-			}
-			intValue, err := strconv.ParseInt(val, 10, 64)
+		Bind: parsedValueBinder("an integer", func(val string, typ reflect.Type) (reflect.Value, error) {
+			intValue, err := strconv.ParseInt(val, 10, typ.Bits())
 			if err != nil {
-				binderLog.Warn("IntBinder Conversion Error", "error", err)
-				return reflect.Zero(typ)
+				return reflect.Value{}, err
 			}
 			pValue := reflect.New(typ)
 			pValue.Elem().SetInt(intValue)
-			return pValue.Elem()
+			return pValue.Elem(), nil
 		}),
 		Unbind: func(output map[string]string, key string, val interface{}) {
 			output[key] = fmt.Sprintf("%d", val)
@@ -97,18 +109,14 @@ var (
 	}
 
 	UintBinder = Binder{
-		Bind: ValueBinder(func(val string, typ reflect.Type) reflect.Value {
-			if len(val) == 0 {
-				return reflect.Zero(typ)
-			}
-			uintValue, err := strconv.ParseUint(val, 10, 64)
+		Bind: parsedValueBinder("a non-negative integer", func(val string, typ reflect.Type) (reflect.Value, error) {
+			uintValue, err := strconv.ParseUint(val, 10, typ.Bits())
 			if err != nil {
-				binderLog.Warn("UintBinder Conversion Error", "error", err)
-				return reflect.Zero(typ)
+				return reflect.Value{}, err
 			}
 			pValue := reflect.New(typ)
 			pValue.Elem().SetUint(uintValue)
-			return pValue.Elem()
+			return pValue.Elem(), nil
 		}),
 		Unbind: func(output map[string]string, key string, val interface{}) {
 			output[key] = fmt.Sprintf("%d", val)
@@ -116,18 +124,14 @@ var (
 	}
 
 	FloatBinder = Binder{
-		Bind: ValueBinder(func(val string, typ reflect.Type) reflect.Value {
-			if len(val) == 0 {
-				return reflect.Zero(typ)
-			}
-			floatValue, err := strconv.ParseFloat(val, 64)
+		Bind: parsedValueBinder("a number", func(val string, typ reflect.Type) (reflect.Value, error) {
+			floatValue, err := strconv.ParseFloat(val, typ.Bits())
 			if err != nil {
-				binderLog.Warn("FloatBinder Conversion Error", "error", err)
-				return reflect.Zero(typ)
+				return reflect.Value{}, err
 			}
 			pValue := reflect.New(typ)
 			pValue.Elem().SetFloat(floatValue)
-			return pValue.Elem()
+			return pValue.Elem(), nil
 		}),
 		Unbind: func(output map[string]string, key string, val interface{}) {
 			output[key] = fmt.Sprintf("%f", val)
@@ -135,9 +139,14 @@ var (
 	}
 
 	StringBinder = Binder{
-		Bind: ValueBinder(func(val string, typ reflect.Type) reflect.Value {
-			return reflect.ValueOf(val)
-		}),
+		// The value is sanitized (see Sanitizer)
+		Bind: func(params *Params, name string, typ reflect.Type) reflect.Value {
+			vals, ok := params.Values[name]
+			if !ok || len(vals) == 0 {
+				return reflect.Zero(typ)
+			}
+			return reflect.ValueOf(params.sanitize(vals[0], typ)).Convert(typ)
+		},
 		Unbind: func(output map[string]string, name string, val interface{}) {
 			output[name] = val.(string)
 		},
@@ -169,14 +178,9 @@ var (
 	}
 
 	TimeBinder = Binder{
-		Bind: ValueBinder(func(val string, typ reflect.Type) reflect.Value {
-			for _, f := range TimeFormats {
-				if r, err := time.Parse(f, val); err == nil {
-					return reflect.ValueOf(r)
-				}
-			}
-			return reflect.Zero(typ)
-		}),
+		Bind: func(params *Params, name string, typ reflect.Type) reflect.Value {
+			return bindTime(params, name, typ, nil, "")
+		},
 		Unbind: func(output map[string]string, name string, val interface{}) {
 			var (
 				t       = val.(time.Time)
@@ -206,28 +210,43 @@ type sliceValue struct {
 // elements, and then sets them to their appropriate location in the slice.
 // If elements are provided without an explicit index, they are added (in
 // unspecified order) to the end of the slice.
+// An element with sub keys (items[0].Name, items[0].Qty) is bound once from all its keys.
+// The gaps between the indexes are zero elements, or are removed (see SliceGaps). An index
+// which is not a number, or is larger than SliceMaxIndex, is a bind error.
 func bindSlice(params *Params, name string, typ reflect.Type) reflect.Value {
 	// Collect an array of slice elements with their indexes (and the max index).
 	maxIndex := -1
 	numNoIndex := 0
 	sliceValues := []sliceValue{}
+	boundIndexes := map[int]bool{}
 
 	// Factor out the common slice logic (between form values and files).
 	processElement := func(key string, vals []string, files []*multipart.FileHeader) {
-		if !strings.HasPrefix(key, name+"[") {
+		// Repeated values (e.g. element=1&element=2) are bound as un-indexed elements
+		if !strings.HasPrefix(key, name+"[") && (key != name || SliceParamFormat != "repeat") {
 			return
 		}
 
 		// Extract the index, and the index where a sub-key starts. (e.g. field[0].subkey)
 		index := -1
 		leftBracket, rightBracket := len(name), strings.Index(key[len(name):], "]")+len(name)
+		subKeyIndex := rightBracket + 1
 		if rightBracket > leftBracket+1 {
-			index, _ = strconv.Atoi(key[leftBracket+1 : rightBracket])
+			var err error
+			if index, err = strconv.Atoi(key[leftBracket+1 : rightBracket]); err != nil || index < 0 || index > SliceMaxIndex {
+				binderLog.Warn("bindSlice: Invalid slice index", "key", key, "maxindex", SliceMaxIndex)
+				params.bindErrors = append(params.bindErrors, &bindError{key[:subKeyIndex], fmt.Sprintf("Must have an index from 0 to %d", SliceMaxIndex)})
+				return
+			}
 		}
-		subKeyIndex := rightBracket + 1
 
 		// Handle the indexed case.
 		if index > -1 {
+			if boundIndexes[index] {
+				// Bound from another sub key of the element
+				return
+			}
+			boundIndexes[index] = true
 			if index > maxIndex {
 				maxIndex = index
 			}
@@ -263,6 +282,19 @@ func bindSlice(params *Params, name string, typ reflect.Type) reflect.Value {
 		processElement(key, nil, fileHeaders)
 	}
 
+	if SliceGaps == "compact" {
+		// The indexed elements are placed one after the other
+		sort.SliceStable(sliceValues, func(i, j int) bool {
+			return sliceValues[j].index == -1 && sliceValues[i].index != -1 ||
+				sliceValues[i].index != -1 && sliceValues[i].index < sliceValues[j].index
+		})
+		resultArray := reflect.MakeSlice(typ, 0, len(sliceValues))
+		for _, sv := range sliceValues {
+			resultArray = reflect.Append(resultArray, sv.value)
+		}
+		return resultArray
+	}
+
 	resultArray := reflect.MakeSlice(typ, maxIndex+1, maxIndex+1+numNoIndex)
 	for _, sv := range sliceValues {
 		if sv.index != -1 {
@@ -295,14 +327,14 @@ func unbindSlice(output map[string]string, name string, val interface{}) {
 func bindStruct(params *Params, name string, typ reflect.Type) reflect.Value {
 	resultPointer := reflect.New(typ)
 	result := resultPointer.Elem()
-	if params.JSON != nil {
-		// Try to inject the response as a json into the created result
-		if err := json.Unmarshal(params.JSON, resultPointer.Interface()); err != nil {
-			binderLog.Error("bindStruct Unable to unmarshal request", "name", name, "error", err,"data", string(params.JSON))
-		}
+	// Try to inject the request body into the created result, the tags of the fields apply
+	// to the decoded body too
+	fieldValues := make(map[string]reflect.Value)
+	if bindBody(params, name, resultPointer) {
+		bindTaggedFields(params, name, result, fieldValues, true)
 		return result
 	}
-	fieldValues := make(map[string]reflect.Value)
+	taggedParams := bindTaggedFields(params, name, result, fieldValues, false)
 	for key := range params.Values {
 		if !strings.HasPrefix(key, name+".") {
 			continue
@@ -314,11 +346,14 @@ func bindStruct(params *Params, name string, typ reflect.Type) reflect.Value {
 		fieldName := nextKey(suffix)
 		fieldLen := len(fieldName)
 
-		if _, ok := fieldValues[fieldName]; !ok {
+		if _, ok := fieldValues[fieldName]; !ok && !taggedParams[fieldName] {
 			// Time to bind this field.  Get it and make sure we can set it.
 			fieldValue := result.FieldByName(fieldName)
 			if !fieldValue.IsValid() {
 				binderLog.Warn("bindStruct Field not found", "name", fieldName)
+				if params.strict {
+					params.bindErrors = append(params.bindErrors, &bindError{key[:len(name)+1+fieldLen], "Is not a known field"})
+				}
 				continue
 			}
 			if !fieldValue.CanSet() {
@@ -334,6 +369,195 @@ func bindStruct(params *Params, name string, typ reflect.Type) reflect.Value {
 	return result
 }
 
+var timeType = reflect.TypeOf(time.Time{})
+
+// Binds the fields of the struct which have a tag, the parameter of a field is
+//   type Filter struct {
+//   	UserID int    `param:"user_id"`                 // Bound from filter.user_id, or user_id for an action argument
+//   	Limit  int    `binding:"query" default:"10"`    // Bound from the query string only, 10 when missing
+//   	Tenant string `binding:"header:X-Tenant"`       // Bound from the X-Tenant header
+//   	Trace  string `header:"X-Request-Id"`           // Bound from the X-Request-Id header
+//   	Hint   string `cookie:"session_hint"`           // Bound from the session_hint cookie
+//   	Token  string `param:"-"`                       // Never bound
+//   }
+// The time_format and time_location tags set the format and the location of a time field,
+// the sanitize tag sets the sanitizers of a string field (see Sanitizer).
+// The binding tag is one of query, form, route, fixed, header or cookie, the header and cookie
+// tags are short for binding:"header:Name" and binding:"cookie:Name". Returns the parameter
+// names of the tagged fields, the other fields are bound by their name.
+// When the struct was decoded from the body, the fields which are never bound are cleared,
+// the fields of a binding are bound again from their source only (a client cannot set a
+// header field in the body) and the defaults are set on the fields the body left empty.
+func bindTaggedFields(params *Params, name string, result reflect.Value, fieldValues map[string]reflect.Value, decoded bool) (taggedParams map[string]bool) {
+	typ := result.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		paramName, hasParam := field.Tag.Lookup("param")
+		source, hasSource := fieldSource(field)
+		defaultValue, hasDefault := field.Tag.Lookup("default")
+		timeFormat, hasTimeFormat := field.Tag.Lookup("time_format")
+		timeLocation, hasTimeLocation := field.Tag.Lookup("time_location")
+		hasTime := (hasTimeFormat || hasTimeLocation) && (field.Type == timeType || field.Type == reflect.PtrTo(timeType))
+		sanitize, hasSanitize := field.Tag.Lookup("sanitize")
+		if field.PkgPath != "" || !(hasParam || hasSource || hasDefault || hasTime || hasSanitize) {
+			continue
+		}
+		if taggedParams == nil {
+			taggedParams = map[string]bool{}
+		}
+		if paramName == "" {
+			paramName = field.Name
+		}
+		taggedParams[paramName] = true
+		if paramName == "-" {
+			result.Field(i).Set(reflect.Zero(field.Type))
+			fieldValues[field.Name] = result.Field(i)
+			continue
+		}
+		if decoded && !hasSource {
+			if hasDefault && result.Field(i).IsZero() {
+				defaultParams := *params
+				defaultParams.JSON, defaultParams.XML, defaultParams.MsgPack, defaultParams.Proto = nil, nil, nil, nil
+				defaultParams.Values = url.Values{paramName: {defaultValue}}
+				result.Field(i).Set(Bind(&defaultParams, paramName, field.Type))
+			}
+			continue
+		}
+
+		sourced := params
+		if hasSource {
+			var known bool
+			if sourced, known = params.sourced(source, paramName, name+"."+paramName, paramName); !known {
+				binderLog.Warn("bindStruct Unknown binding, expected query, form, route, fixed, header or cookie", "field", field.Name, "binding", source)
+				continue
+			}
+		}
+		key := name + "." + paramName
+		// An action argument is bound from the parameter itself, not only from a dotted key
+		if hasParam && !hasParamKey(sourced.Values, key) && !strings.ContainsAny(name, ".[") && hasParamKey(sourced.Values, paramName) {
+			key = paramName
+		}
+		if hasDefault && !hasParamKey(sourced.Values, key) {
+			defaultParams := *sourced
+			defaultParams.Values = url.Values{key: {defaultValue}}
+			sourced = &defaultParams
+		}
+		if hasSanitize {
+			sanitized := *sourced
+			sanitized.fieldSanitizers = append([]string{}, sanitizerNames(sanitize)...)
+			sourced = &sanitized
+		}
+
+		var value reflect.Value
+		if hasTime {
+			var formats []string
+			if hasTimeFormat {
+				formats = strings.Split(timeFormat, ",")
+			}
+			if value = bindTime(sourced, key, timeType, formats, timeLocation); field.Type.Kind() == reflect.Ptr {
+				if value.Interface().(time.Time).IsZero() {
+					value = reflect.Zero(field.Type)
+				} else {
+					pointer := reflect.New(timeType)
+					pointer.Elem().Set(value)
+					value = pointer
+				}
+			}
+		} else {
+			value = Bind(sourced, key, field.Type)
+		}
+		if sourced != params {
+			params.bindErrors = sourced.bindErrors
+		}
+		result.Field(i).Set(value)
+		fieldValues[field.Name] = value
+	}
+	return
+}
+
+// Returns the source of the binding of the field, from its binding, header or cookie tag
+func fieldSource(field reflect.StructField) (source string, found bool) {
+	if source, found = field.Tag.Lookup("binding"); found {
+		return
+	}
+	for _, kind := range []string{"header", "cookie"} {
+		if name, found := field.Tag.Lookup(kind); found {
+			return kind + ":" + name, true
+		}
+	}
+	return "", false
+}
+
+// Returns the kind of the source of a binding and the name of its parameter, the header or
+// cookie of a source like header:X-Auth-Token, or the name by default
+func sourceName(source, name string) (kind, param string) {
+	kind, param = source, name
+	if i := strings.Index(source, ":"); i >= 0 {
+		if kind = source[:i]; source[i+1:] != "" {
+			param = source[i+1:]
+		}
+	}
+	return
+}
+
+// Returns the parameters of the source of a binding, one of query, form, route, fixed,
+// header or cookie. A header or cookie source may name it (header:X-Auth-Token), the header
+// or cookie of the name is used by default, its values are stored under the keys. The
+// request body is not bound from the returned parameters.
+func (params *Params) sourced(source, name string, keys ...string) (*Params, bool) {
+	sourced := *params
+	sourced.JSON, sourced.XML, sourced.MsgPack, sourced.Proto, sourced.Files = nil, nil, nil, nil, nil
+	switch source {
+	case "query":
+		sourced.Values = params.Query
+	case "form":
+		sourced.Values, sourced.Files = params.Form, params.Files
+	case "route":
+		sourced.Values = params.Route
+	case "fixed":
+		sourced.Values = params.Fixed
+	default:
+		var values []string
+		switch kind, param := sourceName(source, name); kind {
+		case "header":
+			if params.header != nil {
+				values = params.header.GetAll(param)
+			}
+		case "cookie":
+			if params.header != nil && params.header.Server != nil {
+				if cookie, err := params.header.Server.GetCookie(param); err == nil {
+					values = []string{cookie.GetValue()}
+				}
+			}
+		default:
+			return nil, false
+		}
+		sourced.Values = url.Values{}
+		if len(values) > 0 {
+			for _, key := range keys {
+				sourced.Values[key] = values
+			}
+		}
+	}
+	if sourced.Values == nil {
+		sourced.Values = url.Values{}
+	}
+	return &sourced, true
+}
+
+// Returns true if the parameters have the key, or keys of the fields or elements of the key
+func hasParamKey(values url.Values, key string) bool {
+	if _, found := values[key]; found {
+		return true
+	}
+	for k := range values {
+		if strings.HasPrefix(k, key+".") || strings.HasPrefix(k, key+"[") {
+			return true
+		}
+	}
+	return false
+}
+
 func unbindStruct(output map[string]string, name string, iface interface{}) {
 	val := reflect.ValueOf(iface)
 	typ := val.Type()
@@ -414,8 +638,20 @@ func bindReadSeeker(params *Params, name string, typ reflect.Type) reflect.Value
 	return reflect.Zero(typ)
 }
 
+// Binds the next file part of a streamed upload, or the upload like an io.ReadSeeker
+func bindReader(params *Params, name string, typ reflect.Type) reflect.Value {
+	if params.uploadStream != nil {
+		return bindUploadPart(params, name, typ)
+	}
+	return bindReadSeeker(params, name, typ)
+}
+
 // bindMap converts parameters using map syntax into the corresponding map. e.g.:
 //   params["a[5]"]=foo, name="a", typ=map[int]string => map[int]string{5: "foo"}
+// The values may be structs, slices or maps
+//   params["addr[home].Street"]=Main, name="addr", typ=map[string]Address => map[string]Address{"home": {Street: "Main"}}
+//   params["tags[color][0]"]=red, name="tags", typ=map[string][]string => map[string][]string{"color": {"red"}}
+// A key which cannot be converted to the key type is a bind error.
 func bindMap(params *Params, name string, typ reflect.Type) reflect.Value {
 	var (
 		keyType   = typ.Key()
@@ -424,29 +660,78 @@ func bindMap(params *Params, name string, typ reflect.Type) reflect.Value {
 		result    = resultPtr.Elem()
 	)
 	result.Set(reflect.MakeMap(typ))
-	if params.JSON != nil {
-		// Try to inject the response as a json into the created result
-		if err := json.Unmarshal(params.JSON, resultPtr.Interface()); err != nil {
-			binderLog.Warn("bindMap: Unable to unmarshal request", "name", name, "error", err)
-		}
+	// Try to inject the request body into the created result
+	if bindBody(params, name, resultPtr) {
 		return result
 	}
 
-	for paramName, _ := range params.Values {
+	boundKeys := map[string]bool{}
+	for paramName := range params.Values {
+		if !strings.HasPrefix(paramName, name+"[") {
+			continue
+		}
+		// The key ends at the first bracket, it may contain dots
 		suffix := paramName[len(name)+1:]
-		fieldName := nextKey(suffix)
-		if fieldName != "" {
-			fieldName = fieldName[:len(fieldName)-1]
+		keyLen := strings.Index(suffix, "]")
+		if keyLen == -1 {
+			continue
 		}
-		if !strings.HasPrefix(paramName, name+"["+fieldName+"]") {
+		key := suffix[:keyLen]
+		if boundKeys[key] {
 			continue
 		}
+		boundKeys[key] = true
 
-		result.SetMapIndex(BindValue(fieldName, keyType), Bind(params, name+"["+fieldName+"]", valueType))
+		keyValue, ok := bindMapKey(key, keyType)
+		if !ok {
+			binderLog.Warn("bindMap: Invalid map key", "name", name, "key", key, "type", keyType)
+			params.bindErrors = append(params.bindErrors, &bindError{name + "[" + key + "]", "Must have a valid key"})
+			continue
+		}
+		result.SetMapIndex(keyValue, Bind(params, name+"["+key+"]", valueType))
 	}
 	return result
 }
 
+// Converts the key of a map parameter to the key type, returns false if it cannot
+func bindMapKey(key string, keyType reflect.Type) (value reflect.Value, ok bool) {
+	value = reflect.New(keyType).Elem()
+	switch keyType.Kind() {
+	case reflect.String:
+		value.SetString(key)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(key, 10, keyType.Bits())
+		if err != nil {
+			return value, false
+		}
+		value.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(key, 10, keyType.Bits())
+		if err != nil {
+			return value, false
+		}
+		value.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(key, keyType.Bits())
+		if err != nil {
+			return value, false
+		}
+		value.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(key)
+		if err != nil {
+			return value, false
+		}
+		value.SetBool(b)
+	default:
+		// Bound like a parameter, the binders of the key types record their errors
+		keyParams := &Params{Values: map[string][]string{"": {key}}}
+		value = Bind(keyParams, "", keyType)
+		return value, len(keyParams.bindErrors) == 0
+	}
+	return value, true
+}
+
 func unbindMap(output map[string]string, name string, iface interface{}) {
 	mapValue := reflect.ValueOf(iface)
 	for _, key := range mapValue.MapKeys() {
@@ -484,15 +769,35 @@ func Unbind(output map[string]string, name string, val interface{}) {
 }
 
 func binderForType(typ reflect.Type) (Binder, bool) {
-	binder, ok := TypeBinders[typ]
+	binder, ok := lookupBinder(typ)
 	if !ok {
-		binder, ok = KindBinders[typ.Kind()]
-		if !ok {
-			binderLog.Error("binderForType: no binder for type", "type", typ)
-			return Binder{}, false
+		binderLog.Error("binderForType: no binder for type", "type", typ)
+	}
+	return binder, ok
+}
+
+// Returns the registered binder for the type, the built-in binder for the type, or the
+// binder for the kind of the type
+func lookupBinder(typ reflect.Type) (binder Binder, ok bool) {
+	if binder, ok = registeredBinders[typ]; ok {
+		return
+	}
+	if binder, ok = TypeBinders[typ]; !ok {
+		if isUUIDType(typ) {
+			return UUIDBinder, true
+		}
+		if isProtoType(typ) {
+			return ProtoBinder, true
+		}
+		if isOptionalType(typ) {
+			return OptionalBinder, true
 		}
+		if binder, ok = unmarshalerBinder(typ); ok {
+			return
+		}
+		binder, ok = KindBinders[typ.Kind()]
 	}
-	return binder, true
+	return
 }
 
 // Sadly, the binder lookups can not be declared initialized -- that results in
@@ -525,12 +830,15 @@ func init() {
 	// Uploads
 	TypeBinders[reflect.TypeOf(&os.File{})] = Binder{bindFile, nil}
 	TypeBinders[reflect.TypeOf([]byte{})] = Binder{bindByteArray, nil}
-	TypeBinders[reflect.TypeOf((*io.Reader)(nil)).Elem()] = Binder{bindReadSeeker, nil}
+	TypeBinders[reflect.TypeOf((*io.Reader)(nil)).Elem()] = Binder{bindReader, nil}
 	TypeBinders[reflect.TypeOf((*io.ReadSeeker)(nil)).Elem()] = Binder{bindReadSeeker, nil}
 
 	OnAppStart(func() {
 		DateTimeFormat = Config.StringDefault("format.datetime", DefaultDateTimeFormat)
 		DateFormat = Config.StringDefault("format.date", DefaultDateFormat)
 		TimeFormats = append(TimeFormats, DateTimeFormat, DateFormat)
+		SliceParamFormat = Config.StringDefault("params.slice.format", "indexed")
+		SliceGaps = Config.StringDefault("params.slice.gaps", "zero")
+		SliceMaxIndex = Config.IntDefault("params.slice.maxindex", 10000)
 	})
 }
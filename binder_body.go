@@ -0,0 +1,139 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// The body of a JSON (application/json, text/json, application/*+json) or XML
+// (application/xml, text/xml, application/*+xml) request is decoded into the struct and map
+// arguments of the action
+//   func (c Bookings) Create(booking models.Booking) revel.Result
+// The errors of the body are added to the Validation, the key of an invalid field is the
+// name of the argument and the JSON name of the field (booking.checkIn). The body, like a
+// MessagePack or protobuf body, is limited in app.conf, an unknown field of a JSON body is an error when the decoding is strict
+//   http.request.body.maxsize = 10MB  # Larger bodies are not bound
+//   http.request.body.strict = false  # The unknown fields of the JSON bodies are errors
+// The unknown elements of an XML body are always ignored.
+
+// The default maximum size of a bound request body
+const defaultBodyMaxSize = 10 << 20
+
+// The limits of the bound bodies, set by app.conf
+var bodyConfig = struct {
+	maxSize int64
+	strict  bool
+}{maxSize: defaultBodyMaxSize}
+
+func init() {
+	OnAppStart(func() {
+		bodyConfig.maxSize = ConfigSizeDefault("http.request.body.maxsize", defaultBodyMaxSize, 1)
+		bodyConfig.strict = Config.BoolDefault("http.request.body.strict", false)
+	})
+}
+
+// Reads the body of the request, a body larger than http.request.body.maxsize is not read
+// and is reported as a bind error
+func readParamsBody(params *Params, body io.Reader) []byte {
+	maxSize := bodyConfig.maxSize
+	content, err := ioutil.ReadAll(io.LimitReader(body, maxSize+1))
+	if err != nil {
+		paramsLogger.Error("ParseParams: Failed to ready request body bytes", "error", err)
+		return nil
+	}
+	if int64(len(content)) > maxSize {
+		paramsLogger.Warn("ParseParams: Request body too large", "maxsize", maxSize)
+		params.bindErrors = append(params.bindErrors, &bindError{"body", fmt.Sprintf("Must be at most %d bytes", maxSize)})
+		return nil
+	}
+	return content
+}
+
+// Decodes the body of the request into the value the pointer points to. Returns false if
+// the request has no body to bind.
+func bindBody(params *Params, name string, pointer reflect.Value) bool {
+	var err error
+	switch {
+	case params.JSON != nil:
+		err = decodeJSONBody(params.JSON, pointer.Interface(), params.strict)
+	case params.XML != nil:
+		err = xml.Unmarshal(params.XML, pointer.Interface())
+	case params.MsgPack != nil:
+		err = unmarshalMsgPack(params.MsgPack, pointer.Interface())
+	default:
+		return false
+	}
+	if err != nil {
+		binderLog.Warn("bindBody: Unable to unmarshal request", "name", name, "error", err)
+		params.bindErrors = append(params.bindErrors, bodyBindError(name, err))
+	}
+	return true
+}
+
+// Decodes the JSON, the unknown fields are errors when strict or http.request.body.strict
+// is set
+func decodeJSONBody(data []byte, dest interface{}, strict bool) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if strict || bodyConfig.strict {
+		decoder.DisallowUnknownFields()
+	}
+	return decoder.Decode(dest)
+}
+
+// Returns the bind error of the decoding error, keyed by the invalid field when known
+func bodyBindError(name string, err error) *bindError {
+	var typeError *json.UnmarshalTypeError
+	var jsonSyntaxError *json.SyntaxError
+	var xmlSyntaxError *xml.SyntaxError
+	switch {
+	case errors.As(err, &typeError):
+		key := name
+		if typeError.Field != "" {
+			key += "." + typeError.Field
+		}
+		return &bindError{key, "Must be " + jsonKindName(typeError.Type)}
+	case errors.As(err, &jsonSyntaxError), err == io.EOF, err == io.ErrUnexpectedEOF:
+		return &bindError{name, "Must be valid JSON"}
+	case errors.As(err, &xmlSyntaxError):
+		return &bindError{name, "Must be valid XML"}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// The error of DisallowUnknownFields has no type
+		field, unquoteErr := strconv.Unquote(strings.TrimPrefix(err.Error(), "json: unknown field "))
+		if unquoteErr == nil {
+			return &bindError{name + "." + field, "Is not a known field"}
+		}
+	}
+	return &bindError{name, err.Error()}
+}
+
+// Returns the JSON description of the type
+func jsonKindName(typ reflect.Type) string {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	switch typ.Kind() {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	}
+	return "an object"
+}
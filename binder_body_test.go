@@ -0,0 +1,97 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"bytes"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+type bodyBooking struct {
+	Name   string `json:"name" xml:"name"`
+	Nights int    `json:"nights" xml:"nights"`
+}
+
+// Returns the params of a request with the body
+func bodyParams(contentType, body string) *Params {
+	req, _ := http.NewRequest("POST", "/bookings", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", contentType)
+	c := NewTestController(nil, req)
+	ParseParams(c.Params, c.Request)
+	return c.Params
+}
+
+func TestBindBody(t *testing.T) {
+	expected := bodyBooking{Name: "Hilton", Nights: 2}
+	for contentType, body := range map[string]string{
+		"application/json":         `{"name":"Hilton","nights":2}`,
+		"application/vnd.api+json": `{"name":"Hilton","nights":2}`,
+		"application/xml":          `<booking><name>Hilton</name><nights>2</nights></booking>`,
+		"application/booking+xml":  `<booking><name>Hilton</name><nights>2</nights></booking>`,
+		"text/xml; charset=utf-8":  `<booking><name>Hilton</name><nights>2</nights><unknown/></booking>`,
+	} {
+		params := bodyParams(contentType, body)
+		if booking := Bind(params, "booking", reflect.TypeOf(bodyBooking{})).Interface(); booking != expected {
+			t.Errorf("Expected %v for %s, got %v", expected, contentType, booking)
+		}
+		if booking := Bind(params, "booking", reflect.TypeOf(&bodyBooking{})).Interface().(*bodyBooking); *booking != expected {
+			t.Errorf("Expected a pointer to %v for %s, got %v", expected, contentType, booking)
+		}
+		if len(params.bindErrors) != 0 {
+			t.Errorf("Unexpected bind errors for %s: %v", contentType, params.bindErrors)
+		}
+	}
+}
+
+func TestBindBodyErrors(t *testing.T) {
+	params := bodyParams("application/json", `{"name":"Hilton","nights":"two"}`)
+	Bind(params, "booking", reflect.TypeOf(bodyBooking{}))
+	if len(params.bindErrors) != 1 || params.bindErrors[0].name != "booking.nights" || params.bindErrors[0].message != "Must be a number" {
+		t.Errorf("Expected an error for the nights, got %v", params.bindErrors)
+	}
+
+	params = bodyParams("application/xml", `<booking><name>`)
+	Bind(params, "booking", reflect.TypeOf(bodyBooking{}))
+	if len(params.bindErrors) != 1 || params.bindErrors[0].name != "booking" || params.bindErrors[0].message != "Must be valid XML" {
+		t.Errorf("Expected an error for the invalid XML, got %v", params.bindErrors)
+	}
+
+	// The unknown fields are errors when the decoding is strict
+	body := `{"name":"Hilton","rooms":3}`
+	params = bodyParams("application/json", body)
+	Bind(params, "booking", reflect.TypeOf(bodyBooking{}))
+	if len(params.bindErrors) != 0 {
+		t.Errorf("Unexpected bind errors %v", params.bindErrors)
+	}
+	bodyConfig.strict = true
+	defer func() { bodyConfig.strict = false }()
+	params = bodyParams("application/json", body)
+	Bind(params, "booking", reflect.TypeOf(bodyBooking{}))
+	if len(params.bindErrors) != 1 || params.bindErrors[0].name != "booking.rooms" || params.bindErrors[0].message != "Is not a known field" {
+		t.Errorf("Expected an error for the unknown field, got %v", params.bindErrors)
+	}
+}
+
+func TestBindBodyMaxSize(t *testing.T) {
+	bodyConfig.maxSize = 10
+	defer func() { bodyConfig.maxSize = defaultBodyMaxSize }()
+	params := bodyParams("application/json", `{"name":"Hilton","nights":2}`)
+	if params.JSON != nil {
+		t.Errorf("Expected the large body not to be read")
+	}
+	if len(params.bindErrors) != 1 || params.bindErrors[0].name != "body" {
+		t.Errorf("Expected an error for the large body, got %v", params.bindErrors)
+	}
+
+	// The MessagePack and protobuf bodies have the same limit
+	for _, contentType := range []string{MsgPackContentType, ProtoContentType} {
+		params = bodyParams(contentType, "0123456789abcdef")
+		if params.MsgPack != nil || params.Proto != nil || len(params.bindErrors) != 1 {
+			t.Errorf("Expected the large %s body not to be read, got %v", contentType, params.bindErrors)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// Optional records if a parameter was sent, a PATCH action changes only the fields sent
+//   type UserPatch struct {
+//   	Name  revel.Optional[string]
+//   	Email revel.Optional[string]
+//   	Age   revel.Optional[int]
+//   }
+//   func (c Users) Patch(id int, patch UserPatch) revel.Result {
+//   	if name, sent := patch.Name.Get(); sent {
+//   		user.Name = name
+//   	}
+//   	...
+//   }
+// A parameter sent empty (email=), or null in a JSON body, is present with the zero value.
+// A missing parameter is not present.
+type Optional[T any] struct {
+	Value   T
+	Present bool // The parameter was sent
+}
+
+// Some returns the present value
+func Some[T any](value T) Optional[T] {
+	return Optional[T]{Value: value, Present: true}
+}
+
+// Get returns the value and true if it was sent
+func (o Optional[T]) Get() (T, bool) {
+	return o.Value, o.Present
+}
+
+// OrElse returns the value if it was sent, the default value otherwise
+func (o Optional[T]) OrElse(defaultValue T) T {
+	if o.Present {
+		return o.Value
+	}
+	return defaultValue
+}
+
+// MarshalJSON marshals the value, null when it is not present
+func (o Optional[T]) MarshalJSON() ([]byte, error) {
+	if !o.Present {
+		return []byte("null"), nil
+	}
+	return json.Marshal(o.Value)
+}
+
+// UnmarshalJSON unmarshals the value of a field of a JSON body, which is present even if null
+func (o *Optional[T]) UnmarshalJSON(data []byte) error {
+	o.Present = true
+	if string(data) == "null" {
+		var zero T
+		o.Value = zero
+		return nil
+	}
+	return json.Unmarshal(data, &o.Value)
+}
+
+// The Optional types, whatever their value type
+type optional interface {
+	optionalType() reflect.Type
+	optionalValue() (value interface{}, present bool)
+}
+
+// The pointers to the Optional types
+type optionalSetter interface {
+	optional
+	setOptional(value reflect.Value)
+}
+
+func (o Optional[T]) optionalType() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+func (o Optional[T]) optionalValue() (interface{}, bool) {
+	return o.Value, o.Present
+}
+
+func (o *Optional[T]) setOptional(value reflect.Value) {
+	o.Value, _ = value.Interface().(T)
+	o.Present = true
+}
+
+var optionalSetterType = reflect.TypeOf((*optionalSetter)(nil)).Elem()
+
+// OptionalBinder binds the Optional types, present when the request has the parameter
+var OptionalBinder Binder
+
+func init() {
+	OptionalBinder = Binder{bindOptional, unbindOptional}
+}
+
+// Returns true if the type is an Optional
+func isOptionalType(typ reflect.Type) bool {
+	return typ.Kind() == reflect.Struct && reflect.PtrTo(typ).Implements(optionalSetterType)
+}
+
+func bindOptional(params *Params, name string, typ reflect.Type) reflect.Value {
+	result := reflect.New(typ)
+	if !hasParamKey(params.Values, name) && len(params.Files[name]) == 0 {
+		return result.Elem()
+	}
+	value := result.Interface().(optionalSetter)
+	value.setOptional(Bind(params, name, value.optionalType()))
+	return result.Elem()
+}
+
+func unbindOptional(output map[string]string, name string, val interface{}) {
+	if value, present := val.(optional).optionalValue(); present {
+		Unbind(output, name, value)
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+type userPatch struct {
+	Name  Optional[string] `json:"name"`
+	Email Optional[string] `json:"email"`
+	Age   Optional[int]    `json:"age"`
+}
+
+func TestOptionalBinder(t *testing.T) {
+	params := &Params{Values: map[string][]string{"user.Name": {"Jane"}, "user.Email": {""}}}
+	patch := Bind(params, "user", reflect.TypeOf(userPatch{})).Interface().(userPatch)
+	expected := userPatch{Name: Some("Jane"), Email: Some("")}
+	if patch != expected {
+		t.Errorf("Expected %#v, got %#v", expected, patch)
+	}
+	if age := patch.Age.OrElse(18); age != 18 {
+		t.Errorf("Expected the default age, got %d", age)
+	}
+
+	// A null field of a JSON body is present
+	params = &Params{JSON: []byte(`{"name": null, "age": 30}`)}
+	patch = Bind(params, "user", reflect.TypeOf(userPatch{})).Interface().(userPatch)
+	if expected = (userPatch{Name: Some(""), Age: Some(30)}); patch != expected {
+		t.Errorf("Expected %#v, got %#v", expected, patch)
+	}
+	if data, _ := json.Marshal(patch); string(data) != `{"name":"","email":null,"age":30}` {
+		t.Errorf("Unexpected JSON %s", data)
+	}
+
+	output := map[string]string{}
+	Unbind(output, "user", patch)
+	if !reflect.DeepEqual(output, map[string]string{"user.Name": "", "user.Age": "30"}) {
+		t.Errorf("Expected only the present fields, got %v", output)
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"fmt"
+	"mime/multipart"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// BindParams binds the parameters in the action instead of the arguments of the action
+//   func (c Hotels) List() revel.Result {
+//   	filter, err := revel.BindParams[models.HotelFilter](c.Controller,
+//   		revel.BindSource("query"), revel.BindStrict(), revel.BindDefaults(map[string]string{"Page": "1"}))
+//   	if err != nil {
+//   		return c.RenderError(err)
+//   	}
+//   	...
+//   }
+// The fields are bound from the parameters of their names (Page, Size), or of the name of
+// BindName (filter.Page). The bind errors are added to the Validation, and returned as a
+// *Problem of status 400 which lists them. The validate tags of the fields are checked, their
+// errors are added to the Validation only (see Validation.Struct).
+
+// The name of the parameters of BindParams when it has no BindName
+const bindParamsName = "params"
+
+// BindOption sets how BindParams binds the parameters
+type BindOption func(*bindOptions)
+
+type bindOptions struct {
+	name     string            // The name of the parameters, empty for the parameters of the fields
+	source   string            // The source of the parameters, all the parameters when empty
+	strict   bool              // The unknown parameters are bind errors
+	defaults map[string]string // The values of the missing parameters
+}
+
+// BindName binds the value from the parameters of the name (filter.Page)
+func BindName(name string) BindOption {
+	return func(options *bindOptions) {
+		options.name = name
+	}
+}
+
+// BindSource binds the value from the source only, one of query, form, route, fixed,
+// header or cookie (see @BindFrom)
+func BindSource(source string) BindOption {
+	return func(options *bindOptions) {
+		options.source = source
+	}
+}
+
+// BindStrict reports the parameters of the name of BindName which are not a field of the
+// value as bind errors, and the unknown fields of a JSON body. Without BindName the
+// parameters of the request are not all bound, the unknown fields of the fields are reported.
+func BindStrict() BindOption {
+	return func(options *bindOptions) {
+		options.strict = true
+	}
+}
+
+// BindDefaults sets the values of the missing parameters, keyed by the name of the field
+func BindDefaults(defaults map[string]string) BindOption {
+	return func(options *bindOptions) {
+		options.defaults = defaults
+	}
+}
+
+// BindParams binds the parameters of the request to a value of type T with the options
+func BindParams[T any](c *Controller, opts ...BindOption) (value T, err error) {
+	options := &bindOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	name := options.name
+	if name == "" {
+		name = bindParamsName
+	}
+
+	params := *c.Params
+	params.bindErrors, params.strict = nil, options.strict
+	sourced := &params
+	if options.source != "" {
+		var known bool
+		if sourced, known = params.sourced(options.source, name, name); !known {
+			return value, fmt.Errorf("BindParams source must be query, form, route, fixed, header or cookie, got %q", options.source)
+		}
+	}
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if options.name == "" {
+		// The other parameters of the request are not the value's
+		fields := structParamNames(typ)
+		sourced.Values, sourced.Files = prefixedValues(sourced.Values, name, fields), prefixedFiles(sourced.Files, name, fields)
+	} else if len(options.defaults) > 0 {
+		sourced.Values = copyValues(sourced.Values)
+	}
+	for key, defaultValue := range options.defaults {
+		if key = name + "." + key; !hasParamKey(sourced.Values, key) {
+			sourced.Values[key] = []string{defaultValue}
+		}
+	}
+
+	if bound := Bind(sourced, name, typ); bound.IsValid() {
+		value, _ = bound.Interface().(T)
+	}
+	for _, bindErr := range sourced.bindErrors {
+		if options.name == "" {
+			// The errors are keyed by the parameters of the fields
+			if bindErr.name = strings.TrimPrefix(bindErr.name, name+"."); bindErr.name == name {
+				bindErr.name = "body"
+			}
+		}
+		if c.Validation != nil {
+			c.Validation.Error("%s", bindErr.message).Key(bindErr.name)
+		}
+	}
+	if c.Validation != nil {
+		c.Validation.Struct(options.name, value)
+	}
+	if len(sourced.bindErrors) > 0 {
+		err = bindErrorsProblem(sourced.bindErrors)
+	}
+	return
+}
+
+// Returns the names of the parameters of the fields of the struct type, nil for another type
+func structParamNames(typ reflect.Type) map[string]bool {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return nil
+	}
+	names := map[string]bool{}
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		names[field.Name] = true
+		if paramName := field.Tag.Get("param"); paramName != "" && paramName != "-" {
+			names[paramName] = true
+		}
+	}
+	return names
+}
+
+// Returns the values with the keys prefixed by the name (page becomes params.page), only the
+// values of the fields when they are not nil
+func prefixedValues(values url.Values, name string, fields map[string]bool) url.Values {
+	prefixed := make(url.Values, len(values))
+	for key, value := range values {
+		if fields == nil || fields[nextKey(key)] {
+			prefixed[name+"."+key] = value
+		}
+	}
+	return prefixed
+}
+
+// Returns the files with the keys prefixed by the name, only the files of the fields when
+// they are not nil
+func prefixedFiles(files map[string][]*multipart.FileHeader, name string, fields map[string]bool) map[string][]*multipart.FileHeader {
+	if files == nil {
+		return nil
+	}
+	prefixed := make(map[string][]*multipart.FileHeader, len(files))
+	for key, value := range files {
+		if fields == nil || fields[nextKey(key)] {
+			prefixed[name+"."+key] = value
+		}
+	}
+	return prefixed
+}
+
+// Returns a copy of the values which may be changed
+func copyValues(values url.Values) url.Values {
+	copied := make(url.Values, len(values))
+	for key, value := range values {
+		copied[key] = value
+	}
+	return copied
+}
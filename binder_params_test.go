@@ -0,0 +1,80 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+type hotelFilter struct {
+	Query string
+	Page  int
+	Size  int
+}
+
+// Returns a controller of the request with its params parsed
+func bindParamsController(target string) *Controller {
+	req, _ := http.NewRequest("GET", target, nil)
+	c := NewTestController(nil, req)
+	c.Validation = &Validation{Request: c.Request, Translator: MessageFunc}
+	ParseParams(c.Params, c.Request)
+	return c
+}
+
+func TestBindParams(t *testing.T) {
+	c := bindParamsController("/hotels?Query=paris&Size=20&Other=1")
+	filter, err := BindParams[hotelFilter](c, BindDefaults(map[string]string{"Page": "1", "Size": "10"}))
+	if err != nil || filter != (hotelFilter{Query: "paris", Page: 1, Size: 20}) {
+		t.Errorf("Unexpected filter %#v %v", filter, err)
+	}
+
+	c = bindParamsController("/hotels?filter.Query=rome&Query=paris")
+	if filter, err = BindParams[hotelFilter](c, BindName("filter")); err != nil || filter.Query != "rome" {
+		t.Errorf("Expected the filter of the name, got %#v %v", filter, err)
+	}
+	if name, err := BindParams[string](c, BindSource("query"), BindName("Query")); err != nil || name != "paris" {
+		t.Errorf("Expected the query value, got %q %v", name, err)
+	}
+	if filter, err = BindParams[hotelFilter](c, BindSource("form")); err != nil || filter.Query != "" {
+		t.Errorf("Expected no form values, got %#v %v", filter, err)
+	}
+	if _, err = BindParams[hotelFilter](c, BindSource("session")); err == nil {
+		t.Errorf("Expected an error for an unknown source")
+	}
+}
+
+func TestBindParamsErrors(t *testing.T) {
+	c := bindParamsController("/hotels?filter.Query=paris&filter.Size=big&filter.Other=1&page=2")
+	_, err := BindParams[hotelFilter](c, BindName("filter"), BindStrict())
+	var problem *Problem
+	if !errors.As(err, &problem) || problem.Status != http.StatusBadRequest {
+		t.Fatalf("Expected a problem, got %v", err)
+	}
+	fields := map[string]string{}
+	for _, field := range problem.Extensions["errors"].([]map[string]string) {
+		fields[field["field"]] = field["message"]
+	}
+	if len(fields) != 2 || fields["filter.Size"] != "Must be an integer" || fields["filter.Other"] != "Is not a known field" {
+		t.Errorf("Unexpected errors %v", fields)
+	}
+	if errorMap := c.Validation.ErrorMap(); errorMap["filter.Size"] == nil || errorMap["filter.Other"] == nil {
+		t.Errorf("Expected the errors in the validation, got %v", errorMap)
+	}
+
+	// Without a name the other parameters of the request are not the value's
+	c = bindParamsController("/hotels?Query=paris&Size=big&Other=1")
+	_, err = BindParams[hotelFilter](c, BindStrict())
+	if !errors.As(err, &problem) || len(problem.Extensions["errors"].([]map[string]string)) != 1 || c.Validation.ErrorMap()["Size"] == nil {
+		t.Errorf("Expected the error of the field only, got %v", err)
+	}
+
+	// The unknown parameters are ignored unless strict
+	c = bindParamsController("/hotels?filter.Query=paris&filter.Other=1")
+	if _, err = BindParams[hotelFilter](c, BindName("filter")); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
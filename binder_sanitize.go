@@ -0,0 +1,139 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+	"golang.org/x/text/unicode/norm"
+)
+
+// The parameters are sanitized before they are bound to the strings. The sanitizers of all
+// the strings are set in app.conf
+//   params.sanitize = trim,control
+// The sanitize tag of a field replaces them, "-" binds the field as it is sent
+//   type Comment struct {
+//   	Author   string `sanitize:"trim,nfc"`
+//   	Body     string `sanitize:"trim,html"`
+//   	Password string `sanitize:"-"`
+//   }
+// The sanitizers of a string type are added to the others, they are set once
+//   revel.SanitizeType(reflect.TypeOf(models.Email("")), "trim", "lower")
+// The sanitizers are
+//   trim    - removes the leading and trailing white space
+//   nfc     - normalizes the unicode to its composed form (NFC)
+//   control - removes the control characters, but the new lines and the tabs
+//   html    - removes the HTML tags, comments, scripts and styles, the text stays escaped
+//   lower   - converts to lower case
+// and the sanitizers registered by the application
+//   revel.RegisterSanitizer("digits", func(value string) string { ... })
+
+// Sanitizer returns the sanitized parameter
+type Sanitizer func(value string) string
+
+var (
+	sanitizers = map[string]Sanitizer{
+		"trim":    strings.TrimSpace,
+		"nfc":     norm.NFC.String,
+		"control": stripControlCharacters,
+		"html":    stripHTML,
+		"lower":   strings.ToLower,
+	}
+
+	// The sanitizers of the string types
+	typeSanitizers = map[reflect.Type][]string{}
+
+	// The sanitizers of all the strings, set by `params.sanitize`
+	paramsSanitizers []string
+)
+
+func init() {
+	OnAppStart(func() {
+		paramsSanitizers = sanitizerNames(Config.StringDefault("params.sanitize", ""))
+	})
+}
+
+// RegisterSanitizer registers the sanitizer of the name, it replaces the sanitizer of the name
+// if any. The sanitizers are registered before the controllers (in an init function).
+func RegisterSanitizer(name string, sanitizer Sanitizer) {
+	sanitizers[name] = sanitizer
+}
+
+// SanitizeType sets the sanitizers of the string type, run after the other sanitizers
+func SanitizeType(typ reflect.Type, names ...string) {
+	typeSanitizers[typ] = names
+}
+
+// Returns the names of the comma separated list, nil for "-"
+func sanitizerNames(list string) (names []string) {
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	return
+}
+
+// Returns the value sanitized for the string type, by the sanitizers of the field bound or
+// of app.conf, then by the sanitizers of the type
+func (params *Params) sanitize(value string, typ reflect.Type) string {
+	names := paramsSanitizers
+	if params.fieldSanitizers != nil {
+		names = params.fieldSanitizers
+	}
+	for _, list := range [][]string{names, typeSanitizers[typ]} {
+		for _, name := range list {
+			if sanitizer, found := sanitizers[name]; found {
+				value = sanitizer(value)
+			} else {
+				binderLog.Error("sanitize: Unknown sanitizer", "name", name)
+			}
+		}
+	}
+	return value
+}
+
+// Removes the control characters, but the new lines and the tabs
+func stripControlCharacters(value string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) && r != '\n' && r != '\r' && r != '\t' {
+			return -1
+		}
+		return r
+	}, value)
+}
+
+// Returns the text of the HTML, without the tags, the comments, the scripts and the styles.
+// The text is escaped again, an escaped tag must not become a tag once sanitized.
+func stripHTML(value string) string {
+	text := &strings.Builder{}
+	tokenizer := html.NewTokenizer(strings.NewReader(value))
+	skipped := ""
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			if tokenizer.Err() != io.EOF {
+				binderLog.Warn("stripHTML: Invalid HTML", "error", tokenizer.Err())
+			}
+			return text.String()
+		case html.StartTagToken:
+			if name, _ := tokenizer.TagName(); skipped == "" && (string(name) == "script" || string(name) == "style") {
+				skipped = string(name)
+			}
+		case html.EndTagToken:
+			if name, _ := tokenizer.TagName(); string(name) == skipped {
+				skipped = ""
+			}
+		case html.TextToken:
+			if skipped == "" {
+				text.WriteString(html.EscapeString(string(tokenizer.Text())))
+			}
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type sanitizedEmail string
+
+type sanitizedComment struct {
+	Author   string `sanitize:"trim,nfc"`
+	Body     string `sanitize:"html"`
+	Password string `sanitize:"-"`
+	Tags     []string
+	Email    sanitizedEmail
+}
+
+func TestSanitizers(t *testing.T) {
+	for name, test := range map[string][2]string{
+		"trim":    {"  Jane \n", "Jane"},
+		"nfc":     {"Zoe\u0301", "Zo\u00e9"},
+		"control": {"a\x00b\x1bc\td\n", "abc\td\n"},
+		"html":    {`<p onclick="x()">Hello <b>Jane</b> &amp; co</p><script>alert(1)</script><!-- c -->`, "Hello Jane &amp; co"},
+		"lower":   {"JANE", "jane"},
+	} {
+		if sanitized := sanitizers[name](test[0]); sanitized != test[1] {
+			t.Errorf("Expected %q for %s, got %q", test[1], name, sanitized)
+		}
+	}
+}
+
+func TestStripHTMLEscapedTags(t *testing.T) {
+	for value, expected := range map[string]string{
+		"&lt;script&gt;alert(1)&lt;/script&gt;": "&lt;script&gt;alert(1)&lt;/script&gt;",
+		"<<b>script>alert(1)<</b>/script>":      "&lt;script&gt;alert(1)&lt;/script&gt;",
+		`Jane "J" O'Hara`:                       "Jane &#34;J&#34; O&#39;Hara",
+	} {
+		if sanitized := stripHTML(value); sanitized != expected {
+			t.Errorf("Expected %q for %q, got %q", expected, value, sanitized)
+		}
+	}
+}
+
+func TestSanitizedBinder(t *testing.T) {
+	paramsSanitizers = []string{"trim", "control"}
+	SanitizeType(reflect.TypeOf(sanitizedEmail("")), "lower")
+	RegisterSanitizer("shout", strings.ToUpper)
+	defer func() {
+		paramsSanitizers = nil
+		delete(typeSanitizers, reflect.TypeOf(sanitizedEmail("")))
+		delete(sanitizers, "shout")
+	}()
+
+	params := &Params{Values: map[string][]string{
+		"comment.Author":   {" Zoe\u0301 "},
+		"comment.Body":     {" <i>Nice</i> "},
+		"comment.Password": {" secret\x00 "},
+		"comment.Tags[0]":  {" travel\x07"},
+		"comment.Email":    {" Jane@Example.com "},
+		"name":             {" jane "},
+	}}
+	comment := Bind(params, "comment", reflect.TypeOf(sanitizedComment{})).Interface().(sanitizedComment)
+	expected := sanitizedComment{Author: "Zo\u00e9", Body: " Nice ", Password: " secret\x00 ", Tags: []string{"travel"}, Email: "jane@example.com"}
+	if !reflect.DeepEqual(comment, expected) {
+		t.Errorf("Expected %#v, got %#v", expected, comment)
+	}
+
+	paramsSanitizers = []string{"trim", "shout"}
+	if name := Bind(params, "name", reflect.TypeOf("")).Interface(); name != "JANE" {
+		t.Errorf("Expected the registered sanitizer to run, got %q", name)
+	}
+}
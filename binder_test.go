@@ -9,9 +9,14 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/netip"
 	"os"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -367,6 +372,394 @@ func TestUnbinder(t *testing.T) {
 
 // Helpers
 
+func TestTypeBinders(t *testing.T) {
+	// The UUID types are recognized by their name
+	type UUID [16]byte
+	params := &Params{Values: map[string][]string{
+		"duration": {"1h30m"},
+		"addr":     {"192.168.0.1"},
+		"prefix":   {"10.0.0.0/8"},
+		"addrport": {"[::1]:80"},
+		"int":      {"123456789012345678901234567890"},
+		"float":    {"1.5e100"},
+		"uuid":     {"6BA7B810-9DAD-11D1-80B4-00C04FD430C8"},
+		"bad":      {"not-valid"},
+	}}
+
+	if d := Bind(params, "duration", reflect.TypeOf(time.Duration(0))).Interface(); d != 90*time.Minute {
+		t.Errorf("Expected 1h30m got %v", d)
+	}
+	if addr := Bind(params, "addr", reflect.TypeOf(netip.Addr{})).Interface(); addr != netip.MustParseAddr("192.168.0.1") {
+		t.Errorf("Expected 192.168.0.1 got %v", addr)
+	}
+	if prefix := Bind(params, "prefix", reflect.TypeOf(netip.Prefix{})).Interface(); prefix != netip.MustParsePrefix("10.0.0.0/8") {
+		t.Errorf("Expected 10.0.0.0/8 got %v", prefix)
+	}
+	if addrPort := Bind(params, "addrport", reflect.TypeOf(netip.AddrPort{})).Interface(); addrPort != netip.MustParseAddrPort("[::1]:80") {
+		t.Errorf("Expected [::1]:80 got %v", addrPort)
+	}
+	if i := Bind(params, "int", reflect.TypeOf(&big.Int{})).Interface().(*big.Int); i.String() != "123456789012345678901234567890" {
+		t.Errorf("Expected the big integer got %s", i)
+	}
+	if f := Bind(params, "float", reflect.TypeOf(big.Float{})).Interface().(big.Float); f.Text('g', 3) != "1.5e+100" {
+		t.Errorf("Expected 1.5e+100 got %s", f.Text('g', 3))
+	}
+	uuid := Bind(params, "uuid", reflect.TypeOf(UUID{})).Interface().(UUID)
+	output := map[string]string{}
+	if Unbind(output, "uuid", uuid); output["uuid"] != "6ba7b810-9dad-11d1-80b4-00c04fd430c8" {
+		t.Errorf("Expected the UUID to round trip, got %s", output["uuid"])
+	}
+
+	for _, typ := range []reflect.Type{reflect.TypeOf(time.Duration(0)), reflect.TypeOf(netip.Addr{}), reflect.TypeOf(big.Int{}), reflect.TypeOf(UUID{})} {
+		if value := Bind(params, "bad", typ); !reflect.DeepEqual(value.Interface(), reflect.Zero(typ).Interface()) {
+			t.Errorf("Expected the zero %s for an invalid value, got %v", typ, value)
+		}
+	}
+	if len(params.bindErrors) != 4 || params.bindErrors[0].name != "bad" || params.bindErrors[0].message != "Must be a duration" {
+		t.Errorf("Expected a bind error for each invalid value, got %v", params.bindErrors)
+	}
+
+	Unbind(output, "duration", 90*time.Minute)
+	Unbind(output, "addr", netip.MustParseAddr("::1"))
+	Unbind(output, "int", big.NewInt(42))
+	if output["duration"] != "1h30m0s" || output["addr"] != "::1" || output["int"] != "42" {
+		t.Errorf("Unexpected unbound values %v", output)
+	}
+}
+
+type taggedFilter struct {
+	UserID int      `param:"user_id"`
+	Limit  int      `binding:"query" default:"10"`
+	Page   int      `default:"1"`
+	Token  string   `param:"-"`
+	Tags   []string `param:"tag"`
+	Owner  taggedOwner
+}
+
+type taggedOwner struct {
+	Name string `param:"owner_name"`
+}
+
+func TestTaggedStructBinder(t *testing.T) {
+	params := &Params{
+		Query: map[string][]string{"filter.Limit": {"25"}},
+		Form:  map[string][]string{"filter.Limit": {"50"}},
+		Values: map[string][]string{
+			"user_id":                 {"12"},
+			"filter.Limit":            {"50"},
+			"filter.Token":            {"secret"},
+			"filter.tag[0]":           {"a"},
+			"filter.tag[1]":           {"b"},
+			"filter.Owner.owner_name": {"Jane"},
+			"filter.Owner.Name":       {"ignored"},
+		},
+	}
+	filter := Bind(params, "filter", reflect.TypeOf(taggedFilter{})).Interface().(taggedFilter)
+	expected := taggedFilter{UserID: 12, Limit: 25, Page: 1, Tags: []string{"a", "b"}, Owner: taggedOwner{Name: "Jane"}}
+	if !reflect.DeepEqual(filter, expected) {
+		t.Errorf("Expected %#v got %#v", expected, filter)
+	}
+
+	// The bare parameter is used by an action argument only, and the default of a missing query
+	params = &Params{Values: map[string][]string{"user_id": {"12"}, "filters[0].UserID": {"3"}}}
+	filters := Bind(params, "filters", reflect.TypeOf([]taggedFilter{})).Interface().([]taggedFilter)
+	if len(filters) != 1 || filters[0].UserID != 0 || filters[0].Limit != 10 {
+		t.Errorf("Expected a filter bound from its own parameters, got %#v", filters)
+	}
+}
+
+func TestTaggedStructBodyBinder(t *testing.T) {
+	// The fields never bound are not taken from the body, the defaults fill the empty fields
+	params := &Params{JSON: []byte(`{"UserID": 12, "Token": "forged", "Page": 3}`)}
+	filter := Bind(params, "filter", reflect.TypeOf(taggedFilter{})).Interface().(taggedFilter)
+	expected := taggedFilter{UserID: 12, Limit: 10, Page: 3}
+	if !reflect.DeepEqual(filter, expected) {
+		t.Errorf("Expected %#v got %#v", expected, filter)
+	}
+
+	// The header and cookie fields are bound from the request only
+	req, _ := http.NewRequest("POST", "/", strings.NewReader(`{"Trace": "forged", "Theme": "forged"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Request-Id", "r-42")
+	c := NewTestController(nil, req)
+	ParseParams(c.Params, c.Request)
+	if meta := Bind(c.Params, "meta", reflect.TypeOf(requestMeta{})).Interface().(requestMeta); meta != (requestMeta{Trace: "r-42"}) {
+		t.Errorf("Expected the fields of the request, got %#v", meta)
+	}
+}
+
+type sliceItem struct {
+	Name string
+	Qty  int
+}
+
+func TestStructSliceBinder(t *testing.T) {
+	values := map[string][]string{
+		"items[0].Name": {"apple"},
+		"items[0].Qty":  {"3"},
+		"items[2].Name": {"pear"},
+		"items[2].Qty":  {"1"},
+		"items[x].Name": {"invalid"},
+		"items[99].Qty": {"9"},
+	}
+	defer func(gaps string, maxIndex int) { SliceGaps, SliceMaxIndex = gaps, maxIndex }(SliceGaps, SliceMaxIndex)
+	SliceMaxIndex = 10
+
+	params := &Params{Values: values}
+	items := Bind(params, "items", reflect.TypeOf([]sliceItem{})).Interface().([]sliceItem)
+	if !reflect.DeepEqual(items, []sliceItem{{"apple", 3}, {}, {"pear", 1}}) {
+		t.Errorf("Expected the items with a zero gap got %v", items)
+	}
+	if len(params.bindErrors) != 2 {
+		t.Errorf("Expected bind errors for the invalid indexes, got %v", params.bindErrors)
+	}
+
+	SliceGaps = "compact"
+	params = &Params{Values: values}
+	items = Bind(params, "items", reflect.TypeOf([]sliceItem{})).Interface().([]sliceItem)
+	if !reflect.DeepEqual(items, []sliceItem{{"apple", 3}, {"pear", 1}}) {
+		t.Errorf("Expected the compacted items got %v", items)
+	}
+}
+
+type timedBooking struct {
+	CheckIn time.Time  `time_format:"02/01/2006" time_location:"locale"`
+	Created time.Time  `time_format:"unixmilli"`
+	Updated *time.Time `time_format:"unix"`
+	Expires *time.Time `time_format:"unix"`
+}
+
+func TestTimeBinder(t *testing.T) {
+	loadMessages(testDataPath)
+	params := &Params{Values: map[string][]string{
+		"date":            {"2024-03-01"},
+		"zoned":           {"2024-03-01T10:00:00+02:00"},
+		"epoch":           {"1709287200"},
+		"bad":             {"yesterday"},
+		"booking.CheckIn": {"01/03/2024"},
+		"booking.Created": {"1709287200000"},
+		"booking.Updated": {"1709287200"},
+	}, locale: "nl"}
+	defer func(formats []string) { TimeFormats = formats }(TimeFormats)
+	TimeFormats = []string{time.RFC3339, "2006-01-02"}
+
+	if date := Bind(params, "date", reflect.TypeOf(time.Time{})).Interface().(time.Time); !date.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the date in UTC got %v", date)
+	}
+	if zoned := Bind(params, "zoned", reflect.TypeOf(time.Time{})).Interface().(time.Time); !zoned.Equal(time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the zone of the value to be kept got %v", zoned)
+	}
+	if epoch := Bind(params, "epoch", reflect.TypeOf(time.Time{})).Interface().(time.Time); !epoch.Equal(time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the Unix time got %v", epoch)
+	}
+	if bad := Bind(params, "bad", reflect.TypeOf(time.Time{})).Interface().(time.Time); !bad.IsZero() {
+		t.Errorf("Expected the zero time got %v", bad)
+	}
+	if len(params.bindErrors) != 1 || params.bindErrors[0].name != "bad" || params.bindErrors[0].message != "Must be a valid time" {
+		t.Errorf("Expected a bind error for the invalid time, got %v", params.bindErrors)
+	}
+
+	defaultTimeLocation = "America/New_York"
+	defer func() { defaultTimeLocation = "UTC" }()
+	if date := Bind(params, "date", reflect.TypeOf(time.Time{})).Interface().(time.Time); date.Location().String() != "America/New_York" || date.Hour() != 0 {
+		t.Errorf("Expected the date in New York got %v", date)
+	}
+
+	amsterdam, _ := time.LoadLocation("Europe/Amsterdam")
+	booking := Bind(params, "booking", reflect.TypeOf(timedBooking{})).Interface().(timedBooking)
+	if !booking.CheckIn.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, amsterdam)) {
+		t.Errorf("Expected the check in in the location of the locale got %v", booking.CheckIn)
+	}
+	if !booking.Created.Equal(time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)) || booking.Updated == nil || !booking.Updated.Equal(booking.Created) {
+		t.Errorf("Expected the epoch times got %v %v", booking.Created, booking.Updated)
+	}
+	if booking.Expires != nil {
+		t.Errorf("Expected no expiry got %v", booking.Expires)
+	}
+}
+
+type mapAddress struct {
+	Street string
+	Zip    int
+}
+
+func TestMapBinder(t *testing.T) {
+	params := &Params{Values: map[string][]string{
+		"attrs[color]":        {"red"},
+		"attrs[size.label]":   {"XL"},
+		"counts[apples]":      {"3"},
+		"addr[home].Street":   {"Main"},
+		"addr[home].Zip":      {"123"},
+		"addr[work].Street":   {"Market"},
+		"tags[color][0]":      {"red"},
+		"tags[color][1]":      {"blue"},
+		"ids[4]":              {"2"},
+		"ids[four]":           {"1"},
+		"nested[a][b]":        {"c"},
+		"attrs":               {"ignored"},
+		"attributes[ignored]": {"x"},
+	}}
+	for name, expected := range map[string]interface{}{
+		"attrs":  map[string]string{"color": "red", "size.label": "XL"},
+		"counts": map[string]int{"apples": 3},
+		"addr":   map[string]mapAddress{"home": {"Main", 123}, "work": {"Market", 0}},
+		"tags":   map[string][]string{"color": {"red", "blue"}},
+		"ids":    map[int]int{4: 2},
+		"nested": map[string]map[string]string{"a": {"b": "c"}},
+		"none":   map[string]string{},
+	} {
+		if actual := Bind(params, name, reflect.TypeOf(expected)).Interface(); !reflect.DeepEqual(actual, expected) {
+			t.Errorf("Expected %v for %s got %v", expected, name, actual)
+		}
+	}
+	if len(params.bindErrors) != 1 || params.bindErrors[0].name != "ids[four]" {
+		t.Errorf("Expected a bind error for the invalid key, got %v", params.bindErrors)
+	}
+}
+
+type orderStatus int
+
+func (s *orderStatus) UnmarshalText(text []byte) error {
+	for i, name := range []string{"pending", "paid"} {
+		if string(text) == name {
+			*s = orderStatus(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown status %q", text)
+}
+
+func (s orderStatus) MarshalText() ([]byte, error) {
+	return []byte([]string{"pending", "paid"}[s]), nil
+}
+
+type orderPriority string
+
+func (p *orderPriority) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*p = orderPriority(strings.ToUpper(s))
+	return nil
+}
+
+type orderID int
+
+func TestUnmarshalerBinders(t *testing.T) {
+	params := &Params{Values: map[string][]string{
+		"status":   {"paid"},
+		"priority": {"high"},
+		"quoted":   {`"low"`},
+		"bad":      {"refunded"},
+	}}
+	if status := Bind(params, "status", reflect.TypeOf(orderStatus(0))).Interface(); status != orderStatus(1) {
+		t.Errorf("Expected the paid status got %v", status)
+	}
+	if status := Bind(params, "status", reflect.TypeOf((*orderStatus)(nil))).Interface().(*orderStatus); *status != orderStatus(1) {
+		t.Errorf("Expected a pointer to the paid status got %v", status)
+	}
+	if priority := Bind(params, "priority", reflect.TypeOf(orderPriority(""))).Interface(); priority != orderPriority("HIGH") {
+		t.Errorf("Expected the HIGH priority got %v", priority)
+	}
+	if priority := Bind(params, "quoted", reflect.TypeOf(orderPriority(""))).Interface(); priority != orderPriority("LOW") {
+		t.Errorf("Expected the LOW priority got %v", priority)
+	}
+	if status := Bind(params, "bad", reflect.TypeOf(orderStatus(0))).Interface(); status != orderStatus(0) {
+		t.Errorf("Expected the zero status got %v", status)
+	}
+	if len(params.bindErrors) != 1 || params.bindErrors[0].name != "bad" || params.bindErrors[0].message != "Must be a valid orderStatus" {
+		t.Errorf("Expected a bind error for the invalid status, got %v", params.bindErrors)
+	}
+	output := map[string]string{}
+	if Unbind(output, "status", orderStatus(1)); output["status"] != "paid" {
+		t.Errorf("Expected the status to round trip, got %s", output["status"])
+	}
+
+	// A registered binder replaces the built-in binders
+	RegisterBinder(reflect.TypeOf(orderID(0)), Binder{
+		Bind: ValueBinder(func(val string, typ reflect.Type) reflect.Value {
+			id, _ := strconv.Atoi(strings.TrimPrefix(val, "order-"))
+			return reflect.ValueOf(orderID(id))
+		}),
+	})
+	defer delete(registeredBinders, reflect.TypeOf(orderID(0)))
+	params.Values["id"] = []string{"order-42"}
+	if id := Bind(params, "id", reflect.TypeOf(orderID(0))).Interface(); id != orderID(42) {
+		t.Errorf("Expected the registered binder to bind 42 got %v", id)
+	}
+}
+
+// A fixed-point decimal with 2 digits, like the decimal packages
+type Decimal struct {
+	cents int64
+}
+
+func (d *Decimal) UnmarshalText(text []byte) error {
+	r, ok := new(big.Rat).SetString(string(text))
+	if !ok {
+		return fmt.Errorf("invalid decimal %q", text)
+	}
+	cents := new(big.Rat).Mul(r, big.NewRat(100, 1))
+	if !cents.IsInt() {
+		return fmt.Errorf("invalid decimal %q", text)
+	}
+	d.cents = cents.Num().Int64()
+	return nil
+}
+
+func (d Decimal) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("%d.%02d", d.cents/100, d.cents%100)), nil
+}
+
+func TestNetAndDecimalBinders(t *testing.T) {
+	params := &Params{Values: map[string][]string{
+		"ip":      {"192.168.0.1"},
+		"network": {"10.0.0.0/8"},
+		"rat":     {"12.50"},
+		"price":   {"12.50"},
+		"int":     {"42"},
+		"bad":     {"twelve"},
+	}}
+	if ip := Bind(params, "ip", reflect.TypeOf(net.IP{})).Interface().(net.IP); !ip.Equal(net.ParseIP("192.168.0.1")) {
+		t.Errorf("Expected 192.168.0.1 got %v", ip)
+	}
+	if network := Bind(params, "network", reflect.TypeOf(&net.IPNet{})).Interface().(*net.IPNet); network.String() != "10.0.0.0/8" {
+		t.Errorf("Expected 10.0.0.0/8 got %v", network)
+	}
+	if rat := Bind(params, "rat", reflect.TypeOf(big.Rat{})).Interface().(big.Rat); rat.Cmp(big.NewRat(25, 2)) != 0 {
+		t.Errorf("Expected 25/2 got %v", rat.String())
+	}
+	if price := Bind(params, "price", reflect.TypeOf(Decimal{})).Interface().(Decimal); price.cents != 1250 {
+		t.Errorf("Expected 1250 cents got %v", price.cents)
+	}
+	if i := Bind(params, "int", reflect.TypeOf(&big.Int{})).Interface().(*big.Int); i.Int64() != 42 {
+		t.Errorf("Expected 42 got %v", i)
+	}
+
+	for _, typ := range []reflect.Type{reflect.TypeOf(net.IP{}), reflect.TypeOf(net.IPNet{}), reflect.TypeOf(big.Rat{}), reflect.TypeOf(Decimal{})} {
+		Bind(params, "bad", typ)
+	}
+	messages := []string{}
+	for _, err := range params.bindErrors {
+		messages = append(messages, err.message)
+	}
+	if !reflect.DeepEqual(messages, []string{"Must be an IP address", "Must be an IP network", "Must be a decimal number", "Must be a valid Decimal"}) {
+		t.Errorf("Unexpected bind errors %v", messages)
+	}
+
+	output := map[string]string{}
+	Unbind(output, "ip", net.ParseIP("::1"))
+	Unbind(output, "rat", *big.NewRat(25, 2))
+	Unbind(output, "third", *big.NewRat(1, 3))
+	Unbind(output, "eighth", *big.NewRat(3, 40))
+	Unbind(output, "price", Decimal{1250})
+	Unbind(output, "int", big.NewInt(42))
+	if !reflect.DeepEqual(output, map[string]string{"ip": "::1", "rat": "12.5", "third": "1/3", "eighth": "0.075", "price": "12.50", "int": "42"}) {
+		t.Errorf("Unexpected unbound values %v", output)
+	}
+}
+
 func valEq(t *testing.T, name string, actual, expected reflect.Value) {
 	switch expected.Kind() {
 	case reflect.Slice:
@@ -0,0 +1,118 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The times are parsed with the TimeFormats (format.datetime and format.date), then as
+// Unix seconds. A value without a zone is in the location of app.conf
+//   params.time.location = UTC     # UTC, Local, an IANA zone (Europe/Paris), or locale
+// The locale location is the time.location message of the locale of the request
+// (messages/app.fr: time.location = Europe/Paris), UTC when it has none.
+// The format and the location of a struct field are set by its tags, the format may be
+// unix or unixmilli for the epoch integers
+//   type Booking struct {
+//   	CheckIn  time.Time `time_format:"2006-01-02" time_location:"locale"`
+//   	Created  time.Time `time_format:"unix"`
+//   }
+// A time which cannot be parsed binds the zero time and is a validation error.
+
+// The formats of the Unix epoch integers
+const (
+	TimeFormatUnix      = "unix"
+	TimeFormatUnixMilli = "unixmilli"
+)
+
+// The locations loaded by name
+var timeLocations sync.Map
+
+// The location of the values without a zone, set by params.time.location
+var defaultTimeLocation = "UTC"
+
+func init() {
+	OnAppStart(func() {
+		defaultTimeLocation = Config.StringDefault("params.time.location", "UTC")
+	})
+}
+
+// Binds the time using the formats, or TimeFormats and the Unix seconds when nil, in the
+// location, or params.time.location when empty
+func bindTime(params *Params, name string, typ reflect.Type, formats []string, location string) reflect.Value {
+	return parsedValueBinder("a valid time", func(val string, typ reflect.Type) (reflect.Value, error) {
+		t, err := parseTime(val, formats, params.timeLocation(location))
+		return reflect.ValueOf(t), err
+	})(params, name, typ)
+}
+
+// Parses the time with the first format which matches, the last error is returned when
+// none matches
+func parseTime(val string, formats []string, location *time.Location) (t time.Time, err error) {
+	if formats == nil {
+		formats = append(append([]string{}, TimeFormats...), TimeFormatUnix)
+	}
+	err = &LiteralError{Value: val, Reason: "no time format"}
+	for _, format := range formats {
+		switch format {
+		case TimeFormatUnix, TimeFormatUnixMilli:
+			var epoch int64
+			if epoch, err = strconv.ParseInt(val, 10, 64); err != nil {
+				continue
+			}
+			if format == TimeFormatUnix {
+				return time.Unix(epoch, 0).In(location), nil
+			}
+			return time.UnixMilli(epoch).In(location), nil
+		default:
+			if t, err = time.ParseInLocation(format, val, location); err == nil {
+				return t, nil
+			}
+		}
+	}
+	return time.Time{}, err
+}
+
+// Returns the location of the name, or of params.time.location when empty
+func (params *Params) timeLocation(name string) *time.Location {
+	if name == "" {
+		name = defaultTimeLocation
+	}
+	switch {
+	case strings.EqualFold(name, "UTC"):
+		return time.UTC
+	case strings.EqualFold(name, "Local"):
+		return time.Local
+	case strings.EqualFold(name, "locale"):
+		if name = localeTimeLocation(params.locale); name == "" {
+			return time.UTC
+		}
+	}
+	if location, found := timeLocations.Load(name); found {
+		return location.(*time.Location)
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		binderLog.Error("Unknown time location, using UTC", "location", name, "error", err)
+		return time.UTC
+	}
+	timeLocations.Store(name, location)
+	return location
+}
+
+// Returns the time.location message of the locale, empty when it has none
+func localeTimeLocation(locale string) string {
+	language, region := parseLocale(locale)
+	if messageConfig, found := loadedMessages()[language]; found {
+		if value, err := messageConfig.String(region, "time.location"); err == nil {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,373 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"encoding"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/netip"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Binders for the types which often appear in API parameters. An invalid value binds
+// the zero value and the ActionInvoker adds a validation error for the parameter.
+//   time.Duration             - 1h30m (see ParseDuration), a number is in nanoseconds
+//   netip.Addr                - 192.168.0.1 or ::1
+//   netip.Prefix              - 10.0.0.0/8
+//   netip.AddrPort            - 10.0.0.1:80
+//   net.IP                    - 192.168.0.1 or ::1
+//   net.IPNet                 - 10.0.0.0/8
+//   big.Int, big.Float        - 123456789012345678901234567890
+//   big.Rat                   - 12.50 or 25/2
+//   [16]byte types named UUID - 6ba7b810-9dad-11d1-80b4-00c04fd430c8, this covers
+//                               github.com/google/uuid and github.com/gofrs/uuid
+//   revel.Optional[T]         - the value of T, present when sent (see Optional)
+// The pointers to the types are bound as well (*big.Int), the values are unbound to their
+// text for the reverse routes.
+// The types which implement encoding.TextUnmarshaler, and the types other than structs,
+// maps and slices which implement json.Unmarshaler, are bound from their text (a value
+// which is not JSON is decoded as a JSON string), this covers the decimal types such as
+// github.com/shopspring/decimal (12.50). The binder of a domain type is
+// registered, it is used instead of the built-in binders
+//   revel.RegisterBinder(reflect.TypeOf(models.OrderID(0)), orderIDBinder)
+var (
+	DurationBinder = Binder{
+		Bind: parsedValueBinder("a duration", func(val string, typ reflect.Type) (reflect.Value, error) {
+			duration, err := ParseDuration(val, time.Nanosecond)
+			return reflect.ValueOf(duration), err
+		}),
+		Unbind: stringerUnbinder,
+	}
+
+	AddrBinder = Binder{
+		Bind: parsedValueBinder("an IP address", func(val string, typ reflect.Type) (reflect.Value, error) {
+			addr, err := netip.ParseAddr(val)
+			return reflect.ValueOf(addr), err
+		}),
+		Unbind: stringerUnbinder,
+	}
+
+	PrefixBinder = Binder{
+		Bind: parsedValueBinder("an IP prefix", func(val string, typ reflect.Type) (reflect.Value, error) {
+			prefix, err := netip.ParsePrefix(val)
+			return reflect.ValueOf(prefix), err
+		}),
+		Unbind: stringerUnbinder,
+	}
+
+	AddrPortBinder = Binder{
+		Bind: parsedValueBinder("an IP address and port", func(val string, typ reflect.Type) (reflect.Value, error) {
+			addrPort, err := netip.ParseAddrPort(val)
+			return reflect.ValueOf(addrPort), err
+		}),
+		Unbind: stringerUnbinder,
+	}
+
+	BigIntBinder = Binder{
+		Bind: parsedValueBinder("an integer", func(val string, typ reflect.Type) (reflect.Value, error) {
+			i, ok := new(big.Int).SetString(val, 10)
+			if !ok {
+				return reflect.Value{}, errors.New("invalid integer")
+			}
+			return reflect.ValueOf(i).Elem(), nil
+		}),
+		Unbind: func(output map[string]string, name string, val interface{}) {
+			i := val.(big.Int)
+			output[name] = i.String()
+		},
+	}
+
+	BigFloatBinder = Binder{
+		Bind: parsedValueBinder("a number", func(val string, typ reflect.Type) (reflect.Value, error) {
+			f, _, err := big.ParseFloat(val, 10, 0, big.ToNearestEven)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			return reflect.ValueOf(f).Elem(), nil
+		}),
+		Unbind: func(output map[string]string, name string, val interface{}) {
+			f := val.(big.Float)
+			output[name] = f.Text('g', -1)
+		},
+	}
+
+	IPBinder = Binder{
+		Bind: parsedValueBinder("an IP address", func(val string, typ reflect.Type) (reflect.Value, error) {
+			ip := net.ParseIP(val)
+			if ip == nil {
+				return reflect.Value{}, errors.New("invalid IP address")
+			}
+			return reflect.ValueOf(ip), nil
+		}),
+		Unbind: stringerUnbinder,
+	}
+
+	IPNetBinder = Binder{
+		Bind: parsedValueBinder("an IP network", func(val string, typ reflect.Type) (reflect.Value, error) {
+			_, ipNet, err := net.ParseCIDR(val)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			return reflect.ValueOf(ipNet).Elem(), nil
+		}),
+		Unbind: func(output map[string]string, name string, val interface{}) {
+			ipNet := val.(net.IPNet)
+			output[name] = ipNet.String()
+		},
+	}
+
+	BigRatBinder = Binder{
+		Bind: parsedValueBinder("a decimal number", func(val string, typ reflect.Type) (reflect.Value, error) {
+			r, ok := new(big.Rat).SetString(val)
+			if !ok {
+				return reflect.Value{}, errors.New("invalid number")
+			}
+			return reflect.ValueOf(r).Elem(), nil
+		}),
+		Unbind: func(output map[string]string, name string, val interface{}) {
+			r := val.(big.Rat)
+			if r.IsInt() {
+				output[name] = r.Num().String()
+				return
+			}
+			// The exact decimal, or the fraction when it has none
+			if precision, exact := decimalPrecision(r.Denom()); exact {
+				output[name] = r.FloatString(precision)
+				return
+			}
+			output[name] = r.String()
+		},
+	}
+
+	UUIDBinder = Binder{
+		Bind: parsedValueBinder("a UUID", func(val string, typ reflect.Type) (reflect.Value, error) {
+			var uuid [16]byte
+			val = strings.TrimPrefix(strings.ToLower(val), "urn:uuid:")
+			if len(val) == 38 && val[0] == '{' && val[37] == '}' {
+				val = val[1:37]
+			}
+			if len(val) == 36 && val[8] == '-' && val[13] == '-' && val[18] == '-' && val[23] == '-' {
+				val = strings.Replace(val, "-", "", -1)
+			}
+			if len(val) != 32 {
+				return reflect.Value{}, errors.New("invalid UUID length")
+			}
+			if _, err := hex.Decode(uuid[:], []byte(val)); err != nil {
+				return reflect.Value{}, err
+			}
+			return reflect.ValueOf(uuid), nil
+		}),
+		Unbind: func(output map[string]string, name string, val interface{}) {
+			var uuid [16]byte
+			reflect.Copy(reflect.ValueOf(uuid[:]), reflect.ValueOf(val))
+			s := hex.EncodeToString(uuid[:])
+			output[name] = s[:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+		},
+	}
+)
+
+func init() {
+	TypeBinders[reflect.TypeOf(time.Duration(0))] = DurationBinder
+	TypeBinders[reflect.TypeOf(netip.Addr{})] = AddrBinder
+	TypeBinders[reflect.TypeOf(netip.Prefix{})] = PrefixBinder
+	TypeBinders[reflect.TypeOf(netip.AddrPort{})] = AddrPortBinder
+	TypeBinders[reflect.TypeOf(big.Int{})] = BigIntBinder
+	TypeBinders[reflect.TypeOf(big.Float{})] = BigFloatBinder
+	TypeBinders[reflect.TypeOf(big.Rat{})] = BigRatBinder
+	TypeBinders[reflect.TypeOf(net.IP{})] = IPBinder
+	TypeBinders[reflect.TypeOf(net.IPNet{})] = IPNetBinder
+}
+
+// The binders registered by the application, consulted before the built-in binders
+var registeredBinders = map[reflect.Type]Binder{}
+
+// RegisterBinder registers the binder of the type, it replaces the built-in binder of the
+// type. The binders are registered before the controllers (in an init function), the
+// binders of the action arguments are resolved when the controllers are registered.
+func RegisterBinder(typ reflect.Type, binder Binder) {
+	registeredBinders[typ] = binder
+}
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	jsonMarshalerType   = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// Returns the binder of a type which unmarshals itself from its text or its JSON, a pointer
+// is bound by the PointerBinder
+func unmarshalerBinder(typ reflect.Type) (binder Binder, ok bool) {
+	if typ.Kind() == reflect.Ptr {
+		return
+	}
+	pointerType := reflect.PtrTo(typ)
+	description := "a valid " + typ.Name()
+	jsonKind := typ.Kind() != reflect.Struct && typ.Kind() != reflect.Map && typ.Kind() != reflect.Slice
+	switch {
+	case pointerType.Implements(textUnmarshalerType):
+		binder.Bind = parsedValueBinder(description, func(val string, typ reflect.Type) (reflect.Value, error) {
+			value := reflect.New(typ)
+			err := value.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(val))
+			return value.Elem(), err
+		})
+	case pointerType.Implements(jsonUnmarshalerType) && jsonKind:
+		binder.Bind = parsedValueBinder(description, func(val string, typ reflect.Type) (reflect.Value, error) {
+			data := []byte(val)
+			if !json.Valid(data) {
+				data, _ = json.Marshal(val)
+			}
+			value := reflect.New(typ)
+			err := value.Interface().(json.Unmarshaler).UnmarshalJSON(data)
+			return value.Elem(), err
+		})
+	default:
+		return
+	}
+	binder.Unbind = unmarshalerUnbinder
+	return binder, true
+}
+
+// Unbinds a value using its MarshalText or MarshalJSON method
+func unmarshalerUnbinder(output map[string]string, name string, val interface{}) {
+	value := reflect.ValueOf(val)
+	pointer := reflect.New(value.Type())
+	pointer.Elem().Set(value)
+	var err error
+	switch {
+	case pointer.Type().Implements(textMarshalerType):
+		var text []byte
+		if text, err = pointer.Interface().(encoding.TextMarshaler).MarshalText(); err == nil {
+			output[name] = string(text)
+		}
+	case pointer.Type().Implements(jsonMarshalerType):
+		var data []byte
+		if data, err = pointer.Interface().(json.Marshaler).MarshalJSON(); err == nil {
+			var s string
+			if json.Unmarshal(data, &s) == nil {
+				output[name] = s
+			} else {
+				output[name] = string(data)
+			}
+		}
+	default:
+		output[name] = fmt.Sprint(val)
+	}
+	if err != nil {
+		binderLog.Error("Unbind: Unable to marshal the value", "name", name, "error", err)
+	}
+}
+
+// A bind error for a parameter, added to the validation errors by the ActionInvoker
+type bindError struct {
+	name    string
+	message string
+}
+
+// The bind errors are keyed by the path of the parameter (booking.Nights, items[2].Qty), the
+// action checks c.Validation.HasErrors(). The ActionInvoker rejects the request with a 400
+// before the action runs when set in app.conf
+//   params.bind.reject = api   # false by default, api for the requests of another format than html, or true
+// A client accepting JSON gets the problem details with the errors of the parameters
+//   {"title": "Invalid parameters", "status": 400, "errors": [{"field": "booking.Nights", "message": "Must be an integer"}]}
+// The errors have the shape of the errors of the validation problem.
+
+// Returns true if the bind errors of the request are rejected
+func rejectsBindErrors(c *Controller) bool {
+	switch Config.StringDefault("params.bind.reject", "false") {
+	case "true":
+		return true
+	case "api":
+		return c.Request.Format != "html"
+	}
+	return false
+}
+
+// Returns the problem of the bind errors, listed in the order they were found, the first
+// error of a parameter is kept
+func bindErrorsProblem(bindErrors []*bindError) *Problem {
+	found := make(map[string]bool, len(bindErrors))
+	fields := make([]map[string]string, 0, len(bindErrors))
+	details := make([]string, 0, len(bindErrors))
+	for _, err := range bindErrors {
+		if !found[err.name] {
+			found[err.name] = true
+			fields = append(fields, map[string]string{"field": err.name, "message": err.message})
+			details = append(details, err.name+": "+err.message)
+		}
+	}
+	return &Problem{
+		Title:      "Invalid parameters",
+		Status:     http.StatusBadRequest,
+		Detail:     strings.Join(details, ", "),
+		Extensions: map[string]interface{}{"errors": fields},
+	}
+}
+
+// parsedValueBinder is like ValueBinder for a parser which may fail, an empty value binds
+// the zero value and a value which fails to parse is recorded as a bind error
+func parsedValueBinder(description string, parse func(value string, typ reflect.Type) (reflect.Value, error)) func(*Params, string, reflect.Type) reflect.Value {
+	return func(params *Params, name string, typ reflect.Type) reflect.Value {
+		vals, ok := params.Values[name]
+		if !ok || len(vals) == 0 || vals[0] == "" {
+			return reflect.Zero(typ)
+		}
+		parsed, err := parse(strings.TrimSpace(vals[0]), typ)
+		if err != nil {
+			binderLog.Warn("Invalid parameter value", "name", name, "type", typ, "error", err)
+			params.bindErrors = append(params.bindErrors, &bindError{name, "Must be " + description})
+			return reflect.Zero(typ)
+		}
+		value := reflect.New(typ).Elem()
+		value.Set(parsed.Convert(typ))
+		return value
+	}
+}
+
+// Unbinds a value using its String method
+func stringerUnbinder(output map[string]string, name string, val interface{}) {
+	output[name] = val.(interface {
+		String() string
+	}).String()
+}
+
+// Returns the number of decimal digits of a fraction with the denominator, exact is false
+// when the fraction has no finite decimal (the denominator has a prime factor other than 2
+// and 5)
+func decimalPrecision(denominator *big.Int) (precision int, exact bool) {
+	d := new(big.Int).Set(denominator)
+	twos := int(d.TrailingZeroBits())
+	d.Rsh(d, uint(twos))
+	five, remainder := big.NewInt(5), new(big.Int)
+	fives := 0
+	for {
+		quotient, mod := new(big.Int).QuoRem(d, five, remainder)
+		if mod.Sign() != 0 {
+			break
+		}
+		d = quotient
+		fives++
+	}
+	if d.Cmp(big.NewInt(1)) != 0 {
+		return 0, false
+	}
+	if twos > fives {
+		return twos, true
+	}
+	return fives, true
+}
+
+// Returns true for the UUID types of the popular uuid packages, which are [16]byte
+func isUUIDType(typ reflect.Type) bool {
+	return typ.Kind() == reflect.Array && typ.Len() == 16 && typ.Elem().Kind() == reflect.Uint8 && typ.Name() == "UUID"
+}
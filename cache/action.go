@@ -0,0 +1,43 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"time"
+
+	"github.com/revel/revel"
+)
+
+// actionCache stores the results of the actions annotated with @Cache in the
+// configured cache (memory, memcached or redis)
+type actionCache struct{}
+
+func init() {
+	revel.ActionCache = actionCache{}
+}
+
+func (actionCache) Get(key string, ptrValue interface{}) error {
+	return Get(key, ptrValue)
+}
+
+func (actionCache) Set(key string, value interface{}, expires time.Duration) error {
+	return Set(key, value, expires)
+}
+
+func (actionCache) Delete(key string) error {
+	return Delete(key)
+}
+
+func (actionCache) SetWithTags(key string, value interface{}, expires time.Duration, tags ...string) error {
+	if err := SetWithTags(key, value, expires, tags...); err != ErrTagsNotSupported {
+		return err
+	}
+	// The results are not tagged in memcached, they expire with their ttl
+	return Set(key, value, expires)
+}
+
+func (actionCache) InvalidateTags(tags ...string) error {
+	return InvalidateTags(tags...)
+}
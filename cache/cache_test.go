@@ -250,3 +250,66 @@ func testGetMulti(t *testing.T, newCache cacheFactory) {
 		t.Errorf("Error getting foo: %s / %v", err, foo)
 	}
 }
+
+func testTags(t *testing.T, newCache cacheFactory) {
+	cache := newCache(t, time.Hour).(TagCache)
+
+	if err := cache.SetWithTags("profile", "foo", time.Minute, "user:42", "org:7"); err != nil {
+		t.Fatalf("Error setting a value: %s", err)
+	}
+	if err := cache.SetWithTags("members", "bar", time.Minute, "org:7"); err != nil {
+		t.Fatalf("Error setting a value: %s", err)
+	}
+	if err := cache.SetWithTags("settings", "baz", time.Minute, "org:8"); err != nil {
+		t.Fatalf("Error setting a value: %s", err)
+	}
+
+	if err := cache.InvalidateTags("org:7"); err != nil {
+		t.Fatalf("Error invalidating a tag: %s", err)
+	}
+	var value string
+	for _, key := range []string{"profile", "members"} {
+		if err := cache.(Cache).Get(key, &value); err != ErrCacheMiss {
+			t.Errorf("Expected %s to be invalidated, got %v", key, err)
+		}
+	}
+	if err := cache.(Cache).Get("settings", &value); err != nil || value != "baz" {
+		t.Errorf("Expected the value of another tag to be kept, got %s / %v", value, err)
+	}
+
+	// An invalidated key set again is not removed by its old tags
+	if err := cache.(Cache).Set("profile", "foo", time.Minute); err != nil {
+		t.Fatalf("Error setting a value: %s", err)
+	}
+	if err := cache.InvalidateTags("user:42"); err != nil {
+		t.Fatalf("Error invalidating a tag: %s", err)
+	}
+	if err := cache.(Cache).Get("profile", &value); err != nil {
+		t.Errorf("Expected the value set without tags to be kept, got %v", err)
+	}
+}
+
+// Test the rate limit buckets kept in the cache
+func testRateLimit(t *testing.T, newCache cacheFactory) {
+	defer func(instance Cache) { Instance = instance }(Instance)
+	Instance = newCache(t, time.Hour)
+	limiter := &rateLimiter{}
+
+	for expected := 2; expected >= 0; expected-- {
+		if allowed, remaining, _ := limiter.Take("ratelimit:user", 3, time.Minute); !allowed || remaining != expected {
+			t.Errorf("Expected the request to be allowed with %d remaining, got %v / %d", expected, allowed, remaining)
+		}
+	}
+	allowed, remaining, reset := limiter.Take("ratelimit:user", 3, time.Minute)
+	if allowed || remaining != 0 {
+		t.Errorf("Expected the request to be limited, got %v / %d", allowed, remaining)
+	}
+	if reset <= 0 || reset > 20*time.Second {
+		t.Errorf("Expected the next token within 20s, got %v", reset)
+	}
+
+	// Another key has its own bucket
+	if allowed, _, _ := limiter.Take("ratelimit:other", 3, time.Minute); !allowed {
+		t.Error("Expected the request of another key to be allowed")
+	}
+}
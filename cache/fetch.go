@@ -0,0 +1,143 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/revel/revel"
+)
+
+// Fetch and FetchAs return the value of a key, loaded when the key is not in the cache
+//   hotels, err := cache.FetchAs("hotels:popular", time.Minute, func() ([]models.Hotel, error) {
+//   	return models.PopularHotels(db)
+//   })
+// The concurrent requests of a missing key wait for the same load (only one request loads it
+// in the process). A key is loaded again in the background before it expires, the sooner the
+// longer it took to load (a probabilistic early refresh), so a hot key does not expire for
+// all the requests at once
+//   cache.fetch.beta = 1.0    # 0 disables the early refresh, above 1 refreshes earlier
+// The values are encoded by the DefaultCodec, the types of the values of Fetch are registered
+// by gob.Register with the gob codec.
+
+// The factor of the early refresh
+var fetchBeta = 1.0
+
+func init() {
+	revel.OnAppStart(func() {
+		fetchBeta = revel.Config.FloatDefault("cache.fetch.beta", 1.0)
+	})
+}
+
+// The value of a key fetched, with the time it took to load and its expiration
+type fetchEntry[T any] struct {
+	Value   T
+	Delta   time.Duration
+	Expires time.Time
+}
+
+// Fetch returns the value of the key, loaded and set for the ttl when it is not in the cache
+func Fetch(key string, ttl time.Duration, loader func() (interface{}, error)) (interface{}, error) {
+	return FetchAs(key, ttl, loader)
+}
+
+// FetchAs returns the value of the key, loaded and set for the ttl when it is not in the
+// cache
+func FetchAs[T any](key string, ttl time.Duration, loader func() (T, error)) (value T, err error) {
+	load := func() (interface{}, error) {
+		start := time.Now()
+		value, err := loader()
+		if err != nil {
+			return value, err
+		}
+		now := time.Now()
+		entry := fetchEntry[T]{Value: value, Delta: now.Sub(start), Expires: now.Add(ttl)}
+		if err := SetAs(key, entry, ttl); err != nil {
+			cacheLog.Error("Fetch: Failed to set the value", "key", key, "error", err)
+		}
+		return value, nil
+	}
+
+	entry, err := GetAs[fetchEntry[T]](key)
+	if err == nil {
+		if refreshEarly(entry.Delta, entry.Expires, time.Now()) {
+			go func() {
+				if _, err, _ := fetches.do(key, load); err != nil {
+					cacheLog.Warn("Fetch: Failed to refresh the value", "key", key, "error", err)
+				}
+			}()
+		}
+		return entry.Value, nil
+	} else if err != ErrCacheMiss {
+		cacheLog.Warn("Fetch: Failed to get the value, it is loaded", "key", key, "error", err)
+	}
+
+	loaded, err, _ := fetches.do(key, load)
+	if typed, ok := loaded.(T); ok {
+		value = typed
+	} else if loaded != nil && err == nil {
+		// The key was loaded by a Fetch of another type
+		err = fmt.Errorf("revel/cache: the value of %s is a %T: %w", key, loaded, ErrInvalidValue)
+	}
+	return value, err
+}
+
+// Returns true if the value which took delta to load and expires at expires is loaded again,
+// the probability increases as it gets closer to its expiration (XFetch)
+func refreshEarly(delta time.Duration, expires, now time.Time) bool {
+	if fetchBeta <= 0 || delta <= 0 || expires.IsZero() {
+		return false
+	}
+	early := time.Duration(-float64(delta) * fetchBeta * math.Log(1-rand.Float64()))
+	return !now.Add(early).Before(expires)
+}
+
+// A load of a key in progress
+type fetchCall struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// The loads of the keys in progress in the process
+type fetchGroup struct {
+	sync.Mutex
+	calls map[string]*fetchCall
+}
+
+var fetches = &fetchGroup{calls: map[string]*fetchCall{}}
+
+// Runs the load of the key, or waits for the load of the key in progress, shared is true if
+// the value was loaded by another call. A panic of the load is returned as the error of the
+// calls.
+func (g *fetchGroup) do(key string, load func() (interface{}, error)) (value interface{}, err error, shared bool) {
+	g.Lock()
+	if call, found := g.calls[key]; found {
+		g.Unlock()
+		<-call.done
+		return call.value, call.err, true
+	}
+	call := &fetchCall{done: make(chan struct{})}
+	g.calls[key] = call
+	g.Unlock()
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			call.value, call.err = nil, fmt.Errorf("revel/cache: the load of %s panicked: %v", key, recovered)
+			value, err = call.value, call.err
+			cacheLog.Error("Fetch: The load panicked", "key", key, "error", recovered)
+		}
+		g.Lock()
+		delete(g.calls, key)
+		g.Unlock()
+		close(call.done)
+	}()
+	call.value, call.err = load()
+	return call.value, call.err, false
+}
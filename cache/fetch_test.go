@@ -0,0 +1,123 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFetch(t *testing.T) {
+	defer func(instance Cache) { Instance = instance }(Instance)
+	Instance = NewInMemoryCache(time.Hour)
+
+	var loads int32
+	release := make(chan struct{})
+	loader := func() ([]string, error) {
+		atomic.AddInt32(&loads, 1)
+		<-release
+		return []string{"a", "b"}, nil
+	}
+
+	// The concurrent requests of a missing key wait for one load
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if value, err := FetchAs("letters", time.Minute, loader); err != nil || len(value) != 2 {
+				t.Errorf("Expected the loaded value, got %v %v", value, err)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if loads != 1 {
+		t.Errorf("Expected one load, got %d", loads)
+	}
+
+	// The value is in the cache
+	if value, err := FetchAs("letters", time.Minute, loader); err != nil || len(value) != 2 || loads != 1 {
+		t.Errorf("Expected the cached value, got %v %v after %d loads", value, err, loads)
+	}
+
+	// The errors of the loader are not cached
+	failing := errors.New("failed")
+	if _, err := Fetch("failing", time.Minute, func() (interface{}, error) { return nil, failing }); err != failing {
+		t.Errorf("Expected the error of the loader, got %v", err)
+	}
+	if err := Instance.Get("failing", new([]byte)); err != ErrCacheMiss {
+		t.Errorf("Expected the failed load to not be cached, got %v", err)
+	}
+}
+
+func TestFetchPanic(t *testing.T) {
+	defer func(instance Cache) { Instance = instance }(Instance)
+	Instance = NewInMemoryCache(time.Hour)
+
+	// The waiting requests get the panic of the load as an error
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := FetchAs("panicking", time.Minute, func() (int, error) {
+				<-release
+				panic("boom")
+			})
+			if err == nil || !strings.Contains(err.Error(), "boom") {
+				t.Errorf("Expected the panic as an error, got %v", err)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if value, err := FetchAs("panicking", time.Minute, func() (int, error) { return 1, nil }); err != nil || value != 1 {
+		t.Errorf("Expected the key to be loaded again, got %v %v", value, err)
+	}
+
+	// A value loaded by a Fetch of another type is an error
+	release = make(chan struct{})
+	go func() {
+		_, _ = FetchAs("typed", time.Minute, func() (int, error) {
+			<-release
+			return 1, nil
+		})
+	}()
+	time.Sleep(20 * time.Millisecond)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+	}()
+	if _, err := FetchAs("typed", time.Minute, func() (string, error) { return "text", nil }); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("Expected the value of another type to be invalid, got %v", err)
+	}
+}
+
+func TestRefreshEarly(t *testing.T) {
+	now := time.Now()
+	if refreshEarly(time.Millisecond, now.Add(time.Hour), now) {
+		t.Error("Expected a value far from its expiration to not be refreshed")
+	}
+	if !refreshEarly(time.Second, now.Add(-time.Second), now) {
+		t.Error("Expected an expired value to be refreshed")
+	}
+	refreshed := 0
+	for i := 0; i < 1000; i++ {
+		if refreshEarly(time.Second, now.Add(time.Second), now) {
+			refreshed++
+		}
+	}
+	if refreshed == 0 || refreshed == 1000 {
+		t.Errorf("Expected some values close to their expiration to be refreshed, got %d", refreshed)
+	}
+}
@@ -19,11 +19,18 @@ func init() {
 		defaultExpiration := time.Hour // The default for the default is one hour.
 		if expireStr, found := revel.Config.String("cache.expires"); found {
 			var err error
-			if defaultExpiration, err = time.ParseDuration(expireStr); err != nil {
+			if defaultExpiration, err = revel.ParseDuration(expireStr, time.Second); err != nil {
 				cacheLog.Panic("Could not parse default cache expiration duration " + expireStr + ": " + err.Error())
 			}
 		}
 
+		codecName := revel.Config.StringDefault("cache.codec", "gob")
+		codec, found := codecs[codecName]
+		if !found {
+			cacheLog.Panic("Unknown cache.codec " + codecName + ", expected gob, json or msgpack")
+		}
+		DefaultCodec = codec
+
 		// make sure you aren't trying to use both memcached and redis
 		if revel.Config.BoolDefault("cache.memcached", false) && revel.Config.BoolDefault("cache.redis", false) {
 			cacheLog.Panic("You've configured both memcached and redis, please only include configuration for one cache!")
@@ -46,11 +53,44 @@ func init() {
 			if len(hosts) == 0 {
 				cacheLog.Panic("Redis enabled but no Redis hosts specified!")
 			}
-			if len(hosts) > 1 {
-				cacheLog.Panic("Redis currently only supports one host!")
-			}
 			password := revel.Config.StringDefault("cache.redis.password", "")
-			Instance = NewRedisCache(hosts[0], password, defaultExpiration)
+			switch mode := revel.Config.StringDefault("cache.redis.mode", "single"); mode {
+			case "single":
+				if len(hosts) > 1 {
+					cacheLog.Panic("Redis only supports one host, unless cache.redis.mode is sentinel or cluster!")
+				}
+				Instance = NewRedisCache(hosts[0], password, defaultExpiration)
+			case "sentinel":
+				master := revel.Config.StringDefault("cache.redis.sentinel.master", "")
+				if master == "" {
+					cacheLog.Panic("Redis sentinel enabled but no cache.redis.sentinel.master specified!")
+				}
+				Instance = NewRedisSentinelCache(hosts, master, password, defaultExpiration)
+			case "cluster":
+				cluster, err := NewRedisClusterCache(hosts, password, defaultExpiration)
+				if err != nil {
+					cacheLog.Panic("Could not read the slots of the Redis cluster", "error", err)
+				}
+				Instance = cluster
+			default:
+				cacheLog.Panic("Unknown cache.redis.mode " + mode + ", expected single, sentinel or cluster")
+			}
+			if revel.Config.BoolDefault("cache.tiered", false) {
+				expires := revel.ConfigDurationDefault("cache.tiered.expires", 5*time.Second, time.Second)
+				channel := revel.Config.StringDefault("cache.tiered.channel", "revel-cache-invalidate")
+				Instance = NewTieredCache(Instance.(RedisCache), expires, channel)
+			}
+			return
+		}
+
+		// Use the memory bounded LRU cache?
+		if revel.Config.BoolDefault("cache.lru", false) {
+			size := revel.ConfigSizeDefault("cache.lru.size", 64<<20, 1<<20)
+			policy := revel.Config.StringDefault("cache.lru.policy", LRUPolicy)
+			if policy != LRUPolicy && policy != ARCPolicy {
+				cacheLog.Panic("Unknown cache.lru.policy " + policy + ", expected lru or arc")
+			}
+			Instance = NewLRUCache(size, policy, defaultExpiration)
 			return
 		}
 
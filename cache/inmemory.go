@@ -15,11 +15,17 @@ import (
 
 type InMemoryCache struct {
 	cache cache.Cache  // Only expose the methods we want to make available
-	mu sync.RWMutex		 // For increment / decrement prevent reads and writes
+	mu    sync.RWMutex // For increment / decrement prevent reads and writes
+	tags  *tagIndex    // The keys of the tags of SetWithTags
 }
 
 func NewInMemoryCache(defaultExpiration time.Duration) InMemoryCache {
-	return InMemoryCache{cache: *cache.New(defaultExpiration, time.Minute), mu: sync.RWMutex{}}
+	c := InMemoryCache{cache: *cache.New(defaultExpiration, time.Minute), mu: sync.RWMutex{}, tags: newTagIndex()}
+	// The expired and deleted keys are removed from their tags
+	c.cache.OnEvicted(func(key string, _ interface{}) {
+		c.tags.remove(key)
+	})
+	return c
 }
 
 func (c InMemoryCache) Get(key string, ptrValue interface{}) error {
@@ -119,6 +125,23 @@ func (c InMemoryCache) Flush() error {
 	defer c.mu.Unlock()
 
 	c.cache.Flush()
+	c.tags.flush()
+	return nil
+}
+
+func (c InMemoryCache) SetWithTags(key string, value interface{}, expires time.Duration, tags ...string) error {
+	if err := c.Set(key, value, expires); err != nil {
+		return err
+	}
+	c.tags.add(key, tags)
+	return nil
+}
+
+func (c InMemoryCache) InvalidateTags(tags ...string) error {
+	for _, key := range c.tags.keysOf(tags) {
+		// Deleting the key removes it from its tags
+		c.cache.Delete(key)
+	}
 	return nil
 }
 
@@ -42,3 +42,11 @@ func TestInMemoryCache_Add(t *testing.T) {
 func TestInMemoryCache_GetMulti(t *testing.T) {
 	testGetMulti(t, newInMemoryCache)
 }
+
+func TestInMemoryCache_Tags(t *testing.T) {
+	testTags(t, newInMemoryCache)
+}
+
+func TestInMemoryCache_RateLimit(t *testing.T) {
+	testRateLimit(t, newInMemoryCache)
+}
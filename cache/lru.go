@@ -0,0 +1,389 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// The LRU cache keeps the values in memory within a budget of bytes, the values are evicted
+// when the budget is used
+//   cache.lru = true
+//   cache.lru.size = 64MB      # The budget, a number is in MB
+//   cache.lru.policy = lru     # lru or arc
+// The values are encoded (by Serialize), the budget is the size of the keys and of the
+// encoded values, with the overhead of an entry. The lru policy evicts the least recently
+// used value, the arc policy (Adaptive Replacement Cache) keeps the values read more than
+// once from being evicted by a scan of values read once. The expired values are removed
+// when they are read, or evicted when they are the least recently used.
+
+// The memory used by an entry, besides its key and value
+const lruEntryOverhead = 64
+
+// The policies of the LRUCache
+const (
+	LRUPolicy = "lru"
+	ARCPolicy = "arc"
+)
+
+// LRUStats are the statistics of an LRUCache
+type LRUStats struct {
+	Items        int
+	Bytes        int64 // The bytes used by the items
+	MaxBytes     int64
+	Hits         uint64
+	Misses       uint64
+	Evictions    uint64 // The items evicted to fit in the budget
+	EvictedBytes uint64
+	Expirations  uint64 // The expired items removed
+}
+
+// An entry of the LRUCache, or the key and size of an entry evicted by the arc policy (a
+// ghost)
+type lruEntry struct {
+	key      string
+	value    []byte
+	expires  time.Time // Zero when the entry never expires
+	size     int64
+	frequent bool // Set when the entry was read more than once (arc)
+}
+
+// A list of entries, the most recently used first
+type lruList struct {
+	entries *list.List
+	bytes   int64
+}
+
+func (l *lruList) pushFront(e *lruEntry) *list.Element {
+	l.bytes += e.size
+	return l.entries.PushFront(e)
+}
+
+func (l *lruList) remove(element *list.Element) *lruEntry {
+	e := l.entries.Remove(element).(*lruEntry)
+	l.bytes -= e.size
+	return e
+}
+
+// LRUCache is a Cache bounded by the bytes of its values
+type LRUCache struct {
+	mu                sync.Mutex
+	defaultExpiration time.Duration
+	maxBytes          int64
+	arc               bool
+
+	items    map[string]*list.Element
+	recent   lruList // The entries read once
+	frequent lruList // The entries read more than once (arc)
+
+	// The entries evicted from the recent and the frequent lists (arc), a key set again after
+	// its eviction adapts the target of the size of the recent list
+	ghosts        map[string]*list.Element
+	ghostRecent   lruList
+	ghostFrequent lruList
+	target        int64
+
+	stats LRUStats
+}
+
+// NewLRUCache returns the LRUCache of the budget of bytes, with the lru or the arc policy
+func NewLRUCache(maxBytes int64, policy string, defaultExpiration time.Duration) *LRUCache {
+	c := &LRUCache{defaultExpiration: defaultExpiration, maxBytes: maxBytes, arc: policy == ARCPolicy}
+	c.reset()
+	return c
+}
+
+func (c *LRUCache) reset() {
+	c.items = map[string]*list.Element{}
+	c.recent = lruList{entries: list.New()}
+	c.frequent = lruList{entries: list.New()}
+	c.ghosts = map[string]*list.Element{}
+	c.ghostRecent = lruList{entries: list.New()}
+	c.ghostFrequent = lruList{entries: list.New()}
+	c.target = 0
+}
+
+// Stats returns the statistics of the cache
+func (c *LRUCache) Stats() LRUStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Items = len(c.items)
+	stats.Bytes = c.recent.bytes + c.frequent.bytes
+	stats.MaxBytes = c.maxBytes
+	return stats
+}
+
+func (c *LRUCache) Get(key string, ptrValue interface{}) error {
+	c.mu.Lock()
+	e := c.entry(key, time.Now())
+	if e == nil {
+		c.stats.Misses++
+		c.mu.Unlock()
+		return ErrCacheMiss
+	}
+	c.stats.Hits++
+	value := e.value
+	c.mu.Unlock()
+	// The stored value is not shared with the caller
+	return Deserialize(append([]byte(nil), value...), ptrValue)
+}
+
+func (c *LRUCache) GetMulti(keys ...string) (Getter, error) {
+	return c, nil
+}
+
+func (c *LRUCache) Set(key string, value interface{}, expires time.Duration) error {
+	data, err := c.serialize(value)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.store(key, data, c.expiresAt(expires, time.Now()))
+}
+
+func (c *LRUCache) Add(key string, value interface{}, expires time.Duration) error {
+	data, err := c.serialize(value)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if c.live(key, now) != nil {
+		return ErrNotStored
+	}
+	return c.store(key, data, c.expiresAt(expires, now))
+}
+
+func (c *LRUCache) Replace(key string, value interface{}, expires time.Duration) error {
+	data, err := c.serialize(value)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if c.live(key, now) == nil {
+		return ErrNotStored
+	}
+	return c.store(key, data, c.expiresAt(expires, now))
+}
+
+func (c *LRUCache) Delete(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element := c.live(key, time.Now())
+	if element == nil {
+		return ErrCacheMiss
+	}
+	c.remove(element)
+	return nil
+}
+
+func (c *LRUCache) Increment(key string, n uint64) (newValue uint64, err error) {
+	return c.add(key, func(value uint64) uint64 {
+		return value + n
+	})
+}
+
+func (c *LRUCache) Decrement(key string, n uint64) (newValue uint64, err error) {
+	return c.add(key, func(value uint64) uint64 {
+		// Stop from going below zero
+		if n > value {
+			return 0
+		}
+		return value - n
+	})
+}
+
+func (c *LRUCache) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reset()
+	return nil
+}
+
+// Sets the counter of the key to the result of the operation, the expiration of the counter
+// is kept
+func (c *LRUCache) add(key string, operation func(uint64) uint64) (uint64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	element := c.live(key, now)
+	if element == nil {
+		return 0, ErrCacheMiss
+	}
+	e := element.Value.(*lruEntry)
+	var value uint64
+	if err := Deserialize(e.value, &value); err != nil {
+		return 0, ErrInvalidValue
+	}
+	value = operation(value)
+	data, _ := Serialize(value)
+	return value, c.store(key, data, e.expires)
+}
+
+// Returns the encoded value, a []byte is copied since Serialize does not copy it
+func (c *LRUCache) serialize(value interface{}) ([]byte, error) {
+	if data, ok := value.([]byte); ok {
+		return append([]byte(nil), data...), nil
+	}
+	return Serialize(value)
+}
+
+// Returns the element of the key, nil if it is not in the cache or expired
+func (c *LRUCache) live(key string, now time.Time) *list.Element {
+	element, found := c.items[key]
+	if !found {
+		return nil
+	}
+	if e := element.Value.(*lruEntry); !e.expires.IsZero() && now.After(e.expires) {
+		c.remove(element)
+		c.stats.Expirations++
+		return nil
+	}
+	return element
+}
+
+// Returns the entry of the key read, nil if it is not in the cache or expired
+func (c *LRUCache) entry(key string, now time.Time) *lruEntry {
+	element := c.live(key, now)
+	if element == nil {
+		return nil
+	}
+	e := element.Value.(*lruEntry)
+	switch {
+	case e.frequent:
+		c.frequent.entries.MoveToFront(element)
+	case c.arc:
+		// An entry read again moves to the frequent list
+		c.recent.remove(element)
+		e.frequent = true
+		c.items[key] = c.frequent.pushFront(e)
+	default:
+		c.recent.entries.MoveToFront(element)
+	}
+	return e
+}
+
+// Removes the entry of the element
+func (c *LRUCache) remove(element *list.Element) *lruEntry {
+	e := element.Value.(*lruEntry)
+	if e.frequent {
+		c.frequent.remove(element)
+	} else {
+		c.recent.remove(element)
+	}
+	delete(c.items, e.key)
+	return e
+}
+
+// Returns the time a value set now for the expiration expires, zero if it never expires
+func (c *LRUCache) expiresAt(expires time.Duration, now time.Time) time.Time {
+	switch expires {
+	case DefaultExpiryTime:
+		expires = c.defaultExpiration
+	case ForEverNeverExpiry:
+		expires = time.Duration(0)
+	}
+	if expires > 0 {
+		return now.Add(expires)
+	}
+	return time.Time{}
+}
+
+// Stores the value of the key until it expires, the entries are evicted to fit it in the
+// budget
+func (c *LRUCache) store(key string, value []byte, expires time.Time) error {
+	e := &lruEntry{key: key, value: value, expires: expires, size: int64(len(key)+len(value)) + lruEntryOverhead}
+	if e.size > c.maxBytes {
+		return ErrNotStored
+	}
+
+	if element, found := c.items[key]; found {
+		// The entry set again stays in its list
+		e.frequent = c.remove(element).frequent
+	} else if ghost, found := c.ghosts[key]; found {
+		// The key was evicted too early, the target of the list it was evicted from grows
+		g := c.removeGhost(ghost)
+		if g.frequent {
+			c.target -= e.size * maxInt64(c.ghostRecent.bytes/maxInt64(c.ghostFrequent.bytes, 1), 1)
+		} else {
+			c.target += e.size * maxInt64(c.ghostFrequent.bytes/maxInt64(c.ghostRecent.bytes, 1), 1)
+		}
+		c.target = minInt64(maxInt64(c.target, 0), c.maxBytes)
+		e.frequent = true
+	}
+
+	now := time.Now()
+	for c.recent.bytes+c.frequent.bytes+e.size > c.maxBytes {
+		c.evict(now)
+	}
+	if e.frequent {
+		c.items[key] = c.frequent.pushFront(e)
+	} else {
+		c.items[key] = c.recent.pushFront(e)
+	}
+	return nil
+}
+
+// Evicts the least recently used entry, with the arc policy from the recent list when it is
+// larger than its target
+func (c *LRUCache) evict(now time.Time) {
+	from := &c.recent
+	if c.arc && (c.recent.entries.Len() == 0 || c.recent.bytes <= c.target && c.frequent.entries.Len() > 0) {
+		from = &c.frequent
+	}
+	e := c.remove(from.entries.Back())
+	if !e.expires.IsZero() && now.After(e.expires) {
+		c.stats.Expirations++
+	} else {
+		c.stats.Evictions++
+		c.stats.EvictedBytes += uint64(e.size)
+	}
+	if !c.arc {
+		return
+	}
+
+	// The key of the entry is remembered, the ghosts of each list are bounded by the budget
+	ghosts := &c.ghostRecent
+	if e.frequent {
+		ghosts = &c.ghostFrequent
+	}
+	c.ghosts[e.key] = ghosts.pushFront(&lruEntry{key: e.key, size: e.size, frequent: e.frequent})
+	for ghosts.bytes > c.maxBytes {
+		c.removeGhost(ghosts.entries.Back())
+	}
+}
+
+// Removes the ghost of the element
+func (c *LRUCache) removeGhost(element *list.Element) *lruEntry {
+	g := element.Value.(*lruEntry)
+	if g.frequent {
+		c.ghostFrequent.remove(element)
+	} else {
+		c.ghostRecent.remove(element)
+	}
+	delete(c.ghosts, g.key)
+	return g
+}
+
+func maxInt64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minInt64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
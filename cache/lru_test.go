@@ -0,0 +1,115 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+var newLRUCache = func(_ *testing.T, defaultExpiration time.Duration) Cache {
+	return NewLRUCache(1<<20, LRUPolicy, defaultExpiration)
+}
+
+var newARCCache = func(_ *testing.T, defaultExpiration time.Duration) Cache {
+	return NewLRUCache(1<<20, ARCPolicy, defaultExpiration)
+}
+
+func TestLRUCache_TypicalGetSet(t *testing.T) {
+	typicalGetSet(t, newLRUCache)
+	typicalGetSet(t, newARCCache)
+}
+
+func TestLRUCache_IncrDecr(t *testing.T) {
+	incrDecr(t, newLRUCache)
+	incrDecr(t, newARCCache)
+}
+
+func TestLRUCache_Expiration(t *testing.T) {
+	expiration(t, newLRUCache)
+}
+
+func TestLRUCache_EmptyCache(t *testing.T) {
+	emptyCache(t, newLRUCache)
+}
+
+func TestLRUCache_Replace(t *testing.T) {
+	testReplace(t, newLRUCache)
+	testReplace(t, newARCCache)
+}
+
+func TestLRUCache_Add(t *testing.T) {
+	testAdd(t, newLRUCache)
+	testAdd(t, newARCCache)
+}
+
+func TestLRUCache_GetMulti(t *testing.T) {
+	testGetMulti(t, newLRUCache)
+}
+
+// Returns the size of the entry of the key and value
+func lruEntrySize(key string, value []byte) int64 {
+	return int64(len(key)+len(value)) + lruEntryOverhead
+}
+
+func TestLRUCache_Eviction(t *testing.T) {
+	value := make([]byte, 100)
+	c := NewLRUCache(3*lruEntrySize("key0", value), LRUPolicy, time.Hour)
+	for i := 0; i < 3; i++ {
+		if err := c.Set(fmt.Sprintf("key%d", i), value, DefaultExpiryTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var read []byte
+	if err := c.Get("key0", &read); err != nil {
+		t.Fatal(err)
+	}
+	// key1 is the least recently used
+	if err := c.Set("key3", value, DefaultExpiryTime); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Get("key1", &read); err != ErrCacheMiss {
+		t.Errorf("Expected the least recently used key to be evicted, got %v", err)
+	}
+	for _, key := range []string{"key0", "key2", "key3"} {
+		if err := c.Get(key, &read); err != nil {
+			t.Errorf("Expected %s to be kept, got %v", key, err)
+		}
+	}
+
+	stats := c.Stats()
+	if stats.Items != 3 || stats.Bytes != stats.MaxBytes || stats.Evictions != 1 || stats.EvictedBytes != uint64(lruEntrySize("key1", value)) {
+		t.Errorf("Expected one eviction within the budget, got %#v", stats)
+	}
+	if stats.Hits != 4 || stats.Misses != 1 {
+		t.Errorf("Expected the hits and misses to be counted, got %#v", stats)
+	}
+	if err := c.Set("large", make([]byte, 1000), DefaultExpiryTime); err != ErrNotStored {
+		t.Errorf("Expected a value larger than the budget not to be stored, got %v", err)
+	}
+}
+
+func TestLRUCache_ARCScan(t *testing.T) {
+	value := make([]byte, 100)
+	c := NewLRUCache(4*lruEntrySize("hot0", value), ARCPolicy, time.Hour)
+	var read []byte
+	for _, key := range []string{"hot0", "hot1"} {
+		_ = c.Set(key, value, DefaultExpiryTime)
+		_ = c.Get(key, &read)
+	}
+	// A scan of keys read once does not evict the keys read twice
+	for i := 0; i < 10; i++ {
+		_ = c.Set(fmt.Sprintf("old%d", i), value, DefaultExpiryTime)
+	}
+	for _, key := range []string{"hot0", "hot1"} {
+		if err := c.Get(key, &read); err != nil {
+			t.Errorf("Expected %s to be kept by the scan, got %v", key, err)
+		}
+	}
+	if stats := c.Stats(); stats.Evictions != 8 || stats.Bytes > stats.MaxBytes {
+		t.Errorf("Expected the scanned keys to be evicted within the budget, got %#v", stats)
+	}
+}
@@ -0,0 +1,280 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/revel/revel"
+)
+
+// The operations of the cache are observed by the instrumentations added before the
+// application starts
+//   cache.AddInstrumentation(prometheusCache{})
+// The Metrics count the operations, hits, misses and errors with a histogram of their latency,
+// by backend and by prefix of the key. They are collected when enabled in app.conf
+//   cache.metrics = true
+//   cache.metrics.separator = :    # The prefix of user:42 is user
+// and read by the metrics endpoint of the application
+//   for _, stats := range cache.DefaultMetrics.Stats() {
+//   	fmt.Fprintf(w, "cache_hit_rate{backend=%q,prefix=%q} %f\n", stats.Backend, stats.Prefix, stats.HitRate())
+//   }
+// The keys without the separator have an empty prefix.
+
+// Operation is an operation of the cache observed by the instrumentations
+type Operation struct {
+	Backend  string // memory, lru, memcached, redis or tiered
+	Name     string // get, getmulti, set, add, replace, delete, increment, decrement, flush, settags, invalidatetags or ratelimit
+	Key      string // Empty for getmulti, flush and invalidatetags
+	Prefix   string // The prefix of the key
+	Hit      bool   // Set when a get found the key
+	Miss     bool   // Set when a get did not find the key
+	Err      error  // The error of the operation, but ErrCacheMiss and ErrNotStored
+	Duration time.Duration
+}
+
+// Instrumentation observes the operations of the cache
+type Instrumentation interface {
+	ObserveCache(op *Operation)
+}
+
+var (
+	instrumentations []Instrumentation
+
+	// Set when the DefaultMetrics are collected
+	metricsEnabled bool
+
+	// The separator of the prefix of the keys
+	metricsSeparator = ":"
+)
+
+// AddInstrumentation adds the instrumentation to the cache, it must be added before the
+// application starts
+func AddInstrumentation(instrumentation Instrumentation) {
+	instrumentations = append(instrumentations, instrumentation)
+}
+
+func init() {
+	// After the cache is configured
+	revel.OnAppStart(func() {
+		metricsSeparator = revel.Config.StringDefault("cache.metrics.separator", ":")
+		metricsEnabled = revel.Config.BoolDefault("cache.metrics", false)
+		if (metricsEnabled || len(instrumentations) > 0) && Instance != nil {
+			Instance = instrumentedCache{cache: Instance, backend: backendName(Instance)}
+		}
+	}, 2)
+}
+
+// Returns the name of the backend of the cache
+func backendName(c Cache) string {
+	switch c.(type) {
+	case InMemoryCache:
+		return "memory"
+	case *LRUCache:
+		return "lru"
+	case MemcachedCache:
+		return "memcached"
+	case RedisCache:
+		return "redis"
+	case *TieredCache:
+		return "tiered"
+	}
+	return "custom"
+}
+
+// Returns the prefix of the key, the part before the separator
+func keyPrefix(key string) string {
+	if i := strings.Index(key, metricsSeparator); i > 0 {
+		return key[:i]
+	}
+	return ""
+}
+
+// instrumentedCache is a Cache whose operations are observed by the instrumentations
+type instrumentedCache struct {
+	cache   Cache
+	backend string
+}
+
+// Returns a function which observes the end of the operation on the key
+func (c instrumentedCache) observe(name, key string) func(err error) {
+	start := time.Now()
+	return func(err error) {
+		op := &Operation{Backend: c.backend, Name: name, Key: key, Prefix: keyPrefix(key), Duration: time.Since(start)}
+		if name == "get" {
+			op.Hit, op.Miss = err == nil, err == ErrCacheMiss
+		}
+		if err != ErrCacheMiss && err != ErrNotStored {
+			op.Err = err
+		}
+		if metricsEnabled {
+			DefaultMetrics.ObserveCache(op)
+		}
+		for _, instrumentation := range instrumentations {
+			instrumentation.ObserveCache(op)
+		}
+	}
+}
+
+func (c instrumentedCache) Get(key string, ptrValue interface{}) (err error) {
+	defer func(done func(error)) { done(err) }(c.observe("get", key))
+	return c.cache.Get(key, ptrValue)
+}
+
+func (c instrumentedCache) GetMulti(keys ...string) (getter Getter, err error) {
+	defer func(done func(error)) { done(err) }(c.observe("getmulti", ""))
+	return c.cache.GetMulti(keys...)
+}
+
+func (c instrumentedCache) Set(key string, value interface{}, expires time.Duration) (err error) {
+	defer func(done func(error)) { done(err) }(c.observe("set", key))
+	return c.cache.Set(key, value, expires)
+}
+
+func (c instrumentedCache) Add(key string, value interface{}, expires time.Duration) (err error) {
+	defer func(done func(error)) { done(err) }(c.observe("add", key))
+	return c.cache.Add(key, value, expires)
+}
+
+func (c instrumentedCache) Replace(key string, value interface{}, expires time.Duration) (err error) {
+	defer func(done func(error)) { done(err) }(c.observe("replace", key))
+	return c.cache.Replace(key, value, expires)
+}
+
+func (c instrumentedCache) Delete(key string) (err error) {
+	defer func(done func(error)) { done(err) }(c.observe("delete", key))
+	return c.cache.Delete(key)
+}
+
+func (c instrumentedCache) Increment(key string, n uint64) (newValue uint64, err error) {
+	defer func(done func(error)) { done(err) }(c.observe("increment", key))
+	return c.cache.Increment(key, n)
+}
+
+func (c instrumentedCache) Decrement(key string, n uint64) (newValue uint64, err error) {
+	defer func(done func(error)) { done(err) }(c.observe("decrement", key))
+	return c.cache.Decrement(key, n)
+}
+
+func (c instrumentedCache) Flush() (err error) {
+	defer func(done func(error)) { done(err) }(c.observe("flush", ""))
+	return c.cache.Flush()
+}
+
+func (c instrumentedCache) SetWithTags(key string, value interface{}, expires time.Duration, tags ...string) (err error) {
+	defer func(done func(error)) { done(err) }(c.observe("settags", key))
+	if tagCache, ok := c.cache.(TagCache); ok {
+		return tagCache.SetWithTags(key, value, expires, tags...)
+	}
+	return ErrTagsNotSupported
+}
+
+func (c instrumentedCache) InvalidateTags(tags ...string) (err error) {
+	defer func(done func(error)) { done(err) }(c.observe("invalidatetags", ""))
+	if tagCache, ok := c.cache.(TagCache); ok {
+		return tagCache.InvalidateTags(tags...)
+	}
+	return ErrTagsNotSupported
+}
+
+// MetricsLatencyBuckets are the upper bounds of the buckets of the latency histograms, the
+// last bucket of a histogram counts the slower operations
+var MetricsLatencyBuckets = []time.Duration{
+	100 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+}
+
+// CacheStats are the metrics of the operations of a backend on the keys of a prefix
+type CacheStats struct {
+	Backend    string
+	Prefix     string
+	Operations uint64
+	Hits       uint64
+	Misses     uint64
+	Errors     uint64
+	Latency    []uint64      // The operations by bucket of MetricsLatencyBuckets, and the slower ones
+	Duration   time.Duration // The total duration of the operations
+}
+
+// HitRate returns the ratio of the gets which found the key
+func (stats CacheStats) HitRate() float64 {
+	if stats.Hits+stats.Misses == 0 {
+		return 0
+	}
+	return float64(stats.Hits) / float64(stats.Hits+stats.Misses)
+}
+
+// Metrics is the Instrumentation which collects the CacheStats
+type Metrics struct {
+	mu    sync.Mutex
+	stats map[[2]string]*CacheStats
+}
+
+// DefaultMetrics collects the metrics of the cache when cache.metrics is set
+var DefaultMetrics = &Metrics{}
+
+// ObserveCache adds the operation to the stats of its backend and prefix
+func (m *Metrics) ObserveCache(op *Operation) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stats == nil {
+		m.stats = map[[2]string]*CacheStats{}
+	}
+	stats, found := m.stats[[2]string{op.Backend, op.Prefix}]
+	if !found {
+		stats = &CacheStats{Backend: op.Backend, Prefix: op.Prefix, Latency: make([]uint64, len(MetricsLatencyBuckets)+1)}
+		m.stats[[2]string{op.Backend, op.Prefix}] = stats
+	}
+	stats.Operations++
+	if op.Hit {
+		stats.Hits++
+	}
+	if op.Miss {
+		stats.Misses++
+	}
+	if op.Err != nil {
+		stats.Errors++
+	}
+	stats.Latency[sort.Search(len(MetricsLatencyBuckets), func(i int) bool {
+		return op.Duration <= MetricsLatencyBuckets[i]
+	})]++
+	stats.Duration += op.Duration
+}
+
+// Stats returns a copy of the stats, sorted by backend and prefix
+func (m *Metrics) Stats() []CacheStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats := make([]CacheStats, 0, len(m.stats))
+	for _, s := range m.stats {
+		copied := *s
+		copied.Latency = append([]uint64(nil), s.Latency...)
+		stats = append(stats, copied)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Backend != stats[j].Backend {
+			return stats[i].Backend < stats[j].Backend
+		}
+		return stats[i].Prefix < stats[j].Prefix
+	})
+	return stats
+}
+
+// Reset removes the stats
+func (m *Metrics) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stats = nil
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInstrumentedCache(t *testing.T) {
+	metrics := &Metrics{}
+	defer func(saved []Instrumentation) { instrumentations = saved }(instrumentations)
+	instrumentations = []Instrumentation{metrics}
+
+	var memory Cache = NewInMemoryCache(time.Hour)
+	c := instrumentedCache{cache: memory, backend: backendName(memory)}
+	var value string
+	_ = c.Set("user:1", "foo", time.Minute)
+	_ = c.Get("user:1", &value)
+	_ = c.Get("user:2", &value)
+	_ = c.Get("user:1", &value)
+	_ = c.Delete("hotel:1")
+	_ = c.Get("total", &value)
+
+	stats := metrics.Stats()
+	if len(stats) != 3 {
+		t.Fatalf("Expected the stats of 3 prefixes, got %#v", stats)
+	}
+	if stats[0].Prefix != "" || stats[0].Misses != 1 || stats[1].Prefix != "hotel" || stats[1].Errors != 0 {
+		t.Errorf("Expected the keys without a prefix and the missing keys to be counted, got %#v", stats[:2])
+	}
+	user := stats[2]
+	if user.Backend != "memory" || user.Prefix != "user" || user.Operations != 4 || user.Hits != 2 || user.Misses != 1 {
+		t.Errorf("Expected the operations on the user keys to be counted, got %#v", user)
+	}
+	if rate := user.HitRate(); rate < 0.66 || rate > 0.67 {
+		t.Errorf("Expected a hit rate of 2/3, got %f", rate)
+	}
+	var observed uint64
+	for _, count := range user.Latency {
+		observed += count
+	}
+	if len(user.Latency) != len(MetricsLatencyBuckets)+1 || observed != user.Operations {
+		t.Errorf("Expected the latency of each operation in the histogram, got %v", user.Latency)
+	}
+
+	if err := c.SetWithTags("user:3", "bar", time.Minute, "org:7"); err != nil {
+		t.Errorf("Expected the tags of the memory cache to be used, got %v", err)
+	}
+	metrics.Reset()
+	if stats := metrics.Stats(); len(stats) != 0 {
+		t.Errorf("Expected the stats to be reset, got %#v", stats)
+	}
+}
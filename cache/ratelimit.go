@@ -0,0 +1,130 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/revel/revel"
+)
+
+// rateLimiter stores the buckets of the @RateLimit annotation in the configured
+// cache, so with redis or memcached the limit is shared by the servers.
+// Redis takes the token atomically with a script. The other caches read and write
+// the bucket, the requests of a key wait for each other on this server only, so
+// concurrent requests on different servers may both take the last token.
+type rateLimiter struct {
+	stripes [rateLimitStripes]sync.Mutex
+}
+
+// The number of locks the keys are spread over, the requests of different keys rarely
+// wait for each other
+const rateLimitStripes = 64
+
+// rateLimitCache is a Cache which takes the tokens of the buckets itself
+type rateLimitCache interface {
+	takeRateLimit(key string, limit int, period time.Duration) (allowed bool, remaining int, reset time.Duration, err error)
+}
+
+func init() {
+	revel.AppRateLimiter = &rateLimiter{}
+}
+
+func (r *rateLimiter) Take(key string, limit int, period time.Duration) (allowed bool, remaining int, reset time.Duration) {
+	if c := tokenCache(); c != nil {
+		var err error
+		if allowed, remaining, reset, err = c.takeRateLimit(key, limit, period); err != nil {
+			cacheLog.Error("Failed to take from the rate limit bucket, the request is allowed", "key", key, "error", err)
+			return true, limit, 0
+		}
+		return
+	}
+
+	mutex := r.lock(key)
+	mutex.Lock()
+	defer mutex.Unlock()
+	bucket := revel.RateLimitBucket{}
+	if err := Get(key, &bucket); err != nil && err != ErrCacheMiss {
+		cacheLog.Error("Failed to read the rate limit bucket, the request is allowed", "key", key, "error", err)
+		return true, limit, 0
+	}
+	allowed, remaining, reset = bucket.Take(limit, period, time.Now())
+	if err := Set(key, bucket, period); err != nil {
+		cacheLog.Error("Failed to store the rate limit bucket", "key", key, "error", err)
+	}
+	return
+}
+
+// Returns the configured cache if it takes the tokens itself
+func tokenCache() rateLimitCache {
+	if instrumented, ok := Instance.(instrumentedCache); ok {
+		if _, ok = instrumented.cache.(rateLimitCache); !ok {
+			return nil
+		}
+	}
+	c, _ := Instance.(rateLimitCache)
+	return c
+}
+
+// Returns the lock of the key
+func (r *rateLimiter) lock(key string) *sync.Mutex {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(key))
+	return &r.stripes[hash.Sum32()%rateLimitStripes]
+}
+
+// Refills the bucket of the key for the time elapsed since it was last updated and takes a
+// token, like revel.RateLimitBucket. The bucket is a hash which expires after the period,
+// the times are in milliseconds. Returns whether the token was taken, the remaining tokens
+// and the milliseconds until the reset.
+var redisRateLimitScript = redis.NewScript(1, `
+local limit = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local rate = limit / period
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "updated")
+local tokens, updated = tonumber(bucket[1]), tonumber(bucket[2])
+if tokens == nil or updated == nil then
+	tokens, updated = limit, now
+elseif now > updated then
+	tokens = math.min(limit, tokens + (now - updated) * rate)
+	updated = now
+end
+local allowed, reset = 0, 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+	reset = (limit - tokens) / rate
+else
+	reset = (1 - tokens) / rate
+end
+redis.call("HMSET", KEYS[1], "tokens", tostring(tokens), "updated", updated)
+redis.call("PEXPIRE", KEYS[1], period)
+return {allowed, math.floor(tokens), math.ceil(reset)}`)
+
+func (c RedisCache) takeRateLimit(key string, limit int, period time.Duration) (allowed bool, remaining int, reset time.Duration, err error) {
+	conn := c.conn(key)
+	defer func() {
+		_ = conn.Close()
+	}()
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	values, err := redis.Ints(redisRateLimitScript.Do(conn, key, limit, int64(period/time.Millisecond), now))
+	if err != nil {
+		return
+	}
+	return values[0] == 1, values[1], time.Duration(values[2]) * time.Millisecond, nil
+}
+
+func (c *TieredCache) takeRateLimit(key string, limit int, period time.Duration) (allowed bool, remaining int, reset time.Duration, err error) {
+	return c.remote.takeRateLimit(key, limit, period)
+}
+
+func (c instrumentedCache) takeRateLimit(key string, limit int, period time.Duration) (allowed bool, remaining int, reset time.Duration, err error) {
+	defer func(done func(error)) { done(err) }(c.observe("ratelimit", key))
+	return c.cache.(rateLimitCache).takeRateLimit(key, limit, period)
+}
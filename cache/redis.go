@@ -14,53 +14,31 @@ import (
 // RedisCache wraps the Redis client to meet the Cache interface.
 type RedisCache struct {
 	pool              *redis.Pool
+	cluster           *redisCluster
 	defaultExpiration time.Duration
 }
 
-// NewRedisCache returns a new RedisCache with given parameters
-// until redigo supports sharding/clustering, only one host will be in hostList
+// NewRedisCache returns a new RedisCache of the single node of the host, see
+// NewRedisSentinelCache and NewRedisClusterCache for the other topologies
 func NewRedisCache(host string, password string, defaultExpiration time.Duration) RedisCache {
-	var pool = &redis.Pool{
-		MaxIdle:     revel.Config.IntDefault("cache.redis.maxidle", 5),
-		MaxActive:   revel.Config.IntDefault("cache.redis.maxactive", 0),
-		IdleTimeout: time.Duration(revel.Config.IntDefault("cache.redis.idletimeout", 240)) * time.Second,
-		Dial: func() (redis.Conn, error) {
-			protocol := revel.Config.StringDefault("cache.redis.protocol", "tcp")
-			toc := time.Millisecond * time.Duration(revel.Config.IntDefault("cache.redis.timeout.connect", 10000))
-			tor := time.Millisecond * time.Duration(revel.Config.IntDefault("cache.redis.timeout.read", 5000))
-			tow := time.Millisecond * time.Duration(revel.Config.IntDefault("cache.redis.timeout.write", 5000))
-			c, err := redis.Dial(protocol, host,
-				redis.DialConnectTimeout(toc),
-				redis.DialReadTimeout(tor),
-				redis.DialWriteTimeout(tow))
-			if err != nil {
-				return nil, err
-			}
-			if len(password) > 0 {
-				if _, err = c.Do("AUTH", password); err != nil {
-					_ = c.Close()
-					return nil, err
-				}
-			} else {
-				// check with PING
-				if _, err = c.Do("PING"); err != nil {
-					_ = c.Close()
-					return nil, err
-				}
-			}
-			return c, err
-		},
-		// custom connection test method
-		TestOnBorrow: func(c redis.Conn, t time.Time) error {
-			_, err := c.Do("PING")
-			return err
-		},
+	username := revel.Config.StringDefault("cache.redis.username", "")
+	database := revel.Config.IntDefault("cache.redis.database", 0)
+	pool := newRedisPool(func() (redis.Conn, error) {
+		return dialRedis(host, username, password, database)
+	}, pingRedis)
+	return RedisCache{pool: pool, defaultExpiration: defaultExpiration}
+}
+
+// Returns the connection of the node of the key
+func (c RedisCache) conn(key string) redis.Conn {
+	if c.cluster != nil {
+		return c.cluster.conn(key)
 	}
-	return RedisCache{pool, defaultExpiration}
+	return c.pool.Get()
 }
 
 func (c RedisCache) Set(key string, value interface{}, expires time.Duration) error {
-	conn := c.pool.Get()
+	conn := c.conn(key)
 	defer func() {
 		_ = conn.Close()
 	}()
@@ -68,7 +46,7 @@ func (c RedisCache) Set(key string, value interface{}, expires time.Duration) er
 }
 
 func (c RedisCache) Add(key string, value interface{}, expires time.Duration) error {
-	conn := c.pool.Get()
+	conn := c.conn(key)
 	defer func() {
 		_ = conn.Close()
 	}()
@@ -83,7 +61,7 @@ func (c RedisCache) Add(key string, value interface{}, expires time.Duration) er
 }
 
 func (c RedisCache) Replace(key string, value interface{}, expires time.Duration) error {
-	conn := c.pool.Get()
+	conn := c.conn(key)
 	defer func() {
 		_ = conn.Close()
 	}()
@@ -103,7 +81,7 @@ func (c RedisCache) Replace(key string, value interface{}, expires time.Duration
 }
 
 func (c RedisCache) Get(key string, ptrValue interface{}) error {
-	conn := c.pool.Get()
+	conn := c.conn(key)
 	defer func() {
 		_ = conn.Close()
 	}()
@@ -129,6 +107,9 @@ func generalizeStringSlice(strs []string) []interface{} {
 }
 
 func (c RedisCache) GetMulti(keys ...string) (Getter, error) {
+	if c.cluster != nil {
+		return c.clusterGetMulti(keys)
+	}
 	conn := c.pool.Get()
 	defer func() {
 		_ = conn.Close()
@@ -159,7 +140,7 @@ func exists(conn redis.Conn, key string) (bool, error) {
 }
 
 func (c RedisCache) Delete(key string) error {
-	conn := c.pool.Get()
+	conn := c.conn(key)
 	defer func() {
 		_ = conn.Close()
 	}()
@@ -171,7 +152,7 @@ func (c RedisCache) Delete(key string) error {
 }
 
 func (c RedisCache) Increment(key string, delta uint64) (uint64, error) {
-	conn := c.pool.Get()
+	conn := c.conn(key)
 	defer func() {
 		_ = conn.Close()
 	}()
@@ -198,7 +179,7 @@ func (c RedisCache) Increment(key string, delta uint64) (uint64, error) {
 }
 
 func (c RedisCache) Decrement(key string, delta uint64) (newValue uint64, err error) {
-	conn := c.pool.Get()
+	conn := c.conn(key)
 	defer func() {
 		_ = conn.Close()
 	}()
@@ -226,7 +207,31 @@ func (c RedisCache) Decrement(key string, delta uint64) (newValue uint64, err er
 	return uint64(tempint), err
 }
 
+// The keys of a cluster are in the slots of several nodes, they are read one by one
+func (c RedisCache) clusterGetMulti(keys []string) (Getter, error) {
+	m := make(map[string][]byte)
+	for _, key := range keys {
+		item, err := redis.Bytes(c.cluster.conn(key).Do("GET", key))
+		if err != nil && err != redis.ErrNil {
+			return nil, err
+		}
+		m[key] = item
+	}
+	return RedisItemMapGetter(m), nil
+}
+
 func (c RedisCache) Flush() error {
+	if c.cluster != nil {
+		for _, address := range c.cluster.masters() {
+			conn := c.cluster.pool(address).Get()
+			_, err := conn.Do("FLUSHALL")
+			_ = conn.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
 	conn := c.pool.Get()
 	defer func() {
 		_ = conn.Close()
@@ -249,10 +254,6 @@ func (c RedisCache) invoke(f func(string, ...interface{}) (interface{}, error),
 	if err != nil {
 		return err
 	}
-	conn := c.pool.Get()
-	defer func() {
-		_ = conn.Close()
-	}()
 	if expires > 0 {
 		_, err = f("SETEX", key, int32(expires/time.Second), b)
 		return err
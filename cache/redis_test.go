@@ -64,3 +64,11 @@ func TestRedisCache_Add(t *testing.T) {
 func TestRedisCache_GetMulti(t *testing.T) {
 	testGetMulti(t, newRedisCache)
 }
+
+func TestRedisCache_Tags(t *testing.T) {
+	testTags(t, newRedisCache)
+}
+
+func TestRedisCache_RateLimit(t *testing.T) {
+	testRateLimit(t, newRedisCache)
+}
@@ -0,0 +1,395 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/revel/revel"
+)
+
+// The Redis cache is a single node, the master of a Sentinel deployment, or a Redis Cluster
+//   cache.redis = true
+//   cache.redis.mode = sentinel             # single (the default), sentinel or cluster
+//   cache.hosts = 10.0.0.1:26379,10.0.0.2:26379
+//   cache.redis.sentinel.master = mymaster  # The name of the master monitored by the sentinels
+//   cache.redis.sentinel.password =         # The password of the sentinels
+// The hosts of a cluster are the nodes the slots of the cluster are read from. The
+// connections are set by
+//   cache.redis.username =                  # The ACL user (Redis 6), with cache.redis.password
+//   cache.redis.password =
+//   cache.redis.database = 0                # Not with a cluster
+//   cache.redis.tls = false
+//   cache.redis.tls.skipverify = false
+//   cache.redis.maxidle = 5                 # The idle connections of each node
+//   cache.redis.maxactive = 0               # The connections of each node, no limit by default
+//   cache.redis.wait = false                # Wait for a connection when maxactive are in use
+//   cache.redis.idletimeout = 240s
+//   cache.redis.healthcheck = 0s            # The idle time of a connection checked when borrowed
+// A connection borrowed from the pool is checked (by a PING) when it was idle for the
+// health check, every time by default. The master of a Sentinel deployment is checked to
+// still be the master, the master is found again after a failover. A cluster reads its slots
+// again when a key has moved or a node is down.
+
+// Returns the connection options of app.conf
+func redisDialOptions() []redis.DialOption {
+	return []redis.DialOption{
+		redis.DialConnectTimeout(revel.ConfigDurationDefault("cache.redis.timeout.connect", 10*time.Second, time.Millisecond)),
+		redis.DialReadTimeout(revel.ConfigDurationDefault("cache.redis.timeout.read", 5*time.Second, time.Millisecond)),
+		redis.DialWriteTimeout(revel.ConfigDurationDefault("cache.redis.timeout.write", 5*time.Second, time.Millisecond)),
+		redis.DialUseTLS(revel.Config.BoolDefault("cache.redis.tls", false)),
+		redis.DialTLSSkipVerify(revel.Config.BoolDefault("cache.redis.tls.skipverify", false)),
+	}
+}
+
+// Dials the Redis node of the address, authenticates and selects the database
+func dialRedis(address, username, password string, database int) (redis.Conn, error) {
+	protocol := revel.Config.StringDefault("cache.redis.protocol", "tcp")
+	c, err := redis.Dial(protocol, address, redisDialOptions()...)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case username != "":
+		_, err = c.Do("AUTH", username, password)
+	case password != "":
+		_, err = c.Do("AUTH", password)
+	default:
+		// check with PING
+		_, err = c.Do("PING")
+	}
+	if err == nil && database != 0 {
+		_, err = c.Do("SELECT", database)
+	}
+	if err != nil {
+		_ = c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Returns the pool of the connections of the dial, the connections idle for the health check
+// are checked when borrowed
+func newRedisPool(dial func() (redis.Conn, error), check func(redis.Conn) error) *redis.Pool {
+	healthCheck := revel.ConfigDurationDefault("cache.redis.healthcheck", 0, time.Second)
+	return &redis.Pool{
+		MaxIdle:     revel.Config.IntDefault("cache.redis.maxidle", 5),
+		MaxActive:   revel.Config.IntDefault("cache.redis.maxactive", 0),
+		IdleTimeout: revel.ConfigDurationDefault("cache.redis.idletimeout", 240*time.Second, time.Second),
+		Wait:        revel.Config.BoolDefault("cache.redis.wait", false),
+		Dial:        dial,
+		// custom connection test method
+		TestOnBorrow: func(c redis.Conn, t time.Time) error {
+			if time.Since(t) < healthCheck {
+				return nil
+			}
+			return check(c)
+		},
+	}
+}
+
+// Checks the connection by a PING
+func pingRedis(c redis.Conn) error {
+	_, err := c.Do("PING")
+	return err
+}
+
+// NewRedisSentinelCache returns the RedisCache of the master monitored by the sentinels,
+// the connections are made to the new master after a failover
+func NewRedisSentinelCache(sentinels []string, master string, password string, defaultExpiration time.Duration) RedisCache {
+	username := revel.Config.StringDefault("cache.redis.username", "")
+	sentinelPassword := revel.Config.StringDefault("cache.redis.sentinel.password", "")
+	database := revel.Config.IntDefault("cache.redis.database", 0)
+	pool := newRedisPool(func() (redis.Conn, error) {
+		address, err := sentinelMasterAddress(sentinels, master, sentinelPassword)
+		if err != nil {
+			return nil, err
+		}
+		return dialRedis(address, username, password, database)
+	}, func(c redis.Conn) error {
+		// The master is a replica after a failover
+		role, err := redis.Values(c.Do("ROLE"))
+		if err != nil {
+			return err
+		}
+		if len(role) == 0 {
+			return errors.New("redis: empty ROLE reply")
+		}
+		if kind, _ := redis.String(role[0], nil); kind != "master" {
+			return fmt.Errorf("redis: the node is a %s, not the master", kind)
+		}
+		return nil
+	})
+	return RedisCache{pool: pool, defaultExpiration: defaultExpiration}
+}
+
+// Returns the address of the master from the first sentinel which knows it
+func sentinelMasterAddress(sentinels []string, master, password string) (address string, err error) {
+	for _, sentinel := range sentinels {
+		var c redis.Conn
+		if c, err = dialRedis(strings.TrimSpace(sentinel), "", password, 0); err != nil {
+			continue
+		}
+		var reply []string
+		reply, err = redis.Strings(c.Do("SENTINEL", "get-master-addr-by-name", master))
+		_ = c.Close()
+		if err == nil && len(reply) == 2 {
+			return net.JoinHostPort(reply[0], reply[1]), nil
+		}
+		if err == nil || err == redis.ErrNil {
+			err = fmt.Errorf("redis: the sentinel %s does not know the master %s", sentinel, master)
+		}
+	}
+	if err == nil {
+		err = errors.New("redis: no sentinel")
+	}
+	return "", err
+}
+
+// The number of the hash slots of a Redis Cluster
+const redisClusterSlots = 16384
+
+// A Redis Cluster, the pools of its nodes and the nodes of its slots
+type redisCluster struct {
+	sync.RWMutex
+	seeds    []string
+	slots    []string // The address of the master of each slot
+	pools    map[string]*redis.Pool
+	username string
+	password string
+}
+
+// NewRedisClusterCache returns the RedisCache of the Redis Cluster of the nodes, the slots
+// of the cluster are read from the first node which answers
+func NewRedisClusterCache(nodes []string, password string, defaultExpiration time.Duration) (RedisCache, error) {
+	cluster := &redisCluster{
+		pools:    map[string]*redis.Pool{},
+		username: revel.Config.StringDefault("cache.redis.username", ""),
+		password: password,
+	}
+	for _, node := range nodes {
+		cluster.seeds = append(cluster.seeds, strings.TrimSpace(node))
+	}
+	if err := cluster.refresh(); err != nil {
+		return RedisCache{}, err
+	}
+	return RedisCache{cluster: cluster, defaultExpiration: defaultExpiration}, nil
+}
+
+// Returns the pool of the node of the address
+func (cluster *redisCluster) pool(address string) *redis.Pool {
+	cluster.RLock()
+	pool, found := cluster.pools[address]
+	cluster.RUnlock()
+	if found {
+		return pool
+	}
+	cluster.Lock()
+	defer cluster.Unlock()
+	if pool, found = cluster.pools[address]; !found {
+		pool = newRedisPool(func() (redis.Conn, error) {
+			return dialRedis(address, cluster.username, cluster.password, 0)
+		}, pingRedis)
+		cluster.pools[address] = pool
+	}
+	return pool
+}
+
+// Reads the slots of the cluster from the first node which answers, the known nodes then the
+// seeds
+func (cluster *redisCluster) refresh() (err error) {
+	cluster.RLock()
+	addresses := append([]string{}, cluster.seeds...)
+	for address := range cluster.pools {
+		addresses = append(addresses, address)
+	}
+	cluster.RUnlock()
+
+	for _, address := range addresses {
+		conn := cluster.pool(address).Get()
+		var reply interface{}
+		reply, err = conn.Do("CLUSTER", "SLOTS")
+		_ = conn.Close()
+		if err != nil {
+			continue
+		}
+		var slots []string
+		if slots, err = parseClusterSlots(reply); err != nil {
+			continue
+		}
+		cluster.Lock()
+		cluster.slots = slots
+		cluster.Unlock()
+		return nil
+	}
+	if err == nil {
+		err = errors.New("redis: no cluster node")
+	}
+	return err
+}
+
+// Returns the address of the master of each slot of the reply of CLUSTER SLOTS, an array of
+// (start, end, (master ip, port, ...), replicas...)
+func parseClusterSlots(reply interface{}) ([]string, error) {
+	ranges, err := redis.Values(reply, nil)
+	if err != nil {
+		return nil, err
+	}
+	slots := make([]string, redisClusterSlots)
+	for _, r := range ranges {
+		fields, err := redis.Values(r, nil)
+		if err != nil || len(fields) < 3 {
+			return nil, fmt.Errorf("redis: invalid CLUSTER SLOTS reply %v", r)
+		}
+		start, err1 := redis.Int(fields[0], nil)
+		end, err2 := redis.Int(fields[1], nil)
+		node, err3 := redis.Values(fields[2], nil)
+		if err1 != nil || err2 != nil || err3 != nil || len(node) < 2 || start < 0 || end >= redisClusterSlots {
+			return nil, fmt.Errorf("redis: invalid CLUSTER SLOTS reply %v", r)
+		}
+		host, err1 := redis.String(node[0], nil)
+		port, err2 := redis.Int(node[1], nil)
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("redis: invalid CLUSTER SLOTS node %v", node)
+		}
+		address := net.JoinHostPort(host, strconv.Itoa(port))
+		for slot := start; slot <= end; slot++ {
+			slots[slot] = address
+		}
+	}
+	return slots, nil
+}
+
+// Returns the address of the master of the slot, the first seed if the slot is not known
+func (cluster *redisCluster) address(slot int) string {
+	cluster.RLock()
+	defer cluster.RUnlock()
+	if address := cluster.slots[slot]; address != "" {
+		return address
+	}
+	return cluster.seeds[0]
+}
+
+// Returns the addresses of the masters
+func (cluster *redisCluster) masters() []string {
+	cluster.RLock()
+	defer cluster.RUnlock()
+	seen := map[string]bool{}
+	var masters []string
+	for _, address := range cluster.slots {
+		if address != "" && !seen[address] {
+			seen[address] = true
+			masters = append(masters, address)
+		}
+	}
+	return masters
+}
+
+// Returns the connection of the node of the key
+func (cluster *redisCluster) conn(key string) redis.Conn {
+	return &redisClusterConn{cluster: cluster, slot: redisKeySlot(key)}
+}
+
+// Returns the hash slot of the key, the hash tag of the key ({user1}.cart) when it has one
+func redisKeySlot(key string) int {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+	return int(crc16(key) % redisClusterSlots)
+}
+
+// Returns the CRC16 (XMODEM) of the key
+func crc16(key string) uint16 {
+	var crc uint16
+	for i := 0; i < len(key); i++ {
+		crc ^= uint16(key[i]) << 8
+		for bit := 0; bit < 8; bit++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// The number of redirections of a command before it fails
+const redisClusterRedirections = 5
+
+// The connection to the node of a slot, the commands are redirected to the node the slot has
+// moved to
+type redisClusterConn struct {
+	cluster *redisCluster
+	slot    int
+}
+
+var errRedisClusterPipeline = errors.New("redis: the cluster connection does not pipeline commands")
+
+func (c *redisClusterConn) Do(command string, args ...interface{}) (reply interface{}, err error) {
+	address, asking := c.cluster.address(c.slot), false
+	for redirection := 0; redirection < redisClusterRedirections; redirection++ {
+		conn := c.cluster.pool(address).Get()
+		if asking {
+			if _, err = conn.Do("ASKING"); err != nil {
+				_ = conn.Close()
+				return nil, err
+			}
+		}
+		reply, err = conn.Do(command, args...)
+		_ = conn.Close()
+
+		if redisErr, ok := err.(redis.Error); ok {
+			// MOVED 3999 127.0.0.1:6381 or ASK 3999 127.0.0.1:6381
+			fields := strings.Fields(string(redisErr))
+			if len(fields) == 3 && (fields[0] == "MOVED" || fields[0] == "ASK") {
+				address, asking = fields[2], fields[0] == "ASK"
+				if !asking {
+					if err := c.cluster.refresh(); err != nil {
+						cacheLog.Warn("Failed to read the slots of the Redis cluster", "error", err)
+					}
+				}
+				continue
+			}
+		} else if _, ok := err.(net.Error); ok && redirection == 0 {
+			// The node is down, a replica may have been promoted
+			if err := c.cluster.refresh(); err == nil {
+				address, asking = c.cluster.address(c.slot), false
+				continue
+			}
+		}
+		return reply, err
+	}
+	return nil, fmt.Errorf("redis: too many redirections of %s", command)
+}
+
+func (c *redisClusterConn) Close() error {
+	return nil
+}
+
+func (c *redisClusterConn) Err() error {
+	return nil
+}
+
+func (c *redisClusterConn) Send(command string, args ...interface{}) error {
+	return errRedisClusterPipeline
+}
+
+func (c *redisClusterConn) Flush() error {
+	return errRedisClusterPipeline
+}
+
+func (c *redisClusterConn) Receive() (reply interface{}, err error) {
+	return nil, errRedisClusterPipeline
+}
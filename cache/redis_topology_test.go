@@ -0,0 +1,47 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import "testing"
+
+func TestClusterKeySlot(t *testing.T) {
+	for key, slot := range map[string]int{
+		"foo":                  12182,
+		"bar":                  5061,
+		"123456789":            12739,
+		"{user1000}.following": redisKeySlot("user1000"),
+		"{}.key":               redisKeySlot("{}.key"),
+	} {
+		if actual := redisKeySlot(key); actual != slot {
+			t.Errorf("Expected the slot of %s to be %d, got %d", key, slot, actual)
+		}
+	}
+	if redisKeySlot("{user1000}.following") != redisKeySlot("{user1000}.followers") {
+		t.Error("Expected the keys of a hash tag to be in the same slot")
+	}
+}
+
+func TestClusterSlots(t *testing.T) {
+	reply := []interface{}{
+		[]interface{}{int64(0), int64(5460), []interface{}{[]byte("10.0.0.1"), int64(7000), []byte("id1")},
+			[]interface{}{[]byte("10.0.0.4"), int64(7003)}},
+		[]interface{}{int64(5461), int64(16383), []interface{}{[]byte("10.0.0.2"), int64(7001)}},
+	}
+	slots, err := parseClusterSlots(reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slots[0] != "10.0.0.1:7000" || slots[5460] != "10.0.0.1:7000" || slots[5461] != "10.0.0.2:7001" || slots[16383] != "10.0.0.2:7001" {
+		t.Errorf("Unexpected slots %s %s %s", slots[0], slots[5461], slots[16383])
+	}
+
+	cluster := &redisCluster{seeds: []string{"10.0.0.9:7000"}, slots: slots}
+	if masters := cluster.masters(); len(masters) != 2 {
+		t.Errorf("Expected 2 masters, got %v", masters)
+	}
+	if _, err = parseClusterSlots([]interface{}{[]interface{}{int64(0)}}); err == nil {
+		t.Error("Expected an invalid reply to be rejected")
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// The values set with tags are removed together by invalidating one of their tags
+//   cache.SetWithTags("user:42:profile", profile, time.Hour, "user:42", "org:7")
+//   cache.SetWithTags("org:7:members", members, time.Hour, "org:7")
+//   cache.InvalidateTags("org:7")    # Removes both values
+// The tags are supported by the memory and redis caches, memcached returns
+// ErrTagsNotSupported. A value keeps its tags until it expires or is deleted, setting the key
+// again without tags does not remove them.
+
+// TagCache is a Cache which removes its values by tag
+type TagCache interface {
+	// Set the given key/value in the cache, the key is removed when one of the tags is
+	// invalidated
+	SetWithTags(key string, value interface{}, expires time.Duration, tags ...string) error
+
+	// Remove the keys set with any of the tags
+	InvalidateTags(tags ...string) error
+}
+
+// ErrTagsNotSupported is returned when the configured cache has no tags
+var ErrTagsNotSupported = errors.New("revel/cache: tags not supported")
+
+// SetWithTags sets the key/value in the cache, tagged with the tags
+func SetWithTags(key string, value interface{}, expires time.Duration, tags ...string) error {
+	if c, ok := Instance.(TagCache); ok {
+		return c.SetWithTags(key, value, expires, tags...)
+	}
+	return ErrTagsNotSupported
+}
+
+// InvalidateTags removes the keys set with any of the tags
+func InvalidateTags(tags ...string) error {
+	if c, ok := Instance.(TagCache); ok {
+		return c.InvalidateTags(tags...)
+	}
+	return ErrTagsNotSupported
+}
+
+// The keys of the tags of an InMemoryCache, the tags of a key are removed when it expires or
+// is deleted
+type tagIndex struct {
+	mu   sync.Mutex
+	keys map[string]map[string]struct{} // The keys of the tags
+	tags map[string][]string            // The tags of the keys
+}
+
+func newTagIndex() *tagIndex {
+	return &tagIndex{keys: map[string]map[string]struct{}{}, tags: map[string][]string{}}
+}
+
+// Adds the key to the tags
+func (index *tagIndex) add(key string, tags []string) {
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	for _, tag := range tags {
+		keys, found := index.keys[tag]
+		if !found {
+			keys = map[string]struct{}{}
+			index.keys[tag] = keys
+		}
+		if _, found = keys[key]; !found {
+			keys[key] = struct{}{}
+			index.tags[key] = append(index.tags[key], tag)
+		}
+	}
+}
+
+// Removes the key from its tags
+func (index *tagIndex) remove(key string) {
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	for _, tag := range index.tags[key] {
+		delete(index.keys[tag], key)
+		if len(index.keys[tag]) == 0 {
+			delete(index.keys, tag)
+		}
+	}
+	delete(index.tags, key)
+}
+
+// Returns the keys of the tags
+func (index *tagIndex) keysOf(tags []string) (keys []string) {
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	for _, tag := range tags {
+		for key := range index.keys[tag] {
+			keys = append(keys, key)
+		}
+	}
+	return
+}
+
+func (index *tagIndex) flush() {
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	index.keys = map[string]map[string]struct{}{}
+	index.tags = map[string][]string{}
+}
+
+// The prefix of the sets of the keys of the tags in redis
+const redisTagPrefix = "revel-tag:"
+
+// Adds the key to the set of the tag, the set expires with the last of its keys
+var redisTagScript = redis.NewScript(1, `
+local ttl = redis.call("PTTL", KEYS[1])
+redis.call("SADD", KEYS[1], ARGV[1])
+local expires = tonumber(ARGV[2])
+if expires == 0 then
+	redis.call("PERSIST", KEYS[1])
+elseif ttl == -2 or (ttl >= 0 and ttl < expires) then
+	redis.call("PEXPIRE", KEYS[1], expires)
+end
+return 1`)
+
+// Removes the set of the tag and returns its keys
+var redisInvalidateTagScript = redis.NewScript(1, `
+local keys = redis.call("SMEMBERS", KEYS[1])
+redis.call("DEL", KEYS[1])
+return keys`)
+
+func (c RedisCache) SetWithTags(key string, value interface{}, expires time.Duration, tags ...string) error {
+	if err := c.Set(key, value, expires); err != nil {
+		return err
+	}
+	switch expires {
+	case DefaultExpiryTime:
+		expires = c.defaultExpiration
+	case ForEverNeverExpiry:
+		expires = time.Duration(0)
+	}
+	for _, tag := range tags {
+		conn := c.conn(redisTagPrefix + tag)
+		_, err := redisTagScript.Do(conn, redisTagPrefix+tag, key, int64(expires/time.Millisecond))
+		_ = conn.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c RedisCache) InvalidateTags(tags ...string) error {
+	_, err := c.invalidateTags(tags...)
+	return err
+}
+
+// Removes the keys set with any of the tags, returns the keys removed
+func (c RedisCache) invalidateTags(tags ...string) (removed []string, err error) {
+	for _, tag := range tags {
+		conn := c.conn(redisTagPrefix + tag)
+		keys, err := redis.Strings(redisInvalidateTagScript.Do(conn, redisTagPrefix+tag))
+		_ = conn.Close()
+		if err != nil {
+			return removed, err
+		}
+		if len(keys) == 0 {
+			continue
+		}
+		// The keys of a cluster are in the slots of several nodes, they are removed one by one
+		if c.cluster != nil {
+			for _, key := range keys {
+				if _, err = c.cluster.conn(key).Do("DEL", key); err != nil {
+					return removed, err
+				}
+				removed = append(removed, key)
+			}
+			continue
+		}
+		conn = c.pool.Get()
+		_, err = conn.Do("DEL", generalizeStringSlice(keys)...)
+		_ = conn.Close()
+		if err != nil {
+			return removed, err
+		}
+		removed = append(removed, keys...)
+	}
+	return removed, nil
+}
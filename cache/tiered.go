@@ -0,0 +1,287 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// The tiered cache keeps the values read from Redis in memory for a short time, the hot
+// keys are read without a round trip to Redis
+//   cache.redis = true
+//   cache.tiered = true
+//   cache.tiered.expires = 5s                       # How long a value is kept in memory
+//   cache.tiered.channel = revel-cache-invalidate   # The channel of the invalidations
+// The values set, deleted or invalidated by tag are removed from the memory of the other
+// instances by a message published on the channel. The invalidations missed while the
+// subscription to the channel is down are lost, the memory is flushed when the subscription
+// is made again. The values are not copied, the values read from the memory must not be
+// modified.
+
+// The interval of the pings of the subscription to the invalidations
+const tieredPingInterval = 30 * time.Second
+
+// TieredCache is a Cache which keeps the values of a RedisCache in memory
+type TieredCache struct {
+	local   InMemoryCache
+	remote  RedisCache
+	expires time.Duration // How long a value is kept in memory
+	channel string        // The channel of the invalidations
+	id      string        // The id of the instance, its own invalidations are skipped
+
+	mu           sync.Mutex
+	subscription redis.Conn
+	done         chan struct{}
+}
+
+// NewTieredCache returns the TieredCache of the Redis cache, the values are kept in memory
+// for the expiration. The invalidations are published on the channel, Close stops the
+// subscription to the channel.
+func NewTieredCache(remote RedisCache, expires time.Duration, channel string) *TieredCache {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		cacheLog.Panic("Could not create the id of the tiered cache", "error", err)
+	}
+	c := &TieredCache{
+		local:   NewInMemoryCache(expires),
+		remote:  remote,
+		expires: expires,
+		channel: channel,
+		id:      hex.EncodeToString(id),
+		done:    make(chan struct{}),
+	}
+	go c.subscribe()
+	return c
+}
+
+// Returns how long a value which expires after the expiration is kept in memory
+func (c *TieredCache) localExpiration(expires time.Duration) time.Duration {
+	switch expires {
+	case DefaultExpiryTime:
+		expires = c.remote.defaultExpiration
+	case ForEverNeverExpiry:
+		expires = time.Duration(0)
+	}
+	if expires > 0 && expires < c.expires {
+		return expires
+	}
+	return c.expires
+}
+
+func (c *TieredCache) Get(key string, ptrValue interface{}) error {
+	// A value read into another type is read from Redis
+	v := reflect.ValueOf(ptrValue)
+	if value, found := c.local.cache.Get(key); found && v.Kind() == reflect.Ptr && v.Elem().CanSet() &&
+		reflect.TypeOf(value).AssignableTo(v.Elem().Type()) {
+		v.Elem().Set(reflect.ValueOf(value))
+		return nil
+	}
+	if err := c.remote.Get(key, ptrValue); err != nil {
+		return err
+	}
+	if v.Kind() == reflect.Ptr {
+		c.local.cache.Set(key, v.Elem().Interface(), c.expires)
+	}
+	return nil
+}
+
+func (c *TieredCache) GetMulti(keys ...string) (Getter, error) {
+	return c.remote.GetMulti(keys...)
+}
+
+func (c *TieredCache) Set(key string, value interface{}, expires time.Duration) error {
+	if err := c.remote.Set(key, value, expires); err != nil {
+		return err
+	}
+	c.local.cache.Set(key, value, c.localExpiration(expires))
+	c.publish("key", key)
+	return nil
+}
+
+func (c *TieredCache) Add(key string, value interface{}, expires time.Duration) error {
+	return c.invalidated(key, c.remote.Add(key, value, expires))
+}
+
+func (c *TieredCache) Replace(key string, value interface{}, expires time.Duration) error {
+	return c.invalidated(key, c.remote.Replace(key, value, expires))
+}
+
+func (c *TieredCache) Delete(key string) error {
+	c.local.cache.Delete(key)
+	err := c.remote.Delete(key)
+	if err == nil {
+		c.publish("key", key)
+	}
+	return err
+}
+
+func (c *TieredCache) Increment(key string, n uint64) (newValue uint64, err error) {
+	newValue, err = c.remote.Increment(key, n)
+	return newValue, c.invalidated(key, err)
+}
+
+func (c *TieredCache) Decrement(key string, n uint64) (newValue uint64, err error) {
+	newValue, err = c.remote.Decrement(key, n)
+	return newValue, c.invalidated(key, err)
+}
+
+func (c *TieredCache) Flush() error {
+	if err := c.remote.Flush(); err != nil {
+		return err
+	}
+	_ = c.local.Flush()
+	c.publish("flush", "")
+	return nil
+}
+
+func (c *TieredCache) SetWithTags(key string, value interface{}, expires time.Duration, tags ...string) error {
+	if err := c.remote.SetWithTags(key, value, expires, tags...); err != nil {
+		return err
+	}
+	_ = c.local.SetWithTags(key, value, c.localExpiration(expires), tags...)
+	c.publish("key", key)
+	return nil
+}
+
+func (c *TieredCache) InvalidateTags(tags ...string) error {
+	// The values read from Redis are kept in memory without their tags, the keys removed
+	// from Redis are removed from the memory of the instances
+	keys, err := c.remote.invalidateTags(tags...)
+	_ = c.local.InvalidateTags(tags...)
+	for _, key := range keys {
+		c.local.cache.Delete(key)
+		c.publish("key", key)
+	}
+	return err
+}
+
+// Removes the key from the memory once it was changed in Redis, returns the error of the change
+func (c *TieredCache) invalidated(key string, err error) error {
+	if err == nil {
+		c.local.cache.Delete(key)
+		c.publish("key", key)
+	}
+	return err
+}
+
+// Publishes the invalidation of the key to the other instances
+func (c *TieredCache) publish(kind, name string) {
+	conn := c.remote.conn(c.channel)
+	defer func() {
+		_ = conn.Close()
+	}()
+	if _, err := conn.Do("PUBLISH", c.channel, c.id+" "+kind+" "+name); err != nil {
+		cacheLog.Warn("Failed to publish the invalidation of the tiered cache", "kind", kind, "name", name, "error", err)
+	}
+}
+
+// Applies the invalidation of another instance (id key name, id tag name or id flush)
+func (c *TieredCache) invalidate(message string) {
+	parts := strings.SplitN(message, " ", 3)
+	if len(parts) != 3 || parts[0] == c.id {
+		return
+	}
+	switch parts[1] {
+	case "key":
+		c.local.cache.Delete(parts[2])
+	case "tag":
+		_ = c.local.InvalidateTags(parts[2])
+	case "flush":
+		_ = c.local.Flush()
+	}
+}
+
+// Returns a connection for the subscription to the channel, the messages published in a
+// cluster are sent to all its nodes
+func (c RedisCache) subscriptionConn(channel string) redis.Conn {
+	if c.cluster != nil {
+		return c.cluster.pool(c.cluster.address(redisKeySlot(channel))).Get()
+	}
+	return c.pool.Get()
+}
+
+// Subscribes to the invalidations of the other instances until the cache is closed, the
+// subscription is made again when it fails
+func (c *TieredCache) subscribe() {
+	for {
+		conn := redis.PubSubConn{Conn: c.remote.subscriptionConn(c.channel)}
+		c.mu.Lock()
+		select {
+		case <-c.done:
+			c.mu.Unlock()
+			_ = conn.Close()
+			return
+		default:
+		}
+		c.subscription = conn.Conn
+		c.mu.Unlock()
+
+		if err := conn.Subscribe(c.channel); err == nil {
+			c.receive(conn)
+		}
+		_ = conn.Close()
+
+		select {
+		case <-c.done:
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// Applies the invalidations received until the subscription fails
+func (c *TieredCache) receive(conn redis.PubSubConn) {
+	stopPings := make(chan struct{})
+	defer close(stopPings)
+	go func() {
+		ticker := time.NewTicker(tieredPingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopPings:
+				return
+			case <-ticker.C:
+				if err := conn.Ping(""); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		switch message := conn.ReceiveWithTimeout(2 * tieredPingInterval).(type) {
+		case redis.Subscription:
+			// The invalidations missed while the subscription was down are lost
+			_ = c.local.Flush()
+		case redis.Message:
+			c.invalidate(string(message.Data))
+		case error:
+			select {
+			case <-c.done:
+			default:
+				cacheLog.Warn("The subscription to the invalidations of the tiered cache failed", "error", message)
+			}
+			return
+		}
+	}
+}
+
+// Close stops the subscription to the invalidations
+func (c *TieredCache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	close(c.done)
+	if c.subscription != nil {
+		return c.subscription.Close()
+	}
+	return nil
+}
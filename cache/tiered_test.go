@@ -0,0 +1,102 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+var newRedisTieredCache = func(t *testing.T, defaultExpiration time.Duration) Cache {
+	c := NewTieredCache(newRedisCache(t, defaultExpiration).(RedisCache), 5*time.Second, "revel-cache-test")
+	t.Cleanup(func() {
+		_ = c.Close()
+	})
+	return c
+}
+
+func TestRedisTieredCache_TypicalGetSet(t *testing.T) {
+	typicalGetSet(t, newRedisTieredCache)
+}
+
+func TestRedisTieredCache_IncrDecr(t *testing.T) {
+	incrDecr(t, newRedisTieredCache)
+}
+
+func TestRedisTieredCache_Expiration(t *testing.T) {
+	expiration(t, newRedisTieredCache)
+}
+
+func TestRedisTieredCache_Tags(t *testing.T) {
+	testTags(t, newRedisTieredCache)
+}
+
+func TestRedisTieredCache_RateLimit(t *testing.T) {
+	testRateLimit(t, newRedisTieredCache)
+}
+
+func TestRedisTieredCache_InvalidateTagsOfOtherInstance(t *testing.T) {
+	a := newRedisTieredCache(t, time.Hour).(*TieredCache)
+	b := NewTieredCache(a.remote, 5*time.Second, "revel-cache-test")
+	defer b.Close()
+
+	if err := a.SetWithTags("profile", "foo", time.Minute, "org:7"); err != nil {
+		t.Fatalf("Error setting a value: %s", err)
+	}
+	// The value is read from Redis into the memory of b, without its tags
+	var value string
+	if err := b.Get("profile", &value); err != nil || value != "foo" {
+		t.Fatalf("Expected the value from Redis, got %s / %v", value, err)
+	}
+	if err := a.InvalidateTags("org:7"); err != nil {
+		t.Fatalf("Error invalidating a tag: %s", err)
+	}
+	for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		if err := b.Get("profile", &value); err == ErrCacheMiss {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatalf("Expected the value to be removed from the memory of the other instance, got %s / %v", value, err)
+		}
+	}
+}
+
+func TestTieredCache_Invalidate(t *testing.T) {
+	c := &TieredCache{local: NewInMemoryCache(time.Minute), id: "self"}
+	_ = c.local.Set("user:1", "foo", time.Minute)
+	_ = c.local.SetWithTags("user:2", "bar", time.Minute, "org:7")
+	_ = c.local.Set("user:3", "baz", time.Minute)
+
+	var value string
+	c.invalidate("self key user:1")
+	if err := c.local.Get("user:1", &value); err != nil {
+		t.Errorf("Expected the own invalidations to be skipped, got %v", err)
+	}
+	c.invalidate("other key user:1")
+	if err := c.local.Get("user:1", &value); err != ErrCacheMiss {
+		t.Errorf("Expected the key to be invalidated, got %v", err)
+	}
+	c.invalidate("other tag org:7")
+	if err := c.local.Get("user:2", &value); err != ErrCacheMiss {
+		t.Errorf("Expected the tag to be invalidated, got %v", err)
+	}
+	c.invalidate("other flush ")
+	if err := c.local.Get("user:3", &value); err != ErrCacheMiss {
+		t.Errorf("Expected the cache to be flushed, got %v", err)
+	}
+}
+
+func TestTieredCache_LocalExpiration(t *testing.T) {
+	c := &TieredCache{remote: RedisCache{defaultExpiration: time.Hour}, expires: 5 * time.Second}
+	for expires, expected := range map[time.Duration]time.Duration{
+		time.Second:        time.Second,
+		time.Minute:        5 * time.Second,
+		DefaultExpiryTime:  5 * time.Second,
+		ForEverNeverExpiry: 5 * time.Second,
+	} {
+		if local := c.localExpiration(expires); local != expected {
+			t.Errorf("Expected %s to be kept %s in memory, got %s", expires, expected, local)
+		}
+	}
+}
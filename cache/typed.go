@@ -0,0 +1,96 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// The values are set and read by their type with SetAs and GetAs
+//   if err := cache.SetAs("user:1", user, cache.DefaultExpiryTime); err != nil {
+//   	...
+//   }
+//   user, err := cache.GetAs[models.User]("user:1")
+// The values are encoded by the codec of app.conf, gob by default
+//   cache.codec = gob    # gob, json or msgpack
+// The values set by SetAs are read by GetAs, a value set by Set is not decoded by GetAs (but
+// for the []byte values of the codec).
+
+// Codec encodes the values of SetAs and decodes the values of GetAs
+type Codec interface {
+	Marshal(value interface{}) ([]byte, error)
+	Unmarshal(data []byte, ptrValue interface{}) error
+}
+
+// The codecs of cache.codec
+var (
+	GobCodec     Codec = gobCodec{}
+	JSONCodec    Codec = jsonCodec{}
+	MsgPackCodec Codec = msgPackCodec{}
+
+	codecs = map[string]Codec{
+		"gob":     GobCodec,
+		"json":    JSONCodec,
+		"msgpack": MsgPackCodec,
+	}
+)
+
+// DefaultCodec is the codec of SetAs and GetAs, set by cache.codec
+var DefaultCodec = GobCodec
+
+// The codec of Serialize and Deserialize, the integers are in ASCII and the other values
+// are gob encoded
+type gobCodec struct{}
+
+func (gobCodec) Marshal(value interface{}) ([]byte, error) {
+	return Serialize(value)
+}
+
+func (gobCodec) Unmarshal(data []byte, ptrValue interface{}) error {
+	return Deserialize(data, ptrValue)
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(value interface{}) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+func (jsonCodec) Unmarshal(data []byte, ptrValue interface{}) error {
+	return json.Unmarshal(data, ptrValue)
+}
+
+type msgPackCodec struct{}
+
+func (msgPackCodec) Marshal(value interface{}) ([]byte, error) {
+	return msgpack.Marshal(value)
+}
+
+func (msgPackCodec) Unmarshal(data []byte, ptrValue interface{}) error {
+	return msgpack.Unmarshal(data, ptrValue)
+}
+
+// SetAs encodes the value by the DefaultCodec and sets it in the cache
+func SetAs[T any](key string, value T, expires time.Duration) error {
+	data, err := DefaultCodec.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return Instance.Set(key, data, expires)
+}
+
+// GetAs returns the value of the key decoded by the DefaultCodec, ErrCacheMiss if the key
+// is not in the cache
+func GetAs[T any](key string) (value T, err error) {
+	var data []byte
+	if err = Instance.Get(key, &data); err != nil {
+		return value, err
+	}
+	err = DefaultCodec.Unmarshal(data, &value)
+	return value, err
+}
@@ -0,0 +1,42 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type typedItem struct {
+	Name  string
+	Tags  []string
+	Count int
+}
+
+func TestTypedCache(t *testing.T) {
+	defer func(instance Cache, codec Codec) { Instance, DefaultCodec = instance, codec }(Instance, DefaultCodec)
+	Instance = NewInMemoryCache(time.Hour)
+
+	item := typedItem{Name: "book", Tags: []string{"new"}, Count: 3}
+	for name, codec := range codecs {
+		DefaultCodec = codec
+		if err := SetAs("item", item, DefaultExpiryTime); err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		if value, err := GetAs[typedItem]("item"); err != nil || !reflect.DeepEqual(value, item) {
+			t.Errorf("%s: Expected %v, got %v %v", name, item, value, err)
+		}
+		if err := SetAs("count", 42, DefaultExpiryTime); err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		if value, err := GetAs[int]("count"); err != nil || value != 42 {
+			t.Errorf("%s: Expected 42, got %v %v", name, value, err)
+		}
+		if _, err := GetAs[typedItem]("missing"); err != ErrCacheMiss {
+			t.Errorf("%s: Expected a cache miss, got %v", name, err)
+		}
+	}
+}
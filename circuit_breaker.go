@@ -0,0 +1,214 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// The circuit breaker stops calling an action which keeps failing (panics or 5xx responses),
+// protecting the rest of the application from it. Enable it in app.conf
+//   circuitbreaker.enabled = true
+//   circuitbreaker.window = 1m      # The period the failures are counted over
+//   circuitbreaker.failures = 5     # The minimum number of failures in the window to trip the breaker
+//   circuitbreaker.ratio = 0.5      # The minimum ratio of failed requests in the window to trip the breaker
+//   circuitbreaker.cooldown = 30s   # The time the breaker stays open
+// While the breaker is open the action is not called and a 503 is returned, the error page is
+// rendered once for each format and language and then served from memory. After the cool-down
+// one trial request is let through, if it succeeds the breaker closes otherwise it opens again.
+// The requests started before the breaker opened do not decide the outcome of the trial.
+// The CIRCUIT_BREAKER_OPENED and CIRCUIT_BREAKER_CLOSED events are fired with the *CircuitBreakerEvent.
+
+// CircuitBreakerEvent is the value of the circuit breaker events
+type CircuitBreakerEvent struct {
+	Action   string // The controller (with namespace) and method
+	Failures int    // The failures in the window
+	Requests int    // The requests in the window
+}
+
+// The state of the breaker for an action
+type circuitBreaker struct {
+	windowStart time.Time
+	requests    int
+	failures    int
+	openUntil   time.Time                      // Set while the breaker is open
+	trial       bool                           // Set while the trial request after the cool-down is running
+	responses   map[string]actionCacheResponse // The 503 pages rendered while the breaker is open, by format and language
+}
+
+var circuitBreakerConfig struct {
+	enabled  bool
+	window   time.Duration
+	failures int
+	ratio    float64
+	cooldown time.Duration
+}
+
+var (
+	circuitBreakers     = map[string]*circuitBreaker{}
+	circuitBreakerMutex sync.Mutex
+)
+
+func init() {
+	OnAppStart(func() {
+		circuitBreakerConfig.enabled = Config.BoolDefault("circuitbreaker.enabled", false)
+		circuitBreakerConfig.window = ConfigDurationDefault("circuitbreaker.window", time.Minute, time.Second)
+		circuitBreakerConfig.failures = Config.IntDefault("circuitbreaker.failures", 5)
+		circuitBreakerConfig.ratio = 0.5
+		if ratio, err := strconv.ParseFloat(Config.StringDefault("circuitbreaker.ratio", "0.5"), 64); err == nil {
+			circuitBreakerConfig.ratio = ratio
+		} else {
+			controllerLog.Error("Invalid circuitbreaker.ratio", "error", err)
+		}
+		circuitBreakerConfig.cooldown = ConfigDurationDefault("circuitbreaker.cooldown", 30*time.Second, time.Second)
+	})
+}
+
+// CircuitBreakerFilter counts the failures of the action and returns a 503 without
+// calling the action while its breaker is open
+func CircuitBreakerFilter(c *Controller, fc []Filter) {
+	if !circuitBreakerConfig.enabled || c.MethodType == nil {
+		fc[0](c, fc[1:])
+		return
+	}
+
+	action := c.Type.Name() + "." + c.MethodType.Name
+	retryAfter, trial := circuitBreakerAllow(action, time.Now())
+	if retryAfter > 0 {
+		c.Response.Out.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		c.Response.Status = http.StatusServiceUnavailable
+		result := c.RenderError(&Error{
+			Title:       "Service Unavailable",
+			Description: "The action is temporarily unavailable",
+		})
+		// A problem has the path of the request, it is not cached
+		if _, problem := result.(ProblemResult); !problem {
+			result = &circuitOpenResult{action: action, key: c.Request.Format + "\n" + c.Request.Locale, result: result}
+		}
+		c.Result = result
+		return
+	}
+
+	defer func() {
+		if err := recover(); err != nil {
+			circuitBreakerRecord(action, trial, true, time.Now())
+			panic(err)
+		}
+	}()
+	fc[0](c, fc[1:])
+	circuitBreakerRecord(action, trial, c.Response.Status >= http.StatusInternalServerError, time.Now())
+}
+
+// Returns the time until the breaker of the action closes, zero if the request may be made.
+// Trial is set for the request let through after the cool-down.
+func circuitBreakerAllow(action string, now time.Time) (retryAfter time.Duration, trial bool) {
+	circuitBreakerMutex.Lock()
+	defer circuitBreakerMutex.Unlock()
+	breaker := circuitBreakers[action]
+	if breaker == nil || breaker.openUntil.IsZero() {
+		return 0, false
+	}
+	if now.Before(breaker.openUntil) {
+		return breaker.openUntil.Sub(now), false
+	}
+	if breaker.trial {
+		// Only the trial request is let through
+		return circuitBreakerConfig.cooldown, false
+	}
+	breaker.trial = true
+	return 0, true
+}
+
+// Records the outcome of a request to the action, opening or closing its breaker. Only the
+// trial request closes an open breaker, the outcomes of the other requests ending while the
+// breaker is open are ignored.
+func circuitBreakerRecord(action string, trial, failed bool, now time.Time) {
+	circuitBreakerMutex.Lock()
+	defer circuitBreakerMutex.Unlock()
+	breaker := circuitBreakers[action]
+	if breaker == nil {
+		breaker = &circuitBreaker{windowStart: now}
+		circuitBreakers[action] = breaker
+	}
+
+	if !breaker.openUntil.IsZero() && !trial {
+		return
+	}
+	if trial {
+		breaker.trial = false
+		if failed {
+			breaker.openUntil = now.Add(circuitBreakerConfig.cooldown)
+			controllerLog.Error("Circuit breaker opened again after the trial request failed", "action", action)
+			return
+		}
+		*breaker = circuitBreaker{windowStart: now}
+		controllerLog.Info("Circuit breaker closed", "action", action)
+		fireEvent(CIRCUIT_BREAKER_CLOSED, &CircuitBreakerEvent{Action: action})
+		return
+	}
+
+	if now.Sub(breaker.windowStart) > circuitBreakerConfig.window {
+		breaker.windowStart, breaker.requests, breaker.failures = now, 0, 0
+	}
+	breaker.requests++
+	if failed {
+		breaker.failures++
+	}
+	if failed && breaker.openUntil.IsZero() && breaker.failures >= circuitBreakerConfig.failures &&
+		float64(breaker.failures)/float64(breaker.requests) >= circuitBreakerConfig.ratio {
+		breaker.openUntil = now.Add(circuitBreakerConfig.cooldown)
+		controllerLog.Error("Circuit breaker opened", "action", action, "failures", breaker.failures, "requests", breaker.requests, "cooldown", circuitBreakerConfig.cooldown)
+		fireEvent(CIRCUIT_BREAKER_OPENED, &CircuitBreakerEvent{Action: action, Failures: breaker.failures, Requests: breaker.requests})
+	}
+}
+
+// circuitOpenResult writes the 503 page of the open breaker of the action, the page is
+// rendered by the first request and then served from the breaker
+type circuitOpenResult struct {
+	action string
+	key    string // The format and language of the page
+	result Result
+}
+
+func (r *circuitOpenResult) Apply(req *Request, resp *Response) {
+	circuitBreakerMutex.Lock()
+	breaker := circuitBreakers[r.action]
+	response, found := actionCacheResponse{}, false
+	if breaker != nil {
+		response, found = breaker.responses[r.key]
+	}
+	circuitBreakerMutex.Unlock()
+	if found {
+		resp.WriteHeader(http.StatusServiceUnavailable, response.ContentType)
+		if _, err := resp.GetWriter().Write(response.Body); err != nil {
+			resultsLog.Error("Apply: Response write failed", "error", err)
+		}
+		return
+	}
+
+	writer := resp.GetWriter()
+	body := &bytes.Buffer{}
+	resp.SetWriter(io.MultiWriter(writer, body))
+	r.result.Apply(req, resp)
+	resp.SetWriter(writer)
+	if resp.Status != http.StatusServiceUnavailable {
+		return
+	}
+	circuitBreakerMutex.Lock()
+	defer circuitBreakerMutex.Unlock()
+	// The breaker may have closed while the page was rendered
+	if breaker = circuitBreakers[r.action]; breaker != nil && !breaker.openUntil.IsZero() {
+		if breaker.responses == nil {
+			breaker.responses = map[string]actionCacheResponse{}
+		}
+		breaker.responses[r.key] = actionCacheResponse{ContentType: resp.ContentType, Body: body.Bytes()}
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	circuitBreakerConfig.window, circuitBreakerConfig.failures, circuitBreakerConfig.ratio, circuitBreakerConfig.cooldown =
+		time.Minute, 3, 0.5, 30*time.Second
+	defer func(handlers []EventHandler) {
+		initEventList = handlers
+		circuitBreakers = map[string]*circuitBreaker{}
+	}(initEventList)
+
+	var opened, closed []*CircuitBreakerEvent
+	AddInitEventHandler(func(typeOf int, value interface{}) (responseOf int) {
+		switch typeOf {
+		case CIRCUIT_BREAKER_OPENED:
+			opened = append(opened, value.(*CircuitBreakerEvent))
+		case CIRCUIT_BREAKER_CLOSED:
+			closed = append(closed, value.(*CircuitBreakerEvent))
+		}
+		return
+	})
+
+	now := time.Now()
+	action := "App\\hotels.Show"
+	for _, failed := range []bool{true, false, true, false, true} {
+		if retryAfter, _ := circuitBreakerAllow(action, now); retryAfter != 0 {
+			t.Fatalf("Expected the breaker to be closed")
+		}
+		circuitBreakerRecord(action, false, failed, now)
+	}
+	if len(opened) != 1 || opened[0].Failures != 3 || opened[0].Requests != 5 {
+		t.Fatalf("Expected the breaker to open after 3 failures, got %v", opened)
+	}
+	if retryAfter, _ := circuitBreakerAllow(action, now.Add(10*time.Second)); retryAfter != 20*time.Second {
+		t.Errorf("Expected the breaker to be open for 20s, got %s", retryAfter)
+	}
+
+	// The trial request fails, then succeeds
+	later := now.Add(31 * time.Second)
+	if retryAfter, trial := circuitBreakerAllow(action, later); retryAfter != 0 || !trial {
+		t.Errorf("Expected the trial request to be allowed")
+	}
+	if retryAfter, trial := circuitBreakerAllow(action, later); retryAfter == 0 || trial {
+		t.Errorf("Expected only the trial request to be allowed")
+	}
+	// A request started before the breaker opened does not end the trial
+	circuitBreakerRecord(action, false, false, later)
+	if breaker := circuitBreakers[action]; breaker.openUntil.IsZero() || !breaker.trial {
+		t.Errorf("Expected the breaker to wait for the trial request, got %+v", breaker)
+	}
+	circuitBreakerRecord(action, true, true, later)
+	if retryAfter, _ := circuitBreakerAllow(action, later); retryAfter == 0 {
+		t.Errorf("Expected the breaker to open after the failed trial")
+	}
+	later = later.Add(31 * time.Second)
+	if retryAfter, trial := circuitBreakerAllow(action, later); retryAfter != 0 || !trial {
+		t.Errorf("Expected the trial request to be allowed")
+	}
+	circuitBreakerRecord(action, true, false, later)
+	if retryAfter, _ := circuitBreakerAllow(action, later); len(closed) != 1 || retryAfter != 0 {
+		t.Errorf("Expected the breaker to close after the successful trial")
+	}
+}
+
+type BrokenController struct {
+	*Controller
+}
+
+var brokenControllerCalls int
+
+func (c BrokenController) Show() Result {
+	brokenControllerCalls++
+	c.Response.Status = http.StatusInternalServerError
+	return c.RenderText("broken")
+}
+
+func TestCircuitBreakerFilterCachedPage(t *testing.T) {
+	startFakeBookingApp()
+	circuitBreakerConfig.enabled, circuitBreakerConfig.window, circuitBreakerConfig.failures, circuitBreakerConfig.ratio, circuitBreakerConfig.cooldown =
+		true, time.Minute, 1, 0.5, 30*time.Second
+	defer func() {
+		circuitBreakerConfig.enabled = false
+		circuitBreakers = map[string]*circuitBreaker{}
+	}()
+	RegisterController((*BrokenController)(nil), []*MethodType{{Name: "Show"}})
+
+	request := func() *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/broken", nil)
+		resp := httptest.NewRecorder()
+		c := NewTestController(resp, req)
+		if err := c.SetAction("BrokenController", "Show"); err != nil {
+			t.Fatal(err)
+		}
+		CircuitBreakerFilter(c, []Filter{func(c *Controller, _ []Filter) { c.Result = c.AppController.(*BrokenController).Show() }})
+		c.Result.Apply(c.Request, c.Response)
+		return resp
+	}
+
+	brokenControllerCalls = 0
+	request()
+	first := request()
+	if brokenControllerCalls != 1 || first.Code != http.StatusServiceUnavailable || first.Header().Get("Retry-After") != "30" {
+		t.Fatalf("Expected the open breaker to return a 503, got %d %v", first.Code, first.Header())
+	}
+	breaker := circuitBreakers[ControllerTypeByName("BrokenController", anyModule).Name()+".Show"]
+	if breaker == nil || len(breaker.responses) != 1 {
+		t.Fatalf("Expected the page to be kept by the breaker, got %v", circuitBreakers)
+	}
+	for key := range breaker.responses {
+		breaker.responses[key] = actionCacheResponse{ContentType: "text/html", Body: []byte("cached")}
+	}
+	if second := request(); second.Code != http.StatusServiceUnavailable || second.Body.String() != "cached" {
+		t.Errorf("Expected the kept page to be served, got %d %s", second.Code, second.Body.String())
+	}
+}
@@ -7,29 +7,38 @@ package revel
 import (
 	"io"
 	"net/http"
-	"strconv"
 	"strings"
 
+	"github.com/andybalholm/brotli"
 	"github.com/klauspost/compress/gzip"
 	"github.com/klauspost/compress/zlib"
 )
 
+// The encodings by order of preference, when the client accepts several with the same quality
 var compressionTypes = [...]string{
+	"br",
 	"gzip",
 	"deflate",
 }
 
+// The content types which are compressed, the types with a +json or +xml suffix are as
+// well. The other types (images, archives...) are usually compressed already. More types
+// are compressed with results.compressed.mimes in app.conf
 var compressableMimes = [...]string{
 	"text/plain",
 	"text/html",
 	"text/xml",
 	"text/css",
+	"text/csv",
+	"text/javascript",
+	"text/event-stream",
 	"application/json",
 	"application/xml",
 	"application/xhtml+xml",
 	"application/rss+xml",
 	"application/javascript",
 	"application/x-javascript",
+	"image/svg+xml",
 }
 
 // Local log instance for this class
@@ -54,28 +63,19 @@ type CompressResponseWriter struct {
 	closed             bool
 }
 
-// CompressFilter does compression of response body in gzip/deflate if
-// `results.compressed=true` in the app.conf
+// CompressFilter does compression of response body in brotli/gzip/deflate if
+// `results.compressed=true` in the app.conf. The encoding is negotiated with the quality
+// values of the Accept-Encoding header, the streamed results are compressed as they are
+// flushed.
+//   results.compressed.brotli.level = 5  # From 0 (fastest) to 11 (smallest)
+//   results.compressed.mimes = application/wasm, application/geo+json
 func CompressFilter(c *Controller, fc []Filter) {
 	if c.Response.Out.internalHeader.Server != nil && Config.BoolDefault("results.compressed", false) {
 		if c.Response.Status != http.StatusNoContent && c.Response.Status != http.StatusNotModified {
+			// The response depends on the Accept-Encoding header, even when it is not compressed
+			addVary(c.Response.Out.internalHeader, "Accept-Encoding")
 			if found, compressType, compressWriter := detectCompressionType(c.Request, c.Response); found {
-				writer := CompressResponseWriter{
-					ControllerResponse: c.Response,
-					OriginalWriter:     c.Response.GetWriter(),
-					compressWriter:     compressWriter,
-					compressionType:    compressType,
-					headersWritten:     false,
-					closeNotify:        make(chan bool, 1),
-					closed:             false,
-				}
-				// Swap out the header with our own
-				writer.Header = NewBufferedServerHeader(c.Response.Out.internalHeader.Server)
-				c.Response.Out.internalHeader.Server = writer.Header
-				if w, ok := c.Response.GetWriter().(http.CloseNotifier); ok {
-					writer.parentNotify = w.CloseNotify()
-				}
-				c.Response.SetWriter(&writer)
+				c.Response.SetWriter(newCompressResponseWriter(c.Response, compressType, compressWriter))
 			}
 		} else {
 			compressLog.Debug("CompressFilter: Compression disabled for response ", "status", c.Response.Status)
@@ -84,6 +84,27 @@ func CompressFilter(c *Controller, fc []Filter) {
 	fc[0](c, fc[1:])
 }
 
+// Returns a writer compressing the response, the headers of the response are buffered
+// until the content type is known
+func newCompressResponseWriter(resp *Response, compressType string, compressWriter WriteFlusher) *CompressResponseWriter {
+	writer := &CompressResponseWriter{
+		ControllerResponse: resp,
+		OriginalWriter:     resp.GetWriter(),
+		compressWriter:     compressWriter,
+		compressionType:    compressType,
+		headersWritten:     false,
+		closeNotify:        make(chan bool, 1),
+		closed:             false,
+	}
+	// Swap out the header with our own
+	writer.Header = NewBufferedServerHeader(resp.Out.internalHeader.Server)
+	resp.Out.internalHeader.Server = writer.Header
+	if w, ok := resp.GetWriter().(http.CloseNotifier); ok {
+		writer.parentNotify = w.CloseNotify()
+	}
+	return writer
+}
+
 func (c CompressResponseWriter) CloseNotify() <-chan bool {
 	if c.parentNotify != nil {
 		return c.parentNotify
@@ -94,7 +115,11 @@ func (c CompressResponseWriter) CloseNotify() <-chan bool {
 func (c *CompressResponseWriter) cancel() {
 	c.closed = true
 }
-func (c *CompressResponseWriter) prepareHeaders() {
+
+// Sets the headers of the compressed response, the response of the status (0 when it is not
+// known yet) is not compressed when it is a range of the content, the range is of the
+// uncompressed content
+func (c *CompressResponseWriter) prepareHeaders(status int) {
 	if c.compressionType != "" {
 		responseMime := ""
 		if t := c.Header.Get("Content-Type"); len(t) > 0 {
@@ -102,16 +127,12 @@ func (c *CompressResponseWriter) prepareHeaders() {
 		}
 		responseMime = strings.TrimSpace(strings.SplitN(responseMime, ";", 2)[0])
 		shouldEncode := false
+		partial := status == http.StatusPartialContent || len(c.Header.Get("Content-Range")) > 0
 
-		if len(c.Header.Get("Content-Encoding")) == 0 {
-			for _, compressableMime := range compressableMimes {
-				if responseMime == compressableMime {
-					shouldEncode = true
-					c.Header.Set("Content-Encoding", c.compressionType)
-					c.Header.Del("Content-Length")
-					break
-				}
-			}
+		if len(c.Header.Get("Content-Encoding")) == 0 && isCompressableMime(responseMime) && !partial {
+			shouldEncode = true
+			c.Header.Set("Content-Encoding", c.compressionType)
+			c.Header.Del("Content-Length")
 		}
 
 		if !shouldEncode {
@@ -127,16 +148,37 @@ func (c *CompressResponseWriter) WriteHeader(status int) {
 		return
 	}
 	c.headersWritten = true
-	c.prepareHeaders()
+	c.prepareHeaders(status)
 	c.Header.SetStatus(status)
 }
 
+// Flush sends the content written so far, so the streamed results (server sent events,
+// chunked JSON or CSV) reach the client while they are written
+func (c *CompressResponseWriter) Flush() {
+	if c.closed {
+		return
+	}
+	if !c.headersWritten {
+		c.prepareHeaders(0)
+		c.headersWritten = true
+	}
+	if c.compressionType != "" {
+		if err := c.compressWriter.Flush(); err != nil {
+			compressLog.Error("Flush: Error flushing compress writer", "type", c.compressionType, "error", err)
+			return
+		}
+	}
+	if flusher, ok := c.OriginalWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
 func (c *CompressResponseWriter) Close() error {
 	if c.closed {
 		return nil
 	}
 	if !c.headersWritten {
-		c.prepareHeaders()
+		c.prepareHeaders(0)
 	}
 	if c.compressionType != "" {
 		c.Header.Del("Content-Length")
@@ -174,7 +216,7 @@ func (c *CompressResponseWriter) Write(b []byte) (int, error) {
 	}
 
 	if !c.headersWritten {
-		c.prepareHeaders()
+		c.prepareHeaders(0)
 		c.headersWritten = true
 	}
 	if c.compressionType != "" {
@@ -187,74 +229,12 @@ func (c *CompressResponseWriter) Write(b []byte) (int, error) {
 // from header "Accept-Encoding"
 func detectCompressionType(req *Request, resp *Response) (found bool, compressionType string, compressionKind WriteFlusher) {
 	if Config.BoolDefault("results.compressed", false) {
-		acceptedEncodings := strings.Split(req.GetHttpHeader("Accept-Encoding"), ",")
-
-		largestQ := 0.0
-		chosenEncoding := len(compressionTypes)
-
-		// I have fixed one edge case for issue #914
-		// But it's better to cover all possible edge cases or
-		// Adapt to https://github.com/golang/gddo/blob/master/httputil/header/header.go#L172
-		for _, encoding := range acceptedEncodings {
-			encoding = strings.TrimSpace(encoding)
-			encodingParts := strings.SplitN(encoding, ";", 2)
-
-			// If we are the format "gzip;q=0.8"
-			if len(encodingParts) > 1 {
-				q := strings.TrimSpace(encodingParts[1])
-				if len(q) == 0 || !strings.HasPrefix(q, "q=") {
-					continue
-				}
-
-				// Strip off the q=
-				num, err := strconv.ParseFloat(q[2:], 32)
-				if err != nil {
-					continue
-				}
-
-				if num >= largestQ && num > 0 {
-					if encodingParts[0] == "*" {
-						chosenEncoding = 0
-						largestQ = num
-						continue
-					}
-					for i, encoding := range compressionTypes {
-						if encoding == encodingParts[0] {
-							if i < chosenEncoding {
-								largestQ = num
-								chosenEncoding = i
-							}
-							break
-						}
-					}
-				}
-			} else {
-				// If we can accept anything, chose our preferred method.
-				if encodingParts[0] == "*" {
-					chosenEncoding = 0
-					largestQ = 1
-					break
-				}
-				// This is for just plain "gzip"
-				for i, encoding := range compressionTypes {
-					if encoding == encodingParts[0] {
-						if i < chosenEncoding {
-							largestQ = 1.0
-							chosenEncoding = i
-						}
-						break
-					}
-				}
-			}
-		}
-
-		if largestQ == 0 {
-			return
-		}
-
-		compressionType = compressionTypes[chosenEncoding]
+		compressionType = negotiateEncoding(req.GetHttpHeader("Accept-Encoding"))
 
 		switch compressionType {
+		case "br":
+			compressionKind = brotli.NewWriterLevel(resp.GetWriter(), Config.IntDefault("results.compressed.brotli.level", 5))
+			found = true
 		case "gzip":
 			compressionKind = gzip.NewWriter(resp.GetWriter())
 			found = true
@@ -266,6 +246,65 @@ func detectCompressionType(req *Request, resp *Response) (found bool, compressio
 	return
 }
 
+// Returns the encoding of the Accept-Encoding header with the highest quality, an empty
+// string if none is accepted. A quality of 0 refuses the encoding, the * applies to the
+// encodings which are not listed.
+func negotiateEncoding(acceptEncoding string) (chosen string) {
+	qualities := map[string]float64{}
+	wildcard := 0.0
+	for _, encoding := range parseAccept(acceptEncoding) {
+		if encoding.mediaType == "*" {
+			wildcard = encoding.quality
+		} else {
+			qualities[encoding.mediaType] = encoding.quality
+		}
+	}
+	best := 0.0
+	for _, encoding := range compressionTypes {
+		quality, listed := qualities[encoding]
+		if !listed {
+			quality = wildcard
+		}
+		if quality > best {
+			chosen, best = encoding, quality
+		}
+	}
+	return
+}
+
+// Returns true if the content type is compressed
+func isCompressableMime(mime string) bool {
+	if mime == "" {
+		return false
+	}
+	if strings.HasSuffix(mime, "+json") || strings.HasSuffix(mime, "+xml") {
+		return true
+	}
+	for _, compressableMime := range compressableMimes {
+		if mime == compressableMime {
+			return true
+		}
+	}
+	for _, compressableMime := range strings.Split(Config.StringDefault("results.compressed.mimes", ""), ",") {
+		if mime == strings.TrimSpace(compressableMime) {
+			return true
+		}
+	}
+	return false
+}
+
+// Adds the header name to the Vary header, unless it is listed already
+func addVary(header *RevelHeader, name string) {
+	for _, value := range header.GetAll("Vary") {
+		for _, varied := range strings.Split(value, ",") {
+			if varied = strings.TrimSpace(varied); varied == "*" || strings.EqualFold(varied, name) {
+				return
+			}
+		}
+	}
+	header.Add("Vary", name)
+}
+
 // BufferedServerHeader will not send content out until the Released is called, from that point on it will act normally
 // It implements all the ServerHeader
 type BufferedServerHeader struct {
@@ -298,7 +337,7 @@ func (bsh *BufferedServerHeader) Set(key string, value string) {
 }
 func (bsh *BufferedServerHeader) Add(key string, value string) {
 	if bsh.released {
-		bsh.original.Set(key, value)
+		bsh.original.Add(key, value)
 	} else {
 		old := []string{}
 		if v, found := bsh.headerMap[key]; found {
@@ -338,8 +377,12 @@ func (bsh *BufferedServerHeader) SetStatus(statusCode int) {
 func (bsh *BufferedServerHeader) Release() {
 	bsh.released = true
 	for k, v := range bsh.headerMap {
-		for _, r := range v {
-			bsh.original.Set(k, r)
+		for i, r := range v {
+			if i == 0 {
+				bsh.original.Set(k, r)
+			} else {
+				bsh.original.Add(k, r)
+			}
 		}
 	}
 	for _, c := range bsh.cookieList {
@@ -5,9 +5,16 @@
 package revel
 
 import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/andybalholm/brotli"
 )
 
 // Test that the render response is as expected.
@@ -59,3 +66,77 @@ func BenchmarkRenderUnCompressed(b *testing.B) {
 		hotels.Show(3).Apply(c.Request, c.Response)
 	}
 }
+
+func TestNegotiateEncoding(t *testing.T) {
+	for accept, expected := range map[string]string{
+		"":                            "",
+		"gzip":                        "gzip",
+		"gzip, deflate, br":           "br",
+		"gzip;q=1.0, br;q=0.5":        "gzip",
+		"*":                           "br",
+		"*;q=0.5, br;q=0":             "gzip",
+		"identity":                    "",
+		"deflate;q=0.8, gzip;q=0.001": "deflate",
+		"GZIP":                        "gzip",
+	} {
+		if actual := negotiateEncoding(accept); actual != expected {
+			t.Errorf("%q: expected %q got %q", accept, expected, actual)
+		}
+	}
+}
+
+func TestCompressFilterStreaming(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOption("results.compressed", "true")
+	defer Config.SetOption("results.compressed", "false")
+
+	request := func(accept string, result func(c *Controller) Result) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/hotels", nil)
+		req.Header.Set("Accept-Encoding", accept)
+		resp := httptest.NewRecorder()
+		c := NewTestController(resp, req)
+		CompressFilter(c, []Filter{func(c *Controller, _ []Filter) {
+			c.Result = result(c)
+		}})
+		c.Result.Apply(c.Request, c.Response)
+		c.Response.GetWriter().(io.Closer).Close()
+		return resp
+	}
+
+	resp := request("gzip, br", func(c *Controller) Result {
+		return c.RenderJSONStream(func(yield func(v interface{}) bool) {
+			for i := 0; i < 3 && yield(i); i++ {
+			}
+		})
+	})
+	if resp.Header().Get("Content-Encoding") != "br" || resp.Header().Get("Vary") != "Accept-Encoding" || !resp.Flushed {
+		t.Fatalf("Expected a flushed brotli response, got %v", resp.Header())
+	}
+	if body, _ := ioutil.ReadAll(brotli.NewReader(resp.Body)); string(body) != "[0,1,2]" {
+		t.Errorf("Unexpected body %q", body)
+	}
+
+	resp = request("gzip", func(c *Controller) Result {
+		return c.RenderBinary(strings.NewReader("PNG"), "image.png", Inline, time.Now())
+	})
+	if resp.Header().Get("Content-Encoding") != "" || resp.Body.String() != "PNG" || resp.Header().Get("Vary") != "Accept-Encoding" {
+		t.Errorf("Expected the image not to be compressed, got %v %q", resp.Header(), resp.Body.String())
+	}
+
+	// A range of the content is not compressed
+	resp = request("gzip", func(c *Controller) Result {
+		return partialResult("0123456789")
+	})
+	if resp.Header().Get("Content-Encoding") != "" || resp.Code != http.StatusPartialContent || resp.Body.String() != "0123456789" {
+		t.Errorf("Expected the range not to be compressed, got %d %v %q", resp.Code, resp.Header(), resp.Body.String())
+	}
+}
+
+// A result sending a range of a text
+type partialResult string
+
+func (r partialResult) Apply(req *Request, resp *Response) {
+	resp.Out.internalHeader.Set("Content-Range", fmt.Sprintf("bytes 0-%d/1200", len(r)-1))
+	resp.WriteHeader(http.StatusPartialContent, "text/plain")
+	resp.GetWriter().Write([]byte(r))
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+func init() {
+	OnAppStart(func() {
+		contentReloadConfig.token = Config.StringDefault("reload.api.token", "")
+		contentReloadConfig.path = Config.StringDefault("reload.api.path", "/@reload")
+	})
+}
+
+// The settings of the ContentReloadFilter, loaded when the application starts
+var contentReloadConfig = struct {
+	token string
+	path  string
+}{path: "/@reload"}
+
+// ReloadContent parses the templates and the message files again and replaces the
+// loaded ones, this works in production mode where the files are not watched.
+// Nothing is replaced unless all the templates and messages are parsed without errors,
+// so a bad deploy of the content leaves the application serving the previous content.
+func ReloadContent() error {
+	if MainTemplateLoader == nil {
+		return errors.New("The template loader has not been initialized")
+	}
+	loader := MainTemplateLoader
+	loader.templateMutex.Lock()
+	defer loader.templateMutex.Unlock()
+
+	runtimeLoader, templateErr := loader.load()
+	if templateErr != nil {
+		return templateErr
+	}
+	loaded, err := readMessages(filepath.Join(BasePath, messageFilesDirectory))
+	if err != nil {
+		return err
+	}
+
+	loader.runtimeLoader.Store(runtimeLoader)
+	setMessages(loaded)
+	RevelLog.Info("Reloaded templates and messages", "templates", len(runtimeLoader.TemplatePaths), "languages", len(loaded))
+	return nil
+}
+
+// ContentReloadFilter serves the control API which calls ReloadContent, it is enabled by
+// setting a secret token in app.conf
+//   reload.api.token = secret
+//   reload.api.path = /@reload
+// The content is reloaded by a POST with the token as the bearer
+//   curl -X POST -H "Authorization: Bearer secret" https://example.com/@reload
+func ContentReloadFilter(c *Controller, fc []Filter) {
+	token := contentReloadConfig.token
+	if token == "" || c.Request.GetPath() != contentReloadConfig.path {
+		fc[0](c, fc[1:])
+		return
+	}
+
+	if c.Request.Method != "POST" {
+		c.Response.Status = http.StatusMethodNotAllowed
+		c.Result = c.RenderJSON(map[string]string{"error": "POST required"})
+		return
+	}
+	authorization := c.Request.GetHttpHeader("Authorization")
+	if !strings.HasPrefix(authorization, "Bearer ") ||
+		subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(authorization, "Bearer ")), []byte(token)) != 1 {
+		RevelLog.Warn("ContentReloadFilter: Unauthorized reload request", "ip", c.ClientIP)
+		c.Response.Status = http.StatusUnauthorized
+		c.Result = c.RenderJSON(map[string]string{"error": "Unauthorized"})
+		return
+	}
+
+	if err := ReloadContent(); err != nil {
+		RevelLog.Error("ContentReloadFilter: Reload failed, the previous content is kept", "error", err)
+		c.Response.Status = http.StatusUnprocessableEntity
+		c.Result = c.RenderJSON(map[string]string{"error": err.Error()})
+		return
+	}
+	c.Result = c.RenderJSON(map[string]string{"status": "reloaded"})
+}
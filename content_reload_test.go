@@ -0,0 +1,56 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReloadContent(t *testing.T) {
+	startFakeBookingApp()
+	if err := ReloadContent(); err != nil {
+		t.Fatalf("Unexpected error reloading %s", err)
+	}
+
+	// A template which fails to parse keeps the current templates
+	dir, _ := ioutil.TempDir("", "revel-reload")
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "broken.html"), []byte("{{if}"), 0644)
+	paths := MainTemplateLoader.paths
+	MainTemplateLoader.paths = append([]string{dir}, paths...)
+	defer func() { MainTemplateLoader.paths = paths }()
+
+	current := MainTemplateLoader.runtimeLoader.Load()
+	if err := ReloadContent(); err == nil {
+		t.Errorf("Expected the broken template to fail the reload")
+	}
+	if MainTemplateLoader.runtimeLoader.Load() != current {
+		t.Errorf("Expected the current templates to be kept")
+	}
+	if _, err := MainTemplateLoader.TemplateLang("hotels/show.html", ""); err != nil {
+		t.Errorf("Expected the current templates to be served, %s", err)
+	}
+}
+
+func TestContentReloadFilter(t *testing.T) {
+	startFakeBookingApp()
+	contentReloadConfig.token = "secret"
+	defer func() { contentReloadConfig.token = "" }()
+
+	for authorization, status := range map[string]int{"": http.StatusUnauthorized, "Bearer wrong": http.StatusUnauthorized, "Bearer secret": http.StatusOK} {
+		req, _ := http.NewRequest("POST", "/@reload", nil)
+		req.Header.Set("Authorization", authorization)
+		c := NewTestController(httptest.NewRecorder(), req)
+		ContentReloadFilter(c, NilChain)
+		if c.Response.Status != status {
+			t.Errorf("Expected status %d for %q, got %d", status, authorization, c.Response.Status)
+		}
+	}
+}
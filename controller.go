@@ -8,7 +8,9 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -37,10 +39,12 @@ type Controller struct {
 	Flash      Flash                  // User cookie, cleared after 1 request.
 	Session    Session                // Session, stored in cookie, signed.
 	Params     *Params                // Parameters from URL and form (including multipart).
-	Args       map[string]interface{} // Per-request scratch space.
+	Args       map[string]interface{} // Per-request scratch space, see SetReqValue for typed values.
 	ViewArgs   map[string]interface{} // Variables passed to the template.
 	Validation *Validation            // Data validation helpers
 	Log        logger.MultiLogger     // Context Logger
+
+	reqValues map[interface{}]interface{} // The request values, see SetReqValue
 }
 
 // The map of controllers, controllers are mapped by using the namespace|controller_name as the key
@@ -94,6 +98,7 @@ func (c *Controller) Destroy() {
 	c.Response.Destroy()
 	c.Params = nil
 	c.Args = nil
+	c.reqValues = nil
 	c.ViewArgs = nil
 	c.Name = ""
 	c.Type = nil
@@ -118,20 +123,38 @@ func (c *Controller) FlashParams() {
 }
 
 func (c *Controller) SetCookie(cookie *http.Cookie) {
-	c.Response.Out.internalHeader.SetCookie(cookie.String())
+	value := cookie.String()
+	if partitionedCookie(cookie.Name) && cookie.Secure {
+		value += "; Partitioned"
+	}
+	c.Response.Out.internalHeader.SetCookie(value)
 }
 
 type ErrorCoder interface {
 	HTTPCode() int
 }
 
+// RenderError renders the error page of the error, or its problem details (RFC 7807) when
+// the client prefers JSON.
 func (c *Controller) RenderError(err error) Result {
 	if coder, ok := err.(ErrorCoder); ok {
 		c.setStatusIfNil(coder.HTTPCode())
+	} else if problemType := problemTypeOf(err); problemType != nil && problemType.status != 0 {
+		c.setStatusIfNil(problemType.status)
 	} else {
 		c.setStatusIfNil(http.StatusInternalServerError)
 	}
 
+	if c.acceptsProblem() {
+		problem := NewProblem(err, c.Response.Status)
+		if problem.Status == 0 {
+			problem.Status = c.Response.Status
+		}
+		if problem.Instance == "" {
+			problem.Instance = c.Request.GetPath()
+		}
+		return ProblemResult{problem}
+	}
 	return ErrorResult{c.ViewArgs, err}
 }
 
@@ -187,9 +210,36 @@ func (c *Controller) Render(extraViewArgs ...interface{}) Result {
 			"(Action", c.Action, ")"))
 	}
 
+	// A document is converted from the HTML template
+	if _, found := documentFormats[c.Request.Format]; found {
+		return c.RenderDocument(c.Name+"/"+c.MethodType.Name+".html", c.Request.Format, "")
+	}
 	return c.RenderTemplate(c.Name + "/" + c.MethodType.Name + "." + c.Request.Format)
 }
 
+// RenderDocument renders the HTML template and converts it with the converter registered
+// for the format (see RegisterDocumentConverter). The filename is sent in the
+// Content-Disposition header when it is not empty.
+func (c *Controller) RenderDocument(templatePath, format, filename string) Result {
+	document, found := documentFormats[format]
+	if !found {
+		return c.RenderError(fmt.Errorf("No document converter registered for the format %s", format))
+	}
+	result := c.RenderTemplate(templatePath)
+	templateResult, ok := result.(*RenderTemplateResult)
+	if !ok {
+		// The template failed to load
+		return result
+	}
+	return &DocumentResult{
+		Template:  templateResult.Template,
+		ViewArgs:  templateResult.ViewArgs,
+		MediaType: document.mediaType,
+		Filename:  filename,
+		Converter: document.converter,
+	}
+}
+
 // RenderTemplate method does less magical way to render a template.
 // Renders the given template, using the current ViewArgs.
 func (c *Controller) RenderTemplate(templatePath string) Result {
@@ -208,6 +258,21 @@ func (c *Controller) RenderTemplate(templatePath string) Result {
 	}
 }
 
+// RenderTemplateFragment renders the fragment (a block or define) of the template instead
+// of the whole page, so a partial page update (htmx, Turbo) needs no template of its own.
+// The view args are added to the ViewArgs of the controller.
+//   c.RenderTemplateFragment("Users/Index.html", "rowList", map[string]interface{}{"users": users})
+func (c *Controller) RenderTemplateFragment(templatePath, fragment string, viewArgs map[string]interface{}) Result {
+	for key, value := range viewArgs {
+		c.ViewArgs[key] = value
+	}
+	result := c.RenderTemplate(templatePath)
+	if templateResult, ok := result.(*RenderTemplateResult); ok {
+		templateResult.Fragment = fragment
+	}
+	return result
+}
+
 // TemplateOutput returns the result of the template rendered using the controllers ViewArgs.
 func (c *Controller) TemplateOutput(templatePath string) (data []byte,err error)  {
 	return TemplateOutputArgs(templatePath,c.ViewArgs)
@@ -217,21 +282,84 @@ func (c *Controller) TemplateOutput(templatePath string) (data []byte,err error)
 func (c *Controller) RenderJSON(o interface{}) Result {
 	c.setStatusIfNil(http.StatusOK)
 
-	return RenderJSONResult{o, ""}
+	return RenderJSONResult{o, "", nil}
+}
+
+// RenderJSONWith renders the JSON with the options instead of the options of app.conf,
+// see DefaultJSONOptions.
+func (c *Controller) RenderJSONWith(o interface{}, options *JSONOptions) Result {
+	c.setStatusIfNil(http.StatusOK)
+
+	return RenderJSONResult{o, "", options}
 }
 
 // RenderJSONP renders JSONP result using encoding/json.Marshal
 func (c *Controller) RenderJSONP(callback string, o interface{}) Result {
 	c.setStatusIfNil(http.StatusOK)
 
-	return RenderJSONResult{o, callback}
+	return RenderJSONResult{o, callback, nil}
+}
+
+// RenderJSONStream renders the values yielded by the iterator as a JSON array, every value
+// is encoded and written as it is yielded so a large collection is never held in memory
+//   return c.RenderJSONStream(func(yield func(v interface{}) bool) {
+//   	for rows.Next() {
+//   		...
+//   		if !yield(booking) {
+//   			return
+//   		}
+//   	}
+//   })
+// yield returns false once the response cannot be written, the iterator should then stop.
+func (c *Controller) RenderJSONStream(iter func(yield func(v interface{}) bool)) Result {
+	c.setStatusIfNil(http.StatusOK)
+
+	return &RenderJSONStreamResult{iter}
+}
+
+// RenderProto renders the protobuf encoding of the message, see ProtoMessageCodec
+func (c *Controller) RenderProto(msg ProtoMessage) Result {
+	c.setStatusIfNil(http.StatusOK)
+
+	return RenderProtoResult{msg}
+}
+
+// RenderMsgPack renders the MessagePack encoding of the object, the struct fields are
+// named by their json tags.
+func (c *Controller) RenderMsgPack(o interface{}) Result {
+	c.setStatusIfNil(http.StatusOK)
+
+	return RenderMsgPackResult{o}
+}
+
+// RenderCSV renders the rows as CSV, see CSVOptions for the options (nil for the defaults)
+func (c *Controller) RenderCSV(rows [][]string, options *CSVOptions) Result {
+	c.setStatusIfNil(http.StatusOK)
+
+	return &RenderCSVResult{rows: rows, options: csvOptions(options)}
+}
+
+// RenderCSVStream renders the rows received from the channel as CSV, every row is written
+// as it is received until the channel is closed
+func (c *Controller) RenderCSVStream(rows <-chan []string, options *CSVOptions) Result {
+	c.setStatusIfNil(http.StatusOK)
+
+	return &RenderCSVResult{stream: rows, options: csvOptions(options)}
 }
 
 // RenderXML uses encoding/xml.Marshal to return XML to the client.
 func (c *Controller) RenderXML(o interface{}) Result {
 	c.setStatusIfNil(http.StatusOK)
 
-	return RenderXMLResult{o}
+	return RenderXMLResult{o, nil}
+}
+
+// RenderXMLWith renders the XML with the options instead of the options of app.conf,
+// see DefaultXMLOptions.
+func (c *Controller) RenderXMLWith(o interface{}, options *XMLOptions) Result {
+	c.setStatusIfNil(http.StatusOK)
+
+	return RenderXMLResult{o, options}
 }
 
 // RenderText renders plaintext in response, printf style.
@@ -252,6 +380,24 @@ func (c *Controller) RenderHTML(html string) Result {
 	return &RenderHTMLResult{html}
 }
 
+// RenderAuto renders the object in the media type the Accept header of the request
+// prefers, see RegisterAutoRenderer. For an action annotated with @Produces, a path with
+// the extension of a format or a request without an Accept header, the Request.Format
+// is rendered. JSON, XML and MessagePack are marshalled, txt is the object printed with
+// %v, a protobuf message is rendered as protobuf for the "proto" format and html renders
+// the action template with the object in the ViewArgs as "result". A 406 is returned
+// when the client accepts none of the media types. Unless the path has the extension of a
+// format, the Vary header of the response has Accept.
+func (c *Controller) RenderAuto(o interface{}) Result {
+	renderer := c.autoRenderer()
+	if renderer == nil {
+		c.Response.Status = http.StatusNotAcceptable
+		return NotAcceptableResult(c, autoRendererMediaTypes())
+	}
+	c.Request.Format = renderer.format
+	return renderer.render(c, o)
+}
+
 // Todo returns an HTTP 501 Not Implemented "todo" indicating that the
 // action isn't done yet.
 func (c *Controller) Todo() Result {
@@ -277,6 +423,20 @@ func (c *Controller) NotFound(msg string, objs ...interface{}) Result {
 	})
 }
 
+// Unauthorized returns an HTTP 401 Unauthorized response whose body is the
+// formatted string of msg and objs.
+func (c *Controller) Unauthorized(msg string, objs ...interface{}) Result {
+	finalText := msg
+	if len(objs) > 0 {
+		finalText = fmt.Sprintf(msg, objs...)
+	}
+	c.Response.Status = http.StatusUnauthorized
+	return c.RenderError(&Error{
+		Title:       "Unauthorized",
+		Description: finalText,
+	})
+}
+
 // Forbidden returns an HTTP 403 Forbidden response whose body is the
 // formatted string of msg and objs.
 func (c *Controller) Forbidden(msg string, objs ...interface{}) Result {
@@ -314,6 +474,8 @@ func (c *Controller) RenderFile(file *os.File, delivery ContentDisposition) Resu
 // the output from some function, or bytes streamed from somewhere else, as long
 // it implements io.Reader).  When called directly on something generated or
 // streamed, modtime should mostly likely be time.Now().
+// When memfile is an io.ReadSeeker (a file, a bytes.Reader) the ranges of the Range
+// header are served, so videos can be seeked and downloads resumed.
 func (c *Controller) RenderBinary(memfile io.Reader, filename string, delivery ContentDisposition, modtime time.Time) Result {
 	c.setStatusIfNil(http.StatusOK)
 
@@ -326,6 +488,28 @@ func (c *Controller) RenderBinary(memfile io.Reader, filename string, delivery C
 	}
 }
 
+// RenderByteRanges serves the content, or the ranges of it asked by the Range header of
+// the request. The content type is found from the extension of the file name.
+func (c *Controller) RenderByteRanges(content io.ReadSeeker, filename string, modtime time.Time) Result {
+	c.setStatusIfNil(http.StatusOK)
+
+	return &ByteRangesResult{Content: content, ContentType: ContentTypeByFilename(filename), ModTime: modtime}
+}
+
+// WithTrailers returns the result, with the trailers the function returns once the body
+// of the result is written.
+func (c *Controller) WithTrailers(result Result, trailers func(body *BodyDigest) map[string]string) Result {
+	return &TrailerResult{Result: result, Trailers: trailers}
+}
+
+// RenderMultipartMixed renders the parts written by the function as a multipart/mixed
+// response, the parts are sent as they are written.
+func (c *Controller) RenderMultipartMixed(write func(parts *multipart.Writer) error) Result {
+	c.setStatusIfNil(http.StatusOK)
+
+	return &MultipartResult{subtype: "mixed", write: write}
+}
+
 // Redirect to an action or to a URL.
 //   c.Redirect(Controller.Action)
 //   c.Redirect("/controller/action")
@@ -335,11 +519,63 @@ func (c *Controller) Redirect(val interface{}, args ...interface{}) Result {
 
 	if url, ok := val.(string); ok {
 		if len(args) == 0 {
-			return &RedirectToURLResult{url}
+			return &RedirectToURLResult{url, nil}
 		}
-		return &RedirectToURLResult{fmt.Sprintf(url, args...)}
+		return &RedirectToURLResult{fmt.Sprintf(url, args...), nil}
+	}
+	return &RedirectToActionResult{val, args, nil}
+}
+
+// RedirectStatus redirects to an action or to a URL like Redirect, with the status:
+// 301, 302, 303 (See Other, the method becomes GET), 307 or 308 (the method is kept).
+func (c *Controller) RedirectStatus(status int, val interface{}, args ...interface{}) Result {
+	c.Response.Status = status
+	return c.Redirect(val, args...)
+}
+
+// RedirectSeeOther redirects with a 303, the client follows it with a GET. It is the
+// redirect of a Post/Redirect/Get flow.
+func (c *Controller) RedirectSeeOther(val interface{}, args ...interface{}) Result {
+	return c.RedirectStatus(http.StatusSeeOther, val, args...)
+}
+
+// RedirectTemporary redirects with a 307, the client follows it with the same method and body.
+func (c *Controller) RedirectTemporary(val interface{}, args ...interface{}) Result {
+	return c.RedirectStatus(http.StatusTemporaryRedirect, val, args...)
+}
+
+// RedirectPermanent redirects with a 308, the client follows it with the same method and body.
+func (c *Controller) RedirectPermanent(val interface{}, args ...interface{}) Result {
+	return c.RedirectStatus(http.StatusPermanentRedirect, val, args...)
+}
+
+// RedirectWithQuery redirects like Redirect, adding the query string built from the map
+//   c.RedirectWithQuery(Hotels.Index, map[string]string{"page": "2", "q": "paris"})
+func (c *Controller) RedirectWithQuery(val interface{}, query map[string]string, args ...interface{}) Result {
+	values := url.Values{}
+	for key, value := range query {
+		values.Set(key, value)
+	}
+	switch result := c.Redirect(val, args...).(type) {
+	case *RedirectToURLResult:
+		result.query = values
+		return result
+	case *RedirectToActionResult:
+		result.query = values
+		return result
+	default:
+		return result
+	}
+}
+
+// RedirectWithFlash sets the flash messages and redirects like Redirect, the Post/Redirect/Get
+// flow needs a single statement
+//   return c.RedirectWithFlash(Hotels.Show, map[string]string{"success": "Booked"}, hotel.HotelID)
+func (c *Controller) RedirectWithFlash(val interface{}, flash map[string]string, args ...interface{}) Result {
+	for key, value := range flash {
+		c.Flash.Out[key] = value
 	}
-	return &RedirectToActionResult{val, args}
+	return c.Redirect(val, args...)
 }
 
 // This stats returns some interesting stats based on what is cached in memory
@@ -388,6 +624,13 @@ func (c *Controller) SetTypeAction(controllerName, methodName string, typeOfCont
 	c.Name, c.MethodName = c.Type.Type.Name(), c.MethodType.Name
 	c.Action = c.Name + "." + c.MethodName
 
+	// Add the default view args defined by the @ViewArg annotation
+	if c.ViewArgs != nil {
+		for key, value := range c.MethodType.viewArgs {
+			c.ViewArgs[key] = value
+		}
+	}
+
 	// Update Logger with controller and namespace
 	if c.Log != nil {
 		c.Log = c.Log.New("action", c.Action, "namespace", c.Type.Namespace)
@@ -406,6 +649,7 @@ func (c *Controller) SetTypeAction(controllerName, methodName string, typeOfCont
 	// Instantiate the controller.
 	c.AppController = cachedControllerMap[c.Name].Pop()
 	c.setAppControllerFields()
+	c.setBoundFields()
 
 	return nil
 }
@@ -416,6 +660,10 @@ func ControllerTypeByName(controllerName string, moduleSource *Module) (c *Contr
 		// Backup, passed in controllerName should be in lower case, but may not be
 		if c, found = controllers[strings.ToLower(controllerName)]; !found {
 			controllerLog.Debug("ControllerTypeByName: Cannot find controller in controllers map ", "controller", controllerName)
+			if moduleSource == anyModule && controllerShortNameAmbiguous(controllerName) {
+				controllerLog.Warn("ControllerTypeByName: Controller name is used by more than one module, use the namespace", "controller", controllerName)
+				return
+			}
 			// Search for the controller by name
 			for _, cType := range controllers {
 				testControllerName := strings.ToLower(cType.Type.Name())
@@ -502,23 +750,32 @@ func findControllers(appControllerType reflect.Type) (indexes [][]int) {
 }
 
 // RegisterController registers a Controller and its Methods with Revel.
-func RegisterController(c interface{}, methods []*MethodType) {
+// Annotations passed in are attached to the controller type.
+func RegisterController(c interface{}, methods []*MethodType, annotations ...*FunctionalAnnotation) {
+	// Fetch module for controller, if none found controller must be part of the app
+	controllerModule := ModuleFromPath(reflect.TypeOf(c).Elem().PkgPath(), true)
+	registerController(controllerModule, c, methods, annotations)
+}
+
+// Registers the controller in the module
+func registerController(controllerModule *Module, c interface{}, methods []*MethodType, annotations []*FunctionalAnnotation) {
 	// De-star the controller type
 	// (e.g. given TypeOf((*Application)(nil)), want TypeOf(Application))
 	elem := reflect.TypeOf(c).Elem()
 
-	// De-star all of the method arg types too.
+	// De-star all of the method arg types too, methods which have been registered
+	// before (with the lowerName set) are already de-starred.
 	for _, m := range methods {
+		if m.lowerName != "" {
+			continue
+		}
 		m.lowerName = strings.ToLower(m.Name)
 		for _, arg := range m.Args {
 			arg.Type = arg.Type.Elem()
 		}
 	}
 
-	// Fetch module for controller, if none found controller must be part of the app
-	controllerModule := ModuleFromPath(elem.PkgPath(), true)
-
-	controllerType := AddControllerType(controllerModule, elem, methods)
+	controllerType := AddControllerType(controllerModule, elem, methods, annotations...)
 
 	controllerLog.Debug("RegisterController:Registered controller", "controller", controllerType.Name())
 }
@@ -0,0 +1,113 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"strings"
+)
+
+// The policies for controllers registered with the same name, set by `controller.conflict.policy`
+//   first-wins        - the first controller registered keeps the name (default)
+//   error             - the application fails to start
+//   require-namespace - a short name used by more than one controller is not mapped, the
+//                       controllers must be referenced with their namespace (like admin\Users).
+//                       Controllers with the same namespaced name fail the application.
+const (
+	CONTROLLER_CONFLICT_FIRST_WINS        = "first-wins"
+	CONTROLLER_CONFLICT_ERROR             = "error"
+	CONTROLLER_CONFLICT_REQUIRE_NAMESPACE = "require-namespace"
+)
+
+// ControllerConflict describes two different controllers registered with the same name
+type ControllerConflict struct {
+	Name        string          // The short or namespaced name claimed by both controllers
+	Registered  *ControllerType // The controller registered first
+	Conflicting *ControllerType // The controller registered later with the same name
+}
+
+var (
+	controllerConflicts []*ControllerConflict
+	shortNameOwners     = map[string]*ControllerType{} // The first controller registered with each short name
+)
+
+// Fail on startup if the policy does not allow the conflicts found
+func init() {
+	OnAppStart(func() {
+		policy := controllerConflictPolicy()
+		failed := false
+		for _, conflict := range controllerConflicts {
+			namespaced := strings.Contains(conflict.Name, namespaceSeperator)
+			if policy == CONTROLLER_CONFLICT_ERROR || (policy == CONTROLLER_CONFLICT_REQUIRE_NAMESPACE && namespaced) {
+				controllerLog.Error("Controller name conflict", "name", conflict.Name,
+					"registered", conflict.Registered.Type.PkgPath(), "conflicting", conflict.Conflicting.Type.PkgPath())
+				failed = true
+			}
+		}
+		if failed {
+			controllerLog.Fatal("Controllers exist with conflicting names, see the errors above", "policy", policy)
+		}
+	}, 5)
+}
+
+// ControllerConflicts returns the name conflicts found while registering the controllers
+func ControllerConflicts() []*ControllerConflict {
+	return controllerConflicts
+}
+
+// Returns the configured conflict policy, the controllers may be registered before the
+// configuration is loaded
+func controllerConflictPolicy() string {
+	if Config == nil {
+		return CONTROLLER_CONFLICT_FIRST_WINS
+	}
+	return Config.StringDefault("controller.conflict.policy", CONTROLLER_CONFLICT_FIRST_WINS)
+}
+
+// Records the conflict between the registered controller and the conflicting one
+func addControllerConflict(name string, registered, conflicting *ControllerType) {
+	for _, conflict := range controllerConflicts {
+		if conflict.Name == name && conflict.Conflicting.Type == conflicting.Type {
+			return
+		}
+	}
+	controllerConflicts = append(controllerConflicts, &ControllerConflict{Name: name, Registered: registered, Conflicting: conflicting})
+	controllerLog.Warn("Controller registered with a name already in use", "name", name,
+		"registered", registered.Type.PkgPath(), "conflicting", conflicting.Type.PkgPath(), "policy", controllerConflictPolicy())
+}
+
+// Maps the short name of an application controller into the global namespace, a short name
+// claimed by controllers in different modules is recorded as a conflict
+func registerShortName(ct *ControllerType) {
+	shortName := ct.ShortName()
+	owner, found := shortNameOwners[shortName]
+	if !found || owner.Type == ct.Type {
+		shortNameOwners[shortName] = ct
+		if ct.ModuleSource == appModule {
+			controllers[shortName] = ct
+		}
+		return
+	}
+	addControllerConflict(shortName, owner, ct)
+	if controllerConflictPolicy() == CONTROLLER_CONFLICT_REQUIRE_NAMESPACE {
+		delete(controllers, shortName)
+	} else if _, mapped := controllers[shortName]; !mapped && ct.ModuleSource == appModule {
+		// The application controller takes the short name from the module controllers
+		controllers[shortName] = ct
+	}
+}
+
+// Returns true if the short name may not be used to find a controller
+func controllerShortNameAmbiguous(shortName string) bool {
+	if controllerConflictPolicy() != CONTROLLER_CONFLICT_REQUIRE_NAMESPACE {
+		return false
+	}
+	shortName = strings.ToLower(shortName)
+	for _, conflict := range controllerConflicts {
+		if conflict.Name == shortName {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// Registers revel.Request and http.Request, two types with the same name in different packages
+func registerConflictingControllers(policy string, module *Module) {
+	Config.SetOption("controller.conflict.policy", policy)
+	controllers = make(map[string]*ControllerType)
+	shortNameOwners = map[string]*ControllerType{}
+	controllerConflicts = nil
+	AddControllerType(appModule, reflect.TypeOf(Request{}), nil)
+	AddControllerType(module, reflect.TypeOf(http.Request{}), nil)
+}
+
+func TestControllerConflictPolicy(t *testing.T) {
+	startFakeBookingApp()
+	defer func() {
+		Config.SetOption("controller.conflict.policy", CONTROLLER_CONFLICT_FIRST_WINS)
+		registerControllers()
+	}()
+	module := &Module{Name: "conflict"}
+
+	// Same namespaced name
+	registerConflictingControllers(CONTROLLER_CONFLICT_FIRST_WINS, appModule)
+	conflicts := ControllerConflicts()
+	if len(conflicts) != 1 || conflicts[0].Name != `App\request` || conflicts[0].Conflicting.Type != reflect.TypeOf(http.Request{}) {
+		t.Fatalf("Expected a conflict for App\\request, got %#v", conflicts)
+	}
+	if ct := ControllerTypeByName("Request", anyModule); ct == nil || ct.Type != reflect.TypeOf(Request{}) {
+		t.Errorf("Expected the first controller to keep the name")
+	}
+
+	// Same short name in different modules
+	registerConflictingControllers(CONTROLLER_CONFLICT_FIRST_WINS, module)
+	if conflicts = ControllerConflicts(); len(conflicts) != 1 || conflicts[0].Name != "request" {
+		t.Fatalf("Expected a conflict for request, got %#v", conflicts)
+	}
+	if ct := ControllerTypeByName("Request", anyModule); ct == nil || ct.Type != reflect.TypeOf(Request{}) {
+		t.Errorf("Expected the application controller to keep the short name")
+	}
+
+	registerConflictingControllers(CONTROLLER_CONFLICT_REQUIRE_NAMESPACE, module)
+	if ct := ControllerTypeByName("Request", anyModule); ct != nil {
+		t.Errorf("Expected the short name to be unmapped, found %s", ct.Name())
+	}
+	if ct := ControllerTypeByName(`conflict\request`, anyModule); ct == nil || ct.Type != reflect.TypeOf(http.Request{}) {
+		t.Errorf("Expected the namespaced name to be mapped")
+	}
+}
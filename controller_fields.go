@@ -0,0 +1,80 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"reflect"
+)
+
+// A controller field populated from a request header or cookie, for example
+//   type Api struct {
+//       *revel.Controller
+//       ClientVersion int    `header:"X-Client-Version"`
+//       Theme         string `cookie:"theme"`
+//   }
+// The values are converted using the registered binders, a missing header or
+// cookie sets the field to its zero value. Slice fields receive every value of a header.
+type controllerBoundField struct {
+	index  []int
+	header string
+	cookie string
+	typ    reflect.Type
+}
+
+// Finds the tagged fields of the controller, including fields of embedded structs
+func findBoundFields(controllerType reflect.Type, parentIndex []int) (fields []*controllerBoundField) {
+	if controllerType.Kind() != reflect.Struct {
+		return
+	}
+	for i := 0; i < controllerType.NumField(); i++ {
+		field := controllerType.Field(i)
+		index := append(append([]int{}, parentIndex...), i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			fields = append(fields, findBoundFields(field.Type, index)...)
+			continue
+		}
+		header, cookie := field.Tag.Get("header"), field.Tag.Get("cookie")
+		if header == "" && cookie == "" {
+			continue
+		}
+		if field.PkgPath != "" {
+			controllerLog.Warn("Unexported controller field cannot be bound", "controller", controllerType.Name(), "field", field.Name)
+			continue
+		}
+		fields = append(fields, &controllerBoundField{index: index, header: header, cookie: cookie, typ: field.Type})
+	}
+	return
+}
+
+// Populates the tagged fields of the AppController from the request
+func (c *Controller) setBoundFields() {
+	if len(c.Type.boundFields) == 0 || c.Request == nil || c.Request.In == nil {
+		return
+	}
+	appController := reflect.ValueOf(c.AppController).Elem()
+	for _, field := range c.Type.boundFields {
+		var values []string
+		if field.header != "" {
+			values = c.Request.Header.GetAll(field.header)
+		} else if cookie, err := c.Request.Cookie(field.cookie); err == nil {
+			values = []string{cookie.GetValue()}
+		}
+		appController.FieldByIndex(field.index).Set(field.bind(values))
+	}
+}
+
+func (field *controllerBoundField) bind(values []string) reflect.Value {
+	if len(values) == 0 {
+		return reflect.Zero(field.typ)
+	}
+	if field.typ.Kind() == reflect.Slice && field.typ.Elem().Kind() != reflect.Uint8 {
+		result := reflect.MakeSlice(field.typ, 0, len(values))
+		for _, value := range values {
+			result = reflect.Append(result, BindValue(value, field.typ.Elem()))
+		}
+		return result
+	}
+	return BindValue(values[0], field.typ)
+}
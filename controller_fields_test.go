@@ -0,0 +1,64 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+type HeaderFields struct {
+	Version int      `header:"X-Client-Version"`
+	Accepts []string `header:"Accept"`
+}
+
+type HeaderController struct {
+	*Controller
+	HeaderFields
+	Theme   string `cookie:"theme"`
+	Missing string `header:"X-Missing"`
+}
+
+func (c HeaderController) Index() Result {
+	return nil
+}
+
+func TestControllerBoundFields(t *testing.T) {
+	RegisterController((*HeaderController)(nil), []*MethodType{{Name: "Index"}})
+
+	request, _ := http.NewRequest("GET", "/", nil)
+	request.Header.Set("X-Client-Version", "42")
+	request.Header.Add("Accept", "text/html")
+	request.Header.Add("Accept", "application/json")
+	request.AddCookie(&http.Cookie{Name: "theme", Value: "dark"})
+
+	c := NewTestController(nil, request)
+	if err := c.SetAction("HeaderController", "Index"); err != nil {
+		t.Fatal(err)
+	}
+	controller := c.AppController.(*HeaderController)
+	if controller.Version != 42 {
+		t.Errorf("Expected version 42 got %d", controller.Version)
+	}
+	if !reflect.DeepEqual(controller.Accepts, []string{"text/html", "application/json"}) {
+		t.Errorf("Unexpected accept values %v", controller.Accepts)
+	}
+	if controller.Theme != "dark" {
+		t.Errorf("Expected theme dark got %s", controller.Theme)
+	}
+
+	// A reused controller must not keep the values from the previous request
+	controller.Missing = "previous"
+	cachedControllerMap[c.Name].Push(controller)
+	c = NewTestController(nil, showRequest)
+	if err := c.SetAction("HeaderController", "Index"); err != nil {
+		t.Fatal(err)
+	}
+	controller = c.AppController.(*HeaderController)
+	if controller.Version != 0 || controller.Theme != "" || controller.Missing != "" {
+		t.Errorf("Expected fields to be reset, got %#v", controller.HeaderFields)
+	}
+}
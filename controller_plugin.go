@@ -0,0 +1,74 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"fmt"
+	"path/filepath"
+	"plugin"
+	"strings"
+)
+
+// ControllerPluginSymbol is the name of the function a controller plugin must export,
+// the function must have the signature of
+//   func RegisterRevelControllers(register revel.ControllerPluginRegister)
+const ControllerPluginSymbol = "RegisterRevelControllers"
+
+// ControllerPluginRegister is passed to the plugin, the plugin calls it once for
+// each controller using the same arguments as revel.RegisterController
+type ControllerPluginRegister func(controller interface{}, methods []*MethodType, annotations ...*FunctionalAnnotation)
+
+var pluginLog = RevelLog.New("section", "plugin")
+
+// Load any plugins listed in the `controller.plugins` config, before the router is created
+func init() {
+	OnAppStart(func() {
+		for _, path := range strings.Split(Config.StringDefault("controller.plugins", ""), ",") {
+			if path = strings.TrimSpace(path); path == "" {
+				continue
+			}
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(BasePath, path)
+			}
+			if err := LoadControllerPlugin(path); err != nil {
+				pluginLog.Fatal("Failed to load controller plugin", "path", path, "error", err)
+			}
+		}
+	}, 0)
+}
+
+// LoadControllerPlugin opens the Go plugin at the path and registers its controllers.
+// The controllers are placed in a module named after the plugin file (without the extension),
+// so a controller Users in the plugin billing.so is routed using billing\Users.Action
+func LoadControllerPlugin(path string) error {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if _, found := ModuleByName(name); found {
+		return fmt.Errorf("a module named %s is already loaded", name)
+	}
+
+	p, err := plugin.Open(path)
+	if err != nil {
+		return err
+	}
+	symbol, err := p.Lookup(ControllerPluginSymbol)
+	if err != nil {
+		return err
+	}
+	registerControllers, ok := symbol.(func(ControllerPluginRegister))
+	if !ok {
+		return fmt.Errorf("%s in %s has the wrong signature %T", ControllerPluginSymbol, path, symbol)
+	}
+
+	module := &Module{Name: name,
+		ImportPath: filepath.ToSlash(path),
+		Path:       filepath.ToSlash(path),
+		Log:        RootLog.New("module", name)}
+	Modules = append(Modules, module)
+	registerControllers(func(controller interface{}, methods []*MethodType, annotations ...*FunctionalAnnotation) {
+		registerController(module, controller, methods, annotations)
+	})
+	pluginLog.Info("Loaded controller plugin", "path", path, "module", name, "controllers", len(module.ControllerTypeList))
+	return nil
+}
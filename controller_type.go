@@ -2,6 +2,7 @@ package revel
 
 import (
 	"reflect"
+	"sort"
 	"strings"
 )
 
@@ -13,6 +14,8 @@ type ControllerType struct {
 	Methods           []*MethodType
 	ControllerIndexes [][]int // FieldByIndex to all embedded *Controllers
 	ControllerEvents  *ControllerTypeEvents
+	Annotations       FunctionalAnnotations   // The annotations found on the controller
+	boundFields       []*controllerBoundField // Fields populated from headers or cookies
 }
 type ControllerTypeEvents struct {
 	Before, After, Finally, Panic []*ControllerFieldPath
@@ -23,48 +26,212 @@ type ControllerFieldPath struct {
 	IsPointer bool
 	FieldIndexPath[]int
 	FunctionCall reflect.Value
+	ArgCount int // The number of arguments the method accepts (excluding the receiver)
 }
 
 type MethodType struct {
-	Name           string
-	Args           []*MethodArg
-	RenderArgNames map[int][]string
-	lowerName      string
-	Index          int
+	Name            string
+	Args            []*MethodArg
+	RenderArgNames  map[int][]string
+	Annotations     FunctionalAnnotations // The annotations found on the method
+	lowerName       string
+	Index           int
+	viewArgs        map[string]interface{} // Populated by the @ViewArg annotation
+	cache           *actionCacheSettings   // Populated by the @Cache annotation
+	authorize       *authorizeSettings     // Populated by the @Authorize annotation
+	jsonStream      bool                   // Set by the @JSONStream annotation
+	rateLimit       *rateLimitSettings     // Populated by the @RateLimit annotation
+	csrfRequired    *bool                  // Set by the @CSRFExempt and @CSRFRequired annotations
+	deprecated      *DeprecatedAction      // Populated by the @Deprecated annotation
+	produces        []string               // The content types of the @Produces annotation
+	consumes        []string               // The content types of the @Consumes annotation
+	response        *responseSettings      // Populated by the @Gzip, @NoStore, @CacheControl and @Header annotations
+	seo             *seoSettings           // Populated by the @Robots and @Canonical annotations
+	surrogateKeys   []string               // The tags of the @SurrogateKey annotation
+	pageCache       *pageCacheSettings     // Populated by the @PageCache annotation
+	upload          *uploadSettings        // Populated by the @Upload annotation
+	scenarios       []string               // The validation scenarios of the @Scenario annotation
+	sessionReadOnly bool                   // Set by the @SessionReadOnly annotation
 }
 
 type MethodArg struct {
-	Name string
-	Type reflect.Type
+	Name   string
+	Type   reflect.Type
+	binder *Binder // The binder for the type, resolved when the controller is added
+	source string  // The source of the argument set by the @BindFrom annotation, all the parameters when empty
+}
+
+// Fail on startup if any action argument has no binder. The binders are resolved
+// again since an application may register a binder after the controllers
+func init() {
+	OnAppStart(func() {
+		failed := false
+		checked := map[*ControllerType]bool{}
+		for _, ct := range controllers {
+			if checked[ct] {
+				continue
+			}
+			checked[ct] = true
+			for _, mt := range ct.Methods {
+				for _, arg := range mt.resolveBinders() {
+					controllerLog.Error("No binder found for action argument", "action", ct.Name()+"."+mt.Name, "argument", arg.Name, "type", arg.Type)
+					failed = true
+				}
+			}
+		}
+		if failed {
+			controllerLog.Fatal("Action arguments exist which cannot be bound, see the errors above")
+		}
+	}, 5)
 }
 
 // Adds the controller to the controllers map using its namespace, also adds it to the module list of controllers.
 // If the controller is in the main application it is added without its namespace as well.
-func AddControllerType(moduleSource *Module,controllerType reflect.Type,methods []*MethodType) (newControllerType *ControllerType) {
+// Any annotations passed are attached to the controller and processed along with the method annotations.
+func AddControllerType(moduleSource *Module,controllerType reflect.Type,methods []*MethodType, annotations ...*FunctionalAnnotation) (newControllerType *ControllerType) {
 	if moduleSource==nil {
 		moduleSource = appModule
 	}
 
 	newControllerType = &ControllerType{ModuleSource:moduleSource,Type:controllerType,Methods:methods,ControllerIndexes:findControllers(controllerType)}
 	newControllerType.ControllerEvents = NewControllerTypeEvents(newControllerType)
+	newControllerType.boundFields = findBoundFields(controllerType, nil)
+	for _, method := range methods {
+		for _, arg := range method.resolveBinders() {
+			controllerLog.Warn("No binder found for action argument", "action", controllerType.Name()+"."+method.Name, "argument", arg.Name, "type", arg.Type)
+		}
+	}
 	newControllerType.Namespace = moduleSource.Namespace()
+	newControllerType.Annotations = annotations
 	controllerName := newControllerType.Name()
+	processAnnotations(newControllerType)
+
+	// Registering the same controller again updates it in place, so any references to it
+	// (like the routes) remain valid
+	if existing := registeredControllerType(controllerName, controllerType); existing != nil {
+		*existing = *newControllerType
+		newControllerType = existing
+	}
 
 	// Store the first controller only in the controllers map with the unmapped namespace.
-	if _, found := controllers[controllerName]; !found {
+	if current, found := controllers[controllerName]; !found {
 		controllers[controllerName] = newControllerType
 		newControllerType.ModuleSource.AddController(newControllerType)
-		if newControllerType.ModuleSource == appModule {
-			// Add the controller mapping into the global namespace
-			controllers[newControllerType.ShortName()] = newControllerType
+		// Add the controller mapping into the global namespace
+		registerShortName(newControllerType)
+		fireEvent(CONTROLLER_REGISTERED, newControllerType)
+		for _, method := range newControllerType.Methods {
+			fireEvent(METHOD_REGISTERED, method)
 		}
-	} else {
-		controllerLog.Errorf("Error, attempt to register duplicate controller as %s",controllerName)
+	} else if current != newControllerType {
+		addControllerConflict(controllerName, current, newControllerType)
 	}
 	controllerLog.Debugf("Registered controller: %s", controllerName)
 
 	return
 }
+
+// OnControllerRegistered adds an event handler which is called with each controller added
+// to the registry. A controller registered again (like on a reload) is not passed again unless
+// the registry has been cleared by ReloadControllers.
+func OnControllerRegistered(f func(ct *ControllerType)) {
+	AddInitEventHandler(func(typeOf int, value interface{}) (responseOf int) {
+		if typeOf == CONTROLLER_REGISTERED {
+			f(value.(*ControllerType))
+		}
+		return
+	})
+}
+
+// OnMethodRegistered adds an event handler which is called with each method of the controllers
+// added to the registry, the methods are passed after their controller
+func OnMethodRegistered(f func(mt *MethodType)) {
+	AddInitEventHandler(func(typeOf int, value interface{}) (responseOf int) {
+		if typeOf == METHOD_REGISTERED {
+			f(value.(*MethodType))
+		}
+		return
+	})
+}
+
+// Returns the controller already registered (or registered before a reload) with the name and type
+func registeredControllerType(name string, controllerType reflect.Type) *ControllerType {
+	for _, registry := range []map[string]*ControllerType{controllers, previousControllers} {
+		if ct, found := registry[name]; found && ct.Type == controllerType {
+			return ct
+		}
+	}
+	return nil
+}
+
+// Resolves and caches the binder for each argument, returns the arguments which have no binder
+func (mt *MethodType) resolveBinders() (missing []*MethodArg) {
+	for _, arg := range mt.Args {
+		if arg.Type.Implements(websocketType) {
+			continue
+		}
+		if binder, found := lookupBinder(arg.Type); found {
+			arg.binder = &binder
+		} else {
+			missing = append(missing, arg)
+		}
+	}
+	return
+}
+
+// Binds the argument using the binder resolved when the controller was added, from the
+// parameters of its source
+func (arg *MethodArg) bind(params *Params) reflect.Value {
+	if arg.source != "" {
+		sourced, _ := params.sourced(arg.source, arg.Name, arg.Name)
+		defer func() { params.bindErrors = sourced.bindErrors }()
+		params = sourced
+	}
+	if arg.binder != nil {
+		return arg.binder.Bind(params, arg.Name, arg.Type)
+	}
+	return Bind(params, arg.Name, arg.Type)
+}
+
+var (
+	controllersReloadedHooks StartupHooks
+	previousControllers      map[string]*ControllerType // The controllers registered before the reload
+)
+
+// OnControllersReloaded registers a function to be run after ReloadControllers,
+// extensions which attach to controllers at runtime use this to attach again.
+// Like OnAppStart the functions are run by order, then in the order they were added.
+func OnControllersReloaded(f func(), order ...int) {
+	o := 1
+	if len(order) > 0 {
+		o = order[0]
+	}
+	controllersReloadedHooks = append(controllersReloadedHooks, StartupHook{order: o, f: f})
+}
+
+// ReloadControllers clears the controller registry, calls register to add the controllers
+// again, and runs the OnControllersReloaded hooks. Controllers which are registered again keep
+// the same *ControllerType so existing references remain valid, controllers which are not
+// registered again are removed.
+func ReloadControllers(register func()) {
+	previousControllers = controllers
+	controllers = make(map[string]*ControllerType)
+	shortNameOwners = map[string]*ControllerType{}
+	controllerConflicts = nil
+	annotationErrors = nil
+	for _, module := range append([]*Module{appModule}, Modules...) {
+		module.ControllerTypeList = nil
+	}
+	register()
+	previousControllers = nil
+
+	sort.Stable(controllersReloadedHooks)
+	for _, hook := range controllersReloadedHooks {
+		hook.f()
+	}
+	controllerLog.Info("Controllers reloaded", "controllers", len(controllers))
+}
+
 // Method searches for a given exported method (case insensitive)
 func (ct *ControllerType) Method(name string) *MethodType {
 	lowerName := strings.ToLower(name)
@@ -111,6 +278,11 @@ func (cte *ControllerTypeEvents) check(theType reflect.Type, fieldPath []int)  {
 					controllerLog.Debug("Found controller type event method","name", checkType.Elem().Name(),"methodname", m.Name)
 				}
 				controllerFieldPath := newFieldPath(checkType.Kind() == reflect.Ptr, m.Func, fieldPath)
+				if !validEventArgs(strings.ToLower(m.Name), m.Type) {
+					controllerLog.Error("Controller type event method has invalid arguments, it will not be called", "name", checkType.String(), "methodname", m.Name)
+					continue
+				}
+				controllerFieldPath.ArgCount = m.Type.NumIn() - 1
 				switch strings.ToLower(m.Name) {
 				case "before":
 					cte.Before = append([]*ControllerFieldPath{controllerFieldPath}, cte.Before...)
@@ -139,6 +311,28 @@ func (cte *ControllerTypeEvents) check(theType reflect.Type, fieldPath []int)  {
 		}
 	}
 }
+var (
+	resultType    = reflect.TypeOf((*Result)(nil)).Elem()
+	interfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
+	byteSliceType = reflect.TypeOf([]byte{})
+)
+
+// Event methods take no arguments, except for
+//   After(result revel.Result)
+//   Panic(recovered interface{}, stack []byte)
+// which may optionally accept them. The method type includes the receiver.
+func validEventArgs(name string, methodType reflect.Type) bool {
+	switch methodType.NumIn() {
+	case 1:
+		return true
+	case 2:
+		return name == "after" && methodType.In(1) == resultType
+	case 3:
+		return name == "panic" && methodType.In(1) == interfaceType && methodType.In(2) == byteSliceType
+	}
+	return false
+}
+
 func newFieldPath(isPointer bool, value reflect.Value, fieldPath []int) *ControllerFieldPath{
 	return &ControllerFieldPath{IsPointer:isPointer,FunctionCall:value,FieldIndexPath:fieldPath}
 }
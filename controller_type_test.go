@@ -0,0 +1,76 @@
+package revel
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRegisterControllerIdempotent(t *testing.T) {
+	controllers = make(map[string]*ControllerType)
+	defer func() { controllers = make(map[string]*ControllerType) }()
+
+	RegisterController((*P)(nil), []*MethodType{{Name: "Method"}})
+	first := ControllerTypeByName("P", anyModule)
+	RegisterController((*P)(nil), []*MethodType{{Name: "Method"}, {Name: "Other"}})
+	if second := ControllerTypeByName("P", anyModule); second != first || second.Method("Other") == nil {
+		t.Errorf("Expected the controller to be updated in place")
+	}
+	count := 0
+	for _, ct := range first.ModuleSource.ControllerTypeList {
+		if ct == first {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Errorf("Expected the controller to be in the module once, found %d", count)
+	}
+}
+
+func TestReloadControllers(t *testing.T) {
+	controllers = make(map[string]*ControllerType)
+	defer func() { controllers = make(map[string]*ControllerType) }()
+
+	methods := []*MethodType{{Name: "Method"}}
+	RegisterController((*P)(nil), methods)
+	RegisterController((*PN)(nil), []*MethodType{{Name: "Method"}})
+	before := ControllerTypeByName("P", anyModule)
+
+	reloaded := 0
+	OnControllersReloaded(func() { reloaded++ })
+	ReloadControllers(func() {
+		RegisterController((*P)(nil), methods)
+	})
+
+	if reloaded != 1 {
+		t.Errorf("Expected reload hook to be called once, called %d", reloaded)
+	}
+	if after := ControllerTypeByName("P", anyModule); after != before {
+		t.Errorf("Expected the reloaded controller to keep the same type instance")
+	}
+	if ControllerTypeByName("PN", anyModule) != nil {
+		t.Errorf("Expected the controller which was not registered again to be removed")
+	}
+}
+
+func TestControllerRegisteredEvents(t *testing.T) {
+	controllers = make(map[string]*ControllerType)
+	defer func(handlers []EventHandler) {
+		controllers = make(map[string]*ControllerType)
+		initEventList = handlers
+	}(initEventList)
+
+	var registered []*ControllerType
+	var methods []string
+	OnControllerRegistered(func(ct *ControllerType) { registered = append(registered, ct) })
+	OnMethodRegistered(func(mt *MethodType) { methods = append(methods, mt.Name) })
+
+	RegisterController((*P)(nil), []*MethodType{{Name: "Method"}, {Name: "Other"}})
+	RegisterController((*P)(nil), []*MethodType{{Name: "Method"}, {Name: "Other"}})
+	if len(registered) != 1 || registered[0].Type != reflect.TypeOf(P{}) {
+		t.Errorf("Expected the controller to be passed once, got %d", len(registered))
+	}
+	if strings.Join(methods, ",") != "Method,Other" {
+		t.Errorf("Expected the methods to be passed once, got %v", methods)
+	}
+}
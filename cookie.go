@@ -0,0 +1,167 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// The attributes of the session, flash and validation errors cookies are set in app.conf,
+// for all of them (cookie.*) or for one of them (cookie.session.*, cookie.flash.*,
+// cookie.errors.*)
+//   cookie.samesite = lax          # lax (the default), strict, none, or default (no attribute)
+//   cookie.secure = true           # true by default in the prod mode, or with http.ssl
+//   cookie.domain = example.com
+//   cookie.path = /
+//   cookie.partitioned = false     # The cookies are partitioned by the top level site (CHIPS)
+//   cookie.host.prefix = false     # The names start with __Host- (Secure, the path /, no domain)
+// The attributes of each environment are set in the section of its mode. An invalid
+// combination (SameSite None, Partitioned or __Host- without Secure, __Host- with a domain
+// or another path) stops the application at start.
+
+// CookieAttributes are the attributes of a cookie of Revel
+type CookieAttributes struct {
+	HostPrefix  bool
+	Domain      string
+	Path        string
+	Secure      bool
+	SameSite    http.SameSite
+	Partitioned bool
+}
+
+// The kinds of the cookies of Revel
+const (
+	sessionCookieKind = "session"
+	flashCookieKind   = "flash"
+	errorsCookieKind  = "errors"
+)
+
+// The suffixes of the names of the kinds of cookies
+var cookieSuffixes = map[string]string{
+	sessionCookieKind: "_SESSION",
+	flashCookieKind:   "_FLASH",
+	errorsCookieKind:  "_ERRORS",
+}
+
+// The attributes of the kinds of cookies, loaded at start
+var cookieAttributes = map[string]*CookieAttributes{}
+
+// Returns the attributes of the kind of cookie, the defaults if they were not loaded
+func cookieAttributesOf(kind string) *CookieAttributes {
+	if attributes, found := cookieAttributes[kind]; found {
+		return attributes
+	}
+	return &CookieAttributes{Domain: CookieDomain, Path: "/", Secure: CookieSecure, SameSite: http.SameSiteLaxMode}
+}
+
+// Name returns the name of the cookie of the suffix (_SESSION), with the cookie prefix
+func (a *CookieAttributes) Name(suffix string) string {
+	if a.HostPrefix {
+		return "__Host-" + CookiePrefix + suffix
+	}
+	return CookiePrefix + suffix
+}
+
+// Cookie returns the http only cookie of the suffix and the value with the attributes, the
+// Partitioned attribute is added by Controller.SetCookie
+func (a *CookieAttributes) Cookie(suffix, value string) *http.Cookie {
+	return &http.Cookie{
+		Name:     a.Name(suffix),
+		Value:    value,
+		Domain:   a.Domain,
+		Path:     a.Path,
+		HttpOnly: true,
+		Secure:   a.Secure,
+		SameSite: a.SameSite,
+	}
+}
+
+// Returns true if the cookie of the name is a partitioned cookie of Revel
+func partitionedCookie(name string) bool {
+	for kind, attributes := range cookieAttributes {
+		if attributes.Partitioned && attributes.Name(cookieSuffixes[kind]) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// validate returns an error if the browsers would reject the cookies of the attributes
+func (a *CookieAttributes) validate() error {
+	switch {
+	case a.SameSite == http.SameSiteNoneMode && !a.Secure:
+		return fmt.Errorf("samesite none needs secure")
+	case a.Partitioned && !a.Secure:
+		return fmt.Errorf("partitioned needs secure")
+	case a.HostPrefix && !a.Secure:
+		return fmt.Errorf("host.prefix needs secure")
+	case a.HostPrefix && (a.Domain != "" || a.Path != "/"):
+		return fmt.Errorf("host.prefix needs no domain and the path /")
+	}
+	return nil
+}
+
+// loadCookieAttributes loads the attributes of the kinds of cookies from app.conf
+func loadCookieAttributes() error {
+	cookieAttributes = map[string]*CookieAttributes{}
+	for kind := range cookieSuffixes {
+		option := func(name string) (string, bool) {
+			if value, found := Config.String("cookie." + kind + "." + name); found {
+				return value, true
+			}
+			return Config.String("cookie." + name)
+		}
+		boolOption := func(name string, value bool) (bool, error) {
+			if text, found := option(name); found {
+				parsed, err := strconv.ParseBool(text)
+				if err != nil {
+					return value, fmt.Errorf("cookie.%s.%s: invalid %s", kind, name, text)
+				}
+				return parsed, nil
+			}
+			return value, nil
+		}
+
+		var err error
+		attributes := &CookieAttributes{Domain: CookieDomain, Path: "/", SameSite: http.SameSiteLaxMode}
+		if domain, found := option("domain"); found {
+			attributes.Domain = domain
+		}
+		if path, found := option("path"); found {
+			attributes.Path = path
+		}
+		if sameSite, found := option("samesite"); found {
+			switch strings.ToLower(sameSite) {
+			case "lax":
+				attributes.SameSite = http.SameSiteLaxMode
+			case "strict":
+				attributes.SameSite = http.SameSiteStrictMode
+			case "none":
+				attributes.SameSite = http.SameSiteNoneMode
+			case "default", "":
+				attributes.SameSite = http.SameSiteDefaultMode
+			default:
+				return fmt.Errorf("cookie.%s.samesite: invalid %s, expected lax, strict, none or default", kind, sameSite)
+			}
+		}
+		if attributes.Secure, err = boolOption("secure", CookieSecure); err != nil {
+			return err
+		}
+		if attributes.Partitioned, err = boolOption("partitioned", false); err != nil {
+			return err
+		}
+		if attributes.HostPrefix, err = boolOption("host.prefix", false); err != nil {
+			return err
+		}
+		if err = attributes.validate(); err != nil {
+			return fmt.Errorf("cookie.%s: %w", kind, err)
+		}
+		cookieAttributes[kind] = attributes
+	}
+	return nil
+}
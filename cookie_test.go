@@ -0,0 +1,66 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/revel/config"
+)
+
+func TestCookieAttributes(t *testing.T) {
+	defer func(c *config.Context) {
+		Config = c
+		cookieAttributes = map[string]*CookieAttributes{}
+	}(Config)
+
+	Config = config.NewContext()
+	Config.SetOption("cookie.secure", "true")
+	Config.SetOption("cookie.samesite", "strict")
+	Config.SetOption("cookie.session.host.prefix", "true")
+	Config.SetOption("cookie.flash.samesite", "none")
+	Config.SetOption("cookie.flash.partitioned", "true")
+	if err := loadCookieAttributes(); err != nil {
+		t.Fatal(err)
+	}
+	session := Session{"user": "jane"}.Cookie()
+	if session.Name != "__Host-"+CookiePrefix+"_SESSION" || !session.Secure || session.SameSite != http.SameSiteStrictMode {
+		t.Errorf("Unexpected session cookie %s", session)
+	}
+	flash := cookieAttributesOf(flashCookieKind).Cookie("_FLASH", "")
+	if flash.SameSite != http.SameSiteNoneMode || !partitionedCookie(flash.Name) || partitionedCookie(session.Name) {
+		t.Errorf("Unexpected flash cookie %s", flash)
+	}
+	c := NewTestController(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	c.SetCookie(flash)
+	if header := c.Response.Out.Header().Get("Set-Cookie"); !strings.HasSuffix(header, "; Partitioned") {
+		t.Errorf("Expected a partitioned cookie, got %s", header)
+	}
+	if errors := errorsCookie(""); errors.Name != CookiePrefix+"_ERRORS" || errors.SameSite != http.SameSiteStrictMode {
+		t.Errorf("Unexpected errors cookie %s", errors)
+	}
+
+	// The cookies the browsers would reject stop the application
+	invalid := []map[string]string{
+		{"cookie.secure": "false", "cookie.samesite": "none"},
+		{"cookie.secure": "false", "cookie.partitioned": "true"},
+		{"cookie.secure": "true", "cookie.host.prefix": "true", "cookie.domain": "example.com"},
+		{"cookie.errors.host.prefix": "true", "cookie.secure": "true", "cookie.errors.path": "/app"},
+		{"cookie.samesite": "sometimes"},
+		{"cookie.flash.secure": "maybe"},
+	}
+	for _, options := range invalid {
+		Config = config.NewContext()
+		for key, value := range options {
+			Config.SetOption(key, value)
+		}
+		if err := loadCookieAttributes(); err == nil {
+			t.Errorf("Expected %v to be invalid", options)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"bufio"
+	"encoding/csv"
+	"io"
+	"net/http"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// The CSV results export tables, either from the rows in memory
+//   return c.RenderCSV(rows, &revel.CSVOptions{Filename: "bookings.csv"})
+// or from a channel the rows are sent to while they are read, the channel must be closed
+// after the last row
+//   rows := make(chan []string)
+//   go func() {
+//   	defer close(rows)
+//   	for ... {
+//   		rows <- []string{booking.Hotel, booking.CheckIn}
+//   	}
+//   }()
+//   return c.RenderCSVStream(rows, &revel.CSVOptions{Filename: "bookings.csv", BOM: true, Delimiter: ';'})
+// The streamed rows are flushed every results.csv.flush rows (default 100). Excel opens a
+// UTF-8 file with a BOM, or a UTF-16LE file, with the characters it has.
+
+// The rows of a stream written between the flushes, loaded when the application starts
+var csvFlushEvery = 100
+
+func init() {
+	OnAppStart(func() {
+		if csvFlushEvery = Config.IntDefault("results.csv.flush", 100); csvFlushEvery < 1 {
+			csvFlushEvery = 1
+		}
+	})
+}
+
+// CSVOptions are the options of a CSV result
+type CSVOptions struct {
+	Filename  string             // The file name of the Content-Disposition header, no header when empty
+	Delivery  ContentDisposition // Defaults to Attachment
+	Delimiter rune               // Defaults to a comma
+	CRLF      bool               // End the lines with \r\n
+	BOM       bool               // Start with a byte order mark, always set for UTF-16LE
+	Encoding  string             // utf-8 (the default) or utf-16le
+}
+
+// Returns a copy of the options, the defaults when nil
+func csvOptions(options *CSVOptions) CSVOptions {
+	if options == nil {
+		return CSVOptions{}
+	}
+	return *options
+}
+
+// RenderCSVResult writes the rows as CSV
+type RenderCSVResult struct {
+	rows    [][]string
+	stream  <-chan []string
+	options CSVOptions
+}
+
+func (r *RenderCSVResult) Apply(req *Request, resp *Response) {
+	encoding := "utf-8"
+	if r.options.Encoding == "utf-16le" {
+		encoding = r.options.Encoding
+	} else if r.options.Encoding != "" && r.options.Encoding != encoding {
+		resultsLog.Warn("Apply: Unsupported CSV encoding, using utf-8", "encoding", r.options.Encoding)
+	}
+	if r.options.Filename != "" {
+		delivery := r.options.Delivery
+		if delivery == "" {
+			delivery = Attachment
+		}
+		resp.Out.internalHeader.Set("Content-Disposition", delivery.Header(r.options.Filename))
+	}
+	resp.WriteHeader(http.StatusOK, "text/csv; charset="+encoding)
+
+	out := resp.GetWriter()
+	buffered := bufio.NewWriter(out)
+	var writer io.Writer = buffered
+	switch {
+	case encoding == "utf-16le":
+		buffered.Write([]byte{0xff, 0xfe})
+		writer = &utf16LEWriter{w: buffered}
+	case r.options.BOM:
+		buffered.Write([]byte{0xef, 0xbb, 0xbf})
+	}
+	csvWriter := csv.NewWriter(writer)
+	if r.options.Delimiter != 0 {
+		csvWriter.Comma = r.options.Delimiter
+	}
+	csvWriter.UseCRLF = r.options.CRLF
+	flush := func() error {
+		csvWriter.Flush()
+		if err := csvWriter.Error(); err != nil {
+			return err
+		}
+		if err := buffered.Flush(); err != nil {
+			return err
+		}
+		if flusher, ok := out.(http.Flusher); ok {
+			flusher.Flush()
+		}
+		return nil
+	}
+
+	if r.stream == nil {
+		if err := csvWriter.WriteAll(r.rows); err != nil {
+			resultsLog.Error("Apply: Response write failed", "error", err)
+			return
+		}
+		if err := flush(); err != nil {
+			resultsLog.Error("Apply: Response write failed", "error", err)
+		}
+		return
+	}
+
+	flushEvery := csvFlushEvery
+	count, failed := 0, false
+	for row := range r.stream {
+		// The channel is drained after a failure so the sender is not blocked
+		if failed {
+			continue
+		}
+		err := csvWriter.Write(row)
+		if count++; err == nil && count%flushEvery == 0 {
+			err = flush()
+		}
+		if err != nil {
+			resultsLog.Error("Apply: Response write failed", "row", count, "error", err)
+			failed = true
+		}
+	}
+	if !failed {
+		if err := flush(); err != nil {
+			resultsLog.Error("Apply: Response write failed", "error", err)
+		}
+	}
+}
+
+// Encodes the UTF-8 written to it as UTF-16LE, a rune split across writes is kept until
+// the next write
+type utf16LEWriter struct {
+	w       io.Writer
+	partial []byte
+}
+
+func (u *utf16LEWriter) Write(p []byte) (int, error) {
+	data := append(u.partial, p...)
+	encoded := make([]byte, 0, len(data)*2)
+	for len(data) > 0 && utf8.FullRune(data) {
+		r, size := utf8.DecodeRune(data)
+		data = data[size:]
+		for _, unit := range utf16.Encode([]rune{r}) {
+			encoded = append(encoded, byte(unit), byte(unit>>8))
+		}
+	}
+	u.partial = append([]byte(nil), data...)
+	if _, err := u.w.Write(encoded); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
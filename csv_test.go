@@ -0,0 +1,51 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRenderCSV(t *testing.T) {
+	resp := httptest.NewRecorder()
+	c := NewTestController(resp, showRequest)
+	c.RenderCSV([][]string{{"hotel", "city"}, {"Ritz", "Paris; France"}}, &CSVOptions{Filename: "hotels.csv", Delimiter: ';', BOM: true}).Apply(c.Request, c.Response)
+	if body := resp.Body.String(); body != "\xef\xbb\xbfhotel;city\nRitz;\"Paris; France\"\n" {
+		t.Errorf("Unexpected CSV %q", body)
+	}
+	if resp.Header().Get("Content-Disposition") != `attachment; filename="hotels.csv"` || resp.Header().Get("Content-Type") != "text/csv; charset=utf-8" {
+		t.Errorf("Unexpected headers %v", resp.Header())
+	}
+
+	resp = httptest.NewRecorder()
+	c = NewTestController(resp, showRequest)
+	c.RenderCSV([][]string{{"€"}}, &CSVOptions{Encoding: "utf-16le", CRLF: true}).Apply(c.Request, c.Response)
+	if body := resp.Body.String(); body != "\xff\xfe\xac\x20\r\x00\n\x00" {
+		t.Errorf("Unexpected UTF-16LE CSV %q", body)
+	}
+}
+
+func TestRenderCSVStream(t *testing.T) {
+	rows := make(chan []string)
+	go func() {
+		defer close(rows)
+		for i := 0; i < 250; i++ {
+			rows <- []string{strconv.Itoa(i)}
+		}
+	}()
+	resp := httptest.NewRecorder()
+	c := NewTestController(resp, showRequest)
+	c.RenderCSVStream(rows, nil).Apply(c.Request, c.Response)
+	lines := strings.Split(strings.TrimSuffix(resp.Body.String(), "\n"), "\n")
+	if len(lines) != 250 || lines[249] != "249" || !resp.Flushed {
+		t.Errorf("Expected the streamed rows to be flushed, got %d rows", len(lines))
+	}
+	if resp.Header().Get("Content-Disposition") != "" {
+		t.Errorf("Expected no Content-Disposition without a file name, got %v", resp.Header())
+	}
+}
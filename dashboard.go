@@ -0,0 +1,139 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"html/template"
+	"sort"
+)
+
+// The dashboard is an admin page made of the panels contributed by the application and
+// its modules. A module adds a panel by registering a widget in its init
+//   revel.RegisterDashboardWidget(JobsWidget{})
+// The dashboard is served by the DashboardFilter when it is enabled in app.conf
+//   dashboard.enabled = true
+//   dashboard.path = /@dashboard
+// It renders dashboard/index.html, which an application overrides by adding the template
+// to its views. Applications with their own admin pages render the panels of the
+// request with DashboardPanels.
+
+func init() {
+	OnAppStart(func() {
+		dashboardConfig.enabled = Config.BoolDefault("dashboard.enabled", false)
+		dashboardConfig.path = Config.StringDefault("dashboard.path", "/@dashboard")
+	})
+}
+
+// The settings of the DashboardFilter, loaded when the application starts
+var dashboardConfig = struct {
+	enabled bool
+	path    string
+}{path: "/@dashboard"}
+
+// DashboardWidget is a panel of the dashboard
+type DashboardWidget interface {
+	// Name returns the unique name of the widget
+	Name() string
+	// Title returns the title of the panel
+	Title() string
+	// Template returns the template path the panel is rendered with, the template
+	// receives the data as "data" along with the ViewArgs of the request
+	Template() string
+	// Allowed returns true if the user of the request may see the panel
+	Allowed(c *Controller) bool
+	// Data returns the data of the panel
+	Data(c *Controller) (interface{}, error)
+}
+
+// DashboardPanel is a rendered widget
+type DashboardPanel struct {
+	Name  string
+	Title string
+	HTML  template.HTML
+	Error error // Set if the data could not be loaded or the template failed to render
+}
+
+type dashboardWidget struct {
+	widget DashboardWidget
+	order  int
+}
+
+type dashboardWidgetList []*dashboardWidget
+
+func (l dashboardWidgetList) Len() int           { return len(l) }
+func (l dashboardWidgetList) Less(i, j int) bool { return l[i].order < l[j].order }
+func (l dashboardWidgetList) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+
+var (
+	dashboardWidgets dashboardWidgetList
+	dashboardLog     = RevelLog.New("section", "dashboard")
+)
+
+// RegisterDashboardWidget adds the widget to the dashboard, the panels are shown by order
+// (default 0) then in the order they were registered. A widget registered with the name
+// of an existing widget replaces it.
+func RegisterDashboardWidget(widget DashboardWidget, order ...int) {
+	o := 0
+	if len(order) > 0 {
+		o = order[0]
+	}
+	for i, registered := range dashboardWidgets {
+		if registered.widget.Name() == widget.Name() {
+			dashboardWidgets = append(dashboardWidgets[:i], dashboardWidgets[i+1:]...)
+			break
+		}
+	}
+	dashboardWidgets = append(dashboardWidgets, &dashboardWidget{widget, o})
+	sort.Stable(dashboardWidgets)
+}
+
+// DashboardPanels renders the widgets the user of the request is allowed to see
+func DashboardPanels(c *Controller) (panels []*DashboardPanel) {
+	for _, registered := range dashboardWidgets {
+		widget := registered.widget
+		if !widget.Allowed(c) {
+			continue
+		}
+		panel := &DashboardPanel{Name: widget.Name(), Title: widget.Title()}
+		data, err := widget.Data(c)
+		if err == nil {
+			args := map[string]interface{}{}
+			for key, value := range c.ViewArgs {
+				args[key] = value
+			}
+			args["data"] = data
+			var output []byte
+			if output, err = TemplateOutputArgs(widget.Template(), args); err == nil {
+				panel.HTML = template.HTML(output)
+			}
+		}
+		if err != nil {
+			dashboardLog.Error("Failed to render the dashboard widget", "widget", panel.Name, "error", err)
+			panel.Error = err
+		}
+		panels = append(panels, panel)
+	}
+	return
+}
+
+// DashboardFilter serves the dashboard, it runs before the RouterFilter since the
+// dashboard has no route. The session is restored for the widgets to check the user, from
+// the SessionStorage when it is set.
+func DashboardFilter(c *Controller, fc []Filter) {
+	if c.Request.Method != "GET" || !dashboardConfig.enabled || c.Request.GetPath() != dashboardConfig.path {
+		fc[0](c, fc[1:])
+		return
+	}
+
+	c.Session = restoreRequestSession(c.Request)
+	panels := DashboardPanels(c)
+	if len(panels) == 0 {
+		dashboardLog.Warn("DashboardFilter: No dashboard widget is allowed for the request", "ip", c.ClientIP)
+		c.Result = c.Forbidden("Forbidden")
+		return
+	}
+	c.ViewArgs["panels"] = panels
+	c.Result = c.RenderTemplate("dashboard/index.html")
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type testWidget struct {
+	name    string
+	allowed bool
+	err     error
+}
+
+func (w testWidget) Name() string               { return w.name }
+func (w testWidget) Title() string              { return strings.Title(w.name) }
+func (w testWidget) Template() string           { return "widgets/bookings.html" }
+func (w testWidget) Allowed(c *Controller) bool { return w.allowed }
+func (w testWidget) Data(c *Controller) (interface{}, error) {
+	return 42, w.err
+}
+
+func TestDashboardFilter(t *testing.T) {
+	startFakeBookingApp()
+	dashboardConfig.enabled = true
+	defer func() {
+		dashboardConfig.enabled = false
+		dashboardWidgets = nil
+	}()
+
+	request := func() (*Controller, *httptest.ResponseRecorder) {
+		req, _ := http.NewRequest("GET", "/@dashboard", nil)
+		resp := httptest.NewRecorder()
+		c := NewTestController(resp, req)
+		DashboardFilter(c, []Filter{func(c *Controller, fc []Filter) { t.Error("Expected the dashboard to be served") }})
+		return c, resp
+	}
+
+	RegisterDashboardWidget(testWidget{name: "secret"})
+	if c, _ := request(); c.Response.Status != http.StatusForbidden {
+		t.Errorf("Expected a 403 without allowed widgets, got %d", c.Response.Status)
+	}
+
+	RegisterDashboardWidget(testWidget{name: "bookings", allowed: true}, 1)
+	RegisterDashboardWidget(testWidget{name: "failing", allowed: true, err: errors.New("database down")}, -1)
+	c, _ := request()
+	panels := DashboardPanels(c)
+	if len(panels) != 2 || panels[0].Name != "failing" || panels[1].Name != "bookings" {
+		t.Fatalf("Expected the allowed panels by order, got %v", panels)
+	}
+	if panels[0].Error == nil || !strings.Contains(string(panels[1].HTML), "42 bookings") {
+		t.Errorf("Unexpected panels %#v %#v", panels[0], panels[1])
+	}
+
+	c, resp := request()
+	c.Result.Apply(c.Request, c.Response)
+	if body := resp.Body.String(); !strings.Contains(body, "42 bookings") || !strings.Contains(body, "database down") {
+		t.Errorf("Expected the dashboard to render the panels, got %s", body)
+	}
+}
+
+type adminWidget struct {
+	testWidget
+}
+
+func (w adminWidget) Allowed(c *Controller) bool { return c.Session["user"] == "admin" }
+
+func TestDashboardFilterSessionStorage(t *testing.T) {
+	startFakeBookingApp()
+	dashboardConfig.enabled = true
+	SessionStorage = testSessionStore{"stored": Session{SessionIDKey: "stored", "user": "admin"}}
+	defer func() {
+		dashboardConfig.enabled, SessionStorage = false, nil
+		dashboardWidgets = nil
+	}()
+	RegisterDashboardWidget(adminWidget{testWidget{name: "bookings"}})
+
+	// The cookie has the ID of the session only, the user is in the stored session
+	req, _ := http.NewRequest("GET", "/@dashboard", nil)
+	req.AddCookie(Session{SessionIDKey: "stored"}.Cookie())
+	c := NewTestController(httptest.NewRecorder(), req)
+	DashboardFilter(c, NilChain)
+	if c.Response.Status == http.StatusForbidden || c.Session["user"] != "admin" {
+		t.Errorf("Expected the widget to be allowed for the stored session, got %d %v", c.Response.Status, c.Session)
+	}
+}
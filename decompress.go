@@ -0,0 +1,99 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zlib"
+)
+
+// The decompressors for each supported request Content-Encoding
+var requestDecompressors = map[string]func(io.Reader) (io.Reader, error){
+	"gzip":    func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+	"x-gzip":  func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+	"deflate": func(r io.Reader) (io.Reader, error) { return zlib.NewReader(r) },
+	"br":      func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil },
+}
+
+// The default maximum size of a decompressed request body
+const defaultDecompressMaxSize = 10 << 20
+
+// The decompression settings, loaded when the application starts
+var decompressConfig = struct {
+	enabled bool
+	maxSize int
+}{enabled: true, maxSize: defaultDecompressMaxSize}
+
+func init() {
+	OnAppStart(func() {
+		decompressConfig.enabled = Config.BoolDefault("http.request.decompress", true)
+		decompressConfig.maxSize = int(ConfigSizeDefault("http.request.decompress.maxsize", defaultDecompressMaxSize, 1))
+	})
+}
+
+// DecompressFilter decompresses the request body when the request has a
+// Content-Encoding of gzip, deflate or br, this must be run before the ParamsFilter.
+// The decompressed body is limited to `http.request.decompress.maxsize` bytes (10MB by default)
+// to protect against compression bombs, larger requests are rejected with a 413.
+// Set `http.request.decompress=false` to disable the decompression.
+func DecompressFilter(c *Controller, fc []Filter) {
+	encoding := strings.TrimSpace(c.Request.GetHttpHeader("Content-Encoding"))
+	if encoding == "" || strings.EqualFold(encoding, "identity") || !decompressConfig.enabled {
+		fc[0](c, fc[1:])
+		return
+	}
+
+	content, status, err := decompressBody(c.Request.GetBody(), encoding, decompressConfig.maxSize)
+	if err == nil && !c.Request.In.Set(HTTP_BODY, bytes.NewReader(content)) {
+		status, err = http.StatusUnsupportedMediaType, fmt.Errorf("The server engine does not support compressed requests")
+	}
+	if err != nil {
+		httpLog.Warn("DecompressFilter: Failed to decompress request", "action", c.Action, "encoding", encoding, "error", err)
+		c.Response.Status = status
+		c.Result = c.RenderText("%s", err.Error())
+		return
+	}
+
+	fc[0](c, fc[1:])
+}
+
+// Decompresses the body, the encodings are listed in the order they were applied
+func decompressBody(body io.Reader, encoding string, maxSize int) (content []byte, status int, err error) {
+	if body == nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("Compressed request has no body")
+	}
+	encodings := strings.Split(encoding, ",")
+	reader := body
+	for i := len(encodings) - 1; i >= 0; i-- {
+		name := strings.ToLower(strings.TrimSpace(encodings[i]))
+		if name == "identity" {
+			continue
+		}
+		decompressor, found := requestDecompressors[name]
+		if !found {
+			return nil, http.StatusUnsupportedMediaType, fmt.Errorf("Unsupported Content-Encoding %s", name)
+		}
+		if reader, err = decompressor(reader); err != nil {
+			return nil, http.StatusBadRequest, fmt.Errorf("Invalid %s request body: %s", name, err)
+		}
+	}
+
+	// Read one more byte than allowed to detect an oversized body
+	if content, err = ioutil.ReadAll(io.LimitReader(reader, int64(maxSize)+1)); err != nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("Invalid %s request body: %s", encoding, err)
+	}
+	if len(content) > maxSize {
+		return nil, http.StatusRequestEntityTooLarge, fmt.Errorf("Decompressed request body exceeds %d bytes", maxSize)
+	}
+	return content, http.StatusOK, nil
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/gzip"
+)
+
+func compressedRequest(encoding string, body string) *http.Request {
+	buffer := &bytes.Buffer{}
+	var writer io.WriteCloser
+	switch encoding {
+	case "gzip":
+		writer = gzip.NewWriter(buffer)
+	case "br":
+		writer = brotli.NewWriter(buffer)
+	}
+	writer.Write([]byte(body))
+	writer.Close()
+	req, _ := http.NewRequest("POST", "/hotels", buffer)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", encoding)
+	return req
+}
+
+func TestDecompressFilter(t *testing.T) {
+	startFakeBookingApp()
+	body := `{"name":"A Hotel"}`
+	for _, encoding := range []string{"gzip", "br"} {
+		c := NewTestController(httptest.NewRecorder(), compressedRequest(encoding, body))
+		c.Params = &Params{}
+		DecompressFilter(c, []Filter{ParamsFilter, NilFilter})
+		if c.Result != nil {
+			t.Errorf("Unexpected result for %s %#v", encoding, c.Result)
+		}
+		if string(c.Params.JSON) != body {
+			t.Errorf("Expected %s body %s, got %s", encoding, body, c.Params.JSON)
+		}
+		if c.Request.GetHttpHeader("Content-Encoding") != "" {
+			t.Errorf("Expected Content-Encoding to be removed")
+		}
+	}
+}
+
+func TestDecompressFilterLimits(t *testing.T) {
+	startFakeBookingApp()
+	decompressConfig.maxSize = 100
+	defer func() { decompressConfig.maxSize = defaultDecompressMaxSize }()
+
+	invalid, _ := http.NewRequest("POST", "/hotels", strings.NewReader("not compressed"))
+	invalid.Header.Set("Content-Encoding", "gzip")
+	unsupported, _ := http.NewRequest("POST", "/hotels", strings.NewReader("data"))
+	unsupported.Header.Set("Content-Encoding", "compress")
+
+	tests := []struct {
+		request *http.Request
+		status  int
+	}{
+		{compressedRequest("gzip", strings.Repeat("a", 101)), http.StatusRequestEntityTooLarge},
+		{compressedRequest("br", strings.Repeat("a", 100)), 0},
+		{invalid, http.StatusBadRequest},
+		{unsupported, http.StatusUnsupportedMediaType},
+	}
+	for i, test := range tests {
+		c := NewTestController(httptest.NewRecorder(), test.request)
+		DecompressFilter(c, NilChain)
+		if c.Response.Status != test.status {
+			t.Errorf("Test %d expected status %d got %d", i, test.status, c.Response.Status)
+		}
+	}
+}
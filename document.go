@@ -0,0 +1,111 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// A module renders documents (PDF...) from the HTML templates by registering a converter
+// for their media type
+//   revel.RegisterDocumentConverter("application/pdf", "pdf", revel.DocumentConverterFunc(func(w io.Writer, html io.Reader) error {
+//   	return wkhtmltopdf(w, html)
+//   }))
+// When the request format is the format of a converter (@Produces("application/pdf"), a
+// path ending with .pdf, or RenderAuto negotiating it), Render renders the HTML template of
+// the action (Controller/Action.html) and pipes it through the converter. The document is
+// sent as it is converted.
+//   return c.RenderDocument("Invoices/Show.html", "pdf", "invoice-12.pdf")
+
+// DocumentConverter converts the rendered HTML to a document
+type DocumentConverter interface {
+	Convert(w io.Writer, html io.Reader) error
+}
+
+// DocumentConverterFunc is a function converting the rendered HTML to a document
+type DocumentConverterFunc func(w io.Writer, html io.Reader) error
+
+// Convert calls the function
+func (f DocumentConverterFunc) Convert(w io.Writer, html io.Reader) error {
+	return f(w, html)
+}
+
+// A registered document format
+type documentFormat struct {
+	mediaType string
+	converter DocumentConverter
+}
+
+// The document formats, mapped by the request format
+var documentFormats = map[string]*documentFormat{}
+
+// RegisterDocumentConverter registers the converter of the media type, the format is the
+// Request.Format of the media type. RenderAuto renders the documents of the media type.
+func RegisterDocumentConverter(mediaType, format string, converter DocumentConverter) {
+	documentFormats[format] = &documentFormat{mediaType: mediaType, converter: converter}
+	RegisterAutoRenderer(mediaType, format, func(c *Controller, o interface{}) Result {
+		c.ViewArgs["result"] = o
+		return c.RenderDocument(c.Name+"/"+c.MethodType.Name+".html", format, "")
+	})
+}
+
+// DocumentResult renders the template and converts it to a document
+type DocumentResult struct {
+	Template  Template
+	ViewArgs  map[string]interface{}
+	MediaType string
+	Filename  string // The file name of the Content-Disposition header, no header when empty
+	Converter DocumentConverter
+}
+
+func (r *DocumentResult) Apply(req *Request, resp *Response) {
+	// The template is rendered while it is converted
+	html, htmlWriter := io.Pipe()
+	go func() {
+		htmlWriter.CloseWithError((&RenderTemplateResult{Template: r.Template, ViewArgs: r.ViewArgs}).renderOutput(htmlWriter))
+	}()
+	// The rendering stops when the converter fails
+	defer html.Close()
+
+	writer := &documentWriter{resp: resp, result: r}
+	if err := r.Converter.Convert(writer, html); err != nil {
+		if writer.started {
+			// The status is sent, the document is left incomplete
+			resultsLog.Error("Apply: Failed to convert the document", "template", r.Template.Name(), "error", err)
+			return
+		}
+		resp.Status = http.StatusInternalServerError
+		ErrorResult{ViewArgs: r.ViewArgs, Error: fmt.Errorf("Failed to convert %s to %s: %s", r.Template.Name(), r.MediaType, err)}.Apply(req, resp)
+		return
+	}
+	if !writer.started {
+		writer.writeHeader()
+	}
+}
+
+// Writes the status and the headers of the response on the first write of the converter,
+// so the error page is rendered when the converter fails before writing
+type documentWriter struct {
+	resp    *Response
+	result  *DocumentResult
+	started bool
+}
+
+func (w *documentWriter) writeHeader() {
+	w.started = true
+	if w.result.Filename != "" {
+		w.resp.Out.internalHeader.Set("Content-Disposition", Inline.Header(w.result.Filename))
+	}
+	w.resp.WriteHeader(http.StatusOK, w.result.MediaType)
+}
+
+func (w *documentWriter) Write(p []byte) (int, error) {
+	if !w.started {
+		w.writeHeader()
+	}
+	return w.resp.GetWriter().Write(p)
+}
@@ -0,0 +1,48 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRenderDocument(t *testing.T) {
+	startFakeBookingApp()
+	RegisterDocumentConverter("application/x-upper", "upper", DocumentConverterFunc(func(w io.Writer, html io.Reader) error {
+		content, err := ioutil.ReadAll(html)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(bytes.ToUpper(content))
+		return err
+	}))
+	RegisterDocumentConverter("application/x-broken", "broken", DocumentConverterFunc(func(w io.Writer, html io.Reader) error {
+		return errors.New("converter not installed")
+	}))
+
+	resp := httptest.NewRecorder()
+	c := NewTestController(resp, showRequest)
+	c.ViewArgs["hotels"] = []string{"Hilton"}
+	c.RenderDocument("hotels/list.html", "upper", "hotels.upper").Apply(c.Request, c.Response)
+	if !strings.Contains(resp.Body.String(), "<TD>HILTON</TD>") || resp.Header().Get("Content-Type") != "application/x-upper" {
+		t.Errorf("Expected the converted template, got %v %q", resp.Header(), resp.Body.String())
+	}
+	if disposition := resp.Header().Get("Content-Disposition"); disposition != `inline; filename="hotels.upper"` {
+		t.Errorf("Unexpected disposition %s", disposition)
+	}
+
+	resp = httptest.NewRecorder()
+	c = NewTestController(resp, showRequest)
+	c.RenderDocument("hotels/list.html", "broken", "hotels.pdf").Apply(c.Request, c.Response)
+	if resp.Code != 500 || resp.Header().Get("Content-Disposition") != "" {
+		t.Errorf("Expected an error page, got %d %v", resp.Code, resp.Header())
+	}
+}
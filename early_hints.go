@@ -0,0 +1,28 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"errors"
+)
+
+// ErrEarlyHintsUnsupported is returned by WriteEarlyHints when the server engine cannot
+// send a 103 Early Hints response, the Go server sends them when built with Go 1.19 or later
+var ErrEarlyHintsUnsupported = errors.New("revel: the server engine does not support early hints")
+
+// WriteEarlyHints sends a 103 Early Hints response with the Link headers, so the browser
+// preloads the resources of the page while the action runs
+//   c.Response.WriteEarlyHints([]string{"</public/css/app.css>; rel=preload; as=style"})
+// The links are kept in the headers of the final response. It must be called before the
+// status of the response is written.
+func (resp *Response) WriteEarlyHints(links []string) error {
+	if len(links) == 0 {
+		return nil
+	}
+	if !resp.Out.Server.Set(HTTP_EARLY_HINTS, links) {
+		return ErrEarlyHintsUnsupported
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+//go:build !go1.19
+
+package revel
+
+// Before Go 1.19 the net/http server takes a 103 for the final status of the response
+const earlyHintsSupported = false
@@ -0,0 +1,270 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The responses cached by a CDN are tagged with surrogate keys, so they are purged by tag
+// when the data they show changes. The @SurrogateKey annotation tags the responses of an
+// action, the parameters are expanded like in the key of @Cache
+//   // @SurrogateKey("hotels", "hotel-:id")
+// and an action adds tags known once it runs
+//   c.SurrogateKeys("city-" + hotel.City)
+// The tags are purged with
+//   revel.EdgePurge("hotel-3")
+// The CDN is configured in app.conf
+//   edge.purger = fastly          # fastly, cloudflare or varnish
+//   edge.fastly.service = SU1Z0isxPaozGVKXdv0eY
+//   edge.fastly.token = ...
+//   edge.fastly.soft = true       # Mark the content as stale instead of removing it
+//   edge.cloudflare.zone = 023e105f4ecef8ad9ca31a8372d0c353
+//   edge.cloudflare.token = ...
+//   edge.varnish.url = http://varnish:6081/  # Varnish with the xkey module
+//   edge.timeout = 10s
+// Without a purger the tags are sent in a Surrogate-Key header and EdgePurge does nothing.
+func init() {
+	RegisterAnnotationProcessor("SurrogateKey", func(ct *ControllerType, mt *MethodType, annotation *FunctionalAnnotation) error {
+		var tags []string
+		for position := 0; ; position++ {
+			if _, found := annotation.Data[strconv.Itoa(position)]; !found {
+				break
+			}
+			tags = append(tags, annotation.GetStrings("", position)...)
+		}
+		if len(tags) == 0 {
+			return fmt.Errorf("@SurrogateKey requires at least one tag")
+		}
+		methods := ct.Methods
+		if mt != nil {
+			methods = []*MethodType{mt}
+		}
+		for _, method := range methods {
+			method.surrogateKeys = tags
+		}
+		return nil
+	})
+	RegisterAnnotationSchema("SurrogateKey", "tags...")
+	OnAppStart(func() {
+		if EdgeCachePurger != nil {
+			return
+		}
+		timeout := ConfigDurationDefault("edge.timeout", 10*time.Second, time.Second)
+		client := &http.Client{Timeout: timeout}
+		switch purger := Config.StringDefault("edge.purger", ""); purger {
+		case "":
+		case "fastly":
+			EdgeCachePurger = &FastlyPurger{
+				ServiceID: Config.StringDefault("edge.fastly.service", ""),
+				Token:     Config.StringDefault("edge.fastly.token", ""),
+				Soft:      Config.BoolDefault("edge.fastly.soft", false),
+				Client:    client,
+			}
+		case "cloudflare":
+			EdgeCachePurger = &CloudflarePurger{
+				ZoneID: Config.StringDefault("edge.cloudflare.zone", ""),
+				Token:  Config.StringDefault("edge.cloudflare.token", ""),
+				Client: client,
+			}
+		case "varnish":
+			EdgeCachePurger = &VarnishPurger{URL: Config.StringDefault("edge.varnish.url", ""), Client: client}
+		default:
+			edgeCacheLog.Fatal("Unknown edge.purger, expected fastly, cloudflare or varnish", "purger", purger)
+		}
+	})
+}
+
+// EdgePurger purges the responses cached by a CDN by their tags
+type EdgePurger interface {
+	// TagHeader returns the response header which tags the response for the CDN
+	TagHeader(tags []string) (name, value string)
+	// Purge removes the responses tagged with one of the tags
+	Purge(tags []string) error
+}
+
+// EdgeCachePurger is the purger of the CDN set by edge.purger, an application may set its own
+var EdgeCachePurger EdgePurger
+
+var edgeCacheLog = RevelLog.New("section", "edgecache")
+
+// The tags of the current request
+var edgeCacheTagsKey = NewReqKey[[]string]("edgeCacheTags")
+
+// EdgePurge purges the responses tagged with one of the tags from the CDN
+func EdgePurge(tags ...string) error {
+	if EdgeCachePurger == nil || len(tags) == 0 {
+		return nil
+	}
+	if err := EdgeCachePurger.Purge(tags); err != nil {
+		edgeCacheLog.Error("Failed to purge the CDN", "tags", tags, "error", err)
+		return err
+	}
+	return nil
+}
+
+// SurrogateKeys tags the response with the tags, besides the ones of the @SurrogateKey
+// annotation of the action. It must be called before the result is applied.
+func (c *Controller) SurrogateKeys(tags ...string) {
+	all, _ := GetReqValue(c, edgeCacheTagsKey)
+	for _, tag := range tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			all = append(all, tag)
+		}
+	}
+	if len(all) == 0 {
+		return
+	}
+	SetReqValue(c, edgeCacheTagsKey, all)
+
+	name, value := "Surrogate-Key", strings.Join(all, " ")
+	if EdgeCachePurger != nil {
+		name, value = EdgeCachePurger.TagHeader(all)
+	}
+	c.Response.Out.internalHeader.Set(name, value)
+}
+
+// EdgeCacheFilter tags the response with the tags of the @SurrogateKey annotation of the
+// action, the parameters in the tags are replaced by their values
+func EdgeCacheFilter(c *Controller, fc []Filter) {
+	if c.MethodType != nil && len(c.MethodType.surrogateKeys) > 0 {
+		tags := make([]string, len(c.MethodType.surrogateKeys))
+		for i, tag := range c.MethodType.surrogateKeys {
+			tags[i] = actionCacheKeyParam.ReplaceAllStringFunc(tag, func(name string) string {
+				return c.Params.Get(name[1:])
+			})
+		}
+		c.SurrogateKeys(tags...)
+	}
+	fc[0](c, fc[1:])
+}
+
+// Sends the purge request, a response which is not a 2xx is an error
+func sendPurgeRequest(client *http.Client, req *http.Request) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("purge failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// Sends the tags in batches of size, as the APIs limit the number of tags of a purge
+func purgeInBatches(tags []string, size int, purge func(batch []string) error) error {
+	for start := 0; start < len(tags); start += size {
+		end := start + size
+		if end > len(tags) {
+			end = len(tags)
+		}
+		if err := purge(tags[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FastlyPurger purges the surrogate keys of a Fastly service
+type FastlyPurger struct {
+	ServiceID string
+	Token     string
+	Soft      bool         // Mark the content as stale instead of removing it
+	Endpoint  string       // Defaults to https://api.fastly.com
+	Client    *http.Client // Defaults to the http.DefaultClient
+}
+
+func (p *FastlyPurger) TagHeader(tags []string) (string, string) {
+	return "Surrogate-Key", strings.Join(tags, " ")
+}
+
+func (p *FastlyPurger) Purge(tags []string) error {
+	endpoint := p.Endpoint
+	if endpoint == "" {
+		endpoint = "https://api.fastly.com"
+	}
+	return purgeInBatches(tags, 256, func(batch []string) error {
+		body, err := json.Marshal(map[string][]string{"surrogate_keys": batch})
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequest("POST", strings.TrimSuffix(endpoint, "/")+"/service/"+p.ServiceID+"/purge", bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Fastly-Key", p.Token)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+		if p.Soft {
+			req.Header.Set("Fastly-Soft-Purge", "1")
+		}
+		return sendPurgeRequest(p.Client, req)
+	})
+}
+
+// CloudflarePurger purges the cache tags of a Cloudflare zone
+type CloudflarePurger struct {
+	ZoneID   string
+	Token    string       // An API token allowed to purge the zone
+	Endpoint string       // Defaults to https://api.cloudflare.com/client/v4
+	Client   *http.Client // Defaults to the http.DefaultClient
+}
+
+func (p *CloudflarePurger) TagHeader(tags []string) (string, string) {
+	return "Cache-Tag", strings.Join(tags, ",")
+}
+
+func (p *CloudflarePurger) Purge(tags []string) error {
+	endpoint := p.Endpoint
+	if endpoint == "" {
+		endpoint = "https://api.cloudflare.com/client/v4"
+	}
+	return purgeInBatches(tags, 30, func(batch []string) error {
+		body, err := json.Marshal(map[string][]string{"tags": batch})
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequest("POST", strings.TrimSuffix(endpoint, "/")+"/zones/"+p.ZoneID+"/purge_cache", bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+p.Token)
+		req.Header.Set("Content-Type", "application/json")
+		return sendPurgeRequest(p.Client, req)
+	})
+}
+
+// VarnishPurger purges the keys of a Varnish cache using the xkey module, the VCL must
+// handle a PURGE request with an xkey-purge header
+type VarnishPurger struct {
+	URL    string
+	Client *http.Client // Defaults to the http.DefaultClient
+}
+
+func (p *VarnishPurger) TagHeader(tags []string) (string, string) {
+	return "xkey", strings.Join(tags, " ")
+}
+
+func (p *VarnishPurger) Purge(tags []string) error {
+	req, err := http.NewRequest("PURGE", p.URL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("xkey-purge", strings.Join(tags, " "))
+	return sendPurgeRequest(p.Client, req)
+}
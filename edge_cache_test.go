@@ -0,0 +1,101 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+type EdgeCachedController struct {
+	*Controller
+}
+
+func (c EdgeCachedController) Show(id int) Result {
+	return nil
+}
+
+func TestEdgeCacheFilter(t *testing.T) {
+	startFakeBookingApp()
+	keys, _ := ParseAnnotation(`@SurrogateKey("hotels", "hotel-:id")`)
+	RegisterController((*EdgeCachedController)(nil), []*MethodType{
+		{Name: "Show", Annotations: FunctionalAnnotations{keys}, Args: []*MethodArg{{Name: "id", Type: reflect.TypeOf((*int)(nil))}}},
+	})
+
+	for _, purger := range []EdgePurger{nil, &CloudflarePurger{}} {
+		EdgeCachePurger = purger
+		resp := httptest.NewRecorder()
+		c := NewTestController(resp, showRequest)
+		if err := c.SetAction("EdgeCachedController", "Show"); err != nil {
+			t.Fatal(err)
+		}
+		c.Params = &Params{Values: url.Values{"id": {"3"}}}
+		EdgeCacheFilter(c, []Filter{func(c *Controller, _ []Filter) {
+			c.SurrogateKeys("city-paris")
+		}})
+		if purger == nil && resp.Header().Get("Surrogate-Key") != "hotels hotel-3 city-paris" {
+			t.Errorf("Expected the Surrogate-Key header, got %v", resp.Header())
+		}
+		if purger != nil && resp.Header().Get("Cache-Tag") != "hotels,hotel-3,city-paris" {
+			t.Errorf("Expected the Cache-Tag header of the purger, got %v", resp.Header())
+		}
+	}
+	EdgeCachePurger = nil
+}
+
+func TestEdgePurgers(t *testing.T) {
+	var requests []*http.Request
+	var bodies []map[string][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := map[string][]string{}
+		json.NewDecoder(r.Body).Decode(&body)
+		requests, bodies = append(requests, r), append(bodies, body)
+	}))
+	defer server.Close()
+
+	tags := make([]string, 31)
+	for i := range tags {
+		tags[i] = "tag"
+	}
+	EdgeCachePurger = &CloudflarePurger{ZoneID: "zone", Token: "token", Endpoint: server.URL}
+	defer func() { EdgeCachePurger = nil }()
+	if err := EdgePurge(tags...); err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 2 || requests[0].URL.Path != "/zones/zone/purge_cache" || requests[0].Header.Get("Authorization") != "Bearer token" ||
+		len(bodies[0]["tags"]) != 30 || len(bodies[1]["tags"]) != 1 {
+		t.Errorf("Expected the tags to be purged in two batches, got %d requests", len(requests))
+	}
+
+	requests, bodies = nil, nil
+	EdgeCachePurger = &FastlyPurger{ServiceID: "service", Token: "token", Soft: true, Endpoint: server.URL}
+	if err := EdgePurge("hotel-3"); err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 1 || requests[0].URL.Path != "/service/service/purge" || requests[0].Header.Get("Fastly-Key") != "token" ||
+		requests[0].Header.Get("Fastly-Soft-Purge") != "1" || !reflect.DeepEqual(bodies[0]["surrogate_keys"], []string{"hotel-3"}) {
+		t.Errorf("Unexpected Fastly purge %v %v", requests, bodies)
+	}
+
+	requests = nil
+	EdgeCachePurger = &VarnishPurger{URL: server.URL}
+	if err := EdgePurge("hotel-3", "hotels"); err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 1 || requests[0].Method != "PURGE" || requests[0].Header.Get("xkey-purge") != "hotel-3 hotels" {
+		t.Errorf("Unexpected Varnish purge %v", requests)
+	}
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+	})
+	if err := EdgePurge("hotel-3"); err == nil {
+		t.Errorf("Expected an error for a failed purge")
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// The results send validators so clients and proxies revalidate them instead of
+// downloading them again, a request with a matching If-None-Match or If-Modified-Since
+// header is answered with a 304 Not Modified.
+//   - RenderFile and RenderBinary send a Last-Modified header and an ETag made of the
+//     size and modification time of the content
+//   - the other results of GET requests (templates, JSON...) send an ETag made of a hash
+//     of their content when results.etag is set, the response is buffered to compute it
+// The behaviour is configured in app.conf
+//   results.etag = weak        # off (the default), weak or strong
+//   results.etag.files = true  # Send the validators of the files
+
+// Returns true if the validators of the request match the ETag or the modification time.
+// If-None-Match is used when the request has it, If-Modified-Since otherwise.
+func notModified(req *Request, etag string, modtime time.Time) bool {
+	if ifNoneMatch := req.GetHttpHeader("If-None-Match"); ifNoneMatch != "" {
+		if etag == "" {
+			return false
+		}
+		for _, candidate := range strings.Split(ifNoneMatch, ",") {
+			// The weak comparison, the W/ prefix is ignored
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+	if modtime.IsZero() || modtime.Unix() <= 0 {
+		return false
+	}
+	since, err := http.ParseTime(req.GetHttpHeader("If-Modified-Since"))
+	// The Last-Modified header truncates the time to the second
+	return err == nil && !modtime.Truncate(time.Second).After(since)
+}
+
+// Writes a 304 Not Modified response, without the headers describing the content
+func writeNotModified(resp *Response) {
+	header := resp.Out.internalHeader.Server
+	header.Del("Content-Type")
+	header.Del("Content-Length")
+	header.Del("Content-Encoding")
+	resp.Status = http.StatusNotModified
+	resp.SetStatus(http.StatusNotModified)
+}
+
+// Returns the ETag of a file of the size and modification time
+func fileETag(size int64, modtime time.Time) string {
+	return fmt.Sprintf(`W/"%x-%x"`, size, modtime.UnixNano())
+}
+
+// The ETag settings, loaded when the application starts
+var etagConfig = struct {
+	mode  string // off, weak or strong
+	files bool   // Send the validators of the files
+}{mode: "off", files: true}
+
+func init() {
+	OnAppStart(func() {
+		etagConfig.mode = etagMode(Config.StringDefault("results.etag", "off"))
+		etagConfig.files = Config.BoolDefault("results.etag.files", true)
+	})
+}
+
+// Returns the results.etag mode, off, weak or strong
+func etagMode(mode string) string {
+	switch mode {
+	case "weak", "strong":
+		return mode
+	case "off", "", "false":
+	default:
+		resultsLog.Warn("Unknown results.etag, expected off, weak or strong", "value", mode)
+	}
+	return "off"
+}
+
+// Wraps the result of a GET or HEAD request so its response gets the ETag of its content,
+// the results which send their own validators or stream their content are not wrapped
+func wrapETagResult(c *Controller, result Result) Result {
+	if result == nil || (c.Request.Method != "GET" && c.Request.Method != "HEAD") {
+		return result
+	}
+	switch result.(type) {
+	case *BinaryResult, *ByteRangesResult, *RenderJSONStreamResult, *RenderCSVResult, *MultipartResult:
+		return result
+	}
+	mode := etagConfig.mode
+	if mode == "off" {
+		return result
+	}
+	return &etagResult{result: result, weak: mode == "weak"}
+}
+
+// etagResult buffers the response of the result to send the ETag of its content, or a
+// 304 when the client has the content
+type etagResult struct {
+	result Result
+	weak   bool
+}
+
+func (r *etagResult) Apply(req *Request, resp *Response) {
+	// The status and headers are held until the ETag is known
+	header := NewBufferedServerHeader(resp.Out.internalHeader.Server)
+	original := resp.Out.internalHeader.Server
+	resp.Out.internalHeader.Server = header
+	writer := resp.GetWriter()
+	body := &bytes.Buffer{}
+	resp.SetWriter(body)
+	r.result.Apply(req, resp)
+	resp.SetWriter(writer)
+	resp.Out.internalHeader.Server = original
+
+	if resp.Status != http.StatusOK {
+		header.Release()
+		if _, err := io.Copy(writer, body); err != nil {
+			resultsLog.Error("Apply: Response write failed", "error", err)
+		}
+		return
+	}
+
+	hash := fnv.New64a()
+	hash.Write(body.Bytes())
+	etag := fmt.Sprintf(`"%x"`, hash.Sum64())
+	if r.weak {
+		etag = "W/" + etag
+	}
+	header.Set("ETag", etag)
+	if notModified(req, etag, time.Time{}) {
+		header.Del("Content-Type")
+		header.Del("Content-Length")
+		header.SetStatus(http.StatusNotModified)
+		resp.Status = http.StatusNotModified
+		header.Release()
+		return
+	}
+	header.Release()
+	if _, err := io.Copy(writer, body); err != nil {
+		resultsLog.Error("Apply: Response write failed", "error", err)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNotModified(t *testing.T) {
+	modtime := time.Date(2017, 3, 1, 10, 0, 0, 500, time.UTC)
+	for _, test := range []struct {
+		header, value string
+		expected      bool
+	}{
+		{"If-None-Match", `W/"abc"`, true},
+		{"If-None-Match", `"abc"`, true},
+		{"If-None-Match", `"def", W/"abc"`, true},
+		{"If-None-Match", `*`, true},
+		{"If-None-Match", `"def"`, false},
+		{"If-Modified-Since", modtime.Format(http.TimeFormat), true},
+		{"If-Modified-Since", modtime.Add(-time.Hour).Format(http.TimeFormat), false},
+		{"If-Modified-Since", "yesterday", false},
+	} {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header.Set(test.header, test.value)
+		c := NewTestController(httptest.NewRecorder(), req)
+		if actual := notModified(c.Request, `W/"abc"`, modtime); actual != test.expected {
+			t.Errorf("%s: %s, expected %v got %v", test.header, test.value, test.expected, actual)
+		}
+	}
+}
+
+func TestBinaryResultNotModified(t *testing.T) {
+	modtime := time.Now()
+	request := func(header, value string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/files/content.txt", nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		resp := httptest.NewRecorder()
+		c := NewTestController(resp, req)
+		result := &BinaryResult{Reader: strings.NewReader("content"), Name: "content.txt", Length: -1, Delivery: Inline, ModTime: modtime}
+		result.Apply(c.Request, c.Response)
+		return resp
+	}
+
+	resp := request("", "")
+	etag := resp.Header().Get("ETag")
+	if resp.Code != http.StatusOK || etag == "" || resp.Header().Get("Last-Modified") == "" {
+		t.Fatalf("Expected the validators, got %d %v", resp.Code, resp.Header())
+	}
+	if resp = request("If-None-Match", etag); resp.Code != http.StatusNotModified || resp.Body.Len() != 0 {
+		t.Errorf("Expected a 304 for the ETag, got %d %q", resp.Code, resp.Body.String())
+	}
+	if resp = request("If-Modified-Since", modtime.UTC().Format(http.TimeFormat)); resp.Code != http.StatusNotModified {
+		t.Errorf("Expected a 304 for the modification time, got %d", resp.Code)
+	}
+}
+
+func TestETagResult(t *testing.T) {
+	request := func(method, ifNoneMatch string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, "/hotels", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		resp := httptest.NewRecorder()
+		c := NewTestController(resp, req)
+		wrapETagResult(c, c.RenderText("hotels")).Apply(c.Request, c.Response)
+		return resp
+	}
+
+	if resp := request("GET", ""); resp.Header().Get("ETag") != "" {
+		t.Errorf("Expected no ETag when results.etag is off, got %s", resp.Header().Get("ETag"))
+	}
+
+	etagConfig.mode = "weak"
+	defer func() { etagConfig.mode = "off" }()
+	resp := request("GET", "")
+	etag := resp.Header().Get("ETag")
+	if resp.Code != http.StatusOK || resp.Body.String() != "hotels" || !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("Expected the content with a weak ETag, got %d %q %s", resp.Code, resp.Body.String(), etag)
+	}
+	if resp = request("GET", etag); resp.Code != http.StatusNotModified || resp.Body.Len() != 0 {
+		t.Errorf("Expected a 304, got %d %q", resp.Code, resp.Body.String())
+	}
+	if resp = request("POST", etag); resp.Code != http.StatusOK || resp.Header().Get("ETag") != "" {
+		t.Errorf("Expected a POST without an ETag, got %d %v", resp.Code, resp.Header())
+	}
+}
@@ -80,14 +80,14 @@ func registerControllers() {
 			{
 				Name: "Show",
 				Args: []*MethodArg{
-					{"id", reflect.TypeOf((*int)(nil))},
+					{Name: "id", Type: reflect.TypeOf((*int)(nil))},
 				},
 				RenderArgNames: map[int][]string{41: {"title", "hotel"}},
 			},
 			{
 				Name: "Book",
 				Args: []*MethodArg{
-					{"id", reflect.TypeOf((*int)(nil))},
+					{Name: "id", Type: reflect.TypeOf((*int)(nil))},
 				},
 			},
 		})
@@ -11,14 +11,27 @@ type Filter func(c *Controller, filterChain []Filter)
 // It may be set by the application on initialization.
 var Filters = []Filter{
 	PanicFilter,             // Recover from panics and display an error page instead.
+	ContentReloadFilter,     // Serve the control API to reload templates and messages (when reload.api.token is set).
+	DashboardFilter,         // Serve the dashboard made of the registered widgets (when dashboard.enabled=true).
+	OpenAPIFilter,           // Serve the OpenAPI document of the routes (when openapi.enabled=true).
+	SPACSRFFilter,           // Protect single page applications against CSRF (when spa.csrf=true).
 	RouterFilter,            // Use the routing table to select the right Action.
+	CircuitBreakerFilter,    // Stop calling actions which keep failing (when circuitbreaker.enabled=true).
 	FilterConfiguringFilter, // A hook for adding or removing per-Action filters.
+	NegotiationFilter,       // Enforce the @Consumes and @Produces annotations of the action.
+	DecompressFilter,        // Decompress gzip or brotli encoded request bodies.
 	ParamsFilter,            // Parse parameters into Controller.Params.
 	SessionFilter,           // Restore and write the session cookie.
 	FlashFilter,             // Restore and write the flash cookie.
 	ValidationFilter,        // Restore kept validation errors and save new ones from cookie.
 	I18nFilter,              // Resolve the requested language.
+	SEOFilter,               // Add the robots directives and canonical URL of the action to the view args.
+	EdgeCacheFilter,         // Tag the response with the surrogate keys of the action for the CDN.
+	RateLimitFilter,         // Enforce the @RateLimit annotation of the action.
+	AuthorizeFilter,         // Enforce the @Authorize annotation of the action.
+	DeprecationFilter,       // Log and count the calls to actions annotated with @Deprecated.
 	InterceptorFilter,       // Run interceptors around the action.
+	PageCacheFilter,         // Serve the pages cached by the @PageCache annotation, after the BEFORE interceptors.
 	CompressFilter,          // Compress the result.
 	ActionInvoker,           // Invoke the action.
 }
@@ -6,6 +6,7 @@ package revel
 
 import (
 	"reflect"
+	"sort"
 	"strings"
 )
 
@@ -201,6 +202,45 @@ func FilterEq(a, b Filter) bool {
 	return reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer()
 }
 
+// Returns the actions of the controllers selected by the function which the filter does not
+// run for, it is neither in the Filters nor in the filters configured for the action
+func actionsMissingFilter(filter Filter, selected func(mt *MethodType) bool) (actions []string) {
+	checked := map[*ControllerType]bool{}
+	for _, ct := range controllers {
+		if checked[ct] {
+			continue
+		}
+		checked[ct] = true
+		for _, mt := range ct.Methods {
+			if selected(mt) && !actionHasFilter(ct, mt, filter) {
+				actions = append(actions, ct.Type.Name()+"."+mt.Name)
+			}
+		}
+	}
+	sort.Strings(actions)
+	return
+}
+
+// Returns true if the filter runs for the action
+func actionHasFilter(ct *ControllerType, mt *MethodType, filter Filter) bool {
+	filters := Filters
+	if chain := getOverrideChain(ct.Type.Name(), ct.Type.Name()+"."+mt.Name); chain != nil {
+		// The configured filters replace the filters after the FilterConfiguringFilter
+		for i, f := range Filters {
+			if FilterEq(f, FilterConfiguringFilter) {
+				filters = append(append([]Filter{}, Filters[:i]...), chain...)
+				break
+			}
+		}
+	}
+	for _, f := range filters {
+		if FilterEq(f, filter) {
+			return true
+		}
+	}
+	return false
+}
+
 // FilterConfiguringFilter is a filter stage that customizes the remaining
 // filter chain for the action being invoked.
 func FilterConfiguringFilter(c *Controller, fc []Filter) {
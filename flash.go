@@ -6,7 +6,6 @@ package revel
 
 import (
 	"fmt"
-	"net/http"
 	"net/url"
 )
 
@@ -42,7 +41,8 @@ func (f Flash) Success(msg string, args ...interface{}) {
 
 // FlashFilter is a Revel Filter that retrieves and sets the flash cookie.
 // Within Revel, it is available as a Flash attribute on Controller instances.
-// The name of the Flash cookie is set as CookiePrefix + "_FLASH".
+// The name of the Flash cookie is set as CookiePrefix + "_FLASH", its attributes are set by
+// cookie.flash.* in app.conf.
 func FlashFilter(c *Controller, fc []Filter) {
 	c.Flash = restoreFlash(c.Request)
 	c.ViewArgs["flash"] = c.Flash.Data
@@ -54,13 +54,7 @@ func FlashFilter(c *Controller, fc []Filter) {
 	for key, value := range c.Flash.Out {
 		flashValue += "\x00" + key + ":" + value + "\x00"
 	}
-	c.SetCookie(&http.Cookie{
-		Name:     CookiePrefix + "_FLASH",
-		Value:    url.QueryEscape(flashValue),
-		HttpOnly: true,
-		Secure:   CookieSecure,
-		Path:     "/",
-	})
+	c.SetCookie(cookieAttributesOf(flashCookieKind).Cookie("_FLASH", url.QueryEscape(flashValue)))
 }
 
 // restoreFlash deserializes a Flash cookie struct from a request.
@@ -69,7 +63,7 @@ func restoreFlash(req *Request) Flash {
 		Data: make(map[string]string),
 		Out:  make(map[string]string),
 	}
-	if cookie, err := req.Cookie(CookiePrefix + "_FLASH"); err == nil {
+	if cookie, err := req.Cookie(cookieAttributesOf(flashCookieKind).Name("_FLASH")); err == nil {
 		ParseKeyValueCookie(cookie.GetValue(), func(key, val string) {
 			flash.Data[key] = val
 		})
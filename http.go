@@ -16,7 +16,6 @@ import (
 	"strings"
 
 	"mime/multipart"
-	"path/filepath"
 )
 
 // Request is Revel's HTTP request object structure
@@ -38,6 +37,8 @@ type Request struct {
 	// DEPRECATED use GetMultipartForm()
 	MultipartForm *MultipartForm
 	controller    *Controller
+	route         *RouteMatch // The route of the request once routed, see routeOf
+	routed        bool
 }
 
 var FORM_NOT_FOUND = errors.New("Form Not Found")
@@ -48,6 +49,7 @@ type Response struct {
 	Status      int
 	ContentType string
 	Out         OutResponse
+	Timing      *ServerTiming // The request phase durations, nil unless server.timing is enabled
 	writer      io.Writer
 }
 type OutResponse struct {
@@ -89,7 +91,7 @@ func (req *Request) SetRequest(r ServerRequest) {
 	req.Method, _ = req.GetValue(HTTP_METHOD).(string)
 	req.RemoteAddr, _ = req.GetValue(HTTP_REMOTE_ADDR).(string)
 	req.Host, _ = req.GetValue(HTTP_HOST).(string)
-
+	req.route, req.routed = nil, false
 }
 func (req *Request) Cookie(key string) (ServerCookie, error) {
 	if req.Header.Server != nil {
@@ -110,6 +112,15 @@ func (req *Request) GetPath() (path string) {
 	path, _ = req.GetValue(ENGINE_PATH).(string)
 	return
 }
+
+// GetRawPath returns the path as sent by the client (still escaped), if the server engine
+// does not provide the URL the decoded path is returned
+func (req *Request) GetRawPath() string {
+	if req.URL != nil {
+		return req.URL.EscapedPath()
+	}
+	return req.GetPath()
+}
 func (req *Request) GetBody() (body io.Reader) {
 	body, _ = req.GetValue(HTTP_BODY).(io.Reader)
 	return
@@ -180,7 +191,7 @@ func (req *Request) Context() map[string]interface{} {
 
 func (req *Request) GetMultipartForm() (ServerMultipartForm, error) {
 	if form, err := req.In.Get(HTTP_MULTIPART_FORM); err != nil {
-		return nil, nil
+		return nil, err
 	} else if values, found := form.(ServerMultipartForm); found {
 		return values, nil
 	}
@@ -198,6 +209,7 @@ func (req *Request) Destroy() {
 	req.URL = nil
 	req.Form = nil
 	req.MultipartForm = nil
+	req.route, req.routed = nil, false
 }
 
 func (resp *Response) SetResponse(r ServerResponse) {
@@ -217,6 +229,7 @@ func (resp *Response) Destroy() {
 	resp.Out.Destroy()
 	resp.Status = 0
 	resp.ContentType = ""
+	resp.Timing = nil
 	resp.writer = nil
 }
 
@@ -246,6 +259,9 @@ func (resp *Response) WriteHeader(defaultStatusCode int, defaultContentType stri
 		resp.ContentType = defaultContentType
 	}
 	resp.Out.internalHeader.Set("Content-Type", resp.ContentType)
+	if resp.Timing != nil {
+		resp.Out.internalHeader.Set("Server-Timing", resp.Timing.String())
+	}
 	if resp.Status == 0 {
 		resp.Status = defaultStatusCode
 	}
@@ -339,42 +355,23 @@ func ResolveContentType(req *Request) string {
 	return strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
 }
 
-// ResolveFormat maps the request's Accept MIME type declaration to
-// a Request.Format attribute, specifically "html", "xml", "json", or "txt",
-// returning a default of "html" when Accept header cannot be mapped to a
-// value above.
+// ResolveFormat maps the extension of the path, or the media type the Accept header
+// prefers among the ones of RegisterAutoRenderer, to a Request.Format attribute like
+// "html", "xml", "json", or "txt", returning a default of "html" when the Accept header
+// cannot be mapped to a format.
 func ResolveFormat(req *Request) string {
-	ext := strings.ToLower(filepath.Ext(req.GetPath()))
-	switch ext {
-	case ".html":
-		return "html"
-	case ".json":
-		return "json"
-	case ".xml":
-		return "xml"
-	case ".txt":
-		return "txt"
+	if format := formatOfExtension(req.GetPath()); format != "" {
+		return format
 	}
 
 	accept := req.GetHttpHeader("accept")
-
-	switch {
-	case accept == "",
-		strings.HasPrefix(accept, "*/*"), // */
-		strings.Contains(accept, "application/xhtml"),
-		strings.Contains(accept, "text/html"):
+	if strings.TrimSpace(accept) == "" {
 		return "html"
-	case strings.Contains(accept, "application/json"),
-		strings.Contains(accept, "text/javascript"),
-		strings.Contains(accept, "application/javascript"):
-		return "json"
-	case strings.Contains(accept, "application/xml"),
-		strings.Contains(accept, "text/xml"):
-		return "xml"
-	case strings.Contains(accept, "text/plain"):
-		return "txt"
 	}
-
+	// The media type the client prefers, html for */* as it is registered first
+	if mediaType := negotiateContentType(accept, autoRendererMediaTypes()); mediaType != "" {
+		return contentTypeFormat(mediaType)
+	}
 	return "html"
 }
 
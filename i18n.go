@@ -11,6 +11,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 
 	"github.com/revel/config"
 )
@@ -30,6 +31,7 @@ const (
 var (
 	// All currently loaded message configs.
 	messages            map[string]*config.Config
+	messagesMutex       sync.RWMutex // Guards messages which are replaced by ReloadContent
 	localeParameterName string
 	i18nLog             = RevelLog.New("section", "i18n")
 )
@@ -44,6 +46,7 @@ var MessageFunc = Message
 
 // MessageLanguages returns all currently loaded message languages.
 func MessageLanguages() []string {
+	messages := loadedMessages()
 	languages := make([]string, len(messages))
 	i := 0
 	for language := range messages {
@@ -60,6 +63,7 @@ func Message(locale, message string, args ...interface{}) string {
 	language, region := parseLocale(locale)
 	unknownValueFormat := getUnknownValueFormat()
 
+	messages := loadedMessages()
 	messageConfig, knownLanguage := messages[language]
 	if !knownLanguage {
 		i18nLog.Debugf("Unsupported language for locale '%s' and message '%s', trying default language", locale, message)
@@ -105,6 +109,21 @@ func Message(locale, message string, args ...interface{}) string {
 	return value
 }
 
+// Returns true if the message files of the locale, or of the default language, have the message
+func hasMessage(locale, message string) bool {
+	language, region := parseLocale(locale)
+	messages := loadedMessages()
+	messageConfig, knownLanguage := messages[language]
+	if !knownLanguage && Config != nil {
+		messageConfig, knownLanguage = messages[Config.StringDefault(defaultLanguageOption, "")]
+	}
+	if !knownLanguage {
+		return false
+	}
+	_, err := messageConfig.String(region, message)
+	return err == nil
+}
+
 func parseLocale(locale string) (language, region string) {
 	if strings.Contains(locale, "-") {
 		languageAndRegion := strings.Split(locale, "-")
@@ -119,27 +138,54 @@ func getUnknownValueFormat() string {
 	return Config.StringDefault(unknownFormatConfigKey, defaultUnknownFormat)
 }
 
+// Returns the currently loaded message configs
+func loadedMessages() map[string]*config.Config {
+	messagesMutex.RLock()
+	defer messagesMutex.RUnlock()
+	return messages
+}
+
+// Replaces the loaded message configs
+func setMessages(loaded map[string]*config.Config) {
+	messagesMutex.Lock()
+	messages = loaded
+	messagesMutex.Unlock()
+}
+
 // Recursively read and cache all available messages from all message files on the given path.
 func loadMessages(path string) {
-	messages = make(map[string]*config.Config)
+	loaded, err := readMessages(path)
+	if err != nil {
+		i18nLog.Error("Error reading messages files:", "error", err)
+	}
+	setMessages(loaded)
+}
+
+// Reads all the message files on the path, returns the messages read and the first error found.
+func readMessages(path string) (loaded map[string]*config.Config, err error) {
+	loaded = make(map[string]*config.Config)
+	walker := func(path string, info os.FileInfo, osError error) error {
+		return loadMessageFile(loaded, path, info, osError)
+	}
 
 	// Read in messages from the modules. Load the module messges first,
 	// so that it can be override in parent application
 	for _, module := range Modules {
 		i18nLog.Debug("Importing messages from module:", "importpath", module.ImportPath)
-		if err := Walk(filepath.Join(module.Path, messageFilesDirectory), loadMessageFile); err != nil &&
-			!os.IsNotExist(err) {
-			i18nLog.Error("Error reading messages files from module:", "error", err)
+		if walkErr := Walk(filepath.Join(module.Path, messageFilesDirectory), walker); walkErr != nil &&
+			!os.IsNotExist(walkErr) && err == nil {
+			err = fmt.Errorf("module %s: %s", module.Name, walkErr)
 		}
 	}
 
-	if err := Walk(path, loadMessageFile); err != nil && !os.IsNotExist(err) {
-		i18nLog.Error("Error reading messages files:", "error", err)
+	if walkErr := Walk(path, walker); walkErr != nil && !os.IsNotExist(walkErr) && err == nil {
+		err = walkErr
 	}
+	return
 }
 
 // Load a single message file
-func loadMessageFile(path string, info os.FileInfo, osError error) error {
+func loadMessageFile(messages map[string]*config.Config, path string, info os.FileInfo, osError error) error {
 	if osError != nil {
 		return osError
 	}
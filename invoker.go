@@ -6,6 +6,7 @@ package revel
 
 import (
 	"io"
+	"net/http"
 	"reflect"
 )
 
@@ -15,18 +16,34 @@ var (
 )
 
 func ActionInvoker(c *Controller, _ []Filter) {
+	// Serve the result cached by the @Cache annotation
+	var storeResult func(Result) Result
+	if c.MethodType.cache != nil {
+		var cached Result
+		if cached, storeResult = c.MethodType.cache.lookup(c); cached != nil {
+			c.Result = wrapETagResult(c, cached)
+			if c.MethodType.response != nil {
+				c.Result = c.MethodType.response.wrap(c.Result)
+			}
+			return
+		}
+	}
+
 	// Instantiate the method.
 	methodValue := reflect.ValueOf(c.AppController).MethodByName(c.MethodType.Name)
 
 	// Collect the values for the method's arguments.
 	var methodArgs []reflect.Value
+	c.Params.locale = c.Request.Locale
 	for _, arg := range c.MethodType.Args {
 		// If they accept a websocket connection, treat that arg specially.
 		var boundArg reflect.Value
 		if arg.Type.Implements(websocketType) {
-			boundArg = reflect.ValueOf(c.Request.WebSocket)
+			boundArg = reflect.ValueOf(newFilteredWebSocket(c, c.Request.WebSocket))
+			// Track the websocket so it is closed properly on shutdown
+			defer trackWebSocket(c.Request.WebSocket)()
 		} else {
-			boundArg = Bind(c.Params, arg.Name, arg.Type)
+			boundArg = arg.bind(c.Params)
 			// #756 - If the argument is a closer, defer a Close call,
 			// so we don't risk on leaks.
 			if closer, ok := boundArg.Interface().(io.Closer); ok {
@@ -37,14 +54,47 @@ func ActionInvoker(c *Controller, _ []Filter) {
 		}
 		methodArgs = append(methodArgs, boundArg)
 	}
+	if c.Validation != nil {
+		for _, err := range c.Params.bindErrors {
+			c.Validation.Error("%s", err.message).Key(err.name)
+		}
+		// The validate tags of the struct arguments, in the scenarios of the action
+		if c.Validation.scenarios == nil {
+			c.Validation.scenarios = c.MethodType.scenarios
+		}
+		for i, arg := range c.MethodType.Args {
+			if !arg.Type.Implements(websocketType) {
+				c.Validation.Struct(arg.Name, methodArgs[i].Interface())
+			}
+		}
+	}
+	if len(c.Params.bindErrors) > 0 && rejectsBindErrors(c) {
+		c.Response.Status = http.StatusBadRequest
+		c.Result = c.RenderError(bindErrorsProblem(c.Params.bindErrors))
+		return
+	}
+	if c.Validation != nil && c.Validation.reject && c.Validation.HasErrors() {
+		c.Response.Status = http.StatusUnprocessableEntity
+		c.Result = c.RenderError(validationProblem(c.Validation.Errors))
+		return
+	}
 
 	var resultValue reflect.Value
+	stopTiming := c.Response.Timing.Start("action")
 	if methodValue.Type().IsVariadic() {
 		resultValue = methodValue.CallSlice(methodArgs)[0]
 	} else {
 		resultValue = methodValue.Call(methodArgs)[0]
 	}
+	stopTiming()
 	if resultValue.Kind() == reflect.Interface && !resultValue.IsNil() {
 		c.Result = resultValue.Interface().(Result)
 	}
+	if storeResult != nil && c.Result != nil {
+		c.Result = storeResult(c.Result)
+	}
+	c.Result = wrapETagResult(c, c.Result)
+	if c.MethodType.response != nil {
+		c.Result = c.MethodType.response.wrap(c.Result)
+	}
 }
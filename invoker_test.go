@@ -5,8 +5,11 @@
 package revel
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -80,6 +83,79 @@ func TestSetAction(t *testing.T) {
 	}
 }
 
+func TestResolveBinders(t *testing.T) {
+	controllers = make(map[string]*ControllerType)
+	// Remove the controller so the startup check does not fail in later tests
+	defer func() { controllers = make(map[string]*ControllerType) }()
+	RegisterController((*P)(nil), []*MethodType{{Name: "Method", Args: []*MethodArg{
+		{Name: "id", Type: reflect.TypeOf((*int)(nil))},
+		{Name: "events", Type: reflect.TypeOf((*chan int)(nil))},
+	}}})
+
+	method := ControllerTypeByName("P", anyModule).Method("Method")
+	if method.Args[0].binder == nil {
+		t.Errorf("Expected the binder for id to be resolved")
+	}
+	if missing := method.resolveBinders(); len(missing) != 1 || missing[0].Name != "events" {
+		t.Errorf("Expected events to have no binder, got %v", missing)
+	}
+
+	params := &Params{Values: url.Values{"id": {"3"}}}
+	if id := method.Args[0].bind(params).Interface(); id != 3 {
+		t.Errorf("Expected id 3 got %v", id)
+	}
+}
+
+type BindErrorController struct {
+	*Controller
+}
+
+func (c BindErrorController) Book(nights int, guests int) Result {
+	return c.RenderText("nights %d guests %d errors %d", nights, guests, len(c.Validation.Errors))
+}
+
+func TestBindErrors(t *testing.T) {
+	startFakeBookingApp()
+	RegisterController((*BindErrorController)(nil), []*MethodType{{Name: "Book", Args: []*MethodArg{
+		{Name: "nights", Type: reflect.TypeOf((*int)(nil))},
+		{Name: "guests", Type: reflect.TypeOf((*int)(nil))},
+	}}})
+	invoke := func(accept string) (*httptest.ResponseRecorder, *Controller) {
+		req, _ := http.NewRequest("POST", "/bookings", nil)
+		req.Header.Set("Accept", accept)
+		resp := httptest.NewRecorder()
+		c := NewTestController(resp, req)
+		if err := c.SetAction("BindErrorController", "Book"); err != nil {
+			t.Fatal(err)
+		}
+		c.Request.Format = ResolveFormat(c.Request)
+		c.Params = &Params{Values: url.Values{"nights": {"two"}, "guests": {"2"}}}
+		c.Validation = &Validation{Request: c.Request, Translator: MessageFunc}
+		ActionInvoker(c, nil)
+		c.Result.Apply(c.Request, c.Response)
+		return resp, c
+	}
+
+	resp, c := invoke("application/json")
+	if body := resp.Body.String(); body != "nights 0 guests 2 errors 1" {
+		t.Errorf("Expected the action to run with the bind error, got %s", body)
+	}
+	if err := c.Validation.ErrorMap()["nights"]; err == nil || err.Message != "Must be an integer" {
+		t.Errorf("Expected a validation error for nights, got %v", c.Validation.ErrorMap())
+	}
+
+	Config.SetOption("params.bind.reject", "api")
+	defer Config.SetOption("params.bind.reject", "false")
+	resp, _ = invoke("application/json")
+	if resp.Code != http.StatusBadRequest || !strings.Contains(resp.Body.String(), `"errors":[{"field":"nights","message":"Must be an integer"}]`) {
+		t.Errorf("Expected the bind errors to be rejected, got %d %s", resp.Code, resp.Body.String())
+	}
+	resp, _ = invoke("text/html")
+	if resp.Code != http.StatusOK {
+		t.Errorf("Expected the HTML request to run the action, got %d", resp.Code)
+	}
+}
+
 func BenchmarkSetAction(b *testing.B) {
 	type Mixin1 struct {
 		*Controller
@@ -0,0 +1,87 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// The JSON results are encoded with the options of app.conf
+//   results.pretty = true             # Indent the JSON, usually in the [dev] section
+//   results.json.indent = "  "        # The indentation of the pretty JSON
+//   results.json.escapehtml = true    # Escape <, > and & so the JSON is safe in HTML
+//   results.json.sortkeys = true      # Sort the keys of the maps
+// The options of a single result are changed from the defaults
+//   options := revel.DefaultJSONOptions()
+//   options.EscapeHTML = false
+//   return c.RenderJSONWith(feed, options)
+// The marshaler of the results is replaced to use another encoder, or to redact fields
+//   revel.ResultJSONMarshaler = jsoniterMarshaler{}
+
+// JSONOptions are the options of a JSON result
+type JSONOptions struct {
+	Pretty     bool          // Indent the JSON
+	Indent     string        // The indentation of the pretty JSON
+	EscapeHTML bool          // Escape <, > and & as \u003c, \u003e and \u0026
+	SortKeys   bool          // Sort the keys of the maps, encoding/json always sorts them
+	Marshaler  JSONMarshaler // Replaces the ResultJSONMarshaler for the result when set
+}
+
+// JSONMarshaler encodes the object of a JSON result
+type JSONMarshaler interface {
+	Marshal(v interface{}, options *JSONOptions) ([]byte, error)
+}
+
+// ResultJSONMarshaler encodes the JSON results, with encoding/json by default
+var ResultJSONMarshaler JSONMarshaler = standardJSONMarshaler{}
+
+// The options of app.conf, loaded when the application starts
+var defaultJSONOptions = JSONOptions{Indent: "  ", EscapeHTML: true, SortKeys: true}
+
+func init() {
+	OnAppStart(func() {
+		defaultJSONOptions = JSONOptions{
+			Pretty:     Config.BoolDefault("results.pretty", false),
+			Indent:     Config.StringDefault("results.json.indent", "  "),
+			EscapeHTML: Config.BoolDefault("results.json.escapehtml", true),
+			SortKeys:   Config.BoolDefault("results.json.sortkeys", true),
+		}
+	})
+}
+
+// DefaultJSONOptions returns the options of app.conf
+func DefaultJSONOptions() *JSONOptions {
+	options := defaultJSONOptions
+	return &options
+}
+
+// Encodes the object with the options, or the options of app.conf when nil
+func marshalJSONResult(v interface{}, options *JSONOptions) ([]byte, error) {
+	if options == nil {
+		options = DefaultJSONOptions()
+	}
+	if options.Marshaler != nil {
+		return options.Marshaler.Marshal(v, options)
+	}
+	return ResultJSONMarshaler.Marshal(v, options)
+}
+
+// The marshaler of encoding/json
+type standardJSONMarshaler struct{}
+
+func (standardJSONMarshaler) Marshal(v interface{}, options *JSONOptions) ([]byte, error) {
+	b := &bytes.Buffer{}
+	encoder := json.NewEncoder(b)
+	encoder.SetEscapeHTML(options.EscapeHTML)
+	if options.Pretty {
+		encoder.SetIndent("", options.Indent)
+	}
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	// Like json.Marshal, without the new line the encoder ends with
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n")), nil
+}
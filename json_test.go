@@ -0,0 +1,45 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type redactingMarshaler struct{}
+
+func (redactingMarshaler) Marshal(v interface{}, options *JSONOptions) ([]byte, error) {
+	if m, ok := v.(map[string]string); ok {
+		m["password"] = "***"
+	}
+	return standardJSONMarshaler{}.Marshal(v, options)
+}
+
+func TestRenderJSONWith(t *testing.T) {
+	render := func(result func(c *Controller) Result) string {
+		resp := httptest.NewRecorder()
+		c := NewTestController(resp, showRequest)
+		result(c).Apply(c.Request, c.Response)
+		return resp.Body.String()
+	}
+	o := map[string]string{"html": "<b>", "password": "secret"}
+
+	if body := render(func(c *Controller) Result { return c.RenderJSON(o) }); body != `{"html":"\u003cb\u003e","password":"secret"}` {
+		t.Errorf("Unexpected default JSON %s", body)
+	}
+	options := DefaultJSONOptions()
+	options.EscapeHTML, options.Pretty, options.Indent = false, true, "\t"
+	if body := render(func(c *Controller) Result { return c.RenderJSONWith(o, options) }); body != "{\n\t\"html\": \"<b>\",\n\t\"password\": \"secret\"\n}" {
+		t.Errorf("Unexpected JSON with options %q", body)
+	}
+
+	ResultJSONMarshaler = redactingMarshaler{}
+	defer func() { ResultJSONMarshaler = standardJSONMarshaler{} }()
+	if body := render(func(c *Controller) Result { return c.RenderJSON(o) }); !strings.Contains(body, `"password":"***"`) {
+		t.Errorf("Expected the marshaler to redact the password, got %s", body)
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sizes and durations in the configuration and in annotations are written as
+//   http.maxrequestsize = 10MB
+//   http.timeout.read = 1m30s
+// A size is a number followed by B, KB, MB, GB or TB (KiB, MiB.. are accepted too and all
+// of them are powers of 1024), a duration is anything time.ParseDuration accepts plus
+// days (d) and weeks (w). A number without a unit is in the unit the option used before
+// it accepted literals, so existing configurations keep working.
+
+// LiteralError is returned for an invalid size or duration, it names the offending option
+type LiteralError struct {
+	Key    string // The configuration option or annotation value, may be empty
+	Value  string
+	Reason string
+}
+
+func (e *LiteralError) Error() string {
+	if e.Key == "" {
+		return fmt.Sprintf("invalid value %q: %s", e.Value, e.Reason)
+	}
+	return fmt.Sprintf("%s: invalid value %q: %s", e.Key, e.Value, e.Reason)
+}
+
+var sizeUnits = map[string]int64{
+	"b":  1,
+	"k":  1 << 10,
+	"kb": 1 << 10,
+	"m":  1 << 20,
+	"mb": 1 << 20,
+	"g":  1 << 30,
+	"gb": 1 << 30,
+	"t":  1 << 40,
+	"tb": 1 << 40,
+}
+
+var (
+	sizeLiteral     = regexp.MustCompile(`^([0-9]*\.?[0-9]+)\s*([a-zA-Z]*)$`)
+	durationElement = regexp.MustCompile(`^([0-9]*\.?[0-9]+)(ns|us|µs|ms|s|m|h|d|w)`)
+	durationUnits   = map[string]time.Duration{
+		"ns": time.Nanosecond,
+		"us": time.Microsecond,
+		"µs": time.Microsecond,
+		"ms": time.Millisecond,
+		"s":  time.Second,
+		"m":  time.Minute,
+		"h":  time.Hour,
+		"d":  24 * time.Hour,
+		"w":  7 * 24 * time.Hour,
+	}
+)
+
+// ParseSize parses a size such as 512KB or 1.5GB into bytes, a number without a unit
+// is multiplied by the unit
+func ParseSize(value string, unit int64) (int64, error) {
+	match := sizeLiteral.FindStringSubmatch(strings.TrimSpace(value))
+	if match == nil {
+		return 0, &LiteralError{Value: value, Reason: "expected a size such as 512KB or 10MB"}
+	}
+	number, _ := strconv.ParseFloat(match[1], 64)
+	if match[2] != "" {
+		suffix := strings.TrimSuffix(strings.ToLower(match[2]), "ib")
+		if len(suffix) < len(match[2]) {
+			suffix += "b"
+		}
+		var found bool
+		if unit, found = sizeUnits[suffix]; !found {
+			return 0, &LiteralError{Value: value, Reason: "unknown size unit " + match[2]}
+		}
+	}
+	size := number * float64(unit)
+	if size > math.MaxInt64 {
+		return 0, &LiteralError{Value: value, Reason: "the size is too large"}
+	}
+	return int64(size), nil
+}
+
+// ParseDuration parses a duration such as 1h30m, 500ms or 7d, a number without a unit
+// is multiplied by the unit
+func ParseDuration(value string, unit time.Duration) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if number, err := strconv.ParseFloat(value, 64); err == nil {
+		return time.Duration(number * float64(unit)), nil
+	}
+	if value == "" {
+		return 0, &LiteralError{Value: value, Reason: "expected a duration such as 30s or 1h30m"}
+	}
+	var duration time.Duration
+	for rest := value; rest != ""; {
+		match := durationElement.FindStringSubmatch(rest)
+		if match == nil {
+			return 0, &LiteralError{Value: value, Reason: "expected a duration such as 30s or 1h30m"}
+		}
+		number, _ := strconv.ParseFloat(match[1], 64)
+		duration += time.Duration(number * float64(durationUnits[match[2]]))
+		rest = rest[len(match[0]):]
+	}
+	return duration, nil
+}
+
+// ConfigSizeDefault returns the size of the configuration option in bytes, a number without
+// a unit is multiplied by the unit. An invalid size is logged and the default is returned.
+func ConfigSizeDefault(key string, defaultSize int64, unit int64) int64 {
+	value, found := Config.String(key)
+	if !found {
+		return defaultSize
+	}
+	size, err := ParseSize(value, unit)
+	if err != nil {
+		err.(*LiteralError).Key = key
+		RevelLog.Error("Invalid size in the configuration, using the default", "option", key, "error", err, "default", defaultSize)
+		return defaultSize
+	}
+	return size
+}
+
+// ConfigDurationDefault returns the duration of the configuration option, a number without
+// a unit is multiplied by the unit. An invalid duration is logged and the default is returned.
+func ConfigDurationDefault(key string, defaultDuration time.Duration, unit time.Duration) time.Duration {
+	value, found := Config.String(key)
+	if !found {
+		return defaultDuration
+	}
+	duration, err := ParseDuration(value, unit)
+	if err != nil {
+		err.(*LiteralError).Key = key
+		RevelLog.Error("Invalid duration in the configuration, using the default", "option", key, "error", err, "default", defaultDuration)
+		return defaultDuration
+	}
+	return duration
+}
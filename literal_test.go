@@ -0,0 +1,82 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]int64{
+		"512":   512 << 20,
+		"10MB":  10 << 20,
+		"10mb":  10 << 20,
+		"10MiB": 10 << 20,
+		"1.5GB": 3 << 29,
+		"64 KB": 64 << 10,
+		"100B":  100,
+		"2T":    2 << 40,
+	}
+	for value, expected := range tests {
+		if size, err := ParseSize(value, 1<<20); err != nil || size != expected {
+			t.Errorf("ParseSize(%q) expected %d got %d %v", value, expected, size, err)
+		}
+	}
+	for _, value := range []string{"", "MB", "10XB", "-1MB", "1.2.3KB"} {
+		if _, err := ParseSize(value, 1); err == nil {
+			t.Errorf("ParseSize(%q) expected an error", value)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := map[string]time.Duration{
+		"30":     30 * time.Second,
+		"1h30m":  90 * time.Minute,
+		"500ms":  500 * time.Millisecond,
+		"1.5h":   90 * time.Minute,
+		"2d":     48 * time.Hour,
+		"1w1d":   8 * 24 * time.Hour,
+		" 10s  ": 10 * time.Second,
+	}
+	for value, expected := range tests {
+		if duration, err := ParseDuration(value, time.Second); err != nil || duration != expected {
+			t.Errorf("ParseDuration(%q) expected %s got %s %v", value, expected, duration, err)
+		}
+	}
+	for _, value := range []string{"", "h", "10x", "1h 30m", "-5s"} {
+		if _, err := ParseDuration(value, time.Second); err == nil {
+			t.Errorf("ParseDuration(%q) expected an error", value)
+		}
+	}
+}
+
+func TestConfigLiterals(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOption("test.literal.size", "2MB")
+	Config.SetOption("test.literal.duration", "1m30s")
+	Config.SetOption("test.literal.invalid", "10 parsecs")
+
+	if size := ConfigSizeDefault("test.literal.size", 1, 1); size != 2<<20 {
+		t.Errorf("Expected 2MB got %d", size)
+	}
+	if duration := ConfigDurationDefault("test.literal.duration", 0, time.Second); duration != 90*time.Second {
+		t.Errorf("Expected 1m30s got %s", duration)
+	}
+	if size := ConfigSizeDefault("test.literal.invalid", 42, 1); size != 42 {
+		t.Errorf("Expected the default for an invalid size, got %d", size)
+	}
+	if duration := ConfigDurationDefault("test.literal.missing", time.Hour, time.Second); duration != time.Hour {
+		t.Errorf("Expected the default for a missing option, got %s", duration)
+	}
+
+	_, err := ParseDuration("10 parsecs", time.Second)
+	err.(*LiteralError).Key = "test.literal.invalid"
+	if !strings.HasPrefix(err.Error(), "test.literal.invalid: ") {
+		t.Errorf("Expected the error to name the option, got %s", err)
+	}
+}
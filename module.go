@@ -80,6 +80,11 @@ func (m *Module) ControllerByName(name, action string) (ctype *ControllerType) {
 
 // Adds the controller type to this module
 func (m *Module) AddController(ct *ControllerType) {
+	for _, existing := range m.ControllerTypeList {
+		if existing == ct {
+			return
+		}
+	}
 	m.ControllerTypeList = append(m.ControllerTypeList, ct)
 }
 
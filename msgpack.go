@@ -0,0 +1,70 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"reflect"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// MessagePack is supported like JSON, an object is rendered with RenderMsgPack (or with
+// RenderAuto for the "msgpack" format) and a request body with the application/msgpack
+// content type is bound to the struct and map arguments of the action. The json tags of
+// the struct fields are used, so an object has the same keys in JSON and MessagePack.
+
+// MsgPackContentType is the content type of a MessagePack request or response body
+const MsgPackContentType = "application/msgpack"
+
+// RenderMsgPackResult writes the MessagePack encoding of the object
+type RenderMsgPackResult struct {
+	obj interface{}
+}
+
+func (r RenderMsgPackResult) Apply(req *Request, resp *Response) {
+	b, err := marshalMsgPack(r.obj)
+	if err != nil {
+		ErrorResult{Error: err}.Apply(req, resp)
+		return
+	}
+
+	resp.WriteHeader(http.StatusOK, MsgPackContentType)
+	if _, err = resp.GetWriter().Write(b); err != nil {
+		resultsLog.Error("Apply: Response write failed", "error", err)
+	}
+}
+
+// BindMsgPack binds the MessagePack data to the dest.
+func (p *Params) BindMsgPack(dest interface{}) error {
+	if reflect.ValueOf(dest).Kind() != reflect.Ptr {
+		paramsLogger.Warn("BindMsgPack: Not a pointer")
+		return errors.New("BindMsgPack not a pointer")
+	}
+	if err := unmarshalMsgPack(p.MsgPack, dest); err != nil {
+		paramsLogger.Warn("BindMsgPack: Unable to unmarshal request:", "error", err)
+		return err
+	}
+	return nil
+}
+
+func marshalMsgPack(obj interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := msgpack.NewEncoder(&buffer)
+	encoder.SetCustomStructTag("json")
+	encoder.UseCompactInts(true)
+	if err := encoder.Encode(obj); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func unmarshalMsgPack(data []byte, dest interface{}) error {
+	decoder := msgpack.NewDecoder(bytes.NewReader(data))
+	decoder.SetCustomStructTag("json")
+	return decoder.Decode(dest)
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+type msgPackBooking struct {
+	ID     int    `json:"id"`
+	Hotel  string `json:"hotel"`
+	Nights int    `json:"nights,omitempty"`
+}
+
+func TestRenderMsgPack(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/bookings/3", nil)
+	req.Header.Set("Accept", "application/x-msgpack")
+	resp := httptest.NewRecorder()
+	c := NewTestController(resp, req)
+	if c.Request.Format != "msgpack" {
+		t.Errorf("Expected the msgpack format, got %s", c.Request.Format)
+	}
+	c.RenderAuto(msgPackBooking{ID: 3, Hotel: "Ritz"}).Apply(c.Request, c.Response)
+	if resp.Header().Get("Content-Type") != MsgPackContentType {
+		t.Errorf("Unexpected content type %v", resp.Header())
+	}
+	var decoded map[string]interface{}
+	if err := unmarshalMsgPack(resp.Body.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 2 || decoded["hotel"] != "Ritz" {
+		t.Errorf("Expected the keys of the json tags, got %v", decoded)
+	}
+}
+
+func TestMsgPackBinding(t *testing.T) {
+	body, _ := marshalMsgPack(msgPackBooking{ID: 3, Hotel: "Ritz", Nights: 2})
+	req, _ := http.NewRequest("POST", "/bookings", bytes.NewReader(body))
+	req.Header.Set("Content-Type", MsgPackContentType)
+	c := NewTestController(nil, req)
+	ParseParams(c.Params, c.Request)
+
+	booking := Bind(c.Params, "booking", reflect.TypeOf(msgPackBooking{})).Interface().(msgPackBooking)
+	if booking != (msgPackBooking{ID: 3, Hotel: "Ritz", Nights: 2}) {
+		t.Errorf("Unexpected bound struct %+v", booking)
+	}
+	values := Bind(c.Params, "values", reflect.TypeOf(map[string]interface{}{})).Interface().(map[string]interface{})
+	if len(values) != 3 {
+		t.Errorf("Unexpected bound map %v", values)
+	}
+	var bound msgPackBooking
+	if err := c.Params.BindMsgPack(&bound); err != nil || bound.Nights != 2 {
+		t.Errorf("Unexpected BindMsgPack result %+v %v", bound, err)
+	}
+}
@@ -0,0 +1,223 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The multipart results write their parts as they are produced. A batch API answers with
+// a multipart/mixed response, every part is written by the function
+//   return c.RenderMultipartMixed(func(parts *multipart.Writer) error {
+//   	for _, op := range batch {
+//   		part, err := parts.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/json"}})
+//   		if err != nil {
+//   			return err
+//   		}
+//   		json.NewEncoder(part).Encode(op.Run())
+//   	}
+//   	return nil
+//   })
+// RenderByteRanges serves the ranges of the Range header of the request, several ranges
+// are sent as a multipart/byteranges response, RenderFile and RenderBinary serve the
+// ranges of a seekable content with it (unless results.ranges = false). At most results.ranges.max ranges (default 20)
+// are served, the whole content is sent for more.
+
+// MultipartResult writes the parts of a multipart response, the boundary is generated
+type MultipartResult struct {
+	subtype string
+	write   func(parts *multipart.Writer) error
+}
+
+func (r *MultipartResult) Apply(req *Request, resp *Response) {
+	out := resp.GetWriter()
+	parts := multipart.NewWriter(out)
+	// The content type must carry the boundary
+	resp.ContentType = "multipart/" + r.subtype + "; boundary=" + parts.Boundary()
+	resp.WriteHeader(http.StatusOK, resp.ContentType)
+	if err := r.write(parts); err != nil {
+		// The status is sent, the response is left without its closing boundary
+		resultsLog.Error("Apply: Failed to write the parts", "error", err)
+		return
+	}
+	if err := parts.Close(); err != nil {
+		resultsLog.Error("Apply: Response write failed", "error", err)
+	}
+}
+
+// A range of the content
+type byteRange struct {
+	start, length int64
+}
+
+func (r byteRange) contentRange(size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", r.start, r.start+r.length-1, size)
+}
+
+var errUnsatisfiableRange = errors.New("unsatisfiable range")
+
+// Parses the Range header for the size, returns nil without a header or for a header
+// which is not in bytes
+func parseByteRanges(header string, size int64) ([]byteRange, error) {
+	if !strings.HasPrefix(header, "bytes=") {
+		return nil, nil
+	}
+	var ranges []byteRange
+	for _, spec := range strings.Split(header[len("bytes="):], ",") {
+		if spec = strings.TrimSpace(spec); spec == "" {
+			continue
+		}
+		dash := strings.Index(spec, "-")
+		if dash < 0 {
+			return nil, fmt.Errorf("invalid range %q", spec)
+		}
+		first, last := strings.TrimSpace(spec[:dash]), strings.TrimSpace(spec[dash+1:])
+		var r byteRange
+		if first == "" {
+			// The suffix range -n is the last n bytes
+			n, err := strconv.ParseInt(last, 10, 64)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid range %q", spec)
+			}
+			if n > size {
+				n = size
+			}
+			r = byteRange{start: size - n, length: n}
+		} else {
+			start, err := strconv.ParseInt(first, 10, 64)
+			if err != nil || start < 0 {
+				return nil, fmt.Errorf("invalid range %q", spec)
+			}
+			if start >= size {
+				// Skipped, the request fails only when no range is satisfiable
+				continue
+			}
+			end := size - 1
+			if last != "" {
+				if end, err = strconv.ParseInt(last, 10, 64); err != nil || end < start {
+					return nil, fmt.Errorf("invalid range %q", spec)
+				}
+				if end >= size {
+					end = size - 1
+				}
+			}
+			r = byteRange{start: start, length: end - start + 1}
+		}
+		if r.length > 0 {
+			ranges = append(ranges, r)
+		}
+	}
+	if len(ranges) == 0 {
+		return nil, errUnsatisfiableRange
+	}
+	return ranges, nil
+}
+
+// ByteRangesResult serves the content, or the ranges of it the request asks for
+type ByteRangesResult struct {
+	Content     io.ReadSeeker
+	ContentType string
+	ModTime     time.Time
+}
+
+func (r *ByteRangesResult) Apply(req *Request, resp *Response) {
+	if closer, ok := r.Content.(io.Closer); ok {
+		defer closer.Close()
+	}
+	size, err := r.Content.Seek(0, io.SeekEnd)
+	if err != nil {
+		ErrorResult{Error: err}.Apply(req, resp)
+		return
+	}
+	header := resp.Out.internalHeader
+	header.Set("Accept-Ranges", "bytes")
+	if !r.ModTime.IsZero() {
+		etag := fileETag(size, r.ModTime)
+		header.Set("ETag", etag)
+		header.Set("Last-Modified", r.ModTime.UTC().Format(http.TimeFormat))
+		if notModified(req, etag, r.ModTime) {
+			writeNotModified(resp)
+			return
+		}
+	}
+
+	rangeHeader := req.GetHttpHeader("Range")
+	if ifRange := req.GetHttpHeader("If-Range"); ifRange != "" {
+		// The ranges are served only for the version of the content the client has
+		if t, err := http.ParseTime(ifRange); err != nil || r.ModTime.IsZero() || r.ModTime.Unix() > t.Unix() {
+			rangeHeader = ""
+		}
+	}
+	ranges, err := parseByteRanges(rangeHeader, size)
+	if err == errUnsatisfiableRange {
+		header.Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+		resp.Status = http.StatusRequestedRangeNotSatisfiable
+		resp.WriteHeader(http.StatusRequestedRangeNotSatisfiable, "text/plain; charset=utf-8")
+		return
+	}
+
+	// Too many ranges, or ranges larger than the content, are answered with the content
+	var total int64
+	for _, byteRange := range ranges {
+		total += byteRange.length
+	}
+	if err != nil || len(ranges) == 0 || len(ranges) > Config.IntDefault("results.ranges.max", 20) || total > size {
+		ranges = []byteRange{{start: 0, length: size}}
+		header.Set("Content-Length", strconv.FormatInt(size, 10))
+		resp.WriteHeader(http.StatusOK, r.ContentType)
+		if err = r.copyRange(resp.GetWriter(), ranges[0]); err != nil {
+			resultsLog.Error("Apply: Response write failed", "error", err)
+		}
+		return
+	}
+
+	if len(ranges) == 1 {
+		header.Set("Content-Range", ranges[0].contentRange(size))
+		header.Set("Content-Length", strconv.FormatInt(ranges[0].length, 10))
+		resp.Status = http.StatusPartialContent
+		resp.WriteHeader(http.StatusPartialContent, r.ContentType)
+		if err = r.copyRange(resp.GetWriter(), ranges[0]); err != nil {
+			resultsLog.Error("Apply: Response write failed", "error", err)
+		}
+		return
+	}
+
+	parts := multipart.NewWriter(resp.GetWriter())
+	resp.Status, resp.ContentType = http.StatusPartialContent, "multipart/byteranges; boundary="+parts.Boundary()
+	resp.WriteHeader(http.StatusPartialContent, resp.ContentType)
+	for _, byteRange := range ranges {
+		part, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":  {r.ContentType},
+			"Content-Range": {byteRange.contentRange(size)},
+		})
+		if err == nil {
+			err = r.copyRange(part, byteRange)
+		}
+		if err != nil {
+			resultsLog.Error("Apply: Response write failed", "error", err)
+			return
+		}
+	}
+	if err = parts.Close(); err != nil {
+		resultsLog.Error("Apply: Response write failed", "error", err)
+	}
+}
+
+// Copies the range of the content to the writer
+func (r *ByteRangesResult) copyRange(w io.Writer, byteRange byteRange) error {
+	if _, err := r.Content.Seek(byteRange.start, io.SeekStart); err != nil {
+		return err
+	}
+	_, err := io.CopyN(w, r.Content, byteRange.length)
+	return err
+}
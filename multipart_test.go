@@ -0,0 +1,128 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Returns the parts of the multipart response with their Content-Range header and body
+func readParts(t *testing.T, resp *httptest.ResponseRecorder, subtype string) (ranges, bodies []string) {
+	mediaType, params, err := mime.ParseMediaType(resp.Header().Get("Content-Type"))
+	if err != nil || mediaType != "multipart/"+subtype {
+		t.Fatalf("Unexpected content type %s %v", resp.Header().Get("Content-Type"), err)
+	}
+	reader := multipart.NewReader(resp.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			return
+		}
+		body, _ := ioutil.ReadAll(part)
+		ranges, bodies = append(ranges, part.Header.Get("Content-Range")), append(bodies, string(body))
+	}
+}
+
+func TestRenderMultipartMixed(t *testing.T) {
+	resp := httptest.NewRecorder()
+	c := NewTestController(resp, showRequest)
+	c.RenderMultipartMixed(func(parts *multipart.Writer) error {
+		for _, body := range []string{`{"id":1}`, `{"id":2}`} {
+			part, err := parts.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/json"}})
+			if err != nil {
+				return err
+			}
+			part.Write([]byte(body))
+		}
+		return nil
+	}).Apply(c.Request, c.Response)
+	if _, bodies := readParts(t, resp, "mixed"); len(bodies) != 2 || bodies[1] != `{"id":2}` {
+		t.Errorf("Unexpected parts %v", bodies)
+	}
+}
+
+func TestRenderByteRanges(t *testing.T) {
+	content := "0123456789abcdefghij"
+	request := func(rangeHeader string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/files/content.txt", nil)
+		req.Header.Set("Range", rangeHeader)
+		resp := httptest.NewRecorder()
+		c := NewTestController(resp, req)
+		c.RenderByteRanges(strings.NewReader(content), "content.txt", time.Now()).Apply(c.Request, c.Response)
+		return resp
+	}
+
+	resp := request("")
+	if resp.Code != http.StatusOK || resp.Body.String() != content || resp.Header().Get("Accept-Ranges") != "bytes" {
+		t.Errorf("Expected the whole content, got %d %q", resp.Code, resp.Body.String())
+	}
+	resp = request("bytes=2-4")
+	if resp.Code != http.StatusPartialContent || resp.Body.String() != "234" || resp.Header().Get("Content-Range") != "bytes 2-4/20" {
+		t.Errorf("Expected a single range, got %d %q %v", resp.Code, resp.Body.String(), resp.Header())
+	}
+	resp = request("bytes=0-1, 10-12, -3")
+	if resp.Code != http.StatusPartialContent {
+		t.Errorf("Expected a partial content, got %d", resp.Code)
+	}
+	ranges, bodies := readParts(t, resp, "byteranges")
+	if strings.Join(bodies, "|") != "01|abc|hij" || ranges[2] != "bytes 17-19/20" {
+		t.Errorf("Unexpected parts %v %v", ranges, bodies)
+	}
+	resp = request("bytes=30-40")
+	if resp.Code != http.StatusRequestedRangeNotSatisfiable || resp.Header().Get("Content-Range") != "bytes */20" {
+		t.Errorf("Expected an unsatisfiable range, got %d %v", resp.Code, resp.Header())
+	}
+}
+
+func TestRenderBinaryRanges(t *testing.T) {
+	content := "0123456789abcdefghij"
+	request := func(rangeHeader string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/files/content.txt", nil)
+		req.Header.Set("Range", rangeHeader)
+		resp := httptest.NewRecorder()
+		c := NewTestController(resp, req)
+		c.RenderBinary(strings.NewReader(content), "content.txt", Attachment, time.Now()).Apply(c.Request, c.Response)
+		return resp
+	}
+
+	resp := request("bytes=-5")
+	if resp.Code != http.StatusPartialContent || resp.Body.String() != "fghij" || resp.Header().Get("Accept-Ranges") != "bytes" {
+		t.Errorf("Expected the last bytes, got %d %q %v", resp.Code, resp.Body.String(), resp.Header())
+	}
+	if disposition := resp.Header().Get("Content-Disposition"); disposition != `attachment; filename="content.txt"` {
+		t.Errorf("Expected the disposition of the binary, got %s", disposition)
+	}
+	resp = request("bytes=0-1,5-6")
+	if _, bodies := readParts(t, resp, "byteranges"); strings.Join(bodies, "|") != "01|56" {
+		t.Errorf("Unexpected parts %v", bodies)
+	}
+
+	// The range of a compressed response is of the content
+	Config.SetOption("results.compressed", "true")
+	defer Config.SetOption("results.compressed", "false")
+	req, _ := http.NewRequest("GET", "/files/content.txt", nil)
+	req.Header.Set("Range", "bytes=2-4")
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp = httptest.NewRecorder()
+	c := NewTestController(resp, req)
+	CompressFilter(c, []Filter{func(c *Controller, _ []Filter) {
+		c.Result = c.RenderBinary(strings.NewReader(content), "content.txt", Inline, time.Now())
+	}})
+	c.Result.Apply(c.Request, c.Response)
+	c.Response.GetWriter().(io.Closer).Close()
+	if resp.Code != http.StatusPartialContent || resp.Body.String() != "234" || resp.Header().Get("Content-Encoding") != "" ||
+		resp.Header().Get("Content-Range") != "bytes 2-4/20" {
+		t.Errorf("Expected the uncompressed range, got %d %q %v", resp.Code, resp.Body.String(), resp.Header())
+	}
+}
@@ -0,0 +1,510 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"encoding/json"
+	"io"
+	"math/big"
+	"mime/multipart"
+	"net/http"
+	"net/netip"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The OpenAPI 3 document of the application is generated from the routes, the action
+// arguments and their annotations, and served when it is enabled in app.conf
+//   openapi.enabled = true
+//   openapi.path = /@openapi.json
+//   openapi.title = Booking API         # Defaults to app.name
+//   openapi.version = 1.0.0
+//   openapi.ui = true                   # Serve the Swagger UI at /@openapi in dev mode
+// The operations are described by annotations on the actions
+//   // @Doc(summary="Show a hotel", description="...", tags="hotels")
+//   // @Response(200, "The hotel")
+//   // @Response(404, "The hotel does not exist")
+// @Produces, @Consumes and @Deprecated are included as well. Routes with a wildcard
+// controller or action are not documented. The struct and map arguments of the POST, PUT and
+// PATCH actions are documented as the request body, the properties of the structs are named
+// by their json tag and constrained by their validate tag (required, min, max, len, match,
+// email, url and uuid).
+func init() {
+	RegisterAnnotationSchema("Doc", "summary", "description", "tags")
+	RegisterAnnotationSchema("Response", "status", "description")
+	OnAppStart(func() {
+		openAPIConfig.enabled = Config.BoolDefault("openapi.enabled", false)
+		openAPIConfig.path = Config.StringDefault("openapi.path", "/@openapi.json")
+		openAPIConfig.ui = Config.BoolDefault("openapi.ui", true)
+		if openAPIConfig.enabled {
+			if _, err := OpenAPIDocument(); err != nil {
+				RevelLog.Error("Failed to generate the OpenAPI document", "error", err)
+			}
+		}
+	}, 10)
+	AddInitEventHandler(func(typeOf int, value interface{}) (responseOf int) {
+		if typeOf == ROUTE_REFRESH_COMPLETED {
+			resetOpenAPIDocument()
+		}
+		return
+	})
+	OnControllersReloaded(resetOpenAPIDocument)
+}
+
+// OpenAPI is the root of an OpenAPI 3 document
+type OpenAPI struct {
+	OpenAPI string                                  `json:"openapi"`
+	Info    OpenAPIInfo                             `json:"info"`
+	Paths   map[string]map[string]*OpenAPIOperation `json:"paths"`
+}
+
+type OpenAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type OpenAPIOperation struct {
+	OperationID string                      `json:"operationId"`
+	Summary     string                      `json:"summary,omitempty"`
+	Description string                      `json:"description,omitempty"`
+	Tags        []string                    `json:"tags,omitempty"`
+	Deprecated  bool                        `json:"deprecated,omitempty"`
+	Parameters  []*OpenAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*OpenAPIResponse `json:"responses"`
+}
+
+type OpenAPIParameter struct {
+	Name     string         `json:"name"`
+	In       string         `json:"in"` // path, query, header or cookie
+	Required bool           `json:"required,omitempty"`
+	Schema   *OpenAPISchema `json:"schema"`
+}
+
+type OpenAPIRequestBody struct {
+	Content map[string]*OpenAPIMediaType `json:"content"`
+}
+
+type OpenAPIResponse struct {
+	Description string                       `json:"description"`
+	Content     map[string]*OpenAPIMediaType `json:"content,omitempty"`
+}
+
+type OpenAPIMediaType struct {
+	Schema *OpenAPISchema `json:"schema,omitempty"`
+}
+
+type OpenAPISchema struct {
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Items                *OpenAPISchema            `json:"items,omitempty"`
+	Properties           map[string]*OpenAPISchema `json:"properties,omitempty"`
+	AdditionalProperties *OpenAPISchema            `json:"additionalProperties,omitempty"`
+	Required             []string                  `json:"required,omitempty"`
+	Minimum              *float64                  `json:"minimum,omitempty"`
+	Maximum              *float64                  `json:"maximum,omitempty"`
+	MinLength            *int                      `json:"minLength,omitempty"`
+	MaxLength            *int                      `json:"maxLength,omitempty"`
+	MinItems             *int                      `json:"minItems,omitempty"`
+	MaxItems             *int                      `json:"maxItems,omitempty"`
+	Pattern              string                    `json:"pattern,omitempty"`
+}
+
+var (
+	openAPIDocument []byte
+	openAPIMutex    sync.Mutex
+)
+
+// The settings of the OpenAPIFilter, loaded when the application starts
+var openAPIConfig = struct {
+	enabled bool
+	path    string
+	ui      bool
+}{path: "/@openapi.json", ui: true}
+
+// The methods of the requests whose struct and map arguments are bound from the body
+var openAPIBodyMethods = map[string]bool{"POST": true, "PUT": true, "PATCH": true}
+
+// OpenAPIDocument returns the JSON OpenAPI document, it is generated on the first call
+// and again after the routes or the controllers are reloaded
+func OpenAPIDocument() ([]byte, error) {
+	openAPIMutex.Lock()
+	defer openAPIMutex.Unlock()
+	if openAPIDocument == nil {
+		document, err := json.MarshalIndent(NewOpenAPI(), "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		openAPIDocument = document
+	}
+	return openAPIDocument, nil
+}
+
+func resetOpenAPIDocument() {
+	openAPIMutex.Lock()
+	openAPIDocument = nil
+	openAPIMutex.Unlock()
+}
+
+// NewOpenAPI generates the OpenAPI document from the routes of the MainRouter
+func NewOpenAPI() *OpenAPI {
+	api := &OpenAPI{
+		OpenAPI: "3.0.3",
+		Info: OpenAPIInfo{
+			Title:   Config.StringDefault("openapi.title", AppName),
+			Version: Config.StringDefault("openapi.version", "1.0.0"),
+		},
+		Paths: map[string]map[string]*OpenAPIOperation{},
+	}
+	if MainRouter == nil {
+		return api
+	}
+	for _, route := range MainRouter.Routes {
+		if route.TypeOfController == nil || route.MethodName == "" || route.MethodName[0] == ':' ||
+			route.Method == "*" || route.Method == "WS" {
+			continue
+		}
+		mt := route.TypeOfController.Method(route.MethodName)
+		if mt == nil {
+			continue
+		}
+		path, pathParams := openAPIPath(route.Path)
+		if api.Paths[path] == nil {
+			api.Paths[path] = map[string]*OpenAPIOperation{}
+		}
+		method := strings.ToLower(route.Method)
+		if _, found := api.Paths[path][method]; !found {
+			api.Paths[path][method] = newOpenAPIOperation(route, mt, pathParams)
+		}
+	}
+	return api
+}
+
+// Converts the route path to an OpenAPI path, /hotels/:id becomes /hotels/{id}
+func openAPIPath(routePath string) (path string, params map[string]bool) {
+	params = map[string]bool{}
+	segments := strings.Split(routePath, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			params[segment[1:]] = true
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+func newOpenAPIOperation(route *Route, mt *MethodType, pathParams map[string]bool) *OpenAPIOperation {
+	operation := &OpenAPIOperation{
+		OperationID: route.TypeOfController.Type.Name() + "." + mt.Name,
+		Tags:        []string{route.TypeOfController.Type.Name()},
+		Deprecated:  mt.deprecated != nil,
+		Responses:   map[string]*OpenAPIResponse{},
+	}
+	if doc := mt.Annotations.Find("Doc"); doc != nil {
+		operation.Summary, _ = doc.Value("summary", 0)
+		operation.Description, _ = doc.Value("description", 1)
+		if tags := doc.GetStrings("tags", 2); len(tags) > 0 {
+			operation.Tags = tags
+		}
+	}
+
+	fixed := map[string]bool{}
+	for i := range route.FixedParams {
+		if i < len(mt.Args) {
+			fixed[mt.Args[i].Name] = true
+		}
+	}
+	// The path parameters are documented even if the action has no argument for them
+	for name := range pathParams {
+		operation.Parameters = append(operation.Parameters, &OpenAPIParameter{Name: name, In: "path", Required: true, Schema: &OpenAPISchema{Type: "string"}})
+	}
+	sort.Slice(operation.Parameters, func(i, j int) bool { return operation.Parameters[i].Name < operation.Parameters[j].Name })
+	for _, arg := range mt.Args {
+		schema := openAPISchema(arg.Type, map[reflect.Type]bool{})
+		if schema == nil || fixed[arg.Name] {
+			continue
+		}
+		if pathParams[arg.Name] {
+			for _, param := range operation.Parameters {
+				if param.Name == arg.Name {
+					param.Schema = schema
+				}
+			}
+			continue
+		}
+		if arg.source == "" && schema.Type == "object" && openAPIBodyMethods[route.Method] {
+			// The first struct or map argument documents the body, the body is decoded in all of them
+			if operation.RequestBody == nil {
+				operation.RequestBody = openAPIRequestBody(mt.consumes, schema)
+			}
+			operation.Parameters = append(operation.Parameters, openAPIFieldParameters(arg.Type)...)
+			continue
+		}
+		in, name := "query", arg.Name
+		if kind, param := sourceName(arg.source, arg.Name); kind == "header" || kind == "cookie" {
+			in, name = kind, param
+		}
+		operation.Parameters = append(operation.Parameters, &OpenAPIParameter{Name: name, In: in, Schema: schema})
+		operation.Parameters = append(operation.Parameters, openAPIFieldParameters(arg.Type)...)
+	}
+
+	if len(mt.consumes) > 0 && operation.RequestBody == nil {
+		operation.RequestBody = openAPIRequestBody(mt.consumes, nil)
+	}
+	for _, annotation := range mt.Annotations {
+		if !strings.EqualFold(annotation.Name, "Response") {
+			continue
+		}
+		status, _ := annotation.Value("status", 0)
+		description, _ := annotation.Value("description", 1)
+		operation.Responses[status] = &OpenAPIResponse{Description: description}
+	}
+	if len(operation.Responses) == 0 {
+		operation.Responses["200"] = &OpenAPIResponse{Description: "OK"}
+	}
+	for status, response := range operation.Responses {
+		if len(mt.produces) > 0 && strings.HasPrefix(status, "2") {
+			response.Content = map[string]*OpenAPIMediaType{}
+			for _, contentType := range mt.produces {
+				response.Content[contentType] = &OpenAPIMediaType{}
+			}
+		}
+	}
+	return operation
+}
+
+// Returns the request body of the content types, of the schema of the body, JSON by default
+func openAPIRequestBody(contentTypes []string, schema *OpenAPISchema) *OpenAPIRequestBody {
+	if len(contentTypes) == 0 {
+		contentTypes = []string{"application/json"}
+	}
+	body := &OpenAPIRequestBody{Content: map[string]*OpenAPIMediaType{}}
+	for _, contentType := range contentTypes {
+		body.Content[contentType] = &OpenAPIMediaType{Schema: schema}
+	}
+	return body
+}
+
+// Returns the header and cookie parameters of the fields of the struct type, bound from
+// their header, cookie or binding tag
+func openAPIFieldParameters(typ reflect.Type) (parameters []*OpenAPIParameter) {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return nil
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		source, found := fieldSource(field)
+		if field.PkgPath != "" || !found {
+			continue
+		}
+		if kind, param := sourceName(source, field.Name); kind == "header" || kind == "cookie" {
+			if schema := openAPISchema(field.Type, map[reflect.Type]bool{}); schema != nil {
+				parameters = append(parameters, &OpenAPIParameter{Name: param, In: kind, Schema: schema})
+			}
+		}
+	}
+	return
+}
+
+var (
+	openAPITimeType     = reflect.TypeOf(time.Time{})
+	openAPIDurationType = reflect.TypeOf(time.Duration(0))
+	openAPIAddrType     = reflect.TypeOf(netip.Addr{})
+	openAPIBigIntType   = reflect.TypeOf(big.Int{})
+	openAPIBigFloatType = reflect.TypeOf(big.Float{})
+	openAPIFileType     = reflect.TypeOf(&os.File{})
+	openAPIReaderType   = reflect.TypeOf((*io.Reader)(nil)).Elem()
+	openAPIHeaderType   = reflect.TypeOf(&multipart.FileHeader{})
+)
+
+// Returns the schema of the type, nil for the types which are not parameters (like websockets)
+func openAPISchema(typ reflect.Type, seen map[reflect.Type]bool) *OpenAPISchema {
+	switch {
+	case typ == openAPITimeType:
+		return &OpenAPISchema{Type: "string", Format: "date-time"}
+	case typ == openAPIDurationType:
+		return &OpenAPISchema{Type: "string", Format: "duration"}
+	case typ == openAPIAddrType:
+		return &OpenAPISchema{Type: "string", Format: "ip"}
+	case typ == openAPIBigIntType:
+		return &OpenAPISchema{Type: "integer"}
+	case typ == openAPIBigFloatType:
+		return &OpenAPISchema{Type: "number"}
+	case isUUIDType(typ):
+		return &OpenAPISchema{Type: "string", Format: "uuid"}
+	case isOptionalType(typ):
+		return openAPISchema(reflect.Zero(typ).Interface().(optional).optionalType(), seen)
+	case typ == openAPIFileType || typ == openAPIHeaderType || typ == reflect.TypeOf([]byte{}) || typ.Implements(openAPIReaderType):
+		return &OpenAPISchema{Type: "string", Format: "binary"}
+	case typ.Implements(websocketType):
+		return nil
+	}
+
+	switch typ.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &OpenAPISchema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &OpenAPISchema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &OpenAPISchema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &OpenAPISchema{Type: "number", Format: "double"}
+	case reflect.Bool:
+		return &OpenAPISchema{Type: "boolean"}
+	case reflect.String:
+		return &OpenAPISchema{Type: "string"}
+	case reflect.Ptr:
+		return openAPISchema(typ.Elem(), seen)
+	case reflect.Slice, reflect.Array:
+		return &OpenAPISchema{Type: "array", Items: openAPISchema(typ.Elem(), seen)}
+	case reflect.Map:
+		return &OpenAPISchema{Type: "object", AdditionalProperties: openAPISchema(typ.Elem(), seen)}
+	case reflect.Struct:
+		schema := &OpenAPISchema{Type: "object"}
+		if seen[typ] {
+			// A recursive type is not expanded again
+			return schema
+		}
+		seen[typ] = true
+		defer delete(seen, typ)
+		validators := map[int][]Validator{}
+		for _, field := range structValidation(typ) {
+			validators[field.index] = field.validators
+		}
+		schema.Properties = map[string]*OpenAPISchema{}
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			name := openAPIPropertyName(field)
+			if field.PkgPath != "" || name == "" {
+				continue
+			}
+			if fieldSchema := openAPISchema(field.Type, seen); fieldSchema != nil {
+				if openAPIConstraints(fieldSchema, validators[i]) {
+					schema.Required = append(schema.Required, name)
+				}
+				schema.Properties[name] = fieldSchema
+			}
+		}
+		return schema
+	}
+	return nil
+}
+
+// Returns the name of the property of the field, the name of its json tag if it has one,
+// empty if the field is not encoded
+func openAPIPropertyName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	}
+	return name
+}
+
+// Adds the constraints of the validators of the validate tag of a field to its schema,
+// returns true if the field is required. The rules of the scenarios are not constraints.
+func openAPIConstraints(schema *OpenAPISchema, validators []Validator) (required bool) {
+	for _, validator := range validators {
+		switch v := validator.(type) {
+		case Required:
+			required = true
+		case Min:
+			schema.Minimum = &v.Min
+		case Max:
+			schema.Maximum = &v.Max
+		case MinSize:
+			if schema.Type == "array" {
+				schema.MinItems = &v.Min
+			} else {
+				schema.MinLength = &v.Min
+			}
+		case MaxSize:
+			if schema.Type == "array" {
+				schema.MaxItems = &v.Max
+			} else {
+				schema.MaxLength = &v.Max
+			}
+		case Length:
+			if schema.Type == "array" {
+				schema.MinItems, schema.MaxItems = &v.N, &v.N
+			} else {
+				schema.MinLength, schema.MaxLength = &v.N, &v.N
+			}
+		case Match:
+			schema.Pattern = v.Regexp.String()
+		case Email:
+			schema.Format = "email"
+		case URL, URLScheme:
+			schema.Format = "uri"
+		case UUID:
+			schema.Format = "uuid"
+		}
+	}
+	return
+}
+
+// OpenAPIFilter serves the OpenAPI document and the Swagger UI, it runs before the
+// RouterFilter since they have no route
+func OpenAPIFilter(c *Controller, fc []Filter) {
+	if c.Request.Method != "GET" || !openAPIConfig.enabled {
+		fc[0](c, fc[1:])
+		return
+	}
+
+	switch c.Request.GetPath() {
+	case openAPIConfig.path:
+		document, err := OpenAPIDocument()
+		if err != nil {
+			c.Result = c.RenderError(err)
+			return
+		}
+		c.Result = openAPIJSONResult(document)
+	case "/@openapi":
+		if !DevMode || !openAPIConfig.ui {
+			fc[0](c, fc[1:])
+			return
+		}
+		c.Result = c.RenderHTML(strings.Replace(openAPIUI, "{{path}}", strconv.Quote(openAPIConfig.path), 1))
+	default:
+		fc[0](c, fc[1:])
+	}
+}
+
+// Writes the JSON document
+type openAPIJSONResult []byte
+
+func (r openAPIJSONResult) Apply(req *Request, resp *Response) {
+	resp.WriteHeader(http.StatusOK, "application/json; charset=utf-8")
+	if _, err := resp.GetWriter().Write(r); err != nil {
+		resultsLog.Error("Apply: Response write failed", "error", err)
+	}
+}
+
+// The Swagger UI page, the assets are loaded from a CDN
+const openAPIUI = `<!DOCTYPE html>
+<html lang="en">
+	<head>
+		<title>API</title>
+		<link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+	</head>
+	<body>
+		<div id="swagger-ui"></div>
+		<script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+		<script>
+			SwaggerUIBundle({url: {{path}}, dom_id: "#swagger-ui"});
+		</script>
+	</body>
+</html>
+`
@@ -0,0 +1,156 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type OpenAPIController struct {
+	*Controller
+}
+
+func (c OpenAPIController) Show(id int, fields []string) Result {
+	return nil
+}
+
+func (c OpenAPIController) Search(q string, since time.Time) Result {
+	return nil
+}
+
+type openAPIClient struct {
+	Trace string `header:"X-Request-Id"`
+	Theme string `binding:"cookie"`
+}
+
+func (c OpenAPIController) Profile(token string, client openAPIClient) Result {
+	return nil
+}
+
+type openAPIBooking struct {
+	Name    string   `json:"name" validate:"required,max=50"`
+	Email   string   `json:"email" validate:"required,email"`
+	Nights  int      `json:"nights" validate:"min=1,max=30"`
+	Code    string   `validate:"match=^[A-Z]{3}$"`
+	Guests  []string `json:"guests,omitempty" validate:"max=4"`
+	Comment string   `json:"-"`
+}
+
+func (c OpenAPIController) Book(id int, booking openAPIBooking) Result {
+	return nil
+}
+
+func TestOpenAPI(t *testing.T) {
+	startFakeBookingApp()
+	doc, _ := ParseAnnotation(`@Doc(summary="Show a room", tags="rooms, hotels")`)
+	notFound, _ := ParseAnnotation(`@Response(404, "The room does not exist")`)
+	found, _ := ParseAnnotation(`@Response(200, "The room")`)
+	produces, _ := ParseAnnotation(`@Produces("application/json")`)
+	bindFrom, _ := ParseAnnotation(`@BindFrom(token="header:X-Auth-Token")`)
+	RegisterController((*OpenAPIController)(nil), []*MethodType{
+		{Name: "Show", Annotations: FunctionalAnnotations{doc, found, notFound, produces}, Args: []*MethodArg{
+			{Name: "id", Type: reflect.TypeOf((*int)(nil))},
+			{Name: "fields", Type: reflect.TypeOf((*[]string)(nil))},
+		}},
+		{Name: "Search", Args: []*MethodArg{
+			{Name: "q", Type: reflect.TypeOf((*string)(nil))},
+			{Name: "since", Type: reflect.TypeOf((*time.Time)(nil))},
+		}},
+		{Name: "Profile", Annotations: FunctionalAnnotations{bindFrom}, Args: []*MethodArg{
+			{Name: "token", Type: reflect.TypeOf((*string)(nil))},
+			{Name: "client", Type: reflect.TypeOf((*openAPIClient)(nil))},
+		}},
+		{Name: "Book", Args: []*MethodArg{
+			{Name: "id", Type: reflect.TypeOf((*int)(nil))},
+			{Name: "booking", Type: reflect.TypeOf((*openAPIBooking)(nil))},
+		}},
+	})
+
+	router := MainRouter
+	MainRouter = NewRouter("")
+	MainRouter.Routes, _ = parseRoutes(appModule, "", "", `
+GET  /rooms/:id         OpenAPIController.Show
+GET  /rooms             OpenAPIController.Search
+GET  /rooms/search      OpenAPIController.Search("hotel")
+GET  /profile           OpenAPIController.Profile
+POST /rooms/:id         OpenAPIController.Book
+*    /:controller/:action :controller.:action
+`, false)
+	if err := MainRouter.updateTree(); err != nil {
+		t.Fatal(err)
+	}
+	openAPIConfig.enabled = true
+	defer func() {
+		MainRouter = router
+		openAPIConfig.enabled = false
+		resetOpenAPIDocument()
+	}()
+
+	api := NewOpenAPI()
+	if len(api.Paths) != 4 {
+		t.Fatalf("Expected the wildcard route to be skipped, got %v", api.Paths)
+	}
+	show := api.Paths["/rooms/{id}"]["get"]
+	if show == nil || show.Summary != "Show a room" || !reflect.DeepEqual(show.Tags, []string{"rooms", "hotels"}) {
+		t.Fatalf("Unexpected operation %+v", show)
+	}
+	if len(show.Parameters) != 2 || show.Parameters[0].In != "path" || !show.Parameters[0].Required ||
+		show.Parameters[0].Schema.Type != "integer" || show.Parameters[1].Schema.Type != "array" {
+		t.Errorf("Unexpected parameters %+v %+v", show.Parameters[0], show.Parameters[1])
+	}
+	if len(show.Responses) != 2 || show.Responses["404"].Description != "The room does not exist" ||
+		show.Responses["200"].Content["application/json"] == nil {
+		t.Errorf("Unexpected responses %v", show.Responses)
+	}
+	search := api.Paths["/rooms"]["get"]
+	if len(search.Parameters) != 2 || search.Parameters[1].Schema.Format != "date-time" || search.Responses["200"] == nil {
+		t.Errorf("Unexpected search operation %+v", search)
+	}
+	if fixed := api.Paths["/rooms/search"]["get"]; len(fixed.Parameters) != 1 || fixed.Parameters[0].Name != "since" {
+		t.Errorf("Expected the fixed parameter to be skipped, got %+v", fixed.Parameters)
+	}
+	profile := api.Paths["/profile"]["get"]
+	if len(profile.Parameters) != 4 || *profile.Parameters[0] != (OpenAPIParameter{Name: "X-Auth-Token", In: "header", Schema: profile.Parameters[0].Schema}) ||
+		profile.Parameters[2].Name != "X-Request-Id" || profile.Parameters[2].In != "header" ||
+		profile.Parameters[3].Name != "Theme" || profile.Parameters[3].In != "cookie" {
+		t.Errorf("Expected the header and cookie parameters, got %+v", profile.Parameters)
+	}
+
+	// The struct argument of a POST is the body, constrained by the validate tags
+	book := api.Paths["/rooms/{id}"]["post"]
+	if book == nil || len(book.Parameters) != 1 || book.RequestBody == nil || book.RequestBody.Content["application/json"] == nil {
+		t.Fatalf("Expected the booking in the request body, got %+v", book)
+	}
+	body := book.RequestBody.Content["application/json"].Schema
+	if body == nil || len(body.Properties) != 5 || body.Properties["Comment"] != nil || body.Properties["comment"] != nil ||
+		!reflect.DeepEqual(body.Required, []string{"name", "email"}) {
+		t.Fatalf("Expected the properties of the json names, got %+v", body)
+	}
+	if name := body.Properties["name"]; name.MaxLength == nil || *name.MaxLength != 50 || name.MinLength != nil {
+		t.Errorf("Expected the max length of the name, got %+v", name)
+	}
+	if nights := body.Properties["nights"]; nights.Minimum == nil || *nights.Minimum != 1 || nights.Maximum == nil || *nights.Maximum != 30 {
+		t.Errorf("Expected the range of the nights, got %+v", nights)
+	}
+	if body.Properties["email"].Format != "email" || body.Properties["Code"].Pattern != "^[A-Z]{3}$" ||
+		body.Properties["guests"].MaxItems == nil || *body.Properties["guests"].MaxItems != 4 {
+		t.Errorf("Expected the constraints of the fields, got %+v", body.Properties)
+	}
+
+	resp := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/@openapi.json", nil)
+	c := NewTestController(resp, req)
+	OpenAPIFilter(c, []Filter{func(c *Controller, fc []Filter) { t.Error("Expected the document to be served") }})
+	c.Result.Apply(c.Request, c.Response)
+	var served OpenAPI
+	if err := json.Unmarshal(resp.Body.Bytes(), &served); err != nil || served.OpenAPI != "3.0.3" || len(served.Paths) != 4 {
+		t.Errorf("Unexpected document %s %v", resp.Body.String(), err)
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The @PageCache(ttl=1h, key="pricing-:plan", tags="marketing", vary="Cookie") annotation
+// caches the rendered page of a GET action in the ActionCache, the cached page is served by
+// the PageCacheFilter after the BEFORE interceptors (which check the user) and before the
+// action runs. Only the pages rendered by a template with a 200 are cached, a page must not
+// show the data of a user.
+// The key is expanded using the parameters (:plan is replaced by the plan parameter), it is
+// the path and the query string of the request by default. A variant of the page is stored
+// for each locale and each value of the vary headers, under its own key in the ActionCache.
+// The pages are expired by their key, or by one of their tags
+//   revel.ExpirePage("/pricing")
+//   revel.ExpirePageTag("marketing")
+// The pages are tagged by the cache when it supports tags (memory or redis), otherwise the
+// keys of the pages of a tag are listed in the cache by this process.
+func init() {
+	RegisterAnnotationProcessor("PageCache", pageCacheAnnotationProcessor)
+	RegisterAnnotationSchema("PageCache", "ttl", "key", "tags", "vary")
+}
+
+// The @PageCache settings of an action
+type pageCacheSettings struct {
+	ttl  time.Duration
+	key  string
+	tags []string
+	vary []string
+}
+
+func pageCacheAnnotationProcessor(ct *ControllerType, mt *MethodType, annotation *FunctionalAnnotation) (err error) {
+	settings := &pageCacheSettings{}
+	if settings.ttl, err = annotation.GetDuration("ttl", 0, 0); err != nil {
+		return err
+	}
+	settings.key, _ = annotation.Value("key", 1)
+	settings.tags = annotation.GetStrings("tags", 2)
+	settings.vary = annotation.GetStrings("vary", 3)
+
+	methods := ct.Methods
+	if mt != nil {
+		methods = []*MethodType{mt}
+	}
+	for _, method := range methods {
+		method.pageCache = settings
+	}
+	return nil
+}
+
+// Returns the cache key of the page
+func pageCacheKey(key string) string {
+	return "revel:page:" + key
+}
+
+// Returns the cache key of the list of the pages of the tag
+func pageCacheTagKey(tag string) string {
+	return "revel:pagetag:" + tag
+}
+
+// ExpirePage removes the cached page of the key, all the variants of the page are removed
+func ExpirePage(key string) error {
+	if ActionCache == nil {
+		return nil
+	}
+	return ActionCache.Delete(pageCacheKey(key))
+}
+
+// ExpirePageTag removes the cached pages of the tag
+func ExpirePageTag(tag string) error {
+	if ActionCache == nil {
+		return nil
+	}
+	if store, ok := ActionCache.(TaggedActionCacheStore); ok {
+		return store.InvalidateTags(pageCacheTagKey(tag))
+	}
+	pageCacheTagLock.Lock()
+	defer pageCacheTagLock.Unlock()
+	var keys []string
+	if err := ActionCache.Get(pageCacheTagKey(tag), &keys); err != nil {
+		// No page of the tag is cached
+		return nil
+	}
+	for _, key := range keys {
+		if err := ExpirePage(key); err != nil {
+			return err
+		}
+	}
+	return ActionCache.Delete(pageCacheTagKey(tag))
+}
+
+// Returns the key of the page for the request
+func (settings *pageCacheSettings) pageKey(c *Controller) string {
+	if settings.key != "" {
+		return actionCacheKeyParam.ReplaceAllStringFunc(settings.key, func(name string) string {
+			return c.Params.Get(name[1:])
+		})
+	}
+	key := c.Request.GetPath()
+	if query := c.Params.Query.Encode(); query != "" {
+		key += "?" + query
+	}
+	return key
+}
+
+// Guards the lists of the pages of the tags, a list is read and written again to add a page
+var pageCacheTagLock sync.Mutex
+
+// Stores the variant of the page of the key (see setActionCacheVariant), tagged with the
+// tags of the page
+func (settings *pageCacheSettings) store(key, generation, variant string, response actionCacheResponse) error {
+	if _, ok := ActionCache.(TaggedActionCacheStore); !ok || len(settings.tags) == 0 {
+		if err := setActionCacheVariant(pageCacheKey(key), generation, variant, response, settings.ttl, nil); err != nil {
+			return err
+		}
+		settings.tagPage(key)
+		return nil
+	}
+	tags := make([]string, len(settings.tags))
+	for i, tag := range settings.tags {
+		tags[i] = pageCacheTagKey(tag)
+	}
+	return setActionCacheVariant(pageCacheKey(key), generation, variant, response, settings.ttl, tags)
+}
+
+// Adds the key to the list of the pages of the tags, for a cache without tags
+func (settings *pageCacheSettings) tagPage(key string) {
+	pageCacheTagLock.Lock()
+	defer pageCacheTagLock.Unlock()
+	for _, tag := range settings.tags {
+		var keys []string
+		ActionCache.Get(pageCacheTagKey(tag), &keys)
+		index := sort.SearchStrings(keys, key)
+		if index < len(keys) && keys[index] == key {
+			continue
+		}
+		keys = append(keys[:index], append([]string{key}, keys[index:]...)...)
+		// The tag is kept as long as a page of the tag may be cached
+		if err := ActionCache.Set(pageCacheTagKey(tag), keys, settings.ttl); err != nil {
+			resultsLog.Warn("Failed to tag the cached page", "tag", tag, "key", key, "error", err)
+		}
+	}
+}
+
+// PageCacheFilter serves the page cached for the request by the @PageCache annotation,
+// or caches the page the action renders
+func PageCacheFilter(c *Controller, fc []Filter) {
+	settings := c.MethodType.pageCache
+	if settings == nil || ActionCache == nil || c.Request.Method != "GET" {
+		fc[0](c, fc[1:])
+		return
+	}
+
+	key := settings.pageKey(c)
+	variant := []string{c.Request.Locale}
+	for _, header := range settings.vary {
+		variant = append(variant, c.Request.GetHttpHeader(header))
+	}
+	variantKey := strings.Join(variant, "\n")
+
+	response, generation, found := getActionCacheVariant(pageCacheKey(key), variantKey)
+	if found {
+		c.Result = &actionCacheResult{response: response}
+		return
+	}
+
+	fc[0](c, fc[1:])
+	if !isTemplateResult(c.Result) {
+		return
+	}
+	c.Result = &actionCacheResult{result: c.Result, store: func(response actionCacheResponse) {
+		if err := settings.store(key, generation, variantKey, response); err != nil {
+			resultsLog.Warn("Failed to cache the page", "action", c.Action, "key", key, "error", err)
+		}
+	}}
+}
+
+// Returns true if the result renders a template
+func isTemplateResult(result Result) bool {
+	switch r := result.(type) {
+	case *RenderTemplateResult:
+		return true
+	case *etagResult:
+		return isTemplateResult(r.result)
+	}
+	return false
+}
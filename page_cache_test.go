@@ -0,0 +1,141 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type PageCachedController struct {
+	*Controller
+}
+
+func (c PageCachedController) Pricing() Result {
+	return nil
+}
+
+func TestPageCacheFilter(t *testing.T) {
+	testPageCacheFilter(t, testActionCache{})
+	testPageCacheFilter(t, testTaggedActionCache{testActionCache{}, map[string][]string{}})
+}
+
+func testPageCacheFilter(t *testing.T, store ActionCacheStore) {
+	startFakeBookingApp()
+	pageCache, _ := ParseAnnotation(`@PageCache(ttl=1h, key="pricing-:plan", tags="marketing")`)
+	RegisterController((*PageCachedController)(nil), []*MethodType{
+		{Name: "Pricing", Annotations: FunctionalAnnotations{pageCache}},
+	})
+	ActionCache = store
+	defer func() { ActionCache = nil }()
+
+	renders := 0
+	serve := func(locale string) string {
+		req, _ := http.NewRequest("GET", "/pricing?plan=pro", nil)
+		resp := httptest.NewRecorder()
+		c := NewTestController(resp, req)
+		if err := c.SetAction("PageCachedController", "Pricing"); err != nil {
+			t.Fatal(err)
+		}
+		c.Params = &Params{Values: url.Values{"plan": {"pro"}}}
+		c.Request.Locale = locale
+		PageCacheFilter(c, []Filter{func(c *Controller, _ []Filter) {
+			renders++
+			c.Result = c.RenderTemplateFragment("hotels/list.html", "rows", map[string]interface{}{"hotels": []string{locale}})
+		}})
+		c.Result.Apply(c.Request, c.Response)
+		return resp.Body.String()
+	}
+
+	first := serve("en")
+	if serve("en") != first || renders != 1 {
+		t.Errorf("Expected the cached page, rendered %d times", renders)
+	}
+	if serve("fr") == first || renders != 2 {
+		t.Errorf("Expected a variant for each locale, rendered %d times", renders)
+	}
+	// The generation of the page and a key for each variant
+	entries := testActionCache{}
+	switch s := store.(type) {
+	case testActionCache:
+		entries = s
+	case testTaggedActionCache:
+		entries = s.testActionCache
+	}
+	stored := 0
+	for key := range entries {
+		if strings.HasPrefix(key, pageCacheKey("pricing-pro")) {
+			stored++
+		}
+	}
+	if stored != 3 {
+		t.Errorf("Expected the variants to be stored under their own keys, got %v", entries)
+	}
+	if err := ExpirePageTag("marketing"); err != nil {
+		t.Fatal(err)
+	}
+	if serve("en"); renders != 3 {
+		t.Errorf("Expected the page of the tag to be expired, rendered %d times", renders)
+	}
+	if err := ExpirePage("pricing-pro"); err != nil {
+		t.Fatal(err)
+	}
+	if serve("en"); renders != 4 {
+		t.Errorf("Expected the page to be expired, rendered %d times", renders)
+	}
+}
+
+func TestPageCacheFilterInterceptors(t *testing.T) {
+	startFakeBookingApp()
+	pageCache, _ := ParseAnnotation(`@PageCache(ttl=1h, key="members")`)
+	RegisterController((*PageCachedController)(nil), []*MethodType{
+		{Name: "Pricing", Annotations: FunctionalAnnotations{pageCache}},
+	})
+	ActionCache = testActionCache{}
+	defer func(saved []*Interception) {
+		ActionCache, interceptors = nil, saved
+	}(interceptors)
+	InterceptFunc(func(c *Controller) Result {
+		if c.Request.GetHttpHeader("X-Member") == "" {
+			return c.Forbidden("Members only")
+		}
+		return nil
+	}, BEFORE, &PageCachedController{})
+
+	// The chain of the interceptors and the page cache, in the order of the Filters
+	var chain []Filter
+	for _, filter := range Filters {
+		if pointer := reflect.ValueOf(filter).Pointer(); pointer == reflect.ValueOf(InterceptorFilter).Pointer() ||
+			pointer == reflect.ValueOf(PageCacheFilter).Pointer() {
+			chain = append(chain, filter)
+		}
+	}
+	chain = append(chain, func(c *Controller, _ []Filter) {
+		c.Result = c.RenderTemplateFragment("hotels/list.html", "rows", map[string]interface{}{"hotels": []string{"members"}})
+	})
+
+	serve := func(member string) int {
+		req, _ := http.NewRequest("GET", "/members", nil)
+		req.Header.Set("X-Member", member)
+		resp := httptest.NewRecorder()
+		c := NewTestController(resp, req)
+		if err := c.SetAction("PageCachedController", "Pricing"); err != nil {
+			t.Fatal(err)
+		}
+		chain[0](c, chain[1:])
+		c.Result.Apply(c.Request, c.Response)
+		return resp.Code
+	}
+	if code := serve("jane"); code != http.StatusOK {
+		t.Errorf("Expected the page of the member, got %d", code)
+	}
+	if code := serve(""); code != http.StatusForbidden {
+		t.Errorf("Expected the cached page to be checked by the interceptors, got %d", code)
+	}
+}
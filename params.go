@@ -5,13 +5,17 @@
 package revel
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
-	"io/ioutil"
+	"fmt"
+	"io"
 	"mime/multipart"
+	"net/http"
 	"net/url"
 	"os"
 	"reflect"
+	"strings"
 )
 
 // Params provides a unified view of the request params.
@@ -35,6 +39,24 @@ type Params struct {
 	Files    map[string][]*multipart.FileHeader // Files uploaded in a multipart form
 	tmpFiles []*os.File                         // Temp files used during the request.
 	JSON     []byte                             // JSON data from request body
+	XML      []byte                             // XML data from request body
+	Proto    []byte                             // Protobuf data from request body
+	MsgPack  []byte                             // MessagePack data from request body
+
+	streamJSON  bool          // Set for actions annotated with @JSONStream, the body is not read by ParseParams
+	jsonBody    io.Reader     // The JSON request body when streamJSON is set
+	jsonDecoder *json.Decoder // The decoder used by BindJSONStream
+	bindErrors  []*bindError  // The parameters which failed to bind, added to the validation errors
+	strict      bool          // The unknown parameters are bind errors, set by BindStrict
+
+	fieldSanitizers []string // The sanitizers of the sanitize tag of the field bound, instead of params.sanitize
+
+	upload       *uploadSettings  // Set for actions annotated with @Upload
+	uploadStream *UploadStream    // The parts of a streamed upload, not read by ParseParams
+	uploadLimit  *partLimitReader // Checks the sizes of the parts of the fields limited by @MaxUpload
+	uploadErr    error            // The upload is too large, the request is rejected by the ParamsFilter
+	locale       string           // The locale of the request, set by the ActionInvoker for the time binders
+	header       *RevelHeader     // The headers of the request, the source of the header bindings
 }
 
 var paramsLogger = RevelLog.New("section", "params")
@@ -42,9 +64,10 @@ var paramsLogger = RevelLog.New("section", "params")
 // ParseParams parses the `http.Request` params into `revel.Controller.Params`
 func ParseParams(params *Params, req *Request) {
 	params.Query = req.GetQuery()
+	params.header = req.Header
 
 	// Parse the body depending on the content type.
-	switch req.ContentType {
+	switch bodyContentType(req.ContentType) {
 	case "application/x-www-form-urlencoded":
 		// Typical form.
 		var err error
@@ -54,8 +77,21 @@ func ParseParams(params *Params, req *Request) {
 
 	case "multipart/form-data":
 		// Multipart form.
-		if mp, err := req.GetMultipartForm(); err != nil {
+		if params.upload != nil {
+			if params.uploadStream, params.uploadErr = params.upload.prepare(params, req); params.uploadStream != nil || params.uploadErr != nil {
+				// Read by the action, or rejected before reading the body
+				break
+			}
+		}
+		mp, err := req.GetMultipartForm()
+		if err == nil && params.uploadLimit != nil {
+			err = params.uploadLimit.wait()
+		}
+		if err != nil {
 			paramsLogger.Warn("ParseParams: parsing request body:", "error", err)
+			if errors.Is(err, ErrUploadTooLarge) {
+				params.uploadErr = err
+			}
 		} else {
 			params.Form = mp.GetValues()
 			params.Files = mp.GetFiles()
@@ -63,21 +99,47 @@ func ParseParams(params *Params, req *Request) {
 	case "application/json":
 		fallthrough
 	case "text/json":
-		if body := req.GetBody(); body != nil {
-			if content, err := ioutil.ReadAll(body); err == nil {
-				// We wont bind it until we determine what we are binding too
-				params.JSON = content
-			} else {
-				paramsLogger.Error("ParseParams: Failed to ready request body bytes", "error", err)
-			}
+		if body := req.GetBody(); body != nil && params.streamJSON {
+			// Decoded by BindJSONStream
+			params.jsonBody = body
+		} else if body != nil {
+			// We wont bind it until we determine what we are binding too
+			params.JSON = readParamsBody(params, body)
 		} else {
 			paramsLogger.Info("ParseParams: Json post received with empty body")
 		}
+	case "application/xml", "text/xml":
+		if body := req.GetBody(); body != nil {
+			// Bound like the JSON data
+			params.XML = readParamsBody(params, body)
+		}
+	case MsgPackContentType, "application/x-msgpack":
+		if body := req.GetBody(); body != nil {
+			// Bound like the JSON data
+			params.MsgPack = readParamsBody(params, body)
+		}
+	case ProtoContentType:
+		if body := req.GetBody(); body != nil {
+			// Decoded by the ProtoBinder
+			params.Proto = readParamsBody(params, body)
+		}
 	}
 
 	params.Values = params.calcValues()
 }
 
+// Returns the content type the body is parsed as, the structured syntax suffixes
+// (application/problem+json, application/atom+xml) are parsed as JSON or XML
+func bodyContentType(contentType string) string {
+	switch {
+	case strings.HasSuffix(contentType, "+json"):
+		return "application/json"
+	case strings.HasSuffix(contentType, "+xml"):
+		return "application/xml"
+	}
+	return contentType
+}
+
 // Bind looks for the named parameter, converts it to the requested type, and
 // writes it into "dest", which must be settable.  If the value can not be
 // parsed, "dest" is set to the zero value.
@@ -94,11 +156,11 @@ func (p *Params) Bind(dest interface{}, name string) {
 	// Remove the json from the Params, this will stop the binder from attempting
 	// to use the json data to populate the destination interface. We do not want
 	// to do this on a named bind directly against the param, it is ok to happen when
-	// the action is invoked.
-	jsonData := p.JSON
-	p.JSON = nil
+	// the action is invoked. The same goes for the XML and the MessagePack data.
+	jsonData, xmlData, msgPackData := p.JSON, p.XML, p.MsgPack
+	p.JSON, p.XML, p.MsgPack = nil, nil, nil
 	value.Set(Bind(p, name, value.Type()))
-	p.JSON = jsonData
+	p.JSON, p.XML, p.MsgPack = jsonData, xmlData, msgPackData
 }
 
 // Bind binds the JSON data to the dest.
@@ -115,6 +177,47 @@ func (p *Params) BindJSON(dest interface{}) error {
 	return nil
 }
 
+// BindJSONStream decodes the next element of the JSON array in the request body into dest,
+// io.EOF is returned after the last element. Actions annotated with @JSONStream decode the
+// elements while the body is read, so large arrays can be processed with bounded memory
+//   for {
+//       var item Item
+//       if err := c.Params.BindJSONStream(&item); err == io.EOF {
+//           break
+//       } else if err != nil {
+//           return c.RenderError(err)
+//       }
+//       ...
+//   }
+func (p *Params) BindJSONStream(dest interface{}) error {
+	if reflect.ValueOf(dest).Kind() != reflect.Ptr {
+		paramsLogger.Warn("BindJSONStream: Not a pointer")
+		return errors.New("BindJSONStream not a pointer")
+	}
+	if p.jsonDecoder == nil {
+		body := p.jsonBody
+		if body == nil {
+			body = bytes.NewReader(p.JSON)
+		}
+		p.jsonDecoder = json.NewDecoder(body)
+		if token, err := p.jsonDecoder.Token(); err == io.EOF {
+			return io.EOF
+		} else if err != nil {
+			return err
+		} else if token != json.Delim('[') {
+			return fmt.Errorf("BindJSONStream: expected a JSON array, found %v", token)
+		}
+	}
+	if !p.jsonDecoder.More() {
+		// Consume the closing bracket, later calls return io.EOF as well
+		if _, err := p.jsonDecoder.Token(); err != nil && err != io.EOF {
+			return err
+		}
+		return io.EOF
+	}
+	return p.jsonDecoder.Decode(dest)
+}
+
 // calcValues returns a unified view of the component param maps.
 func (p *Params) calcValues() url.Values {
 	numParams := len(p.Query) + len(p.Fixed) + len(p.Route) + len(p.Form)
@@ -164,10 +267,17 @@ func (p *Params) calcValues() url.Values {
 }
 
 func ParamsFilter(c *Controller, fc []Filter) {
+	c.Params.streamJSON = c.MethodType != nil && c.MethodType.jsonStream
+	if c.MethodType != nil {
+		c.Params.upload = c.MethodType.upload
+	}
 	ParseParams(c.Params, c.Request)
 
 	// Clean up from the request.
 	defer func() {
+		if c.Params.uploadLimit != nil {
+			c.Params.uploadLimit.Close()
+		}
 		for _, tmpFile := range c.Params.tmpFiles {
 			err := os.Remove(tmpFile.Name())
 			if err != nil {
@@ -176,5 +286,12 @@ func ParamsFilter(c *Controller, fc []Filter) {
 		}
 	}()
 
+	if c.Params.uploadErr != nil {
+		paramsLogger.Warn("ParamsFilter: Upload rejected", "error", c.Params.uploadErr)
+		c.Response.Status = http.StatusRequestEntityTooLarge
+		c.Result = c.RenderError(c.Params.uploadErr)
+		return
+	}
+
 	fc[0](c, fc[1:])
 }
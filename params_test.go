@@ -7,6 +7,7 @@ package revel
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
@@ -180,3 +181,41 @@ func buildHTTPRequestWithAcceptLanguage(acceptLanguage string) *Request {
 
 	return c.Request
 }
+
+func TestBindJSONStream(t *testing.T) {
+	type item struct {
+		ID int `json:"id"`
+	}
+	for _, stream := range []bool{true, false} {
+		req, _ := http.NewRequest("POST", "/import", bytes.NewBufferString(`[{"id":1},{"id":2},{"id":3}]`))
+		req.Header.Set("Content-Type", "application/json")
+		c := NewTestController(nil, req)
+		c.Params.streamJSON = stream
+		ParseParams(c.Params, c.Request)
+		if stream && c.Params.JSON != nil {
+			t.Errorf("Expected the streamed body not to be read")
+		}
+
+		ids := []int{}
+		for {
+			var i item
+			if err := c.Params.BindJSONStream(&i); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, i.ID)
+		}
+		if !reflect.DeepEqual(ids, []int{1, 2, 3}) {
+			t.Errorf("Unexpected elements %v", ids)
+		}
+		if err := c.Params.BindJSONStream(&item{}); err != io.EOF {
+			t.Errorf("Expected EOF after the last element, got %v", err)
+		}
+	}
+
+	params := &Params{JSON: []byte(`{"id":1}`)}
+	if err := params.BindJSONStream(&item{}); err == nil || err == io.EOF {
+		t.Errorf("Expected an error for a JSON object")
+	}
+}
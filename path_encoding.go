@@ -0,0 +1,125 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The handling of encoded characters in a request path which could be used to confuse
+// the router or the static file server
+//   reject - the request is rejected with a 400 Bad Request
+//   decode - the character is decoded
+//   raw    - the character is passed through encoded (like %2F) so it remains part of the path segment
+const (
+	PATH_ENCODING_REJECT = "reject"
+	PATH_ENCODING_DECODE = "decode"
+	PATH_ENCODING_RAW    = "raw"
+)
+
+var (
+	// The handling of an encoded slash (%2F), set by `http.path.encoded_slash`
+	PathEncodedSlash = PATH_ENCODING_REJECT
+	// The handling of an encoded null (%00), set by `http.path.encoded_null`
+	PathEncodedNull = PATH_ENCODING_REJECT
+	// The handling of a double encoded character (like %252F), set by `http.path.double_encoded`.
+	// When decoded the resulting character is handled like it was encoded once, so
+	// %252F is handled as %2F. When raw the character is decoded once (%252F becomes %2F)
+	PathDoubleEncoded = PATH_ENCODING_REJECT
+)
+
+func init() {
+	OnAppStart(func() {
+		PathEncodedSlash = pathEncodingOption("http.path.encoded_slash")
+		PathEncodedNull = pathEncodingOption("http.path.encoded_null")
+		PathDoubleEncoded = pathEncodingOption("http.path.double_encoded")
+	})
+}
+
+// Returns the configured path encoding option, an unknown option is rejected
+func pathEncodingOption(key string) string {
+	option := strings.ToLower(Config.StringDefault(key, PATH_ENCODING_REJECT))
+	switch option {
+	case PATH_ENCODING_REJECT, PATH_ENCODING_DECODE, PATH_ENCODING_RAW:
+		return option
+	}
+	routerLog.Fatal("Invalid path encoding option", "option", key, "value", option)
+	return PATH_ENCODING_REJECT
+}
+
+// DecodeRequestPath decodes the escaped request path (as sent by the client) applying the
+// PathEncodedSlash, PathEncodedNull and PathDoubleEncoded options. An error is returned if
+// the path contains an invalid escape or a character which is rejected.
+// The router uses this to match the routes, a static file server should use it as well
+// so both see the same path.
+func DecodeRequestPath(escaped string) (path string, err error) {
+	if strings.IndexByte(escaped, '%') < 0 {
+		return escaped, nil
+	}
+	decoded := make([]byte, 0, len(escaped))
+	for i := 0; i < len(escaped); i++ {
+		if escaped[i] != '%' {
+			decoded = append(decoded, escaped[i])
+			continue
+		}
+		value, ok := unhexByte(escaped, i+1)
+		if !ok {
+			return "", fmt.Errorf("Invalid escape %q in path", escaped[i:])
+		}
+		escape := escaped[i : i+3]
+		i += 2
+
+		if inner, ok := unhexByte(escaped, i+1); value == '%' && ok {
+			switch PathDoubleEncoded {
+			case PATH_ENCODING_REJECT:
+				return "", fmt.Errorf("Double encoded character %s in path", escaped[i-2:i+3])
+			case PATH_ENCODING_RAW:
+				decoded = append(decoded, '%')
+				continue
+			}
+			value, escape = inner, "%"+escaped[i+1:i+3]
+			i += 2
+		}
+
+		option := PATH_ENCODING_DECODE
+		switch value {
+		case '/':
+			option = PathEncodedSlash
+		case 0:
+			option = PathEncodedNull
+		}
+		switch option {
+		case PATH_ENCODING_REJECT:
+			return "", fmt.Errorf("Encoded character %s in path", escape)
+		case PATH_ENCODING_RAW:
+			decoded = append(decoded, escape...)
+		default:
+			decoded = append(decoded, value)
+		}
+	}
+	return string(decoded), nil
+}
+
+// Returns the byte of the two hex digits at the offset
+func unhexByte(s string, offset int) (value byte, ok bool) {
+	if offset+2 > len(s) {
+		return
+	}
+	for _, c := range []byte(s[offset : offset+2]) {
+		switch {
+		case '0' <= c && c <= '9':
+			c -= '0'
+		case 'a' <= c && c <= 'f':
+			c -= 'a' - 10
+		case 'A' <= c && c <= 'F':
+			c -= 'A' - 10
+		default:
+			return 0, false
+		}
+		value = value<<4 | c
+	}
+	return value, true
+}
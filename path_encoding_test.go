@@ -0,0 +1,40 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"testing"
+)
+
+func TestDecodeRequestPath(t *testing.T) {
+	defer func() {
+		PathEncodedSlash, PathEncodedNull, PathDoubleEncoded = PATH_ENCODING_REJECT, PATH_ENCODING_REJECT, PATH_ENCODING_REJECT
+	}()
+
+	tests := []struct {
+		slash, null, double string
+		escaped, expected   string
+	}{
+		{PATH_ENCODING_REJECT, PATH_ENCODING_REJECT, PATH_ENCODING_REJECT, "/public/a%20b.txt", "/public/a b.txt"},
+		{PATH_ENCODING_REJECT, PATH_ENCODING_REJECT, PATH_ENCODING_REJECT, "/public/..%2Fconf", ""},
+		{PATH_ENCODING_REJECT, PATH_ENCODING_REJECT, PATH_ENCODING_REJECT, "/public/a%00.txt", ""},
+		{PATH_ENCODING_REJECT, PATH_ENCODING_REJECT, PATH_ENCODING_REJECT, "/public/a%252e", ""},
+		{PATH_ENCODING_REJECT, PATH_ENCODING_REJECT, PATH_ENCODING_REJECT, "/public/a%2", ""},
+		{PATH_ENCODING_DECODE, PATH_ENCODING_DECODE, PATH_ENCODING_REJECT, "/a%2fb%00", "/a/b\x00"},
+		{PATH_ENCODING_RAW, PATH_ENCODING_RAW, PATH_ENCODING_REJECT, "/a%2fb%00", "/a%2fb%00"},
+		{PATH_ENCODING_RAW, PATH_ENCODING_REJECT, PATH_ENCODING_RAW, "/a%252Fb", "/a%2Fb"},
+		{PATH_ENCODING_RAW, PATH_ENCODING_REJECT, PATH_ENCODING_DECODE, "/a%252Fb%2541", "/a%2FbA"},
+		{PATH_ENCODING_REJECT, PATH_ENCODING_REJECT, PATH_ENCODING_DECODE, "/a%252Fb", ""},
+	}
+	for _, test := range tests {
+		PathEncodedSlash, PathEncodedNull, PathDoubleEncoded = test.slash, test.null, test.double
+		path, err := DecodeRequestPath(test.escaped)
+		if test.expected == "" && err == nil {
+			t.Errorf("Expected %s to be rejected, got %q", test.escaped, path)
+		} else if test.expected != "" && (err != nil || path != test.expected) {
+			t.Errorf("Expected %s to decode to %q, got %q (%v)", test.escaped, test.expected, path, err)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// RenderError answers a client which prefers JSON to HTML with a problem details document
+// (RFC 7807) instead of the error page
+//   {"type": "https://example.com/problems/out-of-stock", "title": "Out of stock", "status": 409,
+//    "detail": "...", "instance": "/orders/12", "sku": "A-12"}
+// The errors of the application are mapped to their problem type, by value (errors.Is) or
+// by type (errors.As)
+//   revel.RegisterProblemType(ErrOutOfStock, "https://example.com/problems/out-of-stock", "Out of stock", http.StatusConflict)
+//   revel.RegisterProblemTypeOf[*ValidationError]("https://example.com/problems/invalid", "Invalid request", http.StatusUnprocessableEntity)
+// The detail of an error which is not registered is the message of the error in dev mode
+// only. The problem details are turned off in app.conf
+//   results.problem = false
+
+const ProblemContentType = "application/problem+json"
+
+// Problem is the problem details of an error (RFC 7807)
+type Problem struct {
+	Type       string                 // A URI identifying the problem type, about:blank by default
+	Title      string                 // A short summary of the problem type
+	Status     int                    // The HTTP status
+	Detail     string                 // The explanation of this occurrence of the problem
+	Instance   string                 // A URI identifying this occurrence of the problem
+	Extensions map[string]interface{} // The additional members of the document
+}
+
+// MarshalJSON writes the members of the problem and its extensions in a single object
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	members := make(map[string]interface{}, len(p.Extensions)+5)
+	for key, value := range p.Extensions {
+		members[key] = value
+	}
+	members["type"] = p.Type
+	if p.Type == "" {
+		members["type"] = "about:blank"
+	}
+	if p.Title != "" {
+		members["title"] = p.Title
+	}
+	if p.Status != 0 {
+		members["status"] = p.Status
+	}
+	if p.Detail != "" {
+		members["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		members["instance"] = p.Instance
+	}
+	return json.Marshal(members)
+}
+
+// Error returns the title and the detail of the problem
+func (p *Problem) Error() string {
+	if p.Detail == "" {
+		return p.Title
+	}
+	return p.Title + ": " + p.Detail
+}
+
+// HTTPCode returns the status of the problem, the problem is an ErrorCoder
+func (p *Problem) HTTPCode() int {
+	if p.Status == 0 {
+		return http.StatusInternalServerError
+	}
+	return p.Status
+}
+
+// ProblemResult renders the problem details as application/problem+json
+type ProblemResult struct {
+	Problem *Problem
+}
+
+func (r ProblemResult) Apply(req *Request, resp *Response) {
+	b, err := json.Marshal(r.Problem)
+	if err != nil {
+		ErrorResult{Error: err}.Apply(req, resp)
+		return
+	}
+	if r.Problem.Status != 0 {
+		resp.Status = r.Problem.Status
+	}
+	resp.ContentType = ProblemContentType
+	resp.WriteHeader(http.StatusInternalServerError, ProblemContentType)
+	if _, err = resp.GetWriter().Write(b); err != nil {
+		resultsLog.Error("Apply: Response write failed", "error", err)
+	}
+}
+
+// A registered problem type
+type problemType struct {
+	matches func(err error) bool // Returns true if the error is of the problem type
+	uri     string
+	title   string
+	status  int
+}
+
+var problemTypes []*problemType
+
+// RegisterProblemType maps the error to the problem type, an error matches when it is the
+// error (errors.Is). The types of errors are registered by RegisterProblemTypeOf.
+func RegisterProblemType(target error, uri, title string, status int) {
+	problemTypes = append(problemTypes, &problemType{
+		matches: func(err error) bool { return errors.Is(err, target) },
+		uri:     uri,
+		title:   title,
+		status:  status,
+	})
+}
+
+// RegisterProblemTypeOf maps the errors of the type to the problem type, an error matches
+// when it is of the type (errors.As)
+func RegisterProblemTypeOf[T error](uri, title string, status int) {
+	problemTypes = append(problemTypes, &problemType{
+		matches: func(err error) bool {
+			var target T
+			return errors.As(err, &target)
+		},
+		uri:    uri,
+		title:  title,
+		status: status,
+	})
+}
+
+// Returns the registered problem type of the error, nil if none
+func problemTypeOf(err error) *problemType {
+	if err == nil {
+		return nil
+	}
+	for _, problemType := range problemTypes {
+		if problemType.matches(err) {
+			return problemType
+		}
+	}
+	return nil
+}
+
+// NewProblem returns the problem details of the error, the status is used when the error
+// is not registered
+func NewProblem(err error, status int) *Problem {
+	var problem *Problem
+	if errors.As(err, &problem) {
+		copied := *problem
+		return &copied
+	}
+	if problemType := problemTypeOf(err); problemType != nil {
+		return &Problem{Type: problemType.uri, Title: problemType.title, Status: problemType.status, Detail: err.Error()}
+	}
+	problem = &Problem{Title: http.StatusText(status), Status: status}
+	if revelError, ok := err.(*Error); ok {
+		problem.Title, problem.Detail = revelError.Title, revelError.Description
+	} else if DevMode && err != nil {
+		problem.Detail = err.Error()
+	}
+	return problem
+}
+
+// Returns true if the client prefers the JSON problem details to the HTML error page
+func (c *Controller) acceptsProblem() bool {
+	accept := c.Request.GetHttpHeader("Accept")
+	prefersJSON := c.Request.Format == "json"
+	if accept != "" {
+		mediaType := negotiateContentType(accept, []string{"text/html", ProblemContentType, "application/json"})
+		prefersJSON = mediaType == ProblemContentType || mediaType == "application/json"
+	}
+	return prefersJSON && Config.BoolDefault("results.problem", true)
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var errOutOfStock = errors.New("out of stock")
+
+type quotaError struct{ limit int }
+
+func (e *quotaError) Error() string { return fmt.Sprintf("quota of %d exceeded", e.limit) }
+
+func TestRenderErrorProblem(t *testing.T) {
+	startFakeBookingApp()
+	RegisterProblemType(errOutOfStock, "https://example.com/problems/out-of-stock", "Out of stock", http.StatusConflict)
+	RegisterProblemTypeOf[*quotaError]("https://example.com/problems/quota", "Quota exceeded", http.StatusTooManyRequests)
+	defer func() { problemTypes = nil }()
+
+	render := func(accept string, err error) (*httptest.ResponseRecorder, map[string]interface{}) {
+		req, _ := http.NewRequest("GET", "/orders/12", nil)
+		req.Header.Set("Accept", accept)
+		resp := httptest.NewRecorder()
+		c := NewTestController(resp, req)
+		c.RenderError(err).Apply(c.Request, c.Response)
+		members := map[string]interface{}{}
+		json.Unmarshal(resp.Body.Bytes(), &members)
+		return resp, members
+	}
+
+	resp, members := render("application/json", fmt.Errorf("order 12: %w", errOutOfStock))
+	if resp.Code != http.StatusConflict || resp.Header().Get("Content-Type") != ProblemContentType {
+		t.Fatalf("Expected a problem with a 409, got %d %s", resp.Code, resp.Header().Get("Content-Type"))
+	}
+	if members["type"] != "https://example.com/problems/out-of-stock" || members["instance"] != "/orders/12" || members["status"] != float64(409) {
+		t.Errorf("Unexpected problem %v", members)
+	}
+	if resp, members = render("application/problem+json", fmt.Errorf("wrapped: %w", &quotaError{10})); resp.Code != http.StatusTooManyRequests || members["title"] != "Quota exceeded" {
+		t.Errorf("Expected the problem of the error type, got %d %v", resp.Code, members)
+	}
+	problem := &Problem{Type: "https://example.com/problems/custom", Title: "Custom", Status: http.StatusBadRequest, Extensions: map[string]interface{}{"field": "name"}}
+	if resp, members = render("application/json", problem); resp.Code != http.StatusBadRequest || members["field"] != "name" {
+		t.Errorf("Expected the problem with its extensions, got %d %v", resp.Code, members)
+	}
+	// A sentinel does not match the other errors of its type
+	if resp, members = render("application/json", errors.New("unrelated")); resp.Code != http.StatusInternalServerError || members["title"] == "Out of stock" {
+		t.Errorf("Expected an unregistered error not to match a sentinel, got %d %v", resp.Code, members)
+	}
+	if resp, _ = render("text/html,application/xhtml+xml,*/*;q=0.8", errOutOfStock); resp.Header().Get("Content-Type") == ProblemContentType {
+		t.Errorf("Expected the error page for a browser")
+	}
+}
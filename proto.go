@@ -0,0 +1,126 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+)
+
+// Protobuf messages are rendered with RenderProto, and a request body with the
+// application/x-protobuf content type is decoded into the argument of the action which
+// is a message
+//   func (c Bookings) Create(booking *pb.Booking) revel.Result {
+//   	...
+//   	return c.RenderProto(booking)
+//   }
+// RenderAuto renders a message as protobuf when the Request.Format is "proto", which is
+// the format of a request accepting application/x-protobuf.
+//
+// Revel does not depend on a protobuf package, the messages are encoded by the
+// ProtoMessageCodec. The default codec uses the Marshal and Unmarshal methods of the
+// messages generated by gogo/protobuf, for the messages generated by the golang/protobuf
+// or google.golang.org/protobuf packages set a codec using proto.Marshal and proto.Unmarshal
+//   type protoCodec struct{}
+//
+//   func (protoCodec) Marshal(msg revel.ProtoMessage) ([]byte, error) {
+//   	return proto.Marshal(msg.(proto.Message))
+//   }
+//
+//   func (protoCodec) Unmarshal(data []byte, msg revel.ProtoMessage) error {
+//   	return proto.Unmarshal(data, msg.(proto.Message))
+//   }
+//
+//   revel.ProtoMessageCodec = protoCodec{}
+
+// ProtoContentType is the content type of a protobuf request or response body
+const ProtoContentType = "application/x-protobuf"
+
+// ProtoMessage is a protobuf message, the generated messages implement it
+type ProtoMessage interface {
+	Reset()
+	String() string
+	ProtoMessage()
+}
+
+// ProtoCodec encodes and decodes the protobuf messages
+type ProtoCodec interface {
+	Marshal(msg ProtoMessage) ([]byte, error)
+	Unmarshal(data []byte, msg ProtoMessage) error
+}
+
+// ProtoMessageCodec is the codec used by RenderProto and the binder of the messages
+var ProtoMessageCodec ProtoCodec = methodsProtoCodec{}
+
+var protoMessageType = reflect.TypeOf((*ProtoMessage)(nil)).Elem()
+
+// The codec using the Marshal and Unmarshal methods of the message
+type methodsProtoCodec struct{}
+
+func (methodsProtoCodec) Marshal(msg ProtoMessage) ([]byte, error) {
+	if marshaler, ok := msg.(interface {
+		Marshal() ([]byte, error)
+	}); ok {
+		return marshaler.Marshal()
+	}
+	return nil, errors.New("revel: the message has no Marshal method, set revel.ProtoMessageCodec")
+}
+
+func (methodsProtoCodec) Unmarshal(data []byte, msg ProtoMessage) error {
+	if unmarshaler, ok := msg.(interface {
+		Unmarshal([]byte) error
+	}); ok {
+		return unmarshaler.Unmarshal(data)
+	}
+	return errors.New("revel: the message has no Unmarshal method, set revel.ProtoMessageCodec")
+}
+
+// RenderProtoResult writes the protobuf encoding of the message
+type RenderProtoResult struct {
+	msg ProtoMessage
+}
+
+func (r RenderProtoResult) Apply(req *Request, resp *Response) {
+	b, err := ProtoMessageCodec.Marshal(r.msg)
+	if err != nil {
+		ErrorResult{Error: err}.Apply(req, resp)
+		return
+	}
+
+	resp.WriteHeader(http.StatusOK, ProtoContentType)
+	if _, err = resp.GetWriter().Write(b); err != nil {
+		resultsLog.Error("Apply: Response write failed", "error", err)
+	}
+}
+
+// Returns true if the type is a pointer to a protobuf message
+func isProtoType(typ reflect.Type) bool {
+	return typ.Kind() == reflect.Ptr && typ.Implements(protoMessageType)
+}
+
+// ProtoBinder decodes the protobuf request body into the message, without one the
+// message is bound like any other pointer
+var ProtoBinder Binder
+
+func init() {
+	ProtoBinder = Binder{
+		Bind: func(params *Params, name string, typ reflect.Type) reflect.Value {
+			if params.Proto == nil {
+				return PointerBinder.Bind(params, name, typ)
+			}
+			result := reflect.New(typ.Elem())
+			if err := ProtoMessageCodec.Unmarshal(params.Proto, result.Interface().(ProtoMessage)); err != nil {
+				binderLog.Warn("ProtoBinder: Unable to unmarshal request", "name", name, "error", err)
+				params.bindErrors = append(params.bindErrors, &bindError{name, "Must be a valid protobuf message"})
+				return reflect.Zero(typ)
+			}
+			return result
+		},
+		Unbind: func(output map[string]string, name string, val interface{}) {
+			PointerBinder.Unbind(output, name, val)
+		},
+	}
+}
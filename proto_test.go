@@ -0,0 +1,73 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// A message encoding itself like the messages generated by gogo/protobuf
+type testProtoMessage struct {
+	Name string
+}
+
+func (m *testProtoMessage) Reset()         { *m = testProtoMessage{} }
+func (m *testProtoMessage) String() string { return m.Name }
+func (m *testProtoMessage) ProtoMessage()  {}
+
+func (m *testProtoMessage) Marshal() ([]byte, error) {
+	return append([]byte{0x0a, byte(len(m.Name))}, m.Name...), nil
+}
+
+func (m *testProtoMessage) Unmarshal(data []byte) error {
+	if len(data) < 2 || data[0] != 0x0a || int(data[1]) != len(data)-2 {
+		return errors.New("invalid message")
+	}
+	m.Name = string(data[2:])
+	return nil
+}
+
+func TestRenderProto(t *testing.T) {
+	resp := httptest.NewRecorder()
+	c := NewTestController(resp, showRequest)
+	c.RenderProto(&testProtoMessage{Name: "Hotel"}).Apply(c.Request, c.Response)
+	if resp.Body.String() != "\x0a\x05Hotel" || resp.Header().Get("Content-Type") != ProtoContentType {
+		t.Errorf("Unexpected response %q %v", resp.Body.String(), resp.Header())
+	}
+
+	req, _ := http.NewRequest("GET", "/hotels/3", nil)
+	req.Header.Set("Accept", ProtoContentType)
+	resp = httptest.NewRecorder()
+	c = NewTestController(resp, req)
+	if c.Request.Format != "proto" {
+		t.Errorf("Expected the proto format, got %s", c.Request.Format)
+	}
+	if result, ok := c.RenderAuto(&testProtoMessage{Name: "Hotel"}).(RenderProtoResult); !ok || result.msg.String() != "Hotel" {
+		t.Errorf("Expected RenderAuto to render the message, got %#v", result)
+	}
+}
+
+func TestProtoBinder(t *testing.T) {
+	typ := reflect.TypeOf(&testProtoMessage{})
+	for _, body := range []string{"\x0a\x05Hotel", "\x0a\x09Hotel"} {
+		req, _ := http.NewRequest("POST", "/hotels", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", ProtoContentType)
+		c := NewTestController(nil, req)
+		ParseParams(c.Params, c.Request)
+
+		msg := Bind(c.Params, "hotel", typ).Interface().(*testProtoMessage)
+		if body == "\x0a\x05Hotel" && (msg == nil || msg.Name != "Hotel") {
+			t.Errorf("Expected the decoded message, got %v", msg)
+		}
+		if body == "\x0a\x09Hotel" && (msg != nil || len(c.Params.bindErrors) != 1) {
+			t.Errorf("Expected a bind error for the invalid message, got %v %v", msg, c.Params.bindErrors)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// RenderAuto negotiates the media type of the result with the Accept header of the
+// request (RFC 7231: quality values, wildcards and the most specific range win), among the
+// media types of the registered renderers. An application registers its own media types
+//   revel.RegisterAutoRenderer("application/vnd.api+json", "jsonapi", func(c *revel.Controller, o interface{}) revel.Result {
+//   	return c.RenderJSON(toJSONAPI(o))
+//   })
+// The format given is the Request.Format of the media type, and the extension a path may
+// end with to ask for it (/hotels/3.jsonapi). When the Accept header allows none of the
+// media types, the result of NotAcceptableResult is rendered with a 406, unless a
+// fallback format is configured
+//   results.auto.fallback = json
+
+// AutoRenderer renders the object for RenderAuto
+type AutoRenderer func(c *Controller, o interface{}) Result
+
+// A media type RenderAuto renders
+type autoRenderer struct {
+	mediaType string
+	format    string
+	render    AutoRenderer
+}
+
+// The renderers by order of preference when the client accepts several media types
+// with the same quality, html comes first since browsers accept */*
+var autoRenderers []*autoRenderer
+
+// NotAcceptableResult returns the result rendered by RenderAuto when the client accepts
+// none of the media types, the status is set to 406 before it is called
+var NotAcceptableResult = func(c *Controller, offered []string) Result {
+	return c.RenderError(&Error{
+		Title:       "Not Acceptable",
+		Description: "The response can only be one of " + strings.Join(offered, ", "),
+	})
+}
+
+func init() {
+	renderTemplate := func(c *Controller, o interface{}) Result {
+		c.ViewArgs["result"] = o
+		return c.RenderTemplate(c.Name + "/" + c.MethodType.Name + "." + c.Request.Format)
+	}
+	renderJSON := func(c *Controller, o interface{}) Result { return c.RenderJSON(o) }
+	renderXML := func(c *Controller, o interface{}) Result { return c.RenderXML(o) }
+	renderMsgPack := func(c *Controller, o interface{}) Result { return c.RenderMsgPack(o) }
+
+	RegisterAutoRenderer("text/html", "html", renderTemplate)
+	RegisterAutoRenderer("application/xhtml+xml", "html", renderTemplate)
+	RegisterAutoRenderer("application/json", "json", renderJSON)
+	RegisterAutoRenderer("text/javascript", "json", renderJSON)
+	RegisterAutoRenderer("application/javascript", "json", renderJSON)
+	RegisterAutoRenderer("application/xml", "xml", renderXML)
+	RegisterAutoRenderer("text/xml", "xml", renderXML)
+	RegisterAutoRenderer("text/plain", "txt", func(c *Controller, o interface{}) Result { return c.RenderText("%v", o) })
+	RegisterAutoRenderer(ProtoContentType, "proto", func(c *Controller, o interface{}) Result {
+		if msg, ok := o.(ProtoMessage); ok {
+			return c.RenderProto(msg)
+		}
+		return c.RenderJSON(o)
+	})
+	RegisterAutoRenderer(MsgPackContentType, "msgpack", renderMsgPack)
+	RegisterAutoRenderer("application/x-msgpack", "msgpack", renderMsgPack)
+}
+
+// RegisterAutoRenderer registers the renderer of the media type for RenderAuto, the
+// renderer of a registered media type is replaced. The format is the Request.Format of
+// the media type.
+func RegisterAutoRenderer(mediaType, format string, render AutoRenderer) {
+	mediaType = strings.ToLower(mediaType)
+	if _, found := formatContentTypes[format]; !found {
+		formatContentTypes[format] = mediaType
+	} else if formatContentTypes[format] != mediaType {
+		contentTypeFormats[mediaType] = format
+	}
+	for _, renderer := range autoRenderers {
+		if renderer.mediaType == mediaType {
+			renderer.format, renderer.render = format, render
+			return
+		}
+	}
+	autoRenderers = append(autoRenderers, &autoRenderer{mediaType: mediaType, format: format, render: render})
+}
+
+// Returns the registered media types
+func autoRendererMediaTypes() []string {
+	mediaTypes := make([]string, len(autoRenderers))
+	for i, renderer := range autoRenderers {
+		mediaTypes[i] = renderer.mediaType
+	}
+	return mediaTypes
+}
+
+// Returns the renderer of the format
+func autoRendererOfFormat(format string) *autoRenderer {
+	for _, renderer := range autoRenderers {
+		if renderer.format == format {
+			return renderer
+		}
+	}
+	return nil
+}
+
+// Returns the renderer for the request, or nil if the client accepts none. The format
+// chosen by the @Produces annotation, or by the extension of the path, is used as is.
+func (c *Controller) autoRenderer() *autoRenderer {
+	accept := c.Request.GetHttpHeader("Accept")
+	extension := formatOfExtension(c.Request.GetPath())
+	if extension == "" {
+		// The response depends on the Accept header, so the caches keep a response for each
+		addVary(c.Response.Out.Header(), "Accept")
+	}
+	if strings.TrimSpace(accept) == "" || (c.MethodType != nil && len(c.MethodType.produces) > 0) || extension != "" {
+		if renderer := autoRendererOfFormat(c.Request.Format); renderer != nil {
+			return renderer
+		}
+		// A format without a renderer is rendered with a template
+		return &autoRenderer{format: c.Request.Format, render: autoRendererOfFormat("html").render}
+	}
+
+	mediaType := negotiateContentType(accept, autoRendererMediaTypes())
+	if mediaType == "" {
+		fallback := Config.StringDefault("results.auto.fallback", "")
+		if fallback == "" {
+			return nil
+		}
+		return autoRendererOfFormat(fallback)
+	}
+	for _, renderer := range autoRenderers {
+		if renderer.mediaType == mediaType {
+			return renderer
+		}
+	}
+	return nil
+}
+
+// Returns the format of the extension of the path, or an empty string if it is not a
+// known format
+func formatOfExtension(path string) string {
+	format := strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+	if _, found := formatContentTypes[format]; found {
+		return format
+	}
+	return ""
+}
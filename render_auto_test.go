@@ -0,0 +1,80 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResolveFormat(t *testing.T) {
+	tests := map[string]string{
+		"":    "html",
+		"*/*": "html",
+		"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8": "html",
+		"application/json, text/javascript, */*; q=0.01":                  "json",
+		"text/html;q=0.5, application/xml":                                "xml",
+		"application/json;q=0, text/plain":                                "txt",
+		"text/*":                                                          "html",
+		"image/png":                                                       "html",
+	}
+	for accept, expected := range tests {
+		req, _ := http.NewRequest("GET", "/hotels/3", nil)
+		req.Header.Set("Accept", accept)
+		if format := NewTestController(nil, req).Request.Format; format != expected {
+			t.Errorf("Accept %q expected %s got %s", accept, expected, format)
+		}
+	}
+	req, _ := http.NewRequest("GET", "/hotels/3.json", nil)
+	req.Header.Set("Accept", "application/xml")
+	if format := NewTestController(nil, req).Request.Format; format != "json" {
+		t.Errorf("Expected the extension to set the format, got %s", format)
+	}
+}
+
+func TestRenderAutoNegotiation(t *testing.T) {
+	RegisterAutoRenderer("application/vnd.api+json", "jsonapi", func(c *Controller, o interface{}) Result {
+		return c.RenderText("jsonapi %v", o)
+	})
+	defer func() {
+		autoRenderers = autoRenderers[:len(autoRenderers)-1]
+		delete(formatContentTypes, "jsonapi")
+	}()
+
+	render := func(accept string) (*Controller, *httptest.ResponseRecorder) {
+		req, _ := http.NewRequest("GET", "/hotels/3", nil)
+		req.Header.Set("Accept", accept)
+		resp := httptest.NewRecorder()
+		c := NewTestController(resp, req)
+		c.RenderAuto(3).Apply(c.Request, c.Response)
+		return c, resp
+	}
+
+	c, resp := render("application/json;q=0.5, application/vnd.api+json")
+	if resp.Body.String() != "jsonapi 3" || c.Request.Format != "jsonapi" {
+		t.Errorf("Expected the custom renderer, got %q in %s", resp.Body.String(), c.Request.Format)
+	}
+	if _, resp = render("application/*;q=0.2, application/json;q=0.9, application/vnd.api+json;q=0.1"); resp.Body.String() != "3" {
+		t.Errorf("Expected the most specific range to set the quality, got %q", resp.Body.String())
+	}
+	if vary := resp.Header().Get("Vary"); vary != "Accept" {
+		t.Errorf("Expected the response to vary by Accept, got %q", vary)
+	}
+
+	notAcceptable := NotAcceptableResult
+	defer func() { NotAcceptableResult = notAcceptable }()
+	NotAcceptableResult = func(c *Controller, offered []string) Result {
+		return c.RenderText("not acceptable")
+	}
+	if _, resp = render("image/png"); resp.Code != http.StatusNotAcceptable || resp.Body.String() != "not acceptable" {
+		t.Errorf("Expected a 406, got %d %q", resp.Code, resp.Body.String())
+	}
+	Config.SetOption("results.auto.fallback", "json")
+	defer Config.SetOption("results.auto.fallback", "")
+	if _, resp = render("image/png"); resp.Code != http.StatusOK || resp.Body.String() != "3" {
+		t.Errorf("Expected the fallback format, got %d %q", resp.Code, resp.Body.String())
+	}
+}
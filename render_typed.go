@@ -0,0 +1,38 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+// The typed render functions check the type of the payload at compile time, an action
+// declares the type it responds with once
+//   type BookingResponse = revel.Envelope[*models.Booking]
+//   return revel.RenderJSON(c.Controller, BookingResponse{Data: booking})
+// The envelope gives the responses of the application the same shape
+//   {"status": 201, "data": {...}, "meta": {"page": 2}}
+//   return revel.RenderEnvelope(c.Controller, http.StatusCreated, booking, map[string]interface{}{"page": 2})
+// The functions are generic, the Controller methods cannot be.
+
+// Envelope wraps the payload of a response with its status and metadata
+type Envelope[T any] struct {
+	Status int                    `json:"status" xml:"status"`
+	Data   T                      `json:"data" xml:"data"`
+	Meta   map[string]interface{} `json:"meta,omitempty" xml:"-"`
+}
+
+// RenderJSON renders the value as JSON, like Controller.RenderJSON
+func RenderJSON[T any](c *Controller, v T) Result {
+	return c.RenderJSON(v)
+}
+
+// RenderXML renders the value as XML, like Controller.RenderXML
+func RenderXML[T any](c *Controller, v T) Result {
+	return c.RenderXML(v)
+}
+
+// RenderEnvelope renders the data in an Envelope as JSON, the status is the status of the
+// response and of the envelope
+func RenderEnvelope[T any](c *Controller, status int, data T, meta map[string]interface{}) Result {
+	c.Response.Status = status
+	return c.RenderJSON(Envelope[T]{Status: status, Data: data, Meta: meta})
+}
@@ -0,0 +1,40 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type typedBooking struct {
+	Hotel  string `json:"hotel"`
+	Nights int    `json:"nights"`
+}
+
+func TestRenderEnvelope(t *testing.T) {
+	resp := httptest.NewRecorder()
+	c := NewTestController(resp, showRequest)
+	RenderEnvelope(c, http.StatusCreated, &typedBooking{"Hilton", 2}, map[string]interface{}{"page": 2}).Apply(c.Request, c.Response)
+	if resp.Code != http.StatusCreated {
+		t.Errorf("Expected a 201, got %d", resp.Code)
+	}
+	var envelope Envelope[*typedBooking]
+	if err := json.Unmarshal(resp.Body.Bytes(), &envelope); err != nil {
+		t.Fatal(err)
+	}
+	if envelope.Status != http.StatusCreated || envelope.Data.Hotel != "Hilton" || envelope.Meta["page"] != float64(2) {
+		t.Errorf("Unexpected envelope %s", resp.Body.String())
+	}
+
+	resp = httptest.NewRecorder()
+	c = NewTestController(resp, showRequest)
+	RenderJSON(c, []typedBooking{{"Hilton", 1}}).Apply(c.Request, c.Response)
+	if body := resp.Body.String(); body != `[{"hotel":"Hilton","nights":1}]` {
+		t.Errorf("Unexpected JSON %s", body)
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+// The values passed from the filters to the action are stored with typed keys, instead of
+// the string keys of the Controller.Args. A key is declared once by the package setting
+// the value
+//   var CurrentUserKey = revel.NewReqKey[*models.User]("currentUser")
+// the filter sets the value
+//   revel.SetReqValue(c, CurrentUserKey, user)
+// and the action gets it as a *models.User
+//   user, found := revel.GetReqValue(c, CurrentUserKey)
+// Two keys never collide, even with the same name, so modules do not overwrite the values
+// of each other. The values are dropped at the end of the request.
+
+// ReqKey is the key of a request value of type T
+type ReqKey[T any] struct {
+	name string
+}
+
+// NewReqKey returns a new key for a request value of type T, the name is used to log it
+func NewReqKey[T any](name string) *ReqKey[T] {
+	return &ReqKey[T]{name: name}
+}
+
+// String returns the name of the key
+func (k *ReqKey[T]) String() string {
+	return k.name
+}
+
+// SetReqValue sets the value of the key for the request of the controller
+func SetReqValue[T any](c *Controller, key *ReqKey[T], value T) {
+	if c.reqValues == nil {
+		c.reqValues = map[interface{}]interface{}{}
+	}
+	c.reqValues[key] = value
+}
+
+// GetReqValue returns the value of the key for the request of the controller, or the zero
+// value and false if it is not set
+func GetReqValue[T any](c *Controller, key *ReqKey[T]) (value T, found bool) {
+	stored, found := c.reqValues[key]
+	if found {
+		value = stored.(T)
+	}
+	return
+}
+
+// DeleteReqValue removes the value of the key for the request of the controller
+func DeleteReqValue[T any](c *Controller, key *ReqKey[T]) {
+	delete(c.reqValues, key)
+}
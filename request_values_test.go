@@ -0,0 +1,37 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReqValues(t *testing.T) {
+	userKey := NewReqKey[string]("user")
+	otherUserKey := NewReqKey[int]("user")
+	c := NewTestController(httptest.NewRecorder(), showRequest)
+
+	if _, found := GetReqValue(c, userKey); found {
+		t.Errorf("Expected no value before it is set")
+	}
+	SetReqValue(c, userKey, "rob")
+	SetReqValue(c, otherUserKey, 3)
+	if user, found := GetReqValue(c, userKey); !found || user != "rob" {
+		t.Errorf("Expected the value of the key, got %q", user)
+	}
+	if id, found := GetReqValue(c, otherUserKey); !found || id != 3 {
+		t.Errorf("Expected the keys with the same name not to collide, got %d", id)
+	}
+
+	DeleteReqValue(c, userKey)
+	if _, found := GetReqValue(c, userKey); found {
+		t.Errorf("Expected the value to be deleted")
+	}
+	c.Destroy()
+	if _, found := GetReqValue(c, otherUserKey); found {
+		t.Errorf("Expected the values to be dropped with the controller")
+	}
+}
@@ -5,14 +5,15 @@
 package revel
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
-	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"reflect"
 	"strconv"
 	"strings"
@@ -132,6 +133,7 @@ func (r PlaintextErrorResult) Apply(req *Request, resp *Response) {
 type RenderTemplateResult struct {
 	Template Template
 	ViewArgs map[string]interface{}
+	Fragment string // The fragment of the template rendered, the whole template when empty
 }
 
 func (r *RenderTemplateResult) Apply(req *Request, resp *Response) {
@@ -167,7 +169,9 @@ func (r *RenderTemplateResult) Apply(req *Request, resp *Response) {
 	// rendering the template.  If not, then copy it into the response buffer.
 	// Otherwise, template render errors may result in unpredictable HTML (and
 	// would carry a 200 status code)
+	stopTiming := resp.Timing.Start("render")
 	b, err := r.ToBytes()
+	stopTiming()
 	if err!=nil {
 		r.renderError(err,req,resp)
 		return
@@ -207,7 +211,13 @@ func (r *RenderTemplateResult) renderOutput(wr io.Writer) (err error) {
 			err = fmt.Errorf("Template Execution Panic in %s:\n%s", r.Template.Name(), rerr)
 		}
 	}()
-	err = r.Template.Render(wr, r.ViewArgs)
+	if r.Fragment == "" {
+		err = r.Template.Render(wr, r.ViewArgs)
+	} else if fragmentTemplate, ok := r.Template.(FragmentTemplate); ok {
+		err = fragmentTemplate.RenderFragment(wr, r.Fragment, r.ViewArgs)
+	} else {
+		err = fmt.Errorf("template %s cannot render the fragment %s", r.Template.Name(), r.Fragment)
+	}
 	return
 }
 
@@ -298,17 +308,11 @@ func (r RenderHTMLResult) Apply(req *Request, resp *Response) {
 type RenderJSONResult struct {
 	obj      interface{}
 	callback string
+	options  *JSONOptions // The options of app.conf when nil
 }
 
 func (r RenderJSONResult) Apply(req *Request, resp *Response) {
-	var b []byte
-	var err error
-	if Config.BoolDefault("results.pretty", false) {
-		b, err = json.MarshalIndent(r.obj, "", "  ")
-	} else {
-		b, err = json.Marshal(r.obj)
-	}
-
+	b, err := marshalJSONResult(r.obj, r.options)
 	if err != nil {
 		ErrorResult{Error: err}.Apply(req, resp)
 		return
@@ -334,25 +338,90 @@ func (r RenderJSONResult) Apply(req *Request, resp *Response) {
 	}
 }
 
-type RenderXMLResult struct {
-	obj interface{}
+// RenderJSONStreamResult writes the values of the iterator as a JSON array. The response is
+// flushed every results.jsonstream.flush values (default 100), without a Content-Length it
+// is sent chunked. When a value fails to be encoded the array is not closed, so the client
+// sees the response is incomplete.
+type RenderJSONStreamResult struct {
+	iter func(yield func(v interface{}) bool)
 }
 
-func (r RenderXMLResult) Apply(req *Request, resp *Response) {
-	var b []byte
-	var err error
-	if Config.BoolDefault("results.pretty", false) {
-		b, err = xml.MarshalIndent(r.obj, "", "  ")
-	} else {
-		b, err = xml.Marshal(r.obj)
+// The values of a JSON stream written between the flushes, loaded when the application starts
+var jsonStreamFlushEvery = 100
+
+func init() {
+	OnAppStart(func() {
+		if jsonStreamFlushEvery = Config.IntDefault("results.jsonstream.flush", 100); jsonStreamFlushEvery < 1 {
+			jsonStreamFlushEvery = 1
+		}
+	})
+}
+
+func (r *RenderJSONStreamResult) Apply(req *Request, resp *Response) {
+	resp.WriteHeader(http.StatusOK, "application/json; charset=utf-8")
+	out := resp.GetWriter()
+	writer := bufio.NewWriter(out)
+	flushEvery := jsonStreamFlushEvery
+	flush := func() error {
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+		if flusher, ok := out.(http.Flusher); ok {
+			flusher.Flush()
+		}
+		return nil
 	}
 
+	count, failed := 0, false
+	writer.WriteByte('[')
+	r.iter(func(v interface{}) bool {
+		if failed {
+			return false
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			resultsLog.Error("Apply: Failed to encode the streamed value", "index", count, "error", err)
+			failed = true
+			return false
+		}
+		if count > 0 {
+			writer.WriteByte(',')
+		}
+		writer.Write(b)
+		if count++; count%flushEvery == 0 {
+			if err = flush(); err != nil {
+				resultsLog.Error("Apply: Response write failed", "error", err)
+				failed = true
+				return false
+			}
+		}
+		return true
+	})
+	if !failed {
+		writer.WriteByte(']')
+	}
+	if err := flush(); err != nil {
+		resultsLog.Error("Apply: Response write failed", "error", err)
+	}
+}
+
+type RenderXMLResult struct {
+	obj     interface{}
+	options *XMLOptions // The options of app.conf when nil
+}
+
+func (r RenderXMLResult) Apply(req *Request, resp *Response) {
+	b, err := marshalXMLResult(r.obj, r.options)
 	if err != nil {
 		ErrorResult{Error: err}.Apply(req, resp)
 		return
 	}
 
-	resp.WriteHeader(http.StatusOK, "application/xml; charset=utf-8")
+	charset := "utf-8"
+	if r.options != nil {
+		charset = strings.ToLower(r.options.encoding())
+	}
+	resp.WriteHeader(http.StatusOK, "application/xml; charset="+charset)
 	if _, err = resp.GetWriter().Write(b); err != nil {
 		resultsLog.Error("Apply: Response write failed", "error", err)
 	}
@@ -376,6 +445,53 @@ var (
 	Inline     ContentDisposition = "inline"
 )
 
+// Header returns the Content-Disposition header of the file name. A name which is not
+// ASCII is sent in the filename* parameter (RFC 5987), with an ASCII filename for the
+// older clients. The control characters, quotes and path separators of the name are
+// dropped, so the name cannot inject a header.
+func (d ContentDisposition) Header(filename string) string {
+	disposition := string(d)
+	var ascii, name strings.Builder
+	needsEncoding := false
+	for _, r := range filename {
+		switch {
+		case r < 0x20 || r == 0x7f || r == '"' || r == '\\' || r == '/':
+			continue
+		case r > 0x7e:
+			needsEncoding = true
+			ascii.WriteByte('_')
+		default:
+			ascii.WriteRune(r)
+		}
+		name.WriteRune(r)
+	}
+	if name.Len() == 0 {
+		return disposition
+	}
+	disposition += `; filename="` + ascii.String() + `"`
+	if needsEncoding {
+		disposition += "; filename*=UTF-8''" + encodeRFC5987(name.String())
+	}
+	return disposition
+}
+
+// Percent encodes the value as an RFC 5987 ext-value
+func encodeRFC5987(value string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.IndexByte("!#$&+-.^_`|~", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			b.WriteByte('%')
+			b.WriteByte(hex[c>>4])
+			b.WriteByte(hex[c&15])
+		}
+	}
+	return b.String()
+}
+
 type BinaryResult struct {
 	Reader   io.Reader
 	Name     string
@@ -385,12 +501,12 @@ type BinaryResult struct {
 }
 
 func (r *BinaryResult) Apply(req *Request, resp *Response) {
-	disposition := string(r.Delivery)
-	if r.Name != "" {
-		disposition += fmt.Sprintf(`; filename="%s"`, r.Name)
+	// Close the Reader if we can
+	if v, ok := r.Reader.(io.Closer); ok {
+		defer v.Close()
 	}
 
-	resp.Out.internalHeader.Set("Content-Disposition", disposition)
+	resp.Out.internalHeader.Set("Content-Disposition", r.Delivery.Header(r.Name))
 	if resp.ContentType != "" {
 		resp.Out.internalHeader.Set("Content-Type", resp.ContentType)
 	} else {
@@ -409,31 +525,55 @@ func (r *BinaryResult) Apply(req *Request, resp *Response) {
 		}
 	}
 
+	// Send the validators of the content, the client may have it already
+	if !r.ModTime.IsZero() && etagConfig.files {
+		etag := ""
+		if r.Length >= 0 {
+			etag = fileETag(r.Length, r.ModTime)
+			resp.Out.internalHeader.Set("ETag", etag)
+		}
+		resp.Out.internalHeader.Set("Last-Modified", r.ModTime.UTC().Format(http.TimeFormat))
+		if notModified(req, etag, r.ModTime) {
+			writeNotModified(resp)
+			return
+		}
+	}
+
+	// A seekable content of a known size serves the ranges of the request itself, so they
+	// are served whatever the server engine is. The ranges are of the content, they are not
+	// compressed by the CompressFilter.
+	if content, ok := r.Reader.(io.ReadSeeker); ok && r.Length >= 0 && Config.BoolDefault("results.ranges", true) {
+		resp.Out.internalHeader.Set("Accept-Ranges", "bytes")
+		if req.GetHttpHeader("Range") != "" {
+			// The reader is closed by this result
+			ranges := &ByteRangesResult{Content: struct{ io.ReadSeeker }{content}, ContentType: resp.Out.internalHeader.Get("Content-Type"), ModTime: r.ModTime}
+			ranges.Apply(req, resp)
+			return
+		}
+	}
+
 	// Write stream writes the status code to the header as well
 	if ws := resp.GetStreamWriter(); ws != nil {
 		if err := ws.WriteStream(r.Name, r.Length, r.ModTime, r.Reader); err != nil {
 			resultsLog.Error("Apply: Response write failed", "error", err)
 		}
 	}
-
-	// Close the Reader if we can
-	if v, ok := r.Reader.(io.Closer); ok {
-		_ = v.Close()
-	}
 }
 
 type RedirectToURLResult struct {
-	url string
+	url   string
+	query url.Values // Added to the query string of the URL
 }
 
 func (r *RedirectToURLResult) Apply(req *Request, resp *Response) {
-	resp.Out.internalHeader.Set("Location", r.url)
+	resp.Out.internalHeader.Set("Location", addQuery(r.url, r.query))
 	resp.WriteHeader(http.StatusFound, "")
 }
 
 type RedirectToActionResult struct {
-	val interface{}
-	args []interface{}
+	val   interface{}
+	args  []interface{}
+	query url.Values // Added to the query string of the URL
 }
 
 func (r *RedirectToActionResult) Apply(req *Request, resp *Response) {
@@ -443,10 +583,26 @@ func (r *RedirectToActionResult) Apply(req *Request, resp *Response) {
 		ErrorResult{Error: err}.Apply(req, resp)
 		return
 	}
-	resp.Out.internalHeader.Set("Location", url)
+	resp.Out.internalHeader.Set("Location", addQuery(url, r.query))
 	resp.WriteHeader(http.StatusFound, "")
 }
 
+// Returns the URL with the values added to its query string
+func addQuery(location string, query url.Values) string {
+	if len(query) == 0 {
+		return location
+	}
+	fragment := ""
+	if i := strings.Index(location, "#"); i >= 0 {
+		location, fragment = location[:i], location[i:]
+	}
+	separator := "?"
+	if strings.Contains(location, "?") {
+		separator = "&"
+	}
+	return location + separator + query.Encode() + fragment
+}
+
 func getRedirectURL(item interface{}, args []interface{}) (string, error) {
 	// Handle strings
 	if url, ok := item.(string); ok {
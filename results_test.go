@@ -5,6 +5,8 @@
 package revel
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http/httptest"
 	"strings"
 	"testing"
@@ -25,6 +27,40 @@ func TestBenchmarkRender(t *testing.T) {
 	}
 }
 
+func TestRenderJSONStream(t *testing.T) {
+	resp := httptest.NewRecorder()
+	c := NewTestController(resp, showRequest)
+	c.RenderJSONStream(func(yield func(v interface{}) bool) {
+		for i := 0; i < 250; i++ {
+			if !yield(map[string]int{"id": i}) {
+				return
+			}
+		}
+	}).Apply(c.Request, c.Response)
+	var values []map[string]int
+	if err := json.Unmarshal(resp.Body.Bytes(), &values); err != nil || len(values) != 250 || values[249]["id"] != 249 {
+		t.Errorf("Expected the streamed array, got %d values %v", len(values), err)
+	}
+	if !resp.Flushed || resp.Header().Get("Content-Type") != "application/json; charset=utf-8" {
+		t.Errorf("Expected a flushed JSON response, got %v", resp.Header())
+	}
+
+	resp = httptest.NewRecorder()
+	c = NewTestController(resp, showRequest)
+	yielded := 0
+	c.RenderJSONStream(func(yield func(v interface{}) bool) {
+		for _, v := range []interface{}{1, 2, make(chan int), 4} {
+			if !yield(v) {
+				return
+			}
+			yielded++
+		}
+	}).Apply(c.Request, c.Response)
+	if resp.Body.String() != "[1,2" || yielded != 2 {
+		t.Errorf("Expected the stream to stop at the value failing to encode, got %q after %d values", resp.Body.String(), yielded)
+	}
+}
+
 func BenchmarkRenderChunked(b *testing.B) {
 	startFakeBookingApp()
 	resp := httptest.NewRecorder()
@@ -58,3 +94,100 @@ func BenchmarkRenderNotChunked(b *testing.B) {
 		hotels.Show(3).Apply(c.Request, c.Response)
 	}
 }
+
+func TestRenderTemplateFragment(t *testing.T) {
+	startFakeBookingApp()
+	resp := httptest.NewRecorder()
+	c := NewTestController(resp, showRequest)
+	c.RenderTemplateFragment("hotels/list.html", "rows", map[string]interface{}{"hotels": []string{"Hilton"}}).Apply(c.Request, c.Response)
+	if body := resp.Body.String(); strings.TrimSpace(body) != "<tr><td>Hilton</td></tr>" {
+		t.Errorf("Expected the rows only, got %q", body)
+	}
+
+	// The fragment of another template of the same name is kept apart
+	resp = httptest.NewRecorder()
+	c = NewTestController(resp, showRequest)
+	c.RenderTemplateFragment("widgets/rows.html", "rows", map[string]interface{}{"hotels": []string{"Hilton"}}).Apply(c.Request, c.Response)
+	if body := resp.Body.String(); strings.TrimSpace(body) != `<tr class="booking"><td>Hilton</td></tr>` {
+		t.Errorf("Expected the rows of the other template, got %q", body)
+	}
+
+	resp = httptest.NewRecorder()
+	c = NewTestController(resp, showRequest)
+	c.RenderTemplateFragment("hotels/list.html", "missing", nil).Apply(c.Request, c.Response)
+	if resp.Code != 500 {
+		t.Errorf("Expected an error for a missing fragment, got %d", resp.Code)
+	}
+}
+
+func TestRedirectHelpers(t *testing.T) {
+	redirect := func(result func(c *Controller) Result) (*httptest.ResponseRecorder, *Controller) {
+		resp := httptest.NewRecorder()
+		c := NewTestController(resp, showRequest)
+		c.Flash = Flash{Data: map[string]string{}, Out: map[string]string{}}
+		result(c).Apply(c.Request, c.Response)
+		return resp, c
+	}
+
+	for status, result := range map[int]func(c *Controller) Result{
+		302: func(c *Controller) Result { return c.Redirect("/hotels") },
+		303: func(c *Controller) Result { return c.RedirectSeeOther("/hotels") },
+		307: func(c *Controller) Result { return c.RedirectTemporary("/hotels") },
+		308: func(c *Controller) Result { return c.RedirectPermanent("/hotels") },
+	} {
+		if resp, _ := redirect(result); resp.Code != status || resp.Header().Get("Location") != "/hotels" {
+			t.Errorf("Expected a %d redirect, got %d %s", status, resp.Code, resp.Header().Get("Location"))
+		}
+	}
+
+	resp, _ := redirect(func(c *Controller) Result {
+		return c.RedirectWithQuery("/hotels?sort=name#list", map[string]string{"q": "paris & co", "page": "2"})
+	})
+	if location := resp.Header().Get("Location"); location != "/hotels?sort=name&page=2&q=paris+%26+co#list" {
+		t.Errorf("Unexpected location %s", location)
+	}
+
+	resp, c := redirect(func(c *Controller) Result {
+		return c.RedirectWithFlash("/hotels/%d", map[string]string{"success": "Booked"}, 3)
+	})
+	if resp.Header().Get("Location") != "/hotels/3" || c.Flash.Out["success"] != "Booked" {
+		t.Errorf("Expected the flash and the redirect, got %s %v", resp.Header().Get("Location"), c.Flash.Out)
+	}
+}
+
+func TestContentDispositionHeader(t *testing.T) {
+	for _, test := range []struct {
+		delivery ContentDisposition
+		name     string
+		expected string
+	}{
+		{Attachment, "report.pdf", `attachment; filename="report.pdf"`},
+		{Inline, "", `inline`},
+		{Attachment, "résumé 2017.pdf", `attachment; filename="r_sum_ 2017.pdf"; filename*=UTF-8''r%C3%A9sum%C3%A9%202017.pdf`},
+		{Attachment, "数据.csv", `attachment; filename="__.csv"; filename*=UTF-8''%E6%95%B0%E6%8D%AE.csv`},
+		{Attachment, "a.txt\"\r\nSet-Cookie: x=1", `attachment; filename="a.txtSet-Cookie: x=1"`},
+		{Inline, "../../etc/passwd", `inline; filename="....etcpasswd"`},
+	} {
+		if actual := test.delivery.Header(test.name); actual != test.expected {
+			t.Errorf("%q: expected %s got %s", test.name, test.expected, actual)
+		}
+	}
+}
+
+// Test that the client errors of the content negotiation and the body limits have a template
+func TestClientErrorTemplates(t *testing.T) {
+	startFakeBookingApp()
+	for _, status := range []int{400, 406, 409, 413, 415, 422} {
+		for _, format := range []string{"html", "json", "txt", "xml"} {
+			resp := httptest.NewRecorder()
+			c := NewTestController(resp, showRequest)
+			c.Request.Format = format
+			c.Response.Status = status
+			ErrorResult{Error: errors.New("rejected")}.Apply(c.Request, c.Response)
+			body := resp.Body.String()
+			if resp.Code != status || !strings.Contains(body, "rejected") || strings.Contains(body, "an error occurred when rendering the error page") {
+				t.Errorf("Expected the errors/%d.%s template, got %d %s", status, format, resp.Code, body)
+			}
+		}
+	}
+}
@@ -40,6 +40,16 @@ const (
 	ROUTE_REFRESH_REQUESTED
 	// Called after routes have been refreshed
 	ROUTE_REFRESH_COMPLETED
+
+	// Event type after a controller is added to the registry, the value is the *ControllerType
+	CONTROLLER_REGISTERED
+	// Event type after a method of a registered controller is added to the registry, the value is the *MethodType
+	METHOD_REGISTERED
+
+	// Event type when the circuit breaker of an action opens, the value is the *CircuitBreakerEvent
+	CIRCUIT_BREAKER_OPENED
+	// Event type when the circuit breaker of an action closes, the value is the *CircuitBreakerEvent
+	CIRCUIT_BREAKER_CLOSED
 )
 
 type EventHandler func(typeOf int, value interface{}) (responseOf int)
@@ -191,7 +201,10 @@ func Init(mode, importPath, srcPath string) {
 	AppRoot = Config.StringDefault("app.root", "")
 	CookiePrefix = Config.StringDefault("cookie.prefix", "REVEL")
 	CookieDomain = Config.StringDefault("cookie.domain", "")
-	CookieSecure = Config.BoolDefault("cookie.secure", HTTPSsl)
+	CookieSecure = Config.BoolDefault("cookie.secure", HTTPSsl || !DevMode)
+	if err = loadCookieAttributes(); err != nil {
+		RevelLog.Fatal("Invalid cookie attributes in app.conf", "error", err)
+	}
 	if secretStr := Config.StringDefault("app.secret", ""); secretStr != "" {
 		SetSecretKey([]byte(secretStr))
 	}
@@ -9,10 +9,14 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net/http"
 	"net/url"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/revel/pathtree"
 	"os"
@@ -171,7 +175,12 @@ func (router *Router) Route(req *Request) (routeMatch *RouteMatch) {
 		req.Method = method
 	}
 
-	leaf, expansions := router.Tree.Find(treePath(req.Method, req.GetPath()))
+	// The path is decoded using the encoded path options, RouterFilter rejects the invalid paths
+	path, err := DecodeRequestPath(req.GetRawPath())
+	if err != nil {
+		return nil
+	}
+	leaf, expansions := router.Tree.Find(treePath(req.Method, path))
 	if leaf == nil {
 		return nil
 	}
@@ -710,9 +719,7 @@ func (router *Router) Reverse(action string, argValues map[string]string) (ad *A
 		}
 
 		// Add any args that were not inserted into the path into the query string.
-		for k, v := range argValues {
-			queryValues.Set(k, v)
-		}
+		addReverseQueryValues(queryValues, argValues)
 
 		// Calculate the final URL and Method
 		urlPath := strings.Join(pathElements, "/")
@@ -743,9 +750,76 @@ func (router *Router) Reverse(action string, argValues map[string]string) (ad *A
 	return nil
 }
 
+// Adds the arguments to the query string, slice arguments are written using the
+// SliceParamFormat so they can be bound back to the slice. Map arguments are
+// always written using bracketed keys, e.g. filter[status]=open
+func addReverseQueryValues(queryValues url.Values, argValues map[string]string) {
+	format := SliceParamFormat
+	sliceValues := map[string]map[int]string{}
+	for k, v := range argValues {
+		if format == "brackets" || format == "repeat" {
+			if name, index, found := splitSliceKey(k); found {
+				if sliceValues[name] == nil {
+					sliceValues[name] = map[int]string{}
+				}
+				sliceValues[name][index] = v
+				continue
+			}
+		}
+		queryValues.Set(k, v)
+	}
+
+	for name, values := range sliceValues {
+		indexes := make([]int, 0, len(values))
+		for index := range values {
+			indexes = append(indexes, index)
+		}
+		sort.Ints(indexes)
+		if format == "brackets" {
+			name += "[]"
+		}
+		for _, index := range indexes {
+			queryValues.Add(name, values[index])
+		}
+	}
+}
+
+// Splits a key like ids[1] into the name and index
+func splitSliceKey(key string) (name string, index int, found bool) {
+	leftBracket := strings.LastIndex(key, "[")
+	if leftBracket < 1 || !strings.HasSuffix(key, "]") {
+		return
+	}
+	var err error
+	if index, err = strconv.Atoi(key[leftBracket+1 : len(key)-1]); err != nil || index < 0 {
+		return
+	}
+	return key[:leftBracket], index, true
+}
+
+// Returns the route of the request, the request is routed once for the filters which need
+// its action before the RouterFilter (the SPACSRFFilter) and the RouterFilter
+func routeOf(req *Request) *RouteMatch {
+	if !req.routed {
+		req.route, req.routed = MainRouter.Route(req), true
+	}
+	return req.route
+}
+
 func RouterFilter(c *Controller, fc []Filter) {
+	start := time.Now()
+	if _, err := DecodeRequestPath(c.Request.GetRawPath()); err != nil {
+		routerLog.Warn("RouterFilter: Rejected request path", "path", c.Request.GetRawPath(), "error", err)
+		c.Response.Status = http.StatusBadRequest
+		c.Result = c.RenderError(&Error{
+			Title:       "Bad Request",
+			Description: err.Error(),
+		})
+		return
+	}
+
 	// Figure out the Controller/Action
-	route := MainRouter.Route(c.Request)
+	route := routeOf(c.Request)
 	if route == nil {
 		c.Result = c.NotFound("No matching route found: " + c.Request.GetRequestURI())
 		return
@@ -786,6 +860,7 @@ func RouterFilter(c *Controller, fc []Filter) {
 		}
 	}
 
+	c.Response.Timing.Add("routing", time.Since(start))
 	fc[0](c, fc[1:])
 }
 
@@ -0,0 +1,129 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The routes are analyzed on startup, the problems found are logged as warnings
+//   - a route which can never match since an earlier route has the same method and path
+//   - two routes which both match some requests, where neither is more specific
+//     (like /hotels/:id/edit and /hotels/new/:step), the one matching is not obvious
+//   - a path parameter named like a view arg set by Revel (like flash or errors),
+//     rendering the parameter replaces the view arg
+//   - a route to a controller or action which is not registered
+// With routes.strict=true the application fails to start instead. Tests check routes
+// with Router.Analyze.
+
+// RouteProblem is a problem found in the routes
+type RouteProblem struct {
+	Route   *Route
+	Other   *Route // The route the problem is with, if any
+	Message string
+}
+
+func (p *RouteProblem) Error() string {
+	return fmt.Sprintf("%s:%d: %s %s %s", p.Route.routesPath, p.Route.line+1, p.Route.Method, p.Route.Path, p.Message)
+}
+
+// The view args set by Revel, which a path parameter should not be named after
+var reservedViewArgs = []string{"RunMode", "DevMode", "Error", "Router", "session", "flash", "errors", CurrentLocaleViewArg, "seo"}
+
+func init() {
+	OnAppStart(func() {
+		if MainRouter == nil {
+			return
+		}
+		problems := MainRouter.Analyze()
+		for _, problem := range problems {
+			routerLog.Warn("Route problem", "problem", problem.Error())
+		}
+		if len(problems) > 0 && Config.BoolDefault("routes.strict", false) {
+			routerLog.Fatal("Problems found in the routes with routes.strict enabled, see the warnings above")
+		}
+	}, 5)
+}
+
+// Analyze returns the problems found in the routes of the router, in the order of the routes
+func (router *Router) Analyze() (problems []*RouteProblem) {
+	for i, route := range router.Routes {
+		add := func(other *Route, format string, args ...interface{}) {
+			problems = append(problems, &RouteProblem{Route: route, Other: other, Message: fmt.Sprintf(format, args...)})
+		}
+		segments := routeSegments(route)
+		for _, earlier := range router.Routes[:i] {
+			earlierSegments := routeSegments(earlier)
+			if strings.Join(segments, "/") == strings.Join(earlierSegments, "/") {
+				// A wildcard controller may not match, the request then falls through to the next route
+				if earlier.Action == httpStatusCode || (earlier.ControllerName != "" && earlier.ControllerName[0] != ':') {
+					add(earlier, "is unreachable, the route on line %d has the same method and path", earlier.line+1)
+				}
+			} else if routesAmbiguous(segments, earlierSegments) {
+				add(earlier, "overlaps the route on line %d (%s %s), neither is more specific", earlier.line+1, earlier.Method, earlier.Path)
+			}
+		}
+
+		for _, segment := range strings.Split(route.Path, "/") {
+			if segment == "" || (segment[0] != ':' && segment[0] != '*') {
+				continue
+			}
+			for _, reserved := range reservedViewArgs {
+				if segment[1:] == reserved {
+					add(nil, "has the parameter %s, which is a view arg set by Revel", reserved)
+				}
+			}
+		}
+
+		if route.Action == httpStatusCode || route.ControllerName == "" || route.MethodName == "" ||
+			route.ControllerName[0] == ':' || route.MethodName[0] == ':' {
+			continue
+		}
+		if route.TypeOfController == nil {
+			add(nil, "leads to the controller %s, which is not registered", route.ControllerNamespace+route.ControllerName)
+		} else if route.TypeOfController.Method(route.MethodName) == nil {
+			add(nil, "leads to the action %s, which is not a method of %s", route.Action, route.TypeOfController.Type.Name())
+		}
+	}
+	return
+}
+
+// Returns the segments of the tree path of the route, the parameters are replaced by :
+// and the catch all parameters by *
+func routeSegments(route *Route) []string {
+	segments := strings.Split(treePath(route.Method, route.Path), "/")
+	for i, segment := range segments {
+		if segment != "" && (segment[0] == ':' || segment[0] == '*') {
+			segments[i] = segment[:1]
+		}
+	}
+	return segments
+}
+
+// Returns true if the paths match the same requests for some values, and each has a
+// literal segment where the other has a parameter. The router tries the literal segments
+// first, so the route matching depends on the first segment they differ on.
+func routesAmbiguous(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	aLiteral, bLiteral := false, false
+	for i := range a {
+		if a[i] == "*" || b[i] == "*" {
+			return false
+		}
+		aParam, bParam := a[i] == ":", b[i] == ":"
+		switch {
+		case !aParam && !bParam && a[i] != b[i]:
+			return false
+		case aParam && !bParam:
+			bLiteral = true
+		case bParam && !aParam:
+			aLiteral = true
+		}
+	}
+	return aLiteral && bLiteral
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"testing"
+)
+
+func TestRouterAnalyze(t *testing.T) {
+	startFakeBookingApp()
+	router := NewRouter("")
+	router.Routes, _ = parseRoutes(appModule, "routes", "", `
+GET  /hotels/:id          Hotels.Show
+GET  /hotels/:name        Hotels.Index
+GET  /rooms/:id/edit      Hotels.Show
+GET  /rooms/new/:flash    Hotels.Book
+GET  /rooms/new           Hotels.Index
+GET  /hotels/list/all     Hotels.Missing
+*    /:controller/:action :controller.:action
+*    /:controller/:action :controller.:action
+`, false)
+	// The controller of a route may be missing after the controllers are reloaded
+	router.Routes = append(router.Routes[:5], append([]*Route{{Method: "GET", Path: "/missing", Action: "Missing.Index",
+		ControllerName: "missing", MethodName: "index", routesPath: "routes", line: 6}}, router.Routes[5:]...)...)
+
+	expected := []string{
+		"routes:3: GET /hotels/:name is unreachable, the route on line 2 has the same method and path",
+		"routes:5: GET /rooms/new/:flash overlaps the route on line 4 (GET /rooms/:id/edit), neither is more specific",
+		"routes:5: GET /rooms/new/:flash has the parameter flash, which is a view arg set by Revel",
+		"routes:7: GET /missing leads to the controller missing, which is not registered",
+		"routes:7: GET /hotels/list/all leads to the action Hotels.Missing, which is not a method of Hotels",
+	}
+	problems := router.Analyze()
+	if len(problems) != len(expected) {
+		for _, problem := range problems {
+			t.Log(problem)
+		}
+		t.Fatalf("Expected %d problems, got %d", len(expected), len(problems))
+	}
+	for i, problem := range problems {
+		if problem.Error() != expected[i] {
+			t.Errorf("Expected %q, got %q", expected[i], problem.Error())
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -462,6 +463,8 @@ func BenchmarkRouterFilter(b *testing.B) {
 	b.ResetTimer()
 	for i := 0; i < b.N/len(controllers); i++ {
 		for _, c := range controllers {
+			// The request is routed again, not the route kept from the previous iteration
+			c.Request.routed = false
 			RouterFilter(c, NilChain)
 		}
 	}
@@ -477,6 +480,41 @@ func TestOverrideMethodFilter(t *testing.T) {
 	}
 }
 
+func TestReverseQuerySliceFormats(t *testing.T) {
+	ids := []int{3, 1, 2, 4, 5, 6, 7, 8, 9, 10, 11}
+	filter := map[string][]string{"status": {"open", "closed"}}
+	expectedQuery := map[string]string{
+		"indexed":  "ids%5B0%5D=3",
+		"brackets": "ids%5B%5D=3&ids%5B%5D=1",
+		"repeat":   "ids=3&ids=1",
+	}
+	defer func() { SliceParamFormat = "indexed" }()
+	for format, expected := range expectedQuery {
+		SliceParamFormat = format
+		argValues := map[string]string{}
+		Unbind(argValues, "ids", ids)
+		Unbind(argValues, "filter", filter)
+		queryValues := url.Values{}
+		addReverseQueryValues(queryValues, argValues)
+		query := queryValues.Encode()
+		if !strings.Contains(query, expected) {
+			t.Errorf("%s: expected %s in %s", format, expected, query)
+		}
+
+		// Round trip the values through the binder
+		values, _ := url.ParseQuery(query)
+		params := &Params{Values: values}
+		boundIds := Bind(params, "ids", reflect.TypeOf(ids)).Interface().([]int)
+		if !reflect.DeepEqual(boundIds, ids) {
+			t.Errorf("%s: expected %v, got %v", format, ids, boundIds)
+		}
+		boundFilter := Bind(params, "filter", reflect.TypeOf(filter)).Interface().(map[string][]string)
+		if !reflect.DeepEqual(boundFilter, filter) {
+			t.Errorf("%s: expected %v, got %v", format, filter, boundFilter)
+		}
+	}
+}
+
 // Helpers
 
 func eq(t *testing.T, name string, a, b interface{}) bool {
@@ -0,0 +1,177 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+// On startup Revel finds where the application runs (a container, a Kubernetes pod) and
+// the CPU and memory limits of its cgroup. Before the server listens:
+//   - when enabled, GOMAXPROCS is set to the CPU limit, rounded up, unless the GOMAXPROCS
+//     variable is set
+//   - when enabled, the GC memory limit is set to a part of the memory limit, unless
+//     GOMEMLIMIT is set
+//   - the required environment variables and volumes are checked, the application fails
+//     to start when one is missing instead of failing on its first request
+//   - a banner describing the environment is logged
+// The behaviour is configured in app.conf, the runtime settings are left alone by default
+//   runtime.gomaxprocs = true                   # Set GOMAXPROCS from the CPU limit
+//   runtime.memorylimit = 0.9                   # The part of the memory limit for the GC, 0 (the default) to disable
+//   runtime.require.env = DATABASE_URL, REDIS_URL
+//   runtime.require.volumes = /data, /uploads
+//   runtime.banner = true
+func init() {
+	// Before the other hooks, which may connect to the services of the environment
+	OnAppStart(func() {
+		environment := RuntimeEnvironment()
+		applyRuntimeLimits(environment)
+		if missing := missingRuntimeRequirements(); len(missing) > 0 {
+			runtimeLog.Fatal("The environment is missing requirements of the application", "missing", missing)
+		}
+		if Config.BoolDefault("runtime.banner", true) {
+			runtimeLog.Info("Runtime environment", "environment", environment.String(), "gomaxprocs", runtime.GOMAXPROCS(0))
+		}
+	}, 0)
+}
+
+// Environment describes where the application runs
+type Environment struct {
+	Container   bool    // Runs in a container
+	Kubernetes  bool    // Runs in a Kubernetes pod
+	Hostname    string  // The host name, the pod name in Kubernetes
+	Namespace   string  // The Kubernetes namespace, when known
+	CPULimit    float64 // The number of CPUs of the cgroup quota, 0 without a limit
+	MemoryLimit int64   // The memory limit of the cgroup in bytes, 0 without a limit
+	NumCPU      int     // The number of CPUs of the host
+}
+
+// String returns a one line description of the environment
+func (e *Environment) String() string {
+	where := "host"
+	if e.Kubernetes {
+		where = "kubernetes pod"
+		if e.Namespace != "" {
+			where += " in " + e.Namespace
+		}
+	} else if e.Container {
+		where = "container"
+	}
+	description := fmt.Sprintf("%s %s, %d cpus", where, e.Hostname, e.NumCPU)
+	if e.CPULimit > 0 {
+		description += fmt.Sprintf(", cpu limit %g", e.CPULimit)
+	}
+	if e.MemoryLimit > 0 {
+		description += fmt.Sprintf(", memory limit %dMiB", e.MemoryLimit>>20)
+	}
+	return description
+}
+
+var runtimeLog = RevelLog.New("section", "runtime")
+
+// The root of the cgroup file system, and the files marking a container
+var (
+	cgroupRoot     = "/sys/fs/cgroup"
+	containerFiles = []string{"/.dockerenv", "/run/.containerenv"}
+	serviceAccount = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+)
+
+// RuntimeEnvironment returns where the application runs and the limits of its cgroup
+func RuntimeEnvironment() *Environment {
+	environment := &Environment{NumCPU: runtime.NumCPU()}
+	environment.Hostname, _ = os.Hostname()
+	environment.Kubernetes = os.Getenv("KUBERNETES_SERVICE_HOST") != ""
+	environment.Container = environment.Kubernetes
+	for _, file := range containerFiles {
+		if _, err := os.Stat(file); err == nil {
+			environment.Container = true
+		}
+	}
+	if namespace, err := ioutil.ReadFile(serviceAccount); err == nil {
+		environment.Namespace = strings.TrimSpace(string(namespace))
+	}
+	environment.CPULimit, environment.MemoryLimit = cgroupLimits(cgroupRoot)
+	return environment
+}
+
+// Returns the CPU and memory limits of the cgroup (v2, or v1), 0 when unlimited
+func cgroupLimits(root string) (cpu float64, memory int64) {
+	read := func(name string) string {
+		content, err := ioutil.ReadFile(filepath.Join(root, name))
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(content))
+	}
+
+	// cgroup v2, cpu.max is "quota period" or "max period"
+	if fields := strings.Fields(read("cpu.max")); len(fields) == 2 && fields[0] != "max" {
+		quota, err1 := strconv.ParseFloat(fields[0], 64)
+		period, err2 := strconv.ParseFloat(fields[1], 64)
+		if err1 == nil && err2 == nil && period > 0 {
+			cpu = quota / period
+		}
+	} else if quota, err := strconv.ParseFloat(read("cpu/cpu.cfs_quota_us"), 64); err == nil && quota > 0 {
+		if period, err := strconv.ParseFloat(read("cpu/cpu.cfs_period_us"), 64); err == nil && period > 0 {
+			cpu = quota / period
+		}
+	}
+
+	limit := read("memory.max")
+	if limit == "" {
+		limit = read("memory/memory.limit_in_bytes")
+	}
+	// cgroup v1 reports no limit as a huge number
+	if value, err := strconv.ParseInt(limit, 10, 64); err == nil && value > 0 && value < 1<<62 {
+		memory = value
+	}
+	return
+}
+
+// Sets GOMAXPROCS and the GC memory limit from the limits of the environment, when enabled
+// in app.conf
+func applyRuntimeLimits(environment *Environment) {
+	if environment.CPULimit > 0 && os.Getenv("GOMAXPROCS") == "" && Config.BoolDefault("runtime.gomaxprocs", false) {
+		procs := int(math.Ceil(environment.CPULimit))
+		if procs < runtime.NumCPU() {
+			runtime.GOMAXPROCS(procs)
+		}
+	}
+	part := Config.StringDefault("runtime.memorylimit", "0")
+	if environment.MemoryLimit > 0 && os.Getenv("GOMEMLIMIT") == "" {
+		if fraction, err := strconv.ParseFloat(part, 64); err != nil {
+			runtimeLog.Error("Invalid runtime.memorylimit, expected a number between 0 and 1", "value", part)
+		} else if fraction > 0 && fraction <= 1 {
+			debug.SetMemoryLimit(int64(float64(environment.MemoryLimit) * fraction))
+		}
+	}
+}
+
+// Returns the environment variables and volumes listed in runtime.require.env and
+// runtime.require.volumes which are missing
+func missingRuntimeRequirements() (missing []string) {
+	for _, name := range strings.Split(Config.StringDefault("runtime.require.env", ""), ",") {
+		if name = strings.TrimSpace(name); name != "" && os.Getenv(name) == "" {
+			missing = append(missing, "environment variable "+name)
+		}
+	}
+	for _, path := range strings.Split(Config.StringDefault("runtime.require.volumes", ""), ",") {
+		if path = strings.TrimSpace(path); path == "" {
+			continue
+		}
+		if info, err := os.Stat(path); err != nil || !info.IsDir() {
+			missing = append(missing, "volume "+path)
+		}
+	}
+	return
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCgroupLimits(t *testing.T) {
+	write := func(root, name, content string) {
+		os.MkdirAll(filepath.Dir(filepath.Join(root, name)), 0755)
+		if err := ioutil.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	v2 := t.TempDir()
+	write(v2, "cpu.max", "150000 100000\n")
+	write(v2, "memory.max", "536870912\n")
+	if cpu, memory := cgroupLimits(v2); cpu != 1.5 || memory != 512<<20 {
+		t.Errorf("Unexpected cgroup v2 limits %g %d", cpu, memory)
+	}
+
+	v1 := t.TempDir()
+	write(v1, "cpu/cpu.cfs_quota_us", "-1")
+	write(v1, "cpu/cpu.cfs_period_us", "100000")
+	write(v1, "memory/memory.limit_in_bytes", "9223372036854771712")
+	if cpu, memory := cgroupLimits(v1); cpu != 0 || memory != 0 {
+		t.Errorf("Expected no cgroup v1 limits, got %g %d", cpu, memory)
+	}
+
+	write(v2, "cpu.max", "max 100000")
+	if cpu, _ := cgroupLimits(v2); cpu != 0 {
+		t.Errorf("Expected no CPU limit, got %g", cpu)
+	}
+}
+
+func TestMissingRuntimeRequirements(t *testing.T) {
+	volume := t.TempDir()
+	os.Setenv("REVEL_TEST_PRESENT", "1")
+	Config.SetOption("runtime.require.env", "REVEL_TEST_PRESENT, REVEL_TEST_MISSING")
+	Config.SetOption("runtime.require.volumes", volume+", "+filepath.Join(volume, "missing"))
+	defer func() {
+		os.Unsetenv("REVEL_TEST_PRESENT")
+		Config.SetOption("runtime.require.env", "")
+		Config.SetOption("runtime.require.volumes", "")
+	}()
+
+	expected := []string{"environment variable REVEL_TEST_MISSING", "volume " + filepath.Join(volume, "missing")}
+	if missing := missingRuntimeRequirements(); !reflect.DeepEqual(missing, expected) {
+		t.Errorf("Unexpected missing requirements %v", missing)
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// The SEO annotations declare how search engines index the pages of an action
+//   // @Robots("noindex, nofollow")
+//   // @Canonical                     The reverse route of the action is the canonical URL
+//   // @Canonical("Hotels.Show")      The reverse route of another action, with the same parameters
+// On a controller they apply to all its actions. The SEOFilter adds the metadata of the
+// action to the view args as "seo" and layouts render it in their head
+//   {{seo .}}
+// which writes the robots meta tag and the canonical link. The behaviour is configured
+// in app.conf
+//   seo.robots = noindex                        # The default robots directives (a staging server)
+//   seo.header = true                           # Send the directives as an X-Robots-Tag header
+//   seo.canonical.host = https://www.example.com # Defaults to http.addr and http.port
+// The Host header of the request is not used since the clients choose it, without a
+// canonical host or a http.addr naming the server the canonical link is not written.
+func init() {
+	RegisterAnnotationProcessor("Robots", func(ct *ControllerType, mt *MethodType, annotation *FunctionalAnnotation) error {
+		robots := strings.Join(annotation.GetStrings("directives", 0), ", ")
+		if robots == "" {
+			return fmt.Errorf("@Robots requires directives")
+		}
+		for _, settings := range seoSettingsOf(ct, mt) {
+			settings.robots = robots
+		}
+		return nil
+	})
+	RegisterAnnotationProcessor("Canonical", func(ct *ControllerType, mt *MethodType, annotation *FunctionalAnnotation) error {
+		action, _ := annotation.Value("action", 0)
+		if action != "" && !strings.Contains(action, ".") {
+			return fmt.Errorf("@Canonical action %s must be in the form of Controller.Method", action)
+		}
+		for _, settings := range seoSettingsOf(ct, mt) {
+			settings.canonical, settings.canonicalAction = true, action
+		}
+		return nil
+	})
+	RegisterAnnotationSchema("Robots", "directives")
+	RegisterAnnotationSchema("Canonical", "action")
+	OnAppStart(func() {
+		seoConfig.robots = Config.StringDefault("seo.robots", "")
+		seoConfig.header = Config.BoolDefault("seo.header", false)
+		seoConfig.canonicalHost = Config.StringDefault("seo.canonical.host", serverCanonicalHost())
+	})
+}
+
+// The settings of the SEOFilter, loaded when the application starts
+var seoConfig struct {
+	robots        string
+	header        bool
+	canonicalHost string
+}
+
+// SEOMetadata is the search engine metadata of a page
+type SEOMetadata struct {
+	Robots    string // The robots directives, like "noindex, nofollow"
+	Canonical string // The absolute canonical URL of the page
+}
+
+// The SEO settings of an action
+type seoSettings struct {
+	robots          string
+	canonical       bool
+	canonicalAction string // The action the canonical URL is reversed from, the action itself when empty
+}
+
+var seoLog = RevelLog.New("section", "seo")
+
+// Returns the SEO settings of the method, or of all the methods of the controller when
+// the method is nil
+func seoSettingsOf(ct *ControllerType, mt *MethodType) (settings []*seoSettings) {
+	methods := ct.Methods
+	if mt != nil {
+		methods = []*MethodType{mt}
+	}
+	for _, method := range methods {
+		if method.seo == nil {
+			method.seo = &seoSettings{}
+		}
+		settings = append(settings, method.seo)
+	}
+	return
+}
+
+// SEOFilter adds the SEO metadata of the action to the view args, and sends the robots
+// directives as an X-Robots-Tag header when seo.header is set
+func SEOFilter(c *Controller, fc []Filter) {
+	metadata := &SEOMetadata{Robots: seoConfig.robots}
+	if c.MethodType != nil && c.MethodType.seo != nil {
+		settings := c.MethodType.seo
+		if settings.robots != "" {
+			metadata.Robots = settings.robots
+		}
+		if settings.canonical {
+			metadata.Canonical = canonicalURL(c, settings.canonicalAction)
+		}
+	}
+	c.ViewArgs["seo"] = metadata
+	if metadata.Robots != "" && seoConfig.header {
+		c.Response.Out.internalHeader.Set("X-Robots-Tag", metadata.Robots)
+	}
+	fc[0](c, fc[1:])
+}
+
+// Returns the absolute URL of the reverse route of the action, the arguments of the
+// action are taken from the parameters of the request
+func canonicalURL(c *Controller, action string) string {
+	mt := c.MethodType
+	if action == "" {
+		action = c.Action
+	} else {
+		pathData, found := splitActionPath(nil, action, true)
+		if !found || pathData.TypeOfController == nil {
+			seoLog.Error("Canonical action not found", "action", action)
+			return ""
+		}
+		if mt = pathData.TypeOfController.Method(pathData.MethodName); mt == nil {
+			seoLog.Error("Canonical action not found", "action", action)
+			return ""
+		}
+	}
+
+	args := map[string]string{}
+	for _, arg := range mt.Args {
+		if value := c.Params.Get(arg.Name); value != "" {
+			args[arg.Name] = value
+		}
+	}
+	definition := MainRouter.Reverse(action, args)
+	if definition == nil || seoConfig.canonicalHost == "" {
+		return ""
+	}
+	return strings.TrimSuffix(seoConfig.canonicalHost, "/") + definition.URL
+}
+
+// Returns the URL of the server from http.addr and http.port, or an empty string when
+// http.addr is not set or is an address listening on all the interfaces
+func serverCanonicalHost() string {
+	if HTTPAddr == "" || strings.Contains(HTTPAddr, "/") {
+		return ""
+	}
+	if ip := net.ParseIP(HTTPAddr); ip != nil && ip.IsUnspecified() {
+		return ""
+	}
+	scheme, defaultPort := "http://", 80
+	if HTTPSsl {
+		scheme, defaultPort = "https://", 443
+	}
+	if HTTPPort == defaultPort {
+		return scheme + HTTPAddr
+	}
+	return scheme + net.JoinHostPort(HTTPAddr, strconv.Itoa(HTTPPort))
+}
+
+// SEOTags returns the robots meta tag and the canonical link of the SEO metadata in the
+// view args, it is the seo template function
+func SEOTags(viewArgs map[string]interface{}) template.HTML {
+	metadata, ok := viewArgs["seo"].(*SEOMetadata)
+	if !ok {
+		return ""
+	}
+	tags := ""
+	if metadata.Robots != "" {
+		tags += `<meta name="robots" content="` + html.EscapeString(metadata.Robots) + `">`
+	}
+	if metadata.Canonical != "" {
+		tags += `<link rel="canonical" href="` + html.EscapeString(metadata.Canonical) + `">`
+	}
+	return template.HTML(tags)
+}
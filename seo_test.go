@@ -0,0 +1,118 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+type SEOController struct {
+	*Controller
+}
+
+func (c SEOController) Show(id int) Result {
+	return nil
+}
+
+func (c SEOController) Short(id int) Result {
+	return nil
+}
+
+func TestSEOFilter(t *testing.T) {
+	startFakeBookingApp()
+	robots, _ := ParseAnnotation(`@Robots("noindex,nofollow")`)
+	canonical, _ := ParseAnnotation(`@Canonical("SEOController.Show")`)
+	index, _ := ParseAnnotation(`@Robots("index")`)
+	RegisterController((*SEOController)(nil), []*MethodType{
+		{Name: "Show", Annotations: FunctionalAnnotations{index}, Args: []*MethodArg{{Name: "id", Type: reflect.TypeOf((*int)(nil))}}},
+		{Name: "Short", Annotations: FunctionalAnnotations{canonical}, Args: []*MethodArg{{Name: "id", Type: reflect.TypeOf((*int)(nil))}}},
+	}, robots)
+
+	router := MainRouter
+	MainRouter = NewRouter("")
+	MainRouter.Routes, _ = parseRoutes(appModule, "", "", `
+GET  /rooms/:id   SEOController.Show
+GET  /r/:id       SEOController.Short
+`, false)
+	if err := MainRouter.updateTree(); err != nil {
+		t.Fatal(err)
+	}
+	seoConfig.header, seoConfig.canonicalHost = true, "https://www.example.com/"
+	defer func() {
+		MainRouter = router
+		seoConfig.header, seoConfig.canonicalHost = false, ""
+	}()
+
+	request := func(action string) (*Controller, *httptest.ResponseRecorder) {
+		req, _ := http.NewRequest("GET", "/r/3?utm_source=mail", nil)
+		resp := httptest.NewRecorder()
+		c := NewTestController(resp, req)
+		if err := c.SetAction("SEOController", action); err != nil {
+			t.Fatal(err)
+		}
+		c.Params = &Params{Values: url.Values{"id": {"3"}, "utm_source": {"mail"}}}
+		SEOFilter(c, NilChain)
+		return c, resp
+	}
+
+	c, resp := request("Short")
+	metadata := c.ViewArgs["seo"].(*SEOMetadata)
+	if metadata.Robots != "noindex, nofollow" || metadata.Canonical != "https://www.example.com/rooms/3" {
+		t.Errorf("Unexpected metadata %+v", metadata)
+	}
+	if resp.Header().Get("X-Robots-Tag") != "noindex, nofollow" {
+		t.Errorf("Expected the X-Robots-Tag header, got %v", resp.Header())
+	}
+	expected := `<meta name="robots" content="noindex, nofollow"><link rel="canonical" href="https://www.example.com/rooms/3">`
+	if tags := SEOTags(c.ViewArgs); string(tags) != expected {
+		t.Errorf("Unexpected tags %s", tags)
+	}
+
+	c, _ = request("Show")
+	if metadata := c.ViewArgs["seo"].(*SEOMetadata); metadata.Robots != "index" || metadata.Canonical != "" {
+		t.Errorf("Expected the action annotation to replace the controller one, got %+v", metadata)
+	}
+
+	seoConfig.canonicalHost = ""
+	c, _ = request("Short")
+	if metadata := c.ViewArgs["seo"].(*SEOMetadata); metadata.Canonical != "" {
+		t.Errorf("Expected no canonical URL from the Host of the request, got %s", metadata.Canonical)
+	}
+
+	req, _ := http.NewRequest("GET", "/missing", nil)
+	c = NewTestController(httptest.NewRecorder(), req)
+	SEOFilter(c, NilChain)
+	if metadata := c.ViewArgs["seo"].(*SEOMetadata); metadata.Robots != "" || metadata.Canonical != "" {
+		t.Errorf("Expected the default metadata without an action, got %+v", metadata)
+	}
+}
+
+func TestSEOCanonicalHost(t *testing.T) {
+	addr, port, ssl := HTTPAddr, HTTPPort, HTTPSsl
+	defer func() { HTTPAddr, HTTPPort, HTTPSsl = addr, port, ssl }()
+
+	for _, test := range []struct {
+		addr     string
+		port     int
+		ssl      bool
+		expected string
+	}{
+		{"", 9000, false, ""},
+		{"0.0.0.0", 9000, false, ""},
+		{"::", 443, true, ""},
+		{"www.example.com", 80, false, "http://www.example.com"},
+		{"www.example.com", 443, true, "https://www.example.com"},
+		{"10.0.0.1", 9000, false, "http://10.0.0.1:9000"},
+	} {
+		HTTPAddr, HTTPPort, HTTPSsl = test.addr, test.port, test.ssl
+		if host := serverCanonicalHost(); host != test.expected {
+			t.Errorf("Expected the host of %s:%d to be %q, got %q", test.addr, test.port, test.expected, host)
+		}
+	}
+}
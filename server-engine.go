@@ -24,19 +24,21 @@ const (
 )
 const (
 	/* HTTP Engine Type Values Starts at 1000 */
-	HTTP_QUERY          = ENGINE_PARAMETERS
-	HTTP_PATH           = ENGINE_PATH
-	HTTP_BODY           = iota + 1000
-	HTTP_FORM           = iota + 1000
-	HTTP_MULTIPART_FORM = iota + 1000
-	HTTP_METHOD         = iota + 1000
-	HTTP_REQUEST_URI    = iota + 1000
-	HTTP_REMOTE_ADDR    = iota + 1000
-	HTTP_HOST           = iota + 1000
-	HTTP_URL            = iota + 1000
-	HTTP_SERVER_HEADER  = iota + 1000
-	HTTP_STREAM_WRITER  = iota + 1000
-	HTTP_WRITER         = ENGINE_WRITER
+	HTTP_QUERY            = ENGINE_PARAMETERS
+	HTTP_PATH             = ENGINE_PATH
+	HTTP_BODY             = iota + 1000
+	HTTP_FORM             = iota + 1000
+	HTTP_MULTIPART_FORM   = iota + 1000
+	HTTP_METHOD           = iota + 1000
+	HTTP_REQUEST_URI      = iota + 1000
+	HTTP_REMOTE_ADDR      = iota + 1000
+	HTTP_HOST             = iota + 1000
+	HTTP_URL              = iota + 1000
+	HTTP_SERVER_HEADER    = iota + 1000
+	HTTP_STREAM_WRITER    = iota + 1000
+	HTTP_EARLY_HINTS      = iota + 1000 // Set with the []string Link headers to send a 103 Early Hints (if implemented)
+	HTTP_MULTIPART_MEMORY = iota + 1000 // Set with the int64 bytes of a multipart form held in memory (if implemented)
+	HTTP_WRITER           = ENGINE_WRITER
 )
 
 type (
@@ -141,8 +143,16 @@ func handleInternal(ctx ServerContext) {
 	c.ClientIP = clientIP
 	c.Log = AppLog.New("ip", clientIP,
 		"path", req.GetPath(), "method", req.Method)
+	resp.Timing = nil
+	if serverTimingEnabled {
+		resp.Timing = NewServerTiming()
+	}
 	// Call the first filter, this will process the request
 	Filters[0](c, Filters[1:])
+	if resp.Timing != nil {
+		// The time spent in the filters excluding the routing and the action
+		resp.Timing.Add("filters", time.Since(resp.Timing.start)-resp.Timing.Duration("routing")-resp.Timing.Duration("action"))
+	}
 	if c.Result != nil {
 		c.Result.Apply(req, resp)
 	} else if c.Response.Status != 0 {
@@ -1,12 +1,18 @@
 package revel
 
 import (
+	"context"
+	"encoding/binary"
 	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"golang.org/x/net/websocket"
 	"io"
+	"io/ioutil"
 	"mime/multipart"
 	"net/url"
 	"strconv"
@@ -22,12 +28,14 @@ type GoHttpServer struct {
 	Server               *http.Server
 	ServerInit           *EngineInit
 	MaxMultipartSize     int64
+	MaxRequestSize       int64 // The limit of the request bodies set by http.maxrequestsize, 0 for none
 	goContextStack       *SimpleLockStack
 	goMultipartFormStack *SimpleLockStack
 }
 
 func (g *GoHttpServer) Init(init *EngineInit) {
-	g.MaxMultipartSize = int64(Config.IntDefault("server.request.max.multipart.filesize", 32)) << 20 /* 32 MB */
+	g.MaxMultipartSize = ConfigSizeDefault("server.request.max.multipart.filesize", 32<<20, 1<<20) /* 32 MB, a number is in MB */
+	g.MaxRequestSize = ConfigSizeDefault("http.maxrequestsize", 0, 1)
 	g.goContextStack = NewStackLock(Config.IntDefault("server.context.stack", 100),
 		Config.IntDefault("server.context.maxstack", 200),
 		func() interface{} {
@@ -42,8 +50,8 @@ func (g *GoHttpServer) Init(init *EngineInit) {
 		Handler: http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
 			g.Handle(writer, request)
 		}),
-		ReadTimeout:  time.Duration(Config.IntDefault("http.timeout.read", 0)) * time.Second,
-		WriteTimeout: time.Duration(Config.IntDefault("http.timeout.write", 0)) * time.Second,
+		ReadTimeout:  ConfigDurationDefault("http.timeout.read", 0, time.Second),
+		WriteTimeout: ConfigDurationDefault("http.timeout.write", 0, time.Second),
 	}
 	// Server already initialized
 
@@ -55,27 +63,59 @@ func (g *GoHttpServer) Start() {
 		time.Sleep(100 * time.Millisecond)
 		serverLogger.Debugf("Start: Listening on %s...", g.Server.Addr)
 	}()
+	var shutdown chan struct{}
+	if Config.BoolDefault("server.shutdown.graceful", false) {
+		shutdown = g.shutdownOnSignal()
+	}
+	var err error
 	if HTTPSsl {
 		if g.ServerInit.Network != "tcp" {
 			// This limitation is just to reduce complexity, since it is standard
 			// to terminate SSL upstream when using unix domain sockets.
 			serverLogger.Fatal("SSL is only supported for TCP sockets. Specify a port to listen on.")
 		}
-		serverLogger.Fatal("Failed to listen:", "error",
-			g.Server.ListenAndServeTLS(HTTPSslCert, HTTPSslKey))
+		err = g.Server.ListenAndServeTLS(HTTPSslCert, HTTPSslKey)
 	} else {
-		listener, err := net.Listen(g.ServerInit.Network, g.Server.Addr)
-		if err != nil {
+		var listener net.Listener
+		if listener, err = net.Listen(g.ServerInit.Network, g.Server.Addr); err != nil {
 			serverLogger.Fatal("Failed to listen:", "error", err)
 		}
-		serverLogger.Fatal("Failed to serve:", "error", g.Server.Serve(listener))
+		err = g.Server.Serve(listener)
+	}
+	if err == http.ErrServerClosed && shutdown != nil {
+		// Wait for the graceful shutdown to complete
+		<-shutdown
+		return
 	}
+	serverLogger.Fatal("Failed to serve:", "error", err)
+}
 
+// Shuts the server down on SIGINT or SIGTERM, the requests in progress are completed
+// (up to server.shutdown.timeout) then the websockets are closed. The returned channel
+// is closed once the shutdown completes.
+func (g *GoHttpServer) shutdownOnSignal() chan struct{} {
+	done := make(chan struct{})
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		received := <-signals
+		signal.Stop(signals)
+		serverLogger.Info("Shutting down the server", "signal", received)
+		ctx, cancel := context.WithTimeout(context.Background(), ConfigDurationDefault("server.shutdown.timeout", 30*time.Second, time.Second))
+		defer cancel()
+		if err := g.Server.Shutdown(ctx); err != nil {
+			serverLogger.Error("Failed to complete the requests in progress", "error", err)
+		}
+		// The websockets are hijacked connections which the server does not wait for
+		closeWebSocketsOnShutdown()
+		close(done)
+	}()
+	return done
 }
 
 func (g *GoHttpServer) Handle(w http.ResponseWriter, r *http.Request) {
-	if maxRequestSize := int64(Config.IntDefault("http.maxrequestsize", 0)); maxRequestSize > 0 {
-		r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	if g.MaxRequestSize > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, g.MaxRequestSize)
 	}
 
 	upgrade := r.Header.Get("Upgrade")
@@ -137,6 +177,7 @@ type (
 		ParsedForm      *GoMultipartForm
 		Goheader        *GoHeader
 		Engine          *GoHttpServer
+		MultipartMemory int64 // The bytes of the multipart form held in memory, the Engine.MaxMultipartSize when 0
 	}
 
 	GoResponse struct {
@@ -221,8 +262,29 @@ func (r *GoRequest) Get(key int) (value interface{}, err error) {
 
 	return
 }
-func (r *GoRequest) Set(key int, value interface{}) bool {
-	return false
+func (r *GoRequest) Set(key int, value interface{}) (set bool) {
+	switch key {
+	case HTTP_BODY:
+		// Replace the body, used when the body has been decoded (see DecompressFilter)
+		if body, ok := value.(io.Reader); ok {
+			if closer, ok := body.(io.ReadCloser); ok {
+				r.Original.Body = closer
+			} else {
+				r.Original.Body = ioutil.NopCloser(body)
+			}
+			r.Original.ContentLength = -1
+			if sized, ok := body.(interface {
+				Len() int
+			}); ok {
+				r.Original.ContentLength = int64(sized.Len())
+			}
+			r.Original.Header.Del("Content-Encoding")
+			set = true
+		}
+	case HTTP_MULTIPART_MEMORY:
+		r.MultipartMemory, set = value.(int64)
+	}
+	return
 }
 
 func (r *GoRequest) GetForm() (url.Values, error) {
@@ -237,7 +299,11 @@ func (r *GoRequest) GetForm() (url.Values, error) {
 }
 func (r *GoRequest) GetMultipartForm() (ServerMultipartForm, error) {
 	if !r.MultiFormParsed {
-		if e := r.Original.ParseMultipartForm(r.Engine.MaxMultipartSize); e != nil {
+		memory := r.Engine.MaxMultipartSize
+		if r.MultipartMemory > 0 {
+			memory = r.MultipartMemory
+		}
+		if e := r.Original.ParseMultipartForm(memory); e != nil {
 			return nil, e
 		}
 		r.ParsedForm = r.Engine.goMultipartFormStack.Pop().(*GoMultipartForm)
@@ -264,6 +330,7 @@ func (r *GoRequest) Destroy() {
 	r.FormParsed = false
 	r.MultiFormParsed = false
 	r.ParsedForm = nil
+	r.MultipartMemory = 0
 }
 func (r *GoResponse) Get(key int) (value interface{}, err error) {
 	switch key {
@@ -286,6 +353,16 @@ func (r *GoResponse) Set(key int, value interface{}) (set bool) {
 	case HTTP_WRITER:
 		r.SetWriter(value.(io.Writer))
 		set = true
+	case HTTP_EARLY_HINTS:
+		if !earlyHintsSupported {
+			return false
+		}
+		// The links are sent in the 103, and kept in the headers of the final response
+		for _, link := range value.([]string) {
+			r.Original.Header().Add("Link", link)
+		}
+		r.Original.WriteHeader(http.StatusEarlyHints)
+		set = true
 	}
 	return
 }
@@ -427,3 +504,14 @@ func (g *GoWebSocket) MessageSendJSON(v interface{}) error {
 func (g *GoWebSocket) MessageReceiveJSON(v interface{}) error {
 	return websocket.Message.Receive(g.Conn, v)
 }
+
+// MessageClose sends a close frame with the code and reason, the client answers with a
+// close frame which ends the receive calls of the action
+func (g *GoWebSocket) MessageClose(code int, reason string) error {
+	closeFrame := websocket.Codec{Marshal: func(v interface{}) ([]byte, byte, error) {
+		payload := make([]byte, 2, 2+len(reason))
+		binary.BigEndian.PutUint16(payload, uint16(code))
+		return append(payload, reason...), websocket.CloseFrame, nil
+	}}
+	return closeFrame.Send(g.Conn, nil)
+}
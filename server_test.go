@@ -116,6 +116,18 @@ func TestOnAppStart(t *testing.T) {
 	}
 }
 
+// Test that the request size limit is loaded once, when the server is initialized
+func TestMaxRequestSize(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOption("http.maxrequestsize", "1KB")
+	defer Config.SetOption("http.maxrequestsize", "0")
+	server := &GoHttpServer{}
+	server.Init(&EngineInit{})
+	if server.MaxRequestSize != 1024 {
+		t.Errorf("Expected the limit of the request bodies to be 1024, got %d", server.MaxRequestSize)
+	}
+}
+
 var (
 	showRequest, _      = http.NewRequest("GET", "/hotels/3", nil)
 	staticRequest, _    = http.NewRequest("GET", "/public/js/sessvars.js", nil)
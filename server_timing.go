@@ -0,0 +1,94 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ServerTiming collects the durations of the request phases, they are sent to
+// the client in the Server-Timing header when `server.timing=true`.
+// The framework records routing, filters, action and render (for templates),
+// an application can add its own using
+//   c.Response.Timing.Add("db", duration, "Load users")
+// When server timing is disabled Response.Timing is nil, which is safe to call.
+type ServerTiming struct {
+	start   time.Time
+	entries []serverTimingEntry
+}
+
+type serverTimingEntry struct {
+	name        string
+	description string
+	duration    time.Duration
+}
+
+var serverTimingEnabled bool
+
+func init() {
+	OnAppStart(func() {
+		serverTimingEnabled = Config.BoolDefault("server.timing", false)
+	})
+}
+
+// NewServerTiming returns a ServerTiming starting now
+func NewServerTiming() *ServerTiming {
+	return &ServerTiming{start: time.Now()}
+}
+
+// Add records the duration of the named phase
+func (st *ServerTiming) Add(name string, duration time.Duration, description ...string) {
+	if st == nil {
+		return
+	}
+	entry := serverTimingEntry{name: name, duration: duration}
+	if len(description) > 0 {
+		entry.description = description[0]
+	}
+	st.entries = append(st.entries, entry)
+}
+
+// Start begins timing the named phase, call the returned function to record it
+func (st *ServerTiming) Start(name string) func() {
+	if st == nil {
+		return func() {}
+	}
+	start := time.Now()
+	return func() {
+		st.Add(name, time.Since(start))
+	}
+}
+
+// Duration returns the total duration of the named phase
+func (st *ServerTiming) Duration(name string) (duration time.Duration) {
+	if st == nil {
+		return
+	}
+	for _, entry := range st.entries {
+		if entry.name == name {
+			duration += entry.duration
+		}
+	}
+	return
+}
+
+// String returns the value of the Server-Timing header, the total is the time
+// elapsed since the request started
+func (st *ServerTiming) String() string {
+	if st == nil {
+		return ""
+	}
+	values := make([]string, 0, len(st.entries)+1)
+	for _, entry := range append(st.entries, serverTimingEntry{name: "total", duration: time.Since(st.start)}) {
+		value := entry.name + ";dur=" + strconv.FormatFloat(entry.duration.Seconds()*1000, 'f', 2, 64)
+		if entry.description != "" {
+			value += ";desc=" + strconv.Quote(entry.description)
+		}
+		values = append(values, value)
+	}
+	return strings.Join(values, ", ")
+}
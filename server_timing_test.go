@@ -0,0 +1,46 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServerTimingHeader(t *testing.T) {
+	timing := NewServerTiming()
+	timing.Add("routing", 1500*time.Microsecond)
+	timing.Add("db", 2*time.Millisecond, "Load hotels")
+	header := timing.String()
+	if !strings.HasPrefix(header, `routing;dur=1.50, db;dur=2.00;desc="Load hotels", total;dur=`) {
+		t.Errorf("Unexpected header %s", header)
+	}
+
+	// Disabled timing is nil, which must be safe to use
+	var disabled *ServerTiming
+	disabled.Add("routing", time.Second)
+	disabled.Start("action")()
+	if disabled.String() != "" || disabled.Duration("routing") != 0 {
+		t.Errorf("Expected nil timing to be empty")
+	}
+}
+
+func TestServerTimingRender(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOption("results.chunked", "false")
+	resp := httptest.NewRecorder()
+	c := NewTestController(resp, showRequest)
+	if err := c.SetAction("Hotels", "Show"); err != nil {
+		t.Errorf("SetAction failed: %s", err)
+	}
+	c.Response.Timing = NewServerTiming()
+	Hotels{c}.Show(3).Apply(c.Request, c.Response)
+	header := resp.Header().Get("Server-Timing")
+	if !strings.Contains(header, "render;dur=") || !strings.Contains(header, "total;dur=") {
+		t.Errorf("Expected render and total timings, got %s", header)
+	}
+}
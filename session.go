@@ -7,9 +7,10 @@ package revel
 import (
 	"crypto/rand"
 	"encoding/hex"
-	"fmt"
+	"errors"
 	"net/http"
 	"net/url"
+	"reflect"
 	"strconv"
 	"strings"
 	"time"
@@ -23,16 +24,70 @@ type Session map[string]string
 const (
 	SessionIDKey = "_ID"
 	TimestampKey = "_TS"
+	// ActivityKey is the Unix time the session cookie was issued, when session.idle.timeout
+	// is set
+	ActivityKey = "_AT"
 
 	sessionKeyName = "session"
 )
 
+// SessionStore keeps the sessions on the server by their ID, the session cookie has the ID
+// of the session only. The session module has a Redis store and a database/sql store
+//   import _ "github.com/revel/revel/session"
+//   session.store = redis
+//   session.redis.host = localhost:6379
+// The sessions stay in the store for session.expires, or a day for the sessions which end
+// when the browser is closed.
+type SessionStore interface {
+	// Get returns the session of the ID, ErrSessionNotFound if it does not exist or has expired
+	Get(id string) (Session, error)
+	// Set stores the session until it expires
+	Set(id string, session Session, expires time.Duration) error
+	// Destroy removes the session
+	Destroy(id string) error
+	// Touch extends the expiration of the session
+	Touch(id string, expires time.Duration) error
+}
+
+// SessionRegenerator is a SessionStore which replaces the ID of a session atomically, the
+// session is stored under the new ID and the old ID is destroyed
+type SessionRegenerator interface {
+	Regenerate(oldID, id string, session Session, expires time.Duration) error
+}
+
+// SessionStorage keeps the sessions on the server when set, the sessions are in the signed
+// session cookie otherwise (the default)
+var SessionStorage SessionStore
+
+// ErrSessionNotFound is returned by a SessionStore for a session which does not exist
+var ErrSessionNotFound = errors.New("session not found")
+
+// DestroySession removes the session of the ID from the SessionStorage, its user is logged out
+// on their next request (a server side revocation)
+func DestroySession(id string) error {
+	if SessionStorage == nil {
+		return errors.New("revel: the sessions are not kept by a SessionStorage")
+	}
+	return SessionStorage.Destroy(id)
+}
+
 // expireAfterDuration is the time to live, in seconds, of a session cookie.
 // It may be specified in config as "session.expires". Values greater than 0
 // set a persistent cookie with a time to live as specified, and the value 0
 // sets a session cookie.
 var expireAfterDuration time.Duration
 
+// The session expires when there was no request for the idle timeout as well, when set
+//   session.idle.timeout = 30m
+// The session cookie is issued again by the session filter when the session changed, or when
+// its remaining lifetime (or idle time) is below the threshold, half of the lifetime (or of
+// the idle timeout) by default
+//   session.refresh.threshold = 10m
+var (
+	sessionIdleTimeout      time.Duration
+	sessionRefreshThreshold time.Duration
+)
+
 func init() {
 	// Set expireAfterDuration, default to 30 days if no value in config
 	OnAppStart(func() {
@@ -41,9 +96,12 @@ func init() {
 			expireAfterDuration = 30 * 24 * time.Hour
 		} else if expiresString == sessionKeyName {
 			expireAfterDuration = 0
-		} else if expireAfterDuration, err = time.ParseDuration(expiresString); err != nil {
-			panic(fmt.Errorf("session.expires invalid: %s", err))
+		} else if expireAfterDuration, err = ParseDuration(expiresString, time.Second); err != nil {
+			err.(*LiteralError).Key = "session.expires"
+			panic(err)
 		}
+		sessionIdleTimeout = ConfigDurationDefault("session.idle.timeout", 0, time.Second)
+		sessionRefreshThreshold = ConfigDurationDefault("session.refresh.threshold", 0, time.Second)
 	})
 }
 
@@ -63,6 +121,15 @@ func (s Session) ID() string {
 	return s[SessionIDKey]
 }
 
+// Regenerate replaces the ID of the session by a new one and returns it, the values of the
+// session are kept. It is called after a login or a change of privileges, so an ID known
+// before is not the ID of the logged in session. The session kept by the SessionStorage
+// under the old ID is destroyed when the new one is stored.
+func (s Session) Regenerate() string {
+	delete(s, SessionIDKey)
+	return s.ID()
+}
+
 // getExpiration return a time.Time with the session's expiration date.
 // If previous session has set to "session", remain it
 func (s Session) getExpiration() time.Time {
@@ -75,9 +142,55 @@ func (s Session) getExpiration() time.Time {
 
 // Cookie returns an http.Cookie containing the signed session.
 func (s Session) Cookie() *http.Cookie {
-	var sessionValue string
 	ts := s.getExpiration()
 	s[TimestampKey] = getSessionExpirationCookie(ts)
+	if sessionIdleTimeout > 0 {
+		s[ActivityKey] = strconv.FormatInt(time.Now().Unix(), 10)
+	}
+	value, err := SessionCookieCodec.Encode(s)
+	if err != nil {
+		panic(err)
+	}
+
+	cookie := cookieAttributesOf(sessionCookieKind).Cookie("_SESSION", value)
+	cookie.Expires = ts.UTC()
+	cookie.MaxAge = int(expireAfterDuration.Seconds())
+	return cookie
+}
+
+// SessionCodec encodes the session in the value of the session cookie, and decodes it. The
+// session has its expiration (a Unix time, or "session") under the _TS key.
+type SessionCodec interface {
+	Encode(session Session) (string, error)
+	// Decode returns an error for a value which was not encoded by the codec
+	Decode(value string) (Session, error)
+}
+
+// StaleSessionCodec is a SessionCodec which reports the values decoded from a former format
+// or by a former key, the SessionFilter encodes their session again in the cookie
+type StaleSessionCodec interface {
+	SessionCodec
+	// DecodeStale returns the session of the value, stale when it must be encoded again
+	DecodeStale(value string) (session Session, stale bool, err error)
+}
+
+// SessionCookieCodec is the codec of the session cookie, the session is signed by the
+// secret of the application by default, and encrypted when session.encrypt is set. The
+// session module has a JWT codec.
+var SessionCookieCodec SessionCodec = SignedSessionCodec{}
+
+// SignedSessionCodec encodes the session in a key value cookie value signed by Sign
+type SignedSessionCodec struct{}
+
+// Encode returns the signed key value cookie value of the session
+func (SignedSessionCodec) Encode(s Session) (string, error) {
+	sessionData := encodeSessionValue(s)
+	return Sign(sessionData) + "-" + sessionData, nil
+}
+
+// Returns the key value cookie value of the session
+func encodeSessionValue(s Session) string {
+	var sessionValue string
 	for key, value := range s {
 		if strings.ContainsAny(key, ":\x00") {
 			panic("Session keys may not have colons or null bytes")
@@ -87,18 +200,28 @@ func (s Session) Cookie() *http.Cookie {
 		}
 		sessionValue += "\x00" + key + ":" + value + "\x00"
 	}
+	return url.QueryEscape(sessionValue)
+}
 
-	sessionData := url.QueryEscape(sessionValue)
-	return &http.Cookie{
-		Name:     CookiePrefix + "_SESSION",
-		Value:    Sign(sessionData) + "-" + sessionData,
-		Domain:   CookieDomain,
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   CookieSecure,
-		Expires:  ts.UTC(),
-		MaxAge:   int(expireAfterDuration.Seconds()),
+// Decode returns the session of the signed value
+func (SignedSessionCodec) Decode(value string) (Session, error) {
+	// Separate the data from the signature.
+	hyphen := strings.Index(value, "-")
+	if hyphen == -1 || hyphen >= len(value)-1 {
+		return nil, errors.New("invalid session cookie")
+	}
+	sig, data := value[:hyphen], value[hyphen+1:]
+
+	// Verify the signature.
+	if !Verify(data, sig) {
+		return nil, errors.New("session cookie signature failed")
 	}
+
+	session := make(Session)
+	ParseKeyValueCookie(data, func(key, val string) {
+		session[key] = val
+	})
+	return session, nil
 }
 
 // sessionTimeoutExpiredOrMissing returns a boolean of whether the session
@@ -112,65 +235,244 @@ func sessionTimeoutExpiredOrMissing(session Session) bool {
 	} else if expInt, _ := strconv.Atoi(exp); int64(expInt) < time.Now().Unix() {
 		return true
 	}
+	return sessionIdle(session)
+}
+
+// sessionIdle returns true if the session had no request for the idle timeout
+func sessionIdle(session Session) bool {
+	if sessionIdleTimeout <= 0 {
+		return false
+	}
+	at, err := strconv.ParseInt(session[ActivityKey], 10, 64)
+	return err == nil && time.Unix(at, 0).Add(sessionIdleTimeout).Before(time.Now())
+}
+
+// needsRefresh returns true if the remaining lifetime of the session cookie, or its remaining
+// idle time, is below the refresh threshold
+func (s Session) needsRefresh() bool {
+	now := time.Now()
+	below := func(expires time.Time, lifetime time.Duration) bool {
+		threshold := sessionRefreshThreshold
+		if threshold <= 0 {
+			threshold = lifetime / 2
+		}
+		return expires.Sub(now) < threshold
+	}
+	if exp := s[TimestampKey]; exp != sessionKeyName && expireAfterDuration > 0 {
+		expInt, err := strconv.ParseInt(exp, 10, 64)
+		if err != nil || below(time.Unix(expInt, 0), expireAfterDuration) {
+			return true
+		}
+	}
+	if sessionIdleTimeout > 0 {
+		at, err := strconv.ParseInt(s[ActivityKey], 10, 64)
+		if err != nil || below(time.Unix(at, 0).Add(sessionIdleTimeout), sessionIdleTimeout) {
+			return true
+		}
+	}
 	return false
 }
 
+// Returns a copy of the session
+func (s Session) copy() Session {
+	copied := make(Session, len(s))
+	for key, value := range s {
+		copied[key] = value
+	}
+	return copied
+}
+
 // GetSessionFromCookie returns a Session struct pulled from the signed
 // session cookie.
 func GetSessionFromCookie(cookie ServerCookie) Session {
-	session := make(Session)
+	session, _ := decodeSessionCookie(cookie)
+	return session
+}
 
-	// Separate the data from the signature.
+// Returns the session of the cookie, stale when the codec reports the cookie must be encoded
+// again
+func decodeSessionCookie(cookie ServerCookie) (session Session, stale bool) {
 	cookieValue := cookie.GetValue()
-	hyphen := strings.Index(cookieValue, "-")
-	if hyphen == -1 || hyphen >= len(cookieValue)-1 {
-		return session
+	if cookieValue == "" {
+		return make(Session), false
 	}
-	sig, data := cookieValue[:hyphen], cookieValue[hyphen+1:]
-
-	// Verify the signature.
-	if !Verify(data, sig) {
-		utilLog.Warn("Session cookie signature failed")
-		return session
+	var err error
+	if codec, ok := SessionCookieCodec.(StaleSessionCodec); ok {
+		session, stale, err = codec.DecodeStale(cookieValue)
+	} else {
+		session, err = SessionCookieCodec.Decode(cookieValue)
+	}
+	if err != nil {
+		utilLog.Warn("Session cookie could not be decoded", "error", err)
+		return make(Session), false
 	}
-
-	ParseKeyValueCookie(data, func(key, val string) {
-		session[key] = val
-	})
 
 	if sessionTimeoutExpiredOrMissing(session) {
-		session = make(Session)
+		return make(Session), false
 	}
 
-	return session
+	return session, stale
 }
 
 // SessionFilter is a Revel Filter that retrieves and sets the session cookie.
 // Within Revel, it is available as a Session attribute on Controller instances.
-// The name of the Session cookie is set as CookiePrefix + "_SESSION".
+// The name of the Session cookie is set as CookiePrefix + "_SESSION", its attributes are set
+// by cookie.session.* in app.conf.
 func SessionFilter(c *Controller, fc []Filter) {
-	c.Session = restoreSession(c.Request)
+	if SessionStorage != nil {
+		storedSessionFilter(c, fc, SessionStorage)
+		return
+	}
+	var stale bool
+	c.Session, stale = restoreStaleSession(c.Request)
+	overflowedID := ""
+	if c.Session[sessionOverflowKey] != "" {
+		overflowedID = c.Session[SessionIDKey]
+		c.Session = restoreOverflowedSession(c.Session)
+	}
 	sessionWasEmpty := len(c.Session) == 0
+	restored := c.Session.copy()
 
 	// Make session vars available in templates as {{.session.xyz}}
 	c.ViewArgs["session"] = c.Session
 
 	fc[0](c, fc[1:])
 
-	// Store the signed session if it could have changed.
+	if sessionReadOnly(c) {
+		c.Session = discardSessionChanges(c, restored)
+	}
+
+	// Store the signed session if it changed, if its cookie expires soon or is stale.
 	if len(c.Session) > 0 || !sessionWasEmpty {
-		c.SetCookie(c.Session.Cookie())
+		if sessionWasEmpty || stale || !reflect.DeepEqual(restored, c.Session) || c.Session.needsRefresh() {
+			c.SetCookie(overflowedSessionCookie(c.Session, overflowedID))
+		}
 	}
 }
 
+// storedSessionFilter restores the session of the ID of the session cookie from the store,
+// and stores it after the request. An unchanged session is touched, an emptied session is
+// destroyed. The session is locked during the request, or its changes are merged in the
+// stored session, depending on session.concurrency.
+func storedSessionFilter(c *Controller, fc []Filter, store SessionStore) {
+	c.Session = make(Session)
+	cookieSession, stale := restoreStaleSession(c.Request)
+	readOnly := sessionReadOnly(c)
+	if id := cookieSession[SessionIDKey]; id != "" {
+		if sessionConcurrency == sessionConcurrencyLock && !readOnly {
+			unlock, err := lockSession(store, id)
+			if err != nil {
+				utilLog.Error("Session: Failed to lock the session", "error", err)
+				c.Response.Status = http.StatusConflict
+				c.Result = c.RenderError(err)
+				return
+			}
+			defer unlock()
+		}
+		if stored, err := store.Get(id); err == nil {
+			c.Session = stored
+		} else if err != ErrSessionNotFound {
+			utilLog.Error("Session: Failed to get the session from the store", "error", err)
+		}
+	}
+	restored := c.Session.copy()
+	c.ViewArgs["session"] = c.Session
+
+	fc[0](c, fc[1:])
+
+	if readOnly {
+		c.Session = discardSessionChanges(c, restored)
+	}
+	if len(c.Session) == 0 {
+		if id := restored[SessionIDKey]; id != "" {
+			if err := store.Destroy(id); err != nil {
+				utilLog.Error("Session: Failed to destroy the session", "error", err)
+			}
+			cookie := Session{}.Cookie()
+			cookie.MaxAge, cookie.Expires = -1, time.Unix(0, 0)
+			c.SetCookie(cookie)
+		}
+		return
+	}
+
+	id := c.Session.ID()
+	expires := sessionStoreExpiration(c.Session)
+	var err error
+	if oldID := restored[SessionIDKey]; oldID != "" && oldID != id {
+		err = regenerateStoredSession(store, oldID, id, c.Session, expires)
+	} else if reflect.DeepEqual(restored, c.Session) {
+		err = store.Touch(id, expires)
+	} else if sessionConcurrency == sessionConcurrencyMerge && oldID != "" {
+		err = mergeStoredSession(store, id, restored, c.Session, expires)
+	} else {
+		err = store.Set(id, c.Session, expires)
+	}
+	if err != nil {
+		utilLog.Error("Session: Failed to store the session", "error", err)
+	}
+
+	// The cookie has the ID and the expiration of the session only
+	noExpiration := c.Session[TimestampKey] == sessionKeyName
+	if cookieSession[SessionIDKey] == id && (cookieSession[TimestampKey] == sessionKeyName) == noExpiration &&
+		!stale && !cookieSession.needsRefresh() {
+		return
+	}
+	cookie := Session{SessionIDKey: id}
+	if noExpiration {
+		cookie.SetNoExpiration()
+	}
+	c.SetCookie(cookie.Cookie())
+}
+
+// regenerateStoredSession stores the session under its new ID and destroys the old ID,
+// atomically if the store is a SessionRegenerator
+func regenerateStoredSession(store SessionStore, oldID, id string, session Session, expires time.Duration) error {
+	if regenerator, ok := store.(SessionRegenerator); ok {
+		return regenerator.Regenerate(oldID, id, session, expires)
+	}
+	if err := store.Set(id, session, expires); err != nil {
+		return err
+	}
+	return store.Destroy(oldID)
+}
+
 // restoreSession returns either the current session, retrieved from the
 // session cookie, or a new session.
 func restoreSession(req *Request) Session {
-	cookie, err := req.Cookie(CookiePrefix + "_SESSION")
+	session, _ := restoreStaleSession(req)
+	return session
+}
+
+// restoreRequestSession returns the session of the request as the SessionFilter restores
+// it, from the cookie (and the overflow store) or from the SessionStorage when it is set.
+// It is used by the filters which run before the SessionFilter.
+func restoreRequestSession(req *Request) Session {
+	session := restoreSession(req)
+	if SessionStorage == nil {
+		if session[sessionOverflowKey] != "" {
+			session = restoreOverflowedSession(session)
+		}
+		return session
+	}
+	if id := session[SessionIDKey]; id != "" {
+		stored, err := SessionStorage.Get(id)
+		if err == nil {
+			return stored
+		} else if err != ErrSessionNotFound {
+			utilLog.Error("Session: Failed to get the session from the store", "error", err)
+		}
+	}
+	return Session{}
+}
+
+// restoreStaleSession returns the session of the session cookie, stale when its cookie must
+// be encoded again
+func restoreStaleSession(req *Request) (Session, bool) {
+	cookie, err := req.Cookie(cookieAttributesOf(sessionCookieKind).Name("_SESSION"))
 	if err != nil {
-		return make(Session)
+		return make(Session), false
 	}
-	return GetSessionFromCookie(cookie)
+	return decodeSessionCookie(cookie)
 }
 
 // getSessionExpirationCookie retrieves the cookie's time to live as a
@@ -0,0 +1,326 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package session
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/revel/revel"
+)
+
+// The session may be kept in the session cookie as a JWT, signed by HS256 and encrypted by
+// A256GCM (a JWE with the JWT as its content) if it has encryption keys. No session is kept
+// on the server, the application may run on many servers without sticky sessions
+//   session.store = jwt
+//   session.jwt.keys = new-secret,old-secret     # The first key signs, all keys verify (app.secret by default)
+//   session.jwt.encryption.keys = new,old        # The first key encrypts, all keys decrypt (none by default)
+//   session.jwt.claims = _ID:jti,user:sub        # The claims of the session keys, the other keys are claims as is
+//   session.jwt.issuer = https://example.com     # The iss claim, checked when set
+//   session.jwt.audience = api                   # The aud claim, checked when set
+// The expiration of the session is the exp claim, the session which ends when the browser is
+// closed has no exp claim. The keys are identified by the kid header, a key which is removed
+// revokes the sessions it signed.
+
+// JWTCodec encodes the session in a JWT, it is set as revel.SessionCookieCodec
+type JWTCodec struct {
+	// Keys sign the JWT (the first one) and verify it (all of them)
+	Keys [][]byte
+	// EncryptionKeys encrypt the JWT (the first one) and decrypt it (all of them), the JWT
+	// is not encrypted if there are none. They are hashed in 32 bytes keys.
+	EncryptionKeys [][]byte
+	// Claims are the names of the claims of the session keys, the other session keys are
+	// claims of the same name
+	Claims map[string]string
+	// Issuer and Audience are the iss and aud claims, checked when decoding when set
+	Issuer   string
+	Audience string
+}
+
+var (
+	errJWTInvalid   = errors.New("invalid JWT")
+	errJWTSignature = errors.New("JWT signature failed")
+	errJWTKey       = errors.New("JWT key not found")
+)
+
+var jwtEncoding = base64.RawURLEncoding
+
+// The header of a JWS or a JWE
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Enc string `json:"enc,omitempty"`
+	Typ string `json:"typ,omitempty"`
+	Cty string `json:"cty,omitempty"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// Returns the ID of the key
+func jwtKeyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:4])
+}
+
+// Returns the keys of the ID, all the keys if it has no ID
+func jwtKeys(keys [][]byte, kid string) [][]byte {
+	if kid == "" {
+		return keys
+	}
+	for _, key := range keys {
+		if jwtKeyID(key) == kid {
+			return [][]byte{key}
+		}
+	}
+	return nil
+}
+
+// Encode returns the JWT of the session, encrypted if the codec has encryption keys
+func (codec *JWTCodec) Encode(session revel.Session) (string, error) {
+	if len(codec.Keys) == 0 {
+		return "", errJWTKey
+	}
+	claims := make(map[string]interface{}, len(session)+3)
+	for key, value := range session {
+		if key == revel.TimestampKey {
+			if exp, err := strconv.ParseInt(value, 10, 64); err == nil {
+				claims["exp"] = exp
+			}
+			continue
+		}
+		if claim, found := codec.Claims[key]; found {
+			key = claim
+		}
+		claims[key] = value
+	}
+	claims["iat"] = time.Now().Unix()
+	if codec.Issuer != "" {
+		claims["iss"] = codec.Issuer
+	}
+	if codec.Audience != "" {
+		claims["aud"] = codec.Audience
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	key := codec.Keys[0]
+	header, _ := json.Marshal(jwtHeader{Alg: "HS256", Typ: "JWT", Kid: jwtKeyID(key)})
+	signed := jwtEncoding.EncodeToString(header) + "." + jwtEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(signed))
+	token := signed + "." + jwtEncoding.EncodeToString(mac.Sum(nil))
+
+	if len(codec.EncryptionKeys) == 0 {
+		return token, nil
+	}
+	return jweEncrypt(codec.EncryptionKeys[0], []byte(token))
+}
+
+// Decode returns the session of the JWT, the JWT is decrypted if the codec has encryption
+// keys
+func (codec *JWTCodec) Decode(value string) (revel.Session, error) {
+	token := value
+	if len(codec.EncryptionKeys) > 0 {
+		decrypted, err := jweDecrypt(codec.EncryptionKeys, value)
+		if err != nil {
+			return nil, err
+		}
+		token = string(decrypted)
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errJWTInvalid
+	}
+	header := jwtHeader{}
+	if err := jwtDecodePart(parts[0], &header); err != nil || header.Alg != "HS256" {
+		return nil, errJWTInvalid
+	}
+	signature, err := jwtEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errJWTInvalid
+	}
+	verified := false
+	for _, key := range jwtKeys(codec.Keys, header.Kid) {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(parts[0] + "." + parts[1]))
+		if hmac.Equal(signature, mac.Sum(nil)) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, errJWTSignature
+	}
+
+	claims := map[string]interface{}{}
+	if err = jwtDecodePart(parts[1], &claims); err != nil {
+		return nil, errJWTInvalid
+	}
+	if codec.Issuer != "" && claims["iss"] != codec.Issuer {
+		return nil, fmt.Errorf("JWT issuer %v is not %s", claims["iss"], codec.Issuer)
+	}
+	if codec.Audience != "" && !jwtHasAudience(claims["aud"], codec.Audience) {
+		return nil, fmt.Errorf("JWT audience %v is not %s", claims["aud"], codec.Audience)
+	}
+
+	keys := make(map[string]string, len(codec.Claims))
+	for key, claim := range codec.Claims {
+		keys[claim] = key
+	}
+	session := revel.Session{revel.TimestampKey: "session"}
+	for claim, value := range claims {
+		switch claim {
+		case "exp":
+			if exp, ok := value.(float64); ok {
+				session[revel.TimestampKey] = strconv.FormatInt(int64(exp), 10)
+			}
+			continue
+		case "iat", "iss", "aud", "nbf":
+			continue
+		}
+		if key, found := keys[claim]; found {
+			claim = key
+		}
+		if text, ok := value.(string); ok {
+			session[claim] = text
+		} else {
+			encoded, _ := json.Marshal(value)
+			session[claim] = string(encoded)
+		}
+	}
+	return session, nil
+}
+
+// Returns true if the aud claim (a string or an array) has the audience
+func jwtHasAudience(aud interface{}, audience string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == audience
+	case []interface{}:
+		for _, value := range aud {
+			if value == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Decodes the JSON of the base64url part
+func jwtDecodePart(part string, value interface{}) error {
+	data, err := jwtEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, value)
+}
+
+// Returns the AES-GCM of the encryption key
+func jweCipher(key []byte) (cipher.AEAD, error) {
+	sum := sha256.Sum256(key)
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Returns the compact JWE (dir, A256GCM) of the JWT
+func jweEncrypt(key []byte, token []byte) (string, error) {
+	aead, err := jweCipher(key)
+	if err != nil {
+		return "", err
+	}
+	header, _ := json.Marshal(jwtHeader{Alg: "dir", Enc: "A256GCM", Cty: "JWT", Kid: jwtKeyID(key)})
+	protected := jwtEncoding.EncodeToString(header)
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nil, nonce, token, []byte(protected))
+	ciphertext, tag := sealed[:len(sealed)-aead.Overhead()], sealed[len(sealed)-aead.Overhead():]
+	return protected + ".." + jwtEncoding.EncodeToString(nonce) + "." +
+		jwtEncoding.EncodeToString(ciphertext) + "." + jwtEncoding.EncodeToString(tag), nil
+}
+
+// Returns the JWT of the compact JWE, decrypted by the key of its kid header
+func jweDecrypt(keys [][]byte, value string) ([]byte, error) {
+	parts := strings.Split(value, ".")
+	if len(parts) != 5 || parts[1] != "" {
+		return nil, errJWTInvalid
+	}
+	header := jwtHeader{}
+	if err := jwtDecodePart(parts[0], &header); err != nil || header.Alg != "dir" || header.Enc != "A256GCM" {
+		return nil, errJWTInvalid
+	}
+	nonce, err1 := jwtEncoding.DecodeString(parts[2])
+	ciphertext, err2 := jwtEncoding.DecodeString(parts[3])
+	tag, err3 := jwtEncoding.DecodeString(parts[4])
+	if err1 != nil || err2 != nil || err3 != nil {
+		return nil, errJWTInvalid
+	}
+	for _, key := range jwtKeys(keys, header.Kid) {
+		aead, err := jweCipher(key)
+		if err != nil || len(nonce) != aead.NonceSize() {
+			continue
+		}
+		if token, err := aead.Open(nil, nonce, append(ciphertext, tag...), []byte(parts[0])); err == nil {
+			return token, nil
+		}
+	}
+	return nil, errJWTKey
+}
+
+// Returns the JWTCodec of app.conf
+func newConfiguredJWTCodec() *JWTCodec {
+	codec := &JWTCodec{
+		Claims:   map[string]string{},
+		Issuer:   revel.Config.StringDefault("session.jwt.issuer", ""),
+		Audience: revel.Config.StringDefault("session.jwt.audience", ""),
+	}
+	for _, key := range configList("session.jwt.keys") {
+		codec.Keys = append(codec.Keys, []byte(key))
+	}
+	if len(codec.Keys) == 0 {
+		secret := revel.Config.StringDefault("app.secret", "")
+		if secret == "" {
+			sessionLog.Panic("The jwt session.store needs session.jwt.keys or app.secret")
+		}
+		codec.Keys = [][]byte{[]byte(secret)}
+	}
+	for _, key := range configList("session.jwt.encryption.keys") {
+		codec.EncryptionKeys = append(codec.EncryptionKeys, []byte(key))
+	}
+	for _, claim := range configList("session.jwt.claims") {
+		parts := strings.SplitN(claim, ":", 2)
+		if len(parts) != 2 {
+			sessionLog.Panic("Invalid session.jwt.claims, expected key:claim", "claim", claim)
+		}
+		codec.Claims[parts[0]] = parts[1]
+	}
+	return codec
+}
+
+// Returns the values of the comma separated list of the config
+func configList(key string) (values []string) {
+	for _, value := range strings.Split(revel.Config.StringDefault(key, ""), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package session
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/revel/revel"
+)
+
+func TestJWTCodec(t *testing.T) {
+	exp := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+	session := revel.Session{"user": "jane", revel.SessionIDKey: "id", revel.TimestampKey: exp}
+	for _, encrypted := range []bool{false, true} {
+		codec := &JWTCodec{Keys: [][]byte{[]byte("new")}, Claims: map[string]string{"user": "sub"}, Issuer: "revel"}
+		if encrypted {
+			codec.EncryptionKeys = [][]byte{[]byte("secret")}
+		}
+		token, err := codec.Encode(session)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parts := strings.Count(token, "."); (encrypted && parts != 4) || (!encrypted && parts != 2) {
+			t.Errorf("Unexpected token %s", token)
+		}
+		if !encrypted && !strings.Contains(token, ".eyJ") {
+			t.Errorf("Expected a JWT, got %s", token)
+		}
+
+		decoded, err := codec.Decode(token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for key, value := range session {
+			if decoded[key] != value {
+				t.Errorf("Expected %s=%s, got %v", key, value, decoded)
+			}
+		}
+
+		// The keys are rotated, the old key still verifies
+		rotated := &JWTCodec{Keys: [][]byte{[]byte("newer"), []byte("new")}, Claims: codec.Claims, Issuer: "revel"}
+		if encrypted {
+			rotated.EncryptionKeys = [][]byte{[]byte("secret2"), []byte("secret")}
+		}
+		if decoded, err = rotated.Decode(token); err != nil || decoded["user"] != "jane" {
+			t.Errorf("Expected the rotated keys to decode the session, got %v %v", decoded, err)
+		}
+
+		// A removed key, another issuer or a tampered token are rejected
+		invalid := []*JWTCodec{
+			{Keys: [][]byte{[]byte("newer")}, EncryptionKeys: codec.EncryptionKeys, Issuer: "revel"},
+			{Keys: codec.Keys, EncryptionKeys: codec.EncryptionKeys, Issuer: "other"},
+		}
+		for _, other := range invalid {
+			if _, err = other.Decode(token); err == nil {
+				t.Errorf("Expected the token to be rejected by %+v", other)
+			}
+		}
+		if _, err = codec.Decode(token[:len(token)-2] + "AA"); err == nil {
+			t.Error("Expected a tampered token to be rejected")
+		}
+	}
+}
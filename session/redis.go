@@ -0,0 +1,298 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+// Package session keeps the sessions of the application in Redis, in a database (see
+// SQLStore), or in a JWT session cookie (see JWTCodec)
+//   import _ "github.com/revel/revel/session"
+// and in app.conf
+//   session.store = redis
+//   session.redis.host = localhost:6379
+//   session.redis.password =
+//   session.redis.prefix = revel-session:
+//   session.lock.lease = 30s       # How long a lock of a session is held without being renewed
+// The lock of a session (session.concurrency = lock) is renewed while its request runs, it
+// expires after the lease when the server holding it stopped. The changes of a request are
+// merged (session.concurrency = merge) in a transaction which is retried when the session was
+// stored by another request since it was read.
+// The session cookie of the stores has the ID of the session only, the sessions may be large
+// and are revoked on the server by revel.DestroySession.
+package session
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/revel/revel"
+)
+
+var sessionLog = revel.RevelLog.New("section", "session")
+
+func init() {
+	revel.OnAppStart(func() {
+		// The store of a former start is replaced
+		if store, ok := revel.SessionStorage.(*SQLStore); ok {
+			if err := store.Close(); err != nil {
+				sessionLog.Error("Failed to close the session store", "error", err)
+			}
+		}
+		switch store := revel.Config.StringDefault("session.store", "cookie"); store {
+		case "redis":
+			revel.SessionStorage = newConfiguredRedisStore()
+		case "sql":
+			revel.SessionStorage = newConfiguredSQLStore()
+		case "jwt":
+			revel.SessionStorage = nil
+			revel.SessionCookieCodec = newConfiguredJWTCodec()
+		case "cookie":
+			revel.SessionStorage = nil
+		default:
+			sessionLog.Panic("Unknown session.store, expected cookie, redis, sql or jwt", "store", store)
+		}
+		if revel.Config.StringDefault("session.overflow", "") == "redis" {
+			revel.SessionOverflowStorage = newConfiguredRedisStore()
+		}
+	})
+}
+
+// Returns the RedisStore of app.conf
+func newConfiguredRedisStore() *RedisStore {
+	return NewRedisStore(
+		revel.Config.StringDefault("session.redis.host", "localhost:6379"),
+		revel.Config.StringDefault("session.redis.password", ""))
+}
+
+// RedisStore keeps the sessions in Redis, encoded in JSON under the prefix and their ID
+type RedisStore struct {
+	pool      *redis.Pool
+	prefix    string
+	lockLease time.Duration // How long a lock is held without being renewed
+}
+
+// NewRedisStore returns the store of the sessions in the Redis server of the host
+func NewRedisStore(host string, password string) *RedisStore {
+	pool := &redis.Pool{
+		MaxIdle:     revel.Config.IntDefault("session.redis.maxidle", 5),
+		MaxActive:   revel.Config.IntDefault("session.redis.maxactive", 0),
+		IdleTimeout: revel.ConfigDurationDefault("session.redis.idletimeout", 240*time.Second, time.Second),
+		Dial: func() (redis.Conn, error) {
+			c, err := redis.Dial("tcp", host,
+				redis.DialConnectTimeout(revel.ConfigDurationDefault("session.redis.timeout.connect", 10*time.Second, time.Millisecond)),
+				redis.DialReadTimeout(revel.ConfigDurationDefault("session.redis.timeout.read", 5*time.Second, time.Millisecond)),
+				redis.DialWriteTimeout(revel.ConfigDurationDefault("session.redis.timeout.write", 5*time.Second, time.Millisecond)))
+			if err != nil {
+				return nil, err
+			}
+			if password != "" {
+				if _, err = c.Do("AUTH", password); err != nil {
+					_ = c.Close()
+					return nil, err
+				}
+			}
+			return c, nil
+		},
+		TestOnBorrow: func(c redis.Conn, t time.Time) error {
+			_, err := c.Do("PING")
+			return err
+		},
+	}
+	return &RedisStore{
+		pool:      pool,
+		prefix:    revel.Config.StringDefault("session.redis.prefix", "revel-session:"),
+		lockLease: revel.ConfigDurationDefault("session.lock.lease", 30*time.Second, time.Second),
+	}
+}
+
+// Get returns the session of the ID, revel.ErrSessionNotFound if it has expired
+func (s *RedisStore) Get(id string) (revel.Session, error) {
+	conn := s.pool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+	data, err := redis.Bytes(conn.Do("GET", s.prefix+id))
+	if err == redis.ErrNil {
+		return nil, revel.ErrSessionNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	session := revel.Session{}
+	err = json.Unmarshal(data, &session)
+	return session, err
+}
+
+// Set stores the session of the ID until it expires
+func (s *RedisStore) Set(id string, session revel.Session, expires time.Duration) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	conn := s.pool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+	_, err = conn.Do("SET", s.prefix+id, data, "PX", expires.Milliseconds())
+	return err
+}
+
+// Regenerate stores the session under the new ID and removes the old ID in a transaction
+func (s *RedisStore) Regenerate(oldID, id string, session revel.Session, expires time.Duration) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	conn := s.pool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+	if err = conn.Send("MULTI"); err != nil {
+		return err
+	}
+	if err = conn.Send("SET", s.prefix+id, data, "PX", expires.Milliseconds()); err != nil {
+		return err
+	}
+	if err = conn.Send("DEL", s.prefix+oldID); err != nil {
+		return err
+	}
+	_, err = conn.Do("EXEC")
+	return err
+}
+
+// Merge stores the session returned by merge of the stored session, the session is watched
+// from its read to its store which is retried when another request stored it in between
+func (s *RedisStore) Merge(id string, merge func(current revel.Session) revel.Session, expires time.Duration) error {
+	conn := s.pool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+	key := s.prefix + id
+	for attempt := 0; attempt < 3; attempt++ {
+		if _, err := conn.Do("WATCH", key); err != nil {
+			return err
+		}
+		data, err := s.mergedSession(conn, key, merge)
+		if err != nil {
+			_, _ = conn.Do("UNWATCH")
+			return err
+		}
+		if err = conn.Send("MULTI"); err != nil {
+			return err
+		}
+		if err = conn.Send("SET", key, data, "PX", expires.Milliseconds()); err != nil {
+			return err
+		}
+		reply, err := conn.Do("EXEC")
+		if err != nil || reply != nil {
+			return err
+		}
+		// The transaction was aborted, the session was stored by another request
+	}
+	return revel.ErrSessionConflict
+}
+
+// Returns the encoded session merged in the session stored under the key
+func (s *RedisStore) mergedSession(conn redis.Conn, key string, merge func(current revel.Session) revel.Session) ([]byte, error) {
+	var current revel.Session
+	data, err := redis.Bytes(conn.Do("GET", key))
+	if err == nil {
+		if err = json.Unmarshal(data, &current); err != nil {
+			return nil, err
+		}
+	} else if err != redis.ErrNil {
+		return nil, err
+	}
+	return json.Marshal(merge(current))
+}
+
+// The script which removes a lock if it has the token
+var unlockScript = redis.NewScript(1, `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("DEL", KEYS[1]) end return 0`)
+
+// The script which extends the lease of a lock if it has the token
+var renewLockScript = redis.NewScript(1, `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("PEXPIRE", KEYS[1], ARGV[2]) end return 0`)
+
+// Lock waits for the lock of the session until the timeout. The lock is held for the lease
+// and renewed until it is unlocked, it expires after the lease if the server holding it
+// stopped.
+func (s *RedisStore) Lock(id string, timeout time.Duration) (func(), error) {
+	buffer := make([]byte, 16)
+	if _, err := rand.Read(buffer); err != nil {
+		return nil, err
+	}
+	token, key := hex.EncodeToString(buffer), s.prefix+"lock:"+id
+	lease := s.lockLease
+	if lease <= 0 {
+		lease = 30 * time.Second
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		conn := s.pool.Get()
+		_, err := redis.String(conn.Do("SET", key, token, "NX", "PX", lease.Milliseconds()))
+		_ = conn.Close()
+		if err == nil {
+			break
+		} else if err != redis.ErrNil {
+			return nil, err
+		}
+		if time.Now().After(deadline) {
+			return nil, revel.ErrSessionLocked
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	stop := make(chan struct{})
+	go s.renewLock(key, token, lease, stop)
+	return func() {
+		close(stop)
+		conn := s.pool.Get()
+		defer func() {
+			_ = conn.Close()
+		}()
+		if _, err := unlockScript.Do(conn, key, token); err != nil {
+			sessionLog.Error("Failed to unlock the session", "error", err)
+		}
+	}, nil
+}
+
+// Renews the lease of the lock every third of the lease until it is stopped, or lost
+func (s *RedisStore) renewLock(key, token string, lease time.Duration, stop chan struct{}) {
+	ticker := time.NewTicker(lease / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		conn := s.pool.Get()
+		renewed, err := redis.Int(renewLockScript.Do(conn, key, token, lease.Milliseconds()))
+		_ = conn.Close()
+		if err != nil {
+			sessionLog.Error("Failed to renew the lock of the session", "error", err)
+		} else if renewed == 0 {
+			sessionLog.Warn("The lock of the session was lost", "key", key)
+			return
+		}
+	}
+}
+
+// Destroy removes the session of the ID
+func (s *RedisStore) Destroy(id string) error {
+	conn := s.pool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+	_, err := conn.Do("DEL", s.prefix+id)
+	return err
+}
+
+// Touch extends the expiration of the session of the ID
+func (s *RedisStore) Touch(id string, expires time.Duration) error {
+	conn := s.pool.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+	_, err := conn.Do("PEXPIRE", s.prefix+id, expires.Milliseconds())
+	return err
+}
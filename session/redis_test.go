@@ -0,0 +1,78 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/revel/config"
+	"github.com/revel/revel"
+)
+
+// These tests require redis server running on localhost:6379 (the default)
+const redisTestServer = "localhost:6379"
+
+func TestRedisStore(t *testing.T) {
+	revel.Config = config.NewContext()
+	store := NewRedisStore(redisTestServer, "")
+	session := revel.Session{"user": "jane", revel.SessionIDKey: "test-id"}
+	if err := store.Set("test-id", session, time.Minute); err != nil {
+		t.Fatalf("couldn't connect to redis on %s: %s", redisTestServer, err)
+	}
+	stored, err := store.Get("test-id")
+	if err != nil || stored["user"] != "jane" {
+		t.Errorf("Expected the stored session, got %v %v", stored, err)
+	}
+	if err = store.Touch("test-id", time.Hour); err != nil {
+		t.Error(err)
+	}
+	if err = store.Destroy("test-id"); err != nil {
+		t.Error(err)
+	}
+	if _, err = store.Get("test-id"); err != revel.ErrSessionNotFound {
+		t.Errorf("Expected the session to be destroyed, got %v", err)
+	}
+}
+
+func TestRedisStoreLockAndMerge(t *testing.T) {
+	revel.Config = config.NewContext()
+	store := NewRedisStore(redisTestServer, "")
+	store.lockLease = 150 * time.Millisecond
+	if err := store.Set("test-id", revel.Session{"user": "jane"}, time.Minute); err != nil {
+		t.Fatalf("couldn't connect to redis on %s: %s", redisTestServer, err)
+	}
+	defer func() { _ = store.Destroy("test-id") }()
+
+	// The lock is renewed past its lease until it is unlocked
+	unlock, err := store.Lock("test-id", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(400 * time.Millisecond)
+	if _, err = store.Lock("test-id", 10*time.Millisecond); err != revel.ErrSessionLocked {
+		t.Errorf("Expected the renewed lock to be held, got %v", err)
+	}
+	unlock()
+	if unlock, err = store.Lock("test-id", 10*time.Millisecond); err != nil {
+		t.Errorf("Expected the lock to be released, got %v", err)
+	} else {
+		unlock()
+	}
+
+	// The session stored by another request during the merge is merged again
+	attempts := 0
+	err = store.Merge("test-id", func(current revel.Session) revel.Session {
+		if attempts++; attempts == 1 {
+			_ = store.Set("test-id", revel.Session{"user": "jane", "theme": "dark"}, time.Minute)
+		}
+		current["cart"] = "2"
+		return current
+	}, time.Minute)
+	stored, _ := store.Get("test-id")
+	if err != nil || attempts != 2 || stored["theme"] != "dark" || stored["cart"] != "2" {
+		t.Errorf("Expected the merge to be retried, got %v %d %v", err, attempts, stored)
+	}
+}
@@ -0,0 +1,302 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package session
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/revel/revel"
+)
+
+func TestSQLStoreQuery(t *testing.T) {
+	store := NewSQLStore(nil, "sessions", "?")
+	query := "UPDATE sessions SET data = ?, version = ? WHERE id = ? AND version = ?"
+	if store.query(query) != query {
+		t.Errorf("Expected the ? placeholders to be kept, got %s", store.query(query))
+	}
+	store = NewSQLStore(nil, "sessions", "$")
+	if expected := "UPDATE sessions SET data = $1, version = $2 WHERE id = $3 AND version = $4"; store.query(query) != expected {
+		t.Errorf("Expected %s, got %s", expected, store.query(query))
+	}
+	if !strings.HasPrefix(store.Schema(), "CREATE TABLE IF NOT EXISTS sessions (") {
+		t.Errorf("Unexpected schema %s", store.Schema())
+	}
+}
+
+// A row of the table of the fake driver
+type fakeSessionRow struct {
+	data             string
+	version, expires int64
+}
+
+// A database/sql driver which runs the queries of the SQLStore on the rows of a map, the
+// databases of the same name share their rows
+type fakeSessionDriver struct {
+	mu        sync.Mutex
+	databases map[string]map[string]fakeSessionRow
+}
+
+var fakeDriver = &fakeSessionDriver{databases: map[string]map[string]fakeSessionRow{}}
+
+func init() {
+	sql.Register("revel-fake-sessions", fakeDriver)
+}
+
+func (d *fakeSessionDriver) Open(name string) (driver.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.databases[name] == nil {
+		d.databases[name] = map[string]fakeSessionRow{}
+	}
+	return &fakeSessionConn{driver: d, name: name}, nil
+}
+
+type fakeSessionConn struct {
+	driver   *fakeSessionDriver
+	name     string
+	snapshot map[string]fakeSessionRow // The rows when the transaction began
+}
+
+func (c *fakeSessionConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeSessionStmt{conn: c, query: query}, nil
+}
+
+func (c *fakeSessionConn) Close() error {
+	return nil
+}
+
+func (c *fakeSessionConn) Begin() (driver.Tx, error) {
+	c.driver.mu.Lock()
+	defer c.driver.mu.Unlock()
+	c.snapshot = map[string]fakeSessionRow{}
+	for id, row := range c.driver.databases[c.name] {
+		c.snapshot[id] = row
+	}
+	return c, nil
+}
+
+func (c *fakeSessionConn) Commit() error {
+	c.snapshot = nil
+	return nil
+}
+
+func (c *fakeSessionConn) Rollback() error {
+	c.driver.mu.Lock()
+	defer c.driver.mu.Unlock()
+	rows := c.driver.databases[c.name]
+	for id := range rows {
+		delete(rows, id)
+	}
+	for id, row := range c.snapshot {
+		rows[id] = row
+	}
+	c.snapshot = nil
+	return nil
+}
+
+type fakeSessionStmt struct {
+	conn  *fakeSessionConn
+	query string
+}
+
+func (s *fakeSessionStmt) Close() error {
+	return nil
+}
+
+func (s *fakeSessionStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeSessionStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.conn.driver.mu.Lock()
+	defer s.conn.driver.mu.Unlock()
+	rows := s.conn.driver.databases[s.conn.name]
+	affected := int64(0)
+	switch {
+	case strings.HasPrefix(s.query, "CREATE TABLE"):
+	case strings.HasPrefix(s.query, "INSERT"):
+		id := args[0].(string)
+		if _, found := rows[id]; found {
+			return nil, errors.New("duplicate key")
+		}
+		rows[id] = fakeSessionRow{data: args[1].(string), version: args[2].(int64), expires: args[3].(int64)}
+		affected = 1
+	case strings.Contains(s.query, "SET data = ?"):
+		if row, found := rows[args[3].(string)]; found && row.version == args[4].(int64) {
+			rows[args[3].(string)] = fakeSessionRow{data: args[0].(string), version: args[1].(int64), expires: args[2].(int64)}
+			affected = 1
+		}
+	case strings.Contains(s.query, "SET expires = ?"):
+		if row, found := rows[args[1].(string)]; found {
+			row.expires = args[0].(int64)
+			rows[args[1].(string)] = row
+			affected = 1
+		}
+	case strings.Contains(s.query, "WHERE expires < ?"):
+		for id, row := range rows {
+			if row.expires < args[0].(int64) {
+				delete(rows, id)
+				affected++
+			}
+		}
+	case strings.HasPrefix(s.query, "DELETE"):
+		if row, found := rows[args[0].(string)]; found && (len(args) == 1 || row.version == args[1].(int64)) {
+			delete(rows, args[0].(string))
+			affected = 1
+		}
+	default:
+		return nil, errors.New("unexpected statement " + s.query)
+	}
+	return driver.RowsAffected(affected), nil
+}
+
+func (s *fakeSessionStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.conn.driver.mu.Lock()
+	defer s.conn.driver.mu.Unlock()
+	if !strings.HasPrefix(s.query, "SELECT data, version, expires") {
+		return nil, errors.New("unexpected query " + s.query)
+	}
+	result := &fakeSessionRows{}
+	if row, found := s.conn.driver.databases[s.conn.name][args[0].(string)]; found {
+		result.rows = append(result.rows, row)
+	}
+	return result, nil
+}
+
+type fakeSessionRows struct {
+	rows []fakeSessionRow
+}
+
+func (r *fakeSessionRows) Columns() []string {
+	return []string{"data", "version", "expires"}
+}
+
+func (r *fakeSessionRows) Close() error {
+	return nil
+}
+
+func (r *fakeSessionRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	dest[0], dest[1], dest[2] = r.rows[0].data, r.rows[0].version, r.rows[0].expires
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// Returns a store of the fake driver, its rows are the rows of the name
+func newFakeSQLStore(t *testing.T, name string) (*SQLStore, map[string]fakeSessionRow) {
+	db, err := sql.Open("revel-fake-sessions", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := NewSQLStore(db, "sessions", "?")
+	if err = store.CreateTable(); err != nil {
+		t.Fatal(err)
+	}
+	return store, fakeDriver.databases[name]
+}
+
+func TestSQLStore(t *testing.T) {
+	store, rows := newFakeSQLStore(t, t.Name())
+	if _, err := store.Get("id"); err != revel.ErrSessionNotFound {
+		t.Errorf("Expected no session, got %v", err)
+	}
+
+	// The version of the session is bumped when it is stored
+	session := revel.Session{"user": "jane"}
+	if err := store.Set("id", session, time.Minute); err != nil || session[SessionVersionKey] != "1" {
+		t.Fatalf("Expected the session to be inserted, got %v %v", err, session)
+	}
+	stored, err := store.Get("id")
+	if err != nil || stored["user"] != "jane" || stored[SessionVersionKey] != "1" {
+		t.Fatalf("Expected the stored session, got %v %v", stored, err)
+	}
+	if strings.Contains(rows["id"].data, SessionVersionKey) {
+		t.Errorf("Expected the version to be stored in its column, got %s", rows["id"].data)
+	}
+	stored["cart"] = "2"
+	if err = store.Set("id", stored, time.Minute); err != nil || stored[SessionVersionKey] != "2" || rows["id"].version != 2 {
+		t.Errorf("Expected the version to be bumped, got %v %v", err, stored)
+	}
+
+	// A session stored by another request since it was read is not stored
+	session[SessionVersionKey] = "1"
+	if err = store.Set("id", session, time.Minute); err != ErrConflict {
+		t.Errorf("Expected a conflict, got %v", err)
+	}
+	if stored, _ = store.Get("id"); stored["cart"] != "2" {
+		t.Errorf("Expected the session to be kept, got %v", stored)
+	}
+	if err = store.Regenerate("id", "new-id", session, time.Minute); err != ErrConflict || rows["id"].version != 2 || len(rows) != 1 {
+		t.Errorf("Expected the conflicting regeneration to be rolled back, got %v %v", err, rows)
+	}
+	if err = store.Regenerate("id", "new-id", stored, time.Minute); err != nil || stored[SessionVersionKey] != "1" {
+		t.Fatalf("Expected the session to be regenerated, got %v %v", err, stored)
+	}
+	if _, found := rows["id"]; found || rows["new-id"].version != 1 {
+		t.Errorf("Expected the session under its new ID only, got %v", rows)
+	}
+
+	if err = store.Touch("new-id", time.Hour); err != nil || rows["new-id"].expires < time.Now().Add(59*time.Minute).Unix() {
+		t.Errorf("Expected the expiration to be extended, got %v %v", err, rows["new-id"])
+	}
+	if err = store.Destroy("new-id"); err != nil || len(rows) != 0 {
+		t.Errorf("Expected the session to be destroyed, got %v %v", err, rows)
+	}
+}
+
+func TestSQLStoreExpiration(t *testing.T) {
+	store, rows := newFakeSQLStore(t, t.Name())
+	if err := store.Set("expired", revel.Session{"user": "jane"}, -time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set("live", revel.Session{"user": "john"}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get("expired"); err != revel.ErrSessionNotFound {
+		t.Errorf("Expected the expired session not to be found, got %v", err)
+	}
+	if deleted, err := store.DeleteExpired(); err != nil || deleted != 1 || len(rows) != 1 {
+		t.Errorf("Expected the expired session to be deleted, got %d %v", deleted, err)
+	}
+
+	// The cleanup deletes the sessions which expire until it is stopped
+	if err := store.Set("expired", revel.Session{"user": "jane"}, -time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	store.stopCleanup = store.StartCleanup(5 * time.Millisecond)
+	deadline := time.Now().Add(time.Second)
+	for fakeRowCount(t.Name()) != 1 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if fakeRowCount(t.Name()) != 1 {
+		t.Errorf("Expected the cleanup to delete the expired session, got %v", rows)
+	}
+	if err := store.Close(); err != nil || store.stopCleanup != nil {
+		t.Errorf("Expected the cleanup to be stopped, got %v", err)
+	}
+	if err := store.Set("expired", revel.Session{"user": "jane"}, -time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if fakeRowCount(t.Name()) != 2 {
+		t.Errorf("Expected the stopped cleanup not to delete the sessions, got %v", rows)
+	}
+}
+
+// Returns the number of rows of the database of the fake driver
+func fakeRowCount(name string) int {
+	fakeDriver.mu.Lock()
+	defer fakeDriver.mu.Unlock()
+	return len(fakeDriver.databases[name])
+}
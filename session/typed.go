@@ -0,0 +1,98 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package session
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/gob"
+	"encoding/json"
+
+	"github.com/revel/revel"
+)
+
+// The values of the session are strings, the other values are encoded by Set and decoded by
+// Get, in JSON by default or by gob (base64 encoded)
+//   session.encoding = gob
+//
+//   session.Set(c.Session, "cart", cart)
+//   cart, found := session.Get[Cart](c.Session, "cart")
+// The values set in one encoding are not decoded by the other.
+
+// The encodings of the values of the session
+const (
+	JSONEncoding = "json"
+	GobEncoding  = "gob"
+)
+
+// Encoding is the encoding of the values of the session which are not strings
+var Encoding = JSONEncoding
+
+func init() {
+	revel.OnAppStart(func() {
+		switch Encoding = revel.Config.StringDefault("session.encoding", JSONEncoding); Encoding {
+		case JSONEncoding, GobEncoding:
+		default:
+			sessionLog.Panic("Unknown session.encoding, expected json or gob", "encoding", Encoding)
+		}
+	})
+}
+
+// Get returns the value of the key of the session, false if the session does not have the
+// key or if its value could not be decoded in T
+func Get[T any](s revel.Session, key string) (value T, found bool) {
+	encoded, found := s[key]
+	if !found {
+		return value, false
+	}
+	if text, ok := any(&value).(*string); ok {
+		*text = encoded
+		return value, true
+	}
+	if err := decodeValue(encoded, &value); err != nil {
+		sessionLog.Warn("Failed to decode the session value", "key", key, "error", err)
+		return value, false
+	}
+	return value, true
+}
+
+// Set sets the value of the key of the session, the value is encoded if it is not a string
+func Set[T any](s revel.Session, key string, value T) error {
+	if text, ok := any(value).(string); ok {
+		s[key] = text
+		return nil
+	}
+	encoded, err := encodeValue(value)
+	if err != nil {
+		return err
+	}
+	s[key] = encoded
+	return nil
+}
+
+// Returns the value encoded in the Encoding
+func encodeValue(value interface{}) (string, error) {
+	if Encoding == GobEncoding {
+		buffer := &bytes.Buffer{}
+		if err := gob.NewEncoder(buffer).Encode(value); err != nil {
+			return "", err
+		}
+		return base64.RawStdEncoding.EncodeToString(buffer.Bytes()), nil
+	}
+	data, err := json.Marshal(value)
+	return string(data), err
+}
+
+// Decodes the value encoded in the Encoding
+func decodeValue(encoded string, value interface{}) error {
+	if Encoding == GobEncoding {
+		data, err := base64.RawStdEncoding.DecodeString(encoded)
+		if err != nil {
+			return err
+		}
+		return gob.NewDecoder(bytes.NewReader(data)).Decode(value)
+	}
+	return json.Unmarshal([]byte(encoded), value)
+}
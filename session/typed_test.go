@@ -0,0 +1,51 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package session
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/revel/revel"
+)
+
+type testCart struct {
+	Items []string
+	Total float64
+}
+
+func TestTypedValues(t *testing.T) {
+	defer func() { Encoding = JSONEncoding }()
+	for _, encoding := range []string{JSONEncoding, GobEncoding} {
+		Encoding = encoding
+		s := revel.Session{}
+		cart := testCart{Items: []string{"book"}, Total: 12.5}
+		if err := Set(s, "cart", cart); err != nil {
+			t.Fatal(err)
+		}
+		if err := Set(s, "count", 3); err != nil {
+			t.Fatal(err)
+		}
+		if err := Set(s, "user", "jane"); err != nil || s["user"] != "jane" {
+			t.Errorf("Expected the string to be set as is, got %q %v", s["user"], err)
+		}
+
+		if value, found := Get[testCart](s, "cart"); !found || !reflect.DeepEqual(value, cart) {
+			t.Errorf("%s: Expected %v, got %v %v", encoding, cart, value, found)
+		}
+		if value, found := Get[int](s, "count"); !found || value != 3 {
+			t.Errorf("%s: Expected 3, got %v %v", encoding, value, found)
+		}
+		if value, found := Get[string](s, "user"); !found || value != "jane" {
+			t.Errorf("%s: Expected jane, got %v %v", encoding, value, found)
+		}
+		if _, found := Get[int](s, "missing"); found {
+			t.Errorf("%s: Expected a missing key to not be found", encoding)
+		}
+		if _, found := Get[int](s, "user"); found {
+			t.Errorf("%s: Expected a value which could not be decoded to not be found", encoding)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// The parallel requests of a browser write the session they read, the last one replaces the
+// changes of the others. The sessions kept by a SessionStorage may be written otherwise
+//   session.concurrency = merge    # The changes of the request are merged in the stored session
+//   session.concurrency = lock     # The requests of a session are run one at a time
+//   session.lock.timeout = 5s      # How long a request waits for the lock of its session
+// The lock is held by the store when it is a SessionLocker, in the process otherwise. The
+// changes are merged by the store when it is a SessionMerger, or stored again when the store
+// returns ErrSessionConflict. The actions which never write the session are annotated with
+// @SessionReadOnly, they do not wait for the lock and their changes of the session are
+// discarded.
+
+// SessionLocker is a SessionStore which locks the sessions for the servers of the
+// application
+type SessionLocker interface {
+	// Lock waits for the lock of the session until the timeout, and returns its unlock
+	Lock(id string, timeout time.Duration) (unlock func(), err error)
+}
+
+// SessionMerger is a SessionStore which merges the changes of a request in the stored
+// session atomically
+type SessionMerger interface {
+	// Merge stores the session returned by merge of the stored session, nil when the session
+	// is not stored
+	Merge(id string, merge func(current Session) Session, expires time.Duration) error
+}
+
+var (
+	// ErrSessionLocked is returned when the lock of a session could not be acquired in time
+	ErrSessionLocked = errors.New("session locked by another request")
+	// ErrSessionConflict is returned by a SessionStore when the session was stored by another
+	// request since it was read
+	ErrSessionConflict = errors.New("session changed by another request")
+)
+
+// The concurrency modes of the sessions
+const (
+	sessionConcurrencyNone  = "none"
+	sessionConcurrencyMerge = "merge"
+	sessionConcurrencyLock  = "lock"
+)
+
+var (
+	sessionConcurrency = sessionConcurrencyNone
+	sessionLockTimeout = 5 * time.Second
+)
+
+func init() {
+	OnAppStart(func() {
+		switch sessionConcurrency = Config.StringDefault("session.concurrency", sessionConcurrencyNone); sessionConcurrency {
+		case sessionConcurrencyNone, sessionConcurrencyMerge, sessionConcurrencyLock:
+		default:
+			utilLog.Error("Session: Unknown session.concurrency, expected none, merge or lock", "concurrency", sessionConcurrency)
+			sessionConcurrency = sessionConcurrencyNone
+		}
+		sessionLockTimeout = ConfigDurationDefault("session.lock.timeout", 5*time.Second, time.Second)
+	})
+}
+
+// Returns true if the action of the controller is annotated with @SessionReadOnly
+func sessionReadOnly(c *Controller) bool {
+	return c.MethodType != nil && c.MethodType.sessionReadOnly
+}
+
+// Returns the restored session in place of the session changed by a read only action
+func discardSessionChanges(c *Controller, restored Session) Session {
+	if !reflect.DeepEqual(restored, c.Session) {
+		utilLog.Warn("Session: The session was changed by a @SessionReadOnly action, the changes are discarded", "action", c.Action)
+	}
+	return restored
+}
+
+// Locks the session of the ID in the store, or in the process
+func lockSession(store SessionStore, id string) (func(), error) {
+	if locker, ok := store.(SessionLocker); ok {
+		return locker.Lock(id, sessionLockTimeout)
+	}
+	return processSessionLocks.lock(id, sessionLockTimeout)
+}
+
+// Stores the changes of the session (from the restored session) in the stored session, the
+// store is retried when it returns ErrSessionConflict
+func mergeStoredSession(store SessionStore, id string, restored, session Session, expires time.Duration) (err error) {
+	merge := func(current Session) Session {
+		if current == nil {
+			return session
+		}
+		for key, value := range session {
+			if restoredValue, found := restored[key]; !found || restoredValue != value {
+				current[key] = value
+			}
+		}
+		for key := range restored {
+			if _, found := session[key]; !found {
+				delete(current, key)
+			}
+		}
+		return current
+	}
+	if merger, ok := store.(SessionMerger); ok {
+		return merger.Merge(id, merge, expires)
+	}
+	for attempt := 0; attempt < 3; attempt++ {
+		current, err := store.Get(id)
+		if err == ErrSessionNotFound {
+			current = nil
+		} else if err != nil {
+			return err
+		}
+		if err = store.Set(id, merge(current), expires); err != ErrSessionConflict {
+			return err
+		}
+	}
+	return ErrSessionConflict
+}
+
+// The locks of the sessions of the process
+type sessionLocks struct {
+	sync.Mutex
+	locks map[string]*sessionLock
+}
+
+// A lock of a session, and the number of requests which hold it or wait for it
+type sessionLock struct {
+	held chan struct{}
+	refs int
+}
+
+var processSessionLocks = &sessionLocks{locks: map[string]*sessionLock{}}
+
+// Waits for the lock of the ID until the timeout, and returns its unlock
+func (l *sessionLocks) lock(id string, timeout time.Duration) (func(), error) {
+	l.Lock()
+	lock, found := l.locks[id]
+	if !found {
+		lock = &sessionLock{held: make(chan struct{}, 1)}
+		l.locks[id] = lock
+	}
+	lock.refs++
+	l.Unlock()
+
+	release := func() {
+		l.Lock()
+		if lock.refs--; lock.refs == 0 {
+			delete(l.locks, id)
+		}
+		l.Unlock()
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case lock.held <- struct{}{}:
+		return func() {
+			<-lock.held
+			release()
+		}, nil
+	case <-timer.C:
+		release()
+		return nil, ErrSessionLocked
+	}
+}
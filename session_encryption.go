@@ -0,0 +1,134 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// The session cookie is signed, its values may be read by the client. It is encrypted by
+// AES-GCM when set in app.conf, by a key derived from app.secret
+//   session.encrypt = true
+//   session.encrypt.keys = old-secret       # The former app secrets, they decrypt the cookies only
+//   session.encrypt.legacy = true           # The signed cookies are read (while they are replaced)
+// The former secrets decrypt the cookies encrypted before the secret was rotated. The signed
+// cookies of the sessions started before the encryption was enabled are read when legacy is
+// set. The cookies decrypted by a former secret, or read as signed cookies, are stale: they
+// are replaced by cookies encrypted by the current secret at the end of the request.
+
+// The prefix of the encrypted session cookies (the version of the encryption)
+const encryptedSessionPrefix = "v1."
+
+func init() {
+	OnAppStart(func() {
+		if !Config.BoolDefault("session.encrypt", false) {
+			return
+		}
+		secret := Config.StringDefault("app.secret", "")
+		if secret == "" {
+			utilLog.Error("Session: session.encrypt needs app.secret, the session cookie is signed only")
+			return
+		}
+		codec := &EncryptedSessionCodec{
+			Keys:   [][]byte{DeriveSessionKey([]byte(secret))},
+			Legacy: Config.BoolDefault("session.encrypt.legacy", false),
+		}
+		for _, old := range strings.Split(Config.StringDefault("session.encrypt.keys", ""), ",") {
+			if old = strings.TrimSpace(old); old != "" {
+				codec.Keys = append(codec.Keys, DeriveSessionKey([]byte(old)))
+			}
+		}
+		SessionCookieCodec = codec
+	})
+}
+
+// DeriveSessionKey returns the AES-256 key of the session cookies derived from the secret
+func DeriveSessionKey(secret []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("revel session encryption"))
+	return mac.Sum(nil)
+}
+
+// EncryptedSessionCodec encrypts the session cookie by AES-GCM
+type EncryptedSessionCodec struct {
+	// Keys are 32 bytes keys (see DeriveSessionKey), the first one encrypts and all of them
+	// decrypt
+	Keys [][]byte
+	// Legacy decodes the signed cookies of SignedSessionCodec too
+	Legacy bool
+}
+
+var errSessionDecrypt = errors.New("session cookie could not be decrypted")
+
+// Encode returns the encrypted value of the session
+func (codec *EncryptedSessionCodec) Encode(s Session) (string, error) {
+	if len(codec.Keys) == 0 {
+		return "", errors.New("no session encryption key")
+	}
+	aead, err := sessionCipher(codec.Keys[0])
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(encodeSessionValue(s)), nil)
+	return encryptedSessionPrefix + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Decode returns the session of the encrypted value, or of the signed value if Legacy
+func (codec *EncryptedSessionCodec) Decode(value string) (Session, error) {
+	session, _, err := codec.DecodeStale(value)
+	return session, err
+}
+
+// DecodeStale returns the session of the encrypted value, or of the signed value if Legacy.
+// The session is stale when the value is signed or was encrypted by a former key.
+func (codec *EncryptedSessionCodec) DecodeStale(value string) (Session, bool, error) {
+	if !strings.HasPrefix(value, encryptedSessionPrefix) {
+		if codec.Legacy {
+			session, err := SignedSessionCodec{}.Decode(value)
+			return session, true, err
+		}
+		return nil, false, errSessionDecrypt
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(value[len(encryptedSessionPrefix):])
+	if err != nil {
+		return nil, false, errSessionDecrypt
+	}
+	for i, key := range codec.Keys {
+		aead, err := sessionCipher(key)
+		if err != nil || len(sealed) < aead.NonceSize() {
+			continue
+		}
+		data, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+		if err != nil {
+			continue
+		}
+		session := make(Session)
+		ParseKeyValueCookie(string(data), func(key, val string) {
+			session[key] = val
+		})
+		return session, i > 0, nil
+	}
+	return nil, false, errSessionDecrypt
+}
+
+// Returns the AES-GCM of the key
+func sessionCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
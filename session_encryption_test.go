@@ -0,0 +1,92 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEncryptedSession(t *testing.T) {
+	defer func(codec SessionCodec) { SessionCookieCodec = codec }(SessionCookieCodec)
+	oldKey, newKey := DeriveSessionKey([]byte("old secret")), DeriveSessionKey([]byte("new secret"))
+	session := Session{"user": "jane"}
+
+	SessionCookieCodec = &EncryptedSessionCodec{Keys: [][]byte{oldKey}}
+	encrypted := session.Cookie()
+	if !strings.HasPrefix(encrypted.Value, encryptedSessionPrefix) || strings.Contains(encrypted.Value, "jane") {
+		t.Errorf("Expected an encrypted cookie, got %s", encrypted.Value)
+	}
+	if restored := GetSessionFromCookie(GoCookie(*encrypted)); restored["user"] != "jane" {
+		t.Errorf("Expected the session to be decrypted, got %v", restored)
+	}
+	tampered := *encrypted
+	tampered.Value = tampered.Value[:len(tampered.Value)-2] + "AA"
+	if restored := GetSessionFromCookie(GoCookie(tampered)); len(restored) != 0 {
+		t.Errorf("Expected a tampered cookie to be rejected, got %v", restored)
+	}
+
+	// The secret is rotated, the old key decrypts
+	SessionCookieCodec = &EncryptedSessionCodec{Keys: [][]byte{newKey, oldKey}}
+	if restored := GetSessionFromCookie(GoCookie(*encrypted)); restored["user"] != "jane" {
+		t.Errorf("Expected the old key to decrypt the session, got %v", restored)
+	}
+	SessionCookieCodec = &EncryptedSessionCodec{Keys: [][]byte{newKey}}
+	if restored := GetSessionFromCookie(GoCookie(*encrypted)); len(restored) != 0 {
+		t.Errorf("Expected a removed key to not decrypt the session, got %v", restored)
+	}
+
+	// The signed cookies are read when legacy is set
+	SessionCookieCodec = SignedSessionCodec{}
+	signed := session.Cookie()
+	SessionCookieCodec = &EncryptedSessionCodec{Keys: [][]byte{newKey}}
+	if restored := GetSessionFromCookie(GoCookie(*signed)); len(restored) != 0 {
+		t.Errorf("Expected the signed cookie to be rejected, got %v", restored)
+	}
+	SessionCookieCodec = &EncryptedSessionCodec{Keys: [][]byte{newKey}, Legacy: true}
+	if restored := GetSessionFromCookie(GoCookie(*signed)); restored["user"] != "jane" {
+		t.Errorf("Expected the signed cookie to be read, got %v", restored)
+	}
+}
+
+func TestStaleSessionCookieRewritten(t *testing.T) {
+	startFakeBookingApp()
+	defer func(codec SessionCodec) { SessionCookieCodec = codec }(SessionCookieCodec)
+	oldKey, newKey := DeriveSessionKey([]byte("old secret")), DeriveSessionKey([]byte("new secret"))
+	codec := &EncryptedSessionCodec{Keys: [][]byte{newKey, oldKey}, Legacy: true}
+	request := func(cookie *http.Cookie) *http.Cookie {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.AddCookie(cookie)
+		recorder := httptest.NewRecorder()
+		SessionFilter(NewTestController(recorder, req), []Filter{func(c *Controller, _ []Filter) {}})
+		rewritten, _ := getRecordedCookie(recorder, "REVEL_SESSION")
+		return rewritten
+	}
+	session := Session{"user": "jane"}
+	session.SetDefaultExpiration()
+
+	SessionCookieCodec = SignedSessionCodec{}
+	signed := session.Cookie()
+	SessionCookieCodec = &EncryptedSessionCodec{Keys: [][]byte{oldKey}}
+	encryptedByOldKey := session.Cookie()
+	SessionCookieCodec = codec
+	current := session.Cookie()
+
+	for name, cookie := range map[string]*http.Cookie{"signed": signed, "former key": encryptedByOldKey} {
+		rewritten := request(cookie)
+		if rewritten == nil {
+			t.Errorf("Expected the %s cookie to be rewritten", name)
+			continue
+		}
+		if restored, stale, err := codec.DecodeStale(rewritten.Value); err != nil || stale || restored["user"] != "jane" {
+			t.Errorf("Expected the %s cookie to be encrypted by the current key, got %v %v %v", name, restored, stale, err)
+		}
+	}
+	if rewritten := request(current); rewritten != nil {
+		t.Errorf("Expected the current cookie to be kept, got %s", rewritten.Value)
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"net/http"
+	"time"
+)
+
+// The browsers drop the cookies larger than 4KB, a session cookie which would be larger is
+// kept in the overflow store when set in app.conf, and the cookie has the ID of the session
+// only
+//   session.overflow = cache       # The cache module keeps the large sessions
+//   session.overflow = redis       # The session module keeps them in Redis
+//   session.overflow.size = 4000   # The size of the cookies which overflow
+// The session is back in the cookie once it is small enough.
+
+// SessionOverflowStorage keeps the sessions which are too large for the session cookie
+var SessionOverflowStorage SessionStore
+
+// The session key of the cookie of a session kept in the SessionOverflowStorage
+const sessionOverflowKey = "_OV"
+
+// The size of the session cookies which overflow, in bytes
+var sessionOverflowSize int64 = 4000
+
+func init() {
+	OnAppStart(func() {
+		sessionOverflowSize = ConfigSizeDefault("session.overflow.size", 4000, 1)
+		if Config.StringDefault("session.overflow", "") == "cache" {
+			SessionOverflowStorage = cacheSessionStore{}
+		}
+	})
+}
+
+// Returns the session of the cookie of an overflowed session, from the overflow store, an
+// empty session if it is not found
+func restoreOverflowedSession(cookieSession Session) Session {
+	if SessionOverflowStorage == nil {
+		utilLog.Error("Session: The session cookie is an overflowed session, but there is no session.overflow")
+		return make(Session)
+	}
+	session, err := SessionOverflowStorage.Get(cookieSession[SessionIDKey])
+	if err != nil {
+		if err != ErrSessionNotFound {
+			utilLog.Error("Session: Failed to get the overflowed session", "error", err)
+		}
+		return make(Session)
+	}
+	for _, key := range []string{SessionIDKey, TimestampKey, ActivityKey} {
+		if value, found := cookieSession[key]; found {
+			session[key] = value
+		}
+	}
+	return session
+}
+
+// Returns the cookie of the session, the cookie of its ID if it is too large and kept in the
+// overflow store. The overflowed session of the request (its ID if any) is removed from the
+// store when the session fits in the cookie, or when the session has another ID.
+func overflowedSessionCookie(s Session, overflowedID string) *http.Cookie {
+	cookie := s.Cookie()
+	if int64(len(cookie.String())) <= sessionOverflowSize {
+		destroyOverflowedSession(overflowedID)
+		return cookie
+	}
+	if SessionOverflowStorage == nil {
+		utilLog.Error("Session: The session cookie is too large for the browsers, set session.overflow",
+			"size", len(cookie.String()))
+		return cookie
+	}
+
+	id := s.ID()
+	if err := SessionOverflowStorage.Set(id, s, sessionStoreExpiration(s)); err != nil {
+		utilLog.Error("Session: Failed to store the overflowed session", "error", err)
+		return cookie
+	}
+	if overflowedID != id {
+		destroyOverflowedSession(overflowedID)
+	}
+	ref := Session{SessionIDKey: id, sessionOverflowKey: "1"}
+	if s[TimestampKey] == sessionKeyName {
+		ref.SetNoExpiration()
+	}
+	return ref.Cookie()
+}
+
+// Removes the overflowed session of the ID from the overflow store
+func destroyOverflowedSession(id string) {
+	if id == "" || SessionOverflowStorage == nil {
+		return
+	}
+	if err := SessionOverflowStorage.Destroy(id); err != nil {
+		utilLog.Error("Session: Failed to remove the overflowed session", "error", err)
+	}
+}
+
+// Returns how long a store keeps the session, a day for the sessions which end when the
+// browser is closed
+func sessionStoreExpiration(s Session) time.Duration {
+	if expireAfterDuration == 0 || s[TimestampKey] == sessionKeyName {
+		return 24 * time.Hour
+	}
+	return expireAfterDuration
+}
+
+// The sessions kept in the ActionCache of the cache module
+type cacheSessionStore struct{}
+
+// Returns the key of the session in the cache
+func cacheSessionKey(id string) string {
+	return "revel-session:" + id
+}
+
+func (cacheSessionStore) Get(id string) (Session, error) {
+	if ActionCache == nil {
+		return nil, ErrSessionNotFound
+	}
+	session := Session{}
+	if err := ActionCache.Get(cacheSessionKey(id), &session); err != nil {
+		return nil, ErrSessionNotFound
+	}
+	return session, nil
+}
+
+func (cacheSessionStore) Set(id string, session Session, expires time.Duration) error {
+	if ActionCache == nil {
+		utilLog.Error("Session: The cache session.overflow needs the cache module")
+		return ErrSessionNotFound
+	}
+	return ActionCache.Set(cacheSessionKey(id), session, expires)
+}
+
+func (cacheSessionStore) Destroy(id string) error {
+	if ActionCache == nil {
+		return nil
+	}
+	return ActionCache.Delete(cacheSessionKey(id))
+}
+
+func (store cacheSessionStore) Touch(id string, expires time.Duration) error {
+	session, err := store.Get(id)
+	if err != nil {
+		return err
+	}
+	return store.Set(id, session, expires)
+}
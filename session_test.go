@@ -6,6 +6,9 @@ package revel
 
 import (
 	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -66,3 +69,255 @@ func TestSessionExpire(t *testing.T) {
 		t.Error("expect expires", cookie.Expires, "before", expectExpire)
 	}
 }
+
+// A map backed SessionStore
+type testSessionStore map[string]Session
+
+func (m testSessionStore) Get(id string) (Session, error) {
+	session, found := m[id]
+	if !found {
+		return nil, ErrSessionNotFound
+	}
+	copied := Session{}
+	for key, value := range session {
+		copied[key] = value
+	}
+	return copied, nil
+}
+
+func (m testSessionStore) Set(id string, session Session, expires time.Duration) error {
+	m[id] = session
+	return nil
+}
+
+func (m testSessionStore) Destroy(id string) error {
+	delete(m, id)
+	return nil
+}
+
+func (m testSessionStore) Touch(id string, expires time.Duration) error {
+	return nil
+}
+
+func TestSessionStorage(t *testing.T) {
+	startFakeBookingApp()
+	store := testSessionStore{}
+	SessionStorage = store
+	defer func() { SessionStorage = nil }()
+
+	request := func(cookie *http.Cookie, action func(c *Controller)) *http.Cookie {
+		req, _ := http.NewRequest("GET", "/", nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		recorder := httptest.NewRecorder()
+		c := NewTestController(recorder, req)
+		SessionFilter(c, []Filter{func(c *Controller, _ []Filter) { action(c) }})
+		cookie, _ = getRecordedCookie(recorder, "REVEL_SESSION")
+		return cookie
+	}
+
+	large := strings.Repeat("x", 8192)
+	cookie := request(nil, func(c *Controller) {
+		c.Session["user"] = "jane"
+		c.Session["large"] = large
+	})
+	if cookie == nil || len(store) != 1 || len(cookie.Value) > 512 {
+		t.Fatalf("Expected the session in the store and its ID in the cookie, got %v %v", store, cookie)
+	}
+	id := GetSessionFromCookie(GoCookie(*cookie))[SessionIDKey]
+	if store[id]["large"] != large {
+		t.Errorf("Expected the large value in the store")
+	}
+
+	request(cookie, func(c *Controller) {
+		if c.Session["user"] != "jane" {
+			t.Errorf("Expected the session to be restored, got %v", c.Session)
+		}
+	})
+
+	// A regenerated session is stored under the new ID only
+	var newID string
+	cookie = request(cookie, func(c *Controller) { newID = c.Session.Regenerate() })
+	if newID == id || store[id] != nil || store[newID]["user"] != "jane" {
+		t.Errorf("Expected the session to be stored under its new ID only, got %v", store)
+	}
+	if GetSessionFromCookie(GoCookie(*cookie))[SessionIDKey] != newID {
+		t.Errorf("Expected the new ID in the cookie")
+	}
+	id = newID
+
+	// A session destroyed on the server is empty
+	if err := DestroySession(id); err != nil {
+		t.Fatal(err)
+	}
+	request(cookie, func(c *Controller) {
+		if len(c.Session) != 0 {
+			t.Errorf("Expected the destroyed session to be empty, got %v", c.Session)
+		}
+	})
+
+	// A session emptied by the action is destroyed
+	cookie = request(nil, func(c *Controller) { c.Session["user"] = "jane" })
+	if cookie = request(cookie, func(c *Controller) { delete(c.Session, "user"); delete(c.Session, SessionIDKey) }); cookie == nil || cookie.MaxAge >= 0 || len(store) != 0 {
+		t.Errorf("Expected the emptied session to be destroyed, got %v %v", store, cookie)
+	}
+}
+
+func TestSessionRefresh(t *testing.T) {
+	startFakeBookingApp()
+	defer func() { expireAfterDuration, sessionIdleTimeout = 30*24*time.Hour, 0 }()
+	expireAfterDuration, sessionIdleTimeout = time.Hour, 10*time.Minute
+
+	request := func(cookie *http.Cookie, action func(c *Controller)) *http.Cookie {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.AddCookie(cookie)
+		recorder := httptest.NewRecorder()
+		c := NewTestController(recorder, req)
+		SessionFilter(c, []Filter{func(c *Controller, _ []Filter) { action(c) }})
+		cookie, _ = getRecordedCookie(recorder, "REVEL_SESSION")
+		return cookie
+	}
+	nothing := func(c *Controller) {}
+
+	cookie := Session{"user": "jane"}.Cookie()
+	if request(cookie, nothing) != nil {
+		t.Error("Expected the cookie of an unchanged session to not be issued again")
+	}
+	if request(cookie, func(c *Controller) { c.Session["user"] = "john" }) == nil {
+		t.Error("Expected the cookie of a changed session to be issued")
+	}
+
+	// The idle time is below half of the idle timeout
+	session := Session{"user": "jane"}
+	session.Cookie()
+	session[ActivityKey] = strconv.FormatInt(time.Now().Add(-6*time.Minute).Unix(), 10)
+	cookie = &http.Cookie{Name: "REVEL_SESSION", Value: mustEncodeSession(t, session)}
+	if request(cookie, nothing) == nil {
+		t.Error("Expected the cookie of an idle session to be refreshed")
+	}
+
+	// The session had no request for the idle timeout
+	session[ActivityKey] = strconv.FormatInt(time.Now().Add(-11*time.Minute).Unix(), 10)
+	cookie = &http.Cookie{Name: "REVEL_SESSION", Value: mustEncodeSession(t, session)}
+	request(cookie, func(c *Controller) {
+		if c.Session["user"] != "" {
+			t.Errorf("Expected the idle session to expire, got %v", c.Session)
+		}
+	})
+
+	// The remaining lifetime is below half of the lifetime
+	session = Session{"user": "jane"}
+	session.Cookie()
+	session[TimestampKey] = strconv.FormatInt(time.Now().Add(20*time.Minute).Unix(), 10)
+	cookie = &http.Cookie{Name: "REVEL_SESSION", Value: mustEncodeSession(t, session)}
+	if request(cookie, nothing) == nil {
+		t.Error("Expected the cookie of a session which expires soon to be refreshed")
+	}
+}
+
+func mustEncodeSession(t *testing.T, session Session) string {
+	value, err := SessionCookieCodec.Encode(session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return value
+}
+
+func TestSessionOverflow(t *testing.T) {
+	startFakeBookingApp()
+	store := testSessionStore{}
+	SessionOverflowStorage = store
+	defer func() { SessionOverflowStorage = nil }()
+
+	request := func(cookie *http.Cookie, action func(c *Controller)) *http.Cookie {
+		req, _ := http.NewRequest("GET", "/", nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		recorder := httptest.NewRecorder()
+		c := NewTestController(recorder, req)
+		SessionFilter(c, []Filter{func(c *Controller, _ []Filter) { action(c) }})
+		cookie, _ = getRecordedCookie(recorder, "REVEL_SESSION")
+		return cookie
+	}
+
+	large := strings.Repeat("x", 5000)
+	cookie := request(nil, func(c *Controller) {
+		c.Session["user"] = "jane"
+		c.Session["large"] = large
+	})
+	if cookie == nil || len(cookie.String()) > 1024 || len(store) != 1 {
+		t.Fatalf("Expected the large session to overflow to the store, got %v %v", store, cookie)
+	}
+	request(cookie, func(c *Controller) {
+		if c.Session["large"] != large || c.Session["user"] != "jane" {
+			t.Errorf("Expected the overflowed session to be restored, got %v", c.Session)
+		}
+	})
+
+	// The session fits in the cookie again
+	cookie = request(cookie, func(c *Controller) { delete(c.Session, "large") })
+	if cookie == nil || len(store) != 0 {
+		t.Fatalf("Expected the session back in the cookie, got %v %v", store, cookie)
+	}
+	if session := GetSessionFromCookie(GoCookie(*cookie)); session["user"] != "jane" || session[sessionOverflowKey] != "" {
+		t.Errorf("Expected the session in the cookie, got %v", session)
+	}
+}
+
+func TestSessionConcurrency(t *testing.T) {
+	startFakeBookingApp()
+	store := testSessionStore{"id": Session{SessionIDKey: "id", "user": "jane", "cart": "1"}}
+	SessionStorage = store
+	defer func() { SessionStorage, sessionConcurrency = nil, sessionConcurrencyNone }()
+	cookie := Session{SessionIDKey: "id"}.Cookie()
+
+	request := func(readOnly bool, action func(c *Controller)) {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.AddCookie(cookie)
+		c := NewTestController(httptest.NewRecorder(), req)
+		c.MethodType = &MethodType{sessionReadOnly: readOnly}
+		SessionFilter(c, []Filter{func(c *Controller, _ []Filter) { action(c) }})
+	}
+
+	// The changes of a request are merged in the session stored by another request
+	sessionConcurrency = sessionConcurrencyMerge
+	request(false, func(c *Controller) {
+		store["id"]["theme"] = "dark"
+		c.Session["cart"] = "2"
+		delete(c.Session, "user")
+	})
+	if session := store["id"]; session["theme"] != "dark" || session["cart"] != "2" || session["user"] != "" {
+		t.Errorf("Expected the changes to be merged, got %v", session)
+	}
+
+	// The changes of a read only action are discarded
+	request(true, func(c *Controller) { c.Session["cart"] = "3" })
+	if store["id"]["cart"] != "2" {
+		t.Errorf("Expected the changes of the read only action to be discarded, got %v", store["id"])
+	}
+
+	// The requests of a session wait for its lock
+	sessionConcurrency = sessionConcurrencyLock
+	unlock, err := processSessionLocks.lock("id", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan bool)
+	go request(false, func(c *Controller) { done <- true })
+	select {
+	case <-done:
+		t.Error("Expected the request to wait for the lock of the session")
+	case <-time.After(50 * time.Millisecond):
+	}
+	unlock()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("Expected the request to run once the session is unlocked")
+	}
+	if _, err = processSessionLocks.lock("id", 10*time.Millisecond); err != nil {
+		t.Errorf("Expected the lock to be released, got %v", err)
+	}
+}
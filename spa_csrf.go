@@ -0,0 +1,154 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The SPA mode protects single page applications which use the cookie session against CSRF.
+// The client fetches a token bound to its session and sends it in a header with every
+// request which changes state, the token cannot be read by another site. Enable it in app.conf
+//   spa.csrf = true
+//   spa.csrf.path = /@csrf             # GET returns {"token":"..."} (and the token header)
+//   spa.csrf.header = X-CSRF-Token     # The header the client sends the token in
+//   spa.csrf.default = required        # Or exempt, actions override it with @CSRFExempt and @CSRFRequired
+//   spa.cors.origins = https://app.example.com,https://admin.example.com
+//   spa.cors.maxage = 600              # Seconds a browser may cache the preflight response
+// Cross origin requests are allowed (with credentials) from the listed origins only,
+// state changing requests from any other origin are rejected.
+
+// The session key the CSRF token is stored in
+const SPA_CSRF_SESSION_KEY = "_csrf"
+
+// The methods which do not change state, they are not checked for the token
+var spaSafeMethods = map[string]bool{"GET": true, "HEAD": true, "OPTIONS": true, "TRACE": true}
+
+var spaLog = RevelLog.New("section", "spa")
+
+// The SPA configuration, loaded on startup
+var spaConfig struct {
+	enabled bool
+	exempt  bool // The actions are exempt from the check unless annotated with @CSRFRequired
+	path    string
+	header  string
+	origins map[string]bool
+	maxAge  int
+}
+
+func init() {
+	OnAppStart(func() {
+		spaConfig.enabled = Config.BoolDefault("spa.csrf", false)
+		spaConfig.exempt = Config.StringDefault("spa.csrf.default", "required") == "exempt"
+		spaConfig.path = Config.StringDefault("spa.csrf.path", "/@csrf")
+		spaConfig.header = http.CanonicalHeaderKey(Config.StringDefault("spa.csrf.header", "X-CSRF-Token"))
+		spaConfig.maxAge = int(ConfigDurationDefault("spa.cors.maxage", 600*time.Second, time.Second).Seconds())
+		spaConfig.origins = map[string]bool{}
+		for _, origin := range strings.Split(Config.StringDefault("spa.cors.origins", ""), ",") {
+			if origin = strings.TrimRight(strings.TrimSpace(origin), "/"); origin == "*" {
+				// Credentials are never sent to a wildcard origin
+				spaLog.Fatal("spa.cors.origins must list the origins, a wildcard is not allowed")
+			} else if origin != "" {
+				spaConfig.origins[strings.ToLower(origin)] = true
+			}
+		}
+	})
+}
+
+// SPACSRFFilter implements the SPA mode, it serves the token endpoint, answers the CORS
+// preflight requests and rejects state changing requests without the session token.
+// It runs before the RouterFilter since the preflight requests and the token endpoint have no route.
+func SPACSRFFilter(c *Controller, fc []Filter) {
+	if !spaConfig.enabled {
+		fc[0](c, fc[1:])
+		return
+	}
+
+	origin := c.Request.GetHttpHeader("Origin")
+	crossOrigin := origin != "" && !spaSameOrigin(c.Request, origin)
+	if crossOrigin {
+		if !spaConfig.origins[strings.ToLower(origin)] {
+			if c.Request.Method == "OPTIONS" || !spaSafeMethods[c.Request.Method] {
+				spaLog.Warn("SPACSRFFilter: Rejected request from origin", "origin", origin, "path", c.Request.GetPath())
+				c.Result = c.Forbidden("Origin not allowed")
+				return
+			}
+		} else {
+			spaCORSHeaders(c, origin)
+		}
+	}
+
+	// Preflight request
+	if crossOrigin && c.Request.Method == "OPTIONS" && c.Request.GetHttpHeader("Access-Control-Request-Method") != "" {
+		header := c.Response.Out.Header()
+		header.Set("Access-Control-Allow-Methods", "GET, HEAD, POST, PUT, PATCH, DELETE")
+		header.Set("Access-Control-Allow-Headers", "Content-Type, "+spaConfig.header)
+		header.Set("Access-Control-Max-Age", strconv.Itoa(spaConfig.maxAge))
+		c.Response.Status = http.StatusNoContent
+		c.Result = c.RenderText("")
+		return
+	}
+
+	if c.Request.Method == "GET" && c.Request.GetPath() == spaConfig.path {
+		// The token is kept in the session by the SessionFilter, in the cookie or in the
+		// SessionStorage
+		SessionFilter(c, []Filter{func(c *Controller, _ []Filter) {
+			token := c.Session[SPA_CSRF_SESSION_KEY]
+			if token == "" {
+				token = newSPAToken()
+				c.Session[SPA_CSRF_SESSION_KEY] = token
+			}
+			c.Response.Out.Header().Set(spaConfig.header, token)
+			c.Response.Out.Header().Set("Cache-Control", "no-store")
+			c.Result = c.RenderJSON(map[string]string{"token": token})
+		}})
+		return
+	}
+
+	session := restoreRequestSession(c.Request)
+	if !spaSafeMethods[c.Request.Method] && spaCSRFRequired(c) && !spaTokenValid(session[SPA_CSRF_SESSION_KEY], c.Request.GetHttpHeader(spaConfig.header)) {
+		spaLog.Warn("SPACSRFFilter: Missing or invalid CSRF token", "path", c.Request.GetPath(), "method", c.Request.Method)
+		c.Result = c.Forbidden("Missing or invalid CSRF token")
+		return
+	}
+
+	fc[0](c, fc[1:])
+}
+
+// Adds the headers which allow the origin to read the response with credentials
+func spaCORSHeaders(c *Controller, origin string) {
+	header := c.Response.Out.Header()
+	header.Set("Access-Control-Allow-Origin", origin)
+	header.Set("Access-Control-Allow-Credentials", "true")
+	header.Set("Access-Control-Expose-Headers", spaConfig.header)
+	header.Add("Vary", "Origin")
+}
+
+// Returns true if the origin is the host the request was sent to
+func spaSameOrigin(req *Request, origin string) bool {
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, req.Host)
+}
+
+// Compares the tokens in constant time, an empty token is never valid
+func spaTokenValid(sessionToken, requestToken string) bool {
+	return sessionToken != "" && subtle.ConstantTimeCompare([]byte(sessionToken), []byte(requestToken)) == 1
+}
+
+// Returns a new random token
+func newSPAToken() string {
+	buffer := make([]byte, 32)
+	if _, err := rand.Read(buffer); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(buffer)
+}
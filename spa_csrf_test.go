@@ -0,0 +1,104 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func spaRequest(method, path, origin, token string, session Session) (*Controller, *httptest.ResponseRecorder, bool) {
+	req, _ := http.NewRequest(method, "http://api.example.com"+path, nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	if token != "" {
+		req.Header.Set("X-CSRF-Token", token)
+	}
+	if session != nil {
+		req.AddCookie(session.Cookie())
+	}
+	resp := httptest.NewRecorder()
+	c := NewTestController(resp, req)
+	called := false
+	SPACSRFFilter(c, []Filter{func(c *Controller, fc []Filter) { called = true }})
+	return c, resp, called
+}
+
+func TestSPACSRFFilter(t *testing.T) {
+	startFakeBookingApp()
+	spaConfig.enabled = true
+	spaConfig.origins = map[string]bool{"https://app.example.com": true}
+	defer func() { spaConfig.enabled = false }()
+
+	// The token endpoint creates the token in the session
+	c, resp, called := spaRequest("GET", "/@csrf", "https://app.example.com", "", nil)
+	token := resp.Header().Get("X-CSRF-Token")
+	if called || token == "" || resp.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" {
+		t.Fatalf("Expected the token endpoint to return a token with the CORS headers, got %v", resp.Header())
+	}
+	session := Session{SPA_CSRF_SESSION_KEY: token}
+
+	if _, _, called = spaRequest("POST", "/hotels", "https://app.example.com", token, session); !called {
+		t.Errorf("Expected the request with the session token to be allowed")
+	}
+	if c, _, called = spaRequest("POST", "/hotels", "https://app.example.com", "invalid", session); called || c.Response.Status != http.StatusForbidden {
+		t.Errorf("Expected the request with an invalid token to be forbidden")
+	}
+	if c, _, called = spaRequest("DELETE", "/hotels/1", "", "", session); called || c.Response.Status != http.StatusForbidden {
+		t.Errorf("Expected the request without a token to be forbidden")
+	}
+	if c, _, called = spaRequest("POST", "/hotels", "https://evil.example.com", token, session); called || c.Response.Status != http.StatusForbidden {
+		t.Errorf("Expected the request from an unknown origin to be forbidden")
+	}
+	if _, _, called = spaRequest("GET", "/hotels", "https://evil.example.com", "", nil); !called {
+		t.Errorf("Expected the safe request from an unknown origin to be allowed")
+	}
+
+	// Preflight
+	req, _ := http.NewRequest("OPTIONS", "http://api.example.com/hotels", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	resp = httptest.NewRecorder()
+	c = NewTestController(resp, req)
+	SPACSRFFilter(c, NilChain)
+	if c.Response.Status != http.StatusNoContent || resp.Header().Get("Access-Control-Allow-Headers") != "Content-Type, X-Csrf-Token" {
+		t.Errorf("Unexpected preflight response %d %v", c.Response.Status, resp.Header())
+	}
+}
+
+func TestSPACSRFFilterSessionStorage(t *testing.T) {
+	startFakeBookingApp()
+	spaConfig.enabled = true
+	store := testSessionStore{}
+	SessionStorage = store
+	defer func() { spaConfig.enabled, SessionStorage = false, nil }()
+
+	// The token is kept in the stored session, the cookie has its ID only
+	_, resp, _ := spaRequest("GET", "/@csrf", "", "", nil)
+	token := resp.Header().Get("X-CSRF-Token")
+	cookie, _ := getRecordedCookie(resp, "REVEL_SESSION")
+	if token == "" || cookie == nil || len(store) != 1 {
+		t.Fatalf("Expected the token in the stored session, got %v %v", store, cookie)
+	}
+	session := GetSessionFromCookie(GoCookie(*cookie))
+	if session[SPA_CSRF_SESSION_KEY] != "" || store[session[SessionIDKey]][SPA_CSRF_SESSION_KEY] != token {
+		t.Fatalf("Expected the token in the store only, got %v %v", store, session)
+	}
+
+	// The session of the cookie is rewritten by the SessionFilter, the token is kept
+	store[session[SessionIDKey]]["user"] = "jane"
+	if _, resp, _ = spaRequest("GET", "/@csrf", "", "", session); resp.Header().Get("X-CSRF-Token") != token ||
+		store[session[SessionIDKey]]["user"] != "jane" {
+		t.Errorf("Expected the same token in the stored session, got %s %v", resp.Header().Get("X-CSRF-Token"), store)
+	}
+	if _, _, called := spaRequest("POST", "/hotels", "", token, session); !called {
+		t.Errorf("Expected the request with the stored token to be allowed")
+	}
+	if c, _, called := spaRequest("POST", "/hotels", "", "invalid", session); called || c.Response.Status != http.StatusForbidden {
+		t.Errorf("Expected the request with an invalid token to be forbidden")
+	}
+}
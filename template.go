@@ -46,6 +46,13 @@ type Template interface {
 	Location() string // Disk location
 }
 
+// FragmentTemplate is implemented by the templates which render a named fragment of
+// themselves (a block or define of the Go templates), used by RenderTemplateFragment.
+type FragmentTemplate interface {
+	// Called by the server to render the fragment out the io.Writer.
+	RenderFragment(wr io.Writer, fragment string, context interface{}) error
+}
+
 var invalidSlugPattern = regexp.MustCompile(`[^a-z0-9 _-]`)
 var whiteSpacePattern = regexp.MustCompile(`\s+`)
 var templateLog = RevelLog.New("section", "template")
@@ -108,14 +115,24 @@ func (loader *TemplateLoader) Refresh() (err *Error) {
 	loader.templateMutex.Lock()
 	defer loader.templateMutex.Unlock()
 
+	runtimeLoader, err := loader.load()
+	if runtimeLoader != nil {
+		loader.runtimeLoader.Store(runtimeLoader)
+	}
+	return
+}
+
+// Scans the paths and parses all the templates into a new runtime, the caller must hold the
+// templateMutex and store the runtime. The runtime is nil if the engines could not be initialized
+func (loader *TemplateLoader) load() (runtimeLoader *templateRuntime, err *Error) {
 	loader.loadVersionSeed++
-	runtimeLoader := &templateRuntime{loader: loader,
+	runtimeLoader = &templateRuntime{loader: loader,
 		version:     loader.loadVersionSeed,
 		templateMap: map[string]Template{}}
 
 	templateLog.Debug("Refresh: Refreshing templates from ", "path", loader.paths)
 	if err = loader.initializeEngines(runtimeLoader, Config.StringDefault("template.engines", GO_TEMPLATE)); err != nil {
-		return
+		return nil, err
 	}
 	for _, engine := range runtimeLoader.templatesAndEngineList {
 		engine.Event(TEMPLATE_REFRESH_REQUESTED, nil)
@@ -126,9 +143,6 @@ func (loader *TemplateLoader) Refresh() (err *Error) {
 			engine.Event(TEMPLATE_REFRESH_COMPLETED, nil)
 		}
 		fireEvent(TEMPLATE_REFRESH_COMPLETED, nil)
-
-		// Reset the runtimeLoader
-		loader.runtimeLoader.Store(runtimeLoader)
 	}()
 
 	// Resort the paths, make sure the revel path is the last path,
@@ -223,12 +237,12 @@ func (loader *TemplateLoader) Refresh() (err *Error) {
 		// If there was an error with the Funcs, set it and return immediately.
 		if funcErr != nil {
 			runtimeLoader.compileError = NewErrorFromPanic(funcErr)
-			return runtimeLoader.compileError
+			return runtimeLoader, runtimeLoader.compileError
 		}
 	}
 
 	// Note: compileError may or may not be set.
-	return runtimeLoader.compileError
+	return runtimeLoader, runtimeLoader.compileError
 }
 
 type templateRuntime struct {
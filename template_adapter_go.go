@@ -1,10 +1,12 @@
 package revel
 
 import (
+	"fmt"
 	"html/template"
 	"io"
 	"log"
 	"strings"
+	"text/template/parse"
 )
 
 const GO_TEMPLATE = "go"
@@ -14,19 +16,42 @@ type GoTemplate struct {
 	*template.Template
 	engine *GoEngine
 	*TemplateView
+	viewArgRefs []viewArgReference // The view args referenced by the template, populated in strict mode
+	fragments   map[string]string  // The names in the set of the blocks and defines of the template
 }
 
 // return a 'revel.Template' from Go's template.
 func (gotmpl GoTemplate) Render(wr io.Writer, arg interface{}) error {
+	if gotmpl.engine.strictMode != "" {
+		if err := gotmpl.checkViewArgs(arg); err != nil {
+			return err
+		}
+	}
 	return gotmpl.Execute(wr, arg)
 }
 
+// RenderFragment renders the block or define named fragment of the template, without the
+// rest of the page. The fragment is the one of the template, even when another template
+// defines the same name. The view args are not checked in strict mode, the fragment may use
+// only some of them.
+func (gotmpl GoTemplate) RenderFragment(wr io.Writer, fragment string, arg interface{}) error {
+	var tmpl *template.Template
+	if name, found := gotmpl.fragments[fragment]; found {
+		tmpl = gotmpl.Lookup(name)
+	}
+	if tmpl == nil {
+		return fmt.Errorf("template %s has no fragment %s", gotmpl.Name(), fragment)
+	}
+	return tmpl.Execute(wr, arg)
+}
+
 type GoEngine struct {
 	loader          *TemplateLoader
 	templateSet     *template.Template
 	templatesByName map[string]*GoTemplate
 	splitDelims     []string
 	CaseInsensitive bool
+	strictMode      string // Set by template.go.strict, either "error", "log" or empty
 }
 
 func (i *GoEngine) ConvertPath(path string) string {
@@ -42,15 +67,18 @@ func (i *GoEngine) Handles(templateView *TemplateView) bool {
 
 func (engine *GoEngine) ParseAndAdd(baseTemplate *TemplateView) error {
 	// If alternate delimiters set for the project, change them for this set
+	leftDelim, rightDelim := "", ""
 	if engine.splitDelims != nil && strings.Index(baseTemplate.Location(), ViewsPath) > -1 {
-		engine.templateSet.Delims(engine.splitDelims[0], engine.splitDelims[1])
-	} else {
-		// Reset to default otherwise
-		engine.templateSet.Delims("", "")
+		leftDelim, rightDelim = engine.splitDelims[0], engine.splitDelims[1]
 	}
+	engine.templateSet.Delims(leftDelim, rightDelim)
 	templateSource := string(baseTemplate.FileBytes)
 	templateName := engine.ConvertPath(baseTemplate.TemplateName)
 	tpl, err := engine.templateSet.New(baseTemplate.TemplateName).Parse(templateSource)
+	var fragments map[string]string
+	if err == nil {
+		fragments, err = engine.addFragments(baseTemplate.TemplateName, templateSource, leftDelim, rightDelim)
+	}
 	if nil != err {
 		_, line, description := ParseTemplateError(err)
 		return &Error{
@@ -61,10 +89,36 @@ func (engine *GoEngine) ParseAndAdd(baseTemplate *TemplateView) error {
 			SourceLines: strings.Split(templateSource, "\n"),
 		}
 	}
-	engine.templatesByName[templateName] = &GoTemplate{Template: tpl, engine: engine, TemplateView: baseTemplate}
+	engine.templatesByName[templateName] = &GoTemplate{Template: tpl, engine: engine, TemplateView: baseTemplate, fragments: fragments}
 	return nil
 }
 
+// Adds the blocks and defines of the template to the set under names of their own (the name
+// of the template, # and the name of the fragment), the set keeps only the last definition of
+// a name. Returns the names in the set of the fragments.
+func (engine *GoEngine) addFragments(templateName, templateSource, leftDelim, rightDelim string) (map[string]string, error) {
+	if !strings.Contains(templateSource, "define") && !strings.Contains(templateSource, "block") {
+		return nil, nil
+	}
+	trees := map[string]*parse.Tree{}
+	tree := parse.New(templateName)
+	tree.Mode = parse.SkipFuncCheck
+	if _, err := tree.Parse(templateSource, leftDelim, rightDelim, trees); err != nil {
+		return nil, err
+	}
+	fragments := map[string]string{}
+	for fragment, fragmentTree := range trees {
+		if fragment == templateName {
+			continue
+		}
+		fragments[fragment] = templateName + "#" + fragment
+		if _, err := engine.templateSet.AddParseTree(fragments[fragment], fragmentTree); err != nil {
+			return nil, err
+		}
+	}
+	return fragments, nil
+}
+
 func (engine *GoEngine) Lookup(templateName string) Template {
 	// Case-insensitive matching of template file name
 	if tpl, found := engine.templatesByName[engine.ConvertPath(templateName)]; found {
@@ -82,6 +136,13 @@ func (engine *GoEngine) Event(action int, i interface{}) {
 		engine.templateSet = template.New("__root__").Funcs(TemplateFuncs)
 		// Check to see what should be used for case sensitivity
 		engine.CaseInsensitive = Config.BoolDefault("go.template.caseinsensitive", true)
+		engine.strictMode = templateStrictMode()
+	} else if action == TEMPLATE_REFRESH_COMPLETED && engine.strictMode != "" {
+		// All the templates are parsed, find the view args each one references
+		cache := map[string][]viewArgReference{}
+		for _, tpl := range engine.templatesByName {
+			tpl.viewArgRefs = findViewArgReferences(tpl.Template, cache)
+		}
 	}
 }
 func init() {
@@ -0,0 +1,204 @@
+package revel
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+	"text/template/parse"
+)
+
+// Strict mode for Go templates, enabled using
+//   template.go.strict = error   # Fail the render if the template references a missing view arg
+//   template.go.strict = log     # Log the template and line referencing the missing view arg
+// Only the view args referenced from the top level of a template (or a template
+// included using {{template "name" .}}) are checked. A reference which is the
+// only argument of an if, with or range is treated as optional, so
+//   {{if .errors}}
+// does not fail when there are no errors, and so are its references in the body.
+const (
+	TEMPLATE_STRICT_ERROR = "error"
+	TEMPLATE_STRICT_LOG   = "log"
+)
+
+// A view arg referenced by a template, location is in the form of name:line:column
+type viewArgReference struct {
+	key      string
+	location string
+}
+
+// Returns the strict mode from the configuration
+func templateStrictMode() string {
+	switch mode := strings.ToLower(Config.StringDefault("template.go.strict", "")); mode {
+	case TEMPLATE_STRICT_ERROR, "true":
+		return TEMPLATE_STRICT_ERROR
+	case TEMPLATE_STRICT_LOG:
+		return TEMPLATE_STRICT_LOG
+	case "", "false":
+	default:
+		templateLog.Warn("Unknown template.go.strict mode, strict mode is disabled", "mode", mode)
+	}
+	return ""
+}
+
+// Checks the view args against the references in the template
+func (gotmpl GoTemplate) checkViewArgs(arg interface{}) error {
+	viewArgs, ok := arg.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	for _, ref := range gotmpl.viewArgRefs {
+		if _, found := viewArgs[ref.key]; found {
+			continue
+		}
+		if gotmpl.engine.strictMode == TEMPLATE_STRICT_ERROR {
+			// The format matches the Go template errors so ParseTemplateError can find the line
+			return fmt.Errorf("template: %s: undefined view arg %q", ref.location, ref.key)
+		}
+		templateName, line, _ := ParseTemplateError(fmt.Errorf("template: %s: ", ref.location))
+		templateLog.Warn("Template references an undefined view arg", "template", templateName, "line", line, "key", ref.key)
+	}
+	return nil
+}
+
+// Walks the template tree to find the view args it references
+type viewArgWalker struct {
+	template *template.Template
+	tree     *parse.Tree
+	refs     []viewArgReference
+	seen     map[viewArgReference]bool
+	guarded  map[string]int                // The view args checked by an enclosing if, with or range
+	cache    map[string][]viewArgReference // The references of each template of the set
+}
+
+// Returns the view args referenced by the template, the references of the templates it
+// includes are taken from the cache so each template of the set is walked once
+func findViewArgReferences(tmpl *template.Template, cache map[string][]viewArgReference) []viewArgReference {
+	return viewArgReferencesOf(tmpl, tmpl.Name(), cache)
+}
+
+func viewArgReferencesOf(set *template.Template, name string, cache map[string][]viewArgReference) []viewArgReference {
+	if refs, found := cache[name]; found {
+		return refs
+	}
+	// A template including itself does not add its references again
+	cache[name] = nil
+	tmpl := set.Lookup(name)
+	if tmpl == nil || tmpl.Tree == nil || tmpl.Tree.Root == nil {
+		return nil
+	}
+	w := &viewArgWalker{template: set, tree: tmpl.Tree, seen: map[viewArgReference]bool{}, guarded: map[string]int{}, cache: cache}
+	w.walk(tmpl.Tree.Root, true)
+	cache[name] = w.refs
+	return w.refs
+}
+
+// Dot is the view args only when isRoot is true
+func (w *viewArgWalker) walk(node parse.Node, isRoot bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			w.walk(child, isRoot)
+		}
+	case *parse.ActionNode:
+		w.walkPipe(n.Pipe, isRoot, false)
+	case *parse.IfNode:
+		w.walkPipe(n.Pipe, isRoot, true)
+		w.walkGuarded(n.Pipe, n.List, isRoot, isRoot)
+		w.walk(n.ElseList, isRoot)
+	case *parse.RangeNode:
+		w.walkPipe(n.Pipe, isRoot, true)
+		w.walkGuarded(n.Pipe, n.List, isRoot, false)
+		w.walk(n.ElseList, isRoot)
+	case *parse.WithNode:
+		w.walkPipe(n.Pipe, isRoot, true)
+		w.walkGuarded(n.Pipe, n.List, isRoot, false)
+		w.walk(n.ElseList, isRoot)
+	case *parse.TemplateNode:
+		if n.Pipe != nil && len(n.Pipe.Cmds) == 1 && len(n.Pipe.Cmds[0].Args) == 1 {
+			if _, ok := n.Pipe.Cmds[0].Args[0].(*parse.DotNode); ok {
+				if isRoot {
+					for _, ref := range viewArgReferencesOf(w.template, n.Name, w.cache) {
+						if w.guarded[ref.key] == 0 {
+							w.addReference(ref)
+						}
+					}
+				}
+				return
+			}
+		}
+		w.walkPipe(n.Pipe, isRoot, false)
+	}
+}
+
+// Walks the list of an if, with or range, the view arg the pipe checks is optional in
+// the list, so
+//   {{if .user}}{{.user.Name}}{{end}}
+// does not fail when there is no user
+func (w *viewArgWalker) walkGuarded(pipe *parse.PipeNode, list *parse.ListNode, isRoot, listIsRoot bool) {
+	key := ""
+	if pipe != nil && len(pipe.Decl) == 0 && len(pipe.Cmds) == 1 && len(pipe.Cmds[0].Args) == 1 {
+		switch n := pipe.Cmds[0].Args[0].(type) {
+		case *parse.FieldNode:
+			if isRoot {
+				key = n.Ident[0]
+			}
+		case *parse.VariableNode:
+			if n.Ident[0] == "$" && len(n.Ident) > 1 {
+				key = n.Ident[1]
+			}
+		}
+	}
+	if key != "" {
+		w.guarded[key]++
+		defer func() { w.guarded[key]-- }()
+	}
+	w.walk(list, listIsRoot)
+}
+
+func (w *viewArgWalker) walkPipe(pipe *parse.PipeNode, isRoot, optional bool) {
+	if pipe == nil {
+		return
+	}
+	optional = optional && len(pipe.Cmds) == 1 && len(pipe.Cmds[0].Args) == 1
+	for _, cmd := range pipe.Cmds {
+		for _, arg := range cmd.Args {
+			w.walkArg(arg, isRoot, optional)
+		}
+	}
+}
+
+func (w *viewArgWalker) walkArg(node parse.Node, isRoot, optional bool) {
+	switch n := node.(type) {
+	case *parse.FieldNode:
+		if isRoot && !optional {
+			w.add(n, n.Ident[0])
+		}
+	case *parse.VariableNode:
+		if n.Ident[0] == "$" && len(n.Ident) > 1 && !optional {
+			w.add(n, n.Ident[1])
+		}
+	case *parse.ChainNode:
+		w.walkArg(n.Node, isRoot, false)
+	case *parse.PipeNode:
+		w.walkPipe(n, isRoot, false)
+	}
+}
+
+func (w *viewArgWalker) add(node parse.Node, key string) {
+	if w.guarded[key] > 0 {
+		return
+	}
+	location, _ := w.tree.ErrorContext(node)
+	w.addReference(viewArgReference{key: key, location: location})
+}
+
+// Adds the reference once, a template may be included several times
+func (w *viewArgWalker) addReference(ref viewArgReference) {
+	if !w.seen[ref] {
+		w.seen[ref] = true
+		w.refs = append(w.refs, ref)
+	}
+}
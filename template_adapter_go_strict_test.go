@@ -0,0 +1,88 @@
+package revel
+
+import (
+	"bytes"
+	"html/template"
+	"strings"
+	"testing"
+)
+
+func newStrictTestEngine(t *testing.T, mode string, templates map[string]string) *GoEngine {
+	engine := &GoEngine{
+		templateSet:     template.New("__root__").Funcs(TemplateFuncs),
+		templatesByName: map[string]*GoTemplate{},
+		strictMode:      mode,
+	}
+	for name, source := range templates {
+		if err := engine.ParseAndAdd(NewBaseTemplate(name, name, "", []byte(source))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	engine.Event(TEMPLATE_REFRESH_COMPLETED, nil)
+	return engine
+}
+
+func TestTemplateStrictMode(t *testing.T) {
+	engine := newStrictTestEngine(t, TEMPLATE_STRICT_ERROR, map[string]string{
+		"header.html": `<title>{{.title}}</title>`,
+		"show.html": `{{template "header.html" .}}
+{{if .errors}}{{range .errors}}{{.Message}}{{end}}{{end}}
+{{range .items}}{{.Name}} {{$.currency}}{{end}}
+{{.hotel.Name}}`,
+	})
+	tmpl := engine.Lookup("show.html")
+
+	viewArgs := map[string]interface{}{"title": "Hotel", "items": nil, "currency": "USD", "hotel": map[string]string{"Name": "Ritz"}}
+	if err := tmpl.Render(&bytes.Buffer{}, viewArgs); err != nil {
+		t.Errorf("Unexpected error %s", err)
+	}
+
+	for _, missing := range []string{"title", "currency", "hotel"} {
+		args := map[string]interface{}{}
+		for key, value := range viewArgs {
+			if key != missing {
+				args[key] = value
+			}
+		}
+		err := tmpl.Render(&bytes.Buffer{}, args)
+		if err == nil || !strings.Contains(err.Error(), `"`+missing+`"`) {
+			t.Errorf("Expected error for missing %s got %v", missing, err)
+			continue
+		}
+		if name, line, _ := ParseTemplateError(err); name == "" || line == 0 {
+			t.Errorf("Expected template name and line in %s", err)
+		}
+	}
+
+	engine.strictMode = TEMPLATE_STRICT_LOG
+	if err := tmpl.Render(&bytes.Buffer{}, map[string]interface{}{}); err != nil {
+		t.Errorf("Expected no error in log mode, got %s", err)
+	}
+}
+
+func TestTemplateStrictModeGuarded(t *testing.T) {
+	engine := newStrictTestEngine(t, TEMPLATE_STRICT_ERROR, map[string]string{
+		"user.html": `{{.user.Name}}`,
+		"index.html": `{{if .user}}{{.user.Name}} {{template "user.html" .}}{{else}}{{.login}}{{end}}
+{{with .cart}}{{$.cart.Total}}{{end}}`,
+		"profile.html": `{{template "user.html" .}}`,
+	})
+	if err := engine.Lookup("index.html").Render(&bytes.Buffer{}, map[string]interface{}{"login": "/login"}); err != nil {
+		t.Errorf("Expected the references guarded by an if or with to be optional, got %s", err)
+	}
+	if err := engine.Lookup("index.html").Render(&bytes.Buffer{}, map[string]interface{}{}); err == nil || !strings.Contains(err.Error(), `"login"`) {
+		t.Errorf("Expected the reference of the else branch to be checked, got %v", err)
+	}
+	if err := engine.Lookup("profile.html").Render(&bytes.Buffer{}, map[string]interface{}{}); err == nil || !strings.Contains(err.Error(), `"user"`) {
+		t.Errorf("Expected the reference of the included template to be checked, got %v", err)
+	}
+
+	// The included template is walked once for the set
+	cache := map[string][]viewArgReference{}
+	for _, name := range []string{"index.html", "profile.html"} {
+		findViewArgReferences(engine.Lookup(name).(*GoTemplate).Template, cache)
+	}
+	if refs := cache["user.html"]; len(refs) != 1 || refs[0].key != "user" {
+		t.Errorf("Expected the references of the included template in the cache, got %v", cache)
+	}
+}
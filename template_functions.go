@@ -29,6 +29,7 @@ var (
 			return template.JS("")
 		},
 		"field": NewField,
+		"seo":   SEOTags,
 		"firstof": func(args ...interface{}) interface{} {
 			for _, val := range args {
 				switch val.(type) {
@@ -149,6 +150,8 @@ var (
 
 		// Using https://github.com/xeonx/timeago
 		"timeago": TimeAgo,
+		// The validate tags of the arguments of an action (see ValidationSchema)
+		"validationSchema": validationSchemaJS,
 		"i18ntemplate": func(args ...interface{}) (template.HTML, error) {
 			templateName, lang := "", ""
 			var viewArgs interface{}
@@ -1,6 +1,11 @@
 greeting=Hallo 
 greeting.name=Rob
 greeting.suffix=, welkom bij Revel!
+time.location=Europe/Amsterdam
+
+validation.required=Verplicht
+validation.minsize=Minimale lengte is %d
+validation.required.booking.Name=Naam is verplicht
 
 [NL]
 greeting=Goeiedag
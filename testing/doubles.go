@@ -0,0 +1,311 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package testing
+
+import (
+	"bytes"
+	"go/format"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/revel/revel"
+)
+
+// GenerateTestDoubles writes the Go source of test doubles for the actions, so tests do
+// not build requests or fake controllers by hand. For every action it generates a request
+// type holding the typed arguments of the action, which builds the URL of the reverse
+// route and sends itself to the server of a TestSuite
+//   tests.HotelsShowRequest{Id: 3}.Send(&t.TestSuite)
+// and for every controller a mock recording the calls to its actions and returning the
+// results of the functions set on it
+//   mock := &tests.HotelsMock{ShowFunc: func(id int) revel.Result { return nil }}
+//   mock.Show(3) // mock.ShowCalls is []HotelsShowRequest{{Id: 3}}
+// The doubles are usually generated from a test of the application, for its actions
+//   var actions []*revel.ActionRef
+//   for _, action := range revel.Actions() {
+//   	if !strings.Contains(action.Name(), "\\") {
+//   		actions = append(actions, action)
+//   	}
+//   }
+//   err := testing.GenerateTestDoubles(file, "tests", actions)
+// The actions taking a websocket are skipped.
+func GenerateTestDoubles(w io.Writer, packageName string, actions []*revel.ActionRef) error {
+	data := &doublesData{Package: packageName}
+	imports := newDoublesImports()
+	controllers := map[string]*doublesController{}
+	names := map[string]bool{}
+	for _, action := range actions {
+		doubleAction := &doublesAction{Action: action.Name(), Method: "GET"}
+		if len(action.Routes) > 0 && action.Routes[0].Method != "*" {
+			doubleAction.Method = action.Routes[0].Method
+		}
+		websocket := false
+		// The fields of the request do not take the names of its methods, nor the receiver
+		// of the mock the name of an argument
+		fields := map[string]bool{"URL": true, "Send": true}
+		args := map[string]bool{}
+		for _, arg := range action.MethodType.Args {
+			if arg.Type.Implements(reflect.TypeOf((*revel.ServerWebSocket)(nil)).Elem()) {
+				websocket = true
+				break
+			}
+			field := upperFirst(arg.Name)
+			for fields[field] {
+				field += "Arg"
+			}
+			fields[field], args[arg.Name] = true, true
+			doubleAction.Args = append(doubleAction.Args, &doublesArg{Name: arg.Name, Field: field, Type: imports.typeName(arg.Type)})
+		}
+		if websocket {
+			continue
+		}
+		doubleAction.Receiver = "m"
+		for i := 2; args[doubleAction.Receiver]; i++ {
+			doubleAction.Receiver = "m" + strconv.Itoa(i)
+		}
+
+		// The controllers of the same name in several packages get the name of their package
+		typ := action.ControllerType.Type
+		controller, found := controllers[typ.PkgPath()+"."+typ.Name()]
+		if !found {
+			controller = &doublesController{Name: typ.Name()}
+			if names[controller.Name] {
+				controller.Name = upperFirst(packageOf(typ)) + controller.Name
+			}
+			names[controller.Name] = true
+			controllers[typ.PkgPath()+"."+typ.Name()] = controller
+			data.Controllers = append(data.Controllers, controller)
+		}
+		doubleAction.Controller, doubleAction.Name = controller.Name, action.MethodType.Name
+		controller.Actions = append(controller.Actions, doubleAction)
+	}
+	sort.Slice(data.Controllers, func(i, j int) bool { return data.Controllers[i].Name < data.Controllers[j].Name })
+	if len(data.Controllers) > 0 {
+		// Used by the mocks and the requests
+		for _, path := range []string{"net/http", "github.com/revel/revel", "github.com/revel/revel/testing"} {
+			imports.use(path)
+		}
+	}
+	data.Imports = imports.used()
+
+	var source bytes.Buffer
+	if err := doublesTemplate.Execute(&source, data); err != nil {
+		return err
+	}
+	formatted, err := format.Source(source.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(formatted)
+	return err
+}
+
+type doublesData struct {
+	Package     string
+	Imports     []*doublesImport
+	Controllers []*doublesController
+}
+
+type doublesController struct {
+	Name    string // The name of the controller type, prefixed by its package when it is not unique
+	Actions []*doublesAction
+}
+
+type doublesAction struct {
+	Controller string // The controller name
+	Name       string // The method name
+	Action     string // The action name used to reverse the route
+	Method     string // The HTTP method of the first route
+	Receiver   string // The receiver of the mock method, which is not the name of an argument
+	Args       []*doublesArg
+}
+
+type doublesArg struct {
+	Name  string // The argument name
+	Field string // The field of the request
+	Type  string // The type, qualified by the names of the imports
+}
+
+type doublesImport struct {
+	Path  string
+	Name  string // The name of the package in the generated source
+	Alias bool   // Set when the name is not the last element of the path
+	used  bool
+}
+
+// The packages the generated source may import, by path
+type doublesImports struct {
+	byPath map[string]*doublesImport
+	names  map[string]bool
+}
+
+func newDoublesImports() *doublesImports {
+	imports := &doublesImports{byPath: map[string]*doublesImport{}, names: map[string]bool{}}
+	imports.add("net/http", "http")
+	imports.add("github.com/revel/revel", "revel")
+	imports.add("github.com/revel/revel/testing", "testing")
+	return imports
+}
+
+// Adds the package of the path, named by its package name unless the name is taken
+func (imports *doublesImports) add(path, name string) *doublesImport {
+	if imported, found := imports.byPath[path]; found {
+		return imported
+	}
+	unique := name
+	for i := 2; imports.names[unique]; i++ {
+		unique = name + strconv.Itoa(i)
+	}
+	imported := &doublesImport{Path: path, Name: unique, Alias: unique != path[strings.LastIndex(path, "/")+1:]}
+	imports.byPath[path], imports.names[unique] = imported, true
+	return imported
+}
+
+func (imports *doublesImports) use(path string) {
+	imports.byPath[path].used = true
+}
+
+// Returns the imports used, sorted by path
+func (imports *doublesImports) used() (used []*doublesImport) {
+	for _, imported := range imports.byPath {
+		if imported.used {
+			used = append(used, imported)
+		}
+	}
+	sort.Slice(used, func(i, j int) bool { return used[i].Path < used[j].Path })
+	return
+}
+
+// Returns the Go source of the type, the named types are qualified by the names of their
+// imports
+func (imports *doublesImports) typeName(typ reflect.Type) string {
+	if typ.Name() != "" {
+		if typ.PkgPath() == "" {
+			return typ.Name()
+		}
+		imported := imports.add(typ.PkgPath(), packageOf(typ))
+		imported.used = true
+		return imported.Name + "." + typ.Name()
+	}
+	switch typ.Kind() {
+	case reflect.Ptr:
+		return "*" + imports.typeName(typ.Elem())
+	case reflect.Slice:
+		return "[]" + imports.typeName(typ.Elem())
+	case reflect.Array:
+		return "[" + strconv.Itoa(typ.Len()) + "]" + imports.typeName(typ.Elem())
+	case reflect.Map:
+		return "map[" + imports.typeName(typ.Key()) + "]" + imports.typeName(typ.Elem())
+	case reflect.Chan:
+		switch typ.ChanDir() {
+		case reflect.RecvDir:
+			return "<-chan " + imports.typeName(typ.Elem())
+		case reflect.SendDir:
+			return "chan<- " + imports.typeName(typ.Elem())
+		}
+		return "chan " + imports.typeName(typ.Elem())
+	case reflect.Struct:
+		fields := make([]string, typ.NumField())
+		for i := range fields {
+			field := typ.Field(i)
+			fields[i] = field.Name + " " + imports.typeName(field.Type)
+			if field.Anonymous {
+				fields[i] = imports.typeName(field.Type)
+			}
+			if field.Tag != "" {
+				fields[i] += " " + strconv.Quote(string(field.Tag))
+			}
+		}
+		return "struct{" + strings.Join(fields, "; ") + "}"
+	case reflect.Func:
+		in, out := make([]string, typ.NumIn()), make([]string, typ.NumOut())
+		for i := range in {
+			in[i] = imports.typeName(typ.In(i))
+		}
+		if typ.IsVariadic() {
+			in[len(in)-1] = "..." + imports.typeName(typ.In(len(in)-1).Elem())
+		}
+		for i := range out {
+			out[i] = imports.typeName(typ.Out(i))
+		}
+		return "func(" + strings.Join(in, ", ") + ") (" + strings.Join(out, ", ") + ")"
+	case reflect.Interface:
+		if typ.NumMethod() == 0 {
+			return "interface{}"
+		}
+	}
+	return typ.String()
+}
+
+// Returns the name with its first letter in upper case
+func upperFirst(name string) string {
+	if name == "" {
+		return name
+	}
+	r, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToUpper(r)) + name[size:]
+}
+
+// Returns the name of the package of the named type, which may not be the last element of
+// its path
+func packageOf(typ reflect.Type) string {
+	return strings.SplitN(typ.String(), ".", 2)[0]
+}
+
+var doublesTemplate = template.Must(template.New("doubles").Parse(`// Code generated by testing.GenerateTestDoubles. DO NOT EDIT.
+
+package {{.Package}}
+
+{{if .Imports}}
+import (
+{{range .Imports}}	{{if .Alias}}{{.Name}} {{end}}"{{.Path}}"
+{{end}}){{end}}
+{{range .Controllers}}{{$controller := .}}
+// {{.Name}}Mock is a test double of the {{.Name}} controller, it records the calls to
+// its actions and returns the results of their functions
+type {{.Name}}Mock struct {
+{{range .Actions}}	{{.Name}}Func func({{range $i, $arg := .Args}}{{if $i}}, {{end}}{{$arg.Name}} {{$arg.Type}}{{end}}) revel.Result
+	{{.Name}}Calls []{{.Controller}}{{.Name}}Request
+{{end}}}
+{{range .Actions}}
+func ({{.Receiver}} *{{$controller.Name}}Mock) {{.Name}}({{range $i, $arg := .Args}}{{if $i}}, {{end}}{{$arg.Name}} {{$arg.Type}}{{end}}) revel.Result {
+	{{.Receiver}}.{{.Name}}Calls = append({{.Receiver}}.{{.Name}}Calls, {{.Controller}}{{.Name}}Request{ {{- range $i, $arg := .Args}}{{if $i}}, {{end}}{{$arg.Field}}: {{$arg.Name}}{{end -}} })
+	if {{.Receiver}}.{{.Name}}Func != nil {
+		return {{.Receiver}}.{{.Name}}Func({{range $i, $arg := .Args}}{{if $i}}, {{end}}{{$arg.Name}}{{end}})
+	}
+	return nil
+}
+
+// {{.Controller}}{{.Name}}Request is a request to the {{.Action}} action
+type {{.Controller}}{{.Name}}Request struct {
+{{range .Args}}	{{.Field}} {{.Type}}
+{{end}}}
+
+// URL returns the reverse route of the action for the arguments of the request
+func (r {{.Controller}}{{.Name}}Request) URL() string {
+	args := map[string]string{}
+{{range .Args}}	revel.Unbind(args, "{{.Name}}", r.{{.Field}})
+{{end}}	definition := revel.MainRouter.Reverse({{printf "%q" .Action}}, args)
+	if definition == nil {
+		panic({{printf "%q" (print "No route found for " .Action)}})
+	}
+	return definition.URL
+}
+
+// Send sends the request to the server of the test suite
+func (r {{.Controller}}{{.Name}}Request) Send(t *testing.TestSuite) {
+	req, err := http.NewRequest("{{.Method}}", t.BaseUrl()+r.URL(), nil)
+	if err != nil {
+		panic(err)
+	}
+	t.NewTestRequest(req).Send()
+}
+{{end}}{{end}}`))
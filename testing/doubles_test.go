@@ -0,0 +1,114 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package testing
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/revel/revel"
+)
+
+type Rooms struct {
+	*revel.Controller
+}
+
+// A controller of the name of the revel.Controller
+type Controller struct {
+	*revel.Controller
+}
+
+func TestGenerateTestDoubles(t *testing.T) {
+	rooms := &revel.ControllerType{Type: reflect.TypeOf(Rooms{})}
+	actions := []*revel.ActionRef{
+		{ControllerType: rooms, MethodType: &revel.MethodType{Name: "Book", Args: []*revel.MethodArg{
+			{Name: "id", Type: reflect.TypeOf(0)},
+			{Name: "until", Type: reflect.TypeOf(time.Time{})},
+		}}, Routes: []*revel.Route{{Method: "POST", Path: "/rooms/:id/book"}}},
+		{ControllerType: rooms, MethodType: &revel.MethodType{Name: "Index"}},
+		{ControllerType: rooms, MethodType: &revel.MethodType{Name: "Chat", Args: []*revel.MethodArg{
+			{Name: "ws", Type: reflect.TypeOf((*revel.ServerWebSocket)(nil)).Elem()},
+		}}},
+	}
+
+	var source bytes.Buffer
+	if err := GenerateTestDoubles(&source, "tests", actions); err != nil {
+		t.Fatal(err)
+	}
+	file, err := parser.ParseFile(token.NewFileSet(), "doubles.go", source.Bytes(), parser.ImportsOnly)
+	if err != nil {
+		t.Fatalf("Invalid source %v\n%s", err, source.String())
+	}
+	if len(file.Imports) != 4 || file.Imports[3].Path.Value != `"time"` {
+		t.Errorf("Expected the time package to be imported, got %s", source.String())
+	}
+	for _, expected := range []string{
+		"func (m *RoomsMock) Book(id int, until time.Time) revel.Result",
+		"m.BookCalls = append(m.BookCalls, RoomsBookRequest{Id: id, Until: until})",
+		`http.NewRequest("POST", t.BaseUrl()+r.URL(), nil)`,
+		"func (r RoomsIndexRequest) URL() string",
+	} {
+		if !strings.Contains(source.String(), expected) {
+			t.Errorf("Expected the source to contain %q, got %s", expected, source.String())
+		}
+	}
+	if strings.Contains(source.String(), "Chat") {
+		t.Errorf("Expected the websocket action to be skipped")
+	}
+
+	// The receiver and the fields do not collide with the arguments and the methods
+	actions = []*revel.ActionRef{{ControllerType: rooms, MethodType: &revel.MethodType{Name: "Move", Args: []*revel.MethodArg{
+		{Name: "m", Type: reflect.TypeOf(0)},
+		{Name: "send", Type: reflect.TypeOf(true)},
+		{Name: "uRL", Type: reflect.TypeOf("")},
+	}}}}
+	source.Reset()
+	if err := GenerateTestDoubles(&source, "tests", actions); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"func (m2 *RoomsMock) Move(m int, send bool, uRL string) revel.Result",
+		"m2.MoveCalls = append(m2.MoveCalls, RoomsMoveRequest{M: m, SendArg: send, URLArg: uRL})",
+		"\tSendArg bool\n",
+	} {
+		if !strings.Contains(source.String(), expected) {
+			t.Errorf("Expected the source to contain %q, got %s", expected, source.String())
+		}
+	}
+
+	// The controllers and the packages of the same name are kept apart
+	actions = []*revel.ActionRef{
+		{ControllerType: &revel.ControllerType{Type: reflect.TypeOf(Controller{})}, MethodType: &revel.MethodType{Name: "Index"}},
+		{ControllerType: &revel.ControllerType{Type: reflect.TypeOf(revel.Controller{})}, MethodType: &revel.MethodType{Name: "Index", Args: []*revel.MethodArg{
+			{Name: "suite", Type: reflect.TypeOf(&TestSuite{})},
+			{Name: "test", Type: reflect.TypeOf(&testing.T{})},
+		}}},
+	}
+	source.Reset()
+	if err := GenerateTestDoubles(&source, "tests", actions); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"type ControllerMock struct",
+		"type RevelControllerMock struct",
+		"func (m *RevelControllerMock) Index(suite *testing.TestSuite, test *testing2.T) revel.Result",
+		`testing2 "testing"`,
+	} {
+		if !strings.Contains(source.String(), expected) {
+			t.Errorf("Expected the source to contain %q, got %s", expected, source.String())
+		}
+	}
+
+	// Without actions nothing is imported
+	source.Reset()
+	if err := GenerateTestDoubles(&source, "tests", nil); err != nil || strings.Contains(source.String(), "import") {
+		t.Errorf("Expected no imports, got %v %s", err, source.String())
+	}
+}
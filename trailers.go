@@ -0,0 +1,74 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"net/http"
+)
+
+// The trailers are headers sent after the body, a streamed response ends with the
+// checksum or the duration of its body
+//   return c.WithTrailers(c.RenderCSVStream(rows, nil), func(body *revel.BodyDigest) map[string]string {
+//   	return map[string]string{"X-Checksum-SHA256": body.Hex(), "X-Rows-Time": time.Since(start).String()}
+//   })
+// The trailers are sent with chunked (HTTP/1.1) and HTTP/2 responses, by the Go engine.
+
+// SetTrailer sets the trailer of the response, once the body is written
+func (resp *Response) SetTrailer(name, value string) {
+	resp.Out.internalHeader.Set(http.TrailerPrefix+name, value)
+}
+
+// BodyDigest describes the body written by a result
+type BodyDigest struct {
+	Length int64  // The number of bytes written, before compression
+	SHA256 []byte // The SHA-256 of the body, before compression
+}
+
+// Hex returns the SHA-256 of the body in hexadecimal
+func (d *BodyDigest) Hex() string {
+	return hex.EncodeToString(d.SHA256)
+}
+
+// TrailerResult applies the result and sets the trailers returned by the function, which
+// is called once the body is written
+type TrailerResult struct {
+	Result   Result
+	Trailers func(body *BodyDigest) map[string]string
+}
+
+func (r *TrailerResult) Apply(req *Request, resp *Response) {
+	writer := resp.GetWriter()
+	digest := &digestWriter{w: writer, hash: sha256.New()}
+	resp.SetWriter(digest)
+	r.Result.Apply(req, resp)
+	resp.SetWriter(writer)
+	for name, value := range r.Trailers(&BodyDigest{Length: digest.length, SHA256: digest.hash.Sum(nil)}) {
+		resp.SetTrailer(name, value)
+	}
+}
+
+// Counts and hashes the bytes written, the streamed results are still flushed
+type digestWriter struct {
+	w      io.Writer
+	hash   hash.Hash
+	length int64
+}
+
+func (d *digestWriter) Write(p []byte) (int, error) {
+	n, err := d.w.Write(p)
+	d.hash.Write(p[:n])
+	d.length += int64(n)
+	return n, err
+}
+
+func (d *digestWriter) Flush() {
+	if flusher, ok := d.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
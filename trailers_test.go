@@ -0,0 +1,58 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestWithTrailers(t *testing.T) {
+	resp := httptest.NewRecorder()
+	c := NewTestController(resp, showRequest)
+	c.WithTrailers(c.RenderText("hotels"), func(body *BodyDigest) map[string]string {
+		return map[string]string{"X-Checksum": body.Hex(), "X-Length": strconv.FormatInt(body.Length, 10)}
+	}).Apply(c.Request, c.Response)
+
+	sum := sha256.Sum256([]byte("hotels"))
+	trailer := resp.Result().Trailer
+	if trailer.Get("X-Checksum") != hex.EncodeToString(sum[:]) || trailer.Get("X-Length") != "6" {
+		t.Errorf("Unexpected trailers %v", trailer)
+	}
+	if resp.Body.String() != "hotels" {
+		t.Errorf("Unexpected body %q", resp.Body.String())
+	}
+}
+
+func TestWriteEarlyHints(t *testing.T) {
+	const link = "</public/css/app.css>; rel=preload; as=style"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := NewTestController(w, r)
+		if err := c.Response.WriteEarlyHints([]string{link}); err != nil && (earlyHintsSupported || err != ErrEarlyHintsUnsupported) {
+			t.Error(err)
+		}
+		c.RenderText("hotels").Apply(c.Request, c.Response)
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	// The 103 is informational, the final response is the 200 of the result
+	if resp.StatusCode != http.StatusOK || string(body) != "hotels" {
+		t.Errorf("Expected the 200 of the result, got %d %q", resp.StatusCode, body)
+	}
+	if earlyHintsSupported && resp.Header.Get("Link") != link {
+		t.Errorf("Expected the links in the final response, got %v", resp.Header)
+	}
+}
@@ -0,0 +1,431 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/url"
+	"reflect"
+	"strconv"
+)
+
+// The @Upload(memory=1MB, maxsize=4GB, stream=true) annotation sets how the multipart
+// uploads of an action are read. The memory is the size of the form held in memory, the
+// larger files are stored in temp files (server.request.max.multipart.filesize by default).
+// The maxsize limits the request body, there is no limit by default.
+// A streamed upload is not read by the ParamsFilter, nothing is stored on the disk. The
+// action reads each part as it is received, from a *revel.UploadStream argument
+//   func (c Videos) Upload(stream *revel.UploadStream) revel.Result {
+//   	for {
+//   		part, err := stream.NextFile()
+//   		if err == io.EOF {
+//   			break
+//   		} else if err != nil {
+//   			return c.RenderError(err)
+//   		}
+//   		storage.Put(part.FileName(), part)
+//   	}
+//   	title := stream.Values.Get("title")
+//   	...
+//   }
+// or from an io.Reader or *multipart.Part argument, which is the next file part of the
+// name of the argument. The parts are received in order, the arguments must be declared in
+// the order of the form.
+//   func (c Videos) Upload(video io.Reader) revel.Result
+// The form values of a streamed upload are not in the Params, they are in the
+// UploadStream.Values once the parts before them are read.
+//
+// The @MaxUpload annotation limits the size of the request body, or of the file parts of a
+// field, it may be repeated for several fields
+//   // @MaxUpload("8MB")
+//   // @MaxUpload("avatar", "2MB")
+//   func (c Users) Update(avatar []byte, ...) revel.Result
+// A request with a larger Content-Length is rejected before its body is read, a larger part
+// is rejected once it is read. The request is answered with a 413 (Request Entity Too
+// Large), the action is not invoked unless the upload is streamed, then the errors are
+// returned by the UploadStream.
+func init() {
+	RegisterAnnotationProcessor("Upload", uploadAnnotationProcessor)
+	RegisterAnnotationSchema("Upload", "memory", "maxsize", "stream")
+	RegisterAnnotationProcessor("MaxUpload", maxUploadAnnotationProcessor)
+	RegisterAnnotationSchema("MaxUpload", "field", "size")
+
+	TypeBinders[reflect.TypeOf(&UploadStream{})] = Binder{bindUploadStream, nil}
+	TypeBinders[reflect.TypeOf(&multipart.Part{})] = Binder{bindUploadPart, nil}
+}
+
+// The default size of the form values of a streamed upload held in memory
+const defaultUploadMemory = 32 << 20
+
+// ErrUploadTooLarge is returned when reading an upload larger than the maxsize of @Upload,
+// or than a limit of @MaxUpload
+var ErrUploadTooLarge = errors.New("The upload is too large")
+
+// The @Upload and @MaxUpload settings of an action
+type uploadSettings struct {
+	memory      int64            // The bytes of the form held in memory
+	maxSize     int64            // The maximum size of the request body
+	stream      bool             // The parts are read by the action
+	fieldLimits map[string]int64 // The maximum size of the file parts of a field
+}
+
+func uploadAnnotationProcessor(ct *ControllerType, mt *MethodType, annotation *FunctionalAnnotation) (err error) {
+	memory, err := annotation.GetSize("memory", 0, 0)
+	if err != nil {
+		return err
+	}
+	maxSize, err := annotation.GetSize("maxsize", 1, 0)
+	if err != nil {
+		return err
+	}
+	stream, err := annotation.GetBool("stream", 2, false)
+	if err != nil {
+		return err
+	}
+
+	for _, method := range annotatedMethods(ct, mt) {
+		settings := method.uploadSettings()
+		settings.memory, settings.stream = memory, stream
+		if maxSize > 0 {
+			settings.maxSize = maxSize
+		}
+	}
+	return nil
+}
+
+func maxUploadAnnotationProcessor(ct *ControllerType, mt *MethodType, annotation *FunctionalAnnotation) (err error) {
+	field, _ := annotation.Value("field", 0)
+	size, err := annotation.GetSize("size", 1, 0)
+	if err != nil {
+		return err
+	}
+	if _, found := annotation.Value("size", 1); !found {
+		// @MaxUpload("8MB") limits the request body
+		if size, err = annotation.GetSize("field", 0, 0); err != nil {
+			return err
+		}
+		field = ""
+	}
+	if size <= 0 {
+		return fmt.Errorf("@MaxUpload requires a size")
+	}
+
+	for _, method := range annotatedMethods(ct, mt) {
+		settings := method.uploadSettings()
+		if field == "" {
+			settings.maxSize = size
+			continue
+		}
+		if settings.fieldLimits == nil {
+			settings.fieldLimits = map[string]int64{}
+		}
+		settings.fieldLimits[field] = size
+	}
+	return nil
+}
+
+// Returns the methods of the annotation, all the methods of the controller when the
+// method is nil
+func annotatedMethods(ct *ControllerType, mt *MethodType) []*MethodType {
+	if mt != nil {
+		return []*MethodType{mt}
+	}
+	return ct.Methods
+}
+
+// Returns the upload settings of the method to be changed, a copy of the settings of the
+// controller annotations so they are not shared with the other methods
+func (mt *MethodType) uploadSettings() *uploadSettings {
+	settings := &uploadSettings{}
+	if mt.upload != nil {
+		*settings = *mt.upload
+		settings.fieldLimits = nil
+		for field, limit := range mt.upload.fieldLimits {
+			if settings.fieldLimits == nil {
+				settings.fieldLimits = map[string]int64{}
+			}
+			settings.fieldLimits[field] = limit
+		}
+	}
+	mt.upload = settings
+	return settings
+}
+
+// Applies the settings to the multipart request, the body is limited and the memory is set
+// on the server engine. Returns the stream of the parts when the upload is streamed, or
+// ErrUploadTooLarge when the Content-Length is larger than the maxsize.
+func (settings *uploadSettings) prepare(params *Params, req *Request) (*UploadStream, error) {
+	if settings.maxSize > 0 {
+		length, err := strconv.ParseInt(req.GetHttpHeader("Content-Length"), 10, 64)
+		if err == nil && length > settings.maxSize {
+			return nil, fmt.Errorf("The request of %d bytes is larger than %d bytes: %w", length, settings.maxSize, ErrUploadTooLarge)
+		}
+	}
+
+	_, mediaParams, err := mime.ParseMediaType(req.GetHttpHeader("Content-Type"))
+	boundary := mediaParams["boundary"]
+	body := req.GetBody()
+	if body != nil {
+		limited := false
+		if settings.maxSize > 0 {
+			body, limited = &uploadLimitReader{reader: body, remaining: settings.maxSize}, true
+		}
+		if len(settings.fieldLimits) > 0 && boundary != "" {
+			partLimit := newPartLimitReader(body, boundary, settings.fieldLimits)
+			params.uploadLimit = partLimit
+			body, limited = partLimit, true
+		}
+		if limited && !settings.stream && !req.In.Set(HTTP_BODY, body) {
+			paramsLogger.Warn("ParseParams: The server engine does not support limiting the upload size")
+		}
+	}
+	if !settings.stream {
+		if settings.memory > 0 && !req.In.Set(HTTP_MULTIPART_MEMORY, settings.memory) {
+			paramsLogger.Warn("ParseParams: The server engine does not support the upload memory size")
+		}
+		return nil, nil
+	}
+
+	if err != nil || boundary == "" || body == nil {
+		paramsLogger.Warn("ParseParams: Invalid multipart request", "error", err)
+		params.bindErrors = append(params.bindErrors, &bindError{"body", "Must be a multipart form"})
+		return nil, nil
+	}
+	memory := settings.memory
+	if memory <= 0 {
+		memory = defaultUploadMemory
+	}
+	return &UploadStream{Values: url.Values{}, reader: multipart.NewReader(body, boundary), memory: memory, limit: params.uploadLimit}, nil
+}
+
+// Returns ErrUploadTooLarge when more than the remaining bytes are read
+type uploadLimitReader struct {
+	reader    io.Reader
+	remaining int64
+}
+
+func (r *uploadLimitReader) Read(p []byte) (n int, err error) {
+	if r.remaining < 0 {
+		return 0, ErrUploadTooLarge
+	}
+	if int64(len(p)) > r.remaining+1 {
+		p = p[:r.remaining+1]
+	}
+	n, err = r.reader.Read(p)
+	if r.remaining -= int64(n); r.remaining < 0 {
+		return n, ErrUploadTooLarge
+	}
+	return
+}
+
+// Returns ErrUploadTooLarge when a file part is larger than the limit of its field. Each
+// chunk read is scanned by a multipart reader in a goroutine before the Read returns, the
+// scan ends at the end of the body or when the reader is closed. The scan may wait for the
+// next chunk to end a part, wait returns its result once the parts are read.
+type partLimitReader struct {
+	reader  io.Reader
+	chunks  chan []byte   // The chunks read, closed at the end of the body
+	wants   chan struct{} // The scan has read the chunk and wants the next one
+	done    chan error    // The result of the scan
+	closed  bool          // The chunks are closed
+	scanned bool          // The scan has ended, with the scanErr
+	scanErr error
+}
+
+func newPartLimitReader(reader io.Reader, boundary string, limits map[string]int64) *partLimitReader {
+	r := &partLimitReader{reader: reader, chunks: make(chan []byte), wants: make(chan struct{}), done: make(chan error, 1)}
+	go func() {
+		r.done <- scanPartLimits(multipart.NewReader(&chunkReader{chunks: r.chunks, wants: r.wants}, boundary), limits)
+	}()
+	return r
+}
+
+// Returns ErrUploadTooLarge for the first file part larger than the limit of its field,
+// nil at the end of the parts
+func scanPartLimits(reader *multipart.Reader, limits map[string]int64) error {
+	for {
+		part, err := reader.NextRawPart()
+		if err != nil {
+			return nil
+		}
+		limit, found := limits[part.FormName()]
+		if !found || part.FileName() == "" {
+			continue
+		}
+		if n, _ := io.Copy(ioutil.Discard, io.LimitReader(part, limit+1)); n > limit {
+			return fmt.Errorf("The upload %s is larger than %d bytes: %w", part.FormName(), limit, ErrUploadTooLarge)
+		}
+	}
+}
+
+func (r *partLimitReader) Read(p []byte) (n int, err error) {
+	n, err = r.reader.Read(p)
+	if !r.scanned && !r.closed && n > 0 {
+		r.chunks <- p[:n]
+		select {
+		case <-r.wants:
+		case r.scanErr = <-r.done:
+			r.scanned = true
+		}
+	}
+	if err == io.EOF {
+		// The last part is checked once the scan ends
+		if scanErr := r.wait(); scanErr != nil {
+			return n, scanErr
+		}
+	}
+	if r.scanErr != nil {
+		return n, r.scanErr
+	}
+	return
+}
+
+// Ends the scan at the bytes read and returns its result
+func (r *partLimitReader) wait() error {
+	if !r.scanned {
+		r.Close()
+		r.scanErr, r.scanned = <-r.done, true
+	}
+	return r.scanErr
+}
+
+// Returns the result of the scan of the bytes read so far
+func (r *partLimitReader) scanError() error {
+	if !r.scanned {
+		select {
+		case r.scanErr = <-r.done:
+			r.scanned = true
+		default:
+		}
+	}
+	return r.scanErr
+}
+
+// Close ends the scan of the parts
+func (r *partLimitReader) Close() error {
+	if !r.closed {
+		r.closed = true
+		close(r.chunks)
+	}
+	return nil
+}
+
+// Reads the chunks of the partLimitReader, the next chunk is wanted once the previous one
+// is read
+type chunkReader struct {
+	chunks  <-chan []byte
+	wants   chan<- struct{}
+	chunk   []byte
+	started bool
+	eof     bool
+}
+
+func (r *chunkReader) Read(p []byte) (n int, err error) {
+	if len(r.chunk) == 0 {
+		if r.eof {
+			return 0, io.EOF
+		}
+		if r.started {
+			r.wants <- struct{}{}
+		}
+		r.started = true
+		var ok bool
+		if r.chunk, ok = <-r.chunks; !ok {
+			r.eof = true
+			return 0, io.EOF
+		}
+	}
+	n = copy(p, r.chunk)
+	r.chunk = r.chunk[n:]
+	return
+}
+
+// UploadStream reads the parts of a streamed multipart upload, see the @Upload annotation
+type UploadStream struct {
+	Values url.Values // The form values of the parts read
+
+	reader *multipart.Reader
+	part   *multipart.Part
+	memory int64            // The remaining bytes of the form values held in memory
+	limit  *partLimitReader // Checks the limits of @MaxUpload, nil if none
+}
+
+// NextPart returns the next part of the upload, a file or a form value. io.EOF is returned
+// after the last part, ErrUploadTooLarge once a part larger than its @MaxUpload is read. The previous part must not be read once the next one is returned.
+func (s *UploadStream) NextPart() (part *multipart.Part, err error) {
+	if s.part != nil {
+		s.part.Close()
+		s.part = nil
+	}
+	if s.limit != nil {
+		if err = s.limit.scanError(); err != nil {
+			return nil, err
+		}
+	}
+	if part, err = s.reader.NextPart(); err == nil {
+		s.part = part
+	}
+	return
+}
+
+// NextFile returns the next file part of the upload, the form values before it are read
+// into the Values. io.EOF is returned after the last part.
+func (s *UploadStream) NextFile() (*multipart.Part, error) {
+	for {
+		part, err := s.NextPart()
+		if err != nil || part.FileName() != "" {
+			return part, err
+		}
+		value := &bytes.Buffer{}
+		n, err := io.Copy(value, io.LimitReader(part, s.memory+1))
+		if err != nil {
+			return nil, err
+		}
+		if s.memory -= n; s.memory < 0 {
+			return nil, fmt.Errorf("The form value %s is too large: %w", part.FormName(), ErrUploadTooLarge)
+		}
+		s.Values.Add(part.FormName(), value.String())
+	}
+}
+
+// Part returns the next file part of the name, the parts before it are skipped (the form
+// values are read into the Values). io.EOF is returned if there is no such part.
+func (s *UploadStream) Part(name string) (*multipart.Part, error) {
+	for {
+		part, err := s.NextFile()
+		if err != nil || part.FormName() == name {
+			return part, err
+		}
+	}
+}
+
+// Binds the upload stream of the request
+func bindUploadStream(params *Params, name string, typ reflect.Type) reflect.Value {
+	if params.uploadStream == nil {
+		return reflect.Zero(typ)
+	}
+	return reflect.ValueOf(params.uploadStream)
+}
+
+// Binds the next file part of the name of a streamed upload
+func bindUploadPart(params *Params, name string, typ reflect.Type) reflect.Value {
+	if params.uploadStream == nil {
+		return reflect.Zero(typ)
+	}
+	part, err := params.uploadStream.Part(name)
+	if err != nil {
+		if err != io.EOF {
+			binderLog.Warn("bindUploadPart: Failed to read the upload", "name", name, "error", err)
+		}
+		return reflect.Zero(typ)
+	}
+	return reflect.ValueOf(part)
+}
@@ -0,0 +1,169 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// Returns a controller of a multipart upload of a title and a video
+func uploadController(settings *uploadSettings) *Controller {
+	c := NewTestController(nil, uploadRequest())
+	c.Params.upload = settings
+	ParseParams(c.Params, c.Request)
+	return c
+}
+
+// Returns a multipart upload of a title and a video of 500 bytes
+func uploadRequest() *http.Request {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	writer.WriteField("title", "Holidays")
+	file, _ := writer.CreateFormFile("video", "holidays.mp4")
+	file.Write(bytes.Repeat([]byte("frame"), 100))
+	writer.WriteField("tags", "sea")
+	writer.Close()
+
+	req, _ := http.NewRequest("POST", "/videos", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestUploadAnnotation(t *testing.T) {
+	annotation, _ := ParseAnnotation(`@Upload(memory=1MB, maxsize=2GB, stream=true)`)
+	mt := &MethodType{}
+	if err := uploadAnnotationProcessor(&ControllerType{}, mt, annotation); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*mt.upload, uploadSettings{memory: 1 << 20, maxSize: 2 << 30, stream: true}) {
+		t.Errorf("Unexpected settings %#v", mt.upload)
+	}
+	annotation, _ = ParseAnnotation(`@Upload(maxsize=lots)`)
+	if err := uploadAnnotationProcessor(&ControllerType{}, mt, annotation); err == nil {
+		t.Errorf("Expected an error for an invalid size")
+	}
+}
+
+func TestUploadStream(t *testing.T) {
+	c := uploadController(&uploadSettings{stream: true})
+	if c.Params.Files != nil || c.Params.uploadStream == nil {
+		t.Fatalf("Expected the upload to be streamed")
+	}
+	if stream := Bind(c.Params, "stream", reflect.TypeOf(&UploadStream{})).Interface(); stream != c.Params.uploadStream {
+		t.Errorf("Expected the upload stream to be bound")
+	}
+
+	video := Bind(c.Params, "video", reflect.TypeOf((*io.Reader)(nil)).Elem()).Interface().(io.Reader)
+	content, err := ioutil.ReadAll(video)
+	if err != nil || !bytes.Equal(content, bytes.Repeat([]byte("frame"), 100)) {
+		t.Errorf("Unexpected video %q %v", content, err)
+	}
+	if _, err := c.Params.uploadStream.NextFile(); err != io.EOF {
+		t.Errorf("Expected the end of the upload, got %v", err)
+	}
+	if c.Params.uploadStream.Values.Get("title") != "Holidays" || c.Params.uploadStream.Values.Get("tags") != "sea" {
+		t.Errorf("Unexpected form values %v", c.Params.uploadStream.Values)
+	}
+
+	// The form values are limited by the memory
+	c = uploadController(&uploadSettings{stream: true, memory: 4})
+	if _, err := c.Params.uploadStream.NextFile(); err == nil {
+		t.Errorf("Expected an error for a form value larger than the memory")
+	}
+}
+
+func TestUploadMaxSize(t *testing.T) {
+	c := uploadController(&uploadSettings{maxSize: 100})
+	if !errors.Is(c.Params.uploadErr, ErrUploadTooLarge) {
+		t.Errorf("Expected an error for the large upload, got %v", c.Params.uploadErr)
+	}
+
+	c = uploadController(&uploadSettings{maxSize: 1 << 20, memory: 1 << 10})
+	if c.Params.uploadErr != nil || len(c.Params.bindErrors) != 0 || c.Params.Form.Get("title") != "Holidays" || len(c.Params.Files["video"]) != 1 {
+		t.Errorf("Expected the upload to be parsed, got %v %v", c.Params.bindErrors, c.Params.Form)
+	}
+
+	c = uploadController(&uploadSettings{stream: true, maxSize: 100})
+	if _, err := c.Params.uploadStream.Part("video"); err == nil {
+		t.Errorf("Expected an error reading the large upload")
+	}
+}
+
+func TestMaxUploadAnnotation(t *testing.T) {
+	ct := &ControllerType{Methods: []*MethodType{{Name: "Update"}, {Name: "Show"}}}
+	annotation, _ := ParseAnnotation(`@Upload(memory=1MB)`)
+	if err := uploadAnnotationProcessor(ct, nil, annotation); err != nil {
+		t.Fatal(err)
+	}
+	annotation, _ = ParseAnnotation(`@MaxUpload("avatar", "2MB")`)
+	if err := maxUploadAnnotationProcessor(ct, ct.Methods[0], annotation); err != nil {
+		t.Fatal(err)
+	}
+	annotation, _ = ParseAnnotation(`@MaxUpload("8MB")`)
+	if err := maxUploadAnnotationProcessor(ct, nil, annotation); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := uploadSettings{memory: 1 << 20, maxSize: 8 << 20, fieldLimits: map[string]int64{"avatar": 2 << 20}}
+	if !reflect.DeepEqual(*ct.Methods[0].upload, expected) {
+		t.Errorf("Expected %#v, got %#v", expected, ct.Methods[0].upload)
+	}
+	if !reflect.DeepEqual(*ct.Methods[1].upload, uploadSettings{memory: 1 << 20, maxSize: 8 << 20}) {
+		t.Errorf("Expected no field limits, got %#v", ct.Methods[1].upload)
+	}
+
+	annotation, _ = ParseAnnotation(`@MaxUpload("avatar")`)
+	if err := maxUploadAnnotationProcessor(ct, nil, annotation); err == nil {
+		t.Errorf("Expected an error for an invalid size")
+	}
+}
+
+func TestMaxUploadField(t *testing.T) {
+	c := uploadController(&uploadSettings{fieldLimits: map[string]int64{"video": 100}})
+	if !errors.Is(c.Params.uploadErr, ErrUploadTooLarge) {
+		t.Errorf("Expected an error for the large video, got %v", c.Params.uploadErr)
+	}
+	c.Params.uploadLimit.Close()
+
+	c = uploadController(&uploadSettings{fieldLimits: map[string]int64{"video": 500, "title": 1}})
+	if c.Params.uploadErr != nil || len(c.Params.Files["video"]) != 1 || c.Params.Form.Get("title") != "Holidays" {
+		t.Errorf("Expected the upload to be parsed, got %v %v", c.Params.uploadErr, c.Params.Form)
+	}
+	c.Params.uploadLimit.Close()
+
+	// The stream returns the error with the next part
+	c = uploadController(&uploadSettings{stream: true, fieldLimits: map[string]int64{"video": 100}})
+	if part, err := c.Params.uploadStream.Part("video"); err == nil {
+		ioutil.ReadAll(part)
+	}
+	if _, err := c.Params.uploadStream.NextPart(); !errors.Is(err, ErrUploadTooLarge) {
+		t.Errorf("Expected an error after the large video, got %v", err)
+	}
+	c.Params.uploadLimit.Close()
+}
+
+func TestMaxUploadRejected(t *testing.T) {
+	req := uploadRequest()
+	req.Header.Set("Content-Length", strconv.FormatInt(req.ContentLength, 10))
+	c := NewTestController(httptest.NewRecorder(), req)
+	c.MethodType = &MethodType{upload: &uploadSettings{maxSize: 100}}
+	invoked := false
+	ParamsFilter(c, []Filter{func(c *Controller, fc []Filter) { invoked = true }})
+	if invoked || c.Response.Status != http.StatusRequestEntityTooLarge || c.Result == nil {
+		t.Errorf("Expected the upload to be rejected with a 413, got %d", c.Response.Status)
+	}
+	if c.Params.Form != nil || c.Params.Files != nil {
+		t.Errorf("Expected the body not to be read")
+	}
+}
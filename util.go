@@ -116,6 +116,10 @@ func ContentTypeByFilename(filename string) string {
 		return DefaultFileContentType
 	}
 
+	// The mime types are loaded when the application starts
+	if mimeConfig == nil {
+		return DefaultFileContentType
+	}
 	extension := filename[dot+1:]
 	contentType := mimeConfig.StringDefault(extension, "")
 	if contentType == "" {
@@ -5,11 +5,12 @@
 package revel
 
 import (
+	"context"
 	"fmt"
 	"net/http"
-	"net/url"
 	"regexp"
 	"runtime"
+	"strings"
 )
 
 // ValidationError simple struct to store the Message & Key of a validation error
@@ -31,6 +32,12 @@ type Validation struct {
 	Request *Request
 	Translator func(locale, message string, args ...interface{}) string
 	keep   bool
+	reject bool // The errors of the arguments are rejected before the action runs
+
+	scenarios    []string                        // The scenarios of the rules checked by Struct
+	pending      []*asyncCheck                    // The checks queued by CheckAsync
+	asyncStarts  []func(ctx context.Context)      // Start the checks of the values not checked yet
+	asyncResults map[asyncResultKey]*asyncResult // The results of the checks of the request
 }
 
 // Keep tells revel to set a flash cookie on the client to make the validation
@@ -93,12 +100,17 @@ type ValidationResult struct {
 	Ok    bool
 	Locale string
 	Translator func(locale, message string, args ...interface{}) string
+	check Validator // The validator failed, its message is translated for the key
 }
 
 // Key sets the ValidationResult's Error "key" and returns itself for chaining
+// The default message of the validator is translated for the key.
 func (r *ValidationResult) Key(key string) *ValidationResult {
 	if r.Error != nil {
 		r.Error.Key = key
+		if r.check != nil {
+			r.Error.Message = validationMessage(r.Translator, r.Locale, r.check, key)
+		}
 	}
 	return r
 }
@@ -106,6 +118,7 @@ func (r *ValidationResult) Key(key string) *ValidationResult {
 // Message sets the error message for a ValidationResult. Returns itself to
 // allow chaining.  Allows Sprintf() type calling with multiple parameters
 func (r *ValidationResult) Message(message string, args ...interface{}) *ValidationResult {
+	r.check = nil
 	if r.Error != nil {
 		if len(args) == 0 {
 			r.Error.Message = message
@@ -119,6 +132,7 @@ func (r *ValidationResult) Message(message string, args ...interface{}) *Validat
 // Allow a message key to be passed into the validation result. The Validation has already
 // setup the translator to translate the message key
 func (r *ValidationResult) MessageKey(message string, args ...interface{}) *ValidationResult {
+	r.check = nil
 	if r.Error == nil {
 		return r
 	}
@@ -206,6 +220,38 @@ func (v *Validation) FilePath(str string, m int) *ValidationResult {
 	return v.apply(FilePath{m}, str)
 }
 
+func (v *Validation) UUID(str string) *ValidationResult {
+	return v.apply(UUID{}, str)
+}
+
+func (v *Validation) URLScheme(str string, schemes ...string) *ValidationResult {
+	return v.apply(URLScheme{schemes}, str)
+}
+
+func (v *Validation) E164(str string) *ValidationResult {
+	return v.apply(E164{}, str)
+}
+
+func (v *Validation) IBAN(str string) *ValidationResult {
+	return v.apply(IBAN{}, str)
+}
+
+func (v *Validation) CreditCard(str string) *ValidationResult {
+	return v.apply(CreditCard{}, str)
+}
+
+func (v *Validation) CountryCode(str string) *ValidationResult {
+	return v.apply(CountryCode{}, str)
+}
+
+func (v *Validation) CurrencyCode(str string) *ValidationResult {
+	return v.apply(CurrencyCode{}, str)
+}
+
+func (v *Validation) FileName(str string) *ValidationResult {
+	return v.apply(FileName{}, str)
+}
+
 func (v *Validation) apply(chk Validator, obj interface{}) *ValidationResult {
 	if chk.IsSatisfied(obj) {
 		return v.ValidationResult(true)
@@ -224,7 +270,7 @@ func (v *Validation) apply(chk Validator, obj interface{}) *ValidationResult {
 
 	// Add the error to the validation context.
 	err := &ValidationError{
-		Message: chk.DefaultMessage(),
+		Message: v.message(chk, key),
 		Key:     key,
 	}
 	v.Errors = append(v.Errors, err)
@@ -232,6 +278,7 @@ func (v *Validation) apply(chk Validator, obj interface{}) *ValidationResult {
 	// Also return it in the result.
 	vr := v.ValidationResult(false)
 	vr.Error = err
+	vr.check = chk
 	return vr
 }
 
@@ -250,21 +297,28 @@ func (v *Validation) Check(obj interface{}, checks ...Validator) *ValidationResu
 }
 
 // ValidationFilter revel Filter function to be hooked into the filter chain.
+// The ActionInvoker rejects the request with a 422 before the action runs, when the bound
+// arguments are not valid, if set in app.conf
+//   validation.reject = api   # false by default, api for the requests of another format than html, or true
+// A client accepting JSON gets the problem details with the errors of the fields
+//   {"title": "Validation failed", "status": 422, "errors": [{"field": "booking.Name", "message": "Required"}]}
+// The validation errors are not kept in the flash cookie of the requests rejected.
 func ValidationFilter(c *Controller, fc []Filter) {
+	reject := rejectsValidationErrors(c)
+	store := keptErrorsStore()
 	// If json request, we shall assume json response is intended,
 	// as such no validation cookies should be tied response
-	if c.Params != nil && c.Params.JSON != nil {
-		c.Validation = &Validation{Request:c.Request, Translator:MessageFunc}
+	if reject || (store == cookieErrorsStore && c.Params != nil && c.Params.JSON != nil) {
+		c.Validation = &Validation{Request:c.Request, Translator:MessageFunc, reject:reject}
 		fc[0](c, fc[1:])
 	} else {
-		errors, err := restoreValidationErrors(c.Request)
+		errors, restored := store.restore(c)
 		c.Validation = &Validation{
 			Errors: errors,
 			keep:   false,
 			Request:c.Request,
 			Translator:MessageFunc,
 		}
-		hasCookie := (err != http.ErrNoCookie)
 
 		fc[0](c, fc[1:])
 
@@ -282,27 +336,37 @@ func ValidationFilter(c *Controller, fc []Filter) {
 		}
 
 		// When there are errors from Validation and Keep() has been called, store the
-		// values in a cookie. If there previously was a cookie but no errors, remove
-		// the cookie.
-		if errorsValue != "" {
-			c.SetCookie(&http.Cookie{
-				Name:     CookiePrefix + "_ERRORS",
-				Value:    url.QueryEscape(errorsValue),
-				Domain:   CookieDomain,
-				Path:     "/",
-				HttpOnly: true,
-				Secure:   CookieSecure,
-			})
-		} else if hasCookie {
-			c.SetCookie(&http.Cookie{
-				Name:     CookiePrefix + "_ERRORS",
-				MaxAge:   -1,
-				Domain:   CookieDomain,
-				Path:     "/",
-				HttpOnly: true,
-				Secure:   CookieSecure,
-			})
-		}
+		// values for the next request. If there previously were errors stored but no
+		// errors, remove them.
+		store.save(c, errorsValue, restored)
+	}
+}
+
+// Returns true if the validation errors of the arguments of the request are rejected
+func rejectsValidationErrors(c *Controller) bool {
+	switch Config.StringDefault("validation.reject", "false") {
+	case "true":
+		return true
+	case "api":
+		return c.Request.Format != "html"
+	}
+	return false
+}
+
+// Returns the problem of the validation errors, listed in the order they were found
+func validationProblem(errors []*ValidationError) *Problem {
+	fields := make([]map[string]string, 0, len(errors))
+	details := make([]string, 0, len(errors))
+	for _, err := range errors {
+		message := strings.TrimSpace(err.Message)
+		fields = append(fields, map[string]string{"field": err.Key, "message": message})
+		details = append(details, err.Key+": "+message)
+	}
+	return &Problem{
+		Title:      "Validation failed",
+		Status:     http.StatusUnprocessableEntity,
+		Detail:     strings.Join(details, ", "),
+		Extensions: map[string]interface{}{"errors": fields},
 	}
 }
 
@@ -313,7 +377,7 @@ func restoreValidationErrors(req *Request) ([]*ValidationError, error) {
 		cookie ServerCookie
 		errors = make([]*ValidationError, 0, 5)
 	)
-	if cookie, err = req.Cookie(CookiePrefix + "_ERRORS"); err == nil {
+	if cookie, err = req.Cookie(cookieAttributesOf(errorsCookieKind).Name("_ERRORS")); err == nil {
 		ParseKeyValueCookie(cookie.GetValue(), func(key, val string) {
 			errors = append(errors, &ValidationError{
 				Key:     key,
@@ -0,0 +1,189 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// The validators which perform I/O (a uniqueness check in the database, the verification of
+// an address by a remote service) are queued by CheckAsync, and run concurrently by Wait
+//   c.Validation.CheckAsync("user.Email", user.Email, uniqueEmail)
+//   c.Validation.CheckAsync("user.Address", user.Address, verifiedAddress)
+//   if err := c.Validation.Wait(ctx); err != nil {
+//   	c.Log.Warn("The user could not be validated", "error", err)
+//   }
+//   if c.Validation.HasErrors() {
+//   	...
+//   }
+// The checks of a value by a validator are run once per request, the result is reused by the
+// following checks of the same value. The checks are cancelled after the timeout set in
+// app.conf
+//   validation.async.timeout = 5s
+
+// AsyncValidator is a validator which performs I/O, it returns an error when the value
+// could not be checked
+type AsyncValidator interface {
+	IsSatisfiedContext(ctx context.Context, obj interface{}) (bool, error)
+	DefaultMessage() string
+}
+
+// The message of the values which could not be checked by an AsyncValidator
+const asyncValidationFailedMessage = "Could not be validated"
+
+var asyncValidationTimeout = 5 * time.Second
+
+func init() {
+	OnAppStart(func() {
+		asyncValidationTimeout = ConfigDurationDefault("validation.async.timeout", 5*time.Second, time.Second)
+	})
+}
+
+// AsyncValidatorFunc returns the AsyncValidator of the function with the default message.
+// The validator is created once (in a package variable), the checks of the same validator are
+// the ones reused within a request.
+//   var uniqueEmail = revel.AsyncValidatorFunc(func(ctx context.Context, obj interface{}) (bool, error) {
+//   	email, _ := obj.(string)
+//   	count, err := db.CountUsers(ctx, email)
+//   	return count == 0, err
+//   }, "Is already registered")
+func AsyncValidatorFunc(fn func(ctx context.Context, obj interface{}) (bool, error), defaultMessage string) AsyncValidator {
+	return &asyncValidatorFunc{fn: fn, message: defaultMessage}
+}
+
+type asyncValidatorFunc struct {
+	fn      func(ctx context.Context, obj interface{}) (bool, error)
+	message string
+}
+
+func (a *asyncValidatorFunc) IsSatisfiedContext(ctx context.Context, obj interface{}) (bool, error) {
+	return a.fn(ctx, obj)
+}
+
+func (a *asyncValidatorFunc) DefaultMessage() string {
+	return a.message
+}
+
+// A check queued by CheckAsync
+type asyncCheck struct {
+	key    string
+	check  AsyncValidator
+	result *asyncResult
+}
+
+// The result of the check of a value by a validator, done is closed once it is known
+type asyncResult struct {
+	ok   bool
+	err  error
+	done chan struct{}
+}
+
+// The key of the results reused in a request
+type asyncResultKey struct {
+	check AsyncValidator
+	obj   interface{}
+}
+
+// CheckAsync queues the checks of the value by the validators, the errors are added under the
+// key by Wait. The first failed check of the key is kept.
+func (v *Validation) CheckAsync(key string, obj interface{}, checks ...AsyncValidator) {
+	for _, check := range checks {
+		v.pending = append(v.pending, &asyncCheck{key: key, check: check, result: v.asyncResult(check, obj)})
+	}
+}
+
+// Returns the result of the check of the value by the validator, the result of the request if
+// the value was already checked
+func (v *Validation) asyncResult(check AsyncValidator, obj interface{}) *asyncResult {
+	cached := reflect.TypeOf(check).Comparable() && (obj == nil || reflect.TypeOf(obj).Comparable())
+	if cached {
+		if result, found := v.asyncResults[asyncResultKey{check, obj}]; found {
+			return result
+		}
+	}
+	result := &asyncResult{}
+	if cached {
+		if v.asyncResults == nil {
+			v.asyncResults = map[asyncResultKey]*asyncResult{}
+		}
+		v.asyncResults[asyncResultKey{check, obj}] = result
+	}
+	v.asyncStarts = append(v.asyncStarts, func(ctx context.Context) {
+		result.done = make(chan struct{})
+		go func() {
+			defer close(result.done)
+			defer func() {
+				if err := recover(); err != nil {
+					result.err = fmt.Errorf("validator panic: %v", err)
+				}
+			}()
+			result.ok, result.err = check.IsSatisfiedContext(ctx, obj)
+		}()
+	})
+	return result
+}
+
+// Wait runs the checks queued by CheckAsync concurrently, and adds the errors of the checks
+// which failed once they are all done. A check which returns an error (or is cancelled by the
+// context, or by the timeout) adds a validation error too, the first error is returned.
+func (v *Validation) Wait(ctx context.Context) (err error) {
+	pending, started := v.pending, v.asyncStarts
+	v.pending, v.asyncStarts = nil, nil
+	if len(pending) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, asyncValidationTimeout)
+	defer cancel()
+	for _, start := range started {
+		start(ctx)
+	}
+
+	failed, unfinished := map[string]bool{}, map[*asyncResult]bool{}
+	for _, check := range pending {
+		ok, done, checkErr := check.result.wait(ctx)
+		if !done {
+			unfinished[check.result] = true
+		}
+		if failed[check.key] || (ok && checkErr == nil) {
+			continue
+		}
+		failed[check.key] = true
+		message := check.check.DefaultMessage()
+		if checkErr != nil {
+			message = asyncValidationFailedMessage
+			if err == nil {
+				err = fmt.Errorf("validation of %s: %w", check.key, checkErr)
+			}
+		}
+		v.Errors = append(v.Errors, &ValidationError{Message: message, Key: check.key})
+	}
+
+	// The checks which could not be done are not reused
+	for key, result := range v.asyncResults {
+		if unfinished[result] || result.err != nil {
+			delete(v.asyncResults, key)
+		}
+	}
+	return
+}
+
+// Returns the result of the check once it is done, or the error of the context when it is
+// done first, a validator which ignores the context does not hold the request
+func (result *asyncResult) wait(ctx context.Context) (ok, done bool, err error) {
+	select {
+	case <-result.done:
+		return result.ok, true, result.err
+	case <-ctx.Done():
+	}
+	select {
+	case <-result.done:
+		return result.ok, true, result.err
+	default:
+		return false, false, ctx.Err()
+	}
+}
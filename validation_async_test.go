@@ -0,0 +1,101 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestValidationAsync(t *testing.T) {
+	var calls, running, concurrent int32
+	taken := map[string]bool{"jane@example.com": true}
+	uniqueEmail := AsyncValidatorFunc(func(ctx context.Context, obj interface{}) (bool, error) {
+		atomic.AddInt32(&calls, 1)
+		if n := atomic.AddInt32(&running, 1); n > 1 {
+			atomic.StoreInt32(&concurrent, 1)
+		}
+		defer atomic.AddInt32(&running, -1)
+		time.Sleep(20 * time.Millisecond)
+		return !taken[obj.(string)], nil
+	}, "Is already registered")
+	unreachable := AsyncValidatorFunc(func(ctx context.Context, obj interface{}) (bool, error) {
+		return false, errors.New("service unavailable")
+	}, "Is not a known address")
+
+	validation := &Validation{}
+	validation.CheckAsync("user.Email", "jane@example.com", uniqueEmail)
+	validation.CheckAsync("friend.Email", "joe@example.com", uniqueEmail)
+	validation.CheckAsync("contact.Email", "jane@example.com", uniqueEmail)
+	validation.CheckAsync("user.Address", "Main street", unreachable)
+	err := validation.Wait(context.Background())
+	if err == nil || err.Error() != "validation of user.Address: service unavailable" {
+		t.Errorf("Expected the error of the address, got %v", err)
+	}
+	messages := map[string]string{}
+	for key, err := range validation.ErrorMap() {
+		messages[key] = err.Message
+	}
+	if len(messages) != 3 || messages["user.Email"] != "Is already registered" || messages["contact.Email"] != "Is already registered" ||
+		messages["user.Address"] != "Could not be validated" {
+		t.Errorf("Unexpected errors %v", messages)
+	}
+	if calls != 2 || concurrent != 1 {
+		t.Errorf("Expected 2 concurrent checks, got %d calls (concurrent %d)", calls, concurrent)
+	}
+
+	// The results are reused by the request
+	validation.Clear()
+	validation.CheckAsync("user.Email", "jane@example.com", uniqueEmail)
+	if err := validation.Wait(context.Background()); err != nil || calls != 2 || !validation.HasErrors() {
+		t.Errorf("Expected the cached result, got %v %d %v", err, calls, validation.Errors)
+	}
+}
+
+func TestValidationAsyncTimeout(t *testing.T) {
+	asyncValidationTimeout = 10 * time.Millisecond
+	defer func() { asyncValidationTimeout = 5 * time.Second }()
+	slow := AsyncValidatorFunc(func(ctx context.Context, obj interface{}) (bool, error) {
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-time.After(time.Second):
+			return true, nil
+		}
+	}, "Is not valid")
+
+	validation := &Validation{}
+	validation.CheckAsync("user.Address", "Main street", slow)
+	if err := validation.Wait(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the deadline to be exceeded, got %v", err)
+	}
+	if len(validation.asyncResults) != 0 {
+		t.Errorf("Expected the failed check not to be reused")
+	}
+}
+
+func TestValidationAsyncIgnoredContext(t *testing.T) {
+	asyncValidationTimeout = 10 * time.Millisecond
+	defer func() { asyncValidationTimeout = 5 * time.Second }()
+	release := make(chan struct{})
+	defer close(release)
+	stuck := AsyncValidatorFunc(func(ctx context.Context, obj interface{}) (bool, error) {
+		<-release
+		return true, nil
+	}, "Is not valid")
+
+	validation := &Validation{}
+	validation.CheckAsync("user.Address", "Main street", stuck)
+	start := time.Now()
+	if err := validation.Wait(context.Background()); !errors.Is(err, context.DeadlineExceeded) || time.Since(start) > time.Second {
+		t.Errorf("Expected the deadline to be exceeded, got %v after %s", err, time.Since(start))
+	}
+	if len(validation.Errors) != 1 || validation.Errors[0].Message != asyncValidationFailedMessage || len(validation.asyncResults) != 0 {
+		t.Errorf("Expected the unfinished check to fail and not be reused, got %v", validation.Errors)
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import "strings"
+
+// The messages of the validation errors are looked up in the message files of the locale
+// of the request, by the name of the validator and the key of the error, then by the name
+// of the validator. The parameters of the validator are the arguments of the message
+//   validation.required=Obligatoire
+//   validation.min=Doit être au moins %v
+//   validation.maxsize=La taille maximale est %d
+//   validation.required.booking.Name=Le nom est obligatoire
+// The names of the validators are required, min, max, range (min and max), minsize, maxsize,
+// length, match (the regular expression), email, ipaddr, macaddr, domain, url, puretext,
+// filepath, uuid, urlscheme (the schemes), e164, iban, creditcard, country, currency,
+// filename, eqfield, nefield, required_with (the other field), required_if (the other field
+// and its value), and the names of the rules registered by RegisterValidator (the parameter
+// of the rule). The default message of the validator is used when there is no message, the
+// messages set by the ValidationResult (Message, MessageKey) replace them.
+
+// The prefix of the messages of the validators
+const validationMessagePrefix = "validation."
+
+// Returns the message of the validation error of the validator for the key, translated for
+// the locale if the message files have it, the default message of the validator otherwise
+func validationMessage(translator func(locale, message string, args ...interface{}) string, locale string, check Validator, key string) string {
+	if translator == nil {
+		return check.DefaultMessage()
+	}
+	name, args := validatorMessage(check)
+	if name == "" {
+		return check.DefaultMessage()
+	}
+	messages := []string{validationMessagePrefix + name}
+	if key != "" {
+		messages = append([]string{validationMessagePrefix + name + "." + key}, messages...)
+	}
+	for _, message := range messages {
+		if hasMessage(locale, message) {
+			return translator(locale, message, args...)
+		}
+	}
+	return check.DefaultMessage()
+}
+
+// Returns the name of the message of the validator and its arguments, an empty name for
+// the validators which have no message
+func validatorMessage(check Validator) (name string, args []interface{}) {
+	switch check := check.(type) {
+	case Required:
+		return "required", nil
+	case Min:
+		return "min", []interface{}{check.Min}
+	case Max:
+		return "max", []interface{}{check.Max}
+	case Range:
+		return "range", []interface{}{check.Min.Min, check.Max.Max}
+	case MinSize:
+		return "minsize", []interface{}{check.Min}
+	case MaxSize:
+		return "maxsize", []interface{}{check.Max}
+	case Length:
+		return "length", []interface{}{check.N}
+	case Email:
+		return "email", nil
+	case Match:
+		return "match", []interface{}{check.Regexp.String()}
+	case IPAddr:
+		return "ipaddr", nil
+	case MacAddr:
+		return "macaddr", nil
+	case Domain:
+		return "domain", nil
+	case URL:
+		return "url", nil
+	case PureText:
+		return "puretext", nil
+	case FilePath:
+		return "filepath", nil
+	case fieldRule:
+		if check.value != "" {
+			return check.name, []interface{}{check.field, check.value}
+		}
+		return check.name, []interface{}{check.field}
+	case UUID:
+		return "uuid", nil
+	case URLScheme:
+		return "urlscheme", []interface{}{strings.Join(check.Schemes, ", ")}
+	case E164:
+		return "e164", nil
+	case IBAN:
+		return "iban", nil
+	case CreditCard:
+		return "creditcard", nil
+	case CountryCode:
+		return "country", nil
+	case CurrencyCode:
+		return "currency", nil
+	case FileName:
+		return "filename", nil
+	case registeredValidator:
+		if check.name == "" {
+			return "", nil
+		}
+		return check.name, []interface{}{check.param}
+	}
+	return "", nil
+}
+
+// Returns the message of the validation error of the validator for the key
+func (v *Validation) message(check Validator, key string) string {
+	locale := ""
+	if v.Request != nil {
+		locale = v.Request.Locale
+	}
+	return validationMessage(v.Translator, locale, check, key)
+}
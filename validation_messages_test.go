@@ -0,0 +1,54 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestValidationMessages(t *testing.T) {
+	loadMessages(testDataPath)
+	loadTestI18nConfig(t)
+	defer setMessages(nil)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	c := NewTestController(nil, req)
+	c.Request.Locale = "nl"
+	validation := &Validation{Request: c.Request, Translator: MessageFunc}
+
+	tests := []struct {
+		result   *ValidationResult
+		expected string
+	}{
+		{validation.Required("").Key("booking.Email"), "Verplicht"},
+		{validation.Required("").Key("booking.Name"), "Naam is verplicht"},
+		{validation.MinSize("ab", 3).Key("booking.Code"), "Minimale lengte is 3"},
+		{validation.Check("", Required{}).Key("booking.Name"), "Naam is verplicht"},
+		{validation.Required("").Message("Custom").Key("booking.Name"), "Custom"},
+		{validation.MaxSize("abcd", 3).Key("booking.Code"), "Maximum size is 3"},
+	}
+	for _, test := range tests {
+		if message := strings.TrimSpace(test.result.Error.Message); message != test.expected {
+			t.Errorf("Expected %q, got %q", test.expected, message)
+		}
+	}
+
+	// The validate tags use the locale as well
+	validation = &Validation{Request: c.Request, Translator: MessageFunc}
+	validation.Struct("booking", taggedGuest{Age: 20})
+	if errors := validation.ErrorMap(); errors["booking.Name"] == nil || errors["booking.Name"].Message != "Naam is verplicht" {
+		t.Errorf("Expected the translated message of the field, got %v", errors)
+	}
+
+	// The messages of the default language are used for the unknown locales, without a
+	// translation the default message of the validator is used
+	c.Request.Locale = "en"
+	validation = &Validation{Request: c.Request, Translator: MessageFunc}
+	if message := validation.Required("").Key("booking.Name").Error.Message; message != "Required" {
+		t.Errorf("Expected the default message, got %q", message)
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"reflect"
+	"strings"
+)
+
+// The validate tags of the struct arguments of an action are exported for the client side
+// validation by ValidationSchema, keyed by the parameters of the fields
+//   {"booking.Name": {"required": true, "maxLength": 50}, "booking.Nights": {"min": 1, "max": 30}}
+// The elements of the slices are keyed by [] (booking.Guests[].Name). The template function
+// validationSchema writes them in a script
+//   <script>var rules = {{validationSchema "Hotels.Book"}};</script>
+
+// ValidationRules are the rules of a parameter, the rules which have no client side
+// equivalent (nefield, the rules of RegisterValidator) are listed by name in Rules
+type ValidationRules struct {
+	Required  bool     `json:"required,omitempty"`
+	Min       *float64 `json:"min,omitempty"`
+	Max       *float64 `json:"max,omitempty"`
+	MinLength *int     `json:"minLength,omitempty"`
+	MaxLength *int     `json:"maxLength,omitempty"`
+	Pattern   string   `json:"pattern,omitempty"`
+	Format    string   `json:"format,omitempty"`  // email, url, domain, ip, mac, uuid, e164, iban...
+	EqualTo   string   `json:"equalTo,omitempty"` // The parameter of the field it is equal to
+	Rules     []string `json:"rules,omitempty"`
+}
+
+// ValidationSchema returns the rules of the parameters of the struct arguments of the
+// action (Hotels.Book), in the scenarios of its @Scenario annotation
+func ValidationSchema(action string) (map[string]*ValidationRules, error) {
+	parts := strings.Split(action, ".")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("Invalid action %s, expected Controller.Method", action)
+	}
+	ct := ControllerTypeByName(parts[0], anyModule)
+	if ct == nil {
+		return nil, fmt.Errorf("Controller not found for %s", action)
+	}
+	mt := ct.Method(parts[1])
+	if mt == nil {
+		return nil, fmt.Errorf("Action not found for %s", action)
+	}
+	schema := map[string]*ValidationRules{}
+	for _, arg := range mt.Args {
+		if !arg.Type.Implements(websocketType) {
+			addValidationRules(schema, arg.Name, arg.Type, mt.scenarios, map[reflect.Type]bool{})
+		}
+	}
+	return schema, nil
+}
+
+// Returns the JSON of the validation schema of the action for the templates
+func validationSchemaJS(action string) (template.JS, error) {
+	schema, err := ValidationSchema(action)
+	if err != nil {
+		return "", err
+	}
+	js, err := json.Marshal(schema)
+	return template.JS(js), err
+}
+
+// Adds the rules of the fields of the struct type, the types of the elements of the slices
+// and the arrays are walked, a recursive type is not walked again
+func addValidationRules(schema map[string]*ValidationRules, key string, typ reflect.Type, scenarios []string, seen map[reflect.Type]bool) {
+	for typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array {
+		if typ.Kind() != reflect.Ptr {
+			key += "[]"
+		}
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct || seen[typ] {
+		return
+	}
+	seen[typ] = true
+	defer delete(seen, typ)
+	params := make(map[string]string, typ.NumField())
+	for i := 0; i < typ.NumField(); i++ {
+		params[typ.Field(i).Name] = validatedParam(typ.Field(i))
+	}
+	for _, field := range structValidation(typ) {
+		fieldKey := field.param
+		if key != "" {
+			fieldKey = key + "." + field.param
+		}
+		if validators := scenarioValidators(field.validators, scenarios); len(validators) > 0 {
+			rules := &ValidationRules{}
+			for _, validator := range validators {
+				rules.add(validator, key, params)
+			}
+			schema[fieldKey] = rules
+		}
+		if field.nested {
+			addValidationRules(schema, fieldKey, typ.Field(field.index).Type, scenarios, seen)
+		}
+	}
+}
+
+// Adds the rule of the validator, the other fields are keyed by their parameters
+func (rules *ValidationRules) add(validator Validator, key string, params map[string]string) {
+	switch validator := validator.(type) {
+	case Required:
+		rules.Required = true
+	case Min:
+		rules.Min = &validator.Min
+	case Max:
+		rules.Max = &validator.Max
+	case MinSize:
+		rules.MinLength = &validator.Min
+	case MaxSize:
+		rules.MaxLength = &validator.Max
+	case Length:
+		rules.MinLength, rules.MaxLength = &validator.N, &validator.N
+	case Email:
+		rules.Format = "email"
+	case Match:
+		rules.Pattern = validator.Regexp.String()
+	case URL:
+		rules.Format = "url"
+	case Domain:
+		rules.Format = "domain"
+	case IPAddr:
+		rules.Format = "ip"
+	case MacAddr:
+		rules.Format = "mac"
+	case URLScheme:
+		rules.Format = "url"
+	case UUID:
+		rules.Format = "uuid"
+	case E164:
+		rules.Format = "e164"
+	case IBAN:
+		rules.Format = "iban"
+	case CreditCard:
+		rules.Format = "creditcard"
+	case CountryCode:
+		rules.Format = "country"
+	case CurrencyCode:
+		rules.Format = "currency"
+	case FileName:
+		rules.Format = "filename"
+	case fieldRule:
+		if validator.name == "eqfield" {
+			rules.EqualTo = params[validator.field]
+			if key != "" {
+				rules.EqualTo = key + "." + rules.EqualTo
+			}
+		} else {
+			rules.Rules = append(rules.Rules, validator.name)
+		}
+	case registeredValidator:
+		rules.Rules = append(rules.Rules, validator.name)
+	default:
+		rules.Rules = append(rules.Rules, fmt.Sprintf("%T", validator))
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// The validation errors kept by Validation.Keep are restored by the next request, they are
+// stored in a cookie by default. A large form may not fit in a cookie, and a JSON client
+// does not send the cookies back, the store is set in app.conf
+//   validation.store = cookie       # The errors are in the REVEL_ERRORS cookie
+//   validation.store = session      # The errors are in the session, under the _ERRORS key
+//   validation.store = cache        # The errors are in the cache, under a one-time token
+//   validation.store.expires = 10m  # How long the errors stay in the cache
+// The cache store needs the cache module, the token is sent in the REVEL_ERRORS cookie and
+// in the X-Validation-Token header, a client which does not keep the cookies sends the
+// token back in the X-Validation-Token header. The errors are restored once.
+
+const (
+	// ValidationTokenHeader is the header of the token of the errors kept in the cache
+	ValidationTokenHeader = "X-Validation-Token"
+
+	// The session key of the errors kept in the session
+	sessionErrorsKey = "_ERRORS"
+)
+
+// A store of the errors kept for the next request
+type validationErrorsStore interface {
+	// Returns the errors kept by the previous request, and true if any were stored
+	restore(c *Controller) ([]*ValidationError, bool)
+	// Stores the errors (encoded as a key value cookie) for the next request, removes the
+	// errors restored when there are none
+	save(c *Controller, errorsValue string, restored bool)
+}
+
+var (
+	cookieErrorsStore validationErrorsStore = cookieErrors{}
+
+	validationErrorsStores = map[string]validationErrorsStore{
+		"cookie":  cookieErrorsStore,
+		"session": sessionErrors{},
+		"cache":   cacheErrors{},
+	}
+)
+
+// Returns the store of app.conf, the cookie store if unknown
+func keptErrorsStore() validationErrorsStore {
+	name := Config.StringDefault("validation.store", "cookie")
+	store, found := validationErrorsStores[name]
+	if !found {
+		utilLog.Error("Validation: Unknown validation.store, using the cookie", "store", name)
+		return cookieErrorsStore
+	}
+	if name == "cache" && ActionCache == nil {
+		utilLog.Error("Validation: The cache validation.store needs the cache module, using the cookie")
+		return cookieErrorsStore
+	}
+	return store
+}
+
+// Returns the errors of the key value cookie value
+func parseKeptErrors(value string) []*ValidationError {
+	errors := make([]*ValidationError, 0, 5)
+	ParseKeyValueCookie(value, func(key, val string) {
+		errors = append(errors, &ValidationError{Key: key, Message: val})
+	})
+	return errors
+}
+
+// The errors kept in the REVEL_ERRORS cookie
+type cookieErrors struct{}
+
+func (cookieErrors) restore(c *Controller) ([]*ValidationError, bool) {
+	errors, err := restoreValidationErrors(c.Request)
+	return errors, err != http.ErrNoCookie
+}
+
+func (cookieErrors) save(c *Controller, errorsValue string, restored bool) {
+	if errorsValue != "" {
+		c.SetCookie(errorsCookie(url.QueryEscape(errorsValue)))
+	} else if restored {
+		c.SetCookie(expiredErrorsCookie())
+	}
+}
+
+// Returns the REVEL_ERRORS cookie of the value
+func errorsCookie(value string) *http.Cookie {
+	return cookieAttributesOf(errorsCookieKind).Cookie("_ERRORS", value)
+}
+
+// Returns the REVEL_ERRORS cookie which removes the cookie
+func expiredErrorsCookie() *http.Cookie {
+	cookie := errorsCookie("")
+	cookie.MaxAge = -1
+	return cookie
+}
+
+// The errors kept in the session
+type sessionErrors struct{}
+
+func (sessionErrors) restore(c *Controller) ([]*ValidationError, bool) {
+	value, found := c.Session[sessionErrorsKey]
+	if !found {
+		return nil, false
+	}
+	delete(c.Session, sessionErrorsKey)
+	return parseKeptErrors(value), true
+}
+
+func (sessionErrors) save(c *Controller, errorsValue string, restored bool) {
+	if errorsValue == "" {
+		return
+	}
+	if c.Session == nil {
+		utilLog.Error("Validation: The session validation.store needs the SessionFilter")
+		return
+	}
+	// The session values have no null bytes
+	c.Session[sessionErrorsKey] = url.QueryEscape(errorsValue)
+}
+
+// The errors kept in the cache under a one-time token
+type cacheErrors struct{}
+
+// Returns the key of the token in the cache
+func cacheErrorsKey(token string) string {
+	return "revel-validation-errors:" + token
+}
+
+func (cacheErrors) restore(c *Controller) ([]*ValidationError, bool) {
+	token := c.Request.GetHttpHeader(ValidationTokenHeader)
+	hasCookie := false
+	if cookie, err := c.Request.Cookie(cookieAttributesOf(errorsCookieKind).Name("_ERRORS")); err == nil {
+		hasCookie = true
+		if token == "" {
+			token = cookie.GetValue()
+		}
+	}
+	if token == "" {
+		return nil, hasCookie
+	}
+	var value string
+	if err := ActionCache.Get(cacheErrorsKey(token), &value); err != nil {
+		return nil, hasCookie
+	}
+	if err := ActionCache.Delete(cacheErrorsKey(token)); err != nil {
+		utilLog.Warn("Validation: Failed to delete the kept errors", "error", err)
+	}
+	return parseKeptErrors(value), true
+}
+
+func (cacheErrors) save(c *Controller, errorsValue string, restored bool) {
+	if errorsValue == "" {
+		if restored {
+			c.SetCookie(expiredErrorsCookie())
+		}
+		return
+	}
+	buffer := make([]byte, 16)
+	if _, err := rand.Read(buffer); err != nil {
+		utilLog.Error("Validation: Failed to create the token of the kept errors", "error", err)
+		return
+	}
+	token := hex.EncodeToString(buffer)
+	expires := ConfigDurationDefault("validation.store.expires", 10*time.Minute, time.Second)
+	if err := ActionCache.Set(cacheErrorsKey(token), url.QueryEscape(errorsValue), expires); err != nil {
+		utilLog.Error("Validation: Failed to keep the errors in the cache", "error", err)
+		return
+	}
+	c.SetCookie(errorsCookie(token))
+	c.Response.Out.Header().Set(ValidationTokenHeader, token)
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Runs the ValidationFilter with the session and the headers of the request, the action
+// fails the validation and keeps the errors when keep is set
+func keptErrorsRequest(session Session, header http.Header, keep bool) (*httptest.ResponseRecorder, *Controller) {
+	req, _ := http.NewRequest("GET", "/", nil)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	recorder := httptest.NewRecorder()
+	c := NewTestController(recorder, req)
+	c.Session = session
+	ValidationFilter(c, []Filter{func(c *Controller, _ []Filter) {
+		if keep {
+			c.Validation.Required("").Key("user.Name")
+			c.Validation.Keep()
+		}
+	}})
+	return recorder, c
+}
+
+func TestValidationSessionStore(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOption("validation.store", "session")
+	defer Config.SetOption("validation.store", "cookie")
+
+	session := Session{}
+	recorder, _ := keptErrorsRequest(session, nil, true)
+	if _, err := getRecordedCookie(recorder, "REVEL_ERRORS"); err != http.ErrNoCookie {
+		t.Errorf("Expected no errors cookie, got %v", err)
+	}
+	if session[sessionErrorsKey] == "" {
+		t.Fatalf("Expected the errors in the session, got %v", session)
+	}
+
+	_, c := keptErrorsRequest(session, nil, false)
+	if errors := c.Validation.ErrorMap(); len(errors) != 1 || errors["user.Name"] == nil {
+		t.Errorf("Expected the errors to be restored, got %v", errors)
+	}
+	if _, found := session[sessionErrorsKey]; found {
+		t.Errorf("Expected the errors to be removed from the session")
+	}
+}
+
+func TestValidationCacheStore(t *testing.T) {
+	startFakeBookingApp()
+	ActionCache = testActionCache{}
+	Config.SetOption("validation.store", "cache")
+	defer func() {
+		ActionCache = nil
+		Config.SetOption("validation.store", "cookie")
+	}()
+
+	recorder, _ := keptErrorsRequest(nil, nil, true)
+	token := recorder.Header().Get(ValidationTokenHeader)
+	if cookie, err := getRecordedCookie(recorder, "REVEL_ERRORS"); err != nil || token == "" || cookie.Value != token {
+		t.Fatalf("Expected the token in the header and the cookie, got %q %v", token, err)
+	}
+
+	// A JSON client sends the token in the header, it is used once
+	header := http.Header{ValidationTokenHeader: {token}, "Content-Type": {"application/json"}}
+	_, c := keptErrorsRequest(nil, header, false)
+	if errors := c.Validation.ErrorMap(); len(errors) != 1 || errors["user.Name"] == nil {
+		t.Errorf("Expected the errors to be restored, got %v", errors)
+	}
+	if _, c = keptErrorsRequest(nil, header, false); c.Validation.HasErrors() {
+		t.Errorf("Expected the token to be used once, got %v", c.Validation.ErrorMap())
+	}
+}
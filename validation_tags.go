@@ -0,0 +1,467 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// The fields of the struct arguments of an action are validated by their validate tag once
+// they are bound, the errors are keyed by the parameters of the fields (booking.Name)
+//   type Booking struct {
+//   	Name   string   `validate:"required,max=50"`
+//   	Email  string   `validate:"required,email"`
+//   	Nights int      `validate:"min=1,max=30"`
+//   	Code   string   `validate:"match=^[A-Z]{3}[0-9]+$"`
+//   	Guests []Guest  // The fields of the guests are validated as well (booking.Guests[0].Name)
+//   }
+// The action only checks the result
+//   if c.Validation.HasErrors() {
+//   	c.Validation.Keep()
+//   	c.FlashParams()
+//   	return c.Redirect(Hotels.Book, id)
+//   }
+// The rules are
+//   required        - the value is not empty (see Required)
+//   min=n, max=n    - the number is at least or at most n, the size of a string or a slice
+//                     is at least or at most n (see MinSize and MaxSize)
+//   len=n           - the size is n
+//   email, url, domain, ip, mac, uuid, e164 (phone numbers), iban, creditcard, country
+//   (ISO 3166 alpha-2), currency (ISO 4217), filename
+//   url=https http  - the URL has one of the schemes
+//   match=regex     - the string matches the regular expression, which has no comma
+//   eqfield=F       - the value is equal to the value of the field F of the struct
+//   nefield=F       - the value is not equal to the value of the field F
+//   required_if=F v - the value is not empty if the field F has the value v
+//   required_with=F - the value is not empty if the field F is not empty
+// and the rules registered by the application (see RegisterValidator). The rules other than
+// required, required_if, required_with and eqfield are not checked for an empty string or a
+// nil pointer, nor are the rules checked for an Optional which is not present. A struct is
+// validated by the Validation.Struct method as well.
+//
+// A rule followed by scenarios is only checked in these scenarios, selected by the @Scenario
+// annotation of the action, or by Validation.Scenario (in a Before interceptor, or before
+// Validation.Struct is called by the action)
+//   Password string `validate:"required@create,min=8"`
+//   Role     string `validate:"required@admin|update"`
+// The regular expression of a match rule cannot end with @ and a name for that reason.
+//
+// The rules of a whole struct are set by StructLevel, they are checked after its fields
+//   revel.StructLevel(func(v *revel.Validation, key string, booking models.Booking) {
+//   	if booking.CheckOutDate.Before(booking.CheckInDate) {
+//   		v.Error("Must be after the check in date").Key(key + ".CheckOutDate")
+//   	}
+//   })
+
+// A rule of a validate tag, returns the validator of the parameter for the type of field
+type validationRule func(param string, typ reflect.Type) (Validator, error)
+
+var (
+	validationRules = map[string]validationRule{
+		"required": func(string, reflect.Type) (Validator, error) { return Required{}, nil },
+		"min":      sizeOrNumberRule(func(n float64) Validator { return Min{n} }, func(n int) Validator { return MinSize{n} }),
+		"max":      sizeOrNumberRule(func(n float64) Validator { return Max{n} }, func(n int) Validator { return MaxSize{n} }),
+		"len": func(param string, typ reflect.Type) (Validator, error) {
+			n, err := strconv.Atoi(param)
+			return Length{n}, err
+		},
+		"email": func(string, reflect.Type) (Validator, error) { return Email{Match{emailPattern}}, nil },
+		"url": func(param string, typ reflect.Type) (Validator, error) {
+			if schemes := strings.Fields(param); len(schemes) > 0 {
+				return URLScheme{schemes}, nil
+			}
+			return URL{}, nil
+		},
+		"domain":     func(string, reflect.Type) (Validator, error) { return Domain{}, nil },
+		"ip":         func(string, reflect.Type) (Validator, error) { return ValidIPAddr(IPAny), nil },
+		"mac":        func(string, reflect.Type) (Validator, error) { return MacAddr{}, nil },
+		"uuid":       func(string, reflect.Type) (Validator, error) { return UUID{}, nil },
+		"e164":       func(string, reflect.Type) (Validator, error) { return E164{}, nil },
+		"iban":       func(string, reflect.Type) (Validator, error) { return IBAN{}, nil },
+		"creditcard": func(string, reflect.Type) (Validator, error) { return CreditCard{}, nil },
+		"country":    func(string, reflect.Type) (Validator, error) { return CountryCode{}, nil },
+		"currency":   func(string, reflect.Type) (Validator, error) { return CurrencyCode{}, nil },
+		"filename":   func(string, reflect.Type) (Validator, error) { return FileName{}, nil },
+		"match": func(param string, typ reflect.Type) (Validator, error) {
+			regex, err := regexp.Compile(param)
+			return Match{regex}, err
+		},
+		"eqfield": fieldRuleOf("eqfield", "Must be equal to %s", true, func(value, other interface{}, _ string) bool {
+			return reflect.DeepEqual(value, other)
+		}),
+		"nefield": fieldRuleOf("nefield", "Must be different from %s", false, func(value, other interface{}, _ string) bool {
+			return !reflect.DeepEqual(value, other)
+		}),
+		"required_if": fieldRuleOf("required_if", "Required", true, func(value, other interface{}, expected string) bool {
+			return fmt.Sprint(other) != expected || Required{}.IsSatisfied(value)
+		}),
+		"required_with": fieldRuleOf("required_with", "Required", true, func(value, other interface{}, _ string) bool {
+			return !Required{}.IsSatisfied(other) || Required{}.IsSatisfied(value)
+		}),
+	}
+
+	// The validated fields of the struct types
+	structValidations sync.Map
+
+	// The scenarios which end a rule (required@create|update)
+	ruleScenarios = regexp.MustCompile(`@([\w-]+(\|[\w-]+)*)$`)
+
+	// The validations of the struct types set by StructLevel
+	structLevelValidations = map[reflect.Type][]func(v *Validation, key string, value reflect.Value){}
+)
+
+// StructLevel adds the validation of the struct type T, called with the key of the struct
+// once its fields are validated. The validations are added before the controllers (in an
+// init function).
+func StructLevel[T any](fn func(v *Validation, key string, value T)) {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	structLevelValidations[typ] = append(structLevelValidations[typ], func(v *Validation, key string, value reflect.Value) {
+		fn(v, key, value.Interface().(T))
+	})
+}
+
+// A rule of a validate tag which checks the field with another field of the struct
+type fieldRule struct {
+	name     string // The name of the rule
+	field    string // The name of the other field
+	value    string // The value of the other field (required_if)
+	message  string
+	required bool // The rule is checked for the empty values
+	check    func(value, other interface{}, expected string) bool
+}
+
+// Returns the rule of the field rule, the parameter is the name of the other field followed
+// by its value for the rules which have one (required_if)
+func fieldRuleOf(name, message string, required bool, check func(value, other interface{}, expected string) bool) validationRule {
+	return func(param string, typ reflect.Type) (Validator, error) {
+		rule := fieldRule{name: name, message: message, required: required, check: check}
+		if name == "required_if" {
+			fields := strings.Fields(param)
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("%s must have a field and a value, got %q", name, param)
+			}
+			rule.field, rule.value = fields[0], fields[1]
+		} else if rule.field = strings.TrimSpace(param); rule.field == "" {
+			return nil, fmt.Errorf("%s must have a field", name)
+		}
+		if strings.Contains(message, "%s") {
+			rule.message = fmt.Sprintf(message, rule.field)
+		}
+		return rule, nil
+	}
+}
+
+// IsSatisfied is true, the field rules are checked with the struct of the field (see
+// isSatisfiedIn)
+func (r fieldRule) IsSatisfied(obj interface{}) bool {
+	return true
+}
+
+func (r fieldRule) DefaultMessage() string {
+	return r.message
+}
+
+// Returns true if the value is satisfied by the other field of the struct
+func (r fieldRule) isSatisfiedIn(value interface{}, parent reflect.Value) bool {
+	other, _ := validatedField(parent.FieldByName(r.field))
+	return r.check(value, other, r.value)
+}
+
+// RegisterValidator registers the rule of the name, used by the validate tags (sku or
+// vat=FR) and by the Validation.Check method with ValidRule
+//   revel.RegisterValidator("vat", func(value interface{}, country string) bool {
+//   	number, _ := value.(string)
+//   	return vat.Valid(country, number)
+//   }, "Must be a valid VAT number")
+//   c.Validation.Check(company.VAT, revel.ValidRule("vat", "FR")).Key("company.VAT")
+// The value of a field is its basic value, a string, an int, a float64 or a bool for the
+// types of these kinds. The rules are registered before the controllers (in an init function).
+func RegisterValidator(name string, fn func(value interface{}, param string) bool, defaultMessage string) {
+	validationRules[name] = func(param string, typ reflect.Type) (Validator, error) {
+		return registeredValidator{name: name, fn: fn, param: param, message: defaultMessage}, nil
+	}
+}
+
+// ValidRule returns the validator of the rule of the name with the parameter, a rule
+// registered by RegisterValidator or a rule of the strings (email, match=regex). The rules of
+// the other fields (eqfield) are checked by the validate tags only.
+func ValidRule(name, param string) Validator {
+	if rule, found := validationRules[name]; found {
+		validator, err := rule(param, reflect.TypeOf(""))
+		if err == nil {
+			return validator
+		}
+		utilLog.Error("ValidRule: Invalid rule", "rule", name, "param", param, "error", err)
+		return registeredValidator{fn: func(interface{}, string) bool { return false }, message: "Invalid rule " + name}
+	}
+	utilLog.Error("ValidRule: Unknown rule", "rule", name)
+	return registeredValidator{fn: func(interface{}, string) bool { return false }, message: "Unknown rule " + name}
+}
+
+// A validator registered by RegisterValidator, with the parameter of its rule
+type registeredValidator struct {
+	name    string
+	fn      func(value interface{}, param string) bool
+	param   string
+	message string
+}
+
+func (r registeredValidator) IsSatisfied(obj interface{}) bool {
+	return r.fn(validatedValue(reflect.ValueOf(&obj).Elem()), r.param)
+}
+
+func (r registeredValidator) DefaultMessage() string {
+	return r.message
+}
+
+// Returns the rule of the number validator for the numbers, of the size validator otherwise
+func sizeOrNumberRule(number func(float64) Validator, size func(int) Validator) validationRule {
+	return func(param string, typ reflect.Type) (Validator, error) {
+		switch typ.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			n, err := strconv.ParseFloat(param, 64)
+			return number(n), err
+		}
+		n, err := strconv.Atoi(param)
+		return size(n), err
+	}
+}
+
+// The validators of a field of a struct
+type fieldValidation struct {
+	index      int
+	param      string      // The parameter of the field
+	validators []Validator // The validators of the validate tag
+	nested     bool        // The field has structs to validate
+}
+
+// A validator of the rules followed by scenarios
+type scenarioValidator struct {
+	Validator
+	scenarios []string
+}
+
+// Scenario selects the scenarios of the rules checked by the following calls to Struct, the
+// rules which have no scenario are always checked
+func (v *Validation) Scenario(names ...string) *Validation {
+	v.scenarios = names
+	return v
+}
+
+// Returns the validators checked in the scenarios, the validators of the other scenarios are
+// removed
+func scenarioValidators(validators []Validator, scenarios []string) []Validator {
+	selected := make([]Validator, 0, len(validators))
+	for _, validator := range validators {
+		if scenario, ok := validator.(scenarioValidator); ok {
+			if !hasScenario(scenario.scenarios, scenarios) {
+				continue
+			}
+			validator = scenario.Validator
+		}
+		selected = append(selected, validator)
+	}
+	return selected
+}
+
+// Returns true if one of the scenarios of the rule is selected
+func hasScenario(ruleScenarios, selected []string) bool {
+	for _, scenario := range ruleScenarios {
+		for _, name := range selected {
+			if scenario == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Struct validates the fields of the struct by their validate tag, the errors are keyed by
+// the key and the parameters of the fields (booking.Name), or by the parameters of the
+// fields when the key is empty. A pointer to a struct is validated if not nil, the elements
+// of a slice are validated, the other values are ignored.
+func (v *Validation) Struct(key string, obj interface{}) {
+	v.validateValue(key, reflect.ValueOf(obj))
+}
+
+// Validates the struct values of the value, the elements of the slices and the arrays
+func (v *Validation) validateValue(key string, value reflect.Value) {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return
+		}
+		value = value.Elem()
+	}
+	switch value.Kind() {
+	case reflect.Struct:
+		for _, field := range structValidation(value.Type()) {
+			fieldKey := field.param
+			if key != "" {
+				fieldKey = key + "." + field.param
+			}
+			fieldValue := value.Field(field.index)
+			v.validateField(fieldKey, fieldValue, value, scenarioValidators(field.validators, v.scenarios))
+			if field.nested {
+				v.validateValue(fieldKey, fieldValue)
+			}
+		}
+		for _, validate := range structLevelValidations[value.Type()] {
+			validate(v, key, value)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			v.validateValue(fmt.Sprintf("%s[%d]", key, i), value.Index(i))
+		}
+	}
+}
+
+// Checks the validators of the field of the struct until one fails
+func (v *Validation) validateField(key string, value, parent reflect.Value, validators []Validator) {
+	checked, present := validatedField(value)
+	if !present {
+		return
+	}
+	for _, validator := range validators {
+		rule, isFieldRule := validator.(fieldRule)
+		if _, required := validator.(Required); !required && !(isFieldRule && rule.required) && (checked == nil || checked == "") {
+			continue
+		}
+		satisfied := false
+		if isFieldRule {
+			satisfied = rule.isSatisfiedIn(checked, parent)
+		} else {
+			satisfied = validator.IsSatisfied(checked)
+		}
+		if !satisfied {
+			v.Errors = append(v.Errors, &ValidationError{Message: v.message(validator, key), Key: key})
+			return
+		}
+	}
+}
+
+// Returns the value of the field checked by the validators, false for an Optional which is
+// not present
+func validatedField(value reflect.Value) (interface{}, bool) {
+	if opt, ok := value.Interface().(optional); ok {
+		optionalValue, present := opt.optionalValue()
+		if !present {
+			return nil, false
+		}
+		value = reflect.ValueOf(&optionalValue).Elem()
+	}
+	return validatedValue(value), true
+}
+
+// Returns the value checked by the validators, the basic value of its kind (an int for the
+// integers, a float64 for the floats), nil for a nil pointer
+func validatedValue(value reflect.Value) interface{} {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+	switch value.Kind() {
+	case reflect.String:
+		return value.String()
+	case reflect.Bool:
+		return value.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(value.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int(value.Uint())
+	case reflect.Float32, reflect.Float64:
+		return value.Float()
+	}
+	return value.Interface()
+}
+
+// Returns the validated fields of the struct type, the validate tags are parsed once
+func structValidation(typ reflect.Type) []*fieldValidation {
+	if fields, found := structValidations.Load(typ); found {
+		return fields.([]*fieldValidation)
+	}
+	var fields []*fieldValidation
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		validation := &fieldValidation{index: i, param: validatedParam(field), nested: hasStructs(field.Type)}
+		validation.validators = tagValidators(typ, field)
+		if len(validation.validators) > 0 || validation.nested {
+			fields = append(fields, validation)
+		}
+	}
+	structValidations.Store(typ, fields)
+	return fields
+}
+
+// Returns the parameter of the field, its name or its param tag
+func validatedParam(field reflect.StructField) string {
+	if param, found := field.Tag.Lookup("param"); found && param != "" && param != "-" {
+		return param
+	}
+	return field.Name
+}
+
+// Returns the validators of the validate tag of the field, the invalid rules are logged
+func tagValidators(typ reflect.Type, field reflect.StructField) (validators []Validator) {
+	fieldType := field.Type
+	for fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+	if isOptionalType(fieldType) {
+		fieldType = reflect.Zero(fieldType).Interface().(optional).optionalType()
+	}
+	for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+		if rule = strings.TrimSpace(rule); rule == "" {
+			continue
+		}
+		var scenarios []string
+		if match := ruleScenarios.FindStringSubmatch(rule); match != nil {
+			rule, scenarios = strings.TrimSuffix(rule, match[0]), strings.Split(match[1], "|")
+		}
+		name, param := rule, ""
+		if i := strings.Index(rule, "="); i >= 0 {
+			name, param = rule[:i], rule[i+1:]
+		}
+		newValidator, found := validationRules[name]
+		if !found {
+			utilLog.Error("Validation: Unknown validate rule", "type", typ, "field", field.Name, "rule", name)
+			continue
+		}
+		validator, err := newValidator(param, fieldType)
+		if rule, isFieldRule := validator.(fieldRule); err == nil && isFieldRule {
+			if other, found := typ.FieldByName(rule.field); !found || other.PkgPath != "" {
+				err = fmt.Errorf("unknown field %s", rule.field)
+			}
+		}
+		if err != nil {
+			utilLog.Error("Validation: Invalid validate rule", "type", typ, "field", field.Name, "rule", rule, "error", err)
+			continue
+		}
+		if scenarios != nil {
+			validator = scenarioValidator{validator, scenarios}
+		}
+		validators = append(validators, validator)
+	}
+	return
+}
+
+// Returns true if the type is a struct, or has structs, which may have validated fields
+func hasStructs(typ reflect.Type) bool {
+	for typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array {
+		typ = typ.Elem()
+	}
+	return typ.Kind() == reflect.Struct && typ != timeType && !isOptionalType(typ)
+}
@@ -0,0 +1,332 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type taggedGuest struct {
+	Name string `validate:"required"`
+	Age  int    `validate:"min=18"`
+}
+
+type taggedBooking struct {
+	Name    string           `validate:"required,max=10"`
+	Email   string           `validate:"email"`
+	Nights  int64            `validate:"min=1,max=30"`
+	Code    string           `param:"code" validate:"match=^[A-Z]{3}$"`
+	Website *string          `validate:"url"`
+	Tags    []string         `validate:"max=2"`
+	Phone   Optional[string] `validate:"required,len=10"`
+	Guests  []taggedGuest
+	Ignored string `validate:"unknown"`
+}
+
+func TestValidationStruct(t *testing.T) {
+	validation := &Validation{}
+	validation.Struct("booking", &taggedBooking{
+		Name:   "A very long name",
+		Nights: 0,
+		Code:   "ABC",
+		Tags:   []string{"a", "b", "c"},
+		Phone:  Some("123"),
+		Guests: []taggedGuest{{Name: "Jane", Age: 30}, {Age: 12}},
+	})
+	errors := map[string]string{}
+	for key, err := range validation.ErrorMap() {
+		errors[key] = strings.TrimSpace(err.Message)
+	}
+	expected := map[string]string{
+		"booking.Name":           "Maximum size is 10",
+		"booking.Nights":         "Minimum is 1",
+		"booking.Tags":           "Maximum size is 2",
+		"booking.Phone":          "Required length is 10",
+		"booking.Guests[1].Name": "Required",
+		"booking.Guests[1].Age":  "Minimum is 18",
+	}
+	if !reflect.DeepEqual(errors, expected) {
+		t.Errorf("Expected %v, got %v", expected, errors)
+	}
+
+	// The empty values and the missing optionals are only checked when required
+	validation = &Validation{}
+	validation.Struct("", taggedBooking{Name: "Jane", Nights: 2, Code: "abc"})
+	if len(validation.Errors) != 1 || validation.Errors[0].Key != "code" {
+		t.Errorf("Expected an error for the code, got %v", validation.ErrorMap())
+	}
+}
+
+type taggedProduct struct {
+	SKU   string  `validate:"required,sku"`
+	Price float64 `validate:"multiple=5"`
+}
+
+func TestRegisterValidator(t *testing.T) {
+	RegisterValidator("sku", func(value interface{}, param string) bool {
+		sku, _ := value.(string)
+		return regexp.MustCompile(`^[A-Z]{3}-[0-9]{4}$`).MatchString(sku)
+	}, "Must be a valid SKU")
+	RegisterValidator("multiple", func(value interface{}, param string) bool {
+		n, _ := strconv.Atoi(param)
+		price, _ := value.(float64)
+		return int(price)%n == 0
+	}, "Must be a multiple")
+	defer func() {
+		delete(validationRules, "sku")
+		delete(validationRules, "multiple")
+	}()
+
+	validation := &Validation{}
+	validation.Struct("product", taggedProduct{SKU: "abc-1", Price: 12})
+	errors := map[string]string{}
+	for key, err := range validation.ErrorMap() {
+		errors[key] = err.Message
+	}
+	expected := map[string]string{"product.SKU": "Must be a valid SKU", "product.Price": "Must be a multiple"}
+	if !reflect.DeepEqual(errors, expected) {
+		t.Errorf("Expected %v, got %v", expected, errors)
+	}
+
+	validation = &Validation{}
+	validation.Struct("product", taggedProduct{SKU: "ABC-1234", Price: 15})
+	if validation.HasErrors() {
+		t.Errorf("Expected no errors, got %v", validation.ErrorMap())
+	}
+
+	for _, test := range []struct {
+		value     interface{}
+		validator Validator
+		satisfied bool
+	}{
+		{"ABC-1234", ValidRule("sku", ""), true},
+		{"ABC", ValidRule("sku", ""), false},
+		{25.0, ValidRule("multiple", "10"), false},
+		{20.0, ValidRule("multiple", "10"), true},
+		{"jane@example.com", ValidRule("email", ""), true},
+		{"ABC-1234", ValidRule("unknown", ""), false},
+	} {
+		if satisfied := test.validator.IsSatisfied(test.value); satisfied != test.satisfied {
+			t.Errorf("Expected %v for %v, got %v", test.satisfied, test.value, satisfied)
+		}
+	}
+}
+
+type taggedSignup struct {
+	Password        string
+	Confirmation    string `validate:"eqfield=Password"`
+	Login           string `validate:"nefield=Password"`
+	Delivery        string
+	Address         string           `validate:"required_if=Delivery shipping"`
+	Phone           Optional[string] `validate:"required_with=Address"`
+	CheckIn         int
+	CheckOut        int
+	Ignored, Unused string `validate:"eqfield=missing"`
+}
+
+func TestValidationCrossField(t *testing.T) {
+	StructLevel(func(v *Validation, key string, signup taggedSignup) {
+		if signup.CheckOut < signup.CheckIn {
+			v.Errors = append(v.Errors, &ValidationError{Message: "Must be after the check in", Key: key + ".CheckOut"})
+		}
+	})
+	defer delete(structLevelValidations, reflect.TypeOf(taggedSignup{}))
+
+	validation := &Validation{}
+	validation.Struct("signup", taggedSignup{Password: "secret", Login: "secret", Delivery: "shipping",
+		Phone: Some(""), CheckIn: 3, CheckOut: 1})
+	errors := map[string]string{}
+	for key, err := range validation.ErrorMap() {
+		errors[key] = err.Message
+	}
+	expected := map[string]string{
+		"signup.Confirmation": "Must be equal to Password",
+		"signup.Login":        "Must be different from Password",
+		"signup.Address":      "Required",
+		"signup.CheckOut":     "Must be after the check in",
+	}
+	if !reflect.DeepEqual(errors, expected) {
+		t.Errorf("Expected %v, got %v", expected, errors)
+	}
+
+	validation = &Validation{}
+	validation.Struct("signup", taggedSignup{Password: "secret", Confirmation: "secret", Delivery: "pickup",
+		Phone: Some("")})
+	if validation.HasErrors() {
+		t.Errorf("Expected no errors, got %v", validation.ErrorMap())
+	}
+
+	validation = &Validation{}
+	validation.Struct("signup", taggedSignup{Delivery: "shipping", Address: "Main street", Phone: Some("")})
+	if errors := validation.ErrorMap(); len(errors) != 1 || errors["signup.Phone"] == nil {
+		t.Errorf("Expected the phone to be required with the address, got %v", errors)
+	}
+}
+
+type ValidationController struct {
+	*Controller
+}
+
+func (c ValidationController) Book(booking taggedGuest) Result {
+	return c.RenderText("errors %d", len(c.Validation.Errors))
+}
+
+func TestValidationTagsInvoked(t *testing.T) {
+	startFakeBookingApp()
+	RegisterController((*ValidationController)(nil), []*MethodType{{Name: "Book", Args: []*MethodArg{
+		{Name: "booking", Type: reflect.TypeOf((*taggedGuest)(nil))},
+	}}})
+	req, _ := http.NewRequest("POST", "/bookings", nil)
+	resp := httptest.NewRecorder()
+	c := NewTestController(resp, req)
+	if err := c.SetAction("ValidationController", "Book"); err != nil {
+		t.Fatal(err)
+	}
+	c.Params = &Params{Values: url.Values{"booking.Age": {"twelve"}}}
+	c.Validation = &Validation{Request: c.Request, Translator: MessageFunc}
+	ActionInvoker(c, nil)
+	errors := c.Validation.ErrorMap()
+	if len(errors) != 2 || errors["booking.Name"] == nil || errors["booking.Age"].Message != "Must be an integer" {
+		t.Errorf("Expected the errors of the binding and of the validation, got %v", errors)
+	}
+}
+
+func TestValidationRejected(t *testing.T) {
+	startFakeBookingApp()
+	RegisterController((*ValidationController)(nil), []*MethodType{{Name: "Book", Args: []*MethodArg{
+		{Name: "booking", Type: reflect.TypeOf((*taggedGuest)(nil))},
+	}}})
+	invoke := func(accept string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/bookings", nil)
+		req.Header.Set("Accept", accept)
+		resp := httptest.NewRecorder()
+		c := NewTestController(resp, req)
+		if err := c.SetAction("ValidationController", "Book"); err != nil {
+			t.Fatal(err)
+		}
+		c.Request.Format = ResolveFormat(c.Request)
+		c.Params = &Params{Values: url.Values{"booking.Age": {"12"}}}
+		ValidationFilter(c, []Filter{ActionInvoker})
+		c.Result.Apply(c.Request, c.Response)
+		return resp
+	}
+
+	Config.SetOption("validation.reject", "api")
+	defer Config.SetOption("validation.reject", "false")
+	resp := invoke("application/json")
+	expected := `"errors":[{"field":"booking.Name","message":"Required"},{"field":"booking.Age","message":"Minimum is 18"}]`
+	if resp.Code != http.StatusUnprocessableEntity || !strings.Contains(resp.Body.String(), expected) {
+		t.Errorf("Expected the validation errors to be rejected, got %d %s", resp.Code, resp.Body.String())
+	}
+	if resp = invoke("text/html"); resp.Code != http.StatusOK || resp.Body.String() != "errors 2" {
+		t.Errorf("Expected the HTML request to run the action, got %d %s", resp.Code, resp.Body.String())
+	}
+}
+
+type SchemaController struct {
+	*Controller
+}
+
+func (c SchemaController) Signup(booking taggedBooking, signup *taggedSignup, page int) Result {
+	return nil
+}
+
+func TestValidationSchema(t *testing.T) {
+	RegisterController((*SchemaController)(nil), []*MethodType{{Name: "Signup", Args: []*MethodArg{
+		{Name: "booking", Type: reflect.TypeOf((*taggedBooking)(nil))},
+		{Name: "signup", Type: reflect.TypeOf((**taggedSignup)(nil))},
+		{Name: "page", Type: reflect.TypeOf((*int)(nil))},
+	}}})
+	js, err := validationSchemaJS("SchemaController.Signup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		`"booking.Name":{"required":true,"maxLength":10}`,
+		`"booking.Nights":{"min":1,"max":30}`,
+		`"booking.Email":{"format":"email"}`,
+		`"booking.code":{"pattern":"^[A-Z]{3}$"}`,
+		`"booking.Phone":{"required":true,"minLength":10,"maxLength":10}`,
+		`"booking.Guests[].Age":{"min":18}`,
+		`"signup.Confirmation":{"equalTo":"signup.Password"}`,
+		`"signup.Login":{"rules":["nefield"]}`,
+	} {
+		if !strings.Contains(string(js), expected) {
+			t.Errorf("Expected %s in %s", expected, js)
+		}
+	}
+	if _, err := ValidationSchema("SchemaController.Unknown"); err == nil {
+		t.Errorf("Expected an error for an unknown action")
+	}
+}
+
+type scenarioUser struct {
+	Name     string `validate:"required"`
+	Password string `validate:"required@create,min=8"`
+	Role     string `validate:"required@admin|update"`
+}
+
+type ScenarioController struct {
+	*Controller
+}
+
+func (c ScenarioController) Update(user scenarioUser) Result {
+	return c.RenderText("errors %d", len(c.Validation.Errors))
+}
+
+func TestValidationScenarios(t *testing.T) {
+	user := scenarioUser{Name: "Jane", Password: "short"}
+	for _, test := range []struct {
+		scenarios []string
+		expected  []string
+	}{
+		{nil, []string{"user.Password"}},
+		{[]string{"create"}, []string{"user.Password"}},
+		{[]string{"update"}, []string{"user.Password", "user.Role"}},
+		{[]string{"create", "admin"}, []string{"user.Password", "user.Role"}},
+	} {
+		validation := &Validation{}
+		validation.Scenario(test.scenarios...).Struct("user", user)
+		var keys []string
+		for _, err := range validation.Errors {
+			keys = append(keys, err.Key)
+		}
+		if !reflect.DeepEqual(keys, test.expected) {
+			t.Errorf("Expected the errors %v in %v, got %v", test.expected, test.scenarios, keys)
+		}
+	}
+	validation := &Validation{}
+	validation.Scenario("create").Struct("user", scenarioUser{Name: "Jane"})
+	if errors := validation.ErrorMap(); len(errors) != 1 || errors["user.Password"].Message != "Required" {
+		t.Errorf("Expected the password to be required on create, got %v", errors)
+	}
+
+	// The @Scenario annotation selects the scenarios of the action
+	startFakeBookingApp()
+	annotation, _ := ParseAnnotation(`@Scenario(update)`)
+	RegisterController((*ScenarioController)(nil), []*MethodType{{Name: "Update", Annotations: FunctionalAnnotations{annotation},
+		Args: []*MethodArg{{Name: "user", Type: reflect.TypeOf((*scenarioUser)(nil))}}}})
+	req, _ := http.NewRequest("POST", "/users", nil)
+	c := NewTestController(httptest.NewRecorder(), req)
+	if err := c.SetAction("ScenarioController", "Update"); err != nil {
+		t.Fatal(err)
+	}
+	c.Params = &Params{Values: url.Values{"user.Name": {"Jane"}}}
+	c.Validation = &Validation{Request: c.Request, Translator: MessageFunc}
+	ActionInvoker(c, nil)
+	if errors := c.Validation.ErrorMap(); len(errors) != 1 || errors["user.Role"] == nil {
+		t.Errorf("Expected the role to be required on update, got %v", errors)
+	}
+	if schema, _ := ValidationSchema("ScenarioController.Update"); schema["user.Role"] == nil || !schema["user.Role"].Required {
+		t.Errorf("Expected the rules of the scenario in the schema, got %v", schema)
+	}
+}
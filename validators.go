@@ -16,6 +16,9 @@ import (
 	"strings"
 	"time"
 	"unicode/utf8"
+
+	"golang.org/x/text/currency"
+	"golang.org/x/text/language"
 )
 
 type Validator interface {
@@ -641,3 +644,215 @@ func (f FilePath) IsSatisfied(obj interface{}) bool {
 func (f FilePath) DefaultMessage() string {
 	return fmt.Sprintln("Must be a unsanitary string")
 }
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// Requires a string (or a fmt.Stringer, like the UUID types) to be a UUID in its canonical
+// form (123e4567-e89b-12d3-a456-426614174000)
+type UUID struct{}
+
+func ValidUUID() UUID {
+	return UUID{}
+}
+
+func (u UUID) IsSatisfied(obj interface{}) bool {
+	if stringer, ok := obj.(fmt.Stringer); ok {
+		obj = stringer.String()
+	}
+	if str, ok := obj.(string); ok {
+		return uuidPattern.MatchString(str)
+	}
+	return false
+}
+
+func (u UUID) DefaultMessage() string {
+	return "Must be a valid UUID"
+}
+
+// Requires a string to be an absolute URL, with a host and one of the schemes (https)
+type URLScheme struct {
+	Schemes []string
+}
+
+func ValidURLScheme(schemes ...string) URLScheme {
+	return URLScheme{Schemes: schemes}
+}
+
+func (u URLScheme) IsSatisfied(obj interface{}) bool {
+	if str, ok := obj.(string); ok {
+		parsed, err := url.Parse(str)
+		if err != nil || parsed.Host == "" {
+			return false
+		}
+		for _, scheme := range u.Schemes {
+			if strings.EqualFold(parsed.Scheme, scheme) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (u URLScheme) DefaultMessage() string {
+	return "Must be a valid URL (" + strings.Join(u.Schemes, ", ") + ")"
+}
+
+var e164Pattern = regexp.MustCompile(`^\+[1-9][0-9]{1,14}$`)
+
+// Requires a string to be a phone number in the E.164 format (+14155552671)
+type E164 struct{}
+
+func ValidE164() E164 {
+	return E164{}
+}
+
+func (e E164) IsSatisfied(obj interface{}) bool {
+	if str, ok := obj.(string); ok {
+		return e164Pattern.MatchString(str)
+	}
+	return false
+}
+
+func (e E164) DefaultMessage() string {
+	return "Must be a valid phone number"
+}
+
+var ibanPattern = regexp.MustCompile(`^[A-Z]{2}[0-9]{2}[A-Z0-9]{11,30}$`)
+
+// Requires a string to be an IBAN with a valid check digits, the spaces are ignored
+type IBAN struct{}
+
+func ValidIBAN() IBAN {
+	return IBAN{}
+}
+
+func (i IBAN) IsSatisfied(obj interface{}) bool {
+	str, ok := obj.(string)
+	if !ok {
+		return false
+	}
+	iban := strings.ToUpper(strings.Replace(str, " ", "", -1))
+	if !ibanPattern.MatchString(iban) {
+		return false
+	}
+	// The country and the check digits are moved to the end, the letters are numbers from
+	// 10 (A) to 35 (Z), the remainder of the number by 97 is 1
+	remainder := 0
+	for _, r := range iban[4:] + iban[:4] {
+		if r >= 'A' {
+			remainder = (remainder*100 + int(r-'A'+10)) % 97
+		} else {
+			remainder = (remainder*10 + int(r-'0')) % 97
+		}
+	}
+	return remainder == 1
+}
+
+func (i IBAN) DefaultMessage() string {
+	return "Must be a valid IBAN"
+}
+
+// Requires a string to be a credit card number, of 12 to 19 digits which pass the Luhn
+// check, the spaces and the dashes are ignored
+type CreditCard struct{}
+
+func ValidCreditCard() CreditCard {
+	return CreditCard{}
+}
+
+func (c CreditCard) IsSatisfied(obj interface{}) bool {
+	str, ok := obj.(string)
+	if !ok {
+		return false
+	}
+	digits := strings.NewReplacer(" ", "", "-", "").Replace(str)
+	if len(digits) < 12 || len(digits) > 19 {
+		return false
+	}
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		digit := int(digits[i] - '0')
+		if digit < 0 || digit > 9 {
+			return false
+		}
+		if (len(digits)-i)%2 == 0 {
+			if digit *= 2; digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+	}
+	return sum%10 == 0
+}
+
+func (c CreditCard) DefaultMessage() string {
+	return "Must be a valid credit card number"
+}
+
+// Requires a string to be an ISO 3166-1 alpha-2 country code (FR)
+type CountryCode struct{}
+
+func ValidCountryCode() CountryCode {
+	return CountryCode{}
+}
+
+func (c CountryCode) IsSatisfied(obj interface{}) bool {
+	if str, ok := obj.(string); ok && len(str) == 2 && strings.ToUpper(str) == str {
+		region, err := language.ParseRegion(str)
+		return err == nil && region.IsCountry()
+	}
+	return false
+}
+
+func (c CountryCode) DefaultMessage() string {
+	return "Must be a valid country code"
+}
+
+// Requires a string to be an ISO 4217 currency code (EUR)
+type CurrencyCode struct{}
+
+func ValidCurrencyCode() CurrencyCode {
+	return CurrencyCode{}
+}
+
+func (c CurrencyCode) IsSatisfied(obj interface{}) bool {
+	if str, ok := obj.(string); ok && len(str) == 3 && strings.ToUpper(str) == str {
+		_, err := currency.ParseISO(str)
+		return err == nil
+	}
+	return false
+}
+
+func (c CurrencyCode) DefaultMessage() string {
+	return "Must be a valid currency code"
+}
+
+var reservedFileNames = regexp.MustCompile(`(?i)^(con|prn|aux|nul|com[1-9]|lpt[1-9])(\..*)?$`)
+
+// Requires a string to be a file name which is safe on every file system, without a path,
+// a control character, a reserved character (<>:"/\|?*) or a reserved name (NUL, COM1)
+type FileName struct{}
+
+func ValidFileName() FileName {
+	return FileName{}
+}
+
+func (f FileName) IsSatisfied(obj interface{}) bool {
+	str, ok := obj.(string)
+	if !ok || str == "" || len(str) > 255 || str == "." || str == ".." || !utf8.ValidString(str) {
+		return false
+	}
+	if strings.HasSuffix(str, ".") || strings.HasSuffix(str, " ") || reservedFileNames.MatchString(str) {
+		return false
+	}
+	for _, r := range str {
+		if r < 0x20 || r == 0x7f || strings.ContainsRune(`<>:"/\|?*`, r) {
+			return false
+		}
+	}
+	return true
+}
+
+func (f FileName) DefaultMessage() string {
+	return "Must be a valid file name"
+}
@@ -637,3 +637,108 @@ func TestFilePathAllowRelativePath(t *testing.T) {
 		performTests(filepath, tests, t)
 	}
 }
+
+func TestUUID(t *testing.T) {
+	tests := []Expect{
+		{"123e4567-e89b-12d3-a456-426614174000", true, "a UUID"},
+		{"123E4567-E89B-12D3-A456-426614174000", true, "an upper case UUID"},
+		{"123e4567e89b12d3a456426614174000", false, "a UUID without dashes"},
+		{"123e4567-e89b-12d3-a456-42661417400g", false, "a UUID with an invalid digit"},
+		{12, false, "an integer"},
+	}
+	for _, uuid := range []revel.UUID{{}, revel.ValidUUID()} {
+		performTests(uuid, tests, t)
+	}
+}
+
+func TestURLScheme(t *testing.T) {
+	tests := []Expect{
+		{"https://example.com/path", true, "an https URL"},
+		{"HTTPS://example.com", true, "an upper case scheme"},
+		{"http://example.com", false, "an http URL"},
+		{"javascript:alert(1)", false, "a javascript URL"},
+		{"https:///path", false, "a URL without a host"},
+		{"/path", false, "a relative URL"},
+	}
+	performTests(revel.ValidURLScheme("https"), tests, t)
+	performTests(revel.URLScheme{Schemes: []string{"http", "https"}}, []Expect{{"http://example.com", true, "an http URL"}}, t)
+}
+
+func TestE164(t *testing.T) {
+	tests := []Expect{
+		{"+14155552671", true, "a US number"},
+		{"+442071838750", true, "a UK number"},
+		{"14155552671", false, "a number without a plus"},
+		{"+0155552671", false, "a number starting with a zero"},
+		{"+1 415 555 2671", false, "a number with spaces"},
+		{"+1234567890123456", false, "a number of 16 digits"},
+	}
+	for _, e164 := range []revel.E164{{}, revel.ValidE164()} {
+		performTests(e164, tests, t)
+	}
+}
+
+func TestIBAN(t *testing.T) {
+	tests := []Expect{
+		{"GB82WEST12345698765432", true, "a UK IBAN"},
+		{"DE89 3704 0044 0532 0130 00", true, "a German IBAN with spaces"},
+		{"fr1420041010050500013m02606", true, "a lower case French IBAN"},
+		{"GB82WEST12345698765433", false, "an IBAN with invalid check digits"},
+		{"GB82", false, "a short IBAN"},
+		{"GB82WEST1234569876543!", false, "an IBAN with a symbol"},
+	}
+	for _, iban := range []revel.IBAN{{}, revel.ValidIBAN()} {
+		performTests(iban, tests, t)
+	}
+}
+
+func TestCreditCard(t *testing.T) {
+	tests := []Expect{
+		{"4111111111111111", true, "a Visa test number"},
+		{"5500-0000-0000-0004", true, "a MasterCard test number with dashes"},
+		{"3782 822463 10005", true, "an American Express test number with spaces"},
+		{"4111111111111112", false, "a number failing the Luhn check"},
+		{"4111", false, "a short number"},
+		{"4111a11111111111", false, "a number with a letter"},
+	}
+	for _, card := range []revel.CreditCard{{}, revel.ValidCreditCard()} {
+		performTests(card, tests, t)
+	}
+}
+
+func TestCountryAndCurrencyCode(t *testing.T) {
+	performTests(revel.ValidCountryCode(), []Expect{
+		{"FR", true, "France"},
+		{"US", true, "the United States"},
+		{"fr", false, "a lower case code"},
+		{"FRA", false, "an alpha-3 code"},
+		{"ZZ", false, "an unknown region"},
+		{"QO", false, "a region which is not a country"},
+	}, t)
+	performTests(revel.ValidCurrencyCode(), []Expect{
+		{"EUR", true, "the Euro"},
+		{"JPY", true, "the Yen"},
+		{"eur", false, "a lower case code"},
+		{"ABC", false, "an unknown currency"},
+		{"EU", false, "a short code"},
+	}, t)
+}
+
+func TestFileName(t *testing.T) {
+	tests := []Expect{
+		{"report-2017.pdf", true, "a file name"},
+		{"résumé final.docx", true, "a file name with spaces and accents"},
+		{"../passwd", false, "a relative path"},
+		{"dir/file.txt", false, "a path"},
+		{`dir\file.txt`, false, "a windows path"},
+		{"file\x00.txt", false, "a file name with a control character"},
+		{"what?.txt", false, "a file name with a reserved character"},
+		{"NUL.txt", false, "a reserved name"},
+		{"file.", false, "a file name ending with a dot"},
+		{"..", false, "the parent directory"},
+		{"", false, "an empty string"},
+	}
+	for _, name := range []revel.FileName{{}, revel.ValidFileName()} {
+		performTests(name, tests, t)
+	}
+}
@@ -12,5 +12,5 @@ const (
 	BuildDate = "2017-10-30"
 
 	// MinimumGoVersion minimum required Go version for Revel
-	MinimumGoVersion = ">= go1.6"
+	MinimumGoVersion = ">= go1.18"
 )
@@ -0,0 +1,107 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"sync"
+	"time"
+)
+
+// The websockets of the running actions are tracked so they can be closed properly when
+// the server shuts down: a close frame is sent to every client, then the server waits for
+// the actions to return (the client answered with its own close frame and the action
+// received an EOF) up to the timeout. The close frame is configured in app.conf
+//   server.shutdown.websocket.code = 1001
+//   server.shutdown.websocket.reason = Server shutting down
+//   server.shutdown.websocket.timeout = 5s
+
+// WebSocketCloser is implemented by the server websockets which can send a close frame
+type WebSocketCloser interface {
+	MessageClose(code int, reason string) error
+}
+
+var (
+	openWebSockets      = map[ServerWebSocket]chan struct{}{}
+	openWebSocketsMutex sync.Mutex
+	websocketLog        = RevelLog.New("section", "websocket")
+)
+
+// Tracks the websocket until the returned function is called
+func trackWebSocket(ws ServerWebSocket) (untrack func()) {
+	closed := make(chan struct{})
+	openWebSocketsMutex.Lock()
+	openWebSockets[ws] = closed
+	openWebSocketsMutex.Unlock()
+	return func() {
+		openWebSocketsMutex.Lock()
+		delete(openWebSockets, ws)
+		openWebSocketsMutex.Unlock()
+		close(closed)
+	}
+}
+
+// CloseWebSockets sends a close frame with the code and reason to the websockets of the
+// running actions and waits for the actions to return, up to the timeout. It returns the
+// number of actions which did not return in time.
+func CloseWebSockets(code int, reason string, timeout time.Duration) (open int) {
+	openWebSocketsMutex.Lock()
+	websockets := make(map[ServerWebSocket]chan struct{}, len(openWebSockets))
+	for ws, closed := range openWebSockets {
+		websockets[ws] = closed
+	}
+	openWebSocketsMutex.Unlock()
+
+	// The close frames are sent concurrently, a client which does not read its websocket
+	// does not hold the others past the timeout
+	failed := make(map[ServerWebSocket]chan struct{}, len(websockets))
+	for ws := range websockets {
+		closer, ok := ws.(WebSocketCloser)
+		if !ok {
+			continue
+		}
+		sendFailed := make(chan struct{})
+		failed[ws] = sendFailed
+		go func() {
+			if err := closer.MessageClose(code, reason); err != nil {
+				websocketLog.Warn("CloseWebSockets: Failed to send the close frame", "error", err)
+				close(sendFailed)
+			}
+		}()
+	}
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	expired := false
+	for ws, closed := range websockets {
+		if !expired {
+			select {
+			case <-closed:
+				continue
+			case <-failed[ws]:
+				continue
+			case <-deadline.C:
+				expired = true
+			}
+		}
+		// The actions may have returned since the deadline
+		select {
+		case <-closed:
+		case <-failed[ws]:
+		default:
+			open++
+		}
+	}
+	return
+}
+
+// Closes the websockets as configured, called by the server engine when it shuts down
+func closeWebSocketsOnShutdown() {
+	code := Config.IntDefault("server.shutdown.websocket.code", 1001)
+	reason := Config.StringDefault("server.shutdown.websocket.reason", "Server shutting down")
+	timeout := ConfigDurationDefault("server.shutdown.websocket.timeout", 5*time.Second, time.Second)
+	if open := CloseWebSockets(code, reason, timeout); open > 0 {
+		websocketLog.Warn("Websockets still open after the shutdown timeout", "count", open)
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// A websocket which records the close frame and whose client acknowledges it when acks is set
+type closingWebSocket struct {
+	testWebSocket
+	mu     sync.Mutex
+	code   int
+	reason string
+	acks   chan bool
+}
+
+func (ws *closingWebSocket) MessageClose(code int, reason string) error {
+	ws.mu.Lock()
+	ws.code, ws.reason = code, reason
+	ws.mu.Unlock()
+	ws.acks <- true
+	return nil
+}
+
+// Returns the close frame sent
+func (ws *closingWebSocket) closeFrame() (int, string) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	return ws.code, ws.reason
+}
+
+func TestCloseWebSockets(t *testing.T) {
+	polite := &closingWebSocket{acks: make(chan bool, 1)}
+	rude := &closingWebSocket{acks: make(chan bool, 1)}
+	// The client of a stuck websocket does not read, its close frame is never sent
+	stuck := &closingWebSocket{acks: make(chan bool)}
+	trackWebSocket(stuck)
+	for _, ws := range []*closingWebSocket{polite, rude} {
+		untrack := trackWebSocket(ws)
+		go func(ws *closingWebSocket) {
+			// The action returns once the client answers the close frame
+			if <-ws.acks && ws == polite {
+				untrack()
+			}
+		}(ws)
+	}
+	defer func() {
+		openWebSockets = map[ServerWebSocket]chan struct{}{}
+	}()
+
+	start := time.Now()
+	if open := CloseWebSockets(1001, "Going away", 50*time.Millisecond); open != 2 || time.Since(start) > time.Second {
+		t.Errorf("Expected the websockets without an answer to stay open, got %d after %s", open, time.Since(start))
+	}
+	if code, _ := polite.closeFrame(); code != 1001 {
+		t.Errorf("Expected the close frame to be sent, got %d", code)
+	}
+	if _, reason := rude.closeFrame(); reason != "Going away" {
+		t.Errorf("Expected the close frame to be sent, got %q", reason)
+	}
+	if len(openWebSockets) != 2 || openWebSockets[rude] == nil || openWebSockets[stuck] == nil {
+		t.Errorf("Expected only the unanswered websockets to be tracked, got %v", openWebSockets)
+	}
+	<-stuck.acks
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+// WebSocketFilter wraps the sending or receiving of a single websocket message, like
+// a Filter wraps a request. It calls the next filter to continue, or returns an error
+// to stop the message, the error is returned to the action by MessageSendJSON or
+// MessageReceiveJSON. For an inbound message the next filter fills m.Value, so the
+// filter can inspect it after the call
+//   func LogMessages(m *revel.WebSocketMessage, fc []revel.WebSocketFilter) error {
+//   	err := fc[0](m, fc[1:])
+//   	revel.AppLog.Debug("Message", "inbound", m.Inbound, "value", m.Value, "error", err)
+//   	return err
+//   }
+type WebSocketFilter func(m *WebSocketMessage, fc []WebSocketFilter) error
+
+// WebSocketMessage is a message passing through the websocket filters
+type WebSocketMessage struct {
+	Controller *Controller
+	Inbound    bool        // True for a received message
+	Value      interface{} // The value sent, or the pointer the received message is stored in
+}
+
+// WebSocketFilters are applied to the messages of every websocket action, before the
+// filters added for the controller and the action
+var WebSocketFilters []WebSocketFilter
+
+// Map from "Controller" or "Controller.Method" to the websocket filters
+var webSocketFilterOverrides = make(map[string][]WebSocketFilter)
+
+// AddWebSocketFilter adds a filter for the messages of the websocket actions
+//   revel.FilterAction(Chat.Socket).
+//     AddWebSocketFilter(RefreshAuth).
+//     AddWebSocketFilter(LimitMessages)
+func (conf FilterConfigurator) AddWebSocketFilter(f WebSocketFilter) FilterConfigurator {
+	webSocketFilterOverrides[conf.key] = append(webSocketFilterOverrides[conf.key], f)
+	return conf
+}
+
+// Returns the websocket filters of the action, nil if there are none
+func webSocketFilterChain(controllerName, action string) (chain []WebSocketFilter) {
+	chain = append(chain, WebSocketFilters...)
+	chain = append(chain, webSocketFilterOverrides[controllerName]...)
+	if action != controllerName {
+		chain = append(chain, webSocketFilterOverrides[action]...)
+	}
+	return
+}
+
+// filteredWebSocket passes the messages of the websocket through the filters
+type filteredWebSocket struct {
+	ServerWebSocket
+	controller *Controller
+	chain      []WebSocketFilter // Ends with the filter which sends or receives the message
+}
+
+// Returns the websocket of the request wrapped by the filters of the action
+func newFilteredWebSocket(c *Controller, ws ServerWebSocket) ServerWebSocket {
+	chain := webSocketFilterChain(c.Name, c.Action)
+	if ws == nil || len(chain) == 0 {
+		return ws
+	}
+	return &filteredWebSocket{
+		ServerWebSocket: ws,
+		controller:      c,
+		chain: append(chain, func(m *WebSocketMessage, _ []WebSocketFilter) error {
+			if m.Inbound {
+				return ws.MessageReceiveJSON(m.Value)
+			}
+			return ws.MessageSendJSON(m.Value)
+		}),
+	}
+}
+
+func (ws *filteredWebSocket) MessageSendJSON(v interface{}) error {
+	return ws.chain[0](&WebSocketMessage{Controller: ws.controller, Value: v}, ws.chain[1:])
+}
+
+func (ws *filteredWebSocket) MessageReceiveJSON(v interface{}) error {
+	return ws.chain[0](&WebSocketMessage{Controller: ws.controller, Inbound: true, Value: v}, ws.chain[1:])
+}
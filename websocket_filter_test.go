@@ -0,0 +1,80 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// A websocket which records the messages sent and returns the queued messages
+type testWebSocket struct {
+	sent     []interface{}
+	received []string
+}
+
+func (ws *testWebSocket) GetRaw() interface{}                        { return nil }
+func (ws *testWebSocket) Get(theType int) (interface{}, error)       { return nil, nil }
+func (ws *testWebSocket) Set(theType int, theValue interface{}) bool { return false }
+func (ws *testWebSocket) MessageSendJSON(v interface{}) error {
+	ws.sent = append(ws.sent, v)
+	return nil
+}
+func (ws *testWebSocket) MessageReceiveJSON(v interface{}) error {
+	*(v.(*string)) = ws.received[0]
+	ws.received = ws.received[1:]
+	return nil
+}
+
+func TestWebSocketFilters(t *testing.T) {
+	var calls []string
+	tracing := func(name string) WebSocketFilter {
+		return func(m *WebSocketMessage, fc []WebSocketFilter) error {
+			calls = append(calls, name)
+			return fc[0](m, fc[1:])
+		}
+	}
+	WebSocketFilters = []WebSocketFilter{tracing("global")}
+	conf := newFilterConfigurator("Chat", "Socket")
+	conf.AddWebSocketFilter(tracing("action")).AddWebSocketFilter(func(m *WebSocketMessage, fc []WebSocketFilter) error {
+		if m.Inbound {
+			if err := fc[0](m, fc[1:]); err != nil || *(m.Value.(*string)) != "forbidden" {
+				return err
+			}
+			return errors.New("message rejected")
+		}
+		return fc[0](m, fc[1:])
+	})
+	defer func() {
+		WebSocketFilters = nil
+		delete(webSocketFilterOverrides, "Chat.Socket")
+	}()
+
+	ws := &testWebSocket{received: []string{"hello", "forbidden"}}
+	c := &Controller{Name: "Chat", Action: "Chat.Socket"}
+	filtered := newFilteredWebSocket(c, ws)
+	if err := filtered.MessageSendJSON("welcome"); err != nil || !reflect.DeepEqual(ws.sent, []interface{}{"welcome"}) {
+		t.Errorf("Expected the message to be sent, got %v %v", ws.sent, err)
+	}
+	var message string
+	if err := filtered.MessageReceiveJSON(&message); err != nil || message != "hello" {
+		t.Errorf("Expected the message to be received, got %s %v", message, err)
+	}
+	if err := filtered.MessageReceiveJSON(&message); err == nil {
+		t.Errorf("Expected the filter to reject the message")
+	}
+	if expected := []string{"global", "action", "global", "action", "global", "action"}; !reflect.DeepEqual(calls, expected) {
+		t.Errorf("Expected the filters to be called in order %v, got %v", expected, calls)
+	}
+
+	if other := newFilteredWebSocket(&Controller{Name: "Chat", Action: "Chat.Other"}, ws); len(other.(*filteredWebSocket).chain) != 2 {
+		t.Errorf("Expected only the global filter for another action")
+	}
+	WebSocketFilters = nil
+	if unfiltered := newFilteredWebSocket(&Controller{Name: "Chat", Action: "Chat.Other"}, ws); unfiltered != ws {
+		t.Errorf("Expected the websocket to be unwrapped without filters")
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/ianaindex"
+)
+
+// The XML results are encoded with the options of app.conf
+//   results.pretty = true          # Indent the XML, usually in the [dev] section
+//   results.xml.indent = "  "      # The indentation of the pretty XML
+//   results.xml.header = true      # Start with the <?xml version="1.0" encoding="UTF-8"?> declaration
+// The options of a single result are changed from the defaults, an integration expecting
+// a SOAP envelope gets its root element and namespaces
+//   options := revel.DefaultXMLOptions()
+//   options.Root = "soap:Envelope"
+//   options.Namespaces = map[string]string{"soap": "http://schemas.xmlsoap.org/soap/envelope/"}
+//   return c.RenderXMLWith(body, options)
+// The marshaler of the results is replaced to use another encoder
+//   revel.ResultXMLMarshaler = legacyMarshaler{}
+
+// XMLOptions are the options of an XML result
+type XMLOptions struct {
+	Pretty     bool              // Indent the XML
+	Indent     string            // The indentation of the pretty XML
+	Header     bool              // Start with the XML declaration
+	Encoding   string            // The encoding of the XML (an IANA name such as ISO-8859-1), UTF-8 by default
+	Root       string            // The name of the root element, the name of the value by default
+	Namespaces map[string]string // The namespaces declared on the root element, by prefix ("" for the default namespace)
+	Marshaler  XMLMarshaler      // Replaces the ResultXMLMarshaler for the result when set
+}
+
+// XMLMarshaler encodes the object of an XML result in the Encoding of the options. The
+// default marshaler transcodes the XML to the Encoding, the characters it does not have are
+// written as character references.
+type XMLMarshaler interface {
+	Marshal(v interface{}, options *XMLOptions) ([]byte, error)
+}
+
+// ResultXMLMarshaler encodes the XML results, with encoding/xml by default
+var ResultXMLMarshaler XMLMarshaler = standardXMLMarshaler{}
+
+// The options of app.conf, loaded when the application starts
+var defaultXMLOptions = XMLOptions{Indent: "  "}
+
+func init() {
+	OnAppStart(func() {
+		defaultXMLOptions = XMLOptions{
+			Pretty: Config.BoolDefault("results.pretty", false),
+			Indent: Config.StringDefault("results.xml.indent", "  "),
+			Header: Config.BoolDefault("results.xml.header", false),
+		}
+	})
+}
+
+// DefaultXMLOptions returns the options of app.conf
+func DefaultXMLOptions() *XMLOptions {
+	options := defaultXMLOptions
+	return &options
+}
+
+// Returns the encoding of the options
+func (options *XMLOptions) encoding() string {
+	if options.Encoding == "" {
+		return "UTF-8"
+	}
+	return options.Encoding
+}
+
+// Encodes the object with the options, or the options of app.conf when nil
+func marshalXMLResult(v interface{}, options *XMLOptions) ([]byte, error) {
+	if options == nil {
+		options = DefaultXMLOptions()
+	}
+	if options.Marshaler != nil {
+		return options.Marshaler.Marshal(v, options)
+	}
+	return ResultXMLMarshaler.Marshal(v, options)
+}
+
+// The marshaler of encoding/xml
+type standardXMLMarshaler struct{}
+
+func (standardXMLMarshaler) Marshal(v interface{}, options *XMLOptions) ([]byte, error) {
+	b := &bytes.Buffer{}
+	if options.Header {
+		b.WriteString(`<?xml version="1.0" encoding="` + options.encoding() + `"?>` + "\n")
+	}
+	encoder := xml.NewEncoder(b)
+	if options.Pretty {
+		encoder.Indent("", options.Indent)
+	}
+	var err error
+	if options.Root == "" && len(options.Namespaces) == 0 {
+		err = encoder.Encode(v)
+	} else {
+		root := options.Root
+		if root == "" {
+			root = xmlRootName(v)
+		}
+		start := xml.StartElement{Name: xml.Name{Local: root}}
+		prefixes := make([]string, 0, len(options.Namespaces))
+		for prefix := range options.Namespaces {
+			prefixes = append(prefixes, prefix)
+		}
+		sort.Strings(prefixes)
+		for _, prefix := range prefixes {
+			name := "xmlns"
+			if prefix != "" {
+				name += ":" + prefix
+			}
+			start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: name}, Value: options.Namespaces[prefix]})
+		}
+		err = encoder.EncodeElement(v, start)
+	}
+	if err == nil {
+		err = encoder.Flush()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return transcodeXML(b.Bytes(), options.encoding())
+}
+
+// Returns the UTF-8 XML in the encoding, an unknown encoding is an error
+func transcodeXML(data []byte, name string) ([]byte, error) {
+	if strings.EqualFold(name, "UTF-8") {
+		return data, nil
+	}
+	enc, err := ianaindex.IANA.Encoding(name)
+	if err != nil || enc == nil {
+		return nil, fmt.Errorf("xml: unsupported encoding %s", name)
+	}
+	return encoding.HTMLEscapeUnsupported(enc.NewEncoder()).Bytes(data)
+}
+
+// Returns the name encoding/xml gives the root element of the value, the name of its
+// XMLName field or of its type
+func xmlRootName(v interface{}) string {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil {
+		return "xml"
+	}
+	if t.Kind() == reflect.Struct {
+		if field, found := t.FieldByName("XMLName"); found {
+			name := strings.Split(field.Tag.Get("xml"), ",")[0]
+			// The tag may be "namespace name"
+			if i := strings.LastIndex(name, " "); i >= 0 {
+				name = name[i+1:]
+			}
+			if name != "" {
+				return name
+			}
+		}
+	}
+	if t.Name() == "" {
+		return "xml"
+	}
+	return t.Name()
+}
@@ -0,0 +1,48 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"encoding/xml"
+	"net/http/httptest"
+	"testing"
+)
+
+type xmlHotel struct {
+	XMLName xml.Name `xml:"hotel"`
+	Name    string   `xml:"name"`
+}
+
+func TestRenderXMLWith(t *testing.T) {
+	render := func(options *XMLOptions) string {
+		resp := httptest.NewRecorder()
+		c := NewTestController(resp, showRequest)
+		c.RenderXMLWith(&xmlHotel{Name: "Hilton"}, options).Apply(c.Request, c.Response)
+		return resp.Body.String()
+	}
+
+	if body := render(nil); body != `<hotel><name>Hilton</name></hotel>` {
+		t.Errorf("Unexpected default XML %s", body)
+	}
+	options := &XMLOptions{Header: true, Namespaces: map[string]string{"": "urn:hotels", "x": "urn:extra"}}
+	if body := render(options); body != `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+`<hotel xmlns="urn:hotels" xmlns:x="urn:extra"><name>Hilton</name></hotel>` {
+		t.Errorf("Unexpected XML with namespaces %s", body)
+	}
+	options = &XMLOptions{Root: "soap:Body", Pretty: true, Indent: " ", Namespaces: map[string]string{"soap": "http://schemas.xmlsoap.org/soap/envelope/"}}
+	if body := render(options); body != "<soap:Body xmlns:soap=\"http://schemas.xmlsoap.org/soap/envelope/\">\n <name>Hilton</name>\n</soap:Body>" {
+		t.Errorf("Unexpected XML with a root %s", body)
+	}
+
+	resp := httptest.NewRecorder()
+	c := NewTestController(resp, showRequest)
+	options = &XMLOptions{Header: true, Encoding: "ISO-8859-1"}
+	c.RenderXMLWith(&xmlHotel{Name: "Hôtel ★"}, options).Apply(c.Request, c.Response)
+	if body := resp.Body.String(); body != `<?xml version="1.0" encoding="ISO-8859-1"?>`+"\n"+"<hotel><name>H\xf4tel &#9733;</name></hotel>" {
+		t.Errorf("Expected the XML to be transcoded, got %q", body)
+	}
+	if _, err := marshalXMLResult(&xmlHotel{}, &XMLOptions{Encoding: "x-unknown"}); err == nil {
+		t.Errorf("Expected an unknown encoding to be an error")
+	}
+}